@@ -61,7 +61,10 @@ func (c *Commander) Type() interface{} {
 // Start implements common.Runnable.
 func (c *Commander) Start() error {
 	c.Lock()
-	c.server = grpc.NewServer()
+	c.server = grpc.NewServer(
+		grpc.UnaryInterceptor(unaryStatusInterceptor),
+		grpc.StreamInterceptor(streamStatusInterceptor),
+	)
 	for _, service := range c.services {
 		service.Register(c.server)
 	}
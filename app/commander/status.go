@@ -0,0 +1,44 @@
+package commander
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcCodes maps error codes onto gRPC status codes. The mapping is part of the API and must not change.
+var grpcCodes = map[errors.Code]codes.Code{
+	errors.CodeNotFound:      codes.NotFound,
+	errors.CodeAlreadyExists: codes.AlreadyExists,
+	errors.CodeInvalidConfig: codes.InvalidArgument,
+	errors.CodeUnavailable:   codes.Unavailable,
+	errors.CodeUnimplemented: codes.Unimplemented,
+}
+
+// toStatus turns err into a gRPC status error with the code of err, keeping its message.
+// Errors that already carry a status are returned as is.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, found := grpcCodes[errors.CodeOf(err)]
+	if !found {
+		code = codes.Unknown
+	}
+	return status.Error(code, err.Error())
+}
+
+func unaryStatusInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatus(err)
+}
+
+func streamStatusInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatus(handler(srv, ss))
+}
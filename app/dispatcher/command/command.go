@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/routing"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+const defaultConnectionLimit = 65536
+
+// ConnectionTable is the part of the dispatcher that keeps track of live connections.
+type ConnectionTable interface {
+	ListConnections() []dispatcher.Connection
+	CloseConnection(id uint64) bool
+}
+
+type connectionServer struct {
+	table ConnectionTable
+}
+
+func NewConnectionServer(table ConnectionTable) ConnectionServiceServer {
+	return &connectionServer{
+		table: table,
+	}
+}
+
+func (s *connectionServer) ListConnections(ctx context.Context, request *ListConnectionsRequest) (*ListConnectionsResponse, error) {
+	response := &ListConnectionsResponse{}
+	for _, c := range s.table.ListConnections() {
+		if request.Email != "" && c.Email != request.Email {
+			continue
+		}
+		if request.InboundTag != "" && c.InboundTag != request.InboundTag {
+			continue
+		}
+		if request.OutboundTag != "" && c.OutboundTag != request.OutboundTag {
+			continue
+		}
+		conn := &Connection{
+			Id:          c.ID,
+			Email:       c.Email,
+			InboundTag:  c.InboundTag,
+			OutboundTag: c.OutboundTag,
+			StartTime:   c.Start.Unix(),
+			Uplink:      c.Uplink,
+			Downlink:    c.Downlink,
+		}
+		if c.Source.IsValid() {
+			conn.Source = c.Source.String()
+		}
+		if c.Target.IsValid() {
+			conn.Target = c.Target.String()
+		}
+		response.Connections = append(response.Connections, conn)
+	}
+	return response, nil
+}
+
+func (s *connectionServer) CloseConnection(ctx context.Context, request *CloseConnectionRequest) (*CloseConnectionResponse, error) {
+	if !s.table.CloseConnection(request.Id) {
+		return nil, status.Errorf(codes.NotFound, "connection %d not found.", request.Id)
+	}
+	return &CloseConnectionResponse{}, nil
+}
+
+func (s *connectionServer) mustEmbedUnimplementedConnectionServiceServer() {}
+
+type service struct {
+	table ConnectionTable
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterConnectionServiceServer(server, NewConnectionServer(s.table))
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		config := cfg.(*Config)
+		limit := int(config.Limit)
+		if limit == 0 {
+			limit = defaultConnectionLimit
+		}
+		s := new(service)
+		err := core.RequireFeatures(ctx, func(d routing.Dispatcher) error {
+			dd, ok := d.(*dispatcher.DefaultDispatcher)
+			if !ok {
+				return errors.New("ConnectionService only works with the default dispatcher.")
+			}
+			dd.EnableConnectionTable(limit)
+			s.table = dd
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}))
+}
@@ -0,0 +1,72 @@
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	. "github.com/xtls/xray-core/app/dispatcher/command"
+	"github.com/xtls/xray-core/common/net"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeTable struct {
+	connections []dispatcher.Connection
+	closed      []uint64
+}
+
+func (t *fakeTable) ListConnections() []dispatcher.Connection {
+	return t.connections
+}
+
+func (t *fakeTable) CloseConnection(id uint64) bool {
+	for _, c := range t.connections {
+		if c.ID == id {
+			t.closed = append(t.closed, id)
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnectionServer(t *testing.T) {
+	table := &fakeTable{
+		connections: []dispatcher.Connection{
+			{ID: 1, Email: "a@example.com", InboundTag: "in", OutboundTag: "direct", Target: net.TCPDestination(net.DomainAddress("example.com"), 443), Uplink: 10},
+			{ID: 2, Email: "b@example.com", InboundTag: "in", OutboundTag: "proxy"},
+		},
+	}
+	server := NewConnectionServer(table)
+
+	resp, err := server.ListConnections(context.Background(), &ListConnectionsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Connections) != 2 {
+		t.Fatal("expected 2 connections, but got ", len(resp.Connections))
+	}
+
+	resp, err = server.ListConnections(context.Background(), &ListConnectionsRequest{OutboundTag: "direct"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Connections) != 1 {
+		t.Fatal("expected 1 connection, but got ", len(resp.Connections))
+	}
+	if c := resp.Connections[0]; c.Id != 1 || c.Target != "tcp:example.com:443" || c.Uplink != 10 || c.Source != "" {
+		t.Error("unexpected connection: ", c)
+	}
+
+	if _, err := server.CloseConnection(context.Background(), &CloseConnectionRequest{Id: 2}); err != nil {
+		t.Error(err)
+	}
+	if len(table.closed) != 1 || table.closed[0] != 2 {
+		t.Error("unexpected closed connections: ", table.closed)
+	}
+
+	_, err = server.CloseConnection(context.Background(), &CloseConnectionRequest{Id: 3})
+	if status.Code(err) != codes.NotFound {
+		t.Error("expected NotFound, but got ", err)
+	}
+}
@@ -0,0 +1,176 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport"
+)
+
+// Connection is a snapshot of a session going through the dispatcher.
+type Connection struct {
+	ID          uint64
+	Source      net.Destination
+	Target      net.Destination
+	Email       string
+	InboundTag  string
+	OutboundTag string
+	Start       time.Time
+	Uplink      int64
+	Downlink    int64
+}
+
+type trackedConnection struct {
+	info     Connection
+	uplink   *stats.Counter
+	downlink *stats.Counter
+	cancel   context.CancelFunc
+	link     *transport.Link
+}
+
+func (c *trackedConnection) snapshot() Connection {
+	info := c.info
+	info.Uplink = c.uplink.Value()
+	info.Downlink = c.downlink.Value()
+	return info
+}
+
+// close cancels the session and interrupts both directions of its link.
+func (c *trackedConnection) close() {
+	c.cancel()
+	common.Interrupt(c.link.Reader)
+	common.Interrupt(c.link.Writer)
+}
+
+// connectionTable keeps track of the live sessions, up to limit entries.
+type connectionTable struct {
+	access      sync.RWMutex
+	limit       int
+	nextID      atomic.Uint64
+	connections map[uint64]*trackedConnection
+}
+
+func newConnectionTable(limit int) *connectionTable {
+	return &connectionTable{
+		limit:       limit,
+		connections: make(map[uint64]*trackedConnection),
+	}
+}
+
+// add registers a connection, returning false if the table is full.
+func (t *connectionTable) add(c *trackedConnection) bool {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	if len(t.connections) >= t.limit {
+		return false
+	}
+	c.info.ID = t.nextID.Add(1)
+	t.connections[c.info.ID] = c
+	return true
+}
+
+func (t *connectionTable) remove(id uint64) {
+	t.access.Lock()
+	delete(t.connections, id)
+	t.access.Unlock()
+}
+
+func (t *connectionTable) list() []Connection {
+	t.access.RLock()
+	defer t.access.RUnlock()
+
+	list := make([]Connection, 0, len(t.connections))
+	for _, c := range t.connections {
+		list = append(list, c.snapshot())
+	}
+	return list
+}
+
+func (t *connectionTable) close(id uint64) bool {
+	t.access.RLock()
+	c, found := t.connections[id]
+	t.access.RUnlock()
+
+	if found {
+		c.close()
+	}
+	return found
+}
+
+// EnableConnectionTable starts tracking the live sessions, keeping at most limit of them.
+// Sessions beyond the limit are still dispatched normally, but are not listed.
+func (d *DefaultDispatcher) EnableConnectionTable(limit int) {
+	d.tableOnce.Do(func() {
+		d.table.Store(newConnectionTable(limit))
+	})
+}
+
+// ListConnections returns the sessions being tracked.
+func (d *DefaultDispatcher) ListConnections() []Connection {
+	if t := d.table.Load(); t != nil {
+		return t.list()
+	}
+	return nil
+}
+
+// CloseConnection terminates the session with the given id. It returns false if the session is not found.
+func (d *DefaultDispatcher) CloseConnection(id uint64) bool {
+	if t := d.table.Load(); t != nil {
+		return t.close(id)
+	}
+	return false
+}
+
+// TrackLink implements routing.TrackLinkDispatcher.
+func (d *DefaultDispatcher) TrackLink(ctx context.Context, link *transport.Link, destination net.Destination) (context.Context, *transport.Link, func()) {
+	return d.trackConnection(ctx, link, destination, "")
+}
+
+// trackConnection registers the session in the connection table if enabled.
+// The returned function must be called once the session ends.
+func (d *DefaultDispatcher) trackConnection(ctx context.Context, link *transport.Link, destination net.Destination, outboundTag string) (context.Context, *transport.Link, func()) {
+	t := d.table.Load()
+	if t == nil {
+		return ctx, link, func() {}
+	}
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	c := &trackedConnection{
+		info: Connection{
+			Target:      destination,
+			OutboundTag: outboundTag,
+			Start:       time.Now(),
+		},
+		uplink:   new(stats.Counter),
+		downlink: new(stats.Counter),
+		cancel:   cancel,
+		link:     link,
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		c.info.Source = inbound.Source
+		c.info.InboundTag = inbound.Tag
+		if inbound.User != nil {
+			c.info.Email = inbound.User.Email
+		}
+	}
+	if !t.add(c) {
+		cancel()
+		return ctx, link, func() {}
+	}
+
+	id := c.info.ID
+	return sessionCtx, &transport.Link{
+		Reader: &SizeStatReader{Counter: c.uplink, Reader: link.Reader},
+		Writer: &SizeStatWriter{Counter: c.downlink, Writer: link.Writer},
+	}, func() {
+		t.remove(id)
+		cancel()
+	}
+}
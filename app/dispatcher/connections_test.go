@@ -0,0 +1,73 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func TestConnectionTable(t *testing.T) {
+	d := new(DefaultDispatcher)
+	d.EnableConnectionTable(1)
+
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Tag:  "in",
+		User: &protocol.MemoryUser{Email: "love@example.com"},
+	})
+	uplinkReader, _ := pipe.New()
+	_, downlinkWriter := pipe.New()
+	dest := net.TCPDestination(net.DomainAddress("example.com"), 443)
+
+	sessionCtx, link, done := d.trackConnection(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, dest, "out")
+	common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("abcd"))))
+
+	_, _, done2 := d.trackConnection(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, dest, "out")
+	done2()
+
+	conns := d.ListConnections()
+	if len(conns) != 1 {
+		t.Fatal("expected 1 connection within the limit, but got ", len(conns))
+	}
+	c := conns[0]
+	if c.Email != "love@example.com" || c.InboundTag != "in" || c.OutboundTag != "out" || c.Downlink != 4 {
+		t.Error("unexpected connection: ", c)
+	}
+
+	if !d.CloseConnection(c.ID) {
+		t.Error("failed to close connection")
+	}
+	if sessionCtx.Err() == nil {
+		t.Error("expected session context to be cancelled")
+	}
+	if _, err := uplinkReader.ReadMultiBuffer(); err == nil {
+		t.Error("expected uplink to be interrupted")
+	}
+
+	done()
+	if len(d.ListConnections()) != 0 {
+		t.Error("expected connection to be removed")
+	}
+}
+
+func TestConnectionTableDone(t *testing.T) {
+	d := new(DefaultDispatcher)
+	d.EnableConnectionTable(1)
+
+	uplinkReader, _ := pipe.New()
+	_, downlinkWriter := pipe.New()
+	sessionCtx, _, done := d.trackConnection(context.Background(), &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, net.TCPDestination(net.LocalHostIP, 80), "out")
+	done()
+	if sessionCtx.Err() == nil {
+		t.Error("expected the context of the ended session to be cancelled")
+	}
+	if len(d.ListConnections()) != 0 {
+		t.Error("expected connection to be removed")
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
@@ -23,6 +25,7 @@ import (
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/pipe"
+	"golang.org/x/time/rate"
 )
 
 var errSniffingTimeout = errors.New("timeout on sniffing")
@@ -99,6 +102,17 @@ type DefaultDispatcher struct {
 	policy policy.Manager
 	stats  stats.Manager
 	fdns   dns.FakeDNSEngine
+
+	// limiters holds the rate limiters shared by all connections of the same user,
+	// each dropped when the last of those connections closes.
+	limitersAccess sync.Mutex
+	limiters       map[string]*userRateLimiter
+	hosts          *hostStats
+	ruleStats      bool
+
+	tableOnce sync.Once
+	table     atomic.Pointer[connectionTable]
+	sessions  sessionTable
 }
 
 func init() {
@@ -122,6 +136,11 @@ func (d *DefaultDispatcher) Init(config *Config, om outbound.Manager, router rou
 	d.router = router
 	d.policy = pm
 	d.stats = sm
+	sp := pm.ForSystem()
+	if sp.Stats.HostTraffic {
+		d.hosts = newHostStats(sm, sp.Stats.HostTrafficLimit)
+	}
+	d.ruleStats = sp.Stats.RuleTraffic
 	return nil
 }
 
@@ -138,6 +157,58 @@ func (*DefaultDispatcher) Start() error {
 // Close implements common.Closable.
 func (*DefaultDispatcher) Close() error { return nil }
 
+// userRateLimiter is the limiter of a user, with the number of its connections still using it.
+type userRateLimiter struct {
+	limiter *rate.Limiter
+	refs    int
+}
+
+// getRateLimiters returns the uplink and downlink limiters of the user, nil if the direction is unlimited.
+// The limiters of the user are held until ctx is done.
+func (d *DefaultDispatcher) getRateLimiters(ctx context.Context, user *protocol.MemoryUser, p policy.RateLimit) (uplink *rate.Limiter, downlink *rate.Limiter) {
+	get := func(direction string, bytesPerSec uint64) *rate.Limiter {
+		if bytesPerSec == 0 {
+			return nil
+		}
+		if !p.PerUser || len(user.Email) == 0 {
+			return NewRateLimiter(bytesPerSec)
+		}
+		name := "user>>>" + user.Email + ">>>ratelimit>>>" + direction
+		d.limitersAccess.Lock()
+		defer d.limitersAccess.Unlock()
+		l := d.limiters[name]
+		if l == nil {
+			if d.limiters == nil {
+				d.limiters = make(map[string]*userRateLimiter)
+			}
+			l = &userRateLimiter{limiter: NewRateLimiter(bytesPerSec)}
+			d.limiters[name] = l
+		} else if l.limiter.Limit() != rate.Limit(bytesPerSec) {
+			// the level of the user has changed, so all of its sessions go at the new rate
+			SetRateLimit(l.limiter, bytesPerSec)
+		}
+		l.refs++
+		context.AfterFunc(ctx, func() {
+			d.releaseRateLimiter(name)
+		})
+		return l.limiter
+	}
+	return get("uplink", p.Uplink), get("downlink", p.Downlink)
+}
+
+// releaseRateLimiter drops the limiter once no connection of the user uses it, so that the
+// limiters of the users gone do not pile up.
+func (d *DefaultDispatcher) releaseRateLimiter(name string) {
+	d.limitersAccess.Lock()
+	defer d.limitersAccess.Unlock()
+	if l := d.limiters[name]; l != nil {
+		l.refs--
+		if l.refs <= 0 {
+			delete(d.limiters, name)
+		}
+	}
+}
+
 func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *transport.Link) {
 	opt := pipe.OptionsFromContext(ctx)
 	uplinkReader, uplinkWriter := pipe.New(opt...)
@@ -193,6 +264,25 @@ func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *tran
 		}
 	}
 
+	// Throttle outside of the stats writers so that counters reflect the throttled traffic.
+	if user != nil {
+		uplink, downlink := d.getRateLimiters(ctx, user, d.policy.ForLevel(user.Level).RateLimit)
+		if uplink != nil {
+			inboundLink.Writer = &RateLimitWriter{
+				Ctx:     ctx,
+				Limiter: uplink,
+				Writer:  inboundLink.Writer,
+			}
+		}
+		if downlink != nil {
+			outboundLink.Writer = &RateLimitWriter{
+				Ctx:     ctx,
+				Limiter: downlink,
+				Writer:  outboundLink.Writer,
+			}
+		}
+	}
+
 	return inboundLink, outboundLink
 }
 
@@ -203,6 +293,18 @@ func (d *DefaultDispatcher) WrapLink(ctx context.Context, link *transport.Link)
 		user = sessionInbound.User
 	}
 
+	var uplink, downlink *rate.Limiter
+	if user != nil {
+		uplink, downlink = d.getRateLimiters(ctx, user, d.policy.ForLevel(user.Level).RateLimit)
+	}
+	if uplink != nil {
+		link.Reader = &RateLimitReader{
+			Ctx:     ctx,
+			Limiter: uplink,
+			Reader:  link.Reader,
+		}
+	}
+
 	link.Reader = &buf.TimeoutWrapperReader{Reader: link.Reader}
 
 	if user != nil && len(user.Email) > 0 {
@@ -234,9 +336,22 @@ func (d *DefaultDispatcher) WrapLink(ctx context.Context, link *transport.Link)
 		}
 	}
 
+	if downlink != nil {
+		link.Writer = &RateLimitWriter{
+			Ctx:     ctx,
+			Limiter: downlink,
+			Writer:  link.Writer,
+		}
+	}
+
 	return link
 }
 
+// shouldSniff returns whether to sniff the connection. The connections to the ports not listed skip the sniffing, and its buffering, entirely.
+func shouldSniff(request session.SniffingRequest, destination net.Destination) bool {
+	return request.Enabled && (len(request.Ports) == 0 || request.Ports.Contains(destination.Port))
+}
+
 func (d *DefaultDispatcher) shouldOverride(ctx context.Context, result SniffResult, request session.SniffingRequest, destination net.Destination) bool {
 	domain := result.Domain()
 	if domain == "" {
@@ -282,11 +397,21 @@ func (d *DefaultDispatcher) shouldOverride(ctx context.Context, result SniffResu
 	return false
 }
 
+// contextWithSessionID gives an ID to the sessions not coming from an inbound worker, like the internal DNS queries,
+// so that all of them can be matched between the access and error logs.
+func contextWithSessionID(ctx context.Context) context.Context {
+	if c.IDFromContext(ctx) == 0 {
+		return c.ContextWithID(ctx, session.NewID())
+	}
+	return ctx
+}
+
 // Dispatch implements routing.Dispatcher.
 func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destination) (*transport.Link, error) {
 	if !destination.IsValid() {
 		panic("Dispatcher: Invalid destination.")
 	}
+	ctx = contextWithSessionID(ctx)
 	outbounds := session.OutboundsFromContext(ctx)
 	if len(outbounds) == 0 {
 		outbounds = []*session.Outbound{{}}
@@ -303,7 +428,11 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 
 	sniffingRequest := content.SniffingRequest
 	inbound, outbound := d.getLink(ctx)
-	if !sniffingRequest.Enabled {
+	ctx = contextWithLinkPipes(ctx, &linkPipes{
+		uplink:   outbound.Reader.(*pipe.Reader),
+		downlink: inbound.Reader.(*pipe.Reader),
+	})
+	if !shouldSniff(sniffingRequest, destination) {
 		go d.routedDispatch(ctx, outbound, destination)
 	} else {
 		go func() {
@@ -311,10 +440,7 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 				reader: outbound.Reader.(*pipe.Reader),
 			}
 			outbound.Reader = cReader
-			result, err := sniffer(ctx, cReader, sniffingRequest.MetadataOnly, destination.Network)
-			if err == nil {
-				content.Protocol = result.Protocol()
-			}
+			result, err := d.sniff(ctx, content, cReader, sniffingRequest, destination.Network)
 			if err == nil && d.shouldOverride(ctx, result, sniffingRequest, destination) {
 				domain := result.Domain()
 				errors.LogInfo(ctx, "sniffed domain: ", domain)
@@ -344,6 +470,7 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 	if !destination.IsValid() {
 		return errors.New("Dispatcher: Invalid destination.")
 	}
+	ctx = contextWithSessionID(ctx)
 	outbounds := session.OutboundsFromContext(ctx)
 	if len(outbounds) == 0 {
 		outbounds = []*session.Outbound{{}}
@@ -359,17 +486,14 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 	}
 	outbound = d.WrapLink(ctx, outbound)
 	sniffingRequest := content.SniffingRequest
-	if !sniffingRequest.Enabled {
+	if !shouldSniff(sniffingRequest, destination) {
 		d.routedDispatch(ctx, outbound, destination)
 	} else {
 		cReader := &cachedReader{
 			reader: outbound.Reader.(buf.TimeoutReader),
 		}
 		outbound.Reader = cReader
-		result, err := sniffer(ctx, cReader, sniffingRequest.MetadataOnly, destination.Network)
-		if err == nil {
-			content.Protocol = result.Protocol()
-		}
+		result, err := d.sniff(ctx, content, cReader, sniffingRequest, destination.Network)
 		if err == nil && d.shouldOverride(ctx, result, sniffingRequest, destination) {
 			domain := result.Domain()
 			errors.LogInfo(ctx, "sniffed domain: ", domain)
@@ -394,20 +518,66 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 	return nil
 }
 
-func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, network net.Network) (SniffResult, error) {
-	payload := buf.NewWithSize(32767)
+// sniff sniffs the content of the connection, and records the results in it. The results of a content sniffed before,
+// when the connection is dispatched once more like by loopback, are reused without reading it, unless the request resniffs.
+func (d *DefaultDispatcher) sniff(ctx context.Context, content *session.Content, cReader *cachedReader, request session.SniffingRequest, network net.Network) (SniffResult, error) {
+	if content.Sniffed && !request.Resniff {
+		if content.Protocol == "" {
+			return nil, common.ErrNoClue
+		}
+		errors.LogDebug(ctx, "reusing sniffed protocol ", content.Protocol, " and domain ", content.Domain)
+		return &sniffedResult{protocol: content.Protocol, domain: content.Domain}, nil
+	}
+	result, err := sniffer(ctx, cReader, request, network, d.tlsFingerprints())
+	content.Sniffed = true
+	if err == nil {
+		content.Protocol = result.Protocol()
+		content.Domain = result.Domain()
+		errors.LogDebug(ctx, "sniffed content of protocol ", content.Protocol, " and domain ", content.Domain)
+	}
+	return result, err
+}
+
+// tlsFingerprints returns the fingerprints of the TLS ClientHello that the routing rules match, the only ones computed.
+func (d *DefaultDispatcher) tlsFingerprints() tlsFingerprints {
+	r, ok := d.router.(routing.AttributeRouter)
+	if !ok {
+		return tlsFingerprints{}
+	}
+	return tlsFingerprints{
+		ja3: r.MatchesAttribute(ja3Attribute),
+		ja4: r.MatchesAttribute(ja4Attribute),
+	}
+}
+
+func sniffer(ctx context.Context, cReader *cachedReader, request session.SniffingRequest, network net.Network, fingerprints tlsFingerprints) (SniffResult, error) {
+	bufferSize := int32(32767)
+	if request.BufferSize > 0 {
+		bufferSize = request.BufferSize
+	}
+	payload := buf.NewWithSize(bufferSize)
 	defer payload.Release()
 
 	sniffer := NewSniffer(ctx)
+	sniffer.fingerprints = fingerprints
 
 	metaresult, metadataErr := sniffer.SniffMetadata(ctx)
 
-	if metadataOnly {
+	if request.MetadataOnly {
 		return metaresult, metadataErr
 	}
 
+	// Give up after two attempts without a clue, or more for the UDP protocols revealing the domain late.
+	maxAttempts := 2
+	if network == net.Network_UDP && request.UDPPackets > maxAttempts {
+		maxAttempts = request.UDPPackets
+	}
+
 	contentResult, contentErr := func() (SniffResult, error) {
 		cacheDeadline := 200 * time.Millisecond
+		if request.Timeout > 0 {
+			cacheDeadline = request.Timeout
+		}
 		totalAttempt := 0
 		for {
 			select {
@@ -419,6 +589,10 @@ func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, netw
 				if err != nil {
 					return nil, err
 				}
+				// The pooled buffer may be larger than the buffer size, which the sniffers must not see past.
+				if payload.Len() > bufferSize {
+					payload.Resize(0, bufferSize)
+				}
 				cachingTimeElapsed := time.Since(cachingStartingTimeStamp)
 				cacheDeadline -= cachingTimeElapsed
 
@@ -435,7 +609,7 @@ func sniffer(ctx context.Context, cReader *cachedReader, metadataOnly bool, netw
 				} else {
 					totalAttempt++
 				}
-				if totalAttempt >= 2 || cacheDeadline <= 0 {
+				if totalAttempt >= maxAttempts || cacheDeadline <= 0 {
 					return nil, errSniffingTimeout
 				}
 			}
@@ -457,6 +631,8 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 
 	routingLink := routing_session.AsRoutingContext(ctx)
 	inTag := routingLink.GetInboundTag()
+	ruleTag := ""
+	var routeReason *log.RouteReason
 	isPickRoute := 0
 	if forcedOutboundTag := session.GetForcedOutboundTagFromContext(ctx); forcedOutboundTag != "" {
 		ctx = session.SetForcedOutboundTagToContext(ctx, "")
@@ -475,6 +651,11 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			outTag := route.GetOutboundTag()
 			if h := d.ohm.GetHandler(outTag); h != nil {
 				isPickRoute = 2
+				ruleTag = route.GetRuleTag()
+				routeReason = &log.RouteReason{Rule: true, RuleTag: ruleTag}
+				if groups := route.GetOutboundGroupTags(); len(groups) > 0 {
+					routeReason.Balancer = groups[len(groups)-1]
+				}
 				if route.GetRuleTag() == "" {
 					errors.LogInfo(ctx, "taking detour [", outTag, "] for [", destination, "]")
 				} else {
@@ -489,6 +670,7 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			}
 		} else {
 			errors.LogInfo(ctx, "default route for ", destination)
+			routeReason = &log.RouteReason{Default: true}
 		}
 	}
 
@@ -504,7 +686,55 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 	}
 
 	ob.Tag = handler.Tag()
+	// The outbound may override the watermarks of the links of this connection, which
+	// are only known once the connection is routed.
+	if h, ok := handler.(pipeWatermarker); ok {
+		if high, low := h.PipeWatermarks(); high > 0 {
+			linkPipesFromContext(ctx).setWatermarks(high, low)
+		}
+	}
+	if d.hosts != nil {
+		target := ob.Target
+		if ob.RouteTarget.IsValid() {
+			target = ob.RouteTarget
+		}
+		if target.Address != nil {
+			uplink, downlink := d.hosts.Counters(target.Address.String())
+			if uplink != nil && downlink != nil {
+				link = &transport.Link{
+					Reader: &SizeStatReader{Counter: uplink, Reader: link.Reader},
+					Writer: &SizeStatWriter{Counter: downlink, Writer: link.Writer},
+				}
+			}
+		}
+	}
+	if d.ruleStats && ruleTag != "" {
+		uplink, _ := stats.GetOrRegisterCounter(d.stats, ruleCounterName(ruleTag, "uplink"))
+		downlink, _ := stats.GetOrRegisterCounter(d.stats, ruleCounterName(ruleTag, "downlink"))
+		if uplink != nil && downlink != nil {
+			link = &transport.Link{
+				Reader: &SizeStatReader{Counter: uplink, Reader: link.Reader},
+				Writer: &SizeStatWriter{Counter: downlink, Writer: link.Writer},
+			}
+		}
+	}
 	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
+		accessMessage.SessionID = uint32(c.IDFromContext(ctx))
+		accessMessage.InboundTag = inTag
+		accessMessage.OutboundTag = handler.Tag()
+		if content := session.ContentFromContext(ctx); content != nil && content.Protocol == "tls" {
+			accessMessage.JA3 = content.Attribute(ja3Attribute)
+			accessMessage.JA4 = content.Attribute(ja4Attribute)
+		}
+		if ob.OriginalTarget.Address != nil && destination.Address.String() != ob.OriginalTarget.Address.String() {
+			if routeReason == nil {
+				routeReason = new(log.RouteReason)
+			}
+			routeReason.SniffedDomain = destination.Address.String()
+		}
+		if routeReason != nil && routeReason.String() != "" {
+			accessMessage.Reason = routeReason
+		}
 		if tag := handler.Tag(); tag != "" {
 			if inTag == "" {
 				accessMessage.Detour = tag
@@ -519,5 +749,10 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 		log.Record(accessMessage)
 	}
 
+	ctx, link, done := d.trackConnection(ctx, link, destination, handler.Tag())
+	defer done()
+	ctx, link, release := d.sessions.add(ctx, link, destination.Network, handler.Tag())
+	defer release()
+
 	handler.Dispatch(ctx, link)
 }
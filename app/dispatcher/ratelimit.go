@@ -0,0 +1,82 @@
+package dispatcher
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"golang.org/x/time/rate"
+)
+
+// NewRateLimiter creates a token bucket that allows bytesPerSec bytes per second,
+// with a burst of one second worth of traffic.
+func NewRateLimiter(bytesPerSec uint64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(bytesPerSec), rateLimitBurst(bytesPerSec))
+}
+
+// SetRateLimit changes the rate of a limiter from NewRateLimiter, for the sessions already using it as well.
+func SetRateLimit(limiter *rate.Limiter, bytesPerSec uint64) {
+	limiter.SetLimit(rate.Limit(bytesPerSec))
+	limiter.SetBurst(rateLimitBurst(bytesPerSec))
+}
+
+func rateLimitBurst(bytesPerSec uint64) int {
+	return int(min(bytesPerSec, uint64(1<<30)))
+}
+
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	burst := limiter.Burst()
+	for n > 0 {
+		k := min(n, burst)
+		if err := limiter.WaitN(ctx, k); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
+
+// RateLimitWriter delays writes so that the traffic going through it does not exceed the limiter.
+type RateLimitWriter struct {
+	Ctx     context.Context
+	Limiter *rate.Limiter
+	Writer  buf.Writer
+}
+
+func (w *RateLimitWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if err := waitN(w.Ctx, w.Limiter, int(mb.Len())); err != nil {
+		buf.ReleaseMulti(mb)
+		return err
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *RateLimitWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+func (w *RateLimitWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
+
+// RateLimitReader delays reads so that the traffic going through it does not exceed the limiter.
+type RateLimitReader struct {
+	Ctx     context.Context
+	Limiter *rate.Limiter
+	Reader  buf.Reader
+}
+
+func (r *RateLimitReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	if !mb.IsEmpty() {
+		if werr := waitN(r.Ctx, r.Limiter, int(mb.Len())); werr != nil {
+			buf.ReleaseMulti(mb)
+			return nil, werr
+		}
+	}
+	return mb, err
+}
+
+func (r *RateLimitReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
@@ -0,0 +1,69 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/features/policy"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitersOfUserRefreshed(t *testing.T) {
+	d := new(DefaultDispatcher)
+	user := &protocol.MemoryUser{Email: "love@example.com"}
+	ctx := context.Background()
+
+	uplink, _ := d.getRateLimiters(ctx, user, policy.RateLimit{PerUser: true, Uplink: 1000})
+	again, _ := d.getRateLimiters(ctx, user, policy.RateLimit{PerUser: true, Uplink: 1000})
+	if again != uplink {
+		t.Error("expected the sessions of a user to share its limiter")
+	}
+
+	// the user is added back at a level of another rate
+	updated, _ := d.getRateLimiters(ctx, user, policy.RateLimit{PerUser: true, Uplink: 5000})
+	if updated != uplink {
+		t.Error("expected the limiter of the user to be kept")
+	}
+	if uplink.Limit() != rate.Limit(5000) || uplink.Burst() != 5000 {
+		t.Error("expected the limiter to take the new rate, but got ", uplink.Limit(), " with a burst of ", uplink.Burst())
+	}
+}
+
+func TestRateLimitersOfUserReleased(t *testing.T) {
+	d := new(DefaultDispatcher)
+	user := &protocol.MemoryUser{Email: "love@example.com"}
+	p := policy.RateLimit{PerUser: true, Uplink: 1000, Downlink: 1000}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	uplink, _ := d.getRateLimiters(ctx1, user, p)
+	d.getRateLimiters(ctx2, user, p)
+
+	limiters := func() int {
+		d.limitersAccess.Lock()
+		defer d.limitersAccess.Unlock()
+		return len(d.limiters)
+	}
+	waitLimiters := func(n int) {
+		for deadline := time.Now().Add(5 * time.Second); limiters() != n; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal("expected ", n, " limiters, but got ", limiters())
+			}
+		}
+	}
+
+	cancel1()
+	time.Sleep(100 * time.Millisecond)
+	waitLimiters(2)
+
+	// the last connection of the user closes
+	cancel2()
+	waitLimiters(0)
+
+	again, _ := d.getRateLimiters(context.Background(), user, p)
+	if again == uplink {
+		t.Error("expected a new limiter for the user back")
+	}
+}
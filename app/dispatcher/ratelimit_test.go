@@ -0,0 +1,46 @@
+package dispatcher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+)
+
+func TestRateLimitWriter(t *testing.T) {
+	var c TestCounter
+	writer := &RateLimitWriter{
+		Ctx:     context.Background(),
+		Limiter: NewRateLimiter(10000),
+		Writer: &SizeStatWriter{
+			Counter: &c,
+			Writer:  buf.Discard,
+		},
+	}
+
+	start := time.Now()
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 10000))))
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 5000))))
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Error("expected writes to be throttled, but took ", elapsed)
+	}
+	if c.Value() != 15000 {
+		t.Error("unexpected counter value. want 15000, but got ", c.Value())
+	}
+}
+
+func TestRateLimitWriterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer := &RateLimitWriter{
+		Ctx:     ctx,
+		Limiter: NewRateLimiter(1000),
+		Writer:  buf.Discard,
+	}
+	if err := writer.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 5000))); err == nil {
+		t.Error("expected error on cancelled context")
+	}
+}
@@ -0,0 +1,114 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+type staticRoute struct {
+	routing.Context
+	outboundTag string
+	ruleTag     string
+	groupTags   []string
+}
+
+func (r *staticRoute) GetOutboundGroupTags() []string { return r.groupTags }
+
+func (r *staticRoute) GetOutboundTag() string { return r.outboundTag }
+
+func (r *staticRoute) GetRuleTag() string { return r.ruleTag }
+
+// staticRouter routes every connection by its route, or to the default outbound without it.
+type staticRouter struct {
+	routing.DefaultRouter
+	route *staticRoute
+}
+
+func (r *staticRouter) PickRoute(ctx routing.Context) (routing.Route, error) {
+	if r.route == nil {
+		return nil, common.ErrNoClue
+	}
+	return r.route, nil
+}
+
+type discardHandler struct {
+	outbound.Handler
+	tag string
+}
+
+func (h *discardHandler) Tag() string { return h.tag }
+
+func (h *discardHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	common.Close(link.Writer)
+}
+
+type staticOutboundManager struct {
+	outbound.Manager
+	handler *discardHandler
+}
+
+func (m *staticOutboundManager) GetHandler(tag string) outbound.Handler {
+	if tag == m.handler.tag {
+		return m.handler
+	}
+	return nil
+}
+
+func (m *staticOutboundManager) GetDefaultHandler() outbound.Handler { return m.handler }
+
+func TestRoutedDispatchReason(t *testing.T) {
+	ip := net.TCPDestination(net.ParseAddress("1.2.3.4"), 443)
+	domain := net.TCPDestination(net.DomainAddress("example.com"), 443)
+
+	testCases := []struct {
+		name        string
+		route       *staticRoute
+		forced      bool
+		destination net.Destination
+		reason      string
+	}{
+		{name: "tagged rule", route: &staticRoute{outboundTag: "out", ruleTag: "direct"}, destination: ip, reason: "rule:direct"},
+		{name: "untagged rule", route: &staticRoute{outboundTag: "out"}, destination: ip, reason: "rule"},
+		{name: "rule tagged default", route: &staticRoute{outboundTag: "out", ruleTag: "default"}, destination: ip, reason: "rule:default"},
+		{name: "rule tagged rule", route: &staticRoute{outboundTag: "out", ruleTag: "rule"}, destination: ip, reason: "rule:rule"},
+		{name: "balancer", route: &staticRoute{outboundTag: "out", groupTags: []string{"proxies"}}, destination: ip, reason: "rule,balancer:proxies"},
+		{name: "default", destination: ip, reason: "default"},
+		{name: "sniffed", route: &staticRoute{outboundTag: "out"}, destination: domain, reason: "rule,sniffed:example.com"},
+		{name: "forced", forced: true, destination: ip, reason: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &DefaultDispatcher{
+				ohm:    &staticOutboundManager{handler: &discardHandler{tag: "out"}},
+				router: &staticRouter{route: tc.route},
+			}
+
+			accessMessage := &log.AccessMessage{}
+			ctx := log.ContextWithAccessMessage(context.Background(), accessMessage)
+			ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: tc.destination, OriginalTarget: ip}})
+			if tc.forced {
+				ctx = session.SetForcedOutboundTagToContext(ctx, "out")
+			}
+			uplinkReader, _ := pipe.New()
+			_, downlinkWriter := pipe.New()
+			d.routedDispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, tc.destination)
+
+			reason := ""
+			if accessMessage.Reason != nil {
+				reason = accessMessage.Reason.(*log.RouteReason).String()
+			}
+			if reason != tc.reason {
+				t.Error("expected reason ", tc.reason, ", but got ", reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+type resourceKey int
+
+const linkPipesKey resourceKey = 0
+
+// pipeWatermarker is implemented by the outbound handlers whose streamSettings override the pipe watermarks.
+type pipeWatermarker interface {
+	PipeWatermarks() (high, low int32)
+}
+
+// linkPipes are the pipes of the links created by Dispatch.
+type linkPipes struct {
+	uplink   *pipe.Reader
+	downlink *pipe.Reader
+}
+
+func contextWithLinkPipes(ctx context.Context, pipes *linkPipes) context.Context {
+	return context.WithValue(ctx, linkPipesKey, pipes)
+}
+
+func linkPipesFromContext(ctx context.Context) *linkPipes {
+	if pipes, ok := ctx.Value(linkPipesKey).(*linkPipes); ok {
+		return pipes
+	}
+	return nil
+}
+
+// setWatermarks applies the watermarks to both pipes, if any.
+func (p *linkPipes) setWatermarks(high, low int32) {
+	if p == nil {
+		return
+	}
+	p.uplink.SetWatermarks(high, low)
+	p.downlink.SetWatermarks(high, low)
+}
+
+func (p *linkPipes) Len() int64 {
+	if p == nil {
+		return 0
+	}
+	return int64(p.uplink.Len()) + int64(p.downlink.Len())
+}
+
+type liveSession struct {
+	inboundTag  string
+	outboundTag string
+	network     net.Network
+	pipes       *linkPipes
+	level       uint32
+	// lastActivity is the time of the last data in either direction, in unix nanoseconds. TCP only.
+	lastActivity atomic.Int64
+	cancel       context.CancelFunc
+	link         *transport.Link
+}
+
+func (s *liveSession) touch() {
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// close cancels the session and interrupts both directions of its link.
+func (s *liveSession) close() {
+	s.cancel()
+	common.Interrupt(s.link.Reader)
+	common.Interrupt(s.link.Writer)
+}
+
+type activityReader struct {
+	session *liveSession
+	reader  buf.Reader
+}
+
+func (r *activityReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.reader.ReadMultiBuffer()
+	if !mb.IsEmpty() {
+		r.session.touch()
+	}
+	return mb, err
+}
+
+func (r *activityReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.reader.(buf.TimeoutReader)
+	if !ok {
+		return r.ReadMultiBuffer()
+	}
+	mb, err := tr.ReadMultiBufferTimeout(timeout)
+	if !mb.IsEmpty() {
+		r.session.touch()
+	}
+	return mb, err
+}
+
+func (r *activityReader) Interrupt() {
+	common.Interrupt(r.reader)
+}
+
+type activityWriter struct {
+	session *liveSession
+	writer  buf.Writer
+}
+
+func (w *activityWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if !mb.IsEmpty() {
+		w.session.touch()
+	}
+	return w.writer.WriteMultiBuffer(mb)
+}
+
+func (w *activityWriter) Close() error {
+	return common.Close(w.writer)
+}
+
+func (w *activityWriter) Interrupt() {
+	common.Interrupt(w.writer)
+}
+
+// sessionTable keeps the sessions being dispatched, for reporting their resources.
+// Unlike the connection table, it is always on and keeps nothing per session but the tags and the pipes.
+type sessionTable struct {
+	access   sync.Mutex
+	sessions map[*liveSession]struct{}
+}
+
+// add registers the session. TCP sessions get their activity tracked, so that CollectIdle can close them.
+// The returned function must be called once the session ends.
+func (t *sessionTable) add(ctx context.Context, link *transport.Link, network net.Network, outboundTag string) (context.Context, *transport.Link, func()) {
+	s := &liveSession{
+		outboundTag: outboundTag,
+		network:     network,
+		pipes:       linkPipesFromContext(ctx),
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		s.inboundTag = inbound.Tag
+		if inbound.User != nil {
+			s.level = inbound.User.Level
+		}
+	}
+	if network == net.Network_TCP {
+		ctx, s.cancel = context.WithCancel(ctx)
+		s.link = link
+		s.touch()
+		link = &transport.Link{
+			Reader: &activityReader{session: s, reader: link.Reader},
+			Writer: &activityWriter{session: s, writer: link.Writer},
+		}
+	}
+
+	t.access.Lock()
+	if t.sessions == nil {
+		t.sessions = make(map[*liveSession]struct{})
+	}
+	t.sessions[s] = struct{}{}
+	t.access.Unlock()
+
+	return ctx, link, func() {
+		t.access.Lock()
+		delete(t.sessions, s)
+		t.access.Unlock()
+		if s.cancel != nil {
+			s.cancel()
+		}
+	}
+}
+
+// ReportResources implements stats.ResourceReporter. TCP sessions count for both of their handlers,
+// while UDP sessions count as NAT entries for their outbounds only, as inbounds report their own NAT tables.
+func (d *DefaultDispatcher) ReportResources(report *stats.ResourceReport) {
+	d.sessions.access.Lock()
+	defer d.sessions.access.Unlock()
+
+	for s := range d.sessions.sessions {
+		in := report.Inbound(s.inboundTag)
+		out := report.Outbound(s.outboundTag)
+		switch s.network {
+		case net.Network_TCP:
+			in.TCPSessions++
+			out.TCPSessions++
+		case net.Network_UDP:
+			out.UDPNATEntries++
+		}
+		n := s.pipes.Len()
+		in.PipeBytes += n
+		out.PipeBytes += n
+	}
+}
+
+// CollectIdle implements stats.IdleCollector. It closes the TCP sessions without data in either direction
+// for longer than the connIdle of the policy of their users, as UDP sessions expire in the NAT tables of their handlers.
+func (d *DefaultDispatcher) CollectIdle() int {
+	now := time.Now()
+	var idle []*liveSession
+	d.sessions.access.Lock()
+	for s := range d.sessions.sessions {
+		if s.network != net.Network_TCP {
+			continue
+		}
+		timeout := d.policy.ForLevel(s.level).Timeouts.ConnectionIdle
+		if now.Sub(time.Unix(0, s.lastActivity.Load())) > timeout {
+			idle = append(idle, s)
+		}
+	}
+	d.sessions.access.Unlock()
+
+	for _, s := range idle {
+		s.close()
+	}
+	return len(idle)
+}
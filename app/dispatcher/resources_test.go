@@ -0,0 +1,107 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func TestReportResources(t *testing.T) {
+	d := new(DefaultDispatcher)
+
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: "in"})
+	uplinkReader, uplinkWriter := pipe.New()
+	downlinkReader, downlinkWriter := pipe.New()
+	common.Must(uplinkWriter.WriteMultiBuffer(buf.MergeBytes(nil, []byte("abcd"))))
+	tcpCtx := contextWithLinkPipes(ctx, &linkPipes{uplink: uplinkReader, downlink: downlinkReader})
+
+	link := &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}
+	_, _, releaseTCP := d.sessions.add(tcpCtx, link, net.Network_TCP, "out")
+	_, _, releaseUDP := d.sessions.add(ctx, link, net.Network_UDP, "out")
+
+	report := stats.NewResourceReport()
+	d.ReportResources(report)
+	if in := report.Inbound("in"); in.TCPSessions != 1 || in.UDPNATEntries != 0 || in.PipeBytes != 4 {
+		t.Error("unexpected inbound resources: ", *in)
+	}
+	if out := report.Outbound("out"); out.TCPSessions != 1 || out.UDPNATEntries != 1 || out.PipeBytes != 4 {
+		t.Error("unexpected outbound resources: ", *out)
+	}
+
+	releaseTCP()
+	releaseUDP()
+	report = stats.NewResourceReport()
+	d.ReportResources(report)
+	if len(report.Inbounds) != 0 || len(report.Outbounds) != 0 {
+		t.Error("expected sessions to be removed")
+	}
+}
+
+type idlePolicy struct {
+	policy.DefaultManager
+	idle time.Duration
+}
+
+func (p idlePolicy) ForLevel(level uint32) policy.Session {
+	s := policy.SessionDefault()
+	s.Timeouts.ConnectionIdle = p.idle * time.Duration(level+1)
+	return s
+}
+
+func TestCollectIdle(t *testing.T) {
+	d := &DefaultDispatcher{policy: idlePolicy{idle: 200 * time.Millisecond}}
+
+	newSession := func(level uint32, network net.Network) (context.Context, *transport.Link, func()) {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			Tag:  "in",
+			User: &protocol.MemoryUser{Level: level},
+		})
+		uplinkReader, _ := pipe.New()
+		_, downlinkWriter := pipe.New()
+		return d.sessions.add(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, network, "out")
+	}
+	idleCtx, _, releaseIdle := newSession(0, net.Network_TCP)
+	defer releaseIdle()
+	activeCtx, activeLink, releaseActive := newSession(0, net.Network_TCP)
+	defer releaseActive()
+	patientCtx, _, releasePatient := newSession(4, net.Network_TCP)
+	defer releasePatient()
+	udpCtx, _, releaseUDP := newSession(0, net.Network_UDP)
+	defer releaseUDP()
+
+	keepAlive := done.New()
+	defer keepAlive.Close()
+	go func() {
+		for !keepAlive.Done() {
+			if activeLink.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("a"))) != nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	time.Sleep(400 * time.Millisecond)
+	if n := d.CollectIdle(); n != 1 {
+		t.Error("expected 1 idle session, but got ", n)
+	}
+	if idleCtx.Err() == nil {
+		t.Error("idle TCP session not closed")
+	}
+	if activeCtx.Err() != nil || patientCtx.Err() != nil {
+		t.Error("closed a session within its idle timeout")
+	}
+	if udpCtx.Err() != nil {
+		t.Error("closed a UDP session, which is up to the NAT tables")
+	}
+}
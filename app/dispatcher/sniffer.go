@@ -11,6 +11,7 @@ import (
 	"github.com/xtls/xray-core/common/protocol/http"
 	"github.com/xtls/xray-core/common/protocol/quic"
 	"github.com/xtls/xray-core/common/protocol/tls"
+	"github.com/xtls/xray-core/common/session"
 )
 
 type SniffResult interface {
@@ -29,19 +30,34 @@ type protocolSnifferWithMetadata struct {
 	network         net.Network
 }
 
+// The attributes of the content holding the fingerprints of the TLS ClientHello.
+const (
+	ja3Attribute = "ja3"
+	ja4Attribute = "ja4"
+)
+
+// tlsFingerprints is the fingerprints of the TLS ClientHello to compute while sniffing.
+type tlsFingerprints struct {
+	ja3 bool
+	ja4 bool
+}
+
 type Sniffer struct {
-	sniffer []protocolSnifferWithMetadata
+	sniffer      []protocolSnifferWithMetadata
+	fingerprints tlsFingerprints
 }
 
 func NewSniffer(ctx context.Context) *Sniffer {
-	ret := &Sniffer{
-		sniffer: []protocolSnifferWithMetadata{
-			{func(c context.Context, b []byte) (SniffResult, error) { return http.SniffHTTP(b, c) }, false, net.Network_TCP},
-			{func(c context.Context, b []byte) (SniffResult, error) { return tls.SniffTLS(b) }, false, net.Network_TCP},
-			{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffBittorrent(b) }, false, net.Network_TCP},
-			{func(c context.Context, b []byte) (SniffResult, error) { return quic.SniffQUIC(b) }, false, net.Network_UDP},
-			{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffUTP(b) }, false, net.Network_UDP},
-		},
+	utp := new(bittorrent.UTPSniffer)
+	ret := new(Sniffer)
+	ret.sniffer = []protocolSnifferWithMetadata{
+		{func(c context.Context, b []byte) (SniffResult, error) { return http.SniffHTTP(b, c) }, false, net.Network_TCP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return http.SniffHTTP2(b, c) }, false, net.Network_TCP},
+		{ret.sniffTLS, false, net.Network_TCP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffBittorrent(b) }, false, net.Network_TCP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return quic.SniffQUIC(b) }, false, net.Network_UDP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return utp.Sniff(b) }, false, net.Network_UDP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffDHT(b) }, false, net.Network_UDP},
 	}
 	if sniffer, err := newFakeDNSSniffer(ctx); err == nil {
 		others := ret.sniffer
@@ -56,6 +72,27 @@ func NewSniffer(ctx context.Context) *Sniffer {
 
 var errUnknownContent = errors.New("unknown content")
 
+// sniffTLS sniffs the TLS ClientHello, and puts the fingerprints of it to compute into the attributes of the content.
+func (s *Sniffer) sniffTLS(c context.Context, b []byte) (SniffResult, error) {
+	h, err := tls.SniffTLS(b)
+	if err != nil {
+		return nil, err
+	}
+	if content := session.ContentFromContext(c); content != nil {
+		if s.fingerprints.ja3 {
+			if ja3, err := tls.JA3(b); err == nil {
+				content.SetAttribute(ja3Attribute, ja3)
+			}
+		}
+		if s.fingerprints.ja4 {
+			if ja4, err := tls.JA4(b); err == nil {
+				content.SetAttribute(ja4Attribute, ja4)
+			}
+		}
+	}
+	return h, nil
+}
+
 func (s *Sniffer) Sniff(c context.Context, payload []byte, network net.Network) (SniffResult, error) {
 	var pendingSniffer []protocolSnifferWithMetadata
 	for _, si := range s.sniffer {
@@ -133,6 +170,20 @@ func (c compositeResult) ProtocolForDomainResult() string {
 	return c.domainResult.Protocol()
 }
 
+// sniffedResult is the result of an earlier sniffing of the content, recorded in it.
+type sniffedResult struct {
+	protocol string
+	domain   string
+}
+
+func (r *sniffedResult) Protocol() string {
+	return r.protocol
+}
+
+func (r *sniffedResult) Domain() string {
+	return r.domain
+}
+
 type SnifferResultComposite interface {
 	ProtocolForDomainResult() string
 }
@@ -0,0 +1,198 @@
+package dispatcher
+
+import (
+	"context"
+	"crypto/tls"
+	gonet "net"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	protocoltls "github.com/xtls/xray-core/common/protocol/tls"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func sniffDelayed(request session.SniffingRequest, delay time.Duration) (SniffResult, error) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.Background(), core.XrayKey(1), instance)
+
+	reader, writer := pipe.New()
+	go func() {
+		time.Sleep(delay)
+		writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")))
+	}()
+	defer writer.Close()
+	return sniffer(ctx, &cachedReader{reader: reader}, request, net.Network_TCP, tlsFingerprints{})
+}
+
+func TestSnifferTimeout(t *testing.T) {
+	if _, err := sniffDelayed(session.SniffingRequest{}, 500*time.Millisecond); err == nil {
+		t.Error("expected the default timeout to give up on a late payload")
+	}
+
+	result, err := sniffDelayed(session.SniffingRequest{Timeout: 2 * time.Second}, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Protocol() != "http1" || result.Domain() != "example.com" {
+		t.Error("unexpected result: ", result.Protocol(), " ", result.Domain())
+	}
+}
+
+func TestSnifferBufferSize(t *testing.T) {
+	result, err := sniffDelayed(session.SniffingRequest{BufferSize: 64}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Domain() != "example.com" {
+		t.Error("unexpected domain: ", result.Domain())
+	}
+}
+
+func TestShouldSniff(t *testing.T) {
+	request := session.SniffingRequest{
+		Enabled: true,
+		Ports:   net.MemoryPortList{{From: 80, To: 80}, {From: 443, To: 443}, {From: 8443, To: 8443}},
+	}
+	if !shouldSniff(request, net.TCPDestination(net.LocalHostIP, 443)) {
+		t.Error("expected to sniff port 443")
+	}
+	if shouldSniff(request, net.UDPDestination(net.LocalHostIP, 5004)) {
+		t.Error("expected to skip port 5004")
+	}
+	if !shouldSniff(session.SniffingRequest{Enabled: true}, net.UDPDestination(net.LocalHostIP, 5004)) {
+		t.Error("expected to sniff all ports without a list")
+	}
+	request.Enabled = false
+	if shouldSniff(request, net.TCPDestination(net.LocalHostIP, 443)) {
+		t.Error("expected no sniffing when disabled")
+	}
+}
+
+func TestSnifferTLSFingerprints(t *testing.T) {
+	client, server := gonet.Pipe()
+	go tls.Client(client, &tls.Config{ServerName: "example.com"}).Handshake()
+	defer client.Close()
+	hello := make([]byte, 4096)
+	n, err := server.Read(hello)
+	common.Must(err)
+	ja4, err := protocoltls.JA4(hello[:n])
+	common.Must(err)
+
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	content := new(session.Content)
+	ctx := session.ContextWithContent(context.WithValue(context.Background(), core.XrayKey(1), instance), content)
+
+	reader, writer := pipe.New()
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, hello[:n])))
+	defer writer.Close()
+	result, err := sniffer(ctx, &cachedReader{reader: reader}, session.SniffingRequest{}, net.Network_TCP, tlsFingerprints{ja4: true})
+	common.Must(err)
+	if result.Protocol() != "tls" || result.Domain() != "example.com" {
+		t.Error("unexpected result: ", result.Protocol(), " ", result.Domain())
+	}
+	if v := content.Attribute(ja4Attribute); v != ja4 || v[:4] != "t13d" {
+		t.Error("unexpected ja4: ", v, ", wanted ", ja4)
+	}
+	if v := content.Attribute(ja3Attribute); v != "" {
+		t.Error("unexpected ja3 not asked for: ", v)
+	}
+}
+
+func TestSniffOnce(t *testing.T) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.Background(), core.XrayKey(1), instance)
+	d := new(DefaultDispatcher)
+	content := new(session.Content)
+
+	reader, writer := pipe.New()
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))))
+	result, err := d.sniff(ctx, content, &cachedReader{reader: reader}, session.SniffingRequest{}, net.Network_TCP)
+	common.Must(err)
+	if result.Protocol() != "http1" || content.Protocol != "http1" || content.Domain != "example.com" || !content.Sniffed {
+		t.Fatal("unexpected content ", content)
+	}
+
+	// the next pass, like by loopback, reuses the results without reading the connection
+	reader, writer = pipe.New()
+	common.Must(writer.Close())
+	cReader := &cachedReader{reader: reader}
+	result, err = d.sniff(ctx, content, cReader, session.SniffingRequest{}, net.Network_TCP)
+	common.Must(err)
+	if result.Protocol() != "http1" || result.Domain() != "example.com" || !cReader.cache.IsEmpty() {
+		t.Error("unexpected result: ", result.Protocol(), " ", result.Domain())
+	}
+
+	if _, err := d.sniff(ctx, content, cReader, session.SniffingRequest{Resniff: true}, net.Network_TCP); err == nil {
+		t.Error("expected sniffing again to fail on an empty connection")
+	}
+}
+
+func TestSnifferBufferTruncated(t *testing.T) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.Background(), core.XrayKey(1), instance)
+
+	request := []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	reader, writer := pipe.New()
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, request)))
+	defer writer.Close()
+	cReader := &cachedReader{reader: reader}
+
+	// The buffer cuts the request off before the Host header, so there is no domain to sniff.
+	result, err := sniffer(ctx, cReader, session.SniffingRequest{BufferSize: 16, Timeout: 100 * time.Millisecond}, net.Network_TCP, tlsFingerprints{})
+	if err == nil {
+		t.Error("unexpected result: ", result.Protocol(), " ", result.Domain())
+	}
+
+	// The request still goes on in full.
+	mb, err := cReader.ReadMultiBuffer()
+	common.Must(err)
+	if s := mb.String(); s != string(request) {
+		t.Error("unexpected payload: ", s)
+	}
+}
+
+// BenchmarkSniffFirstByte times the first byte of a flow that no sniffer recognizes, like a media stream,
+// as the dispatcher hands it to the outbound with sniffing enabled, and with its port not in destOverridePorts.
+func BenchmarkSniffFirstByte(b *testing.B) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := context.WithValue(context.Background(), core.XrayKey(1), instance)
+	d := new(DefaultDispatcher)
+	destination := net.UDPDestination(net.LocalHostIP, 5004)
+	payload := make([]byte, 1200)
+
+	for _, c := range []struct {
+		name  string
+		ports net.MemoryPortList
+	}{
+		{"Sniffed", nil},
+		{"Skipped", net.MemoryPortList{{From: 443, To: 443}}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			request := session.SniffingRequest{Enabled: true, Ports: c.ports}
+			for i := 0; i < b.N; i++ {
+				reader, writer := pipe.New()
+				common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, payload)))
+				var outbound buf.Reader = reader
+				if shouldSniff(request, destination) {
+					cReader := &cachedReader{reader: reader}
+					d.sniff(ctx, new(session.Content), cReader, request, destination.Network)
+					outbound = cReader
+				}
+				mb, err := outbound.ReadMultiBuffer()
+				common.Must(err)
+				buf.ReleaseMulti(mb)
+				writer.Close()
+			}
+		})
+	}
+}
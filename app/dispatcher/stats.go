@@ -1,6 +1,10 @@
 package dispatcher
 
 import (
+	"container/list"
+	"sync"
+	"time"
+
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/features/stats"
@@ -23,3 +27,84 @@ func (w *SizeStatWriter) Close() error {
 func (w *SizeStatWriter) Interrupt() {
 	common.Interrupt(w.Writer)
 }
+
+type SizeStatReader struct {
+	Counter stats.Counter
+	Reader  buf.Reader
+}
+
+func (r *SizeStatReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	r.Counter.Add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *SizeStatReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.Reader.(buf.TimeoutReader)
+	if !ok {
+		return r.ReadMultiBuffer()
+	}
+	mb, err := tr.ReadMultiBufferTimeout(timeout)
+	r.Counter.Add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *SizeStatReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+func ruleCounterName(ruleTag string, direction string) string {
+	return "rule>>>" + ruleTag + ">>>traffic>>>" + direction
+}
+
+const defaultHostTrafficLimit = 1024
+
+// hostStats keeps traffic counters for a bounded number of destination hosts,
+// dropping the counters of the least recently used host when the limit is exceeded.
+type hostStats struct {
+	sync.Mutex
+	manager stats.Manager
+	limit   int
+	hosts   *list.List
+	index   map[string]*list.Element
+}
+
+func newHostStats(manager stats.Manager, limit uint32) *hostStats {
+	if limit == 0 {
+		limit = defaultHostTrafficLimit
+	}
+	return &hostStats{
+		manager: manager,
+		limit:   int(limit),
+		hosts:   list.New(),
+		index:   make(map[string]*list.Element),
+	}
+}
+
+func hostCounterName(host string, direction string) string {
+	return "host>>>" + host + ">>>traffic>>>" + direction
+}
+
+// Counters returns the uplink and downlink counters of the host.
+func (h *hostStats) Counters(host string) (stats.Counter, stats.Counter) {
+	h.Lock()
+	defer h.Unlock()
+
+	if e, found := h.index[host]; found {
+		h.hosts.MoveToFront(e)
+	} else {
+		h.index[host] = h.hosts.PushFront(host)
+		for h.hosts.Len() > h.limit {
+			e := h.hosts.Back()
+			h.hosts.Remove(e)
+			evicted := e.Value.(string)
+			delete(h.index, evicted)
+			h.manager.UnregisterCounter(hostCounterName(evicted, "uplink"))
+			h.manager.UnregisterCounter(hostCounterName(evicted, "downlink"))
+		}
+	}
+
+	uplink, _ := stats.GetOrRegisterCounter(h.manager, hostCounterName(host, "uplink"))
+	downlink, _ := stats.GetOrRegisterCounter(h.manager, hostCounterName(host, "downlink"))
+	return uplink, downlink
+}
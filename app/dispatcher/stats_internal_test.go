@@ -0,0 +1,28 @@
+package dispatcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+)
+
+func TestHostStatsEviction(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	h := newHostStats(m, 2)
+	up, _ := h.Counters("example.com")
+	up.Add(10)
+	h.Counters("1.2.3.4")
+	h.Counters("example.com")
+	h.Counters("example.org")
+
+	if c := m.GetCounter("host>>>example.com>>>traffic>>>uplink"); c == nil || c.Value() != 10 {
+		t.Error("expected counter of recently used host to be kept")
+	}
+	if c := m.GetCounter("host>>>1.2.3.4>>>traffic>>>downlink"); c != nil {
+		t.Error("expected counter of least recently used host to be dropped")
+	}
+}
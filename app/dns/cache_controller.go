@@ -29,6 +29,8 @@ type CacheController struct {
 	disableCache    bool
 	serveStale      bool
 	serveExpiredTTL int32
+	metrics         *serverMetrics
+	dnsLog          *dnsLogSampler
 
 	ips      map[string]*record
 	dirtyips map[string]*record
@@ -57,6 +59,11 @@ func NewCacheController(name string, disableCache bool, serveStale bool, serveEx
 	return c
 }
 
+// Close stops clearing the cache.
+func (c *CacheController) Close() error {
+	return c.cacheCleanup.Close()
+}
+
 // CacheCleanup clears expired items from cache
 func (c *CacheController) CacheCleanup() error {
 	expiredKeys, err := c.collectExpiredKeys()
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -33,6 +34,10 @@ type DNS struct {
 	domainMatcher          strmatcher.IndexMatcher
 	matcherInfos           []*DomainMatcherInfo
 	checkSystem            bool
+	reloaded               atomic.Pointer[DNS]
+	config                 *Config
+	// the name servers that send their queries through outbounds, by the outbound tags
+	vias map[string]*DNS
 }
 
 // DomainMatcherInfo contains information attached to index returned by Server.domainMatcher
@@ -43,6 +48,12 @@ type DomainMatcherInfo struct {
 
 // New creates a new DNS server with given configuration.
 func New(ctx context.Context, config *Config) (*DNS, error) {
+	return newDNS(ctx, config, "")
+}
+
+// newDNS creates a DNS server whose name servers send their queries through the outbound of the via tag,
+// or as routed if there is no tag. With a tag, the name servers that do not dispatch their queries are left out.
+func newDNS(ctx context.Context, config *Config, via string) (*DNS, error) {
 	var clientIP net.IP
 	switch len(config.ClientIp) {
 	case 0, net.IPv4len, net.IPv6len:
@@ -105,6 +116,9 @@ func New(ctx context.Context, config *Config) (*DNS, error) {
 	domainMatcher := &strmatcher.MatcherGroup{}
 
 	for _, ns := range config.NameServer {
+		if len(via) > 0 && !dispatchesQueries(ns.Address.AsDestination()) {
+			continue
+		}
 		clientIdx := len(clients)
 		updateDomain := func(domainRule strmatcher.Matcher, originalRuleIdx int, matcherInfos []*DomainMatcherInfo) error {
 			midx := domainMatcher.Add(domainRule)
@@ -137,18 +151,27 @@ func New(ctx context.Context, config *Config) (*DNS, error) {
 			return nil, errors.New("no QueryStrategy available for ", ns.Address)
 		}
 
-		client, err := NewClient(ctx, ns, myClientIP, disableCache, serveStale, serveExpiredTTL, tag, clientIPOption, &matcherInfos, updateDomain)
+		client, err := NewClient(ctx, ns, myClientIP, disableCache, serveStale, serveExpiredTTL, tag, via, clientIPOption, &matcherInfos, updateDomain)
 		if err != nil {
 			return nil, errors.New("failed to create client").Base(err)
 		}
 		clients = append(clients, client)
 	}
 
+	if len(via) > 0 && len(clients) == 0 {
+		return nil, errors.New("no name server sends its queries through the dispatcher")
+	}
+
 	// If there is no DNS client in config, add a `localhost` DNS client
 	if len(clients) == 0 {
 		clients = append(clients, NewLocalDNSClient(ipOption))
 	}
 
+	dnsLog := &dnsLogSampler{every: config.LogEvery}
+	for _, client := range clients {
+		client.setDNSLogSampler(dnsLog)
+	}
+
 	return &DNS{
 		hosts:                  hosts,
 		ipOption:               &ipOption,
@@ -160,6 +183,7 @@ func New(ctx context.Context, config *Config) (*DNS, error) {
 		disableFallbackIfMatch: config.DisableFallbackIfMatch,
 		enableParallelQuery:    config.EnableParallelQuery,
 		checkSystem:            checkSystem,
+		config:                 config,
 	}, nil
 }
 
@@ -175,11 +199,71 @@ func (s *DNS) Start() error {
 
 // Close implements common.Closable.
 func (s *DNS) Close() error {
-	return nil
+	var errs []error
+	if d := s.reloaded.Load(); d != nil {
+		errs = append(errs, d.Close())
+	}
+	errs = append(errs, s.closeServers())
+	return errors.Combine(errs...)
+}
+
+// closeServers closes the name servers of the settings in place before the first reload, including the ones through outbounds.
+func (s *DNS) closeServers() error {
+	s.Lock()
+	vias := s.vias
+	s.vias = nil
+	s.Unlock()
+
+	var errs []error
+	for _, d := range vias {
+		errs = append(errs, d.Close())
+	}
+	for _, client := range s.clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Combine(errs...)
+}
+
+// Reload replaces the name servers, hosts and options with the ones in the given config.
+// The name servers of the previous settings are closed, so lookups still in progress with them may fail.
+func (s *DNS) Reload(config *Config) error {
+	d, err := New(s.ctx, config)
+	if err != nil {
+		return err
+	}
+	if old := s.reloaded.Swap(d); old != nil {
+		return old.Close()
+	}
+	return s.closeServers()
+}
+
+// Via implements dns.ViaClient.
+func (s *DNS) Via(tag string) (dns.Client, error) {
+	if d := s.reloaded.Load(); d != nil {
+		return d.Via(tag)
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	if d, found := s.vias[tag]; found {
+		return d, nil
+	}
+	d, err := newDNS(s.ctx, s.config, tag)
+	if err != nil {
+		return nil, errors.New("failed to resolve through outbound ", tag).Base(err)
+	}
+	if s.vias == nil {
+		s.vias = make(map[string]*DNS)
+	}
+	s.vias[tag] = d
+	return d, nil
 }
 
 // IsOwnLink implements proxy.dns.ownLinkVerifier
 func (s *DNS) IsOwnLink(ctx context.Context) bool {
+	if d := s.reloaded.Load(); d != nil {
+		return d.IsOwnLink(ctx)
+	}
 	inbound := session.InboundFromContext(ctx)
 	if inbound == nil {
 		return false
@@ -194,6 +278,10 @@ func (s *DNS) IsOwnLink(ctx context.Context) bool {
 
 // LookupIP implements dns.Client.
 func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, uint32, error) {
+	if d := s.reloaded.Load(); d != nil {
+		return d.LookupIP(domain, option)
+	}
+
 	// Normalize the FQDN form query
 	domain = strings.TrimSuffix(domain, ".")
 	if domain == "" {
@@ -204,7 +292,7 @@ func (s *DNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, uint32, er
 		supportIPv4, supportIPv6 := checkRoutes()
 		option.IPv4Enable = option.IPv4Enable && supportIPv4
 		option.IPv6Enable = option.IPv6Enable && supportIPv6
-	} else {
+	} else if !option.IgnoreQueryStrategy {
 		option.IPv4Enable = option.IPv4Enable && s.ipOption.IPv4Enable
 		option.IPv6Enable = option.IPv6Enable && s.ipOption.IPv6Enable
 	}
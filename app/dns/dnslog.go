@@ -0,0 +1,50 @@
+package dns
+
+import (
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/features/dns"
+)
+
+// dnsLogSampler keeps only one in every few records of the name servers of a DNS instance.
+// A nil sampler keeps all of them.
+type dnsLogSampler struct {
+	every uint32
+	count atomic.Uint64
+}
+
+// record writes the record into the DNS log, unless it is sampled out.
+func (s *dnsLogSampler) record(l *log.DNSLog) {
+	if s != nil && s.every > 1 && s.count.Add(1)%uint64(s.every) != 0 {
+		return
+	}
+	l.RCode = dns.RCodeFromError(l.Error)
+	log.Record(l)
+}
+
+// setDNSLogSampler makes the name server of the client sample its records with s.
+func (c *Client) setDNSLogSampler(s *dnsLogSampler) {
+	switch server := c.server.(type) {
+	case CachedNameserver:
+		server.getCacheController().dnsLog = s
+	case *LocalNameServer:
+		server.dnsLog = s
+	case *FakeDNSServer:
+		server.dnsLog = s
+	}
+}
+
+// queryType returns the types of the records queried with the option.
+func queryType(option dns.IPOption) string {
+	switch {
+	case option.IPv4Enable && option.IPv6Enable:
+		return "A+AAAA"
+	case option.IPv4Enable:
+		return "A"
+	case option.IPv6Enable:
+		return "AAAA"
+	default:
+		return ""
+	}
+}
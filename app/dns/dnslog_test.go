@@ -0,0 +1,24 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+)
+
+func TestDNSLogSamplerPerInstance(t *testing.T) {
+	sampled, err := New(context.Background(), &Config{LogEvery: 4})
+	common.Must(err)
+	defer sampled.Close()
+	full, err := New(context.Background(), &Config{})
+	common.Must(err)
+	defer full.Close()
+
+	if every := sampled.clients[0].server.(*LocalNameServer).dnsLog.every; every != 4 {
+		t.Error("expected one in every 4 records, but got ", every)
+	}
+	if every := full.clients[0].server.(*LocalNameServer).dnsLog.every; every != 0 {
+		t.Error("expected all the records, but got one in every ", every)
+	}
+}
@@ -0,0 +1,63 @@
+package dns
+
+import (
+	go_errors "errors"
+	"time"
+
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/stats"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serverMetrics are the stats of a name server. The counters and histograms are atomic,
+// so recording them adds no locks to the queries.
+type serverMetrics struct {
+	queries   stats.Counter
+	errors    stats.Counter
+	latency   stats.Histogram
+	cacheHit  stats.Counter
+	cacheMiss stats.Counter
+}
+
+// newServerMetrics registers the stats of the name server, or returns nil if stats are not enabled.
+func newServerMetrics(m stats.Manager, name string) *serverMetrics {
+	prefix := "dns>>>" + name + ">>>"
+	queries, _ := stats.GetOrRegisterCounter(m, prefix+"queries")
+	errs, _ := stats.GetOrRegisterCounter(m, prefix+"errors")
+	latency, _ := stats.GetOrRegisterHistogram(m, prefix+"latency>>>histogram")
+	hit, _ := stats.GetOrRegisterCounter(m, "dns>>>cache>>>hit")
+	miss, _ := stats.GetOrRegisterCounter(m, "dns>>>cache>>>miss")
+	if queries == nil || errs == nil || latency == nil || hit == nil || miss == nil {
+		return nil
+	}
+	return &serverMetrics{
+		queries:   queries,
+		errors:    errs,
+		latency:   latency,
+		cacheHit:  hit,
+		cacheMiss: miss,
+	}
+}
+
+// observe records a query sent to the name server. An answer without records is not an error of the server.
+func (m *serverMetrics) observe(elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.queries.Add(1)
+	m.latency.Observe(elapsed.Milliseconds())
+	if err != nil && !go_errors.Is(err, dns.ErrEmptyResponse) && !go_errors.Is(err, dns.RCodeError(dnsmessage.RCodeNameError)) {
+		m.errors.Add(1)
+	}
+}
+
+func (m *serverMetrics) cache(hit bool) {
+	if m == nil {
+		return
+	}
+	if hit {
+		m.cacheHit.Add(1)
+	} else {
+		m.cacheMiss.Add(1)
+	}
+}
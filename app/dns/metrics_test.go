@@ -0,0 +1,50 @@
+package dns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	dns_feature "github.com/xtls/xray-core/features/dns"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestServerMetrics(t *testing.T) {
+	if newServerMetrics(feature_stats.NoopManager{}, "localhost") != nil {
+		t.Error("expected no metrics without stats")
+	}
+
+	m := common.Must2(stats.NewManager(context.Background(), &stats.Config{}))
+	metrics := newServerMetrics(m, "UDP:1.1.1.1:53")
+	metrics.observe(20*time.Millisecond, nil)
+	metrics.observe(30*time.Millisecond, dns_feature.ErrEmptyResponse)
+	metrics.observe(40*time.Millisecond, dns_feature.RCodeError(dnsmessage.RCodeNameError))
+	metrics.observe(50*time.Millisecond, dns_feature.RCodeError(dnsmessage.RCodeServerFailure))
+	metrics.observe(4*time.Second, errors.New("timeout"))
+	metrics.cache(true)
+	metrics.cache(false)
+	metrics.cache(true)
+
+	for name, value := range map[string]int64{
+		"dns>>>UDP:1.1.1.1:53>>>queries": 5,
+		"dns>>>UDP:1.1.1.1:53>>>errors":  2,
+		"dns>>>cache>>>hit":              2,
+		"dns>>>cache>>>miss":             1,
+	} {
+		if v := m.GetCounter(name).Value(); v != value {
+			t.Error(name, ": expected ", value, ", got ", v)
+		}
+	}
+	if _, sum := m.GetHistogram("dns>>>UDP:1.1.1.1:53>>>latency>>>histogram").Counts(); sum != 4140 {
+		t.Error("unexpected sum of latency: ", sum)
+	}
+
+	// the servers share the cache counters
+	if newServerMetrics(m, "localhost").cacheHit.Value() != 2 {
+		t.Error("expected the cache counters to be shared")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
@@ -14,6 +15,8 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
 )
 
 // Server is the interface for Name Server.
@@ -40,6 +43,7 @@ type Client struct {
 	timeoutMs     time.Duration
 	finalQuery    bool
 	ipOption      *dns.IPOption
+	queryStrategy QueryStrategy
 	checkSystem   bool
 	policyID      uint32
 }
@@ -88,6 +92,40 @@ func NewServer(ctx context.Context, dest net.Destination, dispatcher routing.Dis
 	return nil, errors.New("No available name server could be created from ", dest).AtWarning()
 }
 
+// dispatchesQueries returns whether the name server of dest sends its queries through the dispatcher.
+func dispatchesQueries(dest net.Destination) bool {
+	if address := dest.Address; address.Family().IsDomain() {
+		u, err := url.Parse(address.Domain())
+		if err != nil {
+			return false
+		}
+		return !strings.EqualFold(u.String(), "localhost") && !strings.EqualFold(u.String(), "fakedns") &&
+			!strings.HasSuffix(strings.ToLower(u.Scheme), "+local")
+	}
+	return true
+}
+
+// viaDispatcher dispatches the queries of name servers through the outbound of the tag, regardless of the routing.
+type viaDispatcher struct {
+	routing.Dispatcher
+	tag string
+}
+
+func (d *viaDispatcher) context(ctx context.Context) context.Context {
+	ctx = session.ContextWithResolveVia(ctx, d.tag)
+	return session.SetForcedOutboundTagToContext(ctx, d.tag)
+}
+
+// Dispatch implements routing.Dispatcher.
+func (d *viaDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	return d.Dispatcher.Dispatch(d.context(ctx), dest)
+}
+
+// DispatchLink implements routing.Dispatcher.
+func (d *viaDispatcher) DispatchLink(ctx context.Context, dest net.Destination, link *transport.Link) error {
+	return d.Dispatcher.DispatchLink(d.context(ctx), dest, link)
+}
+
 // NewClient creates a DNS client managing a name server with client IP, domain rules and expected IPs.
 func NewClient(
 	ctx context.Context,
@@ -95,6 +133,7 @@ func NewClient(
 	clientIP net.IP,
 	disableCache bool, serveStale bool, serveExpiredTTL uint32,
 	tag string,
+	via string,
 	ipOption dns.IPOption,
 	matcherInfos *[]*DomainMatcherInfo,
 	updateDomainRule func(strmatcher.Matcher, int, []*DomainMatcherInfo) error,
@@ -102,11 +141,41 @@ func NewClient(
 	client := &Client{}
 
 	err := core.RequireFeatures(ctx, func(dispatcher routing.Dispatcher) error {
+		if len(via) > 0 {
+			dispatcher = &viaDispatcher{Dispatcher: dispatcher, tag: via}
+		}
 		// Create a new server for each client for now
 		server, err := NewServer(ctx, ns.Address.AsDestination(), dispatcher, disableCache, serveStale, serveExpiredTTL, clientIP)
 		if err != nil {
 			return errors.New("failed to create nameserver").Base(err).AtWarning()
 		}
+		if ns.Local != nil {
+			if _, isLocalDNS := server.(*LocalNameServer); !isLocalDNS {
+				return errors.New("the local options only apply to the localhost DNS server").AtWarning()
+			}
+			if server, err = NewLocalNameServerWithConfig(ns.Local); err != nil {
+				return errors.New("failed to create localhost nameserver").Base(err).AtWarning()
+			}
+		}
+		if ns.UdpGso {
+			s, isQUIC := server.(*QUICNameServer)
+			if !isQUIC {
+				return errors.New("udpGSO only applies to DNS-over-QUIC servers").AtWarning()
+			}
+			s.udpGSO = true
+		}
+
+		if err := core.RequireFeatures(ctx, func(sm stats.Manager) {
+			metrics := newServerMetrics(sm, server.Name())
+			switch s := server.(type) {
+			case CachedNameserver:
+				s.getCacheController().metrics = metrics
+			case *LocalNameServer:
+				s.metrics = metrics
+			}
+		}); err != nil {
+			return err
+		}
 
 		// Prioritize local domains with specific TLDs or those without any dot for the local DNS
 		if _, isLocalDNS := server.(*LocalNameServer); isLocalDNS {
@@ -202,6 +271,7 @@ func NewClient(
 		client.timeoutMs = timeoutMs
 		client.finalQuery = ns.FinalQuery
 		client.ipOption = &ipOption
+		client.queryStrategy = ns.QueryStrategy
 		client.checkSystem = checkSystem
 		client.policyID = ns.PolicyID
 		return nil
@@ -209,6 +279,11 @@ func NewClient(
 	return client, err
 }
 
+// Close closes the server the client manages, if it holds resources like connections.
+func (c *Client) Close() error {
+	return common.Close(c.server)
+}
+
 // Name returns the server name the client manages.
 func (c *Client) Name() string {
 	return c.server.Name()
@@ -221,8 +296,14 @@ func (c *Client) QueryIP(ctx context.Context, domain string, option dns.IPOption
 		option.IPv4Enable = option.IPv4Enable && supportIPv4
 		option.IPv6Enable = option.IPv6Enable && supportIPv6
 	} else {
-		option.IPv4Enable = option.IPv4Enable && c.ipOption.IPv4Enable
-		option.IPv6Enable = option.IPv6Enable && c.ipOption.IPv6Enable
+		ipOption := c.ipOption
+		if option.IgnoreQueryStrategy {
+			// c.ipOption has the global query strategy merged in, so only the one of the name server applies
+			own := ResolveIpOptionOverride(c.queryStrategy, dns.IPOption{IPv4Enable: true, IPv6Enable: true})
+			ipOption = &own
+		}
+		option.IPv4Enable = option.IPv4Enable && ipOption.IPv4Enable
+		option.IPv6Enable = option.IPv6Enable && ipOption.IPv6Enable
 	}
 
 	if !option.IPv4Enable && !option.IPv6Enable {
@@ -29,17 +29,20 @@ func queryIP(ctx context.Context, s CachedNameserver, domain string, option dns.
 			if !go_errors.Is(err, errRecordNotFound) {
 				if ttl > 0 {
 					errors.LogDebugInner(ctx, err, cache.name, " cache HIT ", fqdn, " -> ", ips)
-					log.Record(&log.DNSLog{Server: cache.name, Domain: fqdn, Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err})
+					cache.dnsLog.record(&log.DNSLog{Server: cache.name, Domain: fqdn, QueryType: queryType(option), Result: ips, Status: log.DNSCacheHit, Elapsed: 0, Error: err})
+					cache.metrics.cache(true)
 					return ips, uint32(ttl), err
 				}
 				if cache.serveStale && (cache.serveExpiredTTL == 0 || cache.serveExpiredTTL < ttl) {
 					errors.LogDebugInner(ctx, err, cache.name, " cache OPTIMISTE ", fqdn, " -> ", ips)
-					log.Record(&log.DNSLog{Server: cache.name, Domain: fqdn, Result: ips, Status: log.DNSCacheOptimiste, Elapsed: 0, Error: err})
+					cache.dnsLog.record(&log.DNSLog{Server: cache.name, Domain: fqdn, QueryType: queryType(option), Result: ips, Status: log.DNSCacheOptimiste, Elapsed: 0, Error: err})
+					cache.metrics.cache(true)
 					go pull(ctx, s, fqdn, option)
 					return ips, 1, err
 				}
 			}
 		}
+		cache.metrics.cache(false)
 	} else {
 		errors.LogDebug(ctx, "DNS cache is disabled. Querying IP for ", fqdn, " at ", cache.name)
 	}
@@ -123,7 +126,14 @@ func doFetch(ctx context.Context, s CachedNameserver, fqdn string, option dns.IP
 		rTTL = 1
 	}
 
-	log.Record(&log.DNSLog{Server: s.getCacheController().name, Domain: fqdn, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err})
+	elapsed := time.Since(start)
+	s.getCacheController().dnsLog.record(&log.DNSLog{Server: s.getCacheController().name, Domain: fqdn, QueryType: queryType(option), Result: ips, Status: log.DNSQueried, Elapsed: elapsed, Error: err})
+	// merge reports a record that never came as not found, so the failures of the exchange come first
+	failure := err
+	if len(errs) > 0 {
+		failure = errs[0]
+	}
+	s.getCacheController().metrics.observe(elapsed, failure)
 	return result{ips, rTTL, err}
 }
 
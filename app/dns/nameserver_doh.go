@@ -116,6 +116,12 @@ func (s *DoHNameServer) Name() string {
 	return s.cacheController.name
 }
 
+// Close implements common.Closable.
+func (s *DoHNameServer) Close() error {
+	s.httpClient.CloseIdleConnections()
+	return s.cacheController.Close()
+}
+
 // IsDisableCache implements Server.
 func (s *DoHNameServer) IsDisableCache() bool {
 	return s.cacheController.disableCache
@@ -4,12 +4,14 @@ import (
 	"context"
 
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/features/dns"
 )
 
 type FakeDNSServer struct {
 	fakeDNSEngine dns.FakeDNSEngine
+	dnsLog        *dnsLogSampler
 }
 
 func NewFakeDNSServer(fd dns.FakeDNSEngine) *FakeDNSServer {
@@ -43,6 +45,7 @@ func (f *FakeDNSServer) QueryIP(ctx context.Context, domain string, opt dns.IPOp
 	}
 
 	errors.LogInfo(ctx, f.Name(), " got answer: ", domain, " -> ", ips)
+	f.dnsLog.record(&log.DNSLog{Server: f.Name(), Domain: domain, QueryType: queryType(opt), Result: netIP, Status: log.DNSFake})
 
 	if len(netIP) > 0 {
 		return netIP, 1, nil // fakeIP ttl is 1
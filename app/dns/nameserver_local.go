@@ -2,30 +2,43 @@ package dns
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/dns/localdns"
+	"github.com/xtls/xray-core/transport/internet"
 )
 
 // LocalNameServer is an wrapper over local DNS feature.
 type LocalNameServer struct {
 	client *localdns.Client
+	// multicast looks up the names under .local, if they are not looked up by client.
+	multicast *localdns.Client
+	metrics   *serverMetrics
+	dnsLog    *dnsLogSampler
 }
 
 // QueryIP implements Server.
 func (s *LocalNameServer) QueryIP(ctx context.Context, domain string, option dns.IPOption) (ips []net.IP, ttl uint32, err error) {
 
 	start := time.Now()
-	ips, ttl, err = s.client.LookupIP(domain, option)
+	client := s.client
+	if s.multicast != nil && isMulticastName(domain) {
+		client = s.multicast
+	}
+	ips, ttl, err = client.LookupIPContext(ctx, domain, option)
 
 	if len(ips) > 0 {
 		errors.LogInfo(ctx, "Localhost got answer: ", domain, " -> ", ips)
-		log.Record(&log.DNSLog{Server: s.Name(), Domain: domain, Result: ips, Status: log.DNSQueried, Elapsed: time.Since(start), Error: err})
 	}
+	elapsed := time.Since(start)
+	s.dnsLog.record(&log.DNSLog{Server: s.Name(), Domain: domain, QueryType: queryType(option), Result: ips, Status: log.DNSQueried, Elapsed: elapsed, Error: err})
+	s.metrics.observe(elapsed, err)
 
 	return
 }
@@ -48,6 +61,55 @@ func NewLocalNameServer() *LocalNameServer {
 	}
 }
 
+// NewLocalNameServerWithConfig creates a localhost server that sends the queries to the name servers of
+// the system from the source address or through the interface of the config, if any.
+func NewLocalNameServerWithConfig(config *LocalNameServerConfig) (*LocalNameServer, error) {
+	if len(config.SourceAddress) == 0 && len(config.Interface) == 0 {
+		return NewLocalNameServer(), nil
+	}
+	var src net.Address
+	if len(config.SourceAddress) > 0 {
+		src = net.ParseAddress(config.SourceAddress)
+		if !src.Family().IsIP() {
+			return nil, errors.New("source address of localhost DNS is not an IP: ", config.SourceAddress)
+		}
+	}
+	var sockopt *internet.SocketConfig
+	if len(config.Interface) > 0 {
+		sockopt = &internet.SocketConfig{Interface: config.Interface}
+	}
+	resolver := &net.Resolver{
+		// only the resolver of Go dials with Dial
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			// network is "udp" or "tcp", maybe with the IP version
+			dest, err := net.ParseDestination(strings.TrimRight(network, "46") + ":" + address)
+			if err != nil {
+				return nil, err
+			}
+			if src != nil {
+				ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Gateway: src}})
+			}
+			return internet.DialSystem(ctx, dest, sockopt)
+		},
+	}
+
+	errors.LogInfo(context.Background(), "DNS: created localhost client from ", config.SourceAddress, " through ", config.Interface)
+	s := &LocalNameServer{
+		client: localdns.NewWithResolver(resolver),
+	}
+	if config.AllowMulticastNames {
+		s.multicast = localdns.New()
+	}
+	return s, nil
+}
+
+// isMulticastName returns whether the domain is in the domain of multicast DNS.
+func isMulticastName(domain string) bool {
+	domain = strings.ToLower(domain)
+	return domain == "local" || strings.HasSuffix(domain, ".local")
+}
+
 // NewLocalDNSClient creates localdns client object for directly lookup in system DNS.
 func NewLocalDNSClient(ipOption dns.IPOption) *Client {
 	return &Client{server: NewLocalNameServer(), ipOption: &ipOption}
@@ -24,3 +24,18 @@ func TestLocalNameServer(t *testing.T) {
 		t.Error("expect some ips, but got 0")
 	}
 }
+
+func TestLocalNameServerWithConfig(t *testing.T) {
+	if _, err := NewLocalNameServerWithConfig(&LocalNameServerConfig{SourceAddress: "example.com"}); err == nil {
+		t.Error("expected an error for a source address not being an IP")
+	}
+
+	s, err := NewLocalNameServerWithConfig(&LocalNameServerConfig{SourceAddress: "127.0.0.1", AllowMulticastNames: true})
+	common.Must(err)
+	// the lookup gives up on the deadline of the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := s.QueryIP(ctx, "example.com", dns.IPOption{IPv4Enable: true}); err == nil {
+		t.Error("expected an error for a canceled query")
+	}
+}
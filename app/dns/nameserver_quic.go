@@ -3,6 +3,7 @@ package dns
 import (
 	"bytes"
 	"context"
+	gotls "crypto/tls"
 	"encoding/binary"
 	"net/url"
 	"sync"
@@ -17,6 +18,7 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	dns_feature "github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/udp"
 	"golang.org/x/net/http2"
 )
 
@@ -33,6 +35,7 @@ type QUICNameServer struct {
 	destination     *net.Destination
 	connection      *quic.Conn
 	clientIP        net.IP
+	udpGSO          bool
 }
 
 // NewQUICNameServer creates DNS-over-QUIC client object for local resolving
@@ -62,6 +65,17 @@ func (s *QUICNameServer) Name() string {
 	return s.cacheController.name
 }
 
+// Close implements common.Closable.
+func (s *QUICNameServer) Close() error {
+	s.Lock()
+	if s.connection != nil {
+		s.connection.CloseWithError(0, "")
+		s.connection = nil
+	}
+	s.Unlock()
+	return s.cacheController.Close()
+}
+
 // IsDisableCache implements Server.
 func (s *QUICNameServer) IsDisableCache() bool {
 	return s.cacheController.disableCache
@@ -251,7 +265,13 @@ func (s *QUICNameServer) openConnection() (*quic.Conn, error) {
 		HandshakeIdleTimeout: handshakeTimeout,
 	}
 	tlsConfig.ServerName = s.destination.Address.String()
-	conn, err := quic.DialAddr(context.Background(), s.destination.NetAddr(), tlsConfig.GetTLSConfig(tls.WithNextProto("http/1.1", http2.NextProtoTLS, NextProtoDQ)), quicConfig)
+	var conn *quic.Conn
+	var err error
+	if s.udpGSO {
+		conn, err = s.dialOffload(tlsConfig.GetTLSConfig(tls.WithNextProto("http/1.1", http2.NextProtoTLS, NextProtoDQ)), quicConfig)
+	} else {
+		conn, err = quic.DialAddr(context.Background(), s.destination.NetAddr(), tlsConfig.GetTLSConfig(tls.WithNextProto("http/1.1", http2.NextProtoTLS, NextProtoDQ)), quicConfig)
+	}
 	log.Record(&log.AccessMessage{
 		From:   "DNS",
 		To:     s.destination,
@@ -265,6 +285,29 @@ func (s *QUICNameServer) openConnection() (*quic.Conn, error) {
 	return conn, nil
 }
 
+// dialOffload dials through a UDP connection that splits the payloads coalesced by UDP_GRO.
+// quic-go segments its sends with UDP_SEGMENT on its own.
+func (s *QUICNameServer) dialOffload(tlsConfig *gotls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp", s.destination.NetAddr())
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := quic.Dial(context.Background(), udp.NewOffloadConn(udpConn), addr, tlsConfig, quicConfig)
+	if err != nil {
+		udpConn.Close()
+		return nil, err
+	}
+	go func() {
+		<-conn.Context().Done()
+		udpConn.Close()
+	}()
+	return conn, nil
+}
+
 func (s *QUICNameServer) openStream(ctx context.Context) (*quic.Stream, error) {
 	conn, err := s.getConnection()
 	if err != nil {
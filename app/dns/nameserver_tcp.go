@@ -95,6 +95,11 @@ func (s *TCPNameServer) Name() string {
 	return s.cacheController.name
 }
 
+// Close implements common.Closable.
+func (s *TCPNameServer) Close() error {
+	return s.cacheController.Close()
+}
+
 // IsDisableCache implements Server.
 func (s *TCPNameServer) IsDisableCache() bool {
 	return s.cacheController.disableCache
@@ -64,6 +64,12 @@ func (s *ClassicNameServer) Name() string {
 	return s.cacheController.name
 }
 
+// Close implements common.Closable.
+func (s *ClassicNameServer) Close() error {
+	s.udpServer.RemoveRay()
+	return errors.Combine(s.requestsCleanup.Close(), s.cacheController.Close())
+}
+
 // IsDisableCache implements Server.
 func (s *ClassicNameServer) IsDisableCache() bool {
 	return s.cacheController.disableCache
@@ -0,0 +1,58 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/features/dns"
+)
+
+type closingServer struct {
+	closed bool
+}
+
+func (*closingServer) Name() string {
+	return "closing"
+}
+
+func (*closingServer) IsDisableCache() bool {
+	return true
+}
+
+func (*closingServer) QueryIP(ctx context.Context, domain string, option dns.IPOption) ([]net.IP, uint32, error) {
+	return nil, 0, dns.ErrEmptyResponse
+}
+
+func (s *closingServer) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestReloadClosesPrevious(t *testing.T) {
+	config := &Config{}
+	d, err := New(context.Background(), config)
+	common.Must(err)
+	first := &closingServer{}
+	d.clients = []*Client{{server: first}}
+
+	common.Must(d.Reload(config))
+	if !first.closed {
+		t.Error("expected the name servers in place before the reload to be closed")
+	}
+
+	second := &closingServer{}
+	d.reloaded.Load().clients = []*Client{{server: second}}
+	common.Must(d.Reload(config))
+	if !second.closed {
+		t.Error("expected the name servers of the previous reload to be closed")
+	}
+
+	third := &closingServer{}
+	d.reloaded.Load().clients = []*Client{{server: third}}
+	common.Must(d.Close())
+	if !third.closed {
+		t.Error("expected the name servers of the last reload to be closed with the DNS")
+	}
+}
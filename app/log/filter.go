@@ -0,0 +1,217 @@
+package log
+
+import (
+	"math/rand"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+)
+
+type accessFilter struct {
+	// cond is nil if the filter has no condition on the connection
+	cond   router.Condition
+	status []log.AccessStatus
+	// keep is the fraction of the matching records that are written
+	keep float32
+}
+
+func newAccessFilter(config *AccessLogFilter) (*accessFilter, error) {
+	f := &accessFilter{keep: 1}
+	if config.Drop || config.Sample > 0 {
+		f.keep = config.Sample
+	}
+	if f.keep < 0 || f.keep > 1 {
+		return nil, errors.New("sample of access log filter must be between 0 and 1: ", config.Sample)
+	}
+	for _, s := range config.Status {
+		status := log.AccessStatus(s)
+		if status != log.AccessAccepted && status != log.AccessRejected {
+			return nil, errors.New("unknown access status: ", s)
+		}
+		f.status = append(f.status, status)
+	}
+
+	conds := router.NewConditionChan()
+	if len(config.Domain) > 0 {
+		matcher, err := router.NewMphMatcherGroup(config.Domain)
+		if err != nil {
+			return nil, errors.New("failed to build the domains of access log filter").Base(err)
+		}
+		conds.Add(matcher)
+	}
+	if len(config.Geoip) > 0 {
+		matcher, err := router.NewIPMatcher(config.Geoip, router.MatcherAsType_Target)
+		if err != nil {
+			return nil, errors.New("failed to build the IPs of access log filter").Base(err)
+		}
+		conds.Add(matcher)
+	}
+	if config.PortList != nil {
+		conds.Add(router.NewPortMatcher(config.PortList, router.MatcherAsType_Target))
+	}
+	if len(config.InboundTag) > 0 {
+		matcher, err := router.NewInboundTagMatcher(config.InboundTag)
+		if err != nil {
+			return nil, errors.New("failed to build the inbound tags of access log filter").Base(err)
+		}
+		conds.Add(matcher)
+	}
+	if len(config.UserEmail) > 0 {
+		conds.Add(router.NewUserMatcher(config.UserEmail))
+	}
+	if conds.Len() > 0 {
+		f.cond = conds
+	}
+	return f, nil
+}
+
+func (f *accessFilter) match(msg *log.AccessMessage, ctx *accessContext) bool {
+	if len(f.status) > 0 {
+		found := false
+		for _, s := range f.status {
+			if s == msg.Status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return f.cond == nil || f.cond.Apply(ctx)
+}
+
+// accessFilters decide whether the access log records are written, by the first of them that matches.
+type accessFilters []*accessFilter
+
+func newAccessFilters(configs []*AccessLogFilter) (accessFilters, error) {
+	var filters accessFilters
+	for _, config := range configs {
+		f, err := newAccessFilter(config)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// Allows returns whether the record is written.
+func (fs accessFilters) Allows(msg *log.AccessMessage) bool {
+	if len(fs) == 0 {
+		return true
+	}
+	ctx := newAccessContext(msg)
+	for _, f := range fs {
+		if f.match(msg, ctx) {
+			return f.keep >= 1 || (f.keep > 0 && rand.Float32() < f.keep)
+		}
+	}
+	return true
+}
+
+// accessContext is the routing.Context of an access log record, for the conditions of the router.
+type accessContext struct {
+	msg    *log.AccessMessage
+	source net.Destination
+	target net.Destination
+}
+
+func newAccessContext(msg *log.AccessMessage) *accessContext {
+	return &accessContext{
+		msg:    msg,
+		source: destinationOf(msg.From),
+		target: destinationOf(msg.To),
+	}
+}
+
+// destinationOf returns the destination logged as from or to, which is invalid if it is not an address, like "DNS".
+func destinationOf(v interface{}) net.Destination {
+	switch v := v.(type) {
+	case net.Destination:
+		return v
+	case *net.Destination:
+		if v != nil {
+			return *v
+		}
+	case *net.TCPAddr, *net.UDPAddr:
+		return net.DestinationFromAddr(v.(net.Addr))
+	case string:
+		if d, err := net.ParseDestination(v); err == nil {
+			return d
+		}
+	}
+	return net.Destination{}
+}
+
+func ipsOf(dest net.Destination) []net.IP {
+	if dest.Address != nil && dest.Address.Family().IsIP() {
+		return []net.IP{dest.Address.IP()}
+	}
+	return nil
+}
+
+func (c *accessContext) GetInboundTag() string {
+	return c.msg.InboundTag
+}
+
+func (c *accessContext) GetSourceIPs() []net.IP {
+	return ipsOf(c.source)
+}
+
+func (c *accessContext) GetSourcePort() net.Port {
+	return c.source.Port
+}
+
+func (c *accessContext) GetTargetIPs() []net.IP {
+	return ipsOf(c.target)
+}
+
+func (c *accessContext) GetTargetPort() net.Port {
+	return c.target.Port
+}
+
+func (c *accessContext) GetLocalIPs() []net.IP {
+	return nil
+}
+
+func (c *accessContext) GetLocalPort() net.Port {
+	return 0
+}
+
+func (c *accessContext) GetTargetDomain() string {
+	if c.target.Address != nil && c.target.Address.Family().IsDomain() {
+		return c.target.Address.Domain()
+	}
+	return ""
+}
+
+func (c *accessContext) GetNetwork() net.Network {
+	return c.target.Network
+}
+
+func (c *accessContext) GetProtocol() string {
+	return ""
+}
+
+func (c *accessContext) GetUser() string {
+	return c.msg.Email
+}
+
+func (c *accessContext) GetVlessRoute() net.Port {
+	return 0
+}
+
+func (c *accessContext) GetAttributes() map[string]string {
+	return nil
+}
+
+func (c *accessContext) GetSkipDNSResolve() bool {
+	return true
+}
+
+func (c *accessContext) GetSessionID() uint32 {
+	return c.msg.SessionID
+}
@@ -18,16 +18,24 @@ type Instance struct {
 	config       *Config
 	accessLogger log.Handler
 	errorLogger  log.Handler
-	active       bool
-	dns          bool
+	// authFailureLogger is nil unless configured
+	authFailureLogger log.Handler
+	accessFilters     accessFilters
+	active            bool
+	dns               bool
 }
 
 // New creates a new log.Instance based on the given config.
 func New(ctx context.Context, config *Config) (*Instance, error) {
+	filters, err := newAccessFilters(config.AccessLogFilter)
+	if err != nil {
+		return nil, err
+	}
 	g := &Instance{
-		config: config,
-		active: false,
-		dns:    config.EnableDnsLog,
+		config:        config,
+		accessFilters: filters,
+		active:        false,
+		dns:           config.EnableDnsLog,
 	}
 	log.RegisterHandler(g)
 
@@ -43,7 +51,12 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 
 func (g *Instance) initAccessLogger() error {
 	handler, err := createHandler(g.config.AccessLogType, HandlerCreatorOptions{
-		Path: g.config.AccessLogPath,
+		Path:     g.config.AccessLogPath,
+		Format:   g.config.Format,
+		Network:  g.config.SyslogNetwork,
+		Address:  g.config.SyslogAddress,
+		Tag:      g.config.SyslogTag,
+		Rotation: g.config.Rotation,
 	})
 	if err != nil {
 		return err
@@ -52,9 +65,33 @@ func (g *Instance) initAccessLogger() error {
 	return nil
 }
 
+func (g *Instance) initAuthFailureLogger() error {
+	if g.config.AuthFailureLogType == LogType_None {
+		return nil
+	}
+	handler, err := createHandler(g.config.AuthFailureLogType, HandlerCreatorOptions{
+		Path:     g.config.AuthFailureLogPath,
+		Format:   g.config.Format,
+		Network:  g.config.SyslogNetwork,
+		Address:  g.config.SyslogAddress,
+		Tag:      g.config.SyslogTag,
+		Rotation: g.config.Rotation,
+	})
+	if err != nil {
+		return err
+	}
+	g.authFailureLogger = handler
+	return nil
+}
+
 func (g *Instance) initErrorLogger() error {
 	handler, err := createHandler(g.config.ErrorLogType, HandlerCreatorOptions{
-		Path: g.config.ErrorLogPath,
+		Path:     g.config.ErrorLogPath,
+		Format:   g.config.Format,
+		Network:  g.config.SyslogNetwork,
+		Address:  g.config.SyslogAddress,
+		Tag:      g.config.SyslogTag,
+		Rotation: g.config.Rotation,
 	})
 	if err != nil {
 		return err
@@ -84,6 +121,9 @@ func (g *Instance) startInternal() error {
 	if err := g.initErrorLogger(); err != nil {
 		return errors.New("failed to initialize error logger").Base(err).AtWarning()
 	}
+	if err := g.initAuthFailureLogger(); err != nil {
+		return errors.New("failed to initialize auth failure logger").Base(err).AtWarning()
+	}
 
 	return nil
 }
@@ -111,13 +151,17 @@ func (g *Instance) Handle(msg log.Message) {
 
 	switch msg := msg.(type) {
 	case *log.AccessMessage:
-		if g.accessLogger != nil {
+		if g.accessLogger != nil && g.accessFilters.Allows(msg) {
 			g.accessLogger.Handle(Msg)
 		}
 	case *log.DNSLog:
 		if g.dns && g.accessLogger != nil {
 			g.accessLogger.Handle(Msg)
 		}
+	case *log.AuthFailureMessage:
+		if g.authFailureLogger != nil {
+			g.authFailureLogger.Handle(Msg)
+		}
 	case *log.GeneralMessage:
 		if g.errorLogger != nil && msg.Severity <= g.config.ErrorLogLevel {
 			g.errorLogger.Handle(Msg)
@@ -127,6 +171,20 @@ func (g *Instance) Handle(msg log.Message) {
 	}
 }
 
+// ReloadAccessLogFilters replaces the access log filters with those of config, leaving the other settings as they are.
+func (g *Instance) ReloadAccessLogFilters(config *Config) error {
+	filters, err := newAccessFilters(config.AccessLogFilter)
+	if err != nil {
+		return err
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	g.accessFilters = filters
+	return nil
+}
+
 // Close implements common.Closable.Close().
 func (g *Instance) Close() error {
 	errors.LogDebug(context.Background(), "Logger closing")
@@ -146,6 +204,9 @@ func (g *Instance) Close() error {
 	common.Close(g.errorLogger)
 	g.errorLogger = nil
 
+	common.Close(g.authFailureLogger)
+	g.authFailureLogger = nil
+
 	return nil
 }
 
@@ -155,9 +216,26 @@ type MaskedMsgWrapper struct {
 	config *Config
 }
 
+// Unwrap returns the wrapped message.
+func (m *MaskedMsgWrapper) Unwrap() log.Message {
+	return m.Message
+}
+
 func (m *MaskedMsgWrapper) String() string {
-	str := m.Message.String()
+	return m.mask(m.Message.String())
+}
+
+// AppendJSON implements log.JSONMessage, masking the addresses in the fields of the wrapped message.
+func (m *MaskedMsgWrapper) AppendJSON(b []byte) []byte {
+	msg, ok := m.Message.(log.JSONMessage)
+	if !ok {
+		b = log.AppendJSONField(b, "level", "info")
+		return log.AppendJSONField(b, "message", m.String())
+	}
+	return append(b, m.mask(string(msg.AppendJSON(nil)))...)
+}
 
+func (m *MaskedMsgWrapper) mask(str string) string {
 	ipv4Regex := regexp.MustCompile(`(\d{1,3}\.){3}\d{1,3}`)
 	ipv6Regex := regexp.MustCompile(`((?:[\da-fA-F]{0,4}:[\da-fA-F]{0,4}){2,7})(?:[\/\\%](\d{1,3}))?`)
 
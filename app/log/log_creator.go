@@ -2,6 +2,7 @@ package log
 
 import (
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
@@ -9,7 +10,12 @@ import (
 )
 
 type HandlerCreatorOptions struct {
-	Path string
+	Path     string
+	Format   LogFormat
+	Network  string
+	Address  string
+	Tag      string
+	Rotation *LogRotation
 }
 
 type HandlerCreator func(LogType, HandlerCreatorOptions) (log.Handler, error)
@@ -41,12 +47,51 @@ func createHandler(logType LogType, options HandlerCreatorOptions) (log.Handler,
 	return creator(logType, options)
 }
 
+func newLogger(creator log.WriterCreator, format LogFormat) log.Handler {
+	if format == LogFormat_JSON {
+		return log.NewJSONLogger(creator)
+	}
+	return log.NewLogger(creator)
+}
+
+// fallbackHandler returns a handler writing to stderr, for the log types failing to start, with a warning about the failure.
+func fallbackHandler(options HandlerCreatorOptions, err error) log.Handler {
+	handler := newLogger(log.CreateStderrLogWriter(), options.Format)
+	handler.Handle(&log.GeneralMessage{
+		Severity: log.Severity_Warning,
+		Content:  err,
+	})
+	return handler
+}
+
 func init() {
 	common.Must(RegisterHandlerCreator(LogType_Console, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		if options.Format == LogFormat_JSON {
+			return log.NewJSONLogger(log.CreateStdoutJSONLogWriter()), nil
+		}
 		return log.NewLogger(log.CreateStdoutLogWriter()), nil
 	}))
 
 	common.Must(RegisterHandlerCreator(LogType_File, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		if r := options.Rotation; r != nil {
+			creator, err := log.CreateRotatingFileLogWriter(options.Path, log.Rotation{
+				MaxSize:    int64(r.MaxSizeMb) * 1024 * 1024,
+				MaxBackups: int(r.MaxBackups),
+				MaxAge:     time.Duration(r.MaxAgeDays) * 24 * time.Hour,
+				Compress:   r.Compress,
+			}, options.Format == LogFormat_JSON)
+			if err != nil {
+				return nil, err
+			}
+			return newLogger(creator, options.Format), nil
+		}
+		if options.Format == LogFormat_JSON {
+			creator, err := log.CreateFileJSONLogWriter(options.Path)
+			if err != nil {
+				return nil, err
+			}
+			return log.NewJSONLogger(creator), nil
+		}
 		creator, err := log.CreateFileLogWriter(options.Path)
 		if err != nil {
 			return nil, err
@@ -54,6 +99,30 @@ func init() {
 		return log.NewLogger(creator), nil
 	}))
 
+	common.Must(RegisterHandlerCreator(LogType_Syslog, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		tag := options.Tag
+		if len(tag) == 0 {
+			tag = "xray"
+		}
+		creator, err := log.CreateSyslogLogWriter(options.Network, options.Address, tag)
+		if err != nil {
+			return fallbackHandler(options, errors.New("failed to connect to syslog, logging to stderr instead").Base(err)), nil
+		}
+		return newLogger(creator, options.Format), nil
+	}))
+
+	common.Must(RegisterHandlerCreator(LogType_Journald, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
+		tag := options.Tag
+		if len(tag) == 0 {
+			tag = "xray"
+		}
+		creator, err := log.CreateJournaldLogWriter(tag)
+		if err != nil {
+			return fallbackHandler(options, errors.New("failed to connect to journald, logging to stderr instead").Base(err)), nil
+		}
+		return newLogger(creator, options.Format), nil
+	}))
+
 	common.Must(RegisterHandlerCreator(LogType_None, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
 		return nil, nil
 	}))
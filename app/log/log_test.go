@@ -6,8 +6,10 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
 	clog "github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/testing/mocks"
 )
 
@@ -50,3 +52,64 @@ func TestCustomLogHandler(t *testing.T) {
 
 	common.Must(logger.Close())
 }
+
+func TestAccessLogFilter(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	var loggedValue []string
+
+	mockHandler := mocks.NewLogHandler(mockCtl)
+	mockHandler.EXPECT().Handle(gomock.Any()).AnyTimes().DoAndReturn(func(msg clog.Message) {
+		loggedValue = append(loggedValue, msg.String())
+	})
+
+	log.RegisterHandlerCreator(log.LogType_Console, func(lt log.LogType, options log.HandlerCreatorOptions) (clog.Handler, error) {
+		return mockHandler, nil
+	})
+
+	logger, err := log.New(context.Background(), &log.Config{
+		ErrorLogType:  log.LogType_None,
+		AccessLogType: log.LogType_Console,
+		AccessLogFilter: []*log.AccessLogFilter{
+			{
+				Domain: []*router.Domain{{Type: router.Domain_Domain, Value: "example.com"}},
+				Status: []string{"rejected"},
+			},
+			{
+				Domain: []*router.Domain{{Type: router.Domain_Domain, Value: "example.com"}},
+				Drop:   true,
+			},
+			{
+				InboundTag: []string{"dns"},
+				Drop:       true,
+			},
+		},
+	})
+	common.Must(err)
+	common.Must(logger.Start())
+
+	for _, msg := range []*clog.AccessMessage{
+		{From: "DNS", To: net.UDPDestination(net.ParseAddress("1.1.1.1"), 53), Status: clog.AccessAccepted, InboundTag: "dns"},
+		{To: net.TCPDestination(net.DomainAddress("www.example.com"), 443), Status: clog.AccessAccepted},
+		{To: net.TCPDestination(net.DomainAddress("www.example.com"), 443), Status: clog.AccessRejected},
+		{To: net.TCPDestination(net.DomainAddress("example.org"), 443), Status: clog.AccessAccepted, InboundTag: "in"},
+	} {
+		clog.Record(msg)
+	}
+
+	if len(loggedValue) != 2 {
+		t.Fatal("expected 2 records, but actually ", loggedValue)
+	}
+	if loggedValue[0] != "from  rejected tcp:www.example.com:443" || loggedValue[1] != "from  accepted tcp:example.org:443" {
+		t.Error("unexpected records: ", loggedValue)
+	}
+
+	common.Must(logger.ReloadAccessLogFilters(&log.Config{}))
+	clog.Record(&clog.AccessMessage{To: net.TCPDestination(net.DomainAddress("www.example.com"), 443), Status: clog.AccessAccepted})
+	if len(loggedValue) != 3 {
+		t.Error("expected the record to be written without filters")
+	}
+
+	common.Must(logger.Close())
+}
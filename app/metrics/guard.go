@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// deniedLogInterval is the least interval between two logs of denied requests.
+const deniedLogInterval = 10 * time.Second
+
+// guard serves the requests of the allowed sources with the credentials, if there are any, and denies the others.
+type guard struct {
+	handler http.Handler
+	allow   router.GeoIPMatcher
+	token   string
+	user    string
+	pass    string
+
+	lastLog atomic.Int64
+	denied  atomic.Int64
+}
+
+// newGuard returns handler itself if config checks nothing.
+func newGuard(handler http.Handler, config *Config) (http.Handler, error) {
+	if len(config.AllowIps) == 0 && config.Token == "" && config.User == "" {
+		return handler, nil
+	}
+	g := &guard{
+		handler: handler,
+		token:   config.Token,
+		user:    config.User,
+		pass:    config.Pass,
+	}
+	if len(config.AllowIps) > 0 {
+		m, err := router.BuildOptimizedGeoIPMatcher(config.AllowIps...)
+		if err != nil {
+			return nil, errors.New("failed to build the allowed IPs of metrics").Base(err)
+		}
+		g.allow = m
+	}
+	return g, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (g *guard) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !g.allows(r) {
+		g.logDenied(r, "source not allowed")
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if !g.authorized(r) {
+		g.logDenied(r, "unauthorized")
+		if g.user != "" {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+		}
+		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	g.handler.ServeHTTP(rw, r)
+}
+
+func (g *guard) allows(r *http.Request) bool {
+	if g.allow == nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && g.allow.Match(ip)
+}
+
+// authorized returns whether r carries the token or the user and password, if either is set.
+func (g *guard) authorized(r *http.Request) bool {
+	if g.token == "" && g.user == "" {
+		return true
+	}
+	if g.token != "" {
+		if token, found := cutPrefixFold(r.Header.Get("Authorization"), "Bearer "); found && equal(token, g.token) {
+			return true
+		}
+	}
+	if g.user != "" {
+		if user, pass, ok := r.BasicAuth(); ok && equal(user, g.user) && equal(pass, g.pass) {
+			return true
+		}
+	}
+	return false
+}
+
+// logDenied logs the denied request, at most once for each interval, with the number of denied requests since the last log.
+func (g *guard) logDenied(r *http.Request, reason string) {
+	count := g.denied.Add(1)
+	now := time.Now().UnixNano()
+	last := g.lastLog.Load()
+	if now-last < int64(deniedLogInterval) || !g.lastLog.CompareAndSwap(last, now) {
+		return
+	}
+	g.denied.Add(-count)
+	errors.LogWarning(context.Background(), "metrics: denied request to ", r.URL.Path, " from ", r.RemoteAddr, ": ", reason, ", denied requests = ", count)
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring case, as the scheme of Authorization is case insensitive.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestGuard(t *testing.T) {
+	ok := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {})
+	handler, err := newGuard(ok, &Config{
+		Token:    "secret",
+		User:     "admin",
+		Pass:     "pass",
+		AllowIps: []*router.GeoIP{{Cidr: []*router.CIDR{{Ip: net.ParseAddress("10.0.0.0").IP(), Prefix: 8}}}},
+	})
+	common.Must(err)
+
+	request := func(source string, auth func(r *http.Request)) int {
+		r := httptest.NewRequest("GET", "/debug/vars", nil)
+		r.RemoteAddr = source
+		if auth != nil {
+			auth(r)
+		}
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, r)
+		return rw.Code
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	basic := func(user, pass string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, pass) }
+	}
+
+	tests := []struct {
+		source string
+		auth   func(r *http.Request)
+		code   int
+	}{
+		{"10.1.2.3:1234", bearer("secret"), http.StatusOK},
+		{"10.1.2.3:1234", basic("admin", "pass"), http.StatusOK},
+		{"10.1.2.3:1234", func(r *http.Request) { r.Header.Set("Authorization", "bearer secret") }, http.StatusOK},
+		{"10.1.2.3:1234", nil, http.StatusUnauthorized},
+		{"10.1.2.3:1234", bearer("wrong"), http.StatusUnauthorized},
+		{"10.1.2.3:1234", basic("admin", "wrong"), http.StatusUnauthorized},
+		{"192.0.2.1:1234", bearer("secret"), http.StatusForbidden},
+	}
+	for i, test := range tests {
+		if code := request(test.source, test.auth); code != test.code {
+			t.Error("case ", i, ": expected ", test.code, ", but got ", code)
+		}
+	}
+}
+
+func TestGuardNothing(t *testing.T) {
+	mux := http.NewServeMux()
+	handler, err := newGuard(mux, &Config{})
+	common.Must(err)
+	if handler != http.Handler(mux) {
+		t.Error("expected the handler itself without checks")
+	}
+}
+
+func TestServeMuxPprof(t *testing.T) {
+	p := new(MetricsHandler)
+	for _, disabled := range []bool{false, true} {
+		mux := p.newServeMux(&Config{DisablePprof: disabled})
+		rw := httptest.NewRecorder()
+		mux.ServeHTTP(rw, httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil))
+		if (rw.Code == http.StatusOK) == disabled {
+			t.Error("pprof disabled = ", disabled, ", but got ", rw.Code)
+		}
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"expvar"
 	"net/http"
-	_ "net/http/pprof"
+	"net/http/pprof"
 	"strings"
 
 	"github.com/xtls/xray-core/app/observatory"
@@ -26,6 +26,7 @@ type MetricsHandler struct {
 	tag          string
 	listen       string
 	tcpListener  net.Listener
+	handler      http.Handler
 }
 
 // NewMetricsHandler creates a new MetricsHandler based on the given config.
@@ -50,6 +51,9 @@ func NewMetricsHandler(ctx context.Context, config *Config) (*MetricsHandler, er
 		}
 		manager.VisitCounters(func(name string, counter feature_stats.Counter) bool {
 			nameSplit := strings.Split(name, ">>>")
+			if len(nameSplit) != 4 || resp[nameSplit[0]] == nil {
+				return true
+			}
 			typeName, tagOrUser, direction := nameSplit[0], nameSplit[1], nameSplit[3]
 			if item, found := resp[typeName][tagOrUser]; found {
 				item[direction] = counter.Value()
@@ -82,9 +86,29 @@ func NewMetricsHandler(ctx context.Context, config *Config) (*MetricsHandler, er
 		}
 		return resp
 	}))
+	handler, err := newGuard(c.newServeMux(config), config)
+	if err != nil {
+		return nil, err
+	}
+	c.handler = handler
 	return c, nil
 }
 
+// newServeMux returns the handler of the endpoints of metrics, with the ones of pprof unless disabled.
+func (p *MetricsHandler) newServeMux(config *Config) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p)
+	mux.Handle("/debug/vars", expvar.Handler())
+	if !config.DisablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
 func (p *MetricsHandler) Type() interface{} {
 	return (*MetricsHandler)(nil)
 }
@@ -101,7 +125,7 @@ func (p *MetricsHandler) Start() error {
 		errors.LogInfo(context.Background(), "Metrics server listening on ", p.listen)
 
 		go func() {
-			if err := http.Serve(TCPlistener, http.DefaultServeMux); err != nil {
+			if err := http.Serve(TCPlistener, p.handler); err != nil {
 				errors.LogErrorInner(context.Background(), err, "failed to start metrics server")
 			}
 		}()
@@ -113,7 +137,7 @@ func (p *MetricsHandler) Start() error {
 	}
 
 	go func() {
-		if err := http.Serve(listener, http.DefaultServeMux); err != nil {
+		if err := http.Serve(listener, p.handler); err != nil {
 			errors.LogErrorInner(context.Background(), err, "failed to start metrics server")
 		}
 	}()
@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/core"
+	feature_stats "github.com/xtls/xray-core/features/stats"
+)
+
+var startTime = time.Now()
+
+type counterSample struct {
+	name  string
+	value int64
+}
+
+// trafficLabels maps the counter namespaces of the form "<type>>>><id>>>>traffic>>><direction>" to the label naming the id.
+var trafficLabels = map[string]string{
+	"inbound":  "tag",
+	"outbound": "tag",
+	"user":     "email",
+	"host":     "host",
+}
+
+// counterNames maps the stats counters that only ever go up to the names of their Prometheus counters.
+var counterNames = map[string]string{
+	"dns>>>cache>>>hit":  "xray_dns_cache_hits_total",
+	"dns>>>cache>>>miss": "xray_dns_cache_misses_total",
+}
+
+// dnsServerLabel labels the stats of the name servers of the form "dns>>><server>>>><name>".
+const dnsServerLabel = "server"
+
+var processMetrics = []struct {
+	metric string
+	name   string
+	help   string
+}{
+	{"/sched/goroutines:goroutines", "go_goroutines", "Number of goroutines that currently exist."},
+	{"/memory/classes/heap/objects:bytes", "go_memstats_heap_alloc_bytes", "Memory occupied by live objects and dead objects that have not yet been freed."},
+	{"/memory/classes/total:bytes", "go_memstats_sys_bytes", "All memory mapped by the Go runtime."},
+}
+
+// snapshotCounters copies the counters out so that the stats lock is held only as long as needed.
+func snapshotCounters(manager feature_stats.Manager) []counterSample {
+	v, ok := manager.(interface {
+		VisitCounters(func(string, feature_stats.Counter) bool)
+	})
+	if !ok {
+		return nil
+	}
+	var samples []counterSample
+	v.VisitCounters(func(name string, c feature_stats.Counter) bool {
+		samples = append(samples, counterSample{name: name, value: c.Value()})
+		return true
+	})
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].name < samples[j].name
+	})
+	return samples
+}
+
+type histogramSample struct {
+	name   string
+	bounds []int64
+	counts []int64
+	sum    int64
+}
+
+func snapshotHistograms(manager feature_stats.Manager) []histogramSample {
+	v, ok := manager.(interface {
+		VisitHistograms(func(string, feature_stats.Histogram) bool)
+	})
+	if !ok {
+		return nil
+	}
+	var samples []histogramSample
+	v.VisitHistograms(func(name string, h feature_stats.Histogram) bool {
+		counts, sum := h.Counts()
+		samples = append(samples, histogramSample{name: name, bounds: h.Bounds(), counts: counts, sum: sum})
+		return true
+	})
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].name < samples[j].name
+	})
+	return samples
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricName converts a stats counter name like "policy>>>buffer>>>multiplier" into a valid metric name.
+func metricName(name string) string {
+	var b strings.Builder
+	b.WriteString("xray_")
+	for _, r := range strings.ReplaceAll(name, ">>>", "_") {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// writePrometheus writes the counters and process metrics in the Prometheus text exposition format.
+func writePrometheus(w *bufio.Writer, samples []counterSample) {
+	var others, servers []counterSample
+
+	w.WriteString("# HELP xray_traffic_bytes_total Traffic in bytes.\n")
+	w.WriteString("# TYPE xray_traffic_bytes_total counter\n")
+	for _, s := range samples {
+		parts := strings.Split(s.name, ">>>")
+		if parts[0] == "dns" && len(parts) == 3 && (parts[2] == "queries" || parts[2] == "errors") {
+			servers = append(servers, s)
+			continue
+		}
+		label, found := trafficLabels[parts[0]]
+		if !found || len(parts) != 4 || parts[2] != "traffic" {
+			others = append(others, s)
+			continue
+		}
+		w.WriteString(`xray_traffic_bytes_total{type="`)
+		w.WriteString(parts[0])
+		w.WriteString(`",`)
+		w.WriteString(label)
+		w.WriteString(`="`)
+		w.WriteString(labelEscaper.Replace(parts[1]))
+		w.WriteString(`",direction="`)
+		w.WriteString(labelEscaper.Replace(parts[3]))
+		w.WriteString(`"} `)
+		w.WriteString(strconv.FormatInt(s.value, 10))
+		w.WriteByte('\n')
+	}
+
+	for _, family := range []string{"queries", "errors"} {
+		if len(servers) == 0 {
+			break
+		}
+		name := "xray_dns_" + family + "_total"
+		w.WriteString("# TYPE " + name + " counter\n")
+		for _, s := range servers {
+			parts := strings.Split(s.name, ">>>")
+			if parts[2] != family {
+				continue
+			}
+			w.WriteString(name + "{" + dnsServerLabel + `="` + labelEscaper.Replace(parts[1]) + `"} `)
+			w.WriteString(strconv.FormatInt(s.value, 10))
+			w.WriteByte('\n')
+		}
+	}
+
+	for _, s := range others {
+		name, counter := counterNames[s.name]
+		if !counter {
+			name = metricName(s.name)
+		}
+		w.WriteString("# TYPE ")
+		w.WriteString(name)
+		if counter {
+			w.WriteString(" counter\n")
+		} else {
+			w.WriteString(" gauge\n")
+		}
+		w.WriteString(name)
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatInt(s.value, 10))
+		w.WriteByte('\n')
+	}
+
+	rs := make([]metrics.Sample, len(processMetrics))
+	for i, m := range processMetrics {
+		rs[i].Name = m.metric
+	}
+	metrics.Read(rs)
+	for i, m := range processMetrics {
+		if rs[i].Value.Kind() != metrics.KindUint64 {
+			continue
+		}
+		w.WriteString("# HELP " + m.name + " " + m.help + "\n")
+		w.WriteString("# TYPE " + m.name + " gauge\n")
+		w.WriteString(m.name + " " + strconv.FormatUint(rs[i].Value.Uint64(), 10) + "\n")
+	}
+
+	w.WriteString("# TYPE process_start_time_seconds gauge\n")
+	w.WriteString("process_start_time_seconds " + strconv.FormatInt(startTime.Unix(), 10) + "\n")
+	w.WriteString("# TYPE xray_build_info gauge\n")
+	w.WriteString(`xray_build_info{version="` + core.Version() + `",goversion="` + runtime.Version() + "\"} 1\n")
+}
+
+// formatMilliseconds formats a duration in milliseconds as seconds.
+func formatMilliseconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
+
+// writeHistograms writes the histograms, recorded in milliseconds, as Prometheus histograms in seconds.
+// Names like "outbound>>>tag>>>handshake>>>histogram" become "xray_outbound_handshake_seconds{tag="tag"}",
+// and "dns>>>server>>>latency>>>histogram" becomes "xray_dns_latency_seconds{server="server"}".
+func writeHistograms(w *bufio.Writer, samples []histogramSample) {
+	type series struct {
+		labels string
+		sample histogramSample
+	}
+	families := make(map[string][]series)
+	var names []string
+	for _, s := range samples {
+		var family, labels string
+		parts := strings.Split(s.name, ">>>")
+		label, found := trafficLabels[parts[0]]
+		if parts[0] == "dns" {
+			label, found = dnsServerLabel, true
+		}
+		if found && len(parts) == 4 && parts[3] == "histogram" {
+			family = metricName(parts[0]+">>>"+parts[2]) + "_seconds"
+			labels = label + `="` + labelEscaper.Replace(parts[1]) + `"`
+		} else {
+			family = metricName(strings.TrimSuffix(s.name, ">>>histogram")) + "_seconds"
+		}
+		if _, found := families[family]; !found {
+			names = append(names, family)
+		}
+		families[family] = append(families[family], series{labels: labels, sample: s})
+	}
+	sort.Strings(names)
+
+	for _, family := range names {
+		w.WriteString("# TYPE " + family + " histogram\n")
+		for _, s := range families[family] {
+			prefix := ""
+			if s.labels != "" {
+				prefix = s.labels + ","
+			}
+			var cumulative int64
+			for i, c := range s.sample.counts {
+				cumulative += c
+				le := "+Inf"
+				if i < len(s.sample.bounds) {
+					le = formatMilliseconds(s.sample.bounds[i])
+				}
+				w.WriteString(family + "_bucket{" + prefix + `le="` + le + `"} ` + strconv.FormatInt(cumulative, 10) + "\n")
+			}
+			labels := ""
+			if s.labels != "" {
+				labels = "{" + s.labels + "}"
+			}
+			w.WriteString(family + "_sum" + labels + " " + formatMilliseconds(s.sample.sum) + "\n")
+			w.WriteString(family + "_count" + labels + " " + strconv.FormatInt(cumulative, 10) + "\n")
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler, serving stats in the Prometheus text exposition format.
+func (p *MetricsHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	samples := snapshotCounters(p.statsManager)
+	histograms := snapshotHistograms(p.statsManager)
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w := bufio.NewWriter(rw)
+	writePrometheus(w, samples)
+	writeHistograms(w, histograms)
+	w.Flush()
+}
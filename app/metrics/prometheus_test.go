@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	writePrometheus(w, []counterSample{
+		{name: "inbound>>>api>>>traffic>>>uplink", value: 10},
+		{name: "user>>>a\"b@example.com>>>traffic>>>downlink", value: 20},
+		{name: "policy>>>buffer>>>multiplier", value: 50},
+		{name: "dns>>>UDP:1.1.1.1:53>>>errors", value: 2},
+		{name: "dns>>>UDP:1.1.1.1:53>>>queries", value: 30},
+		{name: "dns>>>cache>>>hit", value: 40},
+		{name: "dns>>>cache>>>miss", value: 4},
+	})
+	w.Flush()
+	out := b.String()
+
+	for _, line := range []string{
+		`xray_traffic_bytes_total{type="inbound",tag="api",direction="uplink"} 10`,
+		`xray_traffic_bytes_total{type="user",email="a\"b@example.com",direction="downlink"} 20`,
+		`xray_policy_buffer_multiplier 50`,
+		`xray_dns_queries_total{server="UDP:1.1.1.1:53"} 30`,
+		`xray_dns_errors_total{server="UDP:1.1.1.1:53"} 2`,
+		"# TYPE xray_dns_cache_hits_total counter\nxray_dns_cache_hits_total 40",
+		"# TYPE xray_dns_cache_misses_total counter\nxray_dns_cache_misses_total 4",
+		`# TYPE go_goroutines gauge`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Error("missing line: ", line)
+		}
+	}
+}
+
+func TestWriteHistograms(t *testing.T) {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	writeHistograms(w, []histogramSample{
+		{name: "outbound>>>a>>>handshake>>>histogram", bounds: []int64{100, 1500}, counts: []int64{1, 2, 3}, sum: 9000},
+		{name: "outbound>>>a>>>connect>>>histogram", bounds: []int64{100}, counts: []int64{1, 0}, sum: 50},
+		{name: "outbound>>>b>>>handshake>>>histogram", bounds: []int64{100}, counts: []int64{0, 1}, sum: 250},
+		{name: "dns>>>localhost>>>latency>>>histogram", bounds: []int64{100}, counts: []int64{1, 0}, sum: 20},
+	})
+	w.Flush()
+	out := b.String()
+
+	expected := `# TYPE xray_outbound_handshake_seconds histogram
+xray_outbound_handshake_seconds_bucket{tag="a",le="0.1"} 1
+xray_outbound_handshake_seconds_bucket{tag="a",le="1.5"} 3
+xray_outbound_handshake_seconds_bucket{tag="a",le="+Inf"} 6
+xray_outbound_handshake_seconds_sum{tag="a"} 9
+xray_outbound_handshake_seconds_count{tag="a"} 6
+xray_outbound_handshake_seconds_bucket{tag="b",le="0.1"} 0
+xray_outbound_handshake_seconds_bucket{tag="b",le="+Inf"} 1
+xray_outbound_handshake_seconds_sum{tag="b"} 0.25
+xray_outbound_handshake_seconds_count{tag="b"} 1
+`
+	if !strings.Contains(out, expected) {
+		t.Error("unexpected output: ", out)
+	}
+	if !strings.Contains(out, "# TYPE xray_outbound_connect_seconds histogram\n") {
+		t.Error("missing connect histogram: ", out)
+	}
+	if !strings.Contains(out, `xray_dns_latency_seconds_count{server="localhost"} 1`+"\n") {
+		t.Error("missing dns histogram: ", out)
+	}
+}
@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"context"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	defaultBufferHighWaterMark = 64 * 1024 * 1024
+	maxBufferShift             = 3
+	heapUsageMetric            = "/memory/classes/heap/objects:bytes"
+
+	// BufferMultiplierStatName is the name of the stats counter holding the current buffer multiplier, in percent.
+	BufferMultiplierStatName = "policy>>>buffer>>>multiplier"
+)
+
+// bufferMonitor watches the heap usage and shrinks the buffer allowance of new connections under memory pressure.
+type bufferMonitor struct {
+	highWaterMark uint64
+	shift         atomic.Int32
+	samples       []metrics.Sample
+	gauge         atomic.Pointer[stats.Counter]
+	checker       *task.Periodic
+}
+
+func newBufferMonitor(highWaterMark uint64) *bufferMonitor {
+	if highWaterMark == 0 {
+		highWaterMark = defaultBufferHighWaterMark
+	}
+	m := &bufferMonitor{
+		highWaterMark: highWaterMark,
+		samples:       []metrics.Sample{{Name: heapUsageMetric}},
+	}
+	m.checker = &task.Periodic{
+		Interval: time.Second,
+		Execute:  m.check,
+	}
+	return m
+}
+
+func (m *bufferMonitor) setGauge(c stats.Counter) {
+	c.Set(int64(m.multiplier()))
+	m.gauge.Store(&c)
+}
+
+func (m *bufferMonitor) check() error {
+	metrics.Read(m.samples)
+	if m.samples[0].Value.Kind() == metrics.KindUint64 {
+		m.update(m.samples[0].Value.Uint64())
+	}
+	return nil
+}
+
+// update halves the multiplier when usage is above the high-water mark,
+// and doubles it back once usage drops below 3/4 of the mark.
+func (m *bufferMonitor) update(usage uint64) {
+	shift := m.shift.Load()
+	switch {
+	case usage > m.highWaterMark && shift < maxBufferShift:
+		shift++
+	case usage < m.highWaterMark/4*3 && shift > 0:
+		shift--
+	default:
+		return
+	}
+	m.shift.Store(shift)
+	if c := m.gauge.Load(); c != nil {
+		(*c).Set(int64(m.multiplier()))
+	}
+	errors.LogInfo(context.Background(), "adaptive buffer multiplier changed to ", m.multiplier(), "% at heap usage of ", usage, " bytes")
+}
+
+// multiplier returns the current buffer allowance, in percent.
+func (m *bufferMonitor) multiplier() int32 {
+	return 100 >> m.shift.Load()
+}
+
+// apply scales the given buffer size. Unlimited (-1) and zero sizes are left untouched.
+func (m *bufferMonitor) apply(size int32) int32 {
+	if size <= 0 {
+		return size
+	}
+	return size >> m.shift.Load()
+}
@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"testing"
+)
+
+func TestBufferMonitor(t *testing.T) {
+	m := newBufferMonitor(1000)
+
+	if v := m.apply(4096); v != 4096 {
+		t.Error("expect unchanged buffer size, but got ", v)
+	}
+
+	m.update(1001)
+	m.update(1001)
+	if v := m.multiplier(); v != 25 {
+		t.Error("expect 25% multiplier, but got ", v)
+	}
+	if v := m.apply(4096); v != 1024 {
+		t.Error("expect 1024 buffer size, but got ", v)
+	}
+	if v := m.apply(-1); v != -1 {
+		t.Error("expect unlimited buffer size, but got ", v)
+	}
+
+	for i := 0; i < 10; i++ {
+		m.update(2000)
+	}
+	if v := m.multiplier(); v != 100>>maxBufferShift {
+		t.Error("expect multiplier to stop at the minimum, but got ", v)
+	}
+
+	m.update(800)
+	if v := m.multiplier(); v != 100>>maxBufferShift {
+		t.Error("expect multiplier to hold between the marks, but got ", v)
+	}
+
+	for i := 0; i < maxBufferShift; i++ {
+		m.update(100)
+	}
+	if v := m.multiplier(); v != 100 {
+		t.Error("expect multiplier to be restored, but got ", v)
+	}
+}
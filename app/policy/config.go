@@ -43,6 +43,9 @@ func (p *Policy_Timeout) overrideWith(another *Policy_Timeout) {
 	if another.DownlinkOnly != nil {
 		p.DownlinkOnly = &Second{Value: another.DownlinkOnly.Value}
 	}
+	if another.UdpIdle != nil {
+		p.UdpIdle = &Second{Value: another.UdpIdle.Value}
+	}
 }
 
 func (p *Policy) overrideWith(another *Policy) {
@@ -55,7 +58,17 @@ func (p *Policy) overrideWith(another *Policy) {
 	}
 	if another.Buffer != nil {
 		p.Buffer = &Policy_Buffer{
-			Connection: another.Buffer.Connection,
+			Connection:    another.Buffer.Connection,
+			Adaptive:      another.Buffer.Adaptive,
+			HighWatermark: another.Buffer.HighWatermark,
+			LowWatermark:  another.Buffer.LowWatermark,
+		}
+	}
+	if another.RateLimit != nil {
+		p.RateLimit = &Policy_RateLimit{
+			PerUser:  another.RateLimit.PerUser,
+			Uplink:   another.RateLimit.Uplink,
+			Downlink: another.RateLimit.Downlink,
 		}
 	}
 }
@@ -69,6 +82,11 @@ func (p *Policy) ToCorePolicy() policy.Session {
 		cp.Timeouts.Handshake = p.Timeout.Handshake.Duration()
 		cp.Timeouts.DownlinkOnly = p.Timeout.DownlinkOnly.Duration()
 		cp.Timeouts.UplinkOnly = p.Timeout.UplinkOnly.Duration()
+		if p.Timeout.UdpIdle != nil {
+			cp.Timeouts.UDPIdle = p.Timeout.UdpIdle.Duration()
+		} else {
+			cp.Timeouts.UDPIdle = cp.Timeouts.ConnectionIdle
+		}
 	}
 	if p.Stats != nil {
 		cp.Stats.UserUplink = p.Stats.UserUplink
@@ -77,6 +95,14 @@ func (p *Policy) ToCorePolicy() policy.Session {
 	}
 	if p.Buffer != nil {
 		cp.Buffer.PerConnection = p.Buffer.Connection
+		cp.Buffer.Adaptive = p.Buffer.Adaptive
+		cp.Buffer.HighWatermark = p.Buffer.HighWatermark
+		cp.Buffer.LowWatermark = p.Buffer.LowWatermark
+	}
+	if p.RateLimit != nil {
+		cp.RateLimit.PerUser = p.RateLimit.PerUser
+		cp.RateLimit.Uplink = p.RateLimit.Uplink
+		cp.RateLimit.Downlink = p.RateLimit.Downlink
 	}
 	return cp
 }
@@ -85,10 +111,18 @@ func (p *Policy) ToCorePolicy() policy.Session {
 func (p *SystemPolicy) ToCorePolicy() policy.System {
 	return policy.System{
 		Stats: policy.SystemStats{
-			InboundUplink:    p.Stats.InboundUplink,
-			InboundDownlink:  p.Stats.InboundDownlink,
-			OutboundUplink:   p.Stats.OutboundUplink,
-			OutboundDownlink: p.Stats.OutboundDownlink,
+			InboundUplink:     p.Stats.InboundUplink,
+			InboundDownlink:   p.Stats.InboundDownlink,
+			OutboundUplink:    p.Stats.OutboundUplink,
+			OutboundDownlink:  p.Stats.OutboundDownlink,
+			HostTraffic:       p.Stats.HostTraffic,
+			HostTrafficLimit:  p.Stats.HostTrafficLimit,
+			OutboundHistogram: p.Stats.OutboundHistogram,
+			RuleTraffic:       p.Stats.RuleTraffic,
+		},
+		Security: policy.Security{
+			MinTLSVersion:  p.Security.GetMinTlsVersion(),
+			ForbidInsecure: p.Security.GetForbidInsecure(),
 		},
 	}
 }
@@ -4,13 +4,16 @@ import (
 	"context"
 
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
 )
 
 // Instance is an instance of Policy manager.
 type Instance struct {
 	levels map[uint32]*Policy
 	system *SystemPolicy
+	buffer *bufferMonitor
 }
 
 // New creates new Policy manager instance.
@@ -24,9 +27,20 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 			pp := defaultPolicy()
 			pp.overrideWith(p)
 			m.levels[lv] = pp
+			if pp.Buffer.GetAdaptive() && m.buffer == nil {
+				m.buffer = newBufferMonitor(config.System.GetBuffer().GetHighWaterMark())
+			}
 		}
 	}
 
+	if m.buffer != nil && core.FromContext(ctx) != nil {
+		core.OptionalFeatures(ctx, func(sm stats.Manager) {
+			if c, _ := stats.GetOrRegisterCounter(sm, BufferMultiplierStatName); c != nil {
+				m.buffer.setGauge(c)
+			}
+		})
+	}
+
 	return m, nil
 }
 
@@ -38,7 +52,13 @@ func (*Instance) Type() interface{} {
 // ForLevel implements policy.Manager.
 func (m *Instance) ForLevel(level uint32) policy.Session {
 	if p, ok := m.levels[level]; ok {
-		return p.ToCorePolicy()
+		cp := p.ToCorePolicy()
+		if cp.Buffer.Adaptive && m.buffer != nil {
+			cp.Buffer.PerConnection = m.buffer.apply(cp.Buffer.PerConnection)
+			cp.Buffer.HighWatermark = m.buffer.apply(cp.Buffer.HighWatermark)
+			cp.Buffer.LowWatermark = m.buffer.apply(cp.Buffer.LowWatermark)
+		}
+		return cp
 	}
 	return policy.SessionDefault()
 }
@@ -53,11 +73,17 @@ func (m *Instance) ForSystem() policy.System {
 
 // Start implements common.Runnable.Start().
 func (m *Instance) Start() error {
+	if m.buffer != nil {
+		return m.buffer.checker.Start()
+	}
 	return nil
 }
 
 // Close implements common.Closable.Close().
 func (m *Instance) Close() error {
+	if m.buffer != nil {
+		return m.buffer.checker.Close()
+	}
 	return nil
 }
 
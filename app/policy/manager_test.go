@@ -43,3 +43,32 @@ func TestPolicy(t *testing.T) {
 		}
 	}
 }
+
+func TestUDPIdlePolicy(t *testing.T) {
+	manager, err := New(context.Background(), &Config{
+		Level: map[uint32]*Policy{
+			0: {
+				Timeout: &Policy_Timeout{
+					ConnectionIdle: &Second{
+						Value: 600,
+					},
+				},
+			},
+			1: {
+				Timeout: &Policy_Timeout{
+					UdpIdle: &Second{
+						Value: 30,
+					},
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	if p := manager.ForLevel(0); p.Timeouts.UDPIdle != 600*time.Second {
+		t.Error("expect udpIdle to inherit connIdle, but got ", p.Timeouts.UDPIdle)
+	}
+	if p := manager.ForLevel(1); p.Timeouts.UDPIdle != 30*time.Second || p.Timeouts.ConnectionIdle != policy.SessionDefault().Timeouts.ConnectionIdle {
+		t.Error("expect 30 sec udpIdle and default connIdle, but got ", p.Timeouts.UDPIdle, " and ", p.Timeouts.ConnectionIdle)
+	}
+}
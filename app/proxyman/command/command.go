@@ -2,15 +2,25 @@ package command
 
 import (
 	"context"
+	"sort"
 
 	"github.com/xtls/xray-core/app/commander"
+	"github.com/xtls/xray-core/app/proxyman"
+	proxyman_inbound "github.com/xtls/xray-core/app/proxyman/inbound"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
 	grpc "google.golang.org/grpc"
 )
 
@@ -29,7 +39,7 @@ type OutboundOperation interface {
 func getInbound(handler inbound.Handler) (proxy.Inbound, error) {
 	gi, ok := handler.(proxy.GetInbound)
 	if !ok {
-		return nil, errors.New("can't get inbound proxy from handler.")
+		return nil, errors.New("can't get inbound proxy from handler.").WithCode(errors.CodeUnimplemented)
 	}
 	return gi.GetInbound(), nil
 }
@@ -42,11 +52,11 @@ func (op *AddUserOperation) ApplyInbound(ctx context.Context, handler inbound.Ha
 	}
 	um, ok := p.(proxy.UserManager)
 	if !ok {
-		return errors.New("proxy is not a UserManager")
+		return errors.New("proxy is not a UserManager").WithCode(errors.CodeUnimplemented)
 	}
 	mUser, err := op.User.ToMemoryUser()
 	if err != nil {
-		return errors.New("failed to parse user").Base(err)
+		return errors.New("failed to parse user").Base(err).WithCode(errors.CodeInvalidConfig)
 	}
 	return um.AddUser(ctx, mUser)
 }
@@ -59,18 +69,81 @@ func (op *RemoveUserOperation) ApplyInbound(ctx context.Context, handler inbound
 	}
 	um, ok := p.(proxy.UserManager)
 	if !ok {
-		return errors.New("proxy is not a UserManager")
+		return errors.New("proxy is not a UserManager").WithCode(errors.CodeUnimplemented)
 	}
 	return um.RemoveUser(ctx, op.Email)
 }
 
+// inboundConfig returns the config currently held by the handler, with secrets cleared if redact is set.
+func inboundConfig(handler inbound.Handler, redact bool) *core.InboundHandlerConfig {
+	config := &core.InboundHandlerConfig{
+		Tag:              handler.Tag(),
+		ReceiverSettings: handler.ReceiverSettings(),
+		ProxySettings:    handler.ProxySettings(),
+	}
+	if redact {
+		config.ReceiverSettings = redactTypedMessage(config.ReceiverSettings)
+		config.ProxySettings = redactTypedMessage(config.ProxySettings)
+	}
+	return config
+}
+
+// outboundConfig returns the config currently held by the handler, with secrets cleared if redact is set.
+func outboundConfig(handler outbound.Handler, redact bool) *core.OutboundHandlerConfig {
+	config := &core.OutboundHandlerConfig{
+		Tag:            handler.Tag(),
+		SenderSettings: handler.SenderSettings(),
+		ProxySettings:  handler.ProxySettings(),
+	}
+	if redact {
+		config.SenderSettings = redactTypedMessage(config.SenderSettings)
+		config.ProxySettings = redactTypedMessage(config.ProxySettings)
+	}
+	return config
+}
+
+// handlerError classifies an error of removing the handler with the given tag.
+// The managers report a missing tag with common.ErrNoClue.
+func handlerError(tag string, err error) error {
+	if err == common.ErrNoClue {
+		return errors.New("handler not found: ", tag).WithCode(errors.CodeNotFound)
+	}
+	return err
+}
+
 type handlerServer struct {
 	s   *core.Instance
+	ctx context.Context
 	ihm inbound.Manager
 	ohm outbound.Manager
+	d   routing.Dispatcher
+	pm  policy.Manager
+}
+
+// checkSecurity returns the TLS setting that the security policy forbids in settings, the receiver or sender settings
+// of a handler to add. A handler that fails to build is left to core.
+func (s *handlerServer) checkSecurity(settings *serial.TypedMessage) *tls.PolicyViolation {
+	if settings == nil {
+		return nil
+	}
+	instance, err := settings.GetInstance()
+	if err != nil {
+		return nil
+	}
+	var stream *internet.StreamConfig
+	switch config := instance.(type) {
+	case *proxyman.ReceiverConfig:
+		stream = config.StreamSettings
+	case *proxyman.SenderConfig:
+		stream = config.StreamSettings
+	}
+	return proxyman.CheckStreamSecurity(stream, s.pm.ForSystem().Security)
 }
 
 func (s *handlerServer) AddInbound(ctx context.Context, request *AddInboundRequest) (*AddInboundResponse, error) {
+	if v := s.checkSecurity(request.Inbound.GetReceiverSettings()); v != nil {
+		return nil, errors.New("inbound ", request.Inbound.GetTag(), " violates the security policy at ", v).WithCode(errors.CodeInvalidConfig)
+	}
 	if err := core.AddInboundHandler(s.s, request.Inbound); err != nil {
 		return nil, err
 	}
@@ -79,17 +152,20 @@ func (s *handlerServer) AddInbound(ctx context.Context, request *AddInboundReque
 }
 
 func (s *handlerServer) RemoveInbound(ctx context.Context, request *RemoveInboundRequest) (*RemoveInboundResponse, error) {
-	return &RemoveInboundResponse{}, s.ihm.RemoveHandler(ctx, request.Tag)
+	if err := s.ihm.RemoveHandler(ctx, request.Tag); err != nil {
+		return nil, handlerError(request.Tag, err)
+	}
+	return &RemoveInboundResponse{}, nil
 }
 
 func (s *handlerServer) AlterInbound(ctx context.Context, request *AlterInboundRequest) (*AlterInboundResponse, error) {
 	rawOperation, err := request.Operation.GetInstance()
 	if err != nil {
-		return nil, errors.New("unknown operation").Base(err)
+		return nil, errors.New("unknown operation").Base(err).WithCode(errors.CodeInvalidConfig)
 	}
 	operation, ok := rawOperation.(InboundOperation)
 	if !ok {
-		return nil, errors.New("not an inbound operation")
+		return nil, errors.New("not an inbound operation").WithCode(errors.CodeInvalidConfig)
 	}
 
 	handler, err := s.ihm.GetHandler(ctx, request.Tag)
@@ -103,6 +179,13 @@ func (s *handlerServer) AlterInbound(ctx context.Context, request *AlterInboundR
 func (s *handlerServer) ListInbounds(ctx context.Context, request *ListInboundsRequest) (*ListInboundsResponse, error) {
 	handlers := s.ihm.ListHandlers(ctx)
 	response := &ListInboundsResponse{}
+	if m, ok := s.ihm.(pausableManager); ok {
+		for _, handler := range handlers {
+			if tag := handler.Tag(); tag != "" && m.IsPaused(tag) {
+				response.Paused = append(response.Paused, tag)
+			}
+		}
+	}
 	if request.GetIsOnlyTags() {
 		for _, handler := range handlers {
 			response.Inbounds = append(response.Inbounds, &core.InboundHandlerConfig{
@@ -111,17 +194,21 @@ func (s *handlerServer) ListInbounds(ctx context.Context, request *ListInboundsR
 		}
 	} else {
 		for _, handler := range handlers {
-			response.Inbounds = append(response.Inbounds, &core.InboundHandlerConfig{
-				Tag:              handler.Tag(),
-				ReceiverSettings: handler.ReceiverSettings(),
-				ProxySettings:    handler.ProxySettings(),
-			})
+			response.Inbounds = append(response.Inbounds, inboundConfig(handler, request.Redact))
 		}
 	}
 
 	return response, nil
 }
 
+func (s *handlerServer) GetInbound(ctx context.Context, request *GetInboundRequest) (*GetInboundResponse, error) {
+	handler, err := s.ihm.GetHandler(ctx, request.Tag)
+	if err != nil {
+		return nil, errors.New("failed to get handler: ", request.Tag).Base(err)
+	}
+	return &GetInboundResponse{Inbound: inboundConfig(handler, request.Redact)}, nil
+}
+
 func (s *handlerServer) GetInboundUsers(ctx context.Context, request *GetInboundUserRequest) (*GetInboundUserResponse, error) {
 	handler, err := s.ihm.GetHandler(ctx, request.Tag)
 	if err != nil {
@@ -133,7 +220,7 @@ func (s *handlerServer) GetInboundUsers(ctx context.Context, request *GetInbound
 	}
 	um, ok := p.(proxy.UserManager)
 	if !ok {
-		return nil, errors.New("proxy is not a UserManager")
+		return nil, errors.New("proxy is not a UserManager").WithCode(errors.CodeUnimplemented)
 	}
 	if len(request.Email) > 0 {
 		return &GetInboundUserResponse{Users: []*protocol.User{protocol.ToProtoUser(um.GetUser(ctx, request.Email))}}, nil
@@ -157,12 +244,15 @@ func (s *handlerServer) GetInboundUsersCount(ctx context.Context, request *GetIn
 	}
 	um, ok := p.(proxy.UserManager)
 	if !ok {
-		return nil, errors.New("proxy is not a UserManager")
+		return nil, errors.New("proxy is not a UserManager").WithCode(errors.CodeUnimplemented)
 	}
 	return &GetInboundUsersCountResponse{Count: um.GetUsersCount(ctx)}, nil
 }
 
 func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundRequest) (*AddOutboundResponse, error) {
+	if v := s.checkSecurity(request.Outbound.GetSenderSettings()); v != nil {
+		return nil, errors.New("outbound ", request.Outbound.GetTag(), " violates the security policy at ", v).WithCode(errors.CodeInvalidConfig)
+	}
 	if err := core.AddOutboundHandler(s.s, request.Outbound); err != nil {
 		return nil, err
 	}
@@ -170,20 +260,26 @@ func (s *handlerServer) AddOutbound(ctx context.Context, request *AddOutboundReq
 }
 
 func (s *handlerServer) RemoveOutbound(ctx context.Context, request *RemoveOutboundRequest) (*RemoveOutboundResponse, error) {
-	return &RemoveOutboundResponse{}, s.ohm.RemoveHandler(ctx, request.Tag)
+	if err := s.ohm.RemoveHandler(ctx, request.Tag); err != nil {
+		return nil, handlerError(request.Tag, err)
+	}
+	return &RemoveOutboundResponse{}, nil
 }
 
 func (s *handlerServer) AlterOutbound(ctx context.Context, request *AlterOutboundRequest) (*AlterOutboundResponse, error) {
 	rawOperation, err := request.Operation.GetInstance()
 	if err != nil {
-		return nil, errors.New("unknown operation").Base(err)
+		return nil, errors.New("unknown operation").Base(err).WithCode(errors.CodeInvalidConfig)
 	}
 	operation, ok := rawOperation.(OutboundOperation)
 	if !ok {
-		return nil, errors.New("not an outbound operation")
+		return nil, errors.New("not an outbound operation").WithCode(errors.CodeInvalidConfig)
 	}
 
 	handler := s.ohm.GetHandler(request.Tag)
+	if handler == nil {
+		return nil, errors.New("handler not found: ", request.Tag).WithCode(errors.CodeNotFound)
+	}
 	return &AlterOutboundResponse{}, operation.ApplyOutbound(ctx, handler)
 }
 
@@ -195,28 +291,213 @@ func (s *handlerServer) ListOutbounds(ctx context.Context, request *ListOutbound
 		if _, ok := handler.(*commander.Outbound); ok {
 			continue
 		}
-		response.Outbounds = append(response.Outbounds, &core.OutboundHandlerConfig{
-			Tag:            handler.Tag(),
-			SenderSettings: handler.SenderSettings(),
-			ProxySettings:  handler.ProxySettings(),
+		response.Outbounds = append(response.Outbounds, outboundConfig(handler, request.Redact))
+	}
+	return response, nil
+}
+
+func (s *handlerServer) GetOutbound(ctx context.Context, request *GetOutboundRequest) (*GetOutboundResponse, error) {
+	handler := s.ohm.GetHandler(request.Tag)
+	if handler == nil {
+		return nil, errors.New("handler not found: ", request.Tag).WithCode(errors.CodeNotFound)
+	}
+	response := &GetOutboundResponse{Outbound: outboundConfig(handler, request.Redact)}
+	for _, address := range internet.ServerAddresses(request.Tag) {
+		ips := make([]string, len(address.IPs))
+		for i, ip := range address.IPs {
+			ips[i] = ip.String()
+		}
+		response.ServerAddresses = append(response.ServerAddresses, &ServerAddress{
+			Domain: address.Domain,
+			Ips:    ips,
+			Expire: address.Expire.Unix(),
+			Stale:  address.Stale,
 		})
 	}
 	return response, nil
 }
 
+// getBanList returns the ban list of the inbound handler with the given tag.
+func (s *handlerServer) getBanList(ctx context.Context, tag string) (*proxyman_inbound.BanList, error) {
+	handler, err := s.ihm.GetHandler(ctx, tag)
+	if err != nil {
+		return nil, errors.New("failed to get handler: ", tag).Base(err)
+	}
+	h, ok := handler.(interface {
+		BanList() *proxyman_inbound.BanList
+	})
+	if !ok || h.BanList() == nil {
+		return nil, errors.New("auto ban not enabled: ", tag).WithCode(errors.CodeUnavailable)
+	}
+	return h.BanList(), nil
+}
+
+func (s *handlerServer) GetInboundBans(ctx context.Context, request *GetInboundBansRequest) (*GetInboundBansResponse, error) {
+	banList, err := s.getBanList(ctx, request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	response := &GetInboundBansResponse{}
+	for _, ban := range banList.Bans() {
+		response.Bans = append(response.Bans, &InboundBan{
+			Ip:     ban.IP.String(),
+			Expire: ban.Expire.Unix(),
+		})
+	}
+	return response, nil
+}
+
+func (s *handlerServer) ClearInboundBans(ctx context.Context, request *ClearInboundBansRequest) (*ClearInboundBansResponse, error) {
+	banList, err := s.getBanList(ctx, request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(request.Ips))
+	for i, ip := range request.Ips {
+		if ips[i] = net.ParseIP(ip); ips[i] == nil {
+			return nil, errors.New("invalid IP: ", ip).WithCode(errors.CodeInvalidConfig)
+		}
+	}
+	banList.Clear(ips...)
+	return &ClearInboundBansResponse{}, nil
+}
+
+func (s *handlerServer) GetInboundNat(ctx context.Context, request *GetInboundNatRequest) (*GetInboundNatResponse, error) {
+	handler, err := s.ihm.GetHandler(ctx, request.Tag)
+	if err != nil {
+		return nil, errors.New("failed to get handler: ", request.Tag).Base(err)
+	}
+	h, ok := handler.(interface {
+		NatTable() ([]*proxyman_inbound.NatMapping, int64)
+	})
+	if !ok {
+		return nil, errors.New("no NAT table: ", request.Tag).WithCode(errors.CodeUnavailable)
+	}
+	mappings, unrecovered := h.NatTable()
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].LastSeen.After(mappings[j].LastSeen)
+	})
+	response := &GetInboundNatResponse{
+		UnrecoveredPackets: unrecovered,
+	}
+	for _, m := range mappings {
+		response.Mappings = append(response.Mappings, &NatMapping{
+			Source:              m.Source.NetAddr(),
+			OriginalDestination: m.OriginalDestination.NetAddr(),
+			OutboundTag:         m.OutboundTag,
+			Created:             m.Created.Unix(),
+			LastSeen:            m.LastSeen.Unix(),
+		})
+	}
+	return response, nil
+}
+
+// pausableManager is the inbound manager which pauses its handlers.
+type pausableManager interface {
+	PauseHandler(ctx context.Context, tag string) error
+	ResumeHandler(ctx context.Context, tag string) error
+	IsPaused(tag string) bool
+}
+
+func (s *handlerServer) getPausableManager() (pausableManager, error) {
+	m, ok := s.ihm.(pausableManager)
+	if !ok {
+		return nil, errors.New("inbound manager unable to pause handlers").WithCode(errors.CodeUnimplemented)
+	}
+	return m, nil
+}
+
+func (s *handlerServer) PauseInbound(ctx context.Context, request *PauseInboundRequest) (*PauseInboundResponse, error) {
+	m, err := s.getPausableManager()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.PauseHandler(ctx, request.Tag); err != nil {
+		return nil, err
+	}
+	return &PauseInboundResponse{}, nil
+}
+
+func (s *handlerServer) ResumeInbound(ctx context.Context, request *ResumeInboundRequest) (*ResumeInboundResponse, error) {
+	m, err := s.getPausableManager()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ResumeHandler(ctx, request.Tag); err != nil {
+		return nil, err
+	}
+	return &ResumeInboundResponse{}, nil
+}
+
+// owners returns the dispatcher and the handlers, which own the resources of the sessions.
+func (s *handlerServer) owners(ctx context.Context) []interface{} {
+	owners := []interface{}{s.d}
+	for _, h := range s.ihm.ListHandlers(ctx) {
+		owners = append(owners, h)
+	}
+	for _, h := range s.ohm.ListHandlers(ctx) {
+		owners = append(owners, h)
+	}
+	return owners
+}
+
+func handlerResources(m map[string]*stats.Resources) []*HandlerResources {
+	list := make([]*HandlerResources, 0, len(m))
+	for tag, res := range m {
+		list = append(list, &HandlerResources{
+			Tag:           tag,
+			TcpSessions:   res.TCPSessions,
+			UdpNatEntries: res.UDPNATEntries,
+			MuxStreams:    res.MuxStreams,
+			PipeBytes:     res.PipeBytes,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Tag < list[j].Tag
+	})
+	return list
+}
+
+func (s *handlerServer) GetResourceStats(ctx context.Context, request *GetResourceStatsRequest) (*GetResourceStatsResponse, error) {
+	report := stats.NewResourceReport()
+	for _, owner := range s.owners(ctx) {
+		if r, ok := owner.(stats.ResourceReporter); ok {
+			r.ReportResources(report)
+		}
+	}
+	return &GetResourceStatsResponse{
+		Inbounds:  handlerResources(report.Inbounds),
+		Outbounds: handlerResources(report.Outbounds),
+	}, nil
+}
+
+func (s *handlerServer) RunGC(ctx context.Context, request *RunGCRequest) (*RunGCResponse, error) {
+	response := &RunGCResponse{}
+	for _, owner := range s.owners(ctx) {
+		if c, ok := owner.(stats.IdleCollector); ok {
+			response.Collected += int64(c.CollectIdle())
+		}
+	}
+	return response, nil
+}
+
 func (s *handlerServer) mustEmbedUnimplementedHandlerServiceServer() {}
 
 type service struct {
-	v *core.Instance
+	v   *core.Instance
+	ctx context.Context
 }
 
 func (s *service) Register(server *grpc.Server) {
 	hs := &handlerServer{
-		s: s.v,
+		s:   s.v,
+		ctx: s.ctx,
 	}
-	common.Must(s.v.RequireFeatures(func(im inbound.Manager, om outbound.Manager) {
+	common.Must(s.v.RequireFeatures(func(im inbound.Manager, om outbound.Manager, d routing.Dispatcher, pm policy.Manager) {
 		hs.ihm = im
 		hs.ohm = om
+		hs.d = d
+		hs.pm = pm
 	}, false))
 	RegisterHandlerServiceServer(server, hs)
 
@@ -229,6 +510,6 @@ func (s *service) Register(server *grpc.Server) {
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
 		s := core.MustFromContext(ctx)
-		return &service{v: s}, nil
+		return &service{v: s, ctx: core.ToBackgroundDetachedContext(ctx)}, nil
 	}))
 }
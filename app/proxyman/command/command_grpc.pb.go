@@ -23,28 +23,56 @@ const (
 	HandlerService_RemoveInbound_FullMethodName        = "/xray.app.proxyman.command.HandlerService/RemoveInbound"
 	HandlerService_AlterInbound_FullMethodName         = "/xray.app.proxyman.command.HandlerService/AlterInbound"
 	HandlerService_ListInbounds_FullMethodName         = "/xray.app.proxyman.command.HandlerService/ListInbounds"
+	HandlerService_GetInbound_FullMethodName           = "/xray.app.proxyman.command.HandlerService/GetInbound"
 	HandlerService_GetInboundUsers_FullMethodName      = "/xray.app.proxyman.command.HandlerService/GetInboundUsers"
 	HandlerService_GetInboundUsersCount_FullMethodName = "/xray.app.proxyman.command.HandlerService/GetInboundUsersCount"
 	HandlerService_AddOutbound_FullMethodName          = "/xray.app.proxyman.command.HandlerService/AddOutbound"
 	HandlerService_RemoveOutbound_FullMethodName       = "/xray.app.proxyman.command.HandlerService/RemoveOutbound"
 	HandlerService_AlterOutbound_FullMethodName        = "/xray.app.proxyman.command.HandlerService/AlterOutbound"
 	HandlerService_ListOutbounds_FullMethodName        = "/xray.app.proxyman.command.HandlerService/ListOutbounds"
+	HandlerService_GetOutbound_FullMethodName          = "/xray.app.proxyman.command.HandlerService/GetOutbound"
+	HandlerService_GetInboundBans_FullMethodName       = "/xray.app.proxyman.command.HandlerService/GetInboundBans"
+	HandlerService_ClearInboundBans_FullMethodName     = "/xray.app.proxyman.command.HandlerService/ClearInboundBans"
+	HandlerService_GetInboundNat_FullMethodName        = "/xray.app.proxyman.command.HandlerService/GetInboundNat"
+	HandlerService_GetResourceStats_FullMethodName     = "/xray.app.proxyman.command.HandlerService/GetResourceStats"
+	HandlerService_RunGC_FullMethodName                = "/xray.app.proxyman.command.HandlerService/RunGC"
+	HandlerService_PauseInbound_FullMethodName         = "/xray.app.proxyman.command.HandlerService/PauseInbound"
+	HandlerService_ResumeInbound_FullMethodName        = "/xray.app.proxyman.command.HandlerService/ResumeInbound"
+	HandlerService_TestOutbound_FullMethodName         = "/xray.app.proxyman.command.HandlerService/TestOutbound"
 )
 
 // HandlerServiceClient is the client API for HandlerService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Failed calls carry a gRPC status code that is part of the API and stays
+// stable across releases: NOT_FOUND for unknown handlers and users,
+// ALREADY_EXISTS for duplicate tags and users, INVALID_ARGUMENT for configs
+// and operations that cannot be applied, UNAVAILABLE if the handler has no
+// ban list or NAT table, and UNIMPLEMENTED if the handler does not support
+// the operation. Other failures are UNKNOWN. Status messages are for humans
+// and may change.
 type HandlerServiceClient interface {
 	AddInbound(ctx context.Context, in *AddInboundRequest, opts ...grpc.CallOption) (*AddInboundResponse, error)
 	RemoveInbound(ctx context.Context, in *RemoveInboundRequest, opts ...grpc.CallOption) (*RemoveInboundResponse, error)
 	AlterInbound(ctx context.Context, in *AlterInboundRequest, opts ...grpc.CallOption) (*AlterInboundResponse, error)
 	ListInbounds(ctx context.Context, in *ListInboundsRequest, opts ...grpc.CallOption) (*ListInboundsResponse, error)
+	GetInbound(ctx context.Context, in *GetInboundRequest, opts ...grpc.CallOption) (*GetInboundResponse, error)
 	GetInboundUsers(ctx context.Context, in *GetInboundUserRequest, opts ...grpc.CallOption) (*GetInboundUserResponse, error)
 	GetInboundUsersCount(ctx context.Context, in *GetInboundUserRequest, opts ...grpc.CallOption) (*GetInboundUsersCountResponse, error)
 	AddOutbound(ctx context.Context, in *AddOutboundRequest, opts ...grpc.CallOption) (*AddOutboundResponse, error)
 	RemoveOutbound(ctx context.Context, in *RemoveOutboundRequest, opts ...grpc.CallOption) (*RemoveOutboundResponse, error)
 	AlterOutbound(ctx context.Context, in *AlterOutboundRequest, opts ...grpc.CallOption) (*AlterOutboundResponse, error)
 	ListOutbounds(ctx context.Context, in *ListOutboundsRequest, opts ...grpc.CallOption) (*ListOutboundsResponse, error)
+	GetOutbound(ctx context.Context, in *GetOutboundRequest, opts ...grpc.CallOption) (*GetOutboundResponse, error)
+	GetInboundBans(ctx context.Context, in *GetInboundBansRequest, opts ...grpc.CallOption) (*GetInboundBansResponse, error)
+	ClearInboundBans(ctx context.Context, in *ClearInboundBansRequest, opts ...grpc.CallOption) (*ClearInboundBansResponse, error)
+	GetInboundNat(ctx context.Context, in *GetInboundNatRequest, opts ...grpc.CallOption) (*GetInboundNatResponse, error)
+	GetResourceStats(ctx context.Context, in *GetResourceStatsRequest, opts ...grpc.CallOption) (*GetResourceStatsResponse, error)
+	RunGC(ctx context.Context, in *RunGCRequest, opts ...grpc.CallOption) (*RunGCResponse, error)
+	PauseInbound(ctx context.Context, in *PauseInboundRequest, opts ...grpc.CallOption) (*PauseInboundResponse, error)
+	ResumeInbound(ctx context.Context, in *ResumeInboundRequest, opts ...grpc.CallOption) (*ResumeInboundResponse, error)
+	TestOutbound(ctx context.Context, in *TestOutboundRequest, opts ...grpc.CallOption) (*TestOutboundResponse, error)
 }
 
 type handlerServiceClient struct {
@@ -95,6 +123,16 @@ func (c *handlerServiceClient) ListInbounds(ctx context.Context, in *ListInbound
 	return out, nil
 }
 
+func (c *handlerServiceClient) GetInbound(ctx context.Context, in *GetInboundRequest, opts ...grpc.CallOption) (*GetInboundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInboundResponse)
+	err := c.cc.Invoke(ctx, HandlerService_GetInbound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *handlerServiceClient) GetInboundUsers(ctx context.Context, in *GetInboundUserRequest, opts ...grpc.CallOption) (*GetInboundUserResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetInboundUserResponse)
@@ -155,20 +193,128 @@ func (c *handlerServiceClient) ListOutbounds(ctx context.Context, in *ListOutbou
 	return out, nil
 }
 
+func (c *handlerServiceClient) GetOutbound(ctx context.Context, in *GetOutboundRequest, opts ...grpc.CallOption) (*GetOutboundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOutboundResponse)
+	err := c.cc.Invoke(ctx, HandlerService_GetOutbound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) GetInboundBans(ctx context.Context, in *GetInboundBansRequest, opts ...grpc.CallOption) (*GetInboundBansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInboundBansResponse)
+	err := c.cc.Invoke(ctx, HandlerService_GetInboundBans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ClearInboundBans(ctx context.Context, in *ClearInboundBansRequest, opts ...grpc.CallOption) (*ClearInboundBansResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClearInboundBansResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ClearInboundBans_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) GetInboundNat(ctx context.Context, in *GetInboundNatRequest, opts ...grpc.CallOption) (*GetInboundNatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInboundNatResponse)
+	err := c.cc.Invoke(ctx, HandlerService_GetInboundNat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) GetResourceStats(ctx context.Context, in *GetResourceStatsRequest, opts ...grpc.CallOption) (*GetResourceStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResourceStatsResponse)
+	err := c.cc.Invoke(ctx, HandlerService_GetResourceStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) RunGC(ctx context.Context, in *RunGCRequest, opts ...grpc.CallOption) (*RunGCResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunGCResponse)
+	err := c.cc.Invoke(ctx, HandlerService_RunGC_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) PauseInbound(ctx context.Context, in *PauseInboundRequest, opts ...grpc.CallOption) (*PauseInboundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseInboundResponse)
+	err := c.cc.Invoke(ctx, HandlerService_PauseInbound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) ResumeInbound(ctx context.Context, in *ResumeInboundRequest, opts ...grpc.CallOption) (*ResumeInboundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResumeInboundResponse)
+	err := c.cc.Invoke(ctx, HandlerService_ResumeInbound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *handlerServiceClient) TestOutbound(ctx context.Context, in *TestOutboundRequest, opts ...grpc.CallOption) (*TestOutboundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TestOutboundResponse)
+	err := c.cc.Invoke(ctx, HandlerService_TestOutbound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // HandlerServiceServer is the server API for HandlerService service.
 // All implementations must embed UnimplementedHandlerServiceServer
 // for forward compatibility.
+//
+// Failed calls carry a gRPC status code that is part of the API and stays
+// stable across releases: NOT_FOUND for unknown handlers and users,
+// ALREADY_EXISTS for duplicate tags and users, INVALID_ARGUMENT for configs
+// and operations that cannot be applied, UNAVAILABLE if the handler has no
+// ban list or NAT table, and UNIMPLEMENTED if the handler does not support
+// the operation. Other failures are UNKNOWN. Status messages are for humans
+// and may change.
 type HandlerServiceServer interface {
 	AddInbound(context.Context, *AddInboundRequest) (*AddInboundResponse, error)
 	RemoveInbound(context.Context, *RemoveInboundRequest) (*RemoveInboundResponse, error)
 	AlterInbound(context.Context, *AlterInboundRequest) (*AlterInboundResponse, error)
 	ListInbounds(context.Context, *ListInboundsRequest) (*ListInboundsResponse, error)
+	GetInbound(context.Context, *GetInboundRequest) (*GetInboundResponse, error)
 	GetInboundUsers(context.Context, *GetInboundUserRequest) (*GetInboundUserResponse, error)
 	GetInboundUsersCount(context.Context, *GetInboundUserRequest) (*GetInboundUsersCountResponse, error)
 	AddOutbound(context.Context, *AddOutboundRequest) (*AddOutboundResponse, error)
 	RemoveOutbound(context.Context, *RemoveOutboundRequest) (*RemoveOutboundResponse, error)
 	AlterOutbound(context.Context, *AlterOutboundRequest) (*AlterOutboundResponse, error)
 	ListOutbounds(context.Context, *ListOutboundsRequest) (*ListOutboundsResponse, error)
+	GetOutbound(context.Context, *GetOutboundRequest) (*GetOutboundResponse, error)
+	GetInboundBans(context.Context, *GetInboundBansRequest) (*GetInboundBansResponse, error)
+	ClearInboundBans(context.Context, *ClearInboundBansRequest) (*ClearInboundBansResponse, error)
+	GetInboundNat(context.Context, *GetInboundNatRequest) (*GetInboundNatResponse, error)
+	GetResourceStats(context.Context, *GetResourceStatsRequest) (*GetResourceStatsResponse, error)
+	RunGC(context.Context, *RunGCRequest) (*RunGCResponse, error)
+	PauseInbound(context.Context, *PauseInboundRequest) (*PauseInboundResponse, error)
+	ResumeInbound(context.Context, *ResumeInboundRequest) (*ResumeInboundResponse, error)
+	TestOutbound(context.Context, *TestOutboundRequest) (*TestOutboundResponse, error)
 	mustEmbedUnimplementedHandlerServiceServer()
 }
 
@@ -191,6 +337,9 @@ func (UnimplementedHandlerServiceServer) AlterInbound(context.Context, *AlterInb
 func (UnimplementedHandlerServiceServer) ListInbounds(context.Context, *ListInboundsRequest) (*ListInboundsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListInbounds not implemented")
 }
+func (UnimplementedHandlerServiceServer) GetInbound(context.Context, *GetInboundRequest) (*GetInboundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInbound not implemented")
+}
 func (UnimplementedHandlerServiceServer) GetInboundUsers(context.Context, *GetInboundUserRequest) (*GetInboundUserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetInboundUsers not implemented")
 }
@@ -209,6 +358,33 @@ func (UnimplementedHandlerServiceServer) AlterOutbound(context.Context, *AlterOu
 func (UnimplementedHandlerServiceServer) ListOutbounds(context.Context, *ListOutboundsRequest) (*ListOutboundsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListOutbounds not implemented")
 }
+func (UnimplementedHandlerServiceServer) GetOutbound(context.Context, *GetOutboundRequest) (*GetOutboundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOutbound not implemented")
+}
+func (UnimplementedHandlerServiceServer) GetInboundBans(context.Context, *GetInboundBansRequest) (*GetInboundBansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInboundBans not implemented")
+}
+func (UnimplementedHandlerServiceServer) ClearInboundBans(context.Context, *ClearInboundBansRequest) (*ClearInboundBansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearInboundBans not implemented")
+}
+func (UnimplementedHandlerServiceServer) GetInboundNat(context.Context, *GetInboundNatRequest) (*GetInboundNatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInboundNat not implemented")
+}
+func (UnimplementedHandlerServiceServer) GetResourceStats(context.Context, *GetResourceStatsRequest) (*GetResourceStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResourceStats not implemented")
+}
+func (UnimplementedHandlerServiceServer) RunGC(context.Context, *RunGCRequest) (*RunGCResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunGC not implemented")
+}
+func (UnimplementedHandlerServiceServer) PauseInbound(context.Context, *PauseInboundRequest) (*PauseInboundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseInbound not implemented")
+}
+func (UnimplementedHandlerServiceServer) ResumeInbound(context.Context, *ResumeInboundRequest) (*ResumeInboundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeInbound not implemented")
+}
+func (UnimplementedHandlerServiceServer) TestOutbound(context.Context, *TestOutboundRequest) (*TestOutboundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TestOutbound not implemented")
+}
 func (UnimplementedHandlerServiceServer) mustEmbedUnimplementedHandlerServiceServer() {}
 func (UnimplementedHandlerServiceServer) testEmbeddedByValue()                        {}
 
@@ -302,6 +478,24 @@ func _HandlerService_ListInbounds_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HandlerService_GetInbound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInboundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).GetInbound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_GetInbound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).GetInbound(ctx, req.(*GetInboundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _HandlerService_GetInboundUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetInboundUserRequest)
 	if err := dec(in); err != nil {
@@ -410,6 +604,168 @@ func _HandlerService_ListOutbounds_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HandlerService_GetOutbound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOutboundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).GetOutbound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_GetOutbound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).GetOutbound(ctx, req.(*GetOutboundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_GetInboundBans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInboundBansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).GetInboundBans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_GetInboundBans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).GetInboundBans(ctx, req.(*GetInboundBansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ClearInboundBans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearInboundBansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ClearInboundBans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ClearInboundBans_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ClearInboundBans(ctx, req.(*ClearInboundBansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_GetInboundNat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInboundNatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).GetInboundNat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_GetInboundNat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).GetInboundNat(ctx, req.(*GetInboundNatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_GetResourceStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResourceStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).GetResourceStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_GetResourceStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).GetResourceStats(ctx, req.(*GetResourceStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_RunGC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunGCRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).RunGC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_RunGC_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).RunGC(ctx, req.(*RunGCRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_PauseInbound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseInboundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).PauseInbound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_PauseInbound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).PauseInbound(ctx, req.(*PauseInboundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_ResumeInbound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeInboundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).ResumeInbound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_ResumeInbound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).ResumeInbound(ctx, req.(*ResumeInboundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HandlerService_TestOutbound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestOutboundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HandlerServiceServer).TestOutbound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HandlerService_TestOutbound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HandlerServiceServer).TestOutbound(ctx, req.(*TestOutboundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // HandlerService_ServiceDesc is the grpc.ServiceDesc for HandlerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -433,6 +789,10 @@ var HandlerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListInbounds",
 			Handler:    _HandlerService_ListInbounds_Handler,
 		},
+		{
+			MethodName: "GetInbound",
+			Handler:    _HandlerService_GetInbound_Handler,
+		},
 		{
 			MethodName: "GetInboundUsers",
 			Handler:    _HandlerService_GetInboundUsers_Handler,
@@ -457,6 +817,42 @@ var HandlerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListOutbounds",
 			Handler:    _HandlerService_ListOutbounds_Handler,
 		},
+		{
+			MethodName: "GetOutbound",
+			Handler:    _HandlerService_GetOutbound_Handler,
+		},
+		{
+			MethodName: "GetInboundBans",
+			Handler:    _HandlerService_GetInboundBans_Handler,
+		},
+		{
+			MethodName: "ClearInboundBans",
+			Handler:    _HandlerService_ClearInboundBans_Handler,
+		},
+		{
+			MethodName: "GetInboundNat",
+			Handler:    _HandlerService_GetInboundNat_Handler,
+		},
+		{
+			MethodName: "GetResourceStats",
+			Handler:    _HandlerService_GetResourceStats_Handler,
+		},
+		{
+			MethodName: "RunGC",
+			Handler:    _HandlerService_RunGC_Handler,
+		},
+		{
+			MethodName: "PauseInbound",
+			Handler:    _HandlerService_PauseInbound_Handler,
+		},
+		{
+			MethodName: "ResumeInbound",
+			Handler:    _HandlerService_ResumeInbound_Handler,
+		},
+		{
+			MethodName: "TestOutbound",
+			Handler:    _HandlerService_TestOutbound_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "app/proxyman/command/command.proto",
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/inbound"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/proxy/vless"
+	vlessinbound "github.com/xtls/xray-core/proxy/vless/inbound"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+)
+
+func vlessUser(email string) *protocol.User {
+	id := uuid.New()
+	return &protocol.User{
+		Email:   email,
+		Account: serial.ToTypedMessage(&vless.Account{Id: id.String()}),
+	}
+}
+
+func TestInboundConfigUsers(t *testing.T) {
+	server, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(tcp.PickPort())}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&vlessinbound.Config{
+					Clients:    []*protocol.User{vlessUser("a@example.com"), vlessUser("b@example.com")},
+					Decryption: "none",
+				}),
+			},
+		},
+	})
+	common.Must(err)
+	defer server.Close()
+
+	ctx := context.Background()
+	handler, err := server.GetFeature(inbound.ManagerType()).(inbound.Manager).GetHandler(ctx, "in")
+	common.Must(err)
+	common.Must((&AddUserOperation{User: vlessUser("c@example.com")}).ApplyInbound(ctx, handler))
+	common.Must((&RemoveUserOperation{Email: "a@example.com"}).ApplyInbound(ctx, handler))
+
+	config, err := inboundConfig(handler, false).ProxySettings.GetInstance()
+	common.Must(err)
+	var emails []string
+	for _, u := range config.(*vlessinbound.Config).Clients {
+		emails = append(emails, u.Email)
+	}
+	sort.Strings(emails)
+	if r := cmp.Diff([]string{"b@example.com", "c@example.com"}, emails); r != "" {
+		t.Error("unexpected users of the inbound config: ", r)
+	}
+}
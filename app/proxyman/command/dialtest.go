@@ -0,0 +1,280 @@
+package command
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/net/cnc"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/reality"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+const defaultTestOutboundTimeout = 10 * time.Second
+
+// stageTimer is a histogram that keeps the time of a stage of dialing for TestOutbound, to put in the dial timings.
+type stageTimer struct {
+	access sync.Mutex
+	last   int64
+	count  int64
+	sum    int64
+	done   chan struct{}
+}
+
+func newStageTimer() *stageTimer {
+	return &stageTimer{done: make(chan struct{})}
+}
+
+// Observe implements stats.Histogram.
+func (t *stageTimer) Observe(v int64) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	if t.count == 0 {
+		close(t.done)
+	}
+	t.last = v
+	t.count++
+	t.sum += v
+}
+
+// Bounds implements stats.Histogram.
+func (t *stageTimer) Bounds() []int64 {
+	return nil
+}
+
+// Counts implements stats.Histogram.
+func (t *stageTimer) Counts() ([]int64, int64) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return []int64{t.count}, t.sum
+}
+
+// Reset implements stats.Histogram.
+func (t *stageTimer) Reset() ([]int64, int64) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	counts, sum := []int64{t.count}, t.sum
+	t.count, t.sum = 0, 0
+	return counts, sum
+}
+
+// value returns the time of the stage, and whether the stage is done.
+func (t *stageTimer) value() (int64, bool) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return t.last, t.count > 0
+}
+
+// outboundTest is a TestOutbound in progress.
+type outboundTest struct {
+	resolve   *stageTimer
+	connect   *stageTimer
+	handshake *stageTimer
+	// reportsHandshake is whether the transport of the outbound reports the TLS or REALITY handshake.
+	reportsHandshake bool
+	// ready is closed once the outbound reads the link, which it does after it connects, or over a connection
+	// it holds already, like of Mux or XHTTP, that the dial timings do not observe.
+	ready     chan struct{}
+	readyOnce sync.Once
+	// dispatched is closed once the Dispatch of the outbound returns, which Mux does as soon as it takes the link.
+	dispatched chan struct{}
+	errs       chan error
+	response   TestOutboundResponse
+}
+
+// readyReader is the uplink of the outbound, which tells the test once the outbound reads it.
+type readyReader struct {
+	buf.Reader
+	t *outboundTest
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *readyReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	r.t.readyOnce.Do(func() { close(r.t.ready) })
+	return r.Reader.ReadMultiBuffer()
+}
+
+// SubmitError implements session.TrackedRequestErrorFeedback, to be told of the error of the outbound.
+func (t *outboundTest) SubmitError(err error) {
+	select {
+	case t.errs <- err:
+	default:
+	}
+}
+
+// fail sets the response to the failure of stage with err, or to the failure of the outbound if it has failed.
+func (t *outboundTest) fail(stage string, err error) {
+	select {
+	case err = <-t.errs:
+		if _, connected := t.connect.value(); !connected {
+			stage = "connect"
+		} else if _, handshaked := t.handshake.value(); t.reportsHandshake && !handshaked {
+			stage = "handshake"
+		}
+	default:
+	}
+	t.response.FailedStage = stage
+	t.response.Error = err.Error()
+}
+
+// waitConnected waits for the outbound to connect to its server, or to fail.
+func (t *outboundTest) waitConnected(ctx context.Context) error {
+	connected := t.connect.done
+	if t.reportsHandshake {
+		connected = t.handshake.done
+	}
+	select {
+	case <-connected:
+		return nil
+	case <-t.ready:
+		return nil
+	case <-t.dispatched:
+		select {
+		case err := <-t.errs:
+			t.SubmitError(err)
+			return err
+		default:
+			return nil
+		}
+	case err := <-t.errs:
+		// left for fail to tell the stage
+		t.SubmitError(err)
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reportsHandshake returns whether the handshake of the TLS or REALITY of the handler is timed by its transport.
+func reportsHandshake(handler outbound.Handler) bool {
+	h, ok := handler.(interface {
+		StreamSettings() *internet.MemoryStreamConfig
+	})
+	if !ok || h.StreamSettings() == nil || h.StreamSettings().ProtocolName != "tcp" {
+		return false
+	}
+	return tls.ConfigFromStreamSettings(h.StreamSettings()) != nil || reality.ConfigFromStreamSettings(h.StreamSettings()) != nil
+}
+
+func (s *handlerServer) TestOutbound(ctx context.Context, request *TestOutboundRequest) (*TestOutboundResponse, error) {
+	handler := s.ohm.GetHandler(request.Tag)
+	if handler == nil {
+		return nil, errors.New("handler not found: ", request.Tag).WithCode(errors.CodeNotFound)
+	}
+	dest, err := net.ParseDestination("tcp:" + request.Target)
+	if err != nil || dest.Port == 0 {
+		return nil, errors.New("invalid target: ", request.Target).WithCode(errors.CodeInvalidConfig)
+	}
+	timeout := defaultTestOutboundTimeout
+	if request.TimeoutMs > 0 {
+		timeout = time.Duration(request.TimeoutMs) * time.Millisecond
+	}
+
+	t := &outboundTest{
+		resolve:          newStageTimer(),
+		connect:          newStageTimer(),
+		handshake:        newStageTimer(),
+		reportsHandshake: reportsHandshake(handler),
+		ready:            make(chan struct{}),
+		dispatched:       make(chan struct{}),
+		errs:             make(chan error, 1),
+	}
+	start := time.Now()
+	t.run(s, handler, request, dest, timeout)
+	t.response.TotalMs = time.Since(start).Milliseconds()
+	if v, ok := t.resolve.value(); ok {
+		t.response.DnsMs = v
+	}
+	if v, ok := t.connect.value(); ok {
+		t.response.ConnectMs = v - t.response.DnsMs
+	}
+	if v, ok := t.handshake.value(); ok {
+		t.response.HandshakeMs = v
+	}
+	return &t.response, nil
+}
+
+// dial dispatches a connection to dest through the outbound handler, like the dispatcher does to the outbound of
+// a routing rule, and returns the connection of the other end of the link.
+func (t *outboundTest) dial(ctx context.Context, handler outbound.Handler, dest net.Destination) net.Conn {
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest, OriginalTarget: dest}})
+	ctx = session.ContextWithContent(ctx, &session.Content{SkipDNSResolve: true})
+
+	uplinkReader, uplinkWriter := pipe.New()
+	downlinkReader, downlinkWriter := pipe.New()
+	go func() {
+		defer close(t.dispatched)
+		handler.Dispatch(ctx, &transport.Link{Reader: &readyReader{Reader: uplinkReader, t: t}, Writer: downlinkWriter})
+	}()
+	return cnc.NewConnection(cnc.ConnectionInputMulti(uplinkWriter), cnc.ConnectionOutputMulti(downlinkReader))
+}
+
+func (t *outboundTest) run(s *handlerServer, handler outbound.Handler, request *TestOutboundRequest, dest net.Destination, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+	ctx = session.TrackedConnectionError(ctx, t)
+	ctx = session.ContextWithDialTimings(ctx, &session.DialTimings{
+		Resolve:   t.resolve,
+		Connect:   t.connect,
+		Handshake: t.handshake,
+	})
+
+	fail := func(stage string, err error) {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		t.fail(stage, err)
+	}
+
+	conn := t.dial(ctx, handler, dest)
+	defer conn.Close()
+	// unblocks the reads and writes of the stages below on timeout
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if !request.ExpectTls && !request.HttpGet {
+		if err := t.waitConnected(ctx); err != nil {
+			fail("connect", err)
+		}
+		return
+	}
+
+	if request.ExpectTls {
+		// the certificate of an IP is not verified, as only the time of the handshake matters
+		config := &gotls.Config{InsecureSkipVerify: true}
+		if dest.Address.Family().IsDomain() {
+			config = &gotls.Config{ServerName: dest.Address.Domain()}
+		}
+		tlsConn := gotls.Client(conn, config)
+		start := time.Now()
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			fail("tls", err)
+			return
+		}
+		t.response.TlsMs = time.Since(start).Milliseconds()
+		conn = tlsConn
+	}
+
+	if request.HttpGet {
+		start := time.Now()
+		if _, err := fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", dest.Address); err != nil {
+			fail("first_byte", err)
+			return
+		}
+		if _, err := conn.Read(make([]byte, 1)); err != nil {
+			fail("first_byte", err)
+			return
+		}
+		t.response.FirstByteMs = time.Since(start).Milliseconds()
+	}
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"github.com/xtls/xray-core/common/serial"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// secretFields lists the fields cleared from configs when redaction is requested.
+var secretFields = map[protoreflect.FullName]bool{
+	"xray.proxy.vmess.Account.id":                           true,
+	"xray.proxy.vless.Account.id":                           true,
+	"xray.proxy.vless.Account.encryption":                   true,
+	"xray.proxy.vless.inbound.Config.decryption":            true,
+	"xray.proxy.trojan.Account.password":                    true,
+	"xray.proxy.shadowsocks.Account.password":               true,
+	"xray.proxy.shadowsocks_2022.ServerConfig.key":          true,
+	"xray.proxy.shadowsocks_2022.MultiUserServerConfig.key": true,
+	"xray.proxy.shadowsocks_2022.RelayDestination.key":      true,
+	"xray.proxy.shadowsocks_2022.RelayServerConfig.key":     true,
+	"xray.proxy.shadowsocks_2022.Account.key":               true,
+	"xray.proxy.shadowsocks_2022.ClientConfig.key":          true,
+	"xray.proxy.socks.Account.password":                     true,
+	"xray.proxy.http.Account.password":                      true,
+	"xray.proxy.wireguard.PeerConfig.pre_shared_key":        true,
+	"xray.proxy.wireguard.DeviceConfig.secret_key":          true,
+	"xray.transport.internet.tls.Certificate.key":           true,
+	"xray.transport.internet.tls.Config.ech_server_keys":    true,
+	"xray.transport.internet.reality.Config.private_key":    true,
+	"xray.transport.internet.reality.Config.mldsa65_seed":   true,
+	"xray.transport.internet.kcp.EncryptionSeed.seed":       true,
+}
+
+// redactTypedMessage returns a copy of the TypedMessage with all secret fields cleared,
+// including those nested in other TypedMessages. Messages of unknown types are returned as is.
+func redactTypedMessage(tm *serial.TypedMessage) *serial.TypedMessage {
+	if tm == nil {
+		return nil
+	}
+	m, err := tm.GetInstance()
+	if err != nil {
+		return tm
+	}
+	redactMessage(m.ProtoReflect())
+	return serial.ToTypedMessage(m)
+}
+
+func redactMessage(m protoreflect.Message) {
+	if tm, ok := m.Interface().(*serial.TypedMessage); ok {
+		tm.Value = redactTypedMessage(tm).Value
+		return
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case secretFields[fd.FullName()]:
+			m.Clear(fd)
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					redactMessage(mv.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Message() != nil {
+				l := v.List()
+				for i := 0; i < l.Len(); i++ {
+					redactMessage(l.Get(i).Message())
+				}
+			}
+		case fd.Message() != nil:
+			redactMessage(v.Message())
+		}
+		return true
+	})
+}
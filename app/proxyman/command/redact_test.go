@@ -0,0 +1,68 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/proxy/vless"
+	"github.com/xtls/xray-core/proxy/vless/inbound"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+func TestRedactTypedMessage(t *testing.T) {
+	proxyConfig := &inbound.Config{
+		Clients: []*protocol.User{
+			{
+				Email:   "love@example.com",
+				Account: serial.ToTypedMessage(&vless.Account{Id: "27848739-7e62-4138-9fd3-098a63964b6b", Flow: "xtls-rprx-vision"}),
+			},
+		},
+		Decryption: "none",
+	}
+	receiverConfig := &proxyman.ReceiverConfig{
+		StreamSettings: &internet.StreamConfig{
+			SecurityType: serial.GetMessageType(&tls.Config{}),
+			SecuritySettings: []*serial.TypedMessage{
+				serial.ToTypedMessage(&tls.Config{
+					ServerName:  "example.com",
+					Certificate: []*tls.Certificate{{Certificate: []byte("cert"), Key: []byte("key")}},
+				}),
+			},
+		},
+	}
+
+	original := serial.ToTypedMessage(proxyConfig)
+	redacted, err := redactTypedMessage(original).GetInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := redacted.(*inbound.Config); c.Decryption != "" || c.Clients[0].Email != "love@example.com" {
+		t.Error("unexpected redacted config: ", c)
+	}
+	account, err := redacted.(*inbound.Config).Clients[0].Account.GetInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := account.(*vless.Account); a.Id != "" || a.Flow != "xtls-rprx-vision" {
+		t.Error("unexpected redacted account: ", a)
+	}
+	if v, _ := original.GetInstance(); v.(*inbound.Config).Decryption != "none" {
+		t.Error("original config is modified")
+	}
+
+	redacted, err = redactTypedMessage(serial.ToTypedMessage(receiverConfig)).GetInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	security, err := redacted.(*proxyman.ReceiverConfig).StreamSettings.SecuritySettings[0].GetInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := security.(*tls.Config).Certificate[0]
+	if len(cert.Key) != 0 || string(cert.Certificate) != "cert" {
+		t.Error("unexpected redacted certificate: ", cert)
+	}
+}
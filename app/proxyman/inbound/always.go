@@ -8,13 +8,16 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter) {
@@ -49,6 +52,9 @@ type AlwaysOnInboundHandler struct {
 	workers        []worker
 	mux            *mux.Server
 	tag            string
+	banList        *BanList
+	// handed serves the connections handed by the path handlers of other inbounds, without a listener of its own
+	handed *tcpWorker
 }
 
 func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*AlwaysOnInboundHandler, error) {
@@ -70,6 +76,17 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 	}
 
 	uplinkCounter, downlinkCounter := getStatCounter(core.MustFromContext(ctx), tag)
+	// the UDP connections of the inbounds with a level for all their users, like dokodemo-door, idle out at its udpIdle
+	var level uint32
+	if c, ok := proxyConfig.(interface{ GetUserLevel() uint32 }); ok {
+		level = c.GetUserLevel()
+	}
+	udpIdle := core.MustFromContext(ctx).GetFeature(policy.ManagerType()).(policy.Manager).ForLevel(level).Timeouts.UDPIdle
+	gate, err := newSourceGate(core.MustFromContext(ctx), tag, receiverConfig)
+	if err != nil {
+		return nil, err
+	}
+	h.banList = gate.getBanList()
 
 	nl := p.Network()
 	pl := receiverConfig.PortList
@@ -77,6 +94,11 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 	if address == nil {
 		address = net.AnyIP
 	}
+	// workers for every address share the proxy, the mux dispatcher, the counters and the source gate
+	addresses := []net.Address{address}
+	for _, listen := range receiverConfig.ExtraListen {
+		addresses = append(addresses, listen.AsAddress())
+	}
 
 	mss, err := internet.ToMemoryStreamConfig(receiverConfig.StreamSettings)
 	if err != nil {
@@ -92,7 +114,22 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 		}
 		mss.SocketSettings.ReceiveOriginalDestAddress = true
 	}
-	if pl == nil {
+	if net.HasNetwork(nl, net.Network_TCP) || net.HasNetwork(nl, net.Network_UNIX) {
+		h.handed = &tcpWorker{
+			address:         address,
+			proxy:           p,
+			stream:          mss,
+			tag:             tag,
+			dispatcher:      h.mux,
+			sniffingConfig:  receiverConfig.SniffingSettings,
+			uplinkCounter:   uplinkCounter,
+			downlinkCounter: downlinkCounter,
+			sourceGate:      gate,
+			ctx:             ctx,
+		}
+	}
+	// without a port, an inbound listens on a Unix domain socket, or serves only the connections handed to it
+	if pl == nil && address.Family().IsDomain() {
 		if net.HasNetwork(nl, net.Network_UNIX) {
 			errors.LogDebug(ctx, "creating unix domain socket worker on ", address)
 
@@ -110,42 +147,63 @@ func NewAlwaysOnInboundHandler(ctx context.Context, tag string, receiverConfig *
 			h.workers = append(h.workers, worker)
 		}
 	}
+	if d, ok := p.(proxy.DeviceInbound); ok {
+		errors.LogDebug(ctx, "creating device worker for ", tag)
+
+		worker := &deviceWorker{
+			proxy:           d,
+			stream:          mss,
+			tag:             tag,
+			dispatcher:      h.mux,
+			sniffingConfig:  receiverConfig.SniffingSettings,
+			uplinkCounter:   uplinkCounter,
+			downlinkCounter: downlinkCounter,
+			ctx:             ctx,
+		}
+		h.workers = append(h.workers, worker)
+	}
 	if pl != nil {
-		for _, pr := range pl.Range {
-			for port := pr.From; port <= pr.To; port++ {
-				if net.HasNetwork(nl, net.Network_TCP) {
-					errors.LogDebug(ctx, "creating stream worker on ", address, ":", port)
-
-					worker := &tcpWorker{
-						address:         address,
-						port:            net.Port(port),
-						proxy:           p,
-						stream:          mss,
-						recvOrigDest:    receiverConfig.ReceiveOriginalDestination,
-						tag:             tag,
-						dispatcher:      h.mux,
-						sniffingConfig:  receiverConfig.SniffingSettings,
-						uplinkCounter:   uplinkCounter,
-						downlinkCounter: downlinkCounter,
-						ctx:             ctx,
+		for _, address := range addresses {
+			for _, pr := range pl.Range {
+				for port := pr.From; port <= pr.To; port++ {
+					if net.HasNetwork(nl, net.Network_TCP) {
+						errors.LogDebug(ctx, "creating stream worker on ", address, ":", port)
+
+						worker := &tcpWorker{
+							address:         address,
+							port:            net.Port(port),
+							proxy:           p,
+							stream:          mss,
+							recvOrigDest:    receiverConfig.ReceiveOriginalDestination,
+							tag:             tag,
+							dispatcher:      h.mux,
+							sniffingConfig:  receiverConfig.SniffingSettings,
+							uplinkCounter:   uplinkCounter,
+							downlinkCounter: downlinkCounter,
+							sourceGate:      gate,
+							ctx:             ctx,
+						}
+						h.workers = append(h.workers, worker)
 					}
-					h.workers = append(h.workers, worker)
-				}
 
-				if net.HasNetwork(nl, net.Network_UDP) {
-					worker := &udpWorker{
-						tag:             tag,
-						proxy:           p,
-						address:         address,
-						port:            net.Port(port),
-						dispatcher:      h.mux,
-						sniffingConfig:  receiverConfig.SniffingSettings,
-						uplinkCounter:   uplinkCounter,
-						downlinkCounter: downlinkCounter,
-						stream:          mss,
-						ctx:             ctx,
+					if net.HasNetwork(nl, net.Network_UDP) {
+						worker := &udpWorker{
+							tag:             tag,
+							proxy:           p,
+							address:         address,
+							port:            net.Port(port),
+							dispatcher:      h.mux,
+							sniffingConfig:  receiverConfig.SniffingSettings,
+							uplinkCounter:   uplinkCounter,
+							downlinkCounter: downlinkCounter,
+							sourceGate:      gate,
+							stream:          mss,
+							ctx:             ctx,
+							poolSize:        receiverConfig.UdpWorkers,
+							idle:            udpIdle,
+						}
+						h.workers = append(h.workers, worker)
 					}
-					h.workers = append(h.workers, worker)
 				}
 			}
 		}
@@ -177,10 +235,86 @@ func (h *AlwaysOnInboundHandler) Close() error {
 	return nil
 }
 
+// Pause stops the handler accepting connections, while the accepted ones go on.
+func (h *AlwaysOnInboundHandler) Pause() error {
+	var errs []error
+	for _, worker := range h.workers {
+		errs = append(errs, worker.Pause())
+	}
+	if err := errors.Combine(errs...); err != nil {
+		return errors.New("failed to pause all workers").Base(err)
+	}
+	return nil
+}
+
+// Resume accepts connections again, with the same settings as before Pause.
+func (h *AlwaysOnInboundHandler) Resume() error {
+	var errs []error
+	for _, worker := range h.workers {
+		errs = append(errs, worker.Resume())
+	}
+	if err := errors.Combine(errs...); err != nil {
+		return errors.New("failed to resume all workers").Base(err)
+	}
+	return nil
+}
+
+// ReportResources implements stats.ResourceReporter.
+func (h *AlwaysOnInboundHandler) ReportResources(report *stats.ResourceReport) {
+	res := report.Inbound(h.tag)
+	for _, worker := range h.workers {
+		if w, ok := worker.(*udpWorker); ok {
+			res.UDPNATEntries += int64(w.natEntries())
+		}
+	}
+	res.MuxStreams += int64(h.mux.Streams())
+}
+
+// NatTable returns the mappings of the UDP NAT tables of the handler, and the number of the packets dropped
+// as their original destinations could not be recovered.
+func (h *AlwaysOnInboundHandler) NatTable() ([]*NatMapping, int64) {
+	var mappings []*NatMapping
+	var unrecovered int64
+	for _, worker := range h.workers {
+		if w, ok := worker.(*udpWorker); ok {
+			m, n := w.natMappings()
+			mappings = append(mappings, m...)
+			unrecovered += n
+		}
+	}
+	return mappings, unrecovered
+}
+
+// CollectIdle implements stats.IdleCollector.
+func (h *AlwaysOnInboundHandler) CollectIdle() int {
+	collected := 0
+	for _, worker := range h.workers {
+		if w, ok := worker.(stats.IdleCollector); ok {
+			collected += w.CollectIdle()
+		}
+	}
+	return collected
+}
+
+// HandleConnection serves conn, accepted by the listener of another inbound, as a stream worker of the handler
+// would, with the counters and the source gate of the handler. The handler needs no listener of its own for it.
+func (h *AlwaysOnInboundHandler) HandleConnection(conn stat.Connection) error {
+	if h.handed == nil {
+		return errors.New("inbound ", h.tag, " serves no streams")
+	}
+	h.handed.accept(conn)
+	return nil
+}
+
 func (h *AlwaysOnInboundHandler) Tag() string {
 	return h.tag
 }
 
+// BanList returns the sources banned by the handler, or nil if it bans none.
+func (h *AlwaysOnInboundHandler) BanList() *BanList {
+	return h.banList
+}
+
 func (h *AlwaysOnInboundHandler) GetInbound() proxy.Inbound {
 	return h.proxy
 }
@@ -190,10 +324,36 @@ func (h *AlwaysOnInboundHandler) ReceiverSettings() *serial.TypedMessage {
 	return serial.ToTypedMessage(h.receiverConfig)
 }
 
-// ProxySettings implements inbound.Handler.
+// ProxySettings implements inbound.Handler. The users are those the proxy has now,
+// which differ from the config once users are added or removed.
 func (h *AlwaysOnInboundHandler) ProxySettings() *serial.TypedMessage {
-	if v, ok := h.proxyConfig.(proto.Message); ok {
-		return serial.ToTypedMessage(v)
+	v, ok := h.proxyConfig.(proto.Message)
+	if !ok {
+		return nil
 	}
-	return nil
+	if um, ok := h.proxy.(proxy.UserManager); ok {
+		v = withUsers(v, um.GetUsers(context.Background()))
+	}
+	return serial.ToTypedMessage(v)
+}
+
+var userType = (*protocol.User)(nil).ProtoReflect().Descriptor().FullName()
+
+// withUsers returns a copy of config whose list of users, whatever its name, holds users instead.
+func withUsers(config proto.Message, users []*protocol.MemoryUser) proto.Message {
+	config = proto.Clone(config)
+	m := config.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !fd.IsList() || fd.Message() == nil || fd.Message().FullName() != userType {
+			continue
+		}
+		list := m.Mutable(fd).List()
+		list.Truncate(0)
+		for _, u := range users {
+			list.Append(protoreflect.ValueOfMessage(protocol.ToProtoUser(u).ProtoReflect()))
+		}
+	}
+	return config
 }
@@ -0,0 +1,172 @@
+package inbound
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const (
+	defaultBanThreshold  = 5
+	defaultBanWindow     = time.Minute
+	defaultBanDuration   = 10 * time.Minute
+	defaultBanMaxSources = 10000
+)
+
+// Ban is a source banned by an inbound.
+type Ban struct {
+	IP     net.IP
+	Expire time.Time
+}
+
+type banSource struct {
+	// failures within the window, at most threshold of them
+	failures []time.Time
+	until    time.Time
+}
+
+// BanList bans the sources that fail the authentication of an inbound too often within a sliding window.
+// It tracks a bounded number of sources, the ones beyond it are not counted until others are done.
+type BanList struct {
+	sync.Mutex
+	threshold  int
+	window     time.Duration
+	duration   time.Duration
+	maxSources int
+	exempt     router.GeoIPMatcher
+	fallback   bool
+	sources    map[string]*banSource
+}
+
+// newBanList returns the ban list of config, or nil if there is no config.
+func newBanList(config *proxyman.AutoBanConfig) (*BanList, error) {
+	if config == nil {
+		return nil, nil
+	}
+	l := &BanList{
+		threshold:  int(config.Threshold),
+		window:     time.Duration(config.Window) * time.Second,
+		duration:   time.Duration(config.Duration) * time.Second,
+		maxSources: int(config.MaxSources),
+		fallback:   config.Fallback,
+		sources:    make(map[string]*banSource),
+	}
+	if l.threshold == 0 {
+		l.threshold = defaultBanThreshold
+	}
+	if l.window == 0 {
+		l.window = defaultBanWindow
+	}
+	if l.duration == 0 {
+		l.duration = defaultBanDuration
+	}
+	if l.maxSources == 0 {
+		l.maxSources = defaultBanMaxSources
+	}
+	if len(config.Exempt) > 0 {
+		m, err := router.BuildOptimizedGeoIPMatcher(config.Exempt...)
+		if err != nil {
+			return nil, errors.New("failed to build the exempt sources of auto ban").Base(err)
+		}
+		l.exempt = m
+	}
+	return l, nil
+}
+
+// Banned returns whether the source is banned. A nil list bans no source.
+func (l *BanList) Banned(source net.Address) bool {
+	if l == nil || !source.Family().IsIP() {
+		return false
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	s := l.sources[source.IP().String()]
+	return s != nil && time.Now().Before(s.until)
+}
+
+// ObserveAuthFailure implements session.AuthFailureObserver.
+func (l *BanList) ObserveAuthFailure(source net.Address) {
+	if !source.Family().IsIP() || (l.exempt != nil && l.exempt.Match(source.IP())) {
+		return
+	}
+	now := time.Now()
+	key := source.IP().String()
+
+	l.Lock()
+	defer l.Unlock()
+
+	s := l.sources[key]
+	if s == nil {
+		if len(l.sources) >= l.maxSources {
+			l.cleanup(now)
+			if len(l.sources) >= l.maxSources {
+				return
+			}
+		}
+		s = new(banSource)
+		l.sources[key] = s
+	}
+	if now.Before(s.until) {
+		return
+	}
+	failures := s.failures[:0]
+	for _, t := range s.failures {
+		if now.Sub(t) < l.window {
+			failures = append(failures, t)
+		}
+	}
+	s.failures = append(failures, now)
+	if len(s.failures) >= l.threshold {
+		s.failures = nil
+		s.until = now.Add(l.duration)
+		errors.LogInfo(context.Background(), "banned ", key, " for ", l.duration, " after ", l.threshold, " auth failures")
+	}
+}
+
+// cleanup removes the sources that are neither banned nor have failures within the window.
+func (l *BanList) cleanup(now time.Time) {
+	for key, s := range l.sources {
+		if !now.Before(s.until) && (len(s.failures) == 0 || now.Sub(s.failures[len(s.failures)-1]) >= l.window) {
+			delete(l.sources, key)
+		}
+	}
+}
+
+// Bans returns the banned sources, sorted by IP.
+func (l *BanList) Bans() []Ban {
+	now := time.Now()
+	l.Lock()
+	defer l.Unlock()
+
+	var bans []Ban
+	for key, s := range l.sources {
+		if now.Before(s.until) {
+			bans = append(bans, Ban{IP: net.ParseIP(key), Expire: s.until})
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].IP.String() < bans[j].IP.String()
+	})
+	return bans
+}
+
+// Clear unbans the IPs, and forgets their failures. No IP clears all of the sources.
+func (l *BanList) Clear(ips ...net.IP) {
+	l.Lock()
+	defer l.Unlock()
+
+	if len(ips) == 0 {
+		l.sources = make(map[string]*banSource)
+		return
+	}
+	for _, ip := range ips {
+		delete(l.sources, ip.String())
+	}
+}
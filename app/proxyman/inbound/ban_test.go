@@ -0,0 +1,58 @@
+package inbound
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestBanList(t *testing.T) {
+	l, err := newBanList(&proxyman.AutoBanConfig{
+		Threshold:  3,
+		Exempt:     []*router.GeoIP{{Cidr: []*router.CIDR{{Ip: net.ParseAddress("192.0.2.0").IP(), Prefix: 24}}}},
+		MaxSources: 2,
+	})
+	common.Must(err)
+
+	attacker := net.ParseAddress("198.51.100.1")
+	monitor := net.ParseAddress("192.0.2.7")
+	for i := 0; i < 3; i++ {
+		if l.Banned(attacker) {
+			t.Fatal("banned after ", i, " failures")
+		}
+		l.ObserveAuthFailure(attacker)
+		l.ObserveAuthFailure(monitor)
+	}
+	if !l.Banned(attacker) {
+		t.Error("expected the attacker to be banned")
+	}
+	if l.Banned(monitor) {
+		t.Error("expected the exempt source not to be banned")
+	}
+
+	// the table is bounded, the third source is not tracked while the other two are active
+	l.ObserveAuthFailure(net.ParseAddress("198.51.100.2"))
+	for i := 0; i < 3; i++ {
+		l.ObserveAuthFailure(net.ParseAddress("198.51.100.3"))
+	}
+	if l.Banned(net.ParseAddress("198.51.100.3")) {
+		t.Error("expected the source beyond the bound not to be tracked")
+	}
+
+	bans := l.Bans()
+	if len(bans) != 1 || !bans[0].IP.Equal(attacker.IP()) {
+		t.Error("unexpected bans ", bans)
+	}
+	l.Clear(net.ParseIP("198.51.100.1"))
+	if l.Banned(attacker) || len(l.Bans()) != 0 {
+		t.Error("expected the ban to be cleared")
+	}
+
+	var nilList *BanList
+	if nilList.Banned(attacker) {
+		t.Error("expected a nil list to ban nothing")
+	}
+}
@@ -16,16 +16,24 @@ import (
 
 // Manager manages all inbound handlers.
 type Manager struct {
-	access          sync.RWMutex
+	access           sync.RWMutex
 	untaggedHandlers []inbound.Handler
-	taggedHandlers  map[string]inbound.Handler
-	running         bool
+	taggedHandlers   map[string]inbound.Handler
+	paused           map[string]bool
+	running          bool
+}
+
+// pausable is a handler which stops accepting connections while it is paused.
+type pausable interface {
+	Pause() error
+	Resume() error
 }
 
 // New returns a new Manager for inbound handlers.
 func New(ctx context.Context, config *proxyman.InboundConfig) (*Manager, error) {
 	m := &Manager{
 		taggedHandlers: make(map[string]inbound.Handler),
+		paused:         make(map[string]bool),
 	}
 	return m, nil
 }
@@ -43,7 +51,7 @@ func (m *Manager) AddHandler(ctx context.Context, handler inbound.Handler) error
 	tag := handler.Tag()
 	if len(tag) > 0 {
 		if _, found := m.taggedHandlers[tag]; found {
-			return errors.New("existing tag found: " + tag)
+			return errors.New("existing tag found: " + tag).WithCode(errors.CodeAlreadyExists)
 		}
 		m.taggedHandlers[tag] = handler
 	} else {
@@ -64,7 +72,7 @@ func (m *Manager) GetHandler(ctx context.Context, tag string) (inbound.Handler,
 
 	handler, found := m.taggedHandlers[tag]
 	if !found {
-		return nil, errors.New("handler not found: ", tag)
+		return nil, errors.New("handler not found: ", tag).WithCode(errors.CodeNotFound)
 	}
 	return handler, nil
 }
@@ -83,12 +91,64 @@ func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 			errors.LogWarningInner(ctx, err, "failed to close handler ", tag)
 		}
 		delete(m.taggedHandlers, tag)
+		delete(m.paused, tag)
 		return nil
 	}
 
 	return common.ErrNoClue
 }
 
+// PauseHandler stops the handler with the given tag accepting connections.
+// The handler keeps its users and the accepted connections until ResumeHandler.
+func (m *Manager) PauseHandler(ctx context.Context, tag string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	handler, found := m.taggedHandlers[tag]
+	if !found {
+		return errors.New("handler not found: ", tag).WithCode(errors.CodeNotFound)
+	}
+	if m.paused[tag] {
+		return nil
+	}
+	h, ok := handler.(pausable)
+	if !ok {
+		return errors.New("unable to pause handler: ", tag).WithCode(errors.CodeUnimplemented)
+	}
+	if err := h.Pause(); err != nil {
+		return err
+	}
+	m.paused[tag] = true
+	return nil
+}
+
+// ResumeHandler makes the handler with the given tag accept connections again, with the same settings.
+func (m *Manager) ResumeHandler(ctx context.Context, tag string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	handler, found := m.taggedHandlers[tag]
+	if !found {
+		return errors.New("handler not found: ", tag).WithCode(errors.CodeNotFound)
+	}
+	if !m.paused[tag] {
+		return nil
+	}
+	if err := handler.(pausable).Resume(); err != nil {
+		return err
+	}
+	delete(m.paused, tag)
+	return nil
+}
+
+// IsPaused returns whether the handler with the given tag is paused.
+func (m *Manager) IsPaused(tag string) bool {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	return m.paused[tag]
+}
+
 // ListHandlers implements inbound.Manager.
 func (m *Manager) ListHandlers(ctx context.Context) []inbound.Handler {
 	m.access.RLock()
@@ -0,0 +1,155 @@
+package inbound
+
+import (
+	"context"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport/internet"
+)
+
+// sourceFilter drops the connections, or the packets for UDP, of the sources that an inbound does not allow,
+// before a handler goroutine is spent on them.
+type sourceFilter struct {
+	allow router.GeoIPMatcher
+	deny  router.GeoIPMatcher
+}
+
+// newSourceFilter returns the filter of config, or nil if it filters nothing.
+func newSourceFilter(config *proxyman.SourceIPConfig) (*sourceFilter, error) {
+	if len(config.GetAllow()) == 0 && len(config.GetDeny()) == 0 {
+		return nil, nil
+	}
+	f := new(sourceFilter)
+	if len(config.Allow) > 0 {
+		m, err := router.BuildOptimizedGeoIPMatcher(config.Allow...)
+		if err != nil {
+			return nil, errors.New("failed to build the allowed source IPs").Base(err)
+		}
+		f.allow = m
+	}
+	if len(config.Deny) > 0 {
+		m, err := router.BuildOptimizedGeoIPMatcher(config.Deny...)
+		if err != nil {
+			return nil, errors.New("failed to build the denied source IPs").Base(err)
+		}
+		f.deny = m
+	}
+	return f, nil
+}
+
+// Allows returns whether the source is allowed. A nil filter allows any source.
+func (f *sourceFilter) Allows(source net.Address) bool {
+	if f == nil || !source.Family().IsIP() {
+		return true
+	}
+	ip := source.IP()
+	return (f.allow == nil || f.allow.Match(ip)) && (f.deny == nil || !f.deny.Match(ip))
+}
+
+// sourceGate holds what an inbound checks the sources against before handling their connections.
+type sourceGate struct {
+	filter  *sourceFilter
+	banList *BanList
+	// trusted are the peers whose X-Forwarded-For is taken as the source.
+	trusted  router.GeoIPMatcher
+	rejected stats.Counter
+}
+
+// newSourceGate returns the gate of the receiver config, or nil if it lets any source through.
+func newSourceGate(v *core.Instance, tag string, config *proxyman.ReceiverConfig) (*sourceGate, error) {
+	filter, err := newSourceFilter(config.SourceIps)
+	if err != nil {
+		return nil, err
+	}
+	banList, err := newBanList(config.AutoBan)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil && banList == nil {
+		return nil, nil
+	}
+	g := &sourceGate{
+		filter:  filter,
+		banList: banList,
+	}
+	if trusted := config.SourceIps.GetTrustedProxies(); len(trusted) > 0 {
+		m, err := router.BuildOptimizedGeoIPMatcher(trusted...)
+		if err != nil {
+			return nil, errors.New("failed to build the trusted proxies").Base(err)
+		}
+		g.trusted = m
+	}
+	if len(tag) > 0 {
+		statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+		c, _ := stats.GetOrRegisterCounter(statsManager, "inbound>>>"+tag+">>>rejected")
+		if c != nil {
+			g.rejected = c
+		}
+	}
+	return g, nil
+}
+
+// Check returns whether the source may go on, and whether it is banned but let through to the fallbacks,
+// if the connection can fall back. A nil gate lets any source through.
+func (g *sourceGate) Check(ctx context.Context, source net.Address, canFallback bool) (pass bool, banned bool) {
+	if g == nil {
+		return true, false
+	}
+	if !g.filter.Allows(source) {
+		g.reject(ctx, source, "not allowed")
+		return false, false
+	}
+	if g.banList.Banned(source) {
+		if g.banList.fallback && canFallback {
+			return true, true
+		}
+		g.reject(ctx, source, "banned")
+		return false, true
+	}
+	return true, false
+}
+
+// sourceOf returns the address that conn is checked by. It is source, unless source is forwarded
+// by a peer that is not a trusted proxy, as anyone can send X-Forwarded-For. Then it is the peer.
+func (g *sourceGate) sourceOf(conn net.Conn, source net.Address) net.Address {
+	if g == nil {
+		return source
+	}
+	addr, ok := internet.PeerAddr(conn)
+	if !ok {
+		return source
+	}
+	peer := net.DestinationFromAddr(addr).Address
+	if g.trusted != nil && peer.Family().IsIP() && g.trusted.Match(peer.IP()) {
+		return source
+	}
+	return peer
+}
+
+func (g *sourceGate) reject(ctx context.Context, source net.Address, reason string) {
+	if g.rejected != nil {
+		g.rejected.Add(1)
+	}
+	errors.LogDebug(ctx, "rejected the source ", source, ": ", reason)
+}
+
+func (g *sourceGate) getBanList() *BanList {
+	if g == nil {
+		return nil
+	}
+	return g.banList
+}
+
+// contextWithBanList lets the auth failures of the connections in ctx count towards the bans of the gate.
+func (g *sourceGate) contextWithBanList(ctx context.Context) context.Context {
+	if g == nil || g.banList == nil {
+		return ctx
+	}
+	return session.ContextWithAuthFailureObserver(ctx, g.banList)
+}
@@ -0,0 +1,87 @@
+package inbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestSourceFilter(t *testing.T) {
+	cidr := func(ip string, prefix uint32) *router.GeoIP {
+		return &router.GeoIP{Cidr: []*router.CIDR{{Ip: net.ParseAddress(ip).IP(), Prefix: prefix}}}
+	}
+	f, err := newSourceFilter(&proxyman.SourceIPConfig{
+		Allow: []*router.GeoIP{cidr("10.0.0.0", 8)},
+		Deny:  []*router.GeoIP{cidr("10.0.0.1", 32)},
+	})
+	common.Must(err)
+
+	for _, c := range []struct {
+		source string
+		allows bool
+	}{
+		{"10.1.2.3", true},
+		{"10.0.0.1", false},
+		{"192.0.2.1", false},
+		{"example.com", true},
+	} {
+		if allows := f.Allows(net.ParseAddress(c.source)); allows != c.allows {
+			t.Error("expected ", c.allows, " for ", c.source, ", but got ", allows)
+		}
+	}
+
+	f, err = newSourceFilter(&proxyman.SourceIPConfig{})
+	common.Must(err)
+	if f != nil || !f.Allows(net.ParseAddress("192.0.2.1")) {
+		t.Error("expected no filter")
+	}
+}
+
+type forwardedConn struct {
+	net.Conn
+	remote net.Addr
+	peer   net.Addr
+}
+
+func (c *forwardedConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *forwardedConn) PeerAddr() net.Addr {
+	return c.peer
+}
+
+func TestSourceGateForwarded(t *testing.T) {
+	cidr := func(ip string, prefix uint32) *router.GeoIP {
+		return &router.GeoIP{Cidr: []*router.CIDR{{Ip: net.ParseAddress(ip).IP(), Prefix: prefix}}}
+	}
+	g, err := newSourceGate(nil, "", &proxyman.ReceiverConfig{
+		SourceIps: &proxyman.SourceIPConfig{
+			Allow:          []*router.GeoIP{cidr("10.0.0.0", 8)},
+			TrustedProxies: []*router.GeoIP{cidr("192.0.2.0", 24)},
+		},
+	})
+	common.Must(err)
+
+	for _, c := range []struct {
+		name string
+		peer string
+		pass bool
+	}{
+		{"trusted proxy", "192.0.2.1", true},
+		{"spoofed", "198.51.100.1", false},
+	} {
+		conn := &forwardedConn{
+			remote: &net.TCPAddr{IP: net.ParseAddress("10.1.2.3").IP()},
+			peer:   &net.TCPAddr{IP: net.ParseAddress(c.peer).IP(), Port: 443},
+		}
+		source := net.DestinationFromAddr(conn.RemoteAddr()).Address
+		if pass, _ := g.Check(context.Background(), g.sourceOf(conn, source), false); pass != c.pass {
+			t.Error(c.name, ": expected ", c.pass, ", but got ", pass)
+		}
+	}
+}
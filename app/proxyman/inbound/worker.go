@@ -2,7 +2,9 @@ package inbound
 
 import (
 	"context"
+	"hash/maphash"
 	gonet "net"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,10 +15,14 @@ import (
 	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
+	udp_proto "github.com/xtls/xray-core/common/protocol/udp"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy"
@@ -30,10 +36,19 @@ import (
 type worker interface {
 	Start() error
 	Close() error
+	// Pause stops accepting connections, while the accepted ones go on until Resume.
+	Pause() error
+	Resume() error
 	Port() net.Port
 	Proxy() proxy.Inbound
 }
 
+// connectionHandler is an inbound handler that serves the connections handed to it by the listeners of other
+// inbounds.
+type connectionHandler interface {
+	HandleConnection(conn stat.Connection) error
+}
+
 type tcpWorker struct {
 	address         net.Address
 	port            net.Port
@@ -45,8 +60,12 @@ type tcpWorker struct {
 	sniffingConfig  *proxyman.SniffingConfig
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	sourceGate      *sourceGate
 
 	hub internet.Listener
+	// the connections accepted while paused are closed at once, as closing the listeners of some transports,
+	// like gRPC, would close the connections accepted before as well
+	paused atomic.Bool
 
 	ctx context.Context
 }
@@ -58,10 +77,28 @@ func getTProxyType(s *internet.MemoryStreamConfig) internet.SocketConfig_TProxyM
 	return s.SocketSettings.Tproxy
 }
 
-func (w *tcpWorker) callback(conn stat.Connection) {
+// contextWithPipeWatermarks applies the pipe watermarks of the sockopt of s, if any, to the links of the connection.
+func contextWithPipeWatermarks(ctx context.Context, s *internet.MemoryStreamConfig) context.Context {
+	if s == nil || s.SocketSettings.GetPipeHighWatermark() <= 0 {
+		return ctx
+	}
+	return pipe.ContextWithWatermarks(ctx, s.SocketSettings.PipeHighWatermark, s.SocketSettings.PipeLowWatermark)
+}
+
+// connAddrs returns the source and local destinations of conn, which are the ones in the PROXY protocol header
+// if the transport accepted conn with it, over TCP and Unix domain sockets alike.
+func connAddrs(conn stat.Connection) (source net.Destination, local net.Destination) {
+	if src, dst, ok := internet.ProxyProtocolAddrs(conn); ok {
+		return net.DestinationFromAddr(src), net.DestinationFromAddr(dst)
+	}
+	return net.DestinationFromAddr(conn.RemoteAddr()), net.DestinationFromAddr(conn.LocalAddr())
+}
+
+func (w *tcpWorker) callback(conn stat.Connection, banned bool) {
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = c.ContextWithID(ctx, sid)
+	ctx = w.sourceGate.contextWithBanList(ctx)
 
 	outbounds := []*session.Outbound{{}}
 	if w.recvOrigDest {
@@ -100,6 +137,7 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 		}
 	}
 	ctx = session.ContextWithOutbounds(ctx, outbounds)
+	ctx = contextWithPipeWatermarks(ctx, w.stream)
 
 	if w.uplinkCounter != nil || w.downlinkCounter != nil {
 		conn = &stat.CounterConnection{
@@ -108,12 +146,14 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 			WriteCounter: w.downlinkCounter,
 		}
 	}
+	source, local := connAddrs(conn)
 	ctx = session.ContextWithInbound(ctx, &session.Inbound{
-		Source:  net.DestinationFromAddr(conn.RemoteAddr()),
-		Local:   net.DestinationFromAddr(conn.LocalAddr()),
+		Source:  source,
+		Local:   local,
 		Gateway: net.TCPDestination(w.address, w.port),
 		Tag:     w.tag,
 		Conn:    conn,
+		Banned:  banned,
 	})
 
 	content := new(session.Content)
@@ -123,6 +163,13 @@ func (w *tcpWorker) callback(conn stat.Connection) {
 		content.SniffingRequest.ExcludeForDomain = w.sniffingConfig.DomainsExcluded
 		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
 		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+		content.SniffingRequest.Timeout = time.Duration(w.sniffingConfig.TimeoutMs) * time.Millisecond
+		content.SniffingRequest.BufferSize = int32(w.sniffingConfig.BufferSize)
+		content.SniffingRequest.UDPPackets = int(w.sniffingConfig.UdpPackets)
+		content.SniffingRequest.Resniff = w.sniffingConfig.Resniff
+		if ports := w.sniffingConfig.DestinationOverridePorts; ports != nil {
+			content.SniffingRequest.Ports = net.PortListFromProto(ports)
+		}
 	}
 	ctx = session.ContextWithContent(ctx, content)
 
@@ -137,10 +184,57 @@ func (w *tcpWorker) Proxy() proxy.Inbound {
 	return w.proxy
 }
 
+// accept serves conn if its source passes the source gate, unless the worker is paused.
+func (w *tcpWorker) accept(conn stat.Connection) {
+	if w.paused.Load() {
+		conn.Close()
+		return
+	}
+	source, _ := connAddrs(conn)
+	pass, banned := w.sourceGate.Check(w.ctx, w.sourceGate.sourceOf(conn, source.Address), true)
+	if !pass {
+		conn.Close()
+		return
+	}
+	go w.callback(conn, banned)
+}
+
+// handOver hands conn to the inbound that a path handler of the listener has picked for it, and returns false
+// if conn is not picked by any.
+func handOver(ctx context.Context, conn stat.Connection) bool {
+	handed, ok := conn.(*internet.HandedConnection)
+	if !ok {
+		return false
+	}
+	var err error = errors.New("no inbound manager to hand the connection to")
+	if m, ok := core.MustFromContext(ctx).GetFeature(inbound.ManagerType()).(inbound.Manager); ok {
+		var handler inbound.Handler
+		if handler, err = m.GetHandler(ctx, handed.Tag); err == nil {
+			if h, ok := handler.(connectionHandler); ok {
+				err = h.HandleConnection(handed.Connection)
+			} else {
+				err = errors.New("inbound ", handed.Tag, " accepts no connections from other inbounds")
+			}
+		}
+	}
+	if err != nil {
+		errors.LogWarningInner(ctx, err, "failed to hand the connection to ", handed.Tag)
+		handed.Close()
+	}
+	return true
+}
+
 func (w *tcpWorker) Start() error {
 	ctx := context.Background()
 	hub, err := internet.ListenTCP(ctx, w.address, w.port, w.stream, func(conn stat.Connection) {
-		go w.callback(conn)
+		if w.paused.Load() {
+			conn.Close()
+			return
+		}
+		if handOver(w.ctx, conn) {
+			return
+		}
+		w.accept(conn)
 	})
 	if err != nil {
 		return errors.New("failed to listen TCP on ", w.port).AtWarning().Base(err)
@@ -166,6 +260,16 @@ func (w *tcpWorker) Close() error {
 	return nil
 }
 
+func (w *tcpWorker) Pause() error {
+	w.paused.Store(true)
+	return nil
+}
+
+func (w *tcpWorker) Resume() error {
+	w.paused.Store(false)
+	return nil
+}
+
 func (w *tcpWorker) Port() net.Port {
 	return w.port
 }
@@ -175,6 +279,7 @@ type udpConn struct {
 	reader           buf.Reader
 	writer           buf.Writer
 	output           func([]byte) (int, error)
+	outputMulti      func(buf.MultiBuffer) error
 	remote           net.Addr
 	local            net.Addr
 	done             *done.Instance
@@ -182,6 +287,12 @@ type udpConn struct {
 	downlink         stats.Counter
 	inactive         bool
 	cancel           context.CancelFunc
+
+	// outbound is the outbound of the connection, whose tag is kept once it is routed.
+	outbound    *session.Outbound
+	outboundTag atomic.Pointer[string]
+	// natIDs are the mappings of the NAT table through the connection
+	natIDs []connID
 }
 
 func (c *udpConn) setInactive() {
@@ -203,10 +314,24 @@ func (c *udpConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
 	if c.uplink != nil {
 		c.uplink.Add(int64(mb.Len()))
 	}
+	// the payload is read by the outbound only after the connection is routed, beside sniffing
+	if c.outbound != nil && c.outboundTag.Load() == nil {
+		if tag := c.outbound.Tag; tag != "" {
+			c.outboundTag.Store(&tag)
+		}
+	}
 
 	return mb, nil
 }
 
+// getOutboundTag returns the tag of the outbound of the connection, or empty if it is not routed yet.
+func (c *udpConn) getOutboundTag() string {
+	if tag := c.outboundTag.Load(); tag != nil {
+		return *tag
+	}
+	return ""
+}
+
 func (c *udpConn) Read(buf []byte) (int, error) {
 	panic("not implemented")
 }
@@ -223,6 +348,20 @@ func (c *udpConn) Write(buf []byte) (int, error) {
 	return n, err
 }
 
+// WriteMultiBuffer implements buf.Writer. Each buffer is written as a datagram.
+func (c *udpConn) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	n := mb.Len()
+	err := c.outputMulti(mb)
+	if err != nil {
+		return err
+	}
+	if c.downlink != nil {
+		c.downlink.Add(int64(n))
+	}
+	c.updateActivity()
+	return nil
+}
+
 func (c *udpConn) Close() error {
 	if c.cancel != nil {
 		c.cancel()
@@ -257,6 +396,22 @@ type connID struct {
 	dest net.Destination
 }
 
+// natEntry is a mapping of the NAT table, from a source to an original destination, through its connection.
+type natEntry struct {
+	conn     *udpConn
+	created  int64
+	lastSeen int64
+}
+
+// NatMapping is a mapping of the UDP NAT table of an inbound.
+type NatMapping struct {
+	Source              net.Destination
+	OriginalDestination net.Destination
+	OutboundTag         string
+	Created             time.Time
+	LastSeen            time.Time
+}
+
 type udpWorker struct {
 	sync.RWMutex
 
@@ -270,22 +425,39 @@ type udpWorker struct {
 	sniffingConfig  *proxyman.SniffingConfig
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	sourceGate      *sourceGate
 
 	checker    *task.Periodic
 	activeConn map[connID]*udpConn
+	natTable   map[connID]*natEntry
+	// the socket receives the original destinations, so a packet without one can not be sent correctly
+	recvOrigDest bool
+	unrecovered  atomic.Int64
+	// number of the goroutines handling the packets, or GOMAXPROCS if not positive
+	poolSize uint32
 
 	ctx  context.Context
 	cone bool
+	// the connections idle for longer are closed, or after the default UDP idle timeout of the policy if not positive
+	idle time.Duration
+	// the socket is shared by the connections, so pausing only drops the packets of new ones
+	paused atomic.Bool
 }
 
-func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
+// getConnection returns the connection of id, and whether it exists, or nil if it does not while paused.
+// The packet to originalDest, if valid, is recorded in the NAT table.
+func (w *udpWorker) getConnection(id connID, originalDest net.Destination) (*udpConn, bool) {
 	w.Lock()
 	defer w.Unlock()
 
 	if conn, found := w.activeConn[id]; found && !conn.done.Done() {
 		conn.updateActivity()
+		w.trackNat(conn, connID{src: id.src, dest: originalDest})
 		return conn, true
 	}
+	if w.paused.Load() {
+		return nil, false
+	}
 
 	pReader, pWriter := pipe.New(pipe.DiscardOverflow(), pipe.WithSizeLimit(16*1024))
 	conn := &udpConn{
@@ -294,6 +466,9 @@ func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
 		output: func(b []byte) (int, error) {
 			return w.hub.WriteTo(b, id.src)
 		},
+		outputMulti: func(mb buf.MultiBuffer) error {
+			return w.hub.WriteMultiBuffer(mb, id.src)
+		},
 		remote: &net.UDPAddr{
 			IP:   id.src.Address.IP(),
 			Port: int(id.src.Port),
@@ -309,20 +484,57 @@ func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
 	w.activeConn[id] = conn
 
 	conn.updateActivity()
+	w.trackNat(conn, connID{src: id.src, dest: originalDest})
 	return conn, false
 }
 
+// trackNat records the packet through conn in the mapping of id. The caller must hold the lock.
+func (w *udpWorker) trackNat(conn *udpConn, id connID) {
+	if !id.dest.IsValid() {
+		return
+	}
+	now := time.Now().Unix()
+	entry := w.natTable[id]
+	if entry == nil || entry.conn != conn {
+		conn.natIDs = append(conn.natIDs, id)
+		entry = &natEntry{
+			conn:    conn,
+			created: now,
+		}
+		w.natTable[id] = entry
+	}
+	entry.lastSeen = now
+}
+
+// untrackNat removes the mappings through conn. The caller must hold the lock.
+func (w *udpWorker) untrackNat(conn *udpConn) {
+	for _, id := range conn.natIDs {
+		if entry := w.natTable[id]; entry != nil && entry.conn == conn {
+			delete(w.natTable, id)
+		}
+	}
+	conn.natIDs = nil
+}
+
 func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest net.Destination) {
-	id := connID{
-		src: source,
+	if pass, _ := w.sourceGate.Check(w.ctx, source.Address, false); !pass {
+		b.Release()
+		return
+	}
+	if w.recvOrigDest && !originalDest.IsValid() {
+		w.unrecovered.Add(1)
+		b.Release()
+		return
 	}
+	id := w.flowID(source, originalDest)
 	if originalDest.IsValid() {
-		if !w.cone {
-			id.dest = originalDest
-		}
 		b.UDP = &originalDest
 	}
-	conn, existing := w.getConnection(id)
+	conn, existing := w.getConnection(id, originalDest)
+	if conn == nil {
+		b.Release()
+		return
+	}
 
 	// payload will be discarded in pipe is full.
 	conn.writer.WriteMultiBuffer(buf.MultiBuffer{b})
@@ -335,12 +547,14 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 			conn.cancel = cancel
 			sid := session.NewID()
 			ctx = c.ContextWithID(ctx, sid)
+			ctx = w.sourceGate.contextWithBanList(ctx)
 
 			outbounds := []*session.Outbound{{}}
 			if originalDest.IsValid() {
 				outbounds[0].Target = originalDest
 			}
 			ctx = session.ContextWithOutbounds(ctx, outbounds)
+			conn.outbound = outbounds[0]
 			local := net.DestinationFromAddr(w.hub.Addr())
 			if local.Address == net.AnyIP || local.Address == net.AnyIPv6 {
 				if source.Address.Family().IsIPv4() {
@@ -363,8 +577,16 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 				content.SniffingRequest.ExcludeForDomain = w.sniffingConfig.DomainsExcluded
 				content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
 				content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+				content.SniffingRequest.Timeout = time.Duration(w.sniffingConfig.TimeoutMs) * time.Millisecond
+				content.SniffingRequest.BufferSize = int32(w.sniffingConfig.BufferSize)
+				content.SniffingRequest.UDPPackets = int(w.sniffingConfig.UdpPackets)
+				content.SniffingRequest.Resniff = w.sniffingConfig.Resniff
+				if ports := w.sniffingConfig.DestinationOverridePorts; ports != nil {
+					content.SniffingRequest.Ports = net.PortListFromProto(ports)
+				}
 			}
 			ctx = session.ContextWithContent(ctx, content)
+			ctx = contextWithPipeWatermarks(ctx, w.stream)
 			if err := w.proxy.Process(ctx, net.Network_UDP, conn, w.dispatcher); err != nil {
 				errors.LogInfoInner(ctx, err, "connection ends")
 			}
@@ -380,19 +602,58 @@ func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest
 
 func (w *udpWorker) removeConn(id connID) {
 	w.Lock()
+	if conn := w.activeConn[id]; conn != nil {
+		w.untrackNat(conn)
+	}
 	delete(w.activeConn, id)
 	w.Unlock()
 }
 
+// flowID returns the ID of the connection that the packet from source to originalDest belongs to.
+// The connections are keyed by the original destinations as well if they are received, even with cone NAT,
+// as each connection is dispatched to the original destination of its first packet.
+func (w *udpWorker) flowID(source net.Destination, originalDest net.Destination) connID {
+	id := connID{
+		src: source,
+	}
+	if originalDest.IsValid() {
+		id.dest = originalDest
+	}
+	return id
+}
+
+// handlePackets hands the packets over to a pool of goroutines. The packets of a flow are always handled
+// by the same goroutine, so they reach the proxy in the order they are received.
 func (w *udpWorker) handlePackets() {
+	n := int(w.poolSize)
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	seed := maphash.MakeSeed()
+	queues := make([]chan *udp_proto.Packet, n)
+	for i := range queues {
+		queue := make(chan *udp_proto.Packet, 64)
+		queues[i] = queue
+		go func() {
+			for payload := range queue {
+				w.callback(payload.Payload, payload.Source, payload.Target)
+			}
+		}()
+	}
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+	}()
+
 	receive := w.hub.Receive()
 	for payload := range receive {
-		w.callback(payload.Payload, payload.Source, payload.Target)
+		id := w.flowID(payload.Source, payload.Target)
+		queues[maphash.Comparable(seed, id)%uint64(n)] <- payload
 	}
 }
 
 func (w *udpWorker) clean() error {
-	nowSec := time.Now().Unix()
 	w.Lock()
 	defer w.Unlock()
 
@@ -400,25 +661,89 @@ func (w *udpWorker) clean() error {
 		return errors.New("no more connections. stopping...")
 	}
 
+	w.expire()
+	return nil
+}
+
+// idleTimeout returns the timeout of the idle connections in seconds.
+func (w *udpWorker) idleTimeout() int64 {
+	idle := w.idle
+	if idle <= 0 {
+		idle = policy.SessionDefault().Timeouts.UDPIdle
+	}
+	return int64(idle / time.Second)
+}
+
+// expire closes the connections idle for longer than the UDP idle timeout, and returns how many of them it closed.
+// The caller must hold the lock.
+func (w *udpWorker) expire() int {
+	nowSec := time.Now().Unix()
+	idle := w.idleTimeout()
+	expired := 0
 	for addr, conn := range w.activeConn {
-		if nowSec-atomic.LoadInt64(&conn.lastActivityTime) > 2*60 {
+		if nowSec-atomic.LoadInt64(&conn.lastActivityTime) > idle {
 			if !conn.inactive {
 				conn.setInactive()
 				delete(w.activeConn, addr)
+				w.untrackNat(conn)
 			}
 			conn.Close()
+			expired++
+		}
+	}
+	for id, entry := range w.natTable {
+		if nowSec-entry.lastSeen > idle {
+			delete(w.natTable, id)
 		}
 	}
 
 	if len(w.activeConn) == 0 {
 		w.activeConn = make(map[connID]*udpConn, 16)
+		w.natTable = make(map[connID]*natEntry)
 	}
 
-	return nil
+	return expired
+}
+
+// natEntries returns the number of connections in the NAT table.
+func (w *udpWorker) natEntries() int {
+	w.RLock()
+	defer w.RUnlock()
+
+	return len(w.activeConn)
+}
+
+// natMappings returns the mappings of the NAT table, and the number of the packets dropped as their original
+// destinations could not be recovered.
+func (w *udpWorker) natMappings() ([]*NatMapping, int64) {
+	w.RLock()
+	defer w.RUnlock()
+
+	mappings := make([]*NatMapping, 0, len(w.natTable))
+	for id, entry := range w.natTable {
+		mappings = append(mappings, &NatMapping{
+			Source:              id.src,
+			OriginalDestination: id.dest,
+			OutboundTag:         entry.conn.getOutboundTag(),
+			Created:             time.Unix(entry.created, 0),
+			LastSeen:            time.Unix(entry.lastSeen, 0),
+		})
+	}
+	return mappings, w.unrecovered.Load()
+}
+
+// CollectIdle implements stats.IdleCollector.
+func (w *udpWorker) CollectIdle() int {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.expire()
 }
 
 func (w *udpWorker) Start() error {
 	w.activeConn = make(map[connID]*udpConn, 16)
+	w.natTable = make(map[connID]*natEntry)
+	w.recvOrigDest = w.stream.SocketSettings.GetReceiveOriginalDestAddress()
 	ctx := context.Background()
 	h, err := udp.ListenUDP(ctx, w.address, w.port, w.stream, udp.HubCapacity(256))
 	if err != nil {
@@ -427,8 +752,13 @@ func (w *udpWorker) Start() error {
 
 	w.cone = w.ctx.Value("cone").(bool)
 
+	// the idle connections are checked at least as often as they idle out
+	interval := time.Minute
+	if idle := time.Duration(w.idleTimeout()) * time.Second; idle > 0 && idle < interval {
+		interval = idle
+	}
 	w.checker = &task.Periodic{
-		Interval: time.Minute,
+		Interval: interval,
 		Execute:  w.clean,
 	}
 
@@ -465,6 +795,16 @@ func (w *udpWorker) Close() error {
 	return nil
 }
 
+func (w *udpWorker) Pause() error {
+	w.paused.Store(true)
+	return nil
+}
+
+func (w *udpWorker) Resume() error {
+	w.paused.Store(false)
+	return nil
+}
+
 func (w *udpWorker) Port() net.Port {
 	return w.port
 }
@@ -484,6 +824,8 @@ type dsWorker struct {
 	downlinkCounter stats.Counter
 
 	hub internet.Listener
+	// the connections accepted while paused are closed at once, like by tcpWorker
+	paused atomic.Bool
 
 	ctx context.Context
 }
@@ -500,9 +842,10 @@ func (w *dsWorker) callback(conn stat.Connection) {
 			WriteCounter: w.downlinkCounter,
 		}
 	}
+	source, local := connAddrs(conn)
 	ctx = session.ContextWithInbound(ctx, &session.Inbound{
-		Source:  net.DestinationFromAddr(conn.RemoteAddr()),
-		Local:   net.DestinationFromAddr(conn.LocalAddr()),
+		Source:  source,
+		Local:   local,
 		Gateway: net.UnixDestination(w.address),
 		Tag:     w.tag,
 		Conn:    conn,
@@ -515,8 +858,16 @@ func (w *dsWorker) callback(conn stat.Connection) {
 		content.SniffingRequest.ExcludeForDomain = w.sniffingConfig.DomainsExcluded
 		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
 		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+		content.SniffingRequest.Timeout = time.Duration(w.sniffingConfig.TimeoutMs) * time.Millisecond
+		content.SniffingRequest.BufferSize = int32(w.sniffingConfig.BufferSize)
+		content.SniffingRequest.UDPPackets = int(w.sniffingConfig.UdpPackets)
+		content.SniffingRequest.Resniff = w.sniffingConfig.Resniff
+		if ports := w.sniffingConfig.DestinationOverridePorts; ports != nil {
+			content.SniffingRequest.Ports = net.PortListFromProto(ports)
+		}
 	}
 	ctx = session.ContextWithContent(ctx, content)
+	ctx = contextWithPipeWatermarks(ctx, w.stream)
 
 	if err := w.proxy.Process(ctx, net.Network_UNIX, conn, w.dispatcher); err != nil {
 		errors.LogInfoInner(ctx, err, "connection ends")
@@ -538,6 +889,13 @@ func (w *dsWorker) Port() net.Port {
 func (w *dsWorker) Start() error {
 	ctx := context.Background()
 	hub, err := internet.ListenUnix(ctx, w.address, w.stream, func(conn stat.Connection) {
+		if w.paused.Load() {
+			conn.Close()
+			return
+		}
+		if handOver(w.ctx, conn) {
+			return
+		}
 		go w.callback(conn)
 	})
 	if err != nil {
@@ -547,6 +905,16 @@ func (w *dsWorker) Start() error {
 	return nil
 }
 
+func (w *dsWorker) Pause() error {
+	w.paused.Store(true)
+	return nil
+}
+
+func (w *dsWorker) Resume() error {
+	w.paused.Store(false)
+	return nil
+}
+
 func (w *dsWorker) Close() error {
 	var errs []interface{}
 	if w.hub != nil {
@@ -564,6 +932,93 @@ func (w *dsWorker) Close() error {
 	return nil
 }
 
+// deviceWorker passes the connections of a network device run by the proxy itself to the proxy.
+type deviceWorker struct {
+	proxy           proxy.DeviceInbound
+	stream          *internet.MemoryStreamConfig
+	tag             string
+	dispatcher      routing.Dispatcher
+	sniffingConfig  *proxyman.SniffingConfig
+	uplinkCounter   stats.Counter
+	downlinkCounter stats.Counter
+
+	ctx context.Context
+}
+
+func (w *deviceWorker) callback(conn stat.Connection, dest net.Destination) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	sid := session.NewID()
+	ctx = c.ContextWithID(ctx, sid)
+
+	// the original destination is the target for routing and sniffing, as with a redirected connection
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest}})
+	ctx = contextWithPipeWatermarks(ctx, w.stream)
+
+	if w.uplinkCounter != nil || w.downlinkCounter != nil {
+		conn = &stat.CounterConnection{
+			Connection:   conn,
+			ReadCounter:  w.uplinkCounter,
+			WriteCounter: w.downlinkCounter,
+		}
+	}
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Source: net.DestinationFromAddr(conn.RemoteAddr()),
+		Local:  net.DestinationFromAddr(conn.LocalAddr()),
+		Tag:    w.tag,
+		Conn:   conn,
+	})
+
+	content := new(session.Content)
+	if w.sniffingConfig != nil {
+		content.SniffingRequest.Enabled = w.sniffingConfig.Enabled
+		content.SniffingRequest.OverrideDestinationForProtocol = w.sniffingConfig.DestinationOverride
+		content.SniffingRequest.ExcludeForDomain = w.sniffingConfig.DomainsExcluded
+		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
+		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+		content.SniffingRequest.Timeout = time.Duration(w.sniffingConfig.TimeoutMs) * time.Millisecond
+		content.SniffingRequest.BufferSize = int32(w.sniffingConfig.BufferSize)
+		content.SniffingRequest.UDPPackets = int(w.sniffingConfig.UdpPackets)
+		content.SniffingRequest.Resniff = w.sniffingConfig.Resniff
+		if ports := w.sniffingConfig.DestinationOverridePorts; ports != nil {
+			content.SniffingRequest.Ports = net.PortListFromProto(ports)
+		}
+	}
+	ctx = session.ContextWithContent(ctx, content)
+
+	if err := w.proxy.Process(ctx, dest.Network, conn, w.dispatcher); err != nil {
+		errors.LogInfoInner(ctx, err, "connection ends")
+	}
+	cancel()
+	conn.Close()
+}
+
+func (w *deviceWorker) Proxy() proxy.Inbound {
+	return w.proxy
+}
+
+func (w *deviceWorker) Port() net.Port {
+	return net.Port(0)
+}
+
+func (w *deviceWorker) Start() error {
+	if err := w.proxy.Serve(w.callback); err != nil {
+		return errors.New("failed to open device of inbound ", w.tag).AtWarning().Base(err)
+	}
+	return nil
+}
+
+func (w *deviceWorker) Pause() error {
+	return errors.New("unable to pause the device of inbound ", w.tag)
+}
+
+func (w *deviceWorker) Resume() error {
+	return nil
+}
+
+func (w *deviceWorker) Close() error {
+	return w.proxy.Close()
+}
+
 func IsLocal(ip net.IP) bool {
 	addrs, err := gonet.InterfaceAddrs()
 	if err != nil {
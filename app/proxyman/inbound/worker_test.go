@@ -0,0 +1,214 @@
+package inbound
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	featinbound "github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/testing/servers/udp"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+type inboundRecorder struct {
+	inbounds chan *session.Inbound
+}
+
+func (r *inboundRecorder) Network() []net.Network {
+	return []net.Network{net.Network_TCP, net.Network_UNIX}
+}
+
+func (r *inboundRecorder) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	r.inbounds <- session.InboundFromContext(ctx)
+	return nil
+}
+
+func proxyProtocolStream(t *testing.T) *internet.MemoryStreamConfig {
+	stream, err := internet.ToMemoryStreamConfig(&internet.StreamConfig{
+		ProtocolName:   "tcp",
+		SocketSettings: &internet.SocketConfig{AcceptProxyProtocol: true},
+	})
+	common.Must(err)
+	return stream
+}
+
+func TestWorkerProxyProtocol(t *testing.T) {
+	source := &net.TCPAddr{IP: net.ParseAddress("198.51.100.7").IP(), Port: 40000}
+	destination := &net.TCPAddr{IP: net.ParseAddress("203.0.113.1").IP(), Port: 443}
+
+	type listener struct {
+		name    string
+		network string
+		start   func(recorder *inboundRecorder) (worker, string)
+	}
+	listeners := []listener{
+		{
+			name:    "tcp",
+			network: "tcp",
+			start: func(recorder *inboundRecorder) (worker, string) {
+				port := tcp.PickPort()
+				w := &tcpWorker{
+					address: net.LocalHostIP,
+					port:    port,
+					proxy:   recorder,
+					stream:  proxyProtocolStream(t),
+					ctx:     context.Background(),
+				}
+				common.Must(w.Start())
+				return w, net.TCPDestination(net.LocalHostIP, port).NetAddr()
+			},
+		},
+	}
+	if runtime.GOOS != "windows" {
+		listeners = append(listeners, listener{
+			name:    "unix",
+			network: "unix",
+			start: func(recorder *inboundRecorder) (worker, string) {
+				path := filepath.Join(t.TempDir(), "in.sock")
+				w := &dsWorker{
+					address: net.DomainAddress(path),
+					proxy:   recorder,
+					stream:  proxyProtocolStream(t),
+					ctx:     context.Background(),
+				}
+				common.Must(w.Start())
+				return w, path
+			},
+		})
+	}
+
+	for _, l := range listeners {
+		for _, version := range []byte{1, 2} {
+			recorder := &inboundRecorder{inbounds: make(chan *session.Inbound, 1)}
+			w, address := l.start(recorder)
+
+			conn, err := net.Dial(l.network, address)
+			common.Must(err)
+			_, err = proxyproto.HeaderProxyFromAddrs(version, source, destination).WriteTo(conn)
+			common.Must(err)
+
+			select {
+			case inbound := <-recorder.inbounds:
+				if inbound.Source != net.DestinationFromAddr(source) {
+					t.Error(l.name, " v", version, ": unexpected source ", inbound.Source)
+				}
+				if inbound.Local != net.DestinationFromAddr(destination) {
+					t.Error(l.name, " v", version, ": unexpected local ", inbound.Local)
+				}
+			case <-time.After(5 * time.Second):
+				t.Error(l.name, " v", version, ": the connection is not processed")
+			}
+			conn.Close()
+			common.Must(w.Close())
+		}
+	}
+}
+
+func TestUDPWorkerNatTable(t *testing.T) {
+	source := net.UDPDestination(net.ParseAddress("192.168.1.2"), 50000)
+	dest1 := net.UDPDestination(net.ParseAddress("203.0.113.1"), 53)
+	dest2 := net.UDPDestination(net.ParseAddress("203.0.113.2"), 443)
+
+	for _, cone := range []bool{true, false} {
+		w := &udpWorker{
+			address:    net.AnyIP,
+			activeConn: make(map[connID]*udpConn),
+			natTable:   make(map[connID]*natEntry),
+			cone:       cone,
+		}
+		// each original destination has its own connection, which is dispatched to it
+		conn1, _ := w.getConnection(w.flowID(source, dest1), dest1)
+		conn2, existing := w.getConnection(w.flowID(source, dest2), dest2)
+		if existing || conn1 == conn2 {
+			t.Error("cone = ", cone, ": the original destinations share a connection")
+		}
+
+		mappings, _ := w.natMappings()
+		if len(mappings) != 2 {
+			t.Fatal("cone = ", cone, ": expected 2 mappings, but got ", len(mappings))
+		}
+		for _, m := range mappings {
+			if m.Source != source || (m.OriginalDestination != dest1 && m.OriginalDestination != dest2) {
+				t.Error("cone = ", cone, ": unexpected mapping ", m.Source, " -> ", m.OriginalDestination)
+			}
+		}
+
+		w.removeConn(w.flowID(source, dest2))
+		if mappings, _ := w.natMappings(); len(mappings) != 1 {
+			t.Error("cone = ", cone, ": unexpected mappings after removing the connection: ", len(mappings))
+		}
+	}
+}
+
+func TestUDPWorkerUnrecoveredOriginalDestination(t *testing.T) {
+	w := &udpWorker{
+		activeConn:   make(map[connID]*udpConn),
+		natTable:     make(map[connID]*natEntry),
+		recvOrigDest: true,
+	}
+	w.callback(buf.New(), net.UDPDestination(net.ParseAddress("192.168.1.2"), 50000), net.Destination{})
+	if _, unrecovered := w.natMappings(); unrecovered != 1 {
+		t.Error("expected the packet to be dropped, but got ", unrecovered)
+	}
+	if len(w.activeConn) != 0 {
+		t.Error("unexpected connection of the packet without original destination")
+	}
+}
+
+func TestAlwaysOnUDPIdleOfUserLevel(t *testing.T) {
+	port := udp.PickPort()
+	server, err := core.New(&core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&policy.Config{
+				Level: map[uint32]*policy.Policy{
+					1: {Timeout: &policy.Policy_Timeout{UdpIdle: &policy.Second{Value: 7}}},
+				},
+			}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(port)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:   net.NewIPOrDomain(net.LocalHostIP),
+					Port:      53,
+					Networks:  []net.Network{net.Network_UDP},
+					UserLevel: 1,
+				}),
+			},
+		},
+	})
+	common.Must(err)
+
+	// the UDP connections of dokodemo-door idle out at the udpIdle of its level
+	handler, err := server.GetFeature(featinbound.ManagerType()).(featinbound.Manager).GetHandler(context.Background(), "in")
+	common.Must(err)
+	var idle []time.Duration
+	for _, w := range handler.(*AlwaysOnInboundHandler).workers {
+		if w, ok := w.(*udpWorker); ok {
+			idle = append(idle, w.idle)
+		}
+	}
+	if len(idle) != 1 || idle[0] != 7*time.Second {
+		t.Error("expected the udpIdle of level 1, but got ", idle)
+	}
+}
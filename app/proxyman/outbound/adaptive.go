@@ -0,0 +1,218 @@
+package outbound
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+)
+
+const (
+	defaultBulkThresholdKBps = 1024
+	// The throughput of a connection within this window after it starts classifies its destination.
+	adaptiveWindow = 2 * time.Second
+	// The classification of a destination loses half of its weight in this time without new connections.
+	adaptiveHalfLife = 10 * time.Minute
+	// A destination is bulk while its score is at least this. A bulk connection scores 0.5 by itself,
+	// so the destination stays bulk for a half-life, unless small connections to it follow.
+	bulkScore = 0.25
+	// Destinations whose score has decayed below this are forgotten.
+	forgetScore             = 0.01
+	maxAdaptiveDestinations = 4096
+)
+
+type destinationClass struct {
+	score float64
+	at    time.Time
+}
+
+// adaptiveMux sends the connections to the destinations of bulk flows over their own connections,
+// while the other connections keep sharing Mux. A destination is classified by the throughput of the
+// first window of its connections, so the connection that reveals a bulk flow stays on its path,
+// and the next ones to its destination move.
+type adaptiveMux struct {
+	sync.Mutex
+	// threshold is in bytes per second.
+	threshold int64
+	classes   map[string]*destinationClass
+	shared    stats.Counter
+	bulk      stats.Counter
+}
+
+func newAdaptiveMux(v *core.Instance, tag string, config *proxyman.AdaptiveMuxConfig) *adaptiveMux {
+	threshold := int64(config.BulkThresholdKbps)
+	if threshold == 0 {
+		threshold = defaultBulkThresholdKBps
+	}
+	m := &adaptiveMux{
+		threshold: threshold * 1024,
+		classes:   make(map[string]*destinationClass),
+	}
+	if len(tag) > 0 {
+		statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+		if c, _ := stats.GetOrRegisterCounter(statsManager, "outbound>>>"+tag+">>>mux>>>shared"); c != nil {
+			m.shared = c
+		}
+		if c, _ := stats.GetOrRegisterCounter(statsManager, "outbound>>>"+tag+">>>mux>>>bulk"); c != nil {
+			m.bulk = c
+		}
+	}
+	return m
+}
+
+// destinationKey returns the key of the destination the connections are classified by.
+func destinationKey(dest net.Destination) string {
+	if dest.Address == nil {
+		return ""
+	}
+	return dest.Address.String()
+}
+
+func decayed(c *destinationClass, now time.Time) float64 {
+	return c.score * math.Exp2(-float64(now.Sub(c.at))/float64(adaptiveHalfLife))
+}
+
+// route returns whether the connection to the destination goes on its own instead of Mux,
+// and meters the first window of the connection on link to classify the destination.
+func (m *adaptiveMux) route(key string, link *transport.Link) bool {
+	m.Lock()
+	c := m.classes[key]
+	isBulk := c != nil && decayed(c, time.Now()) >= bulkScore
+	m.Unlock()
+
+	if isBulk {
+		if m.bulk != nil {
+			m.bulk.Add(1)
+		}
+	} else if m.shared != nil {
+		m.shared.Add(1)
+	}
+
+	meter := &flowMeter{
+		mux:   m,
+		key:   key,
+		limit: m.threshold * int64(adaptiveWindow) / int64(time.Second),
+	}
+	meter.timer = time.AfterFunc(adaptiveWindow, func() {
+		meter.finish(false)
+	})
+	link.Reader = &meteredReader{Reader: link.Reader, meter: meter}
+	link.Writer = &meteredWriter{Writer: link.Writer, meter: meter}
+	return isBulk
+}
+
+// observe classifies the destination by a connection to it.
+func (m *adaptiveMux) observe(key string, bulk bool) {
+	var x float64
+	if bulk {
+		x = 1
+	}
+	now := time.Now()
+
+	m.Lock()
+	defer m.Unlock()
+
+	c := m.classes[key]
+	if c == nil {
+		if !bulk {
+			// unknown destinations share Mux anyway
+			return
+		}
+		if len(m.classes) >= maxAdaptiveDestinations {
+			m.forget(now)
+			if len(m.classes) >= maxAdaptiveDestinations {
+				return
+			}
+		}
+		c = &destinationClass{}
+		m.classes[key] = c
+	}
+	c.score = (decayed(c, now) + x) / 2
+	c.at = now
+}
+
+func (m *adaptiveMux) forget(now time.Time) {
+	for key, c := range m.classes {
+		if decayed(c, now) < forgetScore {
+			delete(m.classes, key)
+		}
+	}
+}
+
+// flowMeter counts the bytes of a connection in both directions within its first window.
+type flowMeter struct {
+	mux   *adaptiveMux
+	key   string
+	limit int64
+	bytes atomic.Int64
+	done  atomic.Bool
+	timer *time.Timer
+}
+
+func (f *flowMeter) add(n int64) {
+	if n == 0 || f.done.Load() {
+		return
+	}
+	// the throughput of the window exceeds the threshold already
+	if f.bytes.Add(n) >= f.limit {
+		f.timer.Stop()
+		f.finish(true)
+	}
+}
+
+func (f *flowMeter) finish(bulk bool) {
+	if f.done.CompareAndSwap(false, true) {
+		f.mux.observe(f.key, bulk)
+	}
+}
+
+type meteredReader struct {
+	buf.Reader
+	meter *flowMeter
+}
+
+func (r *meteredReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	r.meter.add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *meteredReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.Reader.(buf.TimeoutReader)
+	if !ok {
+		return r.ReadMultiBuffer()
+	}
+	mb, err := tr.ReadMultiBufferTimeout(timeout)
+	r.meter.add(int64(mb.Len()))
+	return mb, err
+}
+
+func (r *meteredReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+type meteredWriter struct {
+	buf.Writer
+	meter *flowMeter
+}
+
+func (w *meteredWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.meter.add(int64(mb.Len()))
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *meteredWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+func (w *meteredWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
@@ -0,0 +1,47 @@
+package outbound
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+func TestAdaptiveMux(t *testing.T) {
+	m := newAdaptiveMux(nil, "", &proxyman.AdaptiveMuxConfig{Enabled: true, BulkThresholdKbps: 1})
+
+	newLink := func() (*transport.Link, *pipe.Reader) {
+		reader, writer := pipe.New(pipe.WithoutSizeLimit())
+		return &transport.Link{Reader: reader, Writer: writer}, reader
+	}
+
+	link, reader := newLink()
+	if m.route("example.com", link) {
+		t.Fatal("expected an unknown destination to share mux")
+	}
+	// 2 KB within the window of 2 seconds is the threshold of 1 KB/s
+	b := buf.New()
+	b.Extend(2048)
+	common.Must(link.Writer.WriteMultiBuffer(buf.MultiBuffer{b}))
+	buf.ReleaseMulti(common.Must2(reader.ReadMultiBuffer()))
+
+	link, _ = newLink()
+	if !m.route("example.com", link) {
+		t.Fatal("expected the destination to be classified as bulk")
+	}
+	link, _ = newLink()
+	if m.route("example.org", link) {
+		t.Error("expected the other destinations to share mux")
+	}
+
+	// small connections bring the destination back to mux
+	m.observe("example.com", false)
+	m.observe("example.com", false)
+	link, _ = newLink()
+	if m.route("example.com", link) {
+		t.Error("expected the destination to share mux again")
+	}
+}
@@ -0,0 +1,124 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/mux"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+type failoverKey struct{}
+
+// dialTracker is the dialer of a session through a handler with a fallback outbound.
+// It records whether the proxy has failed to dial, without any connection dialed.
+type dialTracker struct {
+	*Handler
+	failed atomic.Bool
+	dialed atomic.Bool
+}
+
+// Dial implements internet.Dialer.
+func (d *dialTracker) Dial(ctx context.Context, dest net.Destination) (stat.Connection, error) {
+	conn, err := d.Handler.Dial(ctx, dest)
+	if err != nil {
+		d.failed.Store(true)
+	} else {
+		d.dialed.Store(true)
+	}
+	return conn, err
+}
+
+// dialFailed returns whether the session has failed only at dialing, so nothing has been sent or received yet.
+func (d *dialTracker) dialFailed() bool {
+	return d.failed.Load() && !d.dialed.Load()
+}
+
+// dialSignal is the dialer of a mux worker of a handler with a fallback outbound, which tells when the worker has dialed.
+type dialSignal struct {
+	internet.Dialer
+	once   sync.Once
+	dialed chan struct{}
+}
+
+// Dial implements internet.Dialer.
+func (d *dialSignal) Dial(ctx context.Context, dest net.Destination) (stat.Connection, error) {
+	conn, err := d.Dialer.Dial(ctx, dest)
+	if err == nil {
+		d.once.Do(func() { close(d.dialed) })
+	}
+	return conn, err
+}
+
+// dialingWorkerFactory creates the mux workers of a handler with a fallback outbound. A worker is handed sessions
+// only after it has dialed, so the sessions of a worker failing to dial are left untouched for the fallback.
+type dialingWorkerFactory struct {
+	*mux.DialingWorkerFactory
+}
+
+// Create implements mux.ClientWorkerFactory.
+func (f *dialingWorkerFactory) Create() (*mux.ClientWorker, error) {
+	factory := *f.DialingWorkerFactory
+	dialer := &dialSignal{Dialer: factory.Dialer, dialed: make(chan struct{})}
+	factory.Dialer = dialer
+	worker, err := factory.Create()
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-dialer.dialed:
+		return worker, nil
+	case <-worker.WaitClosed():
+		return nil, errors.New("failed to dial the mux connection")
+	}
+}
+
+// workerFactory returns the factory of the mux workers of the handler, which waits for them to dial
+// if the handler has a fallback outbound.
+func (h *Handler) workerFactory(f *mux.DialingWorkerFactory) mux.ClientWorkerFactory {
+	if len(h.senderSettings.GetFallbackTag()) == 0 {
+		return f
+	}
+	return &dialingWorkerFactory{f}
+}
+
+// failover retries the session of link and target, as they came to the handler, through the fallback outbound
+// of the handler, after dialing has failed with err.
+// It returns false if the session is not retried, as the fallback does not exist or the session is a retry itself.
+func (h *Handler) failover(ctx context.Context, link *transport.Link, target net.Destination, err error) bool {
+	if ctx.Value(failoverKey{}) != nil {
+		return false
+	}
+	tag := h.senderSettings.FallbackTag
+	handler := h.outboundManager.GetHandler(tag)
+	if handler == nil {
+		errors.LogWarning(ctx, "failed to get fallback outbound handler with tag: ", tag)
+		return false
+	}
+	errors.LogInfoInner(ctx, err, "failed to dial through outbound ", h.tag, ", failing over to ", tag)
+	if h.failoverCounter != nil {
+		h.failoverCounter.Add(1)
+	}
+
+	outbounds := session.OutboundsFromContext(ctx)
+	ob := outbounds[len(outbounds)-1]
+	ob.Tag = tag
+	ob.Target = target
+	ob.Conn = nil
+	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
+		msg := *accessMessage
+		msg.OutboundTag = tag
+		msg.Detour += " ~> " + tag
+		log.Record(&msg)
+	}
+
+	handler.Dispatch(context.WithValue(ctx, failoverKey{}, true), link)
+	return true
+}
@@ -8,6 +8,9 @@ import (
 	"math/big"
 	gonet "net"
 	"os"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common/dice"
 
@@ -27,6 +30,7 @@ import (
 	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
 	"github.com/xtls/xray-core/transport/pipe"
@@ -58,6 +62,21 @@ func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter)
 	return uplinkCounter, downlinkCounter
 }
 
+func getStatHistograms(v *core.Instance, tag string) (*session.DialTimings, stats.Histogram) {
+	policy := v.GetFeature(policy.ManagerType()).(policy.Manager)
+	if len(tag) == 0 || !policy.ForSystem().Stats.OutboundHistogram {
+		return nil, nil
+	}
+	statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	connect, _ := stats.GetOrRegisterHistogram(statsManager, "outbound>>>"+tag+">>>connect>>>histogram")
+	handshake, _ := stats.GetOrRegisterHistogram(statsManager, "outbound>>>"+tag+">>>handshake>>>histogram")
+	duration, _ := stats.GetOrRegisterHistogram(statsManager, "outbound>>>"+tag+">>>duration>>>histogram")
+	if connect == nil || handshake == nil || duration == nil {
+		return nil, nil
+	}
+	return &session.DialTimings{Connect: connect, Handshake: handshake}, duration
+}
+
 // Handler implements outbound.Handler.
 type Handler struct {
 	tag             string
@@ -68,20 +87,30 @@ type Handler struct {
 	outboundManager outbound.Manager
 	mux             *mux.ClientManager
 	xudp            *mux.ClientManager
+	adaptive        *adaptiveMux
 	udp443          string
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	dialTimings     *session.DialTimings
+	duration        stats.Histogram
+	prewarm         *prewarmPool
+	failoverCounter stats.Counter
+	bandwidth       *bandwidthLimit
+	sticky          *stickyGateways
 }
 
 // NewHandler creates a new Handler based on the given configuration.
 func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbound.Handler, error) {
 	v := core.MustFromContext(ctx)
 	uplinkCounter, downlinkCounter := getStatCounter(v, config.Tag)
+	dialTimings, duration := getStatHistograms(v, config.Tag)
 	h := &Handler{
 		tag:             config.Tag,
 		outboundManager: v.GetFeature(outbound.ManagerType()).(outbound.Manager),
 		uplinkCounter:   uplinkCounter,
 		downlinkCounter: downlinkCounter,
+		dialTimings:     dialTimings,
+		duration:        duration,
 	}
 
 	if config.SenderSettings != nil {
@@ -97,6 +126,23 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 				return nil, errors.New("failed to parse stream settings").Base(err).AtWarning()
 			}
 			h.streamSettings = mss
+			if domain := s.Via.GetDomain(); (domain == "auto" || strings.HasPrefix(domain, "auto:")) && runtime.GOOS != "linux" {
+				errors.LogWarning(ctx, "sendThrough auto is only supported on Linux, outbound ", config.Tag, " sends through the default")
+			}
+			if s.ViaCidr != "" {
+				// the addresses of the CIDR are usually of a prefix routed to the host, rather than on an interface
+				var sockopt *internet.SocketConfig
+				if mss.SocketSettings != nil {
+					sockopt = proto.Clone(mss.SocketSettings).(*internet.SocketConfig)
+				} else {
+					sockopt = new(internet.SocketConfig)
+				}
+				sockopt.Freebind = true
+				mss.SocketSettings = sockopt
+				if s.ViaCidrTtl > 0 {
+					h.sticky = newStickyGateways(time.Duration(s.ViaCidrTtl) * time.Second)
+				}
+			}
 		default:
 			return nil, errors.New("settings is not SenderConfig")
 		}
@@ -122,6 +168,10 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 
 	if h.senderSettings != nil && h.senderSettings.MultiplexSettings != nil {
 		if config := h.senderSettings.MultiplexSettings; config.Enabled {
+			var brutal mux.Brutal
+			if config.Congestion == "brutal" {
+				brutal = mux.Brutal{Up: mux.MbpsToBytes(config.UpMbps), Down: mux.MbpsToBytes(config.DownMbps)}
+			}
 			if config.Concurrency < 0 {
 				h.mux = &mux.ClientManager{Enabled: false}
 			}
@@ -132,16 +182,20 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 				h.mux = &mux.ClientManager{
 					Enabled: true,
 					Picker: &mux.IncrementalWorkerPicker{
-						Factory: &mux.DialingWorkerFactory{
+						Factory: h.workerFactory(&mux.DialingWorkerFactory{
 							Proxy:  proxyHandler,
 							Dialer: h,
 							Strategy: mux.ClientStrategy{
 								MaxConcurrency: uint32(config.Concurrency),
 								MaxConnection:  128,
+								Brutal:         brutal,
 							},
-						},
+						}),
 					},
 				}
+				if config.Adaptive.GetEnabled() {
+					h.adaptive = newAdaptiveMux(v, h.tag, config.Adaptive)
+				}
 			}
 			if config.XudpConcurrency < 0 {
 				h.xudp = &mux.ClientManager{Enabled: false}
@@ -153,14 +207,15 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 				h.xudp = &mux.ClientManager{
 					Enabled: true,
 					Picker: &mux.IncrementalWorkerPicker{
-						Factory: &mux.DialingWorkerFactory{
+						Factory: h.workerFactory(&mux.DialingWorkerFactory{
 							Proxy:  proxyHandler,
 							Dialer: h,
 							Strategy: mux.ClientStrategy{
 								MaxConcurrency: uint32(config.XudpConcurrency),
 								MaxConnection:  128,
+								Brutal:         brutal,
 							},
-						},
+						}),
 					},
 				}
 			}
@@ -168,6 +223,23 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		}
 	}
 
+	if len(h.senderSettings.GetFallbackTag()) > 0 && len(h.tag) > 0 {
+		statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+		h.failoverCounter, _ = stats.GetOrRegisterCounter(statsManager, "outbound>>>"+h.tag+">>>failover")
+	}
+
+	if config := h.senderSettings.GetBandwidthLimit(); config.GetUplink() > 0 || config.GetDownlink() > 0 {
+		h.bandwidth = newBandwidthLimit(v, h.tag, config)
+	}
+
+	if config := h.senderSettings.GetPrewarm(); config.GetConnections() > 0 {
+		if h.canPrewarm() {
+			h.prewarm = newPrewarmPool(ctx, h, config)
+		} else {
+			errors.LogWarning(ctx, "prewarm of outbound ", h.tag, " is ignored, as it only works with raw TCP dialed directly")
+		}
+	}
+
 	h.proxy = proxyHandler
 	return h, nil
 }
@@ -177,11 +249,48 @@ func (h *Handler) Tag() string {
 	return h.tag
 }
 
+// PipeWatermarks returns the pipe watermarks of the sockopt of the handler, or 0 if it has none.
+func (h *Handler) PipeWatermarks() (high, low int32) {
+	if h.streamSettings == nil {
+		return 0, 0
+	}
+	ss := h.streamSettings.SocketSettings
+	return ss.GetPipeHighWatermark(), ss.GetPipeLowWatermark()
+}
+
+// ReportResources implements stats.ResourceReporter.
+func (h *Handler) ReportResources(report *stats.ResourceReport) {
+	res := report.Outbound(h.tag)
+	for _, m := range []*mux.ClientManager{h.mux, h.xudp} {
+		if m != nil {
+			res.MuxStreams += int64(m.Streams())
+		}
+	}
+}
+
+// CollectIdle implements stats.IdleCollector.
+func (h *Handler) CollectIdle() int {
+	collected := 0
+	for _, m := range []*mux.ClientManager{h.mux, h.xudp} {
+		if m != nil {
+			collected += m.CollectIdle()
+		}
+	}
+	return collected
+}
+
 // Dispatch implements proxy.Outbound.Dispatch.
 func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
 	content := session.ContentFromContext(ctx)
+	// the fallback outbound takes the session as it came, before the handler resolves the target or wraps the link
+	origin, target := *link, ob.Target
+	var adaptiveKey string
+	if h.adaptive != nil {
+		// the domain, before it is resolved for the target strategy
+		adaptiveKey = destinationKey(ob.Target)
+	}
 	if h.senderSettings != nil && h.senderSettings.TargetStrategy.HasStrategy() && ob.Target.Address.Family().IsDomain() && (content == nil || !content.SkipDNSResolve) {
 		strategy := h.senderSettings.TargetStrategy
 		if ob.Target.Network == net.Network_UDP && ob.OriginalTarget.Address != nil {
@@ -208,6 +317,9 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 		link.Reader = &buf.EndpointOverrideReader{Reader: link.Reader, Dest: ob.Target.Address, OriginalDest: ob.OriginalTarget.Address}
 		link.Writer = &buf.EndpointOverrideWriter{Writer: link.Writer, Dest: ob.Target.Address, OriginalDest: ob.OriginalTarget.Address}
 	}
+	if h.bandwidth != nil {
+		h.bandwidth.wrap(ctx, link)
+	}
 	if h.mux != nil {
 		test := func(err error) {
 			if err != nil {
@@ -218,29 +330,50 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 				common.Interrupt(link.Reader)
 			}
 		}
-		if ob.Target.Network == net.Network_UDP && ob.Target.Port == 443 {
-			switch h.udp443 {
-			case "reject":
-				test(errors.New("XUDP rejected UDP/443 traffic").AtInfo())
-				return
-			case "skip":
-				goto out
-			}
-		}
+		// UDP goes to XUDP if it is set, or shares Mux with TCP. Either one disabled sends its traffic directly.
+		m := h.mux
 		if h.xudp != nil && ob.Target.Network == net.Network_UDP {
-			if !h.xudp.Enabled {
+			m = h.xudp
+		}
+		if m.Enabled {
+			if ob.Target.Network == net.Network_UDP && ob.Target.Port == 443 {
+				switch h.udp443 {
+				case "reject":
+					test(errors.New("XUDP rejected UDP/443 traffic").AtInfo())
+					return
+				case "skip":
+					goto out
+				}
+			}
+			if h.adaptive != nil && ob.Target.Network == net.Network_TCP && h.adaptive.route(adaptiveKey, link) {
+				errors.LogDebug(ctx, "sending the bulk flow to ", adaptiveKey, " without Mux")
 				goto out
 			}
-			test(h.xudp.Dispatch(ctx, link))
+			err := m.Dispatch(ctx, link)
+			if err != nil && len(h.senderSettings.GetFallbackTag()) > 0 && h.failover(ctx, &origin, target, err) {
+				return
+			}
+			test(err)
 			return
 		}
-		if h.mux.Enabled {
-			test(h.mux.Dispatch(ctx, link))
+	}
+out:
+	if h.duration != nil {
+		defer func(start time.Time) {
+			h.duration.Observe(time.Since(start).Milliseconds())
+		}(time.Now())
+	}
+	var err error
+	if len(h.senderSettings.GetFallbackTag()) > 0 {
+		// only the sessions failing to dial are retried, mid-stream failures are not
+		dialer := &dialTracker{Handler: h}
+		err = h.proxy.Process(ctx, link, dialer)
+		if err != nil && dialer.dialFailed() && h.failover(ctx, &origin, target, err) {
 			return
 		}
+	} else {
+		err = h.proxy.Process(ctx, link, h)
 	}
-out:
-	err := h.proxy.Process(ctx, link, h)
 	var errC error
 	if err != nil {
 		errC = errors.Cause(err)
@@ -268,6 +401,11 @@ func (h *Handler) DestIpAddress() net.IP {
 	return internet.DestIpAddress()
 }
 
+// StreamSettings returns the transport settings the handler dials with.
+func (h *Handler) StreamSettings() *internet.MemoryStreamConfig {
+	return h.streamSettings
+}
+
 // Dial implements internet.Dialer.
 func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connection, error) {
 	if h.senderSettings != nil {
@@ -283,6 +421,9 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 					Target: dest,
 					Tag:    tag,
 				})) // add another outbound in session ctx
+				if ss := h.streamSettings.SocketSettings; ss.GetPipeHighWatermark() > 0 {
+					ctx = pipe.ContextWithWatermarks(ctx, ss.PipeHighWatermark, ss.PipeLowWatermark)
+				}
 				opts := pipe.OptionsFromContext(ctx)
 				uplinkReader, uplinkWriter := pipe.New(opts...)
 				downlinkReader, downlinkWriter := pipe.New(opts...)
@@ -305,7 +446,7 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 		if h.senderSettings.Via != nil {
 			outbounds := session.OutboundsFromContext(ctx)
 			ob := outbounds[len(outbounds)-1]
-			h.SetOutboundGateway(ctx, ob)
+			h.setOutboundGateway(ctx, ob, dest)
 		}
 
 	}
@@ -314,6 +455,20 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 		return conn, err
 	}
 
+	// a test of the outbound dials a new connection to time it
+	if h.prewarm != nil && dest.Network == net.Network_TCP && session.DialTimingsFromContext(ctx) == nil {
+		if conn := h.prewarm.Get(dest); conn != nil {
+			conn = h.getStatCouterConnection(conn)
+			outbounds := session.OutboundsFromContext(ctx)
+			outbounds[len(outbounds)-1].Conn = conn
+			return conn, nil
+		}
+	}
+
+	// a test of the outbound records the dial itself
+	if h.dialTimings != nil && session.DialTimingsFromContext(ctx) == nil {
+		ctx = session.ContextWithDialTimings(ctx, h.dialTimings)
+	}
 	conn, err := internet.Dial(ctx, dest, h.streamSettings)
 	conn = h.getStatCouterConnection(conn)
 	outbounds := session.OutboundsFromContext(ctx)
@@ -323,11 +478,20 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (stat.Connecti
 }
 
 func (h *Handler) SetOutboundGateway(ctx context.Context, ob *session.Outbound) {
+	h.setOutboundGateway(ctx, ob, ob.Target)
+}
+
+// setOutboundGateway sets the gateway of ob to send through, for the connection to dest.
+func (h *Handler) setOutboundGateway(ctx context.Context, ob *session.Outbound, dest net.Destination) {
 	if ob.Gateway == nil && h.senderSettings != nil && h.senderSettings.Via != nil && !h.senderSettings.ProxySettings.HasTag() && (h.streamSettings.SocketSettings == nil || len(h.streamSettings.SocketSettings.DialerProxy) == 0) {
 		var domain string
 		addr := h.senderSettings.Via.AsAddress()
 		domain = h.senderSettings.Via.GetDomain()
 		switch {
+		case h.senderSettings.ViaCidr != "" && h.sticky != nil:
+			ob.Gateway = h.sticky.get(dest.Address, func() net.Address {
+				return ParseRandomIP(addr, h.senderSettings.ViaCidr)
+			})
 		case h.senderSettings.ViaCidr != "":
 			ob.Gateway = ParseRandomIP(addr, h.senderSettings.ViaCidr)
 
@@ -345,6 +509,8 @@ func (h *Handler) SetOutboundGateway(ctx context.Context, ob *session.Outbound)
 					errors.LogDebug(ctx, "use inbound source ip as sendthrough: ", inbound.Source.Address.String())
 				}
 			}
+		case domain == "auto" || strings.HasPrefix(domain, "auto:"):
+			ob.Gateway = autoGateway(ctx, domain, dest)
 		//case addr.Family().IsDomain():
 		default:
 			ob.Gateway = addr
@@ -354,6 +520,26 @@ func (h *Handler) SetOutboundGateway(ctx context.Context, ob *session.Outbound)
 	}
 }
 
+// autoGateway returns the source address that the system routes the probe of sendThrough "auto:<probe>"
+// from, or the destination for "auto". It is nil to send through the default, if there is no such route.
+func autoGateway(ctx context.Context, via string, dest net.Destination) net.Address {
+	target := dest.Address
+	if probe, found := strings.CutPrefix(via, "auto:"); found {
+		target = net.ParseAddress(probe)
+	}
+	if target == nil || !target.Family().IsIP() {
+		errors.LogDebug(ctx, "no IP to find the route of sendThrough auto for ", dest)
+		return nil
+	}
+	src, err := internet.RouteSource(target.IP())
+	if err != nil {
+		errors.LogDebugInner(ctx, err, "failed to find the route of sendThrough auto")
+		return nil
+	}
+	errors.LogDebug(ctx, "use the source of the route to ", target, " as sendthrough: ", src)
+	return src
+}
+
 func (h *Handler) getStatCouterConnection(conn stat.Connection) stat.Connection {
 	if h.uplinkCounter != nil || h.downlinkCounter != nil {
 		return &stat.CounterConnection{
@@ -372,18 +558,41 @@ func (h *Handler) GetOutbound() proxy.Outbound {
 
 // Start implements common.Runnable.
 func (h *Handler) Start() error {
+	if h.prewarm != nil {
+		return h.prewarm.Start()
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (h *Handler) Close() error {
+	h.drainPrewarm()
+	h.releaseSpider()
 	common.Close(h.mux)
 	common.Close(h.proxy)
 	return nil
 }
 
+// drainPrewarm closes the idle connections of the handler, which no session will use anymore.
+func (h *Handler) drainPrewarm() {
+	if h.prewarm != nil {
+		h.prewarm.Close()
+	}
+}
+
+// releaseSpider forgets the REALITY spider of the handler, which is kept by the config of the handler.
+func (h *Handler) releaseSpider() {
+	if config := reality.ConfigFromStreamSettings(h.streamSettings); config != nil {
+		reality.ReleaseSpider(config)
+	}
+}
+
 // SenderSettings implements outbound.Handler.
 func (h *Handler) SenderSettings() *serial.TypedMessage {
+	if h.senderSettings == nil {
+		// Handlers created without sender settings behave as with the default ones.
+		return serial.ToTypedMessage(&proxyman.SenderConfig{})
+	}
 	return serial.ToTypedMessage(h.senderSettings)
 }
 
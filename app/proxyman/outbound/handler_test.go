@@ -3,6 +3,10 @@ package outbound_test
 import (
 	"context"
 	"fmt"
+	"io"
+	gonet "net"
+	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -12,13 +16,23 @@ import (
 	"github.com/xtls/xray-core/app/proxyman"
 	. "github.com/xtls/xray-core/app/proxyman/outbound"
 	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/uuid"
 	core "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/outbound"
+	feature_stats "github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/proxy/vmess"
+	vmessoutbound "github.com/xtls/xray-core/proxy/vmess/outbound"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/pipe"
 )
 
 func TestInterfaces(t *testing.T) {
@@ -87,6 +101,82 @@ func TestOutboundWithStatCounter(t *testing.T) {
 	}
 }
 
+func TestSelectorPatterns(t *testing.T) {
+	ohm, err := New(context.Background(), &proxyman.OutboundConfig{})
+	common.Must(err)
+	v, _ := core.New(&core.Config{})
+	v.AddFeature(ohm)
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	for _, tag := range []string{"proxy-hk-1", "proxy-hk-2", "proxy-us-1", "proxy", "direct"} {
+		h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+			Tag:           tag,
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		})
+		common.Must(err)
+		common.Must(ohm.AddHandler(ctx, h))
+	}
+
+	for selectors, expected := range map[string][]string{
+		// plain selectors match prefixes, and patterns match whole tags
+		"proxy":             {"proxy", "proxy-hk-1", "proxy-hk-2", "proxy-us-1"},
+		"proxy-*-1":         {"proxy-hk-1", "proxy-us-1"},
+		"proxy-hk-?":        {"proxy-hk-1", "proxy-hk-2"},
+		"regexp:^proxy-us":  {"proxy-us-1"},
+		"direct,proxy-hk-*": {"direct", "proxy-hk-1", "proxy-hk-2"},
+		"prox?":             {"proxy"},
+	} {
+		if tags := ohm.Select(strings.Split(selectors, ",")); !reflect.DeepEqual(tags, expected) {
+			t.Error("unexpected tags for selectors ", selectors, ": ", tags)
+		}
+	}
+}
+
+func TestAddHandlerChain(t *testing.T) {
+	ohm, err := New(context.Background(), &proxyman.OutboundConfig{})
+	common.Must(err)
+	v, _ := core.New(&core.Config{})
+	v.AddFeature(ohm)
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	common.Must(ohm.Start())
+	for _, tag := range []string{"hop-1", "hop-2"} {
+		h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+			Tag:           tag,
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+		})
+		common.Must(err)
+		common.Must(ohm.AddHandler(ctx, h))
+	}
+
+	chained := func(tag string, chain ...string) outbound.Handler {
+		h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+			Tag:           tag,
+			ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+				StreamSettings: &internet.StreamConfig{
+					SocketSettings: &internet.SocketConfig{
+						DialerProxy:      chain[len(chain)-1],
+						DialerProxyChain: chain[:len(chain)-1],
+					},
+				},
+			}),
+		})
+		common.Must(err)
+		return h
+	}
+	if err := ohm.AddHandler(ctx, chained("missing", "hop-1", "hop-3", "hop-2")); err == nil {
+		t.Error("added an outbound chained through a missing outbound")
+	}
+	if err := ohm.AddHandler(ctx, chained("self", "self", "hop-2")); err == nil {
+		t.Error("added an outbound chained through itself")
+	}
+	if err := ohm.AddHandler(ctx, chained("chained", "hop-1", "hop-2")); err != nil {
+		t.Error("failed to add a chained outbound: ", err)
+	}
+	if ohm.GetHandler("missing") != nil || ohm.GetHandler("self") != nil {
+		t.Error("the rejected outbounds were added")
+	}
+}
+
 func TestTagsCache(t *testing.T) {
 
 	test_duration := 10 * time.Second
@@ -174,3 +264,224 @@ func TestTagsCache(t *testing.T) {
 	stop_get = true
 	wg_get.Wait()
 }
+
+func TestOutboundPrewarm(t *testing.T) {
+	listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	var accepted atomic.Int32
+	closed := make(chan struct{}, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				var b [1]byte
+				conn.Read(b[:])
+				conn.Close()
+				closed <- struct{}{}
+			}()
+		}
+	}()
+
+	v, _ := core.New(&core.Config{})
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag: "tag",
+		SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+			Prewarm: &proxyman.PrewarmConfig{Connections: 2, MaxIdleTime: 60},
+		}),
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+	})
+	common.Must(err)
+	common.Must(h.Start())
+
+	dest := net.TCPDestination(net.LocalHostIP, net.Port(listener.Addr().(*gonet.TCPAddr).Port))
+	dial := func() stat.Connection {
+		ctx := session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest}})
+		conn, err := h.(*Handler).Dial(ctx, dest)
+		common.Must(err)
+		return conn
+	}
+
+	// the first dial learns the destination
+	conn := dial()
+	defer conn.Close()
+	time.Sleep(500 * time.Millisecond)
+	if n := accepted.Load(); n != 3 {
+		t.Fatal("expected 3 connections, but got ", n)
+	}
+
+	pooled := dial()
+	defer pooled.Close()
+	time.Sleep(500 * time.Millisecond)
+	if n := accepted.Load(); n != 4 {
+		t.Fatal("expected the pool to be refilled, but got ", n, " connections")
+	}
+
+	common.Must(h.Close())
+	for i := 0; i < 2; i++ {
+		select {
+		case <-closed:
+		case <-time.After(2 * time.Second):
+			t.Fatal("idle connections not closed")
+		}
+	}
+	dial().Close()
+	time.Sleep(100 * time.Millisecond)
+	if n := accepted.Load(); n != 5 {
+		t.Error("expected a dial after close, but got ", n, " connections")
+	}
+}
+
+func TestOutboundFailover(t *testing.T) {
+	testOutboundFailover(t, &proxyman.SenderConfig{FallbackTag: "backup"}, func(net.Destination) *serial.TypedMessage {
+		return serial.ToTypedMessage(&freedom.Config{})
+	})
+}
+
+func TestOutboundFailoverMux(t *testing.T) {
+	sender := &proxyman.SenderConfig{
+		FallbackTag:       "backup",
+		MultiplexSettings: &proxyman.MultiplexingConfig{Enabled: true},
+	}
+	id := uuid.New()
+	testOutboundFailover(t, sender, func(server net.Destination) *serial.TypedMessage {
+		return serial.ToTypedMessage(&vmessoutbound.Config{
+			Receiver: &protocol.ServerEndpoint{
+				Address: net.NewIPOrDomain(server.Address),
+				Port:    uint32(server.Port),
+				User: &protocol.User{
+					Account: serial.ToTypedMessage(&vmess.Account{Id: id.String()}),
+				},
+			},
+		})
+	})
+}
+
+// testOutboundFailover dials a closed port through the primary outbound, of the sender and proxy settings,
+// which fails over to a backup outbound.
+func testOutboundFailover(t *testing.T, sender *proxyman.SenderConfig, proxySettings func(net.Destination) *serial.TypedMessage) {
+	listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+	closed, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	closedDest := net.TCPDestination(net.LocalHostIP, net.Port(closed.Addr().(*gonet.TCPAddr).Port))
+	closed.Close()
+
+	ohm, err := New(context.Background(), &proxyman.OutboundConfig{})
+	common.Must(err)
+	v, _ := core.New(&core.Config{
+		App: []*serial.TypedMessage{serial.ToTypedMessage(&stats.Config{})},
+	})
+	v.AddFeature(ohm)
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	ctx = context.WithValue(ctx, "cone", true)
+
+	primary, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag:            "primary",
+		SenderSettings: serial.ToTypedMessage(sender),
+		ProxySettings:  proxySettings(closedDest),
+	})
+	common.Must(err)
+	common.Must(ohm.AddHandler(ctx, primary))
+	backup, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag: "backup",
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{
+			DestinationOverride: &freedom.DestinationOverride{
+				Server: &protocol.ServerEndpoint{
+					Address: net.NewIPOrDomain(net.LocalHostIP),
+					Port:    uint32(listener.Addr().(*gonet.TCPAddr).Port),
+				},
+			},
+		}),
+	})
+	common.Must(err)
+	common.Must(ohm.AddHandler(ctx, backup))
+
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: closedDest, Tag: "primary"}})
+	uplinkReader, uplinkWriter := pipe.New()
+	downlinkReader, downlinkWriter := pipe.New()
+	go primary.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+
+	common.Must(uplinkWriter.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("ping"))}))
+	mb, err := downlinkReader.ReadMultiBufferTimeout(10 * time.Second)
+	common.Must(err)
+	if s := mb.String(); s != "ping" {
+		t.Error("unexpected response through the fallback: ", s)
+	}
+	uplinkWriter.Close()
+
+	counter := v.GetFeature(feature_stats.ManagerType()).(feature_stats.Manager).GetCounter("outbound>>>primary>>>failover")
+	if counter == nil || counter.Value() != 1 {
+		t.Error("expected a failover to be counted")
+	}
+}
+
+func TestOutboundPrewarmExpire(t *testing.T) {
+	listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			defer conn.Close()
+		}
+	}()
+
+	v, _ := core.New(&core.Config{})
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := context.WithValue(context.Background(), xrayKey, v)
+	h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag: "tag",
+		SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+			Prewarm: &proxyman.PrewarmConfig{Connections: 2, MaxIdleTime: 1},
+		}),
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+	})
+	common.Must(err)
+	common.Must(h.Start())
+	defer h.Close()
+
+	dest := net.TCPDestination(net.LocalHostIP, net.Port(listener.Addr().(*gonet.TCPAddr).Port))
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{Target: dest}})
+	conn, err := h.(*Handler).Dial(ctx, dest)
+	common.Must(err)
+	conn.Close()
+
+	// the pooled connections expire unused, and are not dialed again
+	time.Sleep(3500 * time.Millisecond)
+	if n := accepted.Load(); n != 3 {
+		t.Fatal("expected 3 connections, but got ", n)
+	}
+
+	// a session warms the destination up again
+	conn, err = h.(*Handler).Dial(ctx, dest)
+	common.Must(err)
+	conn.Close()
+	time.Sleep(500 * time.Millisecond)
+	if n := accepted.Load(); n != 6 {
+		t.Fatal("expected 6 connections, but got ", n)
+	}
+}
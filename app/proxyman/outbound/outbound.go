@@ -9,7 +9,9 @@ import (
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/strmatcher"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
 )
 
@@ -37,6 +39,12 @@ func (m *Manager) Type() interface{} {
 	return outbound.ManagerType()
 }
 
+// StartDependencies implements features.HasStartDependencies.
+// Outbound handlers may resolve server addresses as soon as they start.
+func (m *Manager) StartDependencies() []interface{} {
+	return []interface{}{dns.ClientType()}
+}
+
 // Start implements core.Feature
 func (m *Manager) Start() error {
 	m.access.Lock()
@@ -104,6 +112,13 @@ func (m *Manager) AddHandler(ctx context.Context, handler outbound.Handler) erro
 	m.access.Lock()
 	defer m.access.Unlock()
 
+	if m.running {
+		// the chains of the config were checked as it was built, but the handlers added at runtime are not
+		if err := m.checkChain(handler); err != nil {
+			return err
+		}
+	}
+
 	m.tagsCache = &sync.Map{}
 
 	if m.defaultHandler == nil {
@@ -113,7 +128,7 @@ func (m *Manager) AddHandler(ctx context.Context, handler outbound.Handler) erro
 	tag := handler.Tag()
 	if len(tag) > 0 {
 		if _, found := m.taggedHandler[tag]; found {
-			return errors.New("existing tag found: " + tag)
+			return errors.New("existing tag found: " + tag).WithCode(errors.CodeAlreadyExists)
 		}
 		m.taggedHandler[tag] = handler
 	} else {
@@ -127,6 +142,27 @@ func (m *Manager) AddHandler(ctx context.Context, handler outbound.Handler) erro
 	return nil
 }
 
+// checkChain checks that the outbounds of the chain that handler dials through exist, and that it is not one of them.
+func (m *Manager) checkChain(handler outbound.Handler) error {
+	h, ok := handler.(*Handler)
+	if !ok || h.StreamSettings() == nil {
+		return nil
+	}
+	sockopt := h.StreamSettings().SocketSettings
+	if len(sockopt.GetDialerProxyChain()) == 0 {
+		return nil
+	}
+	for _, hop := range append([]string{sockopt.GetDialerProxy()}, sockopt.GetDialerProxyChain()...) {
+		if hop == handler.Tag() {
+			return errors.New("outbound ", hop, " can not be in its own chain")
+		}
+		if _, found := m.taggedHandler[hop]; !found {
+			return errors.New("outbound ", handler.Tag(), " is chained through outbound ", hop, ", which does not exist")
+		}
+	}
+	return nil
+}
+
 // RemoveHandler implements outbound.Manager.
 func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	if tag == "" {
@@ -137,6 +173,11 @@ func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 
 	m.tagsCache = &sync.Map{}
 
+	if h, ok := m.taggedHandler[tag].(*Handler); ok {
+		// the sessions of a removed handler go on, but no new one needs the pool or the spider
+		h.drainPrewarm()
+		h.releaseSpider()
+	}
 	delete(m.taggedHandler, tag)
 	if m.defaultHandler != nil && m.defaultHandler.Tag() == tag {
 		m.defaultHandler = nil
@@ -173,9 +214,14 @@ func (m *Manager) Select(selectors []string) []string {
 
 	tags := make([]string, 0, len(selectors))
 
+	// a selector with a pattern matches whole tags, and the others match the prefixes of tags
+	patterns := make([]strmatcher.Matcher, len(selectors))
+	for i, selector := range selectors {
+		patterns[i], _ = strmatcher.NewTagMatcher(selector)
+	}
 	for tag := range m.taggedHandler {
-		for _, selector := range selectors {
-			if strings.HasPrefix(tag, selector) {
+		for i, selector := range selectors {
+			if (patterns[i] == nil && strings.HasPrefix(tag, selector)) || (patterns[i] != nil && patterns[i].Match(tag)) {
 				tags = append(tags, tag)
 				break
 			}
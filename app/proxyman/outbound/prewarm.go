@@ -0,0 +1,225 @@
+package outbound
+
+import (
+	"context"
+	goerrors "errors"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/task"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tcp"
+)
+
+const (
+	// Xray servers close a connection if no request comes within the handshake timeout, 4s by default.
+	defaultPrewarmIdleTime = 3 * time.Second
+	// The proxies dial one server, or a few of them, so the pool learns at most this many destinations.
+	maxPrewarmDestinations = 8
+)
+
+type idleConn struct {
+	conn  stat.Connection
+	since time.Time
+}
+
+// prewarmPool keeps connections to the servers of the handler dialed and handshaked, so that new sessions
+// do not wait for them. The destinations are learnt from the dials of the sessions.
+type prewarmPool struct {
+	sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	handler *Handler
+	size    int
+	maxIdle time.Duration
+	idle    map[net.Destination][]idleConn
+	dialing map[net.Destination]int
+	// taken is when a session last took an idle connection to a destination.
+	taken map[net.Destination]time.Time
+	// cold is set for a destination that could not be dialed, or whose connections expired unused,
+	// and not refilled until a session needs it again.
+	cold    map[net.Destination]bool
+	closed  bool
+	cleanup *task.Periodic
+}
+
+// canPrewarm returns whether the connections of the handler can be dialed ahead of the sessions.
+// Only raw TCP is pooled, as the probe of an idle connection breaks the framing of other transports.
+func (h *Handler) canPrewarm() bool {
+	if h.senderSettings.ProxySettings.HasTag() || h.streamSettings.ProtocolName != "tcp" {
+		return false
+	}
+	if config, ok := h.streamSettings.ProtocolSettings.(*tcp.Config); !ok || config.HeaderSettings != nil {
+		return false
+	}
+	if via := h.senderSettings.Via; via != nil {
+		// the source of these depends on the inbound of the session
+		if domain := via.GetDomain(); domain == "origin" || domain == "srcip" {
+			return false
+		}
+	}
+	return true
+}
+
+func newPrewarmPool(ctx context.Context, h *Handler, config *proxyman.PrewarmConfig) *prewarmPool {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &prewarmPool{
+		ctx:     ctx,
+		cancel:  cancel,
+		handler: h,
+		size:    int(config.Connections),
+		maxIdle: time.Duration(config.MaxIdleTime) * time.Second,
+		idle:    make(map[net.Destination][]idleConn),
+		dialing: make(map[net.Destination]int),
+		taken:   make(map[net.Destination]time.Time),
+		cold:    make(map[net.Destination]bool),
+	}
+	if p.maxIdle == 0 {
+		p.maxIdle = defaultPrewarmIdleTime
+	}
+	p.cleanup = &task.Periodic{
+		Interval: time.Second,
+		Execute:  p.expire,
+	}
+	return p
+}
+
+// Get returns a healthy idle connection to dest, or nil if there is none, and refills the pool in the background.
+func (p *prewarmPool) Get(dest net.Destination) stat.Connection {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	if _, found := p.dialing[dest]; !found {
+		if len(p.dialing) >= maxPrewarmDestinations {
+			return nil
+		}
+		p.dialing[dest] = 0
+	}
+	delete(p.cold, dest)
+	defer p.refillLocked(dest)
+
+	for conns := p.idle[dest]; len(conns) > 0; conns = p.idle[dest] {
+		// the newest is the least likely to have been closed by the server
+		c := conns[len(conns)-1]
+		p.idle[dest] = conns[:len(conns)-1]
+		if time.Since(c.since) < p.maxIdle && isAlive(c.conn) {
+			p.taken[dest] = time.Now()
+			return c.conn
+		}
+		c.conn.Close()
+	}
+	return nil
+}
+
+func (p *prewarmPool) refillLocked(dest net.Destination) {
+	if p.closed || p.cold[dest] {
+		return
+	}
+	for n := len(p.idle[dest]) + p.dialing[dest]; n < p.size; n++ {
+		p.dialing[dest]++
+		go p.dial(dest)
+	}
+}
+
+func (p *prewarmPool) dial(dest net.Destination) {
+	ob := &session.Outbound{
+		Target: dest,
+		Tag:    p.handler.tag,
+	}
+	p.handler.SetOutboundGateway(p.ctx, ob)
+	ctx := session.ContextWithOutbounds(p.ctx, []*session.Outbound{ob})
+	if p.handler.dialTimings != nil {
+		ctx = session.ContextWithDialTimings(ctx, p.handler.dialTimings)
+	}
+	conn, err := internet.Dial(ctx, dest, p.handler.streamSettings)
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.dialing[dest]--
+	if err != nil {
+		if !p.closed {
+			errors.LogInfoInner(p.ctx, err, "failed to prewarm connection to ", dest)
+			p.cold[dest] = true
+		}
+		return
+	}
+	if p.closed {
+		conn.Close()
+		return
+	}
+	p.idle[dest] = append(p.idle[dest], idleConn{conn: conn, since: time.Now()})
+}
+
+// expire closes the connections that have been idle for too long, and replaces them
+// as long as sessions take connections from the pool.
+func (p *prewarmPool) expire() error {
+	p.Lock()
+	defer p.Unlock()
+
+	for dest, conns := range p.idle {
+		kept := conns[:0]
+		for _, c := range conns {
+			if time.Since(c.since) < p.maxIdle {
+				kept = append(kept, c)
+				continue
+			}
+			c.conn.Close()
+			if p.taken[dest].Before(c.since) {
+				// its replacement would most likely expire unused as well
+				p.cold[dest] = true
+			}
+		}
+		p.idle[dest] = kept
+	}
+	for dest := range p.dialing {
+		p.refillLocked(dest)
+	}
+	return nil
+}
+
+// Start implements common.Runnable.
+func (p *prewarmPool) Start() error {
+	return p.cleanup.Start()
+}
+
+// Close implements common.Closable. It closes all the idle connections and aborts the dials.
+func (p *prewarmPool) Close() error {
+	p.cleanup.Close()
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.closed = true
+	p.cancel()
+	for _, conns := range p.idle {
+		for _, c := range conns {
+			c.conn.Close()
+		}
+	}
+	p.idle = nil
+	return nil
+}
+
+// isAlive probes an idle connection with a read that should time out at once.
+// Data or an error means that the server has closed it, or that it cannot be used anymore.
+func isAlive(conn net.Conn) bool {
+	if conn.SetReadDeadline(time.Now().Add(time.Millisecond)) != nil {
+		return false
+	}
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	var netErr net.Error
+	if !goerrors.As(err, &netErr) || !netErr.Timeout() {
+		return false
+	}
+	return conn.SetReadDeadline(time.Time{}) == nil
+}
@@ -0,0 +1,205 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+	"github.com/xtls/xray-core/transport"
+	"golang.org/x/time/rate"
+)
+
+// shaperQuantum is the most bytes granted to a connection in its turn.
+const shaperQuantum = 16 * 1024
+
+// shaper limits the traffic of a direction of all connections of an outbound together. The token bucket is shared,
+// and the connections waiting for it take turns in deficit round-robin, so a busy connection can not starve the others.
+type shaper struct {
+	limiter *rate.Limiter
+	// throttled counts the milliseconds that the traffic is held back, while the limit binds
+	throttled stats.Counter
+
+	access  sync.Mutex
+	waiting []*shaperRequest
+	running bool
+}
+
+// shaperRequest is the bytes that a connection waits for. A connection waits for one request at a time.
+type shaperRequest struct {
+	remaining int
+	done      chan struct{}
+	canceled  atomic.Bool
+}
+
+func newShaper(bytesPerSec uint64, throttled stats.Counter) *shaper {
+	// a burst of one second worth of traffic, like the rate limit of policy
+	burst := int(min(bytesPerSec, uint64(1<<30)))
+	return &shaper{
+		limiter:   rate.NewLimiter(rate.Limit(bytesPerSec), burst),
+		throttled: throttled,
+	}
+}
+
+// wait returns once n bytes are granted, or ctx is done.
+func (s *shaper) wait(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	s.access.Lock()
+	if len(s.waiting) == 0 && s.limiter.AllowN(time.Now(), n) {
+		s.access.Unlock()
+		return nil
+	}
+	r := &shaperRequest{
+		remaining: n,
+		done:      make(chan struct{}),
+	}
+	s.waiting = append(s.waiting, r)
+	if !s.running {
+		s.running = true
+		go s.run()
+	}
+	s.access.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		r.canceled.Store(true)
+		return ctx.Err()
+	}
+}
+
+// run grants the bytes to the waiting requests in turn, until none is left.
+func (s *shaper) run() {
+	for {
+		s.access.Lock()
+		if len(s.waiting) == 0 {
+			s.running = false
+			s.access.Unlock()
+			return
+		}
+		r := s.waiting[0]
+		s.waiting = s.waiting[1:]
+		s.access.Unlock()
+		if r.canceled.Load() {
+			continue
+		}
+
+		n := min(r.remaining, shaperQuantum, s.limiter.Burst())
+		now := time.Now()
+		if delay := s.limiter.ReserveN(now, n).DelayFrom(now); delay > 0 {
+			if s.throttled != nil {
+				s.throttled.Add(delay.Milliseconds())
+			}
+			time.Sleep(delay)
+		}
+		r.remaining -= n
+		if r.remaining == 0 {
+			close(r.done)
+			continue
+		}
+
+		// the rest waits for its next turn, after the other requests
+		s.access.Lock()
+		s.waiting = append(s.waiting, r)
+		s.access.Unlock()
+	}
+}
+
+// bandwidthLimit is the uplink and downlink shapers of an outbound, nil if the direction is unlimited.
+type bandwidthLimit struct {
+	uplink   *shaper
+	downlink *shaper
+}
+
+func newBandwidthLimit(v *core.Instance, tag string, config *proxyman.BandwidthLimitConfig) *bandwidthLimit {
+	l := new(bandwidthLimit)
+	throttled := func(direction string) stats.Counter {
+		if len(tag) == 0 {
+			return nil
+		}
+		statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+		c, _ := stats.GetOrRegisterCounter(statsManager, "outbound>>>"+tag+">>>throttled>>>"+direction)
+		return c
+	}
+	if config.Uplink > 0 {
+		l.uplink = newShaper(config.Uplink, throttled("uplink"))
+	}
+	if config.Downlink > 0 {
+		l.downlink = newShaper(config.Downlink, throttled("downlink"))
+	}
+	return l
+}
+
+// wrap shapes the traffic of link.
+func (l *bandwidthLimit) wrap(ctx context.Context, link *transport.Link) {
+	if l.uplink != nil {
+		link.Reader = &shapedReader{ctx: ctx, shaper: l.uplink, Reader: link.Reader}
+	}
+	if l.downlink != nil {
+		link.Writer = &shapedWriter{ctx: ctx, shaper: l.downlink, Writer: link.Writer}
+	}
+}
+
+// shapedReader delays the payload read until the shaper grants it.
+type shapedReader struct {
+	buf.Reader
+	ctx    context.Context
+	shaper *shaper
+}
+
+func (r *shapedReader) shape(mb buf.MultiBuffer, err error) (buf.MultiBuffer, error) {
+	if !mb.IsEmpty() {
+		if werr := r.shaper.wait(r.ctx, int(mb.Len())); werr != nil {
+			buf.ReleaseMulti(mb)
+			return nil, werr
+		}
+	}
+	return mb, err
+}
+
+func (r *shapedReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	return r.shape(r.Reader.ReadMultiBuffer())
+}
+
+func (r *shapedReader) ReadMultiBufferTimeout(timeout time.Duration) (buf.MultiBuffer, error) {
+	tr, ok := r.Reader.(buf.TimeoutReader)
+	if !ok {
+		return r.ReadMultiBuffer()
+	}
+	return r.shape(tr.ReadMultiBufferTimeout(timeout))
+}
+
+func (r *shapedReader) Interrupt() {
+	common.Interrupt(r.Reader)
+}
+
+// shapedWriter delays the payload written until the shaper grants it.
+type shapedWriter struct {
+	buf.Writer
+	ctx    context.Context
+	shaper *shaper
+}
+
+func (w *shapedWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if err := w.shaper.wait(w.ctx, int(mb.Len())); err != nil {
+		buf.ReleaseMulti(mb)
+		return err
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+func (w *shapedWriter) Close() error {
+	return common.Close(w.Writer)
+}
+
+func (w *shapedWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
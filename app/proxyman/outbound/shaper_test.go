@@ -0,0 +1,65 @@
+package outbound
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+)
+
+func TestShaperFairness(t *testing.T) {
+	throttled := new(stats.Counter)
+	s := newShaper(1024*1024, throttled)
+	// drain the burst
+	s.limiter.AllowN(time.Now(), s.limiter.Burst())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	busy := make(chan struct{})
+	go func() {
+		close(busy)
+		for ctx.Err() == nil {
+			s.wait(ctx, 256*1024)
+		}
+	}()
+	<-busy
+	time.Sleep(50 * time.Millisecond)
+
+	// a quantum of 16 KB takes 16 ms, while the busy request takes 250 ms as a whole
+	start := time.Now()
+	if err := s.wait(ctx, 1024); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Error("the small request is starved for ", elapsed)
+	}
+	if throttled.Value() == 0 {
+		t.Error("expected the throttled time to be counted")
+	}
+}
+
+func TestShaperRate(t *testing.T) {
+	s := newShaper(64*1024, nil)
+	start := time.Now()
+	ctx := context.Background()
+	// the burst of a second, and then a second more
+	for i := 0; i < 8; i++ {
+		if err := s.wait(ctx, 16*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond || elapsed > 2*time.Second {
+		t.Error("unexpected time of 128 KB at 64 KB/s: ", elapsed)
+	}
+}
+
+func TestShaperCancel(t *testing.T) {
+	s := newShaper(1024, nil)
+	s.limiter.AllowN(time.Now(), s.limiter.Burst())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.wait(ctx, 1024*1024); err == nil {
+		t.Error("expected the wait to be canceled")
+	}
+}
@@ -0,0 +1,54 @@
+package outbound
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+// stickyGateways keeps the random address from the CIDR of sendThrough that each destination is sent through,
+// until the destination has no connection for the TTL.
+type stickyGateways struct {
+	sync.Mutex
+	ttl       time.Duration
+	gateways  map[string]*stickyGateway
+	nextSweep time.Time
+}
+
+type stickyGateway struct {
+	address net.Address
+	expire  time.Time
+}
+
+func newStickyGateways(ttl time.Duration) *stickyGateways {
+	return &stickyGateways{
+		ttl:      ttl,
+		gateways: make(map[string]*stickyGateway),
+	}
+}
+
+// get returns the address that dest is sent through, or a new one from pick if there is none.
+func (s *stickyGateways) get(dest net.Address, pick func() net.Address) net.Address {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for key, g := range s.gateways {
+			if now.After(g.expire) {
+				delete(s.gateways, key)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+
+	key := dest.String()
+	g := s.gateways[key]
+	if g == nil || now.After(g.expire) {
+		g = &stickyGateway{address: pick()}
+		s.gateways[key] = g
+	}
+	g.expire = now.Add(s.ttl)
+	return g.address
+}
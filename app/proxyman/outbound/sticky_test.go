@@ -0,0 +1,32 @@
+package outbound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestStickyGateways(t *testing.T) {
+	s := newStickyGateways(time.Hour)
+	picks := 0
+	pick := func() net.Address {
+		picks++
+		return ParseRandomIP(net.ParseAddress("2001:db8:abcd::"), "64")
+	}
+
+	a := s.get(net.DomainAddress("example.com"), pick)
+	if b := s.get(net.DomainAddress("example.com"), pick); b != a {
+		t.Error("expected the same address for the same destination, but got ", a, " and ", b)
+	}
+	s.get(net.DomainAddress("example.org"), pick)
+	if picks != 2 {
+		t.Error("expected an address picked for each destination, but picked ", picks)
+	}
+
+	s.gateways["example.com"].expire = time.Now().Add(-time.Second)
+	s.get(net.DomainAddress("example.com"), pick)
+	if picks != 3 {
+		t.Error("expected a new address after the TTL, but picked ", picks)
+	}
+}
@@ -0,0 +1,37 @@
+package proxyman
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/splithttp"
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// CheckStreamSecurity returns the first TLS setting in stream that p forbids, including the ones of the download
+// connections of XHTTP, with the path of it under streamSettings. It returns nil if stream complies with p.
+func CheckStreamSecurity(stream *internet.StreamConfig, p policy.Security) *tls.PolicyViolation {
+	if stream == nil {
+		return nil
+	}
+	if stream.HasSecuritySettings() {
+		if config, err := stream.GetEffectiveSecuritySettings(); err == nil {
+			if config, ok := config.(*tls.Config); ok {
+				if v := config.CheckPolicy(p); v != nil {
+					v.Field = "streamSettings.tlsSettings." + v.Field
+					return v
+				}
+			}
+		}
+	}
+	if config, err := stream.GetEffectiveTransportSettings(); err == nil {
+		if config, ok := config.(*splithttp.Config); ok {
+			if v := CheckStreamSecurity(config.DownloadSettings, p); v != nil {
+				v.Field = "streamSettings.xhttpSettings.downloadSettings" + strings.TrimPrefix(v.Field, "streamSettings")
+				return v
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package reload
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	grpc "google.golang.org/grpc"
+)
+
+type reloadServer struct {
+	v *core.Instance
+
+	access sync.Mutex
+	// apps holds the app settings in effect, as handlers can be compared to their live settings but apps can't.
+	apps []*serial.TypedMessage
+}
+
+// NewReloadServer creates a ReloadServiceServer doing reloads on the given instance.
+func NewReloadServer(v *core.Instance) ReloadServiceServer {
+	s := &reloadServer{v: v}
+	if config := v.Config(); config != nil {
+		s.apps = config.App
+	}
+	return s
+}
+
+func (s *reloadServer) ReloadConfig(ctx context.Context, request *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	config := request.Config
+	if len(request.Files) > 0 {
+		format := request.Format
+		if format == "" {
+			format = "auto"
+		}
+		c, err := core.LoadConfig(format, cmdarg.Arg(request.Files))
+		if err != nil {
+			return nil, errors.New("failed to load config").Base(err)
+		}
+		config = c
+	}
+	if config == nil {
+		return nil, errors.New("no config to reload")
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	ihm := s.v.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	ohm := s.v.GetFeature(outbound.ManagerType()).(outbound.Manager)
+
+	p := new(plan)
+	if err := p.diffApps(s.apps, config.App); err != nil {
+		return nil, err
+	}
+	p.diffOutbounds(ohm.ListHandlers(ctx), ohm.GetDefaultHandler(), config.Outbound)
+	p.diffInbounds(ihm.ListHandlers(ctx), config.Inbound)
+
+	response := &ReloadConfigResponse{Changes: p.changes}
+	if request.DryRun {
+		return response, nil
+	}
+	if err := p.apply(ctx, s.v); err != nil {
+		if rollbackErr := p.rollback(ctx, s.v); rollbackErr != nil {
+			errors.LogWarningInner(ctx, rollbackErr, "failed to roll back")
+			return nil, errors.New("failed to apply config, the instance is partially reloaded").Base(err)
+		}
+		return nil, errors.New("failed to apply config, rolled back to the previous one").Base(err)
+	}
+	for _, c := range p.changes {
+		if c.RequiresRestart {
+			errors.LogWarning(ctx, "config reloaded, but changes in ", c.Type, " ", c.Tag, " take effect only after restart")
+		}
+	}
+
+	// Keep the settings that were not applied, so that they keep being reported until restart.
+	apps := make([]*serial.TypedMessage, 0, len(config.App))
+	for _, app := range s.apps {
+		if !p.appReloaded(app.Type) {
+			apps = append(apps, app)
+		}
+	}
+	for _, app := range config.App {
+		if p.appReloaded(app.Type) {
+			apps = append(apps, app)
+		}
+	}
+	s.apps = apps
+	return response, nil
+}
+
+func (s *reloadServer) mustEmbedUnimplementedReloadServiceServer() {}
+
+type service struct {
+	v *core.Instance
+}
+
+func (s *service) Register(server *grpc.Server) {
+	RegisterReloadServiceServer(server, NewReloadServer(s.v))
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
+		s := core.MustFromContext(ctx)
+		return &service{v: s}, nil
+	}))
+}
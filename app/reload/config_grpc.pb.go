@@ -0,0 +1,121 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.2
+// source: app/reload/config.proto
+
+package reload
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ReloadService_ReloadConfig_FullMethodName = "/xray.app.reload.ReloadService/ReloadConfig"
+)
+
+// ReloadServiceClient is the client API for ReloadService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReloadServiceClient interface {
+	ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*ReloadConfigResponse, error)
+}
+
+type reloadServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReloadServiceClient(cc grpc.ClientConnInterface) ReloadServiceClient {
+	return &reloadServiceClient{cc}
+}
+
+func (c *reloadServiceClient) ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*ReloadConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReloadConfigResponse)
+	err := c.cc.Invoke(ctx, ReloadService_ReloadConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReloadServiceServer is the server API for ReloadService service.
+// All implementations must embed UnimplementedReloadServiceServer
+// for forward compatibility.
+type ReloadServiceServer interface {
+	ReloadConfig(context.Context, *ReloadConfigRequest) (*ReloadConfigResponse, error)
+	mustEmbedUnimplementedReloadServiceServer()
+}
+
+// UnimplementedReloadServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReloadServiceServer struct{}
+
+func (UnimplementedReloadServiceServer) ReloadConfig(context.Context, *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
+}
+func (UnimplementedReloadServiceServer) mustEmbedUnimplementedReloadServiceServer() {}
+func (UnimplementedReloadServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeReloadServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReloadServiceServer will
+// result in compilation errors.
+type UnsafeReloadServiceServer interface {
+	mustEmbedUnimplementedReloadServiceServer()
+}
+
+func RegisterReloadServiceServer(s grpc.ServiceRegistrar, srv ReloadServiceServer) {
+	// If the following call pancis, it indicates UnimplementedReloadServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ReloadService_ServiceDesc, srv)
+}
+
+func _ReloadService_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReloadServiceServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReloadService_ReloadConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReloadServiceServer).ReloadConfig(ctx, req.(*ReloadConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ReloadService_ServiceDesc is the grpc.ServiceDesc for ReloadService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReloadService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xray.app.reload.ReloadService",
+	HandlerType: (*ReloadServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _ReloadService_ReloadConfig_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "app/reload/config.proto",
+}
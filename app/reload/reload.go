@@ -0,0 +1,497 @@
+package reload
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/app/dns"
+	applog "github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	feature_dns "github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"google.golang.org/protobuf/proto"
+)
+
+// plan is the set of operations turning the running instance into the new config.
+type plan struct {
+	changes         []*Change
+	removeInbounds  []string
+	addInbounds     []*core.InboundHandlerConfig
+	removeOutbounds []string
+	addOutbounds    []*core.OutboundHandlerConfig
+	router          *router.Config
+	dns             *dns.Config
+	log             *applog.Config
+
+	// The settings replaced by the plan, to roll back to.
+	oldInbounds  map[string]*core.InboundHandlerConfig
+	oldOutbounds map[string]*core.OutboundHandlerConfig
+	oldDefault   string
+	oldRouter    *router.Config
+	oldDNS       *dns.Config
+	oldLog       *applog.Config
+	done         progress
+}
+
+// progress is what apply has carried out so far.
+type progress struct {
+	log             bool
+	dns             bool
+	removeOutbounds []string
+	addOutbounds    []string
+	router          bool
+	removeInbounds  []string
+	addInbounds     []string
+}
+
+func (p *plan) add(typ string, tag string, action Change_Action, requiresRestart bool) {
+	p.changes = append(p.changes, &Change{
+		Type:            typ,
+		Tag:             tag,
+		Action:          action,
+		RequiresRestart: requiresRestart,
+	})
+}
+
+// typedMessageEqual compares the decoded settings, as the encoded ones may differ in field order.
+func typedMessageEqual(a, b *serial.TypedMessage) bool {
+	if a == nil || b == nil || a.Type != b.Type {
+		return a == nil && b == nil
+	}
+	ma, errA := a.GetInstance()
+	mb, errB := b.GetInstance()
+	if errA != nil || errB != nil {
+		return proto.Equal(a, b)
+	}
+	return proto.Equal(ma, mb)
+}
+
+func inboundEqual(a, b *core.InboundHandlerConfig) bool {
+	return typedMessageEqual(a.ReceiverSettings, b.ReceiverSettings) && typedMessageEqual(a.ProxySettings, b.ProxySettings)
+}
+
+func outboundEqual(a, b *core.OutboundHandlerConfig) bool {
+	return typedMessageEqual(senderSettings(a), senderSettings(b)) && typedMessageEqual(a.ProxySettings, b.ProxySettings)
+}
+
+// senderSettings returns the sender settings of the config, where missing ones are the same as the default ones.
+func senderSettings(config *core.OutboundHandlerConfig) *serial.TypedMessage {
+	if config.SenderSettings == nil {
+		return serial.ToTypedMessage(&proxyman.SenderConfig{})
+	}
+	return config.SenderSettings
+}
+
+// diffInbounds compares the inbound handlers by tag. Handlers without settings, like the ones created internally, are left alone.
+func (p *plan) diffInbounds(handlers []inbound.Handler, configs []*core.InboundHandlerConfig) {
+	current := make(map[string]*core.InboundHandlerConfig)
+	var currentUntagged, untagged []*core.InboundHandlerConfig
+	for _, h := range handlers {
+		config := &core.InboundHandlerConfig{
+			Tag:              h.Tag(),
+			ReceiverSettings: h.ReceiverSettings(),
+			ProxySettings:    h.ProxySettings(),
+		}
+		if config.ReceiverSettings == nil || config.ProxySettings == nil {
+			continue
+		}
+		if config.Tag == "" {
+			currentUntagged = append(currentUntagged, config)
+		} else {
+			current[config.Tag] = config
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, config := range configs {
+		if config.Tag == "" {
+			untagged = append(untagged, config)
+			continue
+		}
+		seen[config.Tag] = true
+		old, found := current[config.Tag]
+		switch {
+		case !found:
+			p.add("inbound", config.Tag, Change_Add, false)
+			p.addInbounds = append(p.addInbounds, config)
+		case !inboundEqual(old, config):
+			p.add("inbound", config.Tag, Change_Modify, false)
+			p.removeInbounds = append(p.removeInbounds, config.Tag)
+			p.addInbounds = append(p.addInbounds, config)
+		}
+	}
+	for _, tag := range sortedKeys(current) {
+		if !seen[tag] {
+			p.add("inbound", tag, Change_Remove, false)
+			p.removeInbounds = append(p.removeInbounds, tag)
+		}
+	}
+	p.oldInbounds = current
+
+	// Untagged handlers can't be removed individually.
+	if len(untagged) != len(currentUntagged) {
+		p.add("inbound", "", Change_Modify, true)
+		return
+	}
+	for i := range untagged {
+		if !inboundEqual(untagged[i], currentUntagged[i]) {
+			p.add("inbound", "", Change_Modify, true)
+			return
+		}
+	}
+}
+
+// diffOutbounds compares the outbound handlers by tag, like diffInbounds, and checks that the default handler ends up being the first one in the config.
+func (p *plan) diffOutbounds(handlers []outbound.Handler, defaultHandler outbound.Handler, configs []*core.OutboundHandlerConfig) {
+	current := make(map[string]*core.OutboundHandlerConfig)
+	var currentUntagged, untagged []*core.OutboundHandlerConfig
+	for _, h := range handlers {
+		config := &core.OutboundHandlerConfig{
+			Tag:            h.Tag(),
+			SenderSettings: h.SenderSettings(),
+			ProxySettings:  h.ProxySettings(),
+		}
+		if config.ProxySettings == nil {
+			continue
+		}
+		if config.Tag == "" {
+			currentUntagged = append(currentUntagged, config)
+		} else {
+			current[config.Tag] = config
+		}
+	}
+
+	seen := make(map[string]bool)
+	replaced := make(map[string]bool)
+	for _, config := range configs {
+		if config.Tag == "" {
+			untagged = append(untagged, config)
+			continue
+		}
+		seen[config.Tag] = true
+		old, found := current[config.Tag]
+		switch {
+		case !found:
+			p.add("outbound", config.Tag, Change_Add, false)
+			p.addOutbounds = append(p.addOutbounds, config)
+		case !outboundEqual(old, config):
+			p.add("outbound", config.Tag, Change_Modify, false)
+			p.removeOutbounds = append(p.removeOutbounds, config.Tag)
+			p.addOutbounds = append(p.addOutbounds, config)
+			replaced[config.Tag] = true
+		}
+	}
+	for _, tag := range sortedKeys(current) {
+		if !seen[tag] {
+			p.add("outbound", tag, Change_Remove, false)
+			p.removeOutbounds = append(p.removeOutbounds, tag)
+			replaced[tag] = true
+		}
+	}
+	p.oldOutbounds = current
+	if defaultHandler != nil {
+		p.oldDefault = defaultHandler.Tag()
+	}
+
+	if len(untagged) != len(currentUntagged) {
+		p.add("outbound", "", Change_Modify, true)
+	} else {
+		for i := range untagged {
+			if !outboundEqual(untagged[i], currentUntagged[i]) {
+				p.add("outbound", "", Change_Modify, true)
+				break
+			}
+		}
+	}
+
+	// The manager falls back to the first handler added once the default one is removed.
+	if len(configs) == 0 {
+		return
+	}
+	var defaultTag string
+	switch {
+	case defaultHandler != nil && !replaced[defaultHandler.Tag()]:
+		defaultTag = defaultHandler.Tag()
+	case len(p.addOutbounds) > 0:
+		defaultTag = p.addOutbounds[0].Tag
+	}
+	if defaultTag != configs[0].Tag {
+		p.add("defaultOutbound", configs[0].Tag, Change_Modify, true)
+	}
+}
+
+// onlyAccessLogFiltersDiffer returns whether the log settings differ in the access log filters only.
+func onlyAccessLogFiltersDiffer(a, b *applog.Config) bool {
+	a = proto.Clone(a).(*applog.Config)
+	b = proto.Clone(b).(*applog.Config)
+	a.AccessLogFilter = nil
+	b.AccessLogFilter = nil
+	return proto.Equal(a, b)
+}
+
+// diffApps compares the app settings by type. Only routing rules, DNS and access log filters can be reloaded at runtime.
+func (p *plan) diffApps(current []*serial.TypedMessage, configs []*serial.TypedMessage) error {
+	currentByType := make(map[string]*serial.TypedMessage)
+	for _, app := range current {
+		if _, found := currentByType[app.Type]; !found {
+			currentByType[app.Type] = app
+		}
+	}
+	byType := make(map[string]*serial.TypedMessage)
+	for _, app := range configs {
+		if _, found := byType[app.Type]; !found {
+			byType[app.Type] = app
+		}
+	}
+
+	for _, typ := range sortedKeys(currentByType) {
+		if _, found := byType[typ]; !found {
+			p.add("app", typ, Change_Remove, true)
+		}
+	}
+	for _, typ := range sortedKeys(byType) {
+		app := byType[typ]
+		old, found := currentByType[typ]
+		if !found {
+			p.add("app", typ, Change_Add, true)
+			continue
+		}
+		if typedMessageEqual(old, app) {
+			continue
+		}
+		settings, err := app.GetInstance()
+		if err != nil {
+			return errors.New("failed to parse ", typ).Base(err)
+		}
+		oldSettings, err := old.GetInstance()
+		switch s := settings.(type) {
+		case *router.Config:
+			if err != nil || oldSettings.(*router.Config).DomainStrategy != s.DomainStrategy {
+				p.add("routing", typ, Change_Modify, true)
+				continue
+			}
+			p.add("routing", typ, Change_Modify, false)
+			p.router = s
+			p.oldRouter = oldSettings.(*router.Config)
+		case *dns.Config:
+			if err != nil {
+				p.add("dns", typ, Change_Modify, true)
+				continue
+			}
+			p.add("dns", typ, Change_Modify, false)
+			p.dns = s
+			p.oldDNS = oldSettings.(*dns.Config)
+		case *applog.Config:
+			if err != nil || !onlyAccessLogFiltersDiffer(oldSettings.(*applog.Config), s) {
+				p.add("app", typ, Change_Modify, true)
+				continue
+			}
+			p.add("log", typ, Change_Modify, false)
+			p.log = s
+			p.oldLog = oldSettings.(*applog.Config)
+		default:
+			p.add("app", typ, Change_Modify, true)
+		}
+	}
+	return nil
+}
+
+// apply carries out the plan. DNS goes first and inbounds last, so that new inbounds see the new outbounds and rules.
+func (p *plan) apply(ctx context.Context, v *core.Instance) error {
+	if p.log != nil {
+		l, ok := v.GetFeature((*applog.Instance)(nil)).(*applog.Instance)
+		if !ok {
+			return errors.New("log is not reloadable")
+		}
+		if err := l.ReloadAccessLogFilters(p.log); err != nil {
+			return errors.New("failed to reload access log filters").Base(err)
+		}
+		p.done.log = true
+	}
+
+	if p.dns != nil {
+		d, ok := v.GetFeature(feature_dns.ClientType()).(*dns.DNS)
+		if !ok {
+			return errors.New("DNS is not reloadable")
+		}
+		if err := d.Reload(p.dns); err != nil {
+			return errors.New("failed to reload DNS").Base(err)
+		}
+		p.done.dns = true
+	}
+
+	ohm := v.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	for _, tag := range p.removeOutbounds {
+		if err := ohm.RemoveHandler(ctx, tag); err != nil {
+			return errors.New("failed to remove outbound ", tag).Base(err)
+		}
+		p.done.removeOutbounds = append(p.done.removeOutbounds, tag)
+	}
+	for _, config := range p.addOutbounds {
+		if err := core.AddOutboundHandler(v, config); err != nil {
+			// The handler may be added even though it fails to start.
+			if ohm.GetHandler(config.Tag) != nil {
+				p.done.addOutbounds = append(p.done.addOutbounds, config.Tag)
+			}
+			return errors.New("failed to add outbound ", config.Tag).Base(err)
+		}
+		p.done.addOutbounds = append(p.done.addOutbounds, config.Tag)
+	}
+
+	if p.router != nil {
+		r, ok := v.GetFeature(routing.RouterType()).(*router.Router)
+		if !ok {
+			return errors.New("router is not reloadable")
+		}
+		if err := r.ReloadRules(p.router, false); err != nil {
+			return errors.New("failed to reload routing rules").Base(err)
+		}
+		p.done.router = true
+	}
+
+	// Removing an inbound closes its listeners, while the connections already accepted run to completion.
+	ihm := v.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	for _, tag := range p.removeInbounds {
+		if err := ihm.RemoveHandler(ctx, tag); err != nil {
+			return errors.New("failed to remove inbound ", tag).Base(err)
+		}
+		p.done.removeInbounds = append(p.done.removeInbounds, tag)
+	}
+	for _, config := range p.addInbounds {
+		if err := core.AddInboundHandler(v, config); err != nil {
+			if _, err := ihm.GetHandler(ctx, config.Tag); err == nil {
+				p.done.addInbounds = append(p.done.addInbounds, config.Tag)
+			}
+			return errors.New("failed to add inbound ", config.Tag).Base(err)
+		}
+		p.done.addInbounds = append(p.done.addInbounds, config.Tag)
+	}
+	return nil
+}
+
+// rollback undoes what apply has carried out, in the reverse order, and returns the first error among the steps that could not be undone.
+func (p *plan) rollback(ctx context.Context, v *core.Instance) error {
+	var errs []error
+	fail := func(err error, msg ...interface{}) {
+		if err != nil {
+			errs = append(errs, errors.New(msg...).Base(err))
+		}
+	}
+
+	ihm := v.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	for _, tag := range p.done.addInbounds {
+		fail(ihm.RemoveHandler(ctx, tag), "failed to remove inbound ", tag)
+	}
+	for _, tag := range p.done.removeInbounds {
+		fail(core.AddInboundHandler(v, p.oldInbounds[tag]), "failed to add back inbound ", tag)
+	}
+
+	if p.done.router {
+		fail(v.GetFeature(routing.RouterType()).(*router.Router).ReloadRules(p.oldRouter, false), "failed to reload the previous routing rules")
+	}
+
+	ohm := v.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	for _, tag := range p.done.addOutbounds {
+		fail(ohm.RemoveHandler(ctx, tag), "failed to remove outbound ", tag)
+	}
+	// The first one added back becomes the default handler, if the default one was removed.
+	removed := append([]string(nil), p.done.removeOutbounds...)
+	sort.SliceStable(removed, func(i, j int) bool { return removed[i] == p.oldDefault && removed[j] != p.oldDefault })
+	for _, tag := range removed {
+		fail(core.AddOutboundHandler(v, p.oldOutbounds[tag]), "failed to add back outbound ", tag)
+	}
+
+	if p.done.dns {
+		fail(v.GetFeature(feature_dns.ClientType()).(*dns.DNS).Reload(p.oldDNS), "failed to reload the previous DNS settings")
+	}
+
+	if p.done.log {
+		fail(v.GetFeature((*applog.Instance)(nil)).(*applog.Instance).ReloadAccessLogFilters(p.oldLog), "failed to reload the previous access log filters")
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appReloaded returns whether the app settings of the given type are replaced by the plan.
+func (p *plan) appReloaded(typ string) bool {
+	return (p.router != nil && typ == serial.GetMessageType(p.router)) || (p.dns != nil && typ == serial.GetMessageType(p.dns)) ||
+		(p.log != nil && typ == serial.GetMessageType(p.log))
+}
+
+var changeVerbs = map[Change_Action]string{
+	Change_Add:    "added",
+	Change_Remove: "removed",
+	Change_Modify: "modified",
+}
+
+// Summary describes the changes in a line, like "1 inbound added, 2 outbounds modified, routing rules reloaded".
+func Summary(changes []*Change) string {
+	type key struct {
+		typ    string
+		action Change_Action
+	}
+	var keys []key
+	counts := make(map[key]int)
+	restart := 0
+	for _, c := range changes {
+		if c.RequiresRestart {
+			restart++
+			continue
+		}
+		k := key{c.Type, c.Action}
+		if counts[k] == 0 {
+			keys = append(keys, k)
+		}
+		counts[k]++
+	}
+
+	var parts []string
+	for _, k := range keys {
+		switch k.typ {
+		case "routing":
+			parts = append(parts, "routing rules reloaded")
+		case "dns":
+			parts = append(parts, "DNS reloaded")
+		case "log":
+			parts = append(parts, "access log filters reloaded")
+		default:
+			n := counts[k]
+			noun := k.typ
+			if n > 1 {
+				noun += "s"
+			}
+			parts = append(parts, strconv.Itoa(n)+" "+noun+" "+changeVerbs[k.action])
+		}
+	}
+	if restart > 0 {
+		noun := " change requires"
+		if restart > 1 {
+			noun = " changes require"
+		}
+		parts = append(parts, strconv.Itoa(restart)+noun+" restart")
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
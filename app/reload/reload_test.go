@@ -0,0 +1,296 @@
+package reload_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/dns"
+	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/inbound"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
+	. "github.com/xtls/xray-core/app/reload"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	feature_dns "github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	routing_session "github.com/xtls/xray-core/features/routing/session"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	"google.golang.org/protobuf/proto"
+)
+
+func inboundConfig(tag string, port net.Port) *core.InboundHandlerConfig {
+	return &core.InboundHandlerConfig{
+		Tag: tag,
+		ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+			PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(port)}},
+			Listen:   net.NewIPOrDomain(net.LocalHostIP),
+		}),
+		ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+			Address:  net.NewIPOrDomain(net.LocalHostIP),
+			Port:     80,
+			Networks: []net.Network{net.Network_TCP},
+		}),
+	}
+}
+
+func dnsConfig(ip net.IP) *dns.Config {
+	return &dns.Config{
+		StaticHosts: []*dns.Config_HostMapping{
+			{Domain: "example.com", Ip: [][]byte{ip}},
+		},
+	}
+}
+
+func routerConfig(outboundTag string) *router.Config {
+	return &router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				InboundTag: []string{"in"},
+				TargetTag:  &router.RoutingRule_Tag{Tag: outboundTag},
+			},
+		},
+	}
+}
+
+func newConfig(inbounds []*core.InboundHandlerConfig, outbounds []*core.OutboundHandlerConfig, apps ...proto.Message) *core.Config {
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&Config{}),
+		},
+		Inbound:  inbounds,
+		Outbound: outbounds,
+	}
+	for _, app := range apps {
+		config.App = append(config.App, serial.ToTypedMessage(app))
+	}
+	return config
+}
+
+func TestReloadConfig(t *testing.T) {
+	port1 := tcp.PickPort()
+	port2 := tcp.PickPort()
+	direct := &core.OutboundHandlerConfig{Tag: "direct", ProxySettings: serial.ToTypedMessage(&freedom.Config{})}
+	block := &core.OutboundHandlerConfig{Tag: "block", ProxySettings: serial.ToTypedMessage(&blackhole.Config{})}
+
+	server, err := core.New(newConfig(
+		[]*core.InboundHandlerConfig{inboundConfig("in", port1), inboundConfig("old", port2)},
+		[]*core.OutboundHandlerConfig{direct, block},
+		routerConfig("direct"), dnsConfig(net.IP{1, 1, 1, 1}), &log.Config{},
+	))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	reloaded := newConfig(
+		[]*core.InboundHandlerConfig{inboundConfig("in", port2), inboundConfig("new", port1)},
+		[]*core.OutboundHandlerConfig{direct, {Tag: "block", ProxySettings: serial.ToTypedMessage(&freedom.Config{})}},
+		routerConfig("block"), dnsConfig(net.IP{2, 2, 2, 2}), &log.Config{ErrorLogLevel: 1},
+	)
+	reloadServer := NewReloadServer(server)
+
+	resp, err := reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded, DryRun: true})
+	common.Must(err)
+	expected := []*Change{
+		{Type: "dns", Tag: "xray.app.dns.Config", Action: Change_Modify},
+		{Type: "app", Tag: "xray.app.log.Config", Action: Change_Modify, RequiresRestart: true},
+		{Type: "routing", Tag: "xray.app.router.Config", Action: Change_Modify},
+		{Type: "outbound", Tag: "block", Action: Change_Modify},
+		{Type: "inbound", Tag: "in", Action: Change_Modify},
+		{Type: "inbound", Tag: "new", Action: Change_Add},
+		{Type: "inbound", Tag: "old", Action: Change_Remove},
+	}
+	if len(resp.Changes) != len(expected) {
+		t.Fatal("unexpected changes: ", resp.Changes)
+	}
+	for i, c := range resp.Changes {
+		if !proto.Equal(c, expected[i]) {
+			t.Error("unexpected change ", i, ": ", c)
+		}
+	}
+
+	ihm := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if _, err := ihm.GetHandler(context.Background(), "new"); err == nil {
+		t.Fatal("dry run applied changes")
+	}
+
+	_, err = reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded})
+	common.Must(err)
+
+	if _, err := ihm.GetHandler(context.Background(), "old"); err == nil {
+		t.Error("inbound old is not removed")
+	}
+	if _, err := ihm.GetHandler(context.Background(), "new"); err != nil {
+		t.Error(err)
+	}
+	ohm := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	if ohm.GetDefaultHandler().Tag() != "direct" {
+		t.Error("unexpected default outbound: ", ohm.GetDefaultHandler().Tag())
+	}
+	settings, err := ohm.GetHandler("block").ProxySettings().GetInstance()
+	common.Must(err)
+	if _, ok := settings.(*freedom.Config); !ok {
+		t.Error("outbound block is not replaced")
+	}
+
+	ips, _, err := server.GetFeature(feature_dns.ClientType()).(feature_dns.Client).LookupIP("example.com", feature_dns.IPOption{IPv4Enable: true})
+	common.Must(err)
+	if len(ips) != 1 || !ips[0].Equal(net.IP{2, 2, 2, 2}) {
+		t.Error("unexpected ips after reload: ", ips)
+	}
+
+	r := server.GetFeature(routing.RouterType()).(routing.Router)
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: "in"})
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 80),
+	}})
+	route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	if route.GetOutboundTag() != "block" {
+		t.Error("unexpected route after reload: ", route.GetOutboundTag())
+	}
+
+	// Only the settings requiring restart are left.
+	resp, err = reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded, DryRun: true})
+	common.Must(err)
+	if len(resp.Changes) != 1 || resp.Changes[0].Tag != "xray.app.log.Config" {
+		t.Error("unexpected changes after reload: ", resp.Changes)
+	}
+}
+
+func TestReloadConfigRollback(t *testing.T) {
+	port1 := tcp.PickPort()
+	port2 := tcp.PickPort()
+	direct := &core.OutboundHandlerConfig{Tag: "direct", ProxySettings: serial.ToTypedMessage(&freedom.Config{})}
+	block := &core.OutboundHandlerConfig{Tag: "block", ProxySettings: serial.ToTypedMessage(&blackhole.Config{})}
+
+	server, err := core.New(newConfig(
+		[]*core.InboundHandlerConfig{inboundConfig("in", port1)},
+		[]*core.OutboundHandlerConfig{direct, block},
+		routerConfig("direct"), dnsConfig(net.IP{1, 1, 1, 1}),
+	))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	// The new inbound can't listen on the port taken by another one.
+	listener, err := net.Listen("tcp", net.LocalHostIP.String()+":"+port2.String())
+	common.Must(err)
+	defer listener.Close()
+
+	reloaded := newConfig(
+		[]*core.InboundHandlerConfig{inboundConfig("new", port2)},
+		[]*core.OutboundHandlerConfig{{Tag: "proxy", ProxySettings: serial.ToTypedMessage(&freedom.Config{})}, block},
+		routerConfig("block"), dnsConfig(net.IP{2, 2, 2, 2}),
+	)
+	reloadServer := NewReloadServer(server)
+	if _, err := reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded}); err == nil {
+		t.Fatal("expected the reload to fail")
+	}
+
+	ihm := server.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if _, err := ihm.GetHandler(context.Background(), "in"); err != nil {
+		t.Error("inbound in is not added back: ", err)
+	}
+	if _, err := ihm.GetHandler(context.Background(), "new"); err == nil {
+		t.Error("inbound new is not removed")
+	}
+	ohm := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	if ohm.GetHandler("proxy") != nil || ohm.GetHandler("direct") == nil {
+		t.Error("outbounds are not rolled back")
+	}
+	if ohm.GetDefaultHandler() == nil || ohm.GetDefaultHandler().Tag() != "direct" {
+		t.Error("unexpected default outbound: ", ohm.GetDefaultHandler())
+	}
+
+	ips, _, err := server.GetFeature(feature_dns.ClientType()).(feature_dns.Client).LookupIP("example.com", feature_dns.IPOption{IPv4Enable: true})
+	common.Must(err)
+	if len(ips) != 1 || !ips[0].Equal(net.IP{1, 1, 1, 1}) {
+		t.Error("unexpected ips after rollback: ", ips)
+	}
+
+	r := server.GetFeature(routing.RouterType()).(routing.Router)
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: "in"})
+	ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 80),
+	}})
+	route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	if route.GetOutboundTag() != "direct" {
+		t.Error("unexpected route after rollback: ", route.GetOutboundTag())
+	}
+
+	// Nothing is left applied, so the same changes are reported again.
+	resp, err := reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded, DryRun: true})
+	common.Must(err)
+	if Summary(resp.Changes) != "DNS reloaded, routing rules reloaded, 1 outbound added, 1 outbound removed, 1 inbound added, 1 inbound removed" {
+		t.Error("unexpected changes after rollback: ", Summary(resp.Changes))
+	}
+}
+
+func TestReloadAccessLogFilters(t *testing.T) {
+	server, err := core.New(newConfig(nil, nil, &log.Config{}))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	reloaded := newConfig(nil, nil, &log.Config{
+		AccessLogFilter: []*log.AccessLogFilter{{InboundTag: []string{"in"}, Drop: true}},
+	})
+	reloadServer := NewReloadServer(server)
+	resp, err := reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded})
+	common.Must(err)
+	if Summary(resp.Changes) != "access log filters reloaded" {
+		t.Error("unexpected changes: ", Summary(resp.Changes))
+	}
+
+	resp, err = reloadServer.ReloadConfig(context.Background(), &ReloadConfigRequest{Config: reloaded, DryRun: true})
+	common.Must(err)
+	if len(resp.Changes) != 0 {
+		t.Error("unexpected changes after reload: ", resp.Changes)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	cases := []struct {
+		changes []*Change
+		summary string
+	}{
+		{summary: "no changes"},
+		{
+			changes: []*Change{
+				{Type: "inbound", Tag: "a", Action: Change_Add},
+				{Type: "inbound", Tag: "b", Action: Change_Add},
+				{Type: "inbound", Tag: "c", Action: Change_Remove},
+				{Type: "outbound", Tag: "d", Action: Change_Modify},
+				{Type: "routing", Tag: "xray.app.router.Config", Action: Change_Modify},
+			},
+			summary: "2 inbounds added, 1 inbound removed, 1 outbound modified, routing rules reloaded",
+		},
+		{
+			changes: []*Change{
+				{Type: "app", Tag: "xray.app.log.Config", Action: Change_Modify, RequiresRestart: true},
+				{Type: "defaultOutbound", Tag: "direct", Action: Change_Modify, RequiresRestart: true},
+			},
+			summary: "2 changes require restart",
+		},
+	}
+	for _, test := range cases {
+		if summary := Summary(test.changes); summary != test.summary {
+			t.Error("unexpected summary: ", summary)
+		}
+	}
+}
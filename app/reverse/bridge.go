@@ -2,8 +2,10 @@ package reverse
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
+	"github.com/xtls/xray-core/common/dice"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
@@ -11,11 +13,47 @@ import (
 	"github.com/xtls/xray-core/common/signal"
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/pipe"
 	"google.golang.org/protobuf/proto"
 )
 
+// The states of a bridge, as reported by the stats counter "reverse>>>tag>>>state".
+type bridgeState int64
+
+const (
+	// bridgeIdle is the state of a bridge that has not tried to connect yet.
+	bridgeIdle bridgeState = iota
+	bridgeBackoff
+	bridgeConnecting
+	bridgeConnected
+)
+
+func (s bridgeState) String() string {
+	switch s {
+	case bridgeIdle:
+		return "idle"
+	case bridgeBackoff:
+		return "backoff"
+	case bridgeConnecting:
+		return "connecting"
+	case bridgeConnected:
+		return "connected"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultReconnectInterval    = 2 * time.Second
+	defaultMaxReconnectInterval = time.Minute
+	// A connection still not taken by the portal after this long is given up on.
+	bridgeConnectTimeout = 30 * time.Second
+	// The connections a worker takes before another one is opened, if not set.
+	defaultBridgeMaxConcurrency = 16
+)
+
 // Bridge is a component in reverse proxy, that relays connections from Portal to local address.
 type Bridge struct {
 	dispatcher  routing.Dispatcher
@@ -23,6 +61,16 @@ type Bridge struct {
 	domain      string
 	workers     []*BridgeWorker
 	monitorTask *task.Periodic
+
+	reconnectInterval    time.Duration
+	maxReconnectInterval time.Duration
+	minIdleConnections   int
+	maxConcurrency       uint32
+	state                bridgeState
+	failures             int
+	attemptTime          time.Time
+	nextAttempt          time.Time
+	gauge                atomic.Pointer[stats.Counter]
 }
 
 // NewBridge creates a new Bridge instance.
@@ -35,17 +83,68 @@ func NewBridge(config *BridgeConfig, dispatcher routing.Dispatcher) (*Bridge, er
 	}
 
 	b := &Bridge{
-		dispatcher: dispatcher,
-		tag:        config.Tag,
-		domain:     config.Domain,
+		dispatcher:           dispatcher,
+		tag:                  config.Tag,
+		domain:               config.Domain,
+		reconnectInterval:    defaultReconnectInterval,
+		maxReconnectInterval: defaultMaxReconnectInterval,
+		minIdleConnections:   int(config.MinIdleConnections),
+		maxConcurrency:       defaultBridgeMaxConcurrency,
+	}
+	if config.MaxConcurrency > 0 {
+		b.maxConcurrency = config.MaxConcurrency
+	}
+	if config.ReconnectIntervalMs > 0 {
+		b.reconnectInterval = time.Duration(config.ReconnectIntervalMs) * time.Millisecond
+	}
+	if config.MaxReconnectIntervalMs > 0 {
+		b.maxReconnectInterval = time.Duration(config.MaxReconnectIntervalMs) * time.Millisecond
+	}
+	if b.maxReconnectInterval < b.reconnectInterval {
+		b.maxReconnectInterval = b.reconnectInterval
 	}
 	b.monitorTask = &task.Periodic{
 		Execute:  b.monitor,
-		Interval: time.Second * 2,
+		Interval: min(time.Second*2, b.reconnectInterval),
 	}
 	return b, nil
 }
 
+// StateStatName returns the name of the stats counter holding the state of the bridge with the given tag.
+func StateStatName(tag string) string {
+	return "reverse>>>" + tag + ">>>state"
+}
+
+func (b *Bridge) setGauge(c stats.Counter) {
+	c.Set(int64(b.state))
+	b.gauge.Store(&c)
+}
+
+// setState logs the state transitions of the bridge, and only them, as the attempts are repeated for as long as the portal is down.
+func (b *Bridge) setState(state bridgeState, msg ...interface{}) {
+	if state == b.state {
+		return
+	}
+	b.state = state
+	if c := b.gauge.Load(); c != nil {
+		(*c).Set(int64(state))
+	}
+	errors.LogInfo(context.Background(), append([]interface{}{"bridge ", b.tag, " ", state}, msg...)...)
+}
+
+// backoff schedules the next attempt after a failed one, doubling the interval up to the max, with jitter.
+func (b *Bridge) backoff(now time.Time, reason interface{}) {
+	b.failures++
+	interval := b.reconnectInterval
+	for i := 1; i < b.failures && interval < b.maxReconnectInterval; i++ {
+		interval *= 2
+	}
+	interval = min(interval, b.maxReconnectInterval)
+	interval = interval/2 + time.Duration(dice.RollInt63n(int64(interval/2)+1))
+	b.nextAttempt = now.Add(interval)
+	b.setState(bridgeBackoff, " for ", interval, ": ", reason)
+}
+
 func (b *Bridge) cleanup() {
 	var activeWorkers []*BridgeWorker
 
@@ -68,28 +167,86 @@ func (b *Bridge) cleanup() {
 func (b *Bridge) monitor() error {
 	b.cleanup()
 
-	var numConnections uint32
 	var numWorker uint32
+	connected := false
 
 	for _, w := range b.workers {
 		if w.IsActive() {
-			numConnections += w.Connections()
 			numWorker++
+			connected = connected || w.Connected()
 		}
 	}
 
-	if numWorker == 0 || numConnections/numWorker > 16 {
-		worker, err := NewBridgeWorker(b.domain, b.tag, b.dispatcher)
-		if err != nil {
-			errors.LogWarningInner(context.Background(), err, "failed to create bridge worker")
+	now := time.Now()
+	switch {
+	case connected:
+		b.failures = 0
+		b.setState(bridgeConnected)
+		for i := b.poolDeficit(); i > 0; i-- {
+			worker, err := NewBridgeWorker(b.domain, b.tag, b.dispatcher)
+			if err != nil {
+				errors.LogDebugInner(context.Background(), err, "failed to create bridge worker")
+				break
+			}
+			b.workers = append(b.workers, worker)
+		}
+		return nil
+	case numWorker > 0:
+		if now.Sub(b.attemptTime) < bridgeConnectTimeout {
 			return nil
 		}
-		b.workers = append(b.workers, worker)
+		for _, w := range b.workers {
+			w.Worker.Close()
+		}
+		b.workers = nil
+		b.backoff(now, "portal not responding")
+		return nil
+	case b.state == bridgeConnecting:
+		b.backoff(now, "connection closed")
+		return nil
+	case now.Before(b.nextAttempt):
+		return nil
 	}
 
+	worker, err := NewBridgeWorker(b.domain, b.tag, b.dispatcher)
+	if err != nil {
+		b.backoff(now, err)
+		return nil
+	}
+	b.workers = append(b.workers, worker)
+	b.attemptTime = now
+	b.setState(bridgeConnecting)
+
 	return nil
 }
 
+// poolDeficit returns how many workers to open, so that there is one able to take more connections,
+// and at least minIdleConnections of them are standing by without any.
+func (b *Bridge) poolDeficit() int {
+	var idle, available int
+	for _, w := range b.workers {
+		if !w.IsActive() {
+			continue
+		}
+		// Not counting the control connection from the portal.
+		connections := w.Connections()
+		if w.Connected() && connections > 0 {
+			connections--
+		}
+		if connections == 0 {
+			idle++
+		}
+		if connections < b.maxConcurrency {
+			available++
+		}
+	}
+	deficit := b.minIdleConnections - idle
+	if available == 0 {
+		deficit = max(deficit, 1)
+	}
+	return max(deficit, 0)
+}
+
 func (b *Bridge) Start() error {
 	return b.monitorTask.Start()
 }
@@ -104,6 +261,7 @@ type BridgeWorker struct {
 	Dispatcher routing.Dispatcher
 	State      Control_State
 	Timer      *signal.ActivityTimer
+	connected  atomic.Bool
 }
 
 func NewBridgeWorker(domain string, tag string, d routing.Dispatcher) (*BridgeWorker, error) {
@@ -158,6 +316,11 @@ func (w *BridgeWorker) Closed() bool {
 	return w.Worker.Closed()
 }
 
+// Connected returns whether the portal has taken the worker, by opening the control connection.
+func (w *BridgeWorker) Connected() bool {
+	return w.connected.Load()
+}
+
 func (w *BridgeWorker) Connections() uint32 {
 	return w.Worker.ActiveConnections()
 }
@@ -205,6 +368,7 @@ func (w *BridgeWorker) Dispatch(ctx context.Context, dest net.Destination) (*tra
 		return w.Dispatcher.Dispatch(ctx, dest)
 	}
 
+	w.connected.Store(true)
 	opt := []pipe.Option{pipe.WithSizeLimit(16 * 1024)}
 	uplinkReader, uplinkWriter := pipe.New(opt...)
 	downlinkReader, downlinkWriter := pipe.New(opt...)
@@ -233,6 +397,7 @@ func (w *BridgeWorker) DispatchLink(ctx context.Context, dest net.Destination, l
 	if d, ok := w.Dispatcher.(routing.WrapLinkDispatcher); ok {
 		link = d.WrapLink(ctx, link)
 	}
+	w.connected.Store(true)
 	w.handleInternalConn(link)
 
 	return nil
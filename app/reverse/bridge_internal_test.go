@@ -0,0 +1,166 @@
+package reverse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/mux"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+type testDispatcher struct {
+	links []*pipe.Writer
+	fail  bool
+	calls int
+}
+
+func (*testDispatcher) Type() interface{} { return nil }
+func (*testDispatcher) Start() error      { return nil }
+func (*testDispatcher) Close() error      { return nil }
+
+// Dispatch keeps the links to the portal, so that the test can play the portal. The other links are the connections relayed by the bridge.
+func (d *testDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	uplinkReader, uplinkWriter := pipe.New()
+	_, downlinkWriter := pipe.New()
+	if dest.Address.String() == "portal.example.com" {
+		d.calls++
+		if d.fail {
+			return nil, errors.New("portal unreachable")
+		}
+		d.links = append(d.links, uplinkWriter)
+	}
+	return &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, nil
+}
+
+func (*testDispatcher) DispatchLink(ctx context.Context, dest net.Destination, link *transport.Link) error {
+	return nil
+}
+
+func TestBridgeBackoff(t *testing.T) {
+	d := &testDispatcher{fail: true}
+	b, err := NewBridge(&BridgeConfig{Tag: "bridge", Domain: "portal.example.com", ReconnectIntervalMs: 1000, MaxReconnectIntervalMs: 4000}, d)
+	common.Must(err)
+	gauge := new(stats.Counter)
+	gauge.Set(-1)
+	b.setGauge(gauge)
+	if b.state != bridgeIdle || gauge.Value() != int64(bridgeIdle) || b.state.String() != "idle" {
+		t.Fatal("expected idle, but got ", b.state)
+	}
+
+	for i, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		now := time.Now()
+		common.Must(b.monitor())
+		if b.state != bridgeBackoff || gauge.Value() != int64(bridgeBackoff) {
+			t.Fatal("expected backoff, but got ", b.state)
+		}
+		if delay := b.nextAttempt.Sub(now); delay < max/2 || delay > max+100*time.Millisecond {
+			t.Error("attempt ", i, ": unexpected delay ", delay)
+		}
+		calls := d.calls
+		common.Must(b.monitor())
+		if d.calls != calls {
+			t.Error("expected no attempt before the delay")
+		}
+		b.nextAttempt = time.Now()
+	}
+
+	d.fail = false
+	common.Must(b.monitor())
+	if b.state != bridgeConnecting || gauge.Value() != int64(bridgeConnecting) || len(b.workers) != 1 {
+		t.Fatal("expected connecting, but got ", b.state)
+	}
+
+	b.workers[0].connected.Store(true)
+	common.Must(b.monitor())
+	if b.state != bridgeConnected || gauge.Value() != int64(bridgeConnected) || b.failures != 0 {
+		t.Fatal("expected connected, but got ", b.state)
+	}
+
+	// The portal goes away, and the bridge reconnects right away.
+	common.Must(d.links[0].Close())
+	time.Sleep(100 * time.Millisecond)
+	common.Must(b.monitor())
+	if b.state != bridgeConnecting || len(b.workers) != 1 {
+		t.Fatal("expected reconnecting, but got ", b.state)
+	}
+
+	// The new connection is not taken by the portal in time.
+	b.attemptTime = time.Now().Add(-bridgeConnectTimeout)
+	common.Must(b.monitor())
+	if b.state != bridgeBackoff || len(b.workers) != 0 || b.failures != 1 {
+		t.Fatal("expected backoff after the connect timeout, but got ", b.state)
+	}
+}
+
+// openSession opens a mux session on the link to the portal, like the portal does for the control connection and the relayed ones.
+func openSession(link *pipe.Writer, id uint16, dest net.Destination) *mux.Writer {
+	w := mux.NewWriter(id, dest, link, protocol.TransferTypeStream, [8]byte{}, nil)
+	common.Must(w.WriteMultiBuffer(buf.MultiBuffer{}))
+	return w
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timeout")
+}
+
+func TestBridgeIdlePool(t *testing.T) {
+	d := &testDispatcher{}
+	b, err := NewBridge(&BridgeConfig{Tag: "bridge", Domain: "portal.example.com", MinIdleConnections: 2, MaxConcurrency: 2}, d)
+	common.Must(err)
+
+	common.Must(b.monitor())
+	if b.state != bridgeConnecting || len(b.workers) != 1 {
+		t.Fatal("expected a connecting worker, but got ", len(b.workers))
+	}
+	openSession(d.links[0], 1, net.UDPDestination(net.DomainAddress(internalDomain), 0))
+	waitFor(t, b.workers[0].Connected)
+
+	common.Must(b.monitor())
+	if b.state != bridgeConnected || len(b.workers) != 2 {
+		t.Fatal("expected 2 idle workers, but got ", len(b.workers))
+	}
+	common.Must(b.monitor())
+	if len(b.workers) != 2 {
+		t.Fatal("expected the pool to stay at 2 idle workers, but got ", len(b.workers))
+	}
+	openSession(d.links[1], 1, net.UDPDestination(net.DomainAddress(internalDomain), 0))
+	waitFor(t, b.workers[1].Connected)
+
+	// A burst takes all the standby workers.
+	var sessions []*mux.Writer
+	for i, link := range d.links {
+		for id := uint16(2); id < 4; id++ {
+			sessions = append(sessions, openSession(link, id, net.TCPDestination(net.DomainAddress("example.com"), 80)))
+		}
+		worker := b.workers[i]
+		waitFor(t, func() bool { return worker.Connections() == 3 })
+	}
+	common.Must(b.monitor())
+	if len(b.workers) != 4 {
+		t.Fatal("expected the pool refilled with 2 idle workers, but got ", len(b.workers))
+	}
+
+	for _, s := range sessions {
+		common.Must(s.Close())
+	}
+	worker := b.workers[0]
+	waitFor(t, func() bool { return worker.Connections() == 1 })
+	common.Must(b.monitor())
+	if len(b.workers) != 4 {
+		t.Fatal("expected the idle workers kept open, but got ", len(b.workers))
+	}
+}
@@ -2,6 +2,7 @@ package reverse
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,11 +22,12 @@ import (
 )
 
 type Portal struct {
-	ohm    outbound.Manager
-	tag    string
-	domain string
-	picker *StaticMuxPicker
-	client *mux.ClientManager
+	ohm          outbound.Manager
+	tag          string
+	domains      []string
+	domainPrefix string
+	strategy     mux.ClientStrategy
+	picker       *StaticMuxPicker
 }
 
 func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
@@ -33,7 +35,12 @@ func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
 		return nil, errors.New("portal tag is empty")
 	}
 
-	if config.Domain == "" {
+	var domains []string
+	if config.Domain != "" {
+		domains = append(domains, config.Domain)
+	}
+	domains = append(domains, config.Domains...)
+	if len(domains) == 0 && config.DomainPrefix == "" {
 		return nil, errors.New("portal domain is empty")
 	}
 
@@ -42,17 +49,39 @@ func NewPortal(config *PortalConfig, ohm outbound.Manager) (*Portal, error) {
 		return nil, err
 	}
 
+	var strategy mux.ClientStrategy
+	if config.MaxConcurrency > 0 {
+		// One more for the control connection.
+		strategy.MaxConcurrency = config.MaxConcurrency + 1
+	}
+
 	return &Portal{
-		ohm:    ohm,
-		tag:    config.Tag,
-		domain: config.Domain,
-		picker: picker,
-		client: &mux.ClientManager{
-			Picker: picker,
-		},
+		ohm:          ohm,
+		tag:          config.Tag,
+		domains:      domains,
+		domainPrefix: config.DomainPrefix,
+		strategy:     strategy,
+		picker:       picker,
 	}, nil
 }
 
+// bridgeOf returns the bridge the destination stands for, or empty if it is not one of the bridges of the portal.
+func (p *Portal) bridgeOf(dest net.Destination) string {
+	if !dest.Address.Family().IsDomain() {
+		return ""
+	}
+	domain := dest.Address.Domain()
+	if p.domainPrefix != "" && strings.HasPrefix(domain, p.domainPrefix) {
+		return domain
+	}
+	for _, d := range p.domains {
+		if domain == d {
+			return domain
+		}
+	}
+	return ""
+}
+
 func (p *Portal) Start() error {
 	return p.ohm.AddHandler(context.Background(), &Outbound{
 		portal: p,
@@ -71,13 +100,13 @@ func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) err
 		return errors.New("outbound metadata not found").AtError()
 	}
 
-	if isDomain(ob.Target, p.domain) {
-		muxClient, err := mux.NewClientWorker(*link, mux.ClientStrategy{})
+	if bridge := p.bridgeOf(ob.Target); bridge != "" {
+		muxClient, err := mux.NewClientWorker(*link, p.strategy)
 		if err != nil {
 			return errors.New("failed to create mux client worker").Base(err).AtWarning()
 		}
 
-		worker, err := NewPortalWorker(muxClient)
+		worker, err := NewPortalWorker(muxClient, bridge)
 		if err != nil {
 			return errors.New("failed to create portal worker").Base(err)
 		}
@@ -98,7 +127,47 @@ func (p *Portal) HandleConnection(ctx context.Context, link *transport.Link) err
 		link.Writer = &buf.EndpointOverrideWriter{Writer: link.Writer, Dest: ob.Target.Address, OriginalDest: ob.OriginalTarget.Address}
 	}
 
-	return p.client.Dispatch(ctx, link)
+	return p.dispatch(ctx, link)
+}
+
+// dispatch sends the connection to one of the bridges, the same one as the earlier connections from the same source if possible.
+func (p *Portal) dispatch(ctx context.Context, link *transport.Link) error {
+	var source string
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
+		source = inbound.Source.Address.String()
+	}
+	for i := 0; i < 16; i++ {
+		worker, err := p.picker.PickFor(source)
+		if err != nil {
+			return err
+		}
+		if worker.Dispatch(ctx, &transport.Link{
+			Reader: link.Reader,
+			Writer: &portalWriter{Writer: link.Writer, worker: worker},
+		}) {
+			return nil
+		}
+	}
+
+	return errors.New("unable to find an available mux client").AtWarning()
+}
+
+// portalWriter breaks the connection, instead of closing it, when the bridge disconnects in the middle of it,
+// so that the client sees an error rather than a complete response.
+type portalWriter struct {
+	buf.Writer
+	worker *mux.ClientWorker
+}
+
+func (w *portalWriter) Close() error {
+	if w.worker.Closed() {
+		return common.Interrupt(w.Writer)
+	}
+	return common.Close(w.Writer)
+}
+
+func (w *portalWriter) Interrupt() {
+	common.Interrupt(w.Writer)
 }
 
 type Outbound struct {
@@ -136,14 +205,26 @@ func (o *Outbound) ProxySettings() *serial.TypedMessage {
 	return nil
 }
 
+// The clients not seen for this long may go to another bridge.
+const affinityTimeout = 10 * time.Minute
+
+type bridgeAffinity struct {
+	bridge   string
+	lastUsed time.Time
+}
+
 type StaticMuxPicker struct {
-	access  sync.Mutex
-	workers []*PortalWorker
-	cTask   *task.Periodic
+	access   sync.Mutex
+	workers  []*PortalWorker
+	cTask    *task.Periodic
+	next     int
+	affinity map[string]*bridgeAffinity
 }
 
 func NewStaticMuxPicker() (*StaticMuxPicker, error) {
-	p := &StaticMuxPicker{}
+	p := &StaticMuxPicker{
+		affinity: make(map[string]*bridgeAffinity),
+	}
 	p.cTask = &task.Periodic{
 		Execute:  p.cleanup,
 		Interval: time.Second * 30,
@@ -169,10 +250,47 @@ func (p *StaticMuxPicker) cleanup() error {
 		p.workers = activeWorkers
 	}
 
+	now := time.Now()
+	for source, a := range p.affinity {
+		if now.Sub(a.lastUsed) > affinityTimeout || !p.hasBridge(a.bridge) {
+			delete(p.affinity, source)
+		}
+	}
+
 	return nil
 }
 
+func (p *StaticMuxPicker) hasBridge(bridge string) bool {
+	for _, w := range p.workers {
+		if w.bridge == bridge {
+			return true
+		}
+	}
+	return false
+}
+
+// preferred returns whether w is a better choice than current, which may be nil.
+// The least busy worker that is not draining is the best, then the least busy one.
+func preferred(w *PortalWorker, current *PortalWorker) bool {
+	if w.IsFull() {
+		return false
+	}
+	if current == nil {
+		return true
+	}
+	if w.draining != current.draining {
+		return !w.draining
+	}
+	return w.client.ActiveConnections() < current.client.ActiveConnections()
+}
+
 func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
+	return p.PickFor("")
+}
+
+// PickFor picks a worker for a connection from the given source. The connected bridges take the new sources in turn,
+// and a source sticks to its bridge as long as the bridge stays connected.
+func (p *StaticMuxPicker) PickFor(source string) (*mux.ClientWorker, error) {
 	p.access.Lock()
 	defer p.access.Unlock()
 
@@ -180,49 +298,73 @@ func (p *StaticMuxPicker) PickAvailable() (*mux.ClientWorker, error) {
 		return nil, errors.New("empty worker list")
 	}
 
-	var minIdx int = -1
-	var minConn uint32 = 9999
-	for i, w := range p.workers {
-		if w.draining {
-			continue
-		}
-		if w.IsFull() {
-			continue
+	var bridges []string
+	best := make(map[string]*PortalWorker)
+	for _, w := range p.workers {
+		current, found := best[w.bridge]
+		if !found {
+			bridges = append(bridges, w.bridge)
 		}
-		if w.client.ActiveConnections() < minConn {
-			minConn = w.client.ActiveConnections()
-			minIdx = i
+		if preferred(w, current) {
+			current = w
 		}
+		best[w.bridge] = current
 	}
 
-	if minIdx == -1 {
-		for i, w := range p.workers {
-			if w.IsFull() {
-				continue
-			}
-			if w.client.ActiveConnections() < minConn {
-				minConn = w.client.ActiveConnections()
-				minIdx = i
-			}
+	if a, found := p.affinity[source]; found && source != "" {
+		if w := best[a.bridge]; w != nil {
+			a.lastUsed = time.Now()
+			return w.client, nil
 		}
 	}
 
-	if minIdx != -1 {
-		return p.workers[minIdx].client, nil
+	for i := range bridges {
+		bridge := bridges[(p.next+i)%len(bridges)]
+		if w := best[bridge]; w != nil {
+			p.next = (p.next + i + 1) % len(bridges)
+			if source != "" {
+				p.affinity[source] = &bridgeAffinity{bridge: bridge, lastUsed: time.Now()}
+			}
+			return w.client, nil
+		}
 	}
 
 	return nil, errors.New("no mux client worker available")
 }
 
+// AddWorker adds the worker to the rotation, until its bridge disconnects.
 func (p *StaticMuxPicker) AddWorker(worker *PortalWorker) {
 	p.access.Lock()
 	defer p.access.Unlock()
 
 	p.workers = append(p.workers, worker)
+	go func() {
+		<-worker.client.WaitClosed()
+		p.removeWorker(worker)
+	}()
+}
+
+func (p *StaticMuxPicker) removeWorker(worker *PortalWorker) {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	for i, w := range p.workers {
+		if w == worker {
+			p.workers = append(p.workers[:i:i], p.workers[i+1:]...)
+			w.timer.SetTimeout(0)
+			break
+		}
+	}
+	for source, a := range p.affinity {
+		if a.bridge == worker.bridge && !p.hasBridge(worker.bridge) {
+			delete(p.affinity, source)
+		}
+	}
 }
 
 type PortalWorker struct {
 	client   *mux.ClientWorker
+	bridge   string
 	control  *task.Periodic
 	writer   buf.Writer
 	reader   buf.Reader
@@ -231,7 +373,7 @@ type PortalWorker struct {
 	timer    *signal.ActivityTimer
 }
 
-func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
+func NewPortalWorker(client *mux.ClientWorker, bridge string) (*PortalWorker, error) {
 	opt := []pipe.Option{pipe.WithSizeLimit(16 * 1024)}
 	uplinkReader, uplinkWriter := pipe.New(opt...)
 	downlinkReader, downlinkWriter := pipe.New(opt...)
@@ -253,6 +395,7 @@ func NewPortalWorker(client *mux.ClientWorker) (*PortalWorker, error) {
 	}
 	w := &PortalWorker{
 		client: client,
+		bridge: bridge,
 		reader: downlinkReader,
 		writer: uplinkWriter,
 		timer:  signal.CancelAfterInactivity(ctx, terminate, 24*time.Hour), // // prevent leak
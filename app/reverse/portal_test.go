@@ -2,9 +2,13 @@ package reverse_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/xtls/xray-core/app/reverse"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/mux"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
 )
 
 func TestStaticPickerEmpty(t *testing.T) {
@@ -18,3 +22,55 @@ func TestStaticPickerEmpty(t *testing.T) {
 		t.Error("expected nil worker, but not nil")
 	}
 }
+
+func newPortalWorker(bridge string) *reverse.PortalWorker {
+	reader, _ := pipe.New()
+	_, writer := pipe.New()
+	client, err := mux.NewClientWorker(transport.Link{Reader: reader, Writer: writer}, mux.ClientStrategy{})
+	common.Must(err)
+	worker, err := reverse.NewPortalWorker(client, bridge)
+	common.Must(err)
+	return worker
+}
+
+func TestStaticPickerBridges(t *testing.T) {
+	picker, err := reverse.NewStaticMuxPicker()
+	common.Must(err)
+
+	for _, bridge := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		picker.AddWorker(newPortalWorker(bridge))
+	}
+
+	picked := make(map[*mux.ClientWorker]bool)
+	for i := 0; i < 3; i++ {
+		client, err := picker.PickFor("")
+		common.Must(err)
+		picked[client] = true
+	}
+	if len(picked) != 3 {
+		t.Error("expected the bridges to take turns, but got ", len(picked), " of them")
+	}
+
+	pinned, err := picker.PickFor("10.0.0.1")
+	common.Must(err)
+	for i := 0; i < 5; i++ {
+		if client, _ := picker.PickFor("10.0.0.1"); client != pinned {
+			t.Fatal("expected the same bridge for the same source")
+		}
+	}
+
+	common.Must(pinned.Close())
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 6; i++ {
+		client, err := picker.PickFor("")
+		common.Must(err)
+		if client == pinned {
+			t.Fatal("expected the disconnected bridge out of rotation")
+		}
+	}
+	moved, err := picker.PickFor("10.0.0.1")
+	common.Must(err)
+	if moved == pinned {
+		t.Error("expected the source to move to a connected bridge")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	core "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
 )
 
 const (
@@ -26,8 +27,12 @@ func isInternalDomain(dest net.Destination) bool {
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		r := new(Reverse)
-		if err := core.RequireFeatures(ctx, func(d routing.Dispatcher, om outbound.Manager) error {
-			return r.Init(config.(*Config), d, om)
+		if err := core.RequireFeatures(ctx, func(d routing.Dispatcher, om outbound.Manager, sm stats.Manager) error {
+			if err := r.Init(config.(*Config), d, om); err != nil {
+				return err
+			}
+			r.registerStats(sm)
+			return nil
 		}); err != nil {
 			return nil, err
 		}
@@ -60,6 +65,15 @@ func (r *Reverse) Init(config *Config, d routing.Dispatcher, ohm outbound.Manage
 	return nil
 }
 
+// registerStats registers the state counters of the bridges.
+func (r *Reverse) registerStats(sm stats.Manager) {
+	for _, b := range r.bridges {
+		if c, _ := stats.GetOrRegisterCounter(sm, StateStatName(b.tag)); c != nil {
+			b.setGauge(c)
+		}
+	}
+}
+
 func (r *Reverse) Type() interface{} {
 	return (*Reverse)(nil)
 }
@@ -146,7 +146,7 @@ func (r *Router) GetPrincipleTarget(tag string) ([]string, error) {
 		}
 		return nil, errors.New("unsupported GetPrincipleTarget")
 	}
-	return nil, errors.New("cannot find tag")
+	return nil, errors.New("cannot find tag").WithCode(errors.CodeNotFound)
 }
 
 // SetOverrideTarget implements routing.BalancerOverrider
@@ -155,7 +155,7 @@ func (r *Router) SetOverrideTarget(tag, target string) error {
 		b.override.Put(target)
 		return nil
 	}
-	return errors.New("cannot find tag")
+	return errors.New("cannot find tag").WithCode(errors.CodeNotFound)
 }
 
 // GetOverrideTarget implements routing.BalancerOverrider
@@ -163,5 +163,5 @@ func (r *Router) GetOverrideTarget(tag string) (string, error) {
 	if b, ok := r.balancers[tag]; ok {
 		return b.override.Get(), nil
 	}
-	return "", errors.New("cannot find tag")
+	return "", errors.New("cannot find tag").WithCode(errors.CodeNotFound)
 }
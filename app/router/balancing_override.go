@@ -15,7 +15,7 @@ func (r *Router) OverrideBalancer(balancer string, target string) error {
 		}
 	}
 	if b == nil {
-		return errors.New("balancer '", balancer, "' not found")
+		return errors.New("balancer '", balancer, "' not found").WithCode(errors.CodeNotFound)
 	}
 	b.override.Put(target)
 	return nil
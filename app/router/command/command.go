@@ -50,21 +50,21 @@ func (s *routingServer) OverrideBalancerTarget(ctx context.Context, request *Ove
 	if bo, ok := s.router.(routing.BalancerOverrider); ok {
 		return &OverrideBalancerTargetResponse{}, bo.SetOverrideTarget(request.BalancerTag, request.Target)
 	}
-	return nil, errors.New("unsupported router implementation")
+	return nil, errors.New("unsupported router implementation").WithCode(errors.CodeUnimplemented)
 }
 
 func (s *routingServer) AddRule(ctx context.Context, request *AddRuleRequest) (*AddRuleResponse, error) {
 	if bo, ok := s.router.(routing.Router); ok {
 		return &AddRuleResponse{}, bo.AddRule(request.Config, request.ShouldAppend)
 	}
-	return nil, errors.New("unsupported router implementation")
+	return nil, errors.New("unsupported router implementation").WithCode(errors.CodeUnimplemented)
 
 }
 func (s *routingServer) RemoveRule(ctx context.Context, request *RemoveRuleRequest) (*RemoveRuleResponse, error) {
 	if bo, ok := s.router.(routing.Router); ok {
 		return &RemoveRuleResponse{}, bo.RemoveRule(request.RuleTag)
 	}
-	return nil, errors.New("unsupported router implementation")
+	return nil, errors.New("unsupported router implementation").WithCode(errors.CodeUnimplemented)
 }
 
 // NewRoutingServer creates a statistics service with statistics manager.
@@ -77,7 +77,7 @@ func NewRoutingServer(router routing.Router, routingStats stats.Channel) Routing
 
 func (s *routingServer) TestRoute(ctx context.Context, request *TestRouteRequest) (*RoutingContext, error) {
 	if request.RoutingContext == nil {
-		return nil, errors.New("Invalid routing request.")
+		return nil, errors.New("Invalid routing request.").WithCode(errors.CodeInvalidConfig)
 	}
 	route, err := s.router.PickRoute(AsRoutingContext(request.RoutingContext))
 	if err != nil {
@@ -92,7 +92,7 @@ func (s *routingServer) TestRoute(ctx context.Context, request *TestRouteRequest
 
 func (s *routingServer) SubscribeRoutingStats(request *SubscribeRoutingStatsRequest, stream RoutingService_SubscribeRoutingStatsServer) error {
 	if s.routingStats == nil {
-		return errors.New("Routing statistics not enabled.")
+		return errors.New("Routing statistics not enabled.").WithCode(errors.CodeUnavailable)
 	}
 	genMessage := AsProtobufMessage(request.FieldSelectors)
 	subscriber, err := stats.SubscribeRunnableChannel(s.routingStats)
@@ -30,6 +30,12 @@ const (
 // RoutingServiceClient is the client API for RoutingService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Failed calls carry a stable gRPC status code, as in HandlerService:
+// NOT_FOUND for unknown balancers, ALREADY_EXISTS for duplicate rule and
+// balancer tags, INVALID_ARGUMENT for rules and requests that cannot be
+// applied, UNAVAILABLE if routing statistics are not enabled, and
+// UNIMPLEMENTED if the router does not support the call.
 type RoutingServiceClient interface {
 	SubscribeRoutingStats(ctx context.Context, in *SubscribeRoutingStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RoutingContext], error)
 	TestRoute(ctx context.Context, in *TestRouteRequest, opts ...grpc.CallOption) (*RoutingContext, error)
@@ -119,6 +125,12 @@ func (c *routingServiceClient) RemoveRule(ctx context.Context, in *RemoveRuleReq
 // RoutingServiceServer is the server API for RoutingService service.
 // All implementations must embed UnimplementedRoutingServiceServer
 // for forward compatibility.
+//
+// Failed calls carry a stable gRPC status code, as in HandlerService:
+// NOT_FOUND for unknown balancers, ALREADY_EXISTS for duplicate rule and
+// balancer tags, INVALID_ARGUMENT for rules and requests that cannot be
+// applied, UNAVAILABLE if routing statistics are not enabled, and
+// UNIMPLEMENTED if the router does not support the call.
 type RoutingServiceServer interface {
 	SubscribeRoutingStats(*SubscribeRoutingStatsRequest, grpc.ServerStreamingServer[RoutingContext]) error
 	TestRoute(context.Context, *TestRouteRequest) (*RoutingContext, error)
@@ -40,6 +40,10 @@ func (c routingContext) GetVlessRoute() net.Port {
 	return net.Port(c.RoutingContext.GetVlessRoute())
 }
 
+func (c routingContext) GetSessionID() uint32 {
+	return c.RoutingContext.GetSessionId()
+}
+
 func (c routingContext) GetRuleTag() string {
 	return ""
 }
@@ -76,6 +80,7 @@ var fieldMap = map[string]func(*RoutingContext, routing.Route){
 	"attributes":     func(s *RoutingContext, r routing.Route) { s.Attributes = r.GetAttributes() },
 	"outbound_group": func(s *RoutingContext, r routing.Route) { s.OutboundGroupTags = r.GetOutboundGroupTags() },
 	"outbound":       func(s *RoutingContext, r routing.Route) { s.OutboundTag = r.GetOutboundTag() },
+	"session":        func(s *RoutingContext, r routing.Route) { s.SessionId = r.GetSessionID() },
 }
 
 // AsProtobufMessage takes selectors of fields and returns a function to convert routing.Route to protobuf RoutingContext.
@@ -213,20 +213,33 @@ func (v *UserMatcher) Apply(ctx routing.Context) bool {
 	return false
 }
 
+// InboundTagMatcher matches the inbound tags by the exact tags, and by the globs and "regexp:" patterns of
+// strmatcher.NewTagMatcher. A tag matches if it is any exact tag or matches any pattern, so the order of
+// them does not matter; the rules are still tried in their order, whether they match by patterns or not.
 type InboundTagMatcher struct {
-	tags []string
+	tags     map[string]bool
+	patterns []strmatcher.Matcher
 }
 
-func NewInboundTagMatcher(tags []string) *InboundTagMatcher {
-	tagsCopy := make([]string, 0, len(tags))
+func NewInboundTagMatcher(tags []string) (*InboundTagMatcher, error) {
+	m := &InboundTagMatcher{
+		tags: make(map[string]bool, len(tags)),
+	}
 	for _, tag := range tags {
-		if len(tag) > 0 {
-			tagsCopy = append(tagsCopy, tag)
+		if len(tag) == 0 {
+			continue
+		}
+		pattern, err := strmatcher.NewTagMatcher(tag)
+		if err != nil {
+			return nil, errors.New("invalid inbound tag pattern ", tag).Base(err)
+		}
+		if pattern != nil {
+			m.patterns = append(m.patterns, pattern)
+		} else {
+			m.tags[tag] = true
 		}
 	}
-	return &InboundTagMatcher{
-		tags: tagsCopy,
-	}
+	return m, nil
 }
 
 // Apply implements Condition.
@@ -235,8 +248,11 @@ func (v *InboundTagMatcher) Apply(ctx routing.Context) bool {
 	if len(tag) == 0 {
 		return false
 	}
-	for _, t := range v.tags {
-		if t == tag {
+	if v.tags[tag] {
+		return true
+	}
+	for _, pattern := range v.patterns {
+		if pattern.Match(tag) {
 			return true
 		}
 	}
@@ -6,15 +6,17 @@ import (
 	"strings"
 
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/strmatcher"
 	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/features/routing"
 )
 
 type Rule struct {
-	Tag       string
-	RuleTag   string
-	Balancer  *Balancer
-	Condition Condition
+	Tag         string
+	RuleTag     string
+	Balancer    *Balancer
+	BalancerTag string
+	Condition   Condition
 }
 
 func (r *Rule) GetTag() (string, error) {
@@ -33,7 +35,11 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 	conds := NewConditionChan()
 
 	if len(rr.InboundTag) > 0 {
-		conds.Add(NewInboundTagMatcher(rr.InboundTag))
+		matcher, err := NewInboundTagMatcher(rr.InboundTag)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(matcher)
 	}
 
 	if len(rr.Networks) > 0 {
@@ -115,6 +121,11 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 
 // Build builds the balancing rule
 func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatcher) (*Balancer, error) {
+	for _, selector := range br.OutboundSelector {
+		if _, err := strmatcher.NewTagMatcher(selector); err != nil {
+			return nil, errors.New("invalid selector ", selector, " of balancer ", br.Tag).Base(err)
+		}
+	}
 	switch strings.ToLower(br.Strategy) {
 	case "leastping":
 		return &Balancer{
@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"strings"
 	sync "sync"
 
 	"github.com/xtls/xray-core/common"
@@ -68,9 +69,10 @@ func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm out
 		if len(btag) > 0 {
 			brule, found := r.balancers[btag]
 			if !found {
-				return errors.New("balancer ", btag, " not found")
+				return errors.New("balancer ", btag, " not found").WithCode(errors.CodeInvalidConfig)
 			}
 			rr.Balancer = brule
+			rr.BalancerTag = btag
 		}
 		r.rules = append(r.rules, rr)
 	}
@@ -88,7 +90,11 @@ func (r *Router) PickRoute(ctx routing.Context) (routing.Route, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Route{Context: ctx, outboundTag: tag, ruleTag: rule.RuleTag}, nil
+	route := &Route{Context: ctx, outboundTag: tag, ruleTag: rule.RuleTag}
+	if rule.Balancer != nil {
+		route.outboundGroupTags = []string{rule.BalancerTag}
+	}
+	return route, nil
 }
 
 // AddRule implements routing.Router.
@@ -101,7 +107,7 @@ func (r *Router) AddRule(config *serial.TypedMessage, shouldAppend bool) error {
 	if c, ok := inst.(*Config); ok {
 		return r.ReloadRules(c, shouldAppend)
 	}
-	return errors.New("AddRule: config type error")
+	return errors.New("AddRule: config type error").WithCode(errors.CodeInvalidConfig)
 }
 
 func (r *Router) ReloadRules(config *Config, shouldAppend bool) error {
@@ -115,7 +121,7 @@ func (r *Router) ReloadRules(config *Config, shouldAppend bool) error {
 	for _, rule := range config.BalancingRule {
 		_, found := r.balancers[rule.Tag]
 		if found {
-			return errors.New("duplicate balancer tag")
+			return errors.New("duplicate balancer tag").WithCode(errors.CodeAlreadyExists)
 		}
 		balancer, err := rule.Build(r.ohm, r.dispatcher)
 		if err != nil {
@@ -127,7 +133,7 @@ func (r *Router) ReloadRules(config *Config, shouldAppend bool) error {
 
 	for _, rule := range config.Rule {
 		if r.RuleExists(rule.GetRuleTag()) {
-			return errors.New("duplicate ruleTag ", rule.GetRuleTag())
+			return errors.New("duplicate ruleTag ", rule.GetRuleTag()).WithCode(errors.CodeAlreadyExists)
 		}
 		cond, err := rule.BuildCondition()
 		if err != nil {
@@ -142,9 +148,10 @@ func (r *Router) ReloadRules(config *Config, shouldAppend bool) error {
 		if len(btag) > 0 {
 			brule, found := r.balancers[btag]
 			if !found {
-				return errors.New("balancer ", btag, " not found")
+				return errors.New("balancer ", btag, " not found").WithCode(errors.CodeInvalidConfig)
 			}
 			rr.Balancer = brule
+			rr.BalancerTag = btag
 		}
 		r.rules = append(r.rules, rr)
 	}
@@ -178,9 +185,24 @@ func (r *Router) RemoveRule(tag string) error {
 		r.rules = newRules
 		return nil
 	}
-	return errors.New("empty tag name!")
+	return errors.New("empty tag name!").WithCode(errors.CodeInvalidConfig)
 
 }
+
+// MatchesAttribute implements routing.AttributeRouter.
+func (r *Router) MatchesAttribute(name string) bool {
+	for _, rule := range r.rules {
+		if conds, ok := rule.Condition.(*ConditionChan); ok {
+			for _, cond := range *conds {
+				if m, ok := cond.(*AttributeMatcher); ok && m.configuredKeys[strings.ToLower(name)] != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context, error) {
 	// SkipDNSResolve is set from DNS module.
 	// the DOH remote server maybe a domain name,
@@ -189,6 +211,8 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 
 	if r.domainStrategy == Config_IpOnDemand && !skipDNSResolve {
 		ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
+	} else if r.domainStrategy == Config_IpOnDemandCached && !skipDNSResolve {
+		ctx = routing_dns.ContextWithDNSClientKeepingIPs(ctx, r.dns)
 	}
 
 	for _, rule := range r.rules {
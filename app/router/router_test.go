@@ -2,17 +2,35 @@ package router_test
 
 import (
 	"context"
+	"io"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	mdns "github.com/miekg/dns"
+	"github.com/xtls/xray-core/app/dispatcher"
+	dnsapp "github.com/xtls/xray-core/app/dns"
+	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/inbound"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
 	. "github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/features/outbound"
 	routing_session "github.com/xtls/xray-core/features/routing/session"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/dokodemo"
+	"github.com/xtls/xray-core/proxy/freedom"
 	"github.com/xtls/xray-core/testing/mocks"
+	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/testing/servers/udp"
+	"github.com/xtls/xray-core/transport/internet"
 )
 
 type mockOutboundManager struct {
@@ -55,6 +73,55 @@ func TestSimpleRouter(t *testing.T) {
 	}
 }
 
+func TestInboundTagPatterns(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag:  &RoutingRule_Tag{Tag: "users"},
+				InboundTag: []string{"in-user-*"},
+			},
+			{
+				// never hit for in-user-admin, as the pattern of the rule before matches it
+				TargetTag:  &RoutingRule_Tag{Tag: "admin"},
+				InboundTag: []string{"in-user-admin"},
+			},
+			{
+				TargetTag:  &RoutingRule_Tag{Tag: "nodes"},
+				InboundTag: []string{"api", `regexp:^node-\d+$`},
+			},
+		},
+	}
+
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), config, nil, nil, nil))
+
+	for inboundTag, expected := range map[string]string{
+		"in-user-123":   "users",
+		"in-user-admin": "users",
+		"node-42":       "nodes",
+		"api":           "nodes",
+		"node-x":        "",
+		"in-users":      "",
+	} {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: inboundTag})
+		ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{
+			Target: net.TCPDestination(net.DomainAddress("example.com"), 80),
+		}})
+		var tag string
+		if route, err := r.PickRoute(routing_session.AsRoutingContext(ctx)); err == nil {
+			tag = route.GetOutboundTag()
+		}
+		if tag != expected {
+			t.Error("expect tag '", expected, "' for inbound ", inboundTag, ", but actually ", tag)
+		}
+	}
+
+	config.Rule[0].InboundTag = []string{"regexp:("}
+	if err := new(Router).Init(context.TODO(), config, nil, nil, nil); err == nil {
+		t.Error("expect error for an invalid inbound tag pattern")
+	}
+}
+
 func TestSimpleBalancer(t *testing.T) {
 	config := &Config{
 		Rule: []*RoutingRule{
@@ -96,6 +163,9 @@ func TestSimpleBalancer(t *testing.T) {
 	if tag := route.GetOutboundTag(); tag != "test" {
 		t.Error("expect tag 'test', bug actually ", tag)
 	}
+	if groups := route.GetOutboundGroupTags(); len(groups) != 1 || groups[0] != "balance" {
+		t.Error("expect group 'balance', but actually ", groups)
+	}
 }
 
 /*
@@ -192,6 +262,139 @@ func TestIPOnDemand(t *testing.T) {
 	}
 }
 
+// countingDNSHandler answers ondemand.test with 127.0.0.1, counting the queries of it.
+type countingDNSHandler struct {
+	queries atomic.Int32
+}
+
+func (h *countingDNSHandler) ServeDNS(w mdns.ResponseWriter, r *mdns.Msg) {
+	ans := new(mdns.Msg)
+	ans.SetReply(r)
+	for _, q := range r.Question {
+		if q.Name != "ondemand.test." {
+			continue
+		}
+		h.queries.Add(1)
+		if q.Qtype == mdns.TypeA {
+			rr, _ := mdns.NewRR("ondemand.test. IN A 127.0.0.1")
+			ans.Answer = append(ans.Answer, rr)
+		}
+	}
+	w.WriteMsg(ans)
+}
+
+func TestIPOnDemandCached(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	dnsPort := udp.PickPort()
+	dnsHandler := &countingDNSHandler{}
+	dnsServer := mdns.Server{
+		Addr:    "127.0.0.1:" + dnsPort.String(),
+		Net:     "udp",
+		Handler: dnsHandler,
+		UDPSize: 1200,
+	}
+	go dnsServer.ListenAndServe()
+	defer dnsServer.Shutdown()
+	time.Sleep(time.Second)
+
+	for _, strategy := range []internet.DomainStrategy{internet.DomainStrategy_AS_IS, internet.DomainStrategy_USE_IP4} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			dnsHandler.queries.Store(0)
+			clientPort := tcp.PickPort()
+			server, err := core.New(&core.Config{
+				App: []*serial.TypedMessage{
+					serial.ToTypedMessage(&dnsapp.Config{
+						NameServer: []*dnsapp.NameServer{
+							{
+								Address: &net.Endpoint{
+									Network: net.Network_UDP,
+									Address: net.NewIPOrDomain(net.LocalHostIP),
+									Port:    uint32(dnsPort),
+								},
+							},
+						},
+						QueryStrategy: dnsapp.QueryStrategy_USE_IP4,
+						DisableCache:  true,
+					}),
+					serial.ToTypedMessage(&Config{
+						DomainStrategy: Config_IpOnDemandCached,
+						Rule: []*RoutingRule{
+							{
+								TargetTag: &RoutingRule_Tag{
+									Tag: "direct",
+								},
+								Geoip: []*GeoIP{
+									{
+										Cidr: []*CIDR{
+											{
+												Ip:     []byte{127, 0, 0, 0},
+												Prefix: 8,
+											},
+										},
+									},
+								},
+							},
+						},
+					}),
+					serial.ToTypedMessage(&dispatcher.Config{}),
+					serial.ToTypedMessage(&proxyman.InboundConfig{}),
+					serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+					serial.ToTypedMessage(&policy.Config{}),
+				},
+				Inbound: []*core.InboundHandlerConfig{
+					{
+						ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+							PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+							Listen:   net.NewIPOrDomain(net.LocalHostIP),
+						}),
+						ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+							Address:  net.NewIPOrDomain(net.DomainAddress("ondemand.test")),
+							Port:     uint32(dest.Port),
+							Networks: []net.Network{net.Network_TCP},
+						}),
+					},
+				},
+				Outbound: []*core.OutboundHandlerConfig{
+					{
+						// the default outbound, for the connections not routed by the resolved IP
+						Tag:           "blocked",
+						ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+					},
+					{
+						Tag:           "direct",
+						ProxySettings: serial.ToTypedMessage(&freedom.Config{DomainStrategy: strategy}),
+					},
+				},
+			})
+			common.Must(err)
+			common.Must(server.Start())
+			defer server.Close()
+
+			conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: int(clientPort)})
+			common.Must(err)
+			defer conn.Close()
+			payload := []byte("ondemand")
+			common.Must2(conn.Write(payload))
+			common.Must(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+			response := make([]byte, len(payload))
+			if _, err := io.ReadFull(conn, response); err != nil {
+				t.Fatal("expect the routed connection to go through freedom, but actually ", err)
+			}
+
+			// the domain is resolved once, for both routing and dialing
+			if n := dnsHandler.queries.Load(); n != 1 {
+				t.Error("expect 1 query of the domain, but actually ", n)
+			}
+		})
+	}
+}
+
 func TestIPIfNonMatchDomain(t *testing.T) {
 	config := &Config{
 		DomainStrategy: Config_IpIfNonMatch,
@@ -276,3 +479,38 @@ func TestIPIfNonMatchIP(t *testing.T) {
 		t.Error("expect tag 'test', bug actually ", tag)
 	}
 }
+
+func TestRouterMatchesAttribute(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag:  &RoutingRule_Tag{Tag: "honeypot"},
+				Attributes: map[string]string{"JA3": "^e7d705a3286e19ea42f587b344ee6865$"},
+			},
+			{
+				TargetTag: &RoutingRule_Tag{Tag: "test"},
+				Networks:  []net.Network{net.Network_TCP},
+			},
+		},
+	}
+
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), config, nil, nil, nil))
+	if !r.MatchesAttribute("ja3") {
+		t.Error("expected a rule matching ja3")
+	}
+	if r.MatchesAttribute("ja4") {
+		t.Error("unexpected rule matching ja4")
+	}
+
+	content := &session.Content{Protocol: "tls"}
+	content.SetAttribute("ja3", "e7d705a3286e19ea42f587b344ee6865")
+	ctx := session.ContextWithContent(session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 443),
+	}}), content)
+	route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+	if tag := route.GetOutboundTag(); tag != "honeypot" {
+		t.Error("expect tag 'honeypot', but actually ", tag)
+	}
+}
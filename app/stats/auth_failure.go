@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"sync"
+
+	"github.com/xtls/xray-core/common/log"
+)
+
+// authFailureBufferSize is the number of events kept for a subscriber that is behind.
+const authFailureBufferSize = 256
+
+type authFailureFeed struct {
+	access      sync.RWMutex
+	subscribers map[chan *log.AuthFailureMessage]struct{}
+}
+
+// PublishAuthFailure implements stats.AuthFailureFeed.
+func (m *Manager) PublishAuthFailure(msg *log.AuthFailureMessage) {
+	f := &m.authFailures
+	f.access.RLock()
+	defer f.access.RUnlock()
+
+	for sub := range f.subscribers {
+		select {
+		case sub <- msg:
+		default: // never block the inbound, the subscriber misses the event
+		}
+	}
+}
+
+// SubscribeAuthFailures implements stats.AuthFailureFeed.
+func (m *Manager) SubscribeAuthFailures() (<-chan *log.AuthFailureMessage, func()) {
+	f := &m.authFailures
+	f.access.Lock()
+	defer f.access.Unlock()
+
+	if f.subscribers == nil {
+		f.subscribers = make(map[chan *log.AuthFailureMessage]struct{})
+	}
+	sub := make(chan *log.AuthFailureMessage, authFailureBufferSize)
+	f.subscribers[sub] = struct{}{}
+	var once sync.Once
+	return sub, func() {
+		once.Do(func() {
+			f.access.Lock()
+			defer f.access.Unlock()
+			delete(f.subscribers, sub)
+		})
+	}
+}
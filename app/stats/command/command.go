@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/xtls/xray-core/app/stats"
@@ -90,8 +91,9 @@ func (s *statsServer) QueryStats(ctx context.Context, request *QueryStatsRequest
 
 	manager, ok := s.stats.(*stats.Manager)
 	if !ok {
-		return nil, errors.New("QueryStats only works its own stats.Manager.")
+		return nil, errors.New("QueryStats only works its own stats.Manager.").WithCode(errors.CodeUnimplemented)
 	}
+	response.Fresh = !manager.Restored()
 
 	manager.VisitCounters(func(name string, c feature_stats.Counter) bool {
 		if matcher.Match(name) {
@@ -109,6 +111,95 @@ func (s *statsServer) QueryStats(ctx context.Context, request *QueryStatsRequest
 		return true
 	})
 
+	manager.VisitHistograms(func(name string, h feature_stats.Histogram) bool {
+		if matcher.Match(name) {
+			response.Stat = append(response.Stat, histogramStats(name, h, request.Reset_)...)
+		}
+		return true
+	})
+
+	return response, nil
+}
+
+// histogramStats flattens a histogram into one stat per bucket, named like "<name>>>>bucket>>>100"
+// after the upper bound of the bucket or "<name>>>>bucket>>>inf" for the last one, and a "<name>>>>sum" stat.
+func histogramStats(name string, h feature_stats.Histogram, reset bool) []*Stat {
+	var counts []int64
+	var sum int64
+	if reset {
+		counts, sum = h.Reset()
+	} else {
+		counts, sum = h.Counts()
+	}
+	bounds := h.Bounds()
+	result := make([]*Stat, 0, len(counts)+1)
+	for i, count := range counts {
+		bound := "inf"
+		if i < len(bounds) {
+			bound = strconv.FormatInt(bounds[i], 10)
+		}
+		result = append(result, &Stat{
+			Name:  name + ">>>bucket>>>" + bound,
+			Value: count,
+		})
+	}
+	return append(result, &Stat{
+		Name:  name + ">>>sum",
+		Value: sum,
+	})
+}
+
+func (s *statsServer) BatchQueryStats(ctx context.Context, request *BatchQueryStatsRequest) (*BatchQueryStatsResponse, error) {
+	matchers := make([]strmatcher.Matcher, 0, len(request.Patterns))
+	for _, pattern := range request.Patterns {
+		matcher, err := strmatcher.Substr.New(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		return nil, errors.New("BatchQueryStats only works its own stats.Manager.").WithCode(errors.CodeUnimplemented)
+	}
+
+	response := &BatchQueryStatsResponse{
+		Result: make([]*BatchQueryStatsResult, len(request.Patterns)),
+	}
+	for i, pattern := range request.Patterns {
+		response.Result[i] = &BatchQueryStatsResult{Pattern: pattern}
+	}
+
+	// Each counter is swapped (or read) exactly once, even if it matches several patterns,
+	// so that the same value is reported to all of them and none is lost to a second reset.
+	manager.VisitCounters(func(name string, c feature_stats.Counter) bool {
+		var value int64
+		fetched := false
+		for i, matcher := range matchers {
+			if !matcher.Match(name) {
+				continue
+			}
+			if !fetched {
+				if request.Reset_ {
+					value = c.Set(0)
+				} else {
+					value = c.Value()
+				}
+				fetched = true
+			}
+			result := response.Result[i]
+			result.Sum += value
+			if !request.SumOnly {
+				result.Stat = append(result.Stat, &Stat{
+					Name:  name,
+					Value: value,
+				})
+			}
+		}
+		return true
+	})
+
 	return response, nil
 }
 
@@ -134,6 +225,110 @@ func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest)
 	return response, nil
 }
 
+func (s *statsServer) SubscribeAuthFailures(request *SubscribeAuthFailuresRequest, stream StatsService_SubscribeAuthFailuresServer) error {
+	feed, ok := s.stats.(feature_stats.AuthFailureFeed)
+	if !ok {
+		return errors.New("Auth failures not enabled.").WithCode(errors.CodeUnavailable)
+	}
+	events, cancel := feed.SubscribeAuthFailures()
+	defer cancel()
+	for {
+		select {
+		case msg := <-events:
+			err := stream.Send(&AuthFailure{
+				Timestamp:  msg.Time.UnixMilli(),
+				InboundTag: msg.InboundTag,
+				Protocol:   msg.Protocol,
+				Source:     msg.Source,
+				Reason:     msg.Reason,
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *statsServer) SubscribeStats(request *SubscribeStatsRequest, stream StatsService_SubscribeStatsServer) error {
+	matchers := make([]strmatcher.Matcher, 0, len(request.Patterns))
+	for _, pattern := range request.Patterns {
+		matcher, err := strmatcher.Substr.New(pattern)
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		return errors.New("SubscribeStats only works its own stats.Manager.").WithCode(errors.CodeUnimplemented)
+	}
+
+	interval := time.Duration(request.IntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// the values last pushed of the matching counters, and the names that do not match
+	last := make(map[string]int64)
+	unmatched := make(map[string]bool)
+	match := func(name string) bool {
+		if len(matchers) == 0 {
+			return true
+		}
+		for _, matcher := range matchers {
+			if matcher.Match(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for {
+		// The counters are read without holding them up, only their registration waits for the visit.
+		response := &SubscribeStatsResponse{}
+		current := make(map[string]int64, len(last))
+		currentUnmatched := make(map[string]bool, len(unmatched))
+		manager.VisitCounters(func(name string, c feature_stats.Counter) bool {
+			previous, pushed := last[name]
+			if !pushed && (unmatched[name] || !match(name)) {
+				currentUnmatched[name] = true
+				return true
+			}
+			value := c.Value()
+			current[name] = value
+			if value != previous {
+				response.Stats = append(response.Stats, &StatDelta{
+					Name:  name,
+					Delta: value - previous,
+					Value: value,
+				})
+			}
+			return true
+		})
+		// the counters that are gone are forgotten
+		last, unmatched = current, currentUnmatched
+
+		if len(response.Stats) > 0 {
+			if err := stream.Send(response); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-manager.Done():
+			return errors.New("Stats closed.").WithCode(errors.CodeUnavailable)
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
 func (s *statsServer) mustEmbedUnimplementedStatsServiceServer() {}
 
 type service struct {
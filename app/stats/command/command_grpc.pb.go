@@ -19,22 +19,33 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	StatsService_GetStats_FullMethodName             = "/xray.app.stats.command.StatsService/GetStats"
-	StatsService_GetStatsOnline_FullMethodName       = "/xray.app.stats.command.StatsService/GetStatsOnline"
-	StatsService_QueryStats_FullMethodName           = "/xray.app.stats.command.StatsService/QueryStats"
-	StatsService_GetSysStats_FullMethodName          = "/xray.app.stats.command.StatsService/GetSysStats"
-	StatsService_GetStatsOnlineIpList_FullMethodName = "/xray.app.stats.command.StatsService/GetStatsOnlineIpList"
+	StatsService_GetStats_FullMethodName              = "/xray.app.stats.command.StatsService/GetStats"
+	StatsService_GetStatsOnline_FullMethodName        = "/xray.app.stats.command.StatsService/GetStatsOnline"
+	StatsService_QueryStats_FullMethodName            = "/xray.app.stats.command.StatsService/QueryStats"
+	StatsService_BatchQueryStats_FullMethodName       = "/xray.app.stats.command.StatsService/BatchQueryStats"
+	StatsService_GetSysStats_FullMethodName           = "/xray.app.stats.command.StatsService/GetSysStats"
+	StatsService_GetStatsOnlineIpList_FullMethodName  = "/xray.app.stats.command.StatsService/GetStatsOnlineIpList"
+	StatsService_SubscribeAuthFailures_FullMethodName = "/xray.app.stats.command.StatsService/SubscribeAuthFailures"
+	StatsService_SubscribeStats_FullMethodName        = "/xray.app.stats.command.StatsService/SubscribeStats"
 )
 
 // StatsServiceClient is the client API for StatsService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Failed calls carry a stable gRPC status code, as in HandlerService:
+// NOT_FOUND for unknown counters and online maps, UNAVAILABLE if auth
+// failures are not tracked or the stats manager is closed, and UNIMPLEMENTED
+// for stats managers other than the built-in one.
 type StatsServiceClient interface {
 	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 	GetStatsOnline(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
 	QueryStats(ctx context.Context, in *QueryStatsRequest, opts ...grpc.CallOption) (*QueryStatsResponse, error)
+	BatchQueryStats(ctx context.Context, in *BatchQueryStatsRequest, opts ...grpc.CallOption) (*BatchQueryStatsResponse, error)
 	GetSysStats(ctx context.Context, in *SysStatsRequest, opts ...grpc.CallOption) (*SysStatsResponse, error)
 	GetStatsOnlineIpList(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsOnlineIpListResponse, error)
+	SubscribeAuthFailures(ctx context.Context, in *SubscribeAuthFailuresRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AuthFailure], error)
+	SubscribeStats(ctx context.Context, in *SubscribeStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeStatsResponse], error)
 }
 
 type statsServiceClient struct {
@@ -75,6 +86,16 @@ func (c *statsServiceClient) QueryStats(ctx context.Context, in *QueryStatsReque
 	return out, nil
 }
 
+func (c *statsServiceClient) BatchQueryStats(ctx context.Context, in *BatchQueryStatsRequest, opts ...grpc.CallOption) (*BatchQueryStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchQueryStatsResponse)
+	err := c.cc.Invoke(ctx, StatsService_BatchQueryStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *statsServiceClient) GetSysStats(ctx context.Context, in *SysStatsRequest, opts ...grpc.CallOption) (*SysStatsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(SysStatsResponse)
@@ -95,15 +116,61 @@ func (c *statsServiceClient) GetStatsOnlineIpList(ctx context.Context, in *GetSt
 	return out, nil
 }
 
+func (c *statsServiceClient) SubscribeAuthFailures(ctx context.Context, in *SubscribeAuthFailuresRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AuthFailure], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[0], StatsService_SubscribeAuthFailures_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeAuthFailuresRequest, AuthFailure]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeAuthFailuresClient = grpc.ServerStreamingClient[AuthFailure]
+
+func (c *statsServiceClient) SubscribeStats(ctx context.Context, in *SubscribeStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeStatsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[1], StatsService_SubscribeStats_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeStatsRequest, SubscribeStatsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeStatsClient = grpc.ServerStreamingClient[SubscribeStatsResponse]
+
 // StatsServiceServer is the server API for StatsService service.
 // All implementations must embed UnimplementedStatsServiceServer
 // for forward compatibility.
+//
+// Failed calls carry a stable gRPC status code, as in HandlerService:
+// NOT_FOUND for unknown counters and online maps, UNAVAILABLE if auth
+// failures are not tracked or the stats manager is closed, and UNIMPLEMENTED
+// for stats managers other than the built-in one.
 type StatsServiceServer interface {
 	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	GetStatsOnline(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
 	QueryStats(context.Context, *QueryStatsRequest) (*QueryStatsResponse, error)
+	BatchQueryStats(context.Context, *BatchQueryStatsRequest) (*BatchQueryStatsResponse, error)
 	GetSysStats(context.Context, *SysStatsRequest) (*SysStatsResponse, error)
 	GetStatsOnlineIpList(context.Context, *GetStatsRequest) (*GetStatsOnlineIpListResponse, error)
+	SubscribeAuthFailures(*SubscribeAuthFailuresRequest, grpc.ServerStreamingServer[AuthFailure]) error
+	SubscribeStats(*SubscribeStatsRequest, grpc.ServerStreamingServer[SubscribeStatsResponse]) error
 	mustEmbedUnimplementedStatsServiceServer()
 }
 
@@ -123,12 +190,21 @@ func (UnimplementedStatsServiceServer) GetStatsOnline(context.Context, *GetStats
 func (UnimplementedStatsServiceServer) QueryStats(context.Context, *QueryStatsRequest) (*QueryStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method QueryStats not implemented")
 }
+func (UnimplementedStatsServiceServer) BatchQueryStats(context.Context, *BatchQueryStatsRequest) (*BatchQueryStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchQueryStats not implemented")
+}
 func (UnimplementedStatsServiceServer) GetSysStats(context.Context, *SysStatsRequest) (*SysStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSysStats not implemented")
 }
 func (UnimplementedStatsServiceServer) GetStatsOnlineIpList(context.Context, *GetStatsRequest) (*GetStatsOnlineIpListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStatsOnlineIpList not implemented")
 }
+func (UnimplementedStatsServiceServer) SubscribeAuthFailures(*SubscribeAuthFailuresRequest, grpc.ServerStreamingServer[AuthFailure]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeAuthFailures not implemented")
+}
+func (UnimplementedStatsServiceServer) SubscribeStats(*SubscribeStatsRequest, grpc.ServerStreamingServer[SubscribeStatsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeStats not implemented")
+}
 func (UnimplementedStatsServiceServer) mustEmbedUnimplementedStatsServiceServer() {}
 func (UnimplementedStatsServiceServer) testEmbeddedByValue()                      {}
 
@@ -204,6 +280,24 @@ func _StatsService_QueryStats_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StatsService_BatchQueryStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchQueryStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).BatchQueryStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_BatchQueryStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).BatchQueryStats(ctx, req.(*BatchQueryStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StatsService_GetSysStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SysStatsRequest)
 	if err := dec(in); err != nil {
@@ -240,6 +334,28 @@ func _StatsService_GetStatsOnlineIpList_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StatsService_SubscribeAuthFailures_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeAuthFailuresRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).SubscribeAuthFailures(m, &grpc.GenericServerStream[SubscribeAuthFailuresRequest, AuthFailure]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeAuthFailuresServer = grpc.ServerStreamingServer[AuthFailure]
+
+func _StatsService_SubscribeStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).SubscribeStats(m, &grpc.GenericServerStream[SubscribeStatsRequest, SubscribeStatsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_SubscribeStatsServer = grpc.ServerStreamingServer[SubscribeStatsResponse]
+
 // StatsService_ServiceDesc is the grpc.ServiceDesc for StatsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -259,6 +375,10 @@ var StatsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "QueryStats",
 			Handler:    _StatsService_QueryStats_Handler,
 		},
+		{
+			MethodName: "BatchQueryStats",
+			Handler:    _StatsService_BatchQueryStats_Handler,
+		},
 		{
 			MethodName: "GetSysStats",
 			Handler:    _StatsService_GetSysStats_Handler,
@@ -268,6 +388,17 @@ var StatsService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _StatsService_GetStatsOnlineIpList_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeAuthFailures",
+			Handler:       _StatsService_SubscribeAuthFailures_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeStats",
+			Handler:       _StatsService_SubscribeStats_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "app/stats/command/command.proto",
 }
@@ -3,12 +3,16 @@ package command_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/xtls/xray-core/app/stats"
 	. "github.com/xtls/xray-core/app/stats/command"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 func TestGetStats(t *testing.T) {
@@ -89,3 +93,163 @@ func TestQueryStats(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+func TestQueryStatsHistogram(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{HistogramBounds: []int64{10, 100}})
+	common.Must(err)
+
+	h, err := m.RegisterHistogram("outbound>>>proxy>>>handshake>>>histogram")
+	common.Must(err)
+	h.Observe(5)
+	h.Observe(50)
+	h.Observe(60)
+	h.Observe(500)
+
+	s := NewStatsServer(m)
+	resp, err := s.QueryStats(context.Background(), &QueryStatsRequest{
+		Pattern: "handshake",
+		Reset_:  true,
+	})
+	common.Must(err)
+	if r := cmp.Diff(resp.Stat, []*Stat{
+		{Name: "outbound>>>proxy>>>handshake>>>histogram>>>bucket>>>10", Value: 1},
+		{Name: "outbound>>>proxy>>>handshake>>>histogram>>>bucket>>>100", Value: 2},
+		{Name: "outbound>>>proxy>>>handshake>>>histogram>>>bucket>>>inf", Value: 1},
+		{Name: "outbound>>>proxy>>>handshake>>>histogram>>>sum", Value: 615},
+	}, cmpopts.IgnoreUnexported(Stat{})); r != "" {
+		t.Error(r)
+	}
+	if counts, sum := h.Counts(); sum != 0 || counts[1] != 0 {
+		t.Error("expected histogram to be reset, but got ", counts, sum)
+	}
+}
+
+func TestBatchQueryStats(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	sc1, err := m.RegisterCounter("user>>>a>>>traffic>>>uplink")
+	common.Must(err)
+	sc1.Set(1)
+
+	sc2, err := m.RegisterCounter("user>>>b>>>traffic>>>uplink")
+	common.Must(err)
+	sc2.Set(2)
+
+	sc3, err := m.RegisterCounter("inbound>>>in>>>traffic>>>uplink")
+	common.Must(err)
+	sc3.Set(3)
+
+	s := NewStatsServer(m)
+	resp, err := s.BatchQueryStats(context.Background(), &BatchQueryStatsRequest{
+		Patterns: []string{"user>>>", "uplink"},
+		Reset_:   true,
+	})
+	common.Must(err)
+
+	if len(resp.Result) != 2 {
+		t.Fatal("expected 2 results, but got ", len(resp.Result))
+	}
+	if r := cmp.Diff(resp.Result[0].Stat, []*Stat{
+		{Name: "user>>>a>>>traffic>>>uplink", Value: 1},
+		{Name: "user>>>b>>>traffic>>>uplink", Value: 2},
+	}, cmpopts.SortSlices(func(s1, s2 *Stat) bool { return s1.Name < s2.Name }),
+		cmpopts.IgnoreUnexported(Stat{})); r != "" {
+		t.Error(r)
+	}
+	if resp.Result[0].Sum != 3 {
+		t.Error("expected sum 3, but got ", resp.Result[0].Sum)
+	}
+	if resp.Result[1].Sum != 6 {
+		t.Error("expected counters matching several patterns to be counted in each, but got sum ", resp.Result[1].Sum)
+	}
+	if sc1.Value() != 0 || sc2.Value() != 0 || sc3.Value() != 0 {
+		t.Error("expected all counters to be reset")
+	}
+
+	resp, err = s.BatchQueryStats(context.Background(), &BatchQueryStatsRequest{
+		Patterns: []string{"user>>>"},
+		SumOnly:  true,
+	})
+	common.Must(err)
+	if len(resp.Result[0].Stat) != 0 || resp.Result[0].Sum != 0 {
+		t.Error("unexpected sum only result: ", resp.Result[0])
+	}
+}
+
+// subscribeStatsStream collects the pushes of SubscribeStats.
+type subscribeStatsStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	pushes chan *SubscribeStatsResponse
+}
+
+func (s *subscribeStatsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *subscribeStatsStream) Send(response *SubscribeStatsResponse) error {
+	s.pushes <- response
+	return nil
+}
+
+func TestSubscribeStats(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	up, err := m.RegisterCounter("user>>>test>>>traffic>>>uplink")
+	common.Must(err)
+	down, err := m.RegisterCounter("user>>>test>>>traffic>>>downlink")
+	common.Must(err)
+	other, err := m.RegisterCounter("inbound>>>api>>>traffic>>>uplink")
+	common.Must(err)
+	up.Set(10)
+	other.Set(1)
+
+	stream := &subscribeStatsStream{
+		ctx:    context.Background(),
+		pushes: make(chan *SubscribeStatsResponse, 16),
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- NewStatsServer(m).SubscribeStats(&SubscribeStatsRequest{
+			Patterns:   []string{"user>>>"},
+			IntervalMs: 10,
+		}, stream)
+	}()
+
+	// counters still at zero are not pushed
+	if r := cmp.Diff((<-stream.pushes).Stats, []*StatDelta{
+		{Name: "user>>>test>>>traffic>>>uplink", Delta: 10, Value: 10},
+	}, protocmp.Transform()); r != "" {
+		t.Error(r)
+	}
+
+	up.Add(5)
+	down.Add(7)
+	other.Add(1)
+	// the changes may be split across two pushes
+	deltas := make(map[string]int64)
+	for len(deltas) < 2 {
+		for _, stat := range (<-stream.pushes).Stats {
+			deltas[stat.Name] += stat.Delta
+		}
+	}
+	if r := cmp.Diff(deltas, map[string]int64{
+		"user>>>test>>>traffic>>>uplink":   5,
+		"user>>>test>>>traffic>>>downlink": 7,
+	}); r != "" {
+		t.Error(r)
+	}
+
+	common.Must(m.Close())
+	select {
+	case err := <-errs:
+		if errors.CodeOf(err) != errors.CodeUnavailable {
+			t.Error("expected UNAVAILABLE after the stats closed, but got ", err)
+		}
+	case push := <-stream.pushes:
+		t.Error("unexpected push without changes: ", push)
+	case <-time.After(time.Second):
+		t.Error("the stream did not end after the stats closed")
+	}
+}
@@ -0,0 +1,59 @@
+package stats
+
+import "sync/atomic"
+
+// DefaultHistogramBounds are the bucket bounds used when none is configured, in milliseconds.
+var DefaultHistogramBounds = []int64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000, 900000, 3600000}
+
+// Histogram is an implementation of stats.Histogram.
+type Histogram struct {
+	bounds []int64
+	counts []atomic.Int64
+	sum    atomic.Int64
+}
+
+// NewHistogram creates a histogram with the given bucket bounds, which must be in ascending order.
+func NewHistogram(bounds []int64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]atomic.Int64, len(bounds)+1),
+	}
+}
+
+// Observe implements stats.Histogram.
+func (h *Histogram) Observe(value int64) {
+	i, j := 0, len(h.bounds)
+	for i < j {
+		m := int(uint(i+j) >> 1)
+		if h.bounds[m] < value {
+			i = m + 1
+		} else {
+			j = m
+		}
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(value)
+}
+
+// Bounds implements stats.Histogram.
+func (h *Histogram) Bounds() []int64 {
+	return h.bounds
+}
+
+// Counts implements stats.Histogram.
+func (h *Histogram) Counts() ([]int64, int64) {
+	counts := make([]int64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+	}
+	return counts, h.sum.Load()
+}
+
+// Reset implements stats.Histogram.
+func (h *Histogram) Reset() ([]int64, int64) {
+	counts := make([]int64, len(h.counts))
+	for i := range h.counts {
+		counts[i] = h.counts[i].Swap(0)
+	}
+	return counts, h.sum.Swap(0)
+}
@@ -0,0 +1,41 @@
+package stats_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+func TestStatsHistogram(t *testing.T) {
+	raw, err := common.CreateObject(context.Background(), &Config{HistogramBounds: []int64{10, 100, 1000}})
+	common.Must(err)
+
+	m := raw.(stats.Manager)
+	h, err := m.RegisterHistogram("test.histogram")
+	common.Must(err)
+
+	for _, v := range []int64{0, 10, 11, 100, 999, 1000, 1001} {
+		h.Observe(v)
+	}
+	counts, sum := h.Counts()
+	expected := []int64{2, 2, 2, 1}
+	for i := range expected {
+		if counts[i] != expected[i] {
+			t.Error("unexpected count of bucket ", i, ": ", counts[i], ", wanted ", expected[i])
+		}
+	}
+	if sum != 3121 {
+		t.Error("unexpected sum: ", sum)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { h.Observe(50) }); allocs != 0 {
+		t.Error("Observe allocates: ", allocs)
+	}
+
+	if _, err := common.CreateObject(context.Background(), &Config{HistogramBounds: []int64{10, 10}}); err == nil {
+		t.Error("expected error for bounds not in ascending order")
+	}
+}
@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const defaultPersistInterval = 60 * time.Second
+
+// snapshotMagic starts the snapshots of the counters. The entries follow, each a uvarint length and the name,
+// then a varint value, and a big endian CRC-32 of everything before ends the file.
+var snapshotMagic = []byte("XRSTATS1")
+
+func encodeSnapshot(values map[string]int64) []byte {
+	b := append([]byte(nil), snapshotMagic...)
+	for name, value := range values {
+		b = binary.AppendUvarint(b, uint64(len(name)))
+		b = append(b, name...)
+		b = binary.AppendVarint(b, value)
+	}
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b))
+}
+
+func decodeSnapshot(b []byte) (map[string]int64, error) {
+	if len(b) < len(snapshotMagic)+4 || !bytes.HasPrefix(b, snapshotMagic) {
+		return nil, errors.New("not a snapshot of stats")
+	}
+	body, sum := b[:len(b)-4], binary.BigEndian.Uint32(b[len(b)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errors.New("checksum mismatch")
+	}
+	values := make(map[string]int64)
+	for rest := body[len(snapshotMagic):]; len(rest) > 0; {
+		l, n := binary.Uvarint(rest)
+		if n <= 0 || uint64(len(rest)-n) < l {
+			return nil, errors.New("truncated name")
+		}
+		name := string(rest[n : n+int(l)])
+		rest = rest[n+int(l):]
+		value, n := binary.Varint(rest)
+		if n <= 0 {
+			return nil, errors.New("truncated value of ", name)
+		}
+		rest = rest[n:]
+		values[name] = value
+	}
+	return values, nil
+}
+
+// restore loads the snapshot of the counters, which the counters registered later continue from.
+func (m *Manager) restore() {
+	b, err := os.ReadFile(m.persist.Path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		m.restored, err = decodeSnapshot(b)
+	}
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "ignored the snapshot of stats in ", m.persist.Path)
+		return
+	}
+	m.history = len(m.restored) > 0
+	errors.LogInfo(context.Background(), "restored ", len(m.restored), " counters from ", m.persist.Path)
+}
+
+// snapshot returns the values of the counters, and the restored ones that are not registered yet.
+// The caller holds m.access.
+func (m *Manager) snapshot() map[string]int64 {
+	values := make(map[string]int64, len(m.counters)+len(m.restored))
+	for name, value := range m.restored {
+		values[name] = value
+	}
+	for name, c := range m.counters {
+		values[name] = c.Value()
+	}
+	return values
+}
+
+// save writes the snapshot through a temporary file, so a crash never leaves a partial one.
+func (m *Manager) save(values map[string]int64) {
+	tmp := m.persist.Path + ".tmp"
+	err := os.WriteFile(tmp, encodeSnapshot(values), 0o600)
+	if err == nil {
+		err = os.Rename(tmp, m.persist.Path)
+	}
+	if err != nil {
+		errors.LogWarningInner(context.Background(), err, "failed to write the snapshot of stats to ", m.persist.Path)
+	}
+}
+
+func (m *Manager) persistLoop() {
+	interval := time.Duration(m.persist.Interval) * time.Second
+	if interval == 0 {
+		interval = defaultPersistInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done.Wait():
+			return
+		case <-ticker.C:
+			m.saving.Lock()
+			// the snapshot on close is the last one
+			if !m.done.Done() {
+				m.access.RLock()
+				values := m.snapshot()
+				m.access.RUnlock()
+				m.save(values)
+			}
+			m.saving.Unlock()
+		}
+	}
+}
+
+// Restored returns whether the counters continue from the values of a snapshot.
+func (m *Manager) Restored() bool {
+	return m.history
+}
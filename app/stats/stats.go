@@ -6,6 +6,7 @@ import (
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/signal/done"
 	"github.com/xtls/xray-core/features/stats"
 )
 
@@ -16,14 +17,43 @@ type Manager struct {
 	onlineMap map[string]*OnlineMap
 	channels  map[string]*Channel
 	running   bool
+
+	histograms      map[string]*Histogram
+	histogramBounds []int64
+
+	authFailures authFailureFeed
+	done         *done.Instance
+
+	persist *PersistConfig
+	// restored are the values of the snapshot for the counters that are not registered yet.
+	restored map[string]int64
+	history  bool
+	saving   sync.Mutex
 }
 
 // NewManager creates an instance of Statistics Manager.
 func NewManager(ctx context.Context, config *Config) (*Manager, error) {
 	m := &Manager{
-		counters:  make(map[string]*Counter),
-		onlineMap: make(map[string]*OnlineMap),
-		channels:  make(map[string]*Channel),
+		counters:        make(map[string]*Counter),
+		onlineMap:       make(map[string]*OnlineMap),
+		channels:        make(map[string]*Channel),
+		histograms:      make(map[string]*Histogram),
+		histogramBounds: DefaultHistogramBounds,
+		done:            done.New(),
+	}
+
+	if bounds := config.GetHistogramBounds(); len(bounds) > 0 {
+		for i := 1; i < len(bounds); i++ {
+			if bounds[i] <= bounds[i-1] {
+				return nil, errors.New("histogram bounds must be in ascending order")
+			}
+		}
+		m.histogramBounds = bounds
+	}
+
+	if persist := config.GetPersist(); len(persist.GetPath()) > 0 {
+		m.persist = persist
+		m.restore()
 	}
 
 	return m, nil
@@ -44,6 +74,10 @@ func (m *Manager) RegisterCounter(name string) (stats.Counter, error) {
 	}
 	errors.LogDebug(context.Background(), "create new counter ", name)
 	c := new(Counter)
+	if value, found := m.restored[name]; found {
+		c.value = value
+		delete(m.restored, name)
+	}
 	m.counters[name] = c
 	return c, nil
 }
@@ -161,11 +195,63 @@ func (m *Manager) GetChannel(name string) stats.Channel {
 	return nil
 }
 
+// RegisterHistogram implements stats.Manager.
+func (m *Manager) RegisterHistogram(name string) (stats.Histogram, error) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.histograms[name]; found {
+		return nil, errors.New("Histogram ", name, " already registered.")
+	}
+	errors.LogDebug(context.Background(), "create new histogram ", name)
+	h := NewHistogram(m.histogramBounds)
+	m.histograms[name] = h
+	return h, nil
+}
+
+// UnregisterHistogram implements stats.Manager.
+func (m *Manager) UnregisterHistogram(name string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.histograms[name]; found {
+		errors.LogDebug(context.Background(), "remove histogram ", name)
+		delete(m.histograms, name)
+	}
+	return nil
+}
+
+// GetHistogram implements stats.Manager.
+func (m *Manager) GetHistogram(name string) stats.Histogram {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	if h, found := m.histograms[name]; found {
+		return h
+	}
+	return nil
+}
+
+// VisitHistograms calls visitor function on all managed histograms.
+func (m *Manager) VisitHistograms(visitor func(string, stats.Histogram) bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for name, h := range m.histograms {
+		if !visitor(name, h) {
+			break
+		}
+	}
+}
+
 // Start implements common.Runnable.
 func (m *Manager) Start() error {
 	m.access.Lock()
 	defer m.access.Unlock()
 	m.running = true
+	if m.persist != nil {
+		go m.persistLoop()
+	}
 	errs := []error{}
 	for _, channel := range m.channels {
 		if err := channel.Start(); err != nil {
@@ -180,9 +266,15 @@ func (m *Manager) Start() error {
 
 // Close implement common.Closable.
 func (m *Manager) Close() error {
+	m.saving.Lock()
+	defer m.saving.Unlock()
 	m.access.Lock()
 	defer m.access.Unlock()
 	m.running = false
+	if m.persist != nil && !m.done.Done() {
+		m.save(m.snapshot())
+	}
+	m.done.Close()
 	errs := []error{}
 	for name, channel := range m.channels {
 		errors.LogDebug(context.Background(), "remove channel ", name)
@@ -197,6 +289,11 @@ func (m *Manager) Close() error {
 	return nil
 }
 
+// Done returns a channel that is closed when the manager closes.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done.Wait()
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		return NewManager(ctx, config.(*Config))
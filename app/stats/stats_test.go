@@ -2,6 +2,8 @@ package stats_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -84,3 +86,47 @@ func TestStatsChannelRunnable(t *testing.T) {
 		t.Fatalf("unexpected running channel: test.channel.%d", 3)
 	}
 }
+
+func TestStatsPersist(t *testing.T) {
+	config := &Config{Persist: &PersistConfig{Path: filepath.Join(t.TempDir(), "stats")}}
+	start := func() *Manager {
+		m, err := NewManager(context.Background(), config)
+		common.Must(err)
+		common.Must(m.Start())
+		return m
+	}
+
+	m := start()
+	if m.Restored() {
+		t.Error("expected fresh counters without a snapshot")
+	}
+	up := common.Must2(m.RegisterCounter("user>>>a>>>traffic>>>uplink"))
+	up.Add(100)
+	down := common.Must2(m.RegisterCounter("user>>>b>>>traffic>>>downlink"))
+	down.Add(5)
+	common.Must(m.Close())
+
+	// the counters not registered again are kept in the next snapshot
+	m = start()
+	if !m.Restored() {
+		t.Error("expected counters restored from the snapshot")
+	}
+	up = common.Must2(m.RegisterCounter("user>>>a>>>traffic>>>uplink"))
+	if v := up.Add(1); v != 101 {
+		t.Error("unexpected value of restored counter: ", v)
+	}
+	common.Must(m.Close())
+
+	m = start()
+	if v := common.Must2(m.RegisterCounter("user>>>b>>>traffic>>>downlink")).Value(); v != 5 {
+		t.Error("unexpected value of counter restored twice: ", v)
+	}
+	common.Must(m.Close())
+
+	common.Must(os.WriteFile(config.Persist.Path, []byte("XRSTATS1 corrupt"), 0o600))
+	m = start()
+	if m.Restored() {
+		t.Error("expected a corrupt snapshot to be ignored")
+	}
+	common.Must(m.Close())
+}
@@ -2,6 +2,7 @@ package buf
 
 import (
 	"io"
+	"sync"
 
 	"github.com/xtls/xray-core/common/bytespool"
 	"github.com/xtls/xray-core/common/errors"
@@ -11,12 +12,20 @@ import (
 const (
 	// Size of a regular buffer.
 	Size = 8192
+	// LargeSize is the size of a large buffer, for sources that naturally produce big reads.
+	LargeSize = 65536
 )
 
 var ErrBufferFull = errors.New("buffer is full")
 
 var pool = bytespool.GetPool(Size)
 
+var largePool = sync.Pool{
+	New: func() interface{} {
+		return make([]byte, LargeSize)
+	},
+}
+
 // ownership represents the data owner of the buffer.
 type ownership uint8
 
@@ -51,6 +60,14 @@ func New() *Buffer {
 	}
 }
 
+// NewLarge creates a Buffer with 0 length and 64K capacity, managed.
+// Large buffers are recycled into their own pool on Release().
+func NewLarge() *Buffer {
+	return &Buffer{
+		v: largePool.Get().([]byte)[:LargeSize],
+	}
+}
+
 // NewExisted creates a standard size Buffer with an existed bytearray, managed.
 func NewExisted(b []byte) *Buffer {
 	if cap(b) < Size {
@@ -112,8 +129,11 @@ func (b *Buffer) Release() {
 
 	switch b.ownership {
 	case managed:
-		if cap(p) == Size {
+		switch cap(p) {
+		case Size:
 			pool.Put(p)
+		case LargeSize:
+			largePool.Put(p)
 		}
 	case bytespools:
 		bytespool.Free(p)
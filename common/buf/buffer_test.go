@@ -156,6 +156,13 @@ func BenchmarkNewBuffer(b *testing.B) {
 	}
 }
 
+func BenchmarkNewLargeBuffer(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		buffer := NewLarge()
+		buffer.Release()
+	}
+}
+
 func BenchmarkNewBufferStack(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		buffer := StackNew()
@@ -13,6 +13,8 @@ type dataHandler func(MultiBuffer)
 
 type copyHandler struct {
 	onData []dataHandler
+	// onSize mirrors onData for the splice path, where only the number of bytes copied is known.
+	onSize []func(int64)
 }
 
 // SizeCounter is for counting bytes copied by Copy().
@@ -29,6 +31,9 @@ func UpdateActivity(timer signal.ActivityUpdater) CopyOption {
 		handler.onData = append(handler.onData, func(MultiBuffer) {
 			timer.Update()
 		})
+		handler.onSize = append(handler.onSize, func(int64) {
+			timer.Update()
+		})
 	}
 }
 
@@ -38,6 +43,9 @@ func CountSize(sc *SizeCounter) CopyOption {
 		handler.onData = append(handler.onData, func(b MultiBuffer) {
 			sc.Size += int64(b.Len())
 		})
+		handler.onSize = append(handler.onSize, func(n int64) {
+			sc.Size += n
+		})
 	}
 }
 
@@ -49,6 +57,11 @@ func AddToStatCounter(sc stats.Counter) CopyOption {
 				sc.Add(int64(b.Len()))
 			}
 		})
+		handler.onSize = append(handler.onSize, func(n int64) {
+			if sc != nil {
+				sc.Add(n)
+			}
+		})
 	}
 }
 
@@ -113,7 +126,7 @@ func Copy(reader Reader, writer Writer, options ...CopyOption) error {
 	for _, option := range options {
 		option(&handler)
 	}
-	err := copyInternal(reader, writer, &handler)
+	err := copyConns(reader, writer, &handler)
 	if err != nil && errors.Cause(err) != io.EOF {
 		return err
 	}
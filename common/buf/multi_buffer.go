@@ -55,6 +55,7 @@ func MergeBytes(dest MultiBuffer, src []byte) MultiBuffer {
 
 // ReleaseMulti releases all content of the MultiBuffer, and returns an empty MultiBuffer.
 func ReleaseMulti(mb MultiBuffer) MultiBuffer {
+	// Each Buffer returns to the pool matching its capacity, so regular and large buffers may be mixed.
 	for i := range mb {
 		mb[i].Release()
 		mb[i] = nil
@@ -157,6 +158,31 @@ func Compact(mb MultiBuffer) MultiBuffer {
 	return mb2
 }
 
+// CompactLarge returns another MultiBuffer by merging all content of the given one into large buffers.
+// It is for streams that arrive in many small pieces, where per-buffer overhead would dominate.
+func CompactLarge(mb MultiBuffer) MultiBuffer {
+	if len(mb) <= 1 {
+		return mb
+	}
+
+	mb2 := make(MultiBuffer, 0, mb.Len()/LargeSize+1)
+	var last *Buffer
+
+	for _, b := range mb {
+		for !b.IsEmpty() {
+			if last == nil || last.IsFull() {
+				last = NewLarge()
+				mb2 = append(mb2, last)
+			}
+			n, _ := last.Write(b.Bytes())
+			b.Advance(int32(n))
+		}
+		b.Release()
+	}
+
+	return mb2
+}
+
 // SplitFirst splits the first Buffer from the beginning of the MultiBuffer.
 func SplitFirst(mb MultiBuffer) (MultiBuffer, *Buffer) {
 	if len(mb) == 0 {
@@ -177,6 +203,10 @@ func SplitSize(mb MultiBuffer, size int32) (MultiBuffer, MultiBuffer) {
 
 	if mb[0].Len() > size {
 		b := New()
+		if size > Size {
+			b.Release()
+			b = NewLarge()
+		}
 		copy(b.Extend(size), mb[0].BytesTo(size))
 		mb[0].Advance(size)
 		return mb, MultiBuffer{b}
@@ -40,6 +40,42 @@ func TestMultiBufferAppend(t *testing.T) {
 	}
 }
 
+func TestCompactLarge(t *testing.T) {
+	payload := make([]byte, LargeSize+1000)
+	common.Must2(io.ReadFull(rand.Reader, payload))
+
+	mb := MergeBytes(nil, payload)
+	mb = CompactLarge(mb)
+	if len(mb) != 2 {
+		t.Fatal("expect 2 buffers, but got ", len(mb))
+	}
+	if mb[0].Cap() != LargeSize {
+		t.Error("expect large buffer, but got capacity ", mb[0].Cap())
+	}
+
+	bs := make([]byte, len(payload))
+	mb, _ = SplitBytes(mb, bs)
+	if r := cmp.Diff(bs, payload); r != "" {
+		t.Error(r)
+	}
+	ReleaseMulti(mb)
+}
+
+func TestSplitSizeFromLarge(t *testing.T) {
+	b := NewLarge()
+	b.Extend(LargeSize)
+
+	mb, mb2 := SplitSize(MultiBuffer{b}, 3*Size)
+	if mb2.Len() != 3*Size {
+		t.Error("expect length ", 3*Size, ", but got ", mb2.Len())
+	}
+	if mb.Len() != LargeSize-3*Size {
+		t.Error("expect length ", LargeSize-3*Size, ", but got ", mb.Len())
+	}
+	ReleaseMulti(mb)
+	ReleaseMulti(mb2)
+}
+
 func TestMultiBufferSliceBySizeLarge(t *testing.T) {
 	lb := make([]byte, 8*1024)
 	common.Must2(io.ReadFull(rand.Reader, lb))
@@ -35,6 +35,17 @@ func ReadBuffer(r io.Reader) (*Buffer, error) {
 	return nil, err
 }
 
+// ReadLargeBuffer is ReadBuffer with a large buffer, for readers that usually have more than Size bytes available.
+func ReadLargeBuffer(r io.Reader) (*Buffer, error) {
+	b := NewLarge()
+	n, err := b.ReadFrom(r)
+	if n > 0 {
+		return b, err
+	}
+	b.Release()
+	return nil, err
+}
+
 // BufferedReader is a Reader that keeps its internal buffer.
 type BufferedReader struct {
 	// Reader is the underlying reader to be read from
@@ -129,3 +129,30 @@ func TestReaderInterface(t *testing.T) {
 	_ = (io.ByteReader)(new(BufferedReader))
 	_ = (io.WriterTo)(new(BufferedReader))
 }
+
+func benchmarkReadBuffer(b *testing.B, read func(io.Reader) (*Buffer, error)) {
+	const size = 1024 * 1024
+	payload := make([]byte, size)
+	b.SetBytes(size)
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := bytes.NewReader(payload)
+		for {
+			buffer, err := read(reader)
+			buffer.Release()
+			if err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkReadBuffer(b *testing.B) {
+	benchmarkReadBuffer(b, ReadBuffer)
+}
+
+func BenchmarkReadLargeBuffer(b *testing.B) {
+	benchmarkReadBuffer(b, ReadLargeBuffer)
+}
@@ -36,6 +36,11 @@ func (s *allocStrategy) Adjust(n uint32) {
 }
 
 func (s *allocStrategy) Alloc() []*Buffer {
+	// A socket that keeps filling 8 regular buffers per read is fast enough to
+	// fill a large one, which saves the per-buffer overhead.
+	if s.current == 8 {
+		return []*Buffer{NewLarge()}
+	}
 	bs := make([]*Buffer, s.current)
 	for i := range bs {
 		bs[i] = New()
@@ -103,8 +108,8 @@ func (r *ReadVReader) readMulti() (MultiBuffer, error) {
 			break
 		}
 		end := nBytes
-		if end > Size {
-			end = Size
+		if c := bs[nBuf].Cap(); end > c {
+			end = c
 		}
 		bs[nBuf].end = end
 		nBytes -= end
@@ -139,7 +144,8 @@ func (r *ReadVReader) ReadMultiBuffer() (MultiBuffer, error) {
 	if err != nil {
 		return nil, err
 	}
-	r.alloc.Adjust(uint32(len(mb)))
+	// Adjust by the number of regular buffers the data would have filled.
+	r.alloc.Adjust(uint32((mb.Len() + Size - 1) / Size))
 	return mb, nil
 }
 
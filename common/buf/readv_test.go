@@ -5,6 +5,7 @@ package buf_test
 
 import (
 	"crypto/rand"
+	"io"
 	"net"
 	"testing"
 
@@ -70,3 +71,125 @@ func TestReadvReader(t *testing.T) {
 		t.Fatal(r)
 	}
 }
+
+func benchmarkLoopbackRead(b *testing.B, newReader func(conn *net.TCPConn) Reader) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	const size = 64 * 1024
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data := make([]byte, size)
+		for {
+			if _, err := conn.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+	reader := newReader(conn.(*net.TCPConn))
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for total := int64(0); total < int64(b.N)*size; {
+		mb, err := reader.ReadMultiBuffer()
+		if err != nil {
+			b.Fatal(err)
+		}
+		total += int64(mb.Len())
+		ReleaseMulti(mb)
+	}
+}
+
+func BenchmarkReadvReader(b *testing.B) {
+	benchmarkLoopbackRead(b, func(conn *net.TCPConn) Reader {
+		rawConn, err := conn.SyscallConn()
+		common.Must(err)
+		return NewReadVReader(conn, rawConn, nil)
+	})
+}
+
+func BenchmarkSingleReader(b *testing.B) {
+	benchmarkLoopbackRead(b, func(conn *net.TCPConn) Reader {
+		return &SingleReader{Reader: conn}
+	})
+}
+
+// largeReader reads into large buffers, like the connections of XHTTP.
+type largeReader struct {
+	io.Reader
+}
+
+func (r largeReader) ReadMultiBuffer() (MultiBuffer, error) {
+	b, err := ReadLargeBuffer(r.Reader)
+	if b == nil {
+		return nil, err
+	}
+	return MultiBuffer{b}, err
+}
+
+func BenchmarkLargeReader(b *testing.B) {
+	benchmarkLoopbackRead(b, func(conn *net.TCPConn) Reader {
+		return largeReader{Reader: conn}
+	})
+}
+
+func benchmarkLoopbackWrite(b *testing.B, newWriter func(conn *net.TCPConn) Writer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data := make([]byte, 64*1024)
+		for {
+			if _, err := conn.Read(data); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+	writer := newWriter(conn.(*net.TCPConn))
+
+	const count = 8
+	b.SetBytes(count * Size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mb := make(MultiBuffer, count)
+		for j := range mb {
+			mb[j] = New()
+			mb[j].Extend(Size)
+		}
+		if err := writer.WriteMultiBuffer(mb); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBytesWriter(b *testing.B) {
+	benchmarkLoopbackWrite(b, func(conn *net.TCPConn) Writer {
+		return NewWriter(conn)
+	})
+}
+
+func BenchmarkSequentialWriter(b *testing.B) {
+	benchmarkLoopbackWrite(b, func(conn *net.TCPConn) Writer {
+		return &SequentialWriter{Writer: conn}
+	})
+}
@@ -2,10 +2,18 @@ package buf
 
 import (
 	"syscall"
+	"unsafe"
 )
 
+// fionread is FIONREAD of winsock, the number of bytes that a recv takes without blocking.
+const fionread = 0x4004667f
+
 type windowsReader struct {
 	bufs []syscall.WSABuf
+	// waited is whether the runtime has waited for the socket to be readable since the last read.
+	waited bool
+	// noProbe is set if the socket does not report the bytes it has, like under some layered providers.
+	noProbe bool
 }
 
 func (r *windowsReader) Init(bs []*Buffer) {
@@ -13,7 +21,7 @@ func (r *windowsReader) Init(bs []*Buffer) {
 		r.bufs = make([]syscall.WSABuf, 0, len(bs))
 	}
 	for _, b := range bs {
-		r.bufs = append(r.bufs, syscall.WSABuf{Len: uint32(Size), Buf: &b.v[0]})
+		r.bufs = append(r.bufs, syscall.WSABuf{Len: uint32(len(b.v)), Buf: &b.v[0]})
 	}
 }
 
@@ -24,7 +32,28 @@ func (r *windowsReader) Clear() {
 	r.bufs = r.bufs[:0]
 }
 
+// available returns whether a read takes data without blocking. The sockets of Go are in blocking mode,
+// so WSARecv on an idle socket would block the thread, instead of the runtime waiting on its IOCP.
+func (r *windowsReader) available(fd uintptr) bool {
+	if r.waited || r.noProbe {
+		return true
+	}
+	var n, ret uint32
+	if err := syscall.WSAIoctl(syscall.Handle(fd), fionread, nil, 0, (*byte)(unsafe.Pointer(&n)), 4, &ret, nil, 0); err != nil {
+		r.noProbe = true
+		return true
+	}
+	// after the wait, the socket is read even with nothing, to report EOF
+	r.waited = n == 0
+	return n > 0
+}
+
 func (r *windowsReader) Read(fd uintptr) int32 {
+	if !r.available(fd) {
+		return -1
+	}
+	r.waited = false
+
 	var nBytes uint32
 	var flags uint32
 	err := syscall.WSARecv(syscall.Handle(fd), &r.bufs[0], uint32(len(r.bufs)), &nBytes, &flags, nil, nil)
@@ -0,0 +1,149 @@
+//go:build linux
+// +build linux
+
+package buf
+
+import (
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/features/stats"
+	"golang.org/x/sys/unix"
+)
+
+// spliceChunkSize is the maximum number of bytes moved by one splice round.
+const spliceChunkSize = 256 * 1024
+
+var useSplice bool
+
+func init() {
+	const defaultFlagValue = "NOT_DEFINED_AT_ALL"
+	value := platform.NewEnvFlag(platform.UseFreedomSplice).GetValue(func() string { return defaultFlagValue })
+	switch value {
+	case defaultFlagValue, "auto", "enable":
+		useSplice = true
+	}
+}
+
+// CanSplice returns whether the kernel can splice from readerConn to writerConn.
+// Linux splices from TCP and Unix stream sockets to TCP sockets, and from TCP sockets to Unix stream sockets.
+func CanSplice(readerConn net.Conn, writerConn net.Conn) bool {
+	switch writerConn.(type) {
+	case *net.TCPConn:
+		switch readerConn.(type) {
+		case *net.TCPConn, *net.UnixConn:
+			return true
+		}
+	case *net.UnixConn:
+		_, ok := readerConn.(*net.TCPConn)
+		return ok
+	}
+	return false
+}
+
+type spliceConn struct {
+	syscall.RawConn
+	counter stats.Counter
+}
+
+// spliceConns returns the raw connections behind reader and writer,
+// if data between them can be moved by splice(2) without passing through userspace.
+func spliceConns(reader Reader, writer Writer) (src, dst spliceConn, ok bool) {
+	if !useSplice {
+		return
+	}
+
+	var readerConn, writerConn net.Conn
+	switch r := reader.(type) {
+	case *ReadVReader:
+		readerConn, _ = r.Reader.(net.Conn)
+		src.counter = r.counter
+	case *SingleReader:
+		readerConn, _ = r.Reader.(net.Conn)
+	}
+	if w, isBytesWriter := writer.(*BufferToBytesWriter); isBytesWriter && len(w.cache) == 0 {
+		writerConn, _ = w.Writer.(net.Conn)
+		dst.counter = w.counter
+	}
+	if !CanSplice(readerConn, writerConn) {
+		return
+	}
+
+	var err error
+	if src.RawConn, err = readerConn.(syscall.Conn).SyscallConn(); err != nil {
+		return
+	}
+	if dst.RawConn, err = writerConn.(syscall.Conn).SyscallConn(); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// copyConns copies from reader to writer by splice(2) through a pipe if it can, or in userspace otherwise.
+func copyConns(reader Reader, writer Writer, handler *copyHandler) error {
+	src, dst, ok := spliceConns(reader, writer)
+	if !ok {
+		return copyInternal(reader, writer, handler)
+	}
+	var p [2]int
+	if err := unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return copyInternal(reader, writer, handler)
+	}
+	defer unix.Close(p[0])
+	defer unix.Close(p[1])
+	return copySplice(src, dst, p, handler)
+}
+
+// copySplice moves the data of src to dst through the pipe p as soon as it arrives, so that the activity timers
+// and counters are updated like by copyInternal.
+func copySplice(src, dst spliceConn, p [2]int, handler *copyHandler) error {
+	for {
+		var n int64
+		var serr error
+		if err := src.Read(func(fd uintptr) bool {
+			n, serr = unix.Splice(int(fd), nil, p[1], nil, spliceChunkSize, unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+			return serr != unix.EAGAIN
+		}); err != nil {
+			return readError{err}
+		}
+		if serr != nil {
+			return readError{serr}
+		}
+		if n == 0 {
+			return readError{io.EOF}
+		}
+
+		for left := n; left > 0; {
+			var werr error
+			if err := dst.Write(func(fd uintptr) bool {
+				var written int64
+				written, werr = unix.Splice(p[0], nil, int(fd), nil, int(left), unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+				if werr == unix.EAGAIN {
+					return false
+				}
+				if werr == nil {
+					left -= written
+				}
+				return true
+			}); err != nil {
+				return writeError{err}
+			}
+			if werr != nil {
+				return writeError{werr}
+			}
+		}
+
+		if src.counter != nil {
+			src.counter.Add(n)
+		}
+		if dst.counter != nil {
+			dst.counter.Add(n)
+		}
+		for _, handler := range handler.onSize {
+			handler(n)
+		}
+	}
+}
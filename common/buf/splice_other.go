@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package buf
+
+import (
+	"net"
+)
+
+// CanSplice returns whether the kernel can splice from readerConn to writerConn, which only Linux does.
+func CanSplice(readerConn net.Conn, writerConn net.Conn) bool {
+	return false
+}
+
+// copyConns copies from reader to writer in userspace, as splice(2) is only on Linux.
+func copyConns(reader Reader, writer Writer, handler *copyHandler) error {
+	return copyInternal(reader, writer, handler)
+}
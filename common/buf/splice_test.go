@@ -0,0 +1,107 @@
+//go:build !wasm
+// +build !wasm
+
+package buf_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+)
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(t testing.TB) (*net.TCPConn, *net.TCPConn) {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	common.Must(err)
+	defer l.Close()
+
+	accepted := make(chan *net.TCPConn, 1)
+	go func() {
+		c, err := l.AcceptTCP()
+		common.Must(err)
+		accepted <- c
+	}()
+	c, err := net.DialTCP("tcp", nil, l.Addr().(*net.TCPAddr))
+	common.Must(err)
+	return c, <-accepted
+}
+
+// relay copies everything written to the returned source connection into the returned sink connection.
+func relay(t testing.TB, wrap func(buf.Reader) buf.Reader, options ...buf.CopyOption) (source, sink *net.TCPConn, done chan error) {
+	source, relayIn := tcpPair(t)
+	relayOut, sink := tcpPair(t)
+	done = make(chan error, 1)
+	go func() {
+		err := buf.Copy(wrap(buf.NewReader(relayIn)), buf.NewWriter(relayOut), options...)
+		relayIn.Close()
+		relayOut.Close()
+		done <- err
+	}()
+	return
+}
+
+func TestCopyTCP(t *testing.T) {
+	payload := make([]byte, 4*1024*1024+17)
+	common.Must2(rand.Read(payload))
+
+	var counter buf.SizeCounter
+	source, sink, done := relay(t, func(r buf.Reader) buf.Reader { return r }, buf.CountSize(&counter))
+	defer sink.Close()
+
+	go func() {
+		common.Must2(source.Write(payload))
+		source.Close()
+	}()
+
+	received, err := io.ReadAll(sink)
+	common.Must(err)
+	common.Must(<-done)
+
+	if !bytes.Equal(received, payload) {
+		t.Error("payload mismatch, received ", len(received), " bytes")
+	}
+	if counter.Size != int64(len(payload)) {
+		t.Error("unexpected size count: ", counter.Size)
+	}
+}
+
+// userspaceReader hides the underlying connection from buf.Copy, forcing the buffered path.
+type userspaceReader struct {
+	buf.Reader
+}
+
+func benchmarkCopyTCP(b *testing.B, wrap func(buf.Reader) buf.Reader) {
+	const size = 64 * 1024 * 1024
+	source, sink, done := relay(b, wrap)
+	defer sink.Close()
+
+	chunk := make([]byte, 1024*1024)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			for written := 0; written < size; written += len(chunk) {
+				if _, err := source.Write(chunk); err != nil {
+					return
+				}
+			}
+		}
+		source.Close()
+	}()
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	common.Must2(io.Copy(io.Discard, sink))
+	<-done
+}
+
+func BenchmarkCopyTCP(b *testing.B) {
+	benchmarkCopyTCP(b, func(r buf.Reader) buf.Reader { return r })
+}
+
+func BenchmarkCopyTCPUserspace(b *testing.B) {
+	benchmarkCopyTCP(b, func(r buf.Reader) buf.Reader { return userspaceReader{r} })
+}
@@ -11,6 +11,8 @@ import (
 )
 
 // BufferToBytesWriter is a Writer that writes alloc.Buffer into underlying writer.
+// The buffers of a MultiBuffer go to a connection of the net package in one vectored write, which is writev
+// on Unix and WSASend with a WSABUF for each buffer on Windows.
 type BufferToBytesWriter struct {
 	io.Writer
 
@@ -0,0 +1,242 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+const (
+	gcmSivNonceSize = 12
+	gcmSivTagSize   = 16
+)
+
+// aesGcmSiv is AES-GCM-SIV of RFC 8452. It derives the keys of every message from its nonce,
+// so a repeated nonce reveals only whether the messages are the same.
+type aesGcmSiv struct {
+	block cipher.Block
+	// of the key-generating key, 16 or 32 bytes
+	keySize int
+}
+
+// NewAesGcmSiv creates an AEAD cipher based on AES-GCM-SIV, which resists the misuse of nonces.
+// Caller must ensure the length of key is either 16 or 32 bytes.
+func NewAesGcmSiv(key []byte) cipher.AEAD {
+	if len(key) != 16 && len(key) != 32 {
+		panic("crypto: invalid AES-GCM-SIV key size")
+	}
+	return &aesGcmSiv{
+		block:   common.Must2(aes.NewCipher(key)),
+		keySize: len(key),
+	}
+}
+
+func (*aesGcmSiv) NonceSize() int {
+	return gcmSivNonceSize
+}
+
+func (*aesGcmSiv) Overhead() int {
+	return gcmSivTagSize
+}
+
+// deriveKeys returns the POLYVAL key and the AES block of the message encryption key of the nonce.
+func (c *aesGcmSiv) deriveKeys(nonce []byte) ([]byte, cipher.Block) {
+	var in, out [16]byte
+	copy(in[4:], nonce)
+	derived := make([]byte, 16+c.keySize)
+	for i := 0; i < len(derived)/8; i++ {
+		binary.LittleEndian.PutUint32(in[:4], uint32(i))
+		c.block.Encrypt(out[:], in[:])
+		copy(derived[i*8:], out[:8])
+	}
+	return derived[:16], common.Must2(aes.NewCipher(derived[16:]))
+}
+
+// tag computes the tag of the plaintext and the additional data.
+func (c *aesGcmSiv) tag(authKey []byte, block cipher.Block, nonce, plaintext, additionalData []byte) [16]byte {
+	var p polyval
+	p.init(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+	var tag [16]byte
+	block.Encrypt(tag[:], s[:])
+	return tag
+}
+
+// ctr encrypts src into dst with the counter starting from the tag, whose first 32 bits increase in little endian.
+func ctr(block cipher.Block, tag []byte, dst, src []byte) {
+	var counter, stream [16]byte
+	copy(counter[:], tag)
+	counter[15] |= 0x80
+	for len(src) > 0 {
+		block.Encrypt(stream[:], counter[:])
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+		n := subtle.XORBytes(dst, src, stream[:])
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+func (c *aesGcmSiv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSivNonceSize {
+		panic("crypto: incorrect nonce length given to AES-GCM-SIV")
+	}
+	authKey, block := c.deriveKeys(nonce)
+	tag := c.tag(authKey, block, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSivTagSize)
+	ctr(block, tag[:], out, plaintext)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+var errOpen = errors.New("message authentication failed")
+
+func (c *aesGcmSiv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSivNonceSize {
+		panic("crypto: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if len(ciphertext) < gcmSivTagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-gcmSivTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-gcmSivTagSize]
+
+	authKey, block := c.deriveKeys(nonce)
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctr(block, tag, out, ciphertext)
+	expected := c.tag(authKey, block, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// sliceForAppend extends in by n bytes, and returns the whole slice and the extension.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+// polyval is POLYVAL of RFC 8452, computed by GHASH of the byte reversed blocks as in its appendix A.
+type polyval struct {
+	productTable [16]fieldElement
+	y            fieldElement
+}
+
+// fieldElement is an element of GF(2^128) in the bit order of GHASH.
+type fieldElement struct {
+	low, high uint64
+}
+
+func (p *polyval) init(key []byte) {
+	var h [16]byte
+	reverseBytes(h[:], key)
+	x := double(&fieldElement{binary.BigEndian.Uint64(h[:8]), binary.BigEndian.Uint64(h[8:])})
+	// productTable[i] is x multiplied by the 4-bit element i, in the reversed bit order
+	p.productTable[reverseBits(1)] = x
+	for i := 2; i < 16; i += 2 {
+		p.productTable[reverseBits(i)] = double(&p.productTable[reverseBits(i/2)])
+		p.productTable[reverseBits(i+1)] = add(&p.productTable[reverseBits(i)], &x)
+	}
+}
+
+// update absorbs data, padded with zeros to whole blocks.
+func (p *polyval) update(data []byte) {
+	var block [16]byte
+	for len(data) > 0 {
+		n := copy(block[:], data)
+		clear(block[n:])
+		data = data[n:]
+		reverseBytes(block[:], block[:])
+		p.y.low ^= binary.BigEndian.Uint64(block[:8])
+		p.y.high ^= binary.BigEndian.Uint64(block[8:])
+		p.mul(&p.y)
+	}
+}
+
+func (p *polyval) sum() [16]byte {
+	var s [16]byte
+	binary.BigEndian.PutUint64(s[:8], p.y.low)
+	binary.BigEndian.PutUint64(s[8:], p.y.high)
+	reverseBytes(s[:], s[:])
+	return s
+}
+
+var reductionTable = []uint16{
+	0x0000, 0x1c20, 0x3840, 0x2460, 0x7080, 0x6ca0, 0x48c0, 0x54e0,
+	0xe100, 0xfd20, 0xd940, 0xc560, 0x9180, 0x8da0, 0xa9c0, 0xb5e0,
+}
+
+// mul sets y to y*H.
+func (p *polyval) mul(y *fieldElement) {
+	var z fieldElement
+	for i := 0; i < 2; i++ {
+		word := y.high
+		if i == 1 {
+			word = y.low
+		}
+		for j := 0; j < 64; j += 4 {
+			msw := z.high & 0xf
+			z.high >>= 4
+			z.high |= z.low << 60
+			z.low >>= 4
+			z.low ^= uint64(reductionTable[msw]) << 48
+
+			t := &p.productTable[word&0xf]
+			z.low ^= t.low
+			z.high ^= t.high
+			word >>= 4
+		}
+	}
+	*y = z
+}
+
+func add(x, y *fieldElement) fieldElement {
+	return fieldElement{x.low ^ y.low, x.high ^ y.high}
+}
+
+// double returns x multiplied by the generator of the field.
+func double(x *fieldElement) (d fieldElement) {
+	msbSet := x.high&1 == 1
+	d.high = x.high >> 1
+	d.high |= x.low << 63
+	d.low = x.low >> 1
+	if msbSet {
+		d.low ^= 0xe100000000000000
+	}
+	return
+}
+
+// reverseBits reverses the order of the bits of 4-bit number in i.
+func reverseBits(i int) int {
+	i = ((i << 2) & 0xc) | ((i >> 2) & 0x3)
+	i = ((i << 1) & 0xa) | ((i >> 1) & 0x5)
+	return i
+}
+
+func reverseBytes(dst, src []byte) {
+	for i, j := 0, len(src)-1; i <= j; i, j = i+1, j-1 {
+		dst[i], dst[j] = src[j], src[i]
+	}
+}
@@ -0,0 +1,58 @@
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/crypto"
+)
+
+// the vectors of RFC 8452, appendix C
+func TestAesGcmSivVectors(t *testing.T) {
+	cases := []struct {
+		key       string
+		plaintext string
+		aad       string
+		result    string
+	}{
+		{"01000000000000000000000000000000", "", "", "dc20e2d83f25705bb49e439eca56de25"},
+		{"01000000000000000000000000000000", "0100000000000000", "", "b5d839330ac7b786578782fff6013b815b287c22493a364c"},
+		{"01000000000000000000000000000000", "010000000000000000000000", "", "7323ea61d05932260047d942a4978db357391a0bc4fdec8b0d106639"},
+		{"01000000000000000000000000000000", "0200000000000000", "01", "1e6daba35669f4273b0a1a2560969cdf790d99759abd1508"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "", "", "07f5f4169bbf55a8400cd47ea6fd400f"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "0100000000000000", "", "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28"},
+	}
+	nonce := mustDecodeHex("030000000000000000000000")
+	for _, c := range cases {
+		aead := NewAesGcmSiv(mustDecodeHex(c.key))
+		plaintext := mustDecodeHex(c.plaintext)
+		result := aead.Seal(nil, nonce, plaintext, mustDecodeHex(c.aad))
+		if !bytes.Equal(result, mustDecodeHex(c.result)) {
+			t.Errorf("Seal(%s) = %x, want %s", c.plaintext, result, c.result)
+		}
+		opened, err := aead.Open(nil, nonce, result, mustDecodeHex(c.aad))
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("Open(%s) = %x, %v", c.result, opened, err)
+		}
+	}
+}
+
+func TestAesGcmSivTampered(t *testing.T) {
+	key := make([]byte, 32)
+	common.Must2(rand.Read(key))
+	aead := NewAesGcmSiv(key)
+	nonce := make([]byte, aead.NonceSize())
+	plaintext := make([]byte, 16*1024+7)
+	common.Must2(rand.Read(plaintext))
+
+	sealed := aead.Seal(nil, nonce, plaintext, []byte("aad"))
+	if opened, err := aead.Open(nil, nonce, sealed, []byte("aad")); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatal("failed to open: ", err)
+	}
+	sealed[100] ^= 1
+	if _, err := aead.Open(nil, nonce, sealed, []byte("aad")); err == nil {
+		t.Error("opened a tampered message")
+	}
+}
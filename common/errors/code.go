@@ -0,0 +1,70 @@
+package errors
+
+// Code is a machine-readable class of an error, for API clients that must not parse error messages.
+// Codes keep their meaning across releases; new codes may be added.
+type Code int32
+
+const (
+	// CodeUnknown is the code of errors that have not been classified.
+	CodeUnknown Code = iota
+	// CodeNotFound means the requested handler, user, rule or other object does not exist.
+	CodeNotFound
+	// CodeAlreadyExists means the object to add conflicts with an existing one.
+	CodeAlreadyExists
+	// CodeInvalidConfig means the request carries a config that cannot be built or applied.
+	CodeInvalidConfig
+	// CodeUnavailable means the feature needed by the request is not enabled.
+	CodeUnavailable
+	// CodeUnimplemented means the operation is not supported by the target object.
+	CodeUnimplemented
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeNotFound:
+		return "NotFound"
+	case CodeAlreadyExists:
+		return "AlreadyExists"
+	case CodeInvalidConfig:
+		return "InvalidConfig"
+	case CodeUnavailable:
+		return "Unavailable"
+	case CodeUnimplemented:
+		return "Unimplemented"
+	default:
+		return "Unknown"
+	}
+}
+
+type hasCode interface {
+	Code() Code
+}
+
+// WithCode sets the code of the error.
+func (err *Error) WithCode(code Code) *Error {
+	err.code = code
+	return err
+}
+
+// Code returns the code of the error, or the code of its inner error if it has none.
+func (err *Error) Code() Code {
+	if err.code != CodeUnknown {
+		return err.code
+	}
+	return CodeOf(err.inner)
+}
+
+// CodeOf returns the code of err, looking through wrapping errors.
+func CodeOf(err error) Code {
+	for err != nil {
+		if c, ok := err.(hasCode); ok {
+			return c.Code()
+		}
+		inner, ok := err.(hasInnerError)
+		if !ok {
+			return CodeUnknown
+		}
+		err = inner.Unwrap()
+	}
+	return CodeUnknown
+}
@@ -29,6 +29,7 @@ type Error struct {
 	caller   string
 	inner    error
 	severity log.Severity
+	code     Code
 }
 
 // Error implements error.Error().
@@ -120,6 +121,22 @@ type ExportOptionHolder struct {
 
 type ExportOption func(*ExportOptionHolder)
 
+// WriteToLog writes the error into the error log, with the session ID from the options as its prefix.
+func (err *Error) WriteToLog(opts ...ExportOption) {
+	var holder ExportOptionHolder
+	for _, opt := range opts {
+		opt(&holder)
+	}
+	if holder.SessionID > 0 {
+		err.prefix = append(err.prefix, holder.SessionID)
+	}
+	log.Record(&log.GeneralMessage{
+		Severity:  GetSeverity(err),
+		Content:   err,
+		SessionID: holder.SessionID,
+	})
+}
+
 // New returns a new error object with message formed from given arguments.
 func New(msg ...interface{}) *Error {
 	pc, _, _, _ := runtime.Caller(1)
@@ -186,15 +203,17 @@ func doLog(ctx context.Context, inner error, severity log.Severity, msg ...inter
 		caller:   details,
 		inner:    inner,
 	}
+	var id uint32
 	if ctx != nil && ctx != context.Background() {
-		id := uint32(c.IDFromContext(ctx))
+		id = uint32(c.IDFromContext(ctx))
 		if id > 0 {
 			err.prefix = append(err.prefix, id)
 		}
 	}
 	log.Record(&log.GeneralMessage{
-		Severity: GetSeverity(err),
-		Content:  err,
+		Severity:  GetSeverity(err),
+		Content:   err,
+		SessionID: id,
 	})
 }
 
@@ -1,11 +1,13 @@
 package errors_test
 
 import (
+	"context"
 	"io"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	c "github.com/xtls/xray-core/common/ctx"
 	. "github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/log"
 )
@@ -53,3 +55,53 @@ func TestErrorMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorCode(t *testing.T) {
+	err := New("user not found").WithCode(CodeNotFound)
+	if v := CodeOf(err); v != CodeNotFound {
+		t.Error("code: ", v)
+	}
+
+	wrapped := New("failed to remove user").Base(New("proxy").Base(err))
+	if v := CodeOf(wrapped); v != CodeNotFound {
+		t.Error("wrapped code: ", v)
+	}
+
+	if v := CodeOf(New("invalid").Base(err).WithCode(CodeInvalidConfig)); v != CodeInvalidConfig {
+		t.Error("outer code: ", v)
+	}
+
+	if v := CodeOf(New("a").Base(io.EOF)); v != CodeUnknown {
+		t.Error("unclassified code: ", v)
+	}
+}
+
+type testLogger struct {
+	msg *log.GeneralMessage
+}
+
+func (l *testLogger) Handle(msg log.Message) {
+	l.msg, _ = msg.(*log.GeneralMessage)
+}
+
+func TestErrorSessionID(t *testing.T) {
+	var logger testLogger
+	log.RegisterHandler(&logger)
+
+	ctx := c.ContextWithID(context.Background(), 42)
+	LogWarning(ctx, "a")
+	if logger.msg == nil || logger.msg.SessionID != 42 {
+		t.Fatal("unexpected message: ", logger.msg)
+	}
+	if diff := cmp.Diff("[Warning] [42] common/errors_test: a", logger.msg.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	New("b").AtWarning().WriteToLog(func(h *ExportOptionHolder) { h.SessionID = 43 })
+	if logger.msg.SessionID != 43 {
+		t.Error("unexpected session ID: ", logger.msg.SessionID)
+	}
+	if diff := cmp.Diff("[Warning] [43] common/errors_test: b", logger.msg.String()); diff != "" {
+		t.Error(diff)
+	}
+}
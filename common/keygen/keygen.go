@@ -0,0 +1,161 @@
+// Package keygen generates the keys of REALITY, VLESS Encryption and WireGuard, for the commands and the APIs
+// that make configs.
+package keygen
+
+import (
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/uuid"
+	"lukechampine.com/blake3"
+)
+
+// Generator generates keys from its source of randomness.
+type Generator struct {
+	rand io.Reader
+}
+
+// New returns a Generator of random keys.
+func New() *Generator {
+	return &Generator{rand: rand.Reader}
+}
+
+// NewSeeded returns a Generator whose keys are determined by seed, which is for tests only, as anyone knowing
+// the seed knows the keys.
+func NewSeeded(seed string) *Generator {
+	h := blake3.New(32, nil)
+	h.Write([]byte(seed))
+	return &Generator{rand: h.XOF()}
+}
+
+func (g *Generator) read(b []byte) error {
+	if _, err := io.ReadFull(g.rand, b); err != nil {
+		return errors.New("failed to read random bytes").Base(err)
+	}
+	return nil
+}
+
+// X25519Key is a key pair of X25519, for REALITY, the X25519 authentication of VLESS Encryption and WireGuard.
+type X25519Key struct {
+	PrivateKey []byte
+	// PublicKey is the password of REALITY.
+	PublicKey []byte
+}
+
+// NewX25519Key returns the key pair of privateKey, which is clamped in place as described at
+// https://cr.yp.to/ecdh.html, so that the real private key is shown.
+func NewX25519Key(privateKey []byte) (*X25519Key, error) {
+	if len(privateKey) != 32 {
+		return nil, errors.New("invalid length of X25519 private key: ", len(privateKey))
+	}
+	privateKey[0] &= 248
+	privateKey[31] &= 127
+	privateKey[31] |= 64
+
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &X25519Key{PrivateKey: privateKey, PublicKey: key.PublicKey().Bytes()}, nil
+}
+
+// Hash32 returns the BLAKE3 hash of the public key.
+func (k *X25519Key) Hash32() [32]byte {
+	return blake3.Sum256(k.PublicKey)
+}
+
+// X25519 returns a new key pair of X25519.
+func (g *Generator) X25519() (*X25519Key, error) {
+	privateKey := make([]byte, 32)
+	if err := g.read(privateKey); err != nil {
+		return nil, err
+	}
+	return NewX25519Key(privateKey)
+}
+
+// MLKEM768Key is a key pair of ML-KEM-768, for the post-quantum authentication of VLESS Encryption.
+type MLKEM768Key struct {
+	Seed   [64]byte
+	Client []byte
+}
+
+// NewMLKEM768Key returns the key pair of seed.
+func NewMLKEM768Key(seed [64]byte) (*MLKEM768Key, error) {
+	key, err := mlkem.NewDecapsulationKey768(seed[:])
+	if err != nil {
+		return nil, err
+	}
+	return &MLKEM768Key{Seed: seed, Client: key.EncapsulationKey().Bytes()}, nil
+}
+
+// Hash32 returns the BLAKE3 hash of the client key.
+func (k *MLKEM768Key) Hash32() [32]byte {
+	return blake3.Sum256(k.Client)
+}
+
+// MLKEM768 returns a new key pair of ML-KEM-768.
+func (g *Generator) MLKEM768() (*MLKEM768Key, error) {
+	var seed [64]byte
+	if err := g.read(seed[:]); err != nil {
+		return nil, err
+	}
+	return NewMLKEM768Key(seed)
+}
+
+// ShortIds returns count short IDs of REALITY, each of length hex digits, which is even and at most 16.
+func (g *Generator) ShortIds(count int, length int) ([]string, error) {
+	if length < 0 || length > 16 || length%2 != 0 {
+		return nil, errors.New("invalid length of short ID: ", length, ", expected an even number up to 16")
+	}
+	ids := make([]string, 0, count)
+	for range count {
+		b := make([]byte, length/2)
+		if err := g.read(b); err != nil {
+			return nil, err
+		}
+		ids = append(ids, hex.EncodeToString(b))
+	}
+	return ids, nil
+}
+
+// UUID returns a new UUID of version 4, as the ID of a user.
+func (g *Generator) UUID() (string, error) {
+	var id uuid.UUID
+	if err := g.read(id[:]); err != nil {
+		return "", err
+	}
+	id[6] = (id[6] & 0x0f) | (4 << 4)
+	id[8] = (id[8]&(0xff>>2) | (0x02 << 6))
+	return id.String(), nil
+}
+
+// VLESSEncryption returns the "decryption" of the server and the "encryption" of the client of VLESS Encryption,
+// which authenticates the server with auth, either "x25519" or "mlkem768".
+func (g *Generator) VLESSEncryption(auth string) (decryption string, encryption string, err error) {
+	var serverKey, clientKey []byte
+	switch auth {
+	case "x25519":
+		key, err := g.X25519()
+		if err != nil {
+			return "", "", err
+		}
+		serverKey, clientKey = key.PrivateKey, key.PublicKey
+	case "mlkem768":
+		key, err := g.MLKEM768()
+		if err != nil {
+			return "", "", err
+		}
+		serverKey, clientKey = key.Seed[:], key.Client
+	default:
+		return "", "", errors.New("unknown authentication of VLESS Encryption: ", auth)
+	}
+	decryption = strings.Join([]string{"mlkem768x25519plus", "native", "600s", base64.RawURLEncoding.EncodeToString(serverKey)}, ".")
+	encryption = strings.Join([]string{"mlkem768x25519plus", "native", "0rtt", base64.RawURLEncoding.EncodeToString(clientKey)}, ".")
+	return decryption, encryption, nil
+}
@@ -0,0 +1,69 @@
+package keygen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/keygen"
+)
+
+func TestSeeded(t *testing.T) {
+	generate := func() []string {
+		g := NewSeeded("test")
+		key, err := g.X25519()
+		common.Must(err)
+		shortIds, err := g.ShortIds(2, 8)
+		common.Must(err)
+		decryption, encryption, err := g.VLESSEncryption("mlkem768")
+		common.Must(err)
+		return append(shortIds, string(key.PrivateKey), decryption, encryption)
+	}
+	if diff := cmp.Diff(generate(), generate()); diff != "" {
+		t.Error(diff)
+	}
+
+	a, err := NewSeeded("a").UUID()
+	common.Must(err)
+	b, err := NewSeeded("b").UUID()
+	common.Must(err)
+	if a == b {
+		t.Error("expected different IDs of different seeds, but got ", a)
+	}
+}
+
+func TestShortIds(t *testing.T) {
+	shortIds, err := New().ShortIds(3, 6)
+	common.Must(err)
+	if len(shortIds) != 3 {
+		t.Fatal("expected 3 short IDs, but got ", shortIds)
+	}
+	for _, shortId := range shortIds {
+		if len(shortId) != 6 {
+			t.Error("expected a short ID of 6 hex digits, but got ", shortId)
+		}
+	}
+
+	for _, length := range []int{3, 18, -2} {
+		if _, err := New().ShortIds(1, length); err == nil {
+			t.Error("expected an error of the length ", length)
+		}
+	}
+}
+
+func TestVLESSEncryption(t *testing.T) {
+	for _, auth := range []string{"x25519", "mlkem768"} {
+		decryption, encryption, err := New().VLESSEncryption(auth)
+		common.Must(err)
+		if !strings.HasPrefix(decryption, "mlkem768x25519plus.native.600s.") {
+			t.Error("unexpected decryption ", decryption)
+		}
+		if !strings.HasPrefix(encryption, "mlkem768x25519plus.native.0rtt.") {
+			t.Error("unexpected encryption ", encryption)
+		}
+	}
+	if _, _, err := New().VLESSEncryption("unknown"); err == nil {
+		t.Error("expected an error of an unknown auth")
+	}
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"strconv"
 	"strings"
 
 	"github.com/xtls/xray-core/common/serial"
@@ -24,13 +25,25 @@ type AccessMessage struct {
 	From   interface{}
 	To     interface{}
 	Status AccessStatus
-	Reason interface{}
-	Email  string
-	Detour string
+	// Reason of a rejected connection, or the RouteReason of an accepted one
+	Reason      interface{}
+	Email       string
+	Detour      string
+	InboundTag  string
+	OutboundTag string
+	SessionID   uint32
+	// Fingerprints of the TLS ClientHello, if computed for the routing rules
+	JA3 string
+	JA4 string
 }
 
 func (m *AccessMessage) String() string {
 	builder := strings.Builder{}
+	if m.SessionID > 0 {
+		builder.WriteByte('[')
+		builder.WriteString(strconv.FormatUint(uint64(m.SessionID), 10))
+		builder.WriteString("] ")
+	}
 	builder.WriteString("from")
 	builder.WriteByte(' ')
 	builder.WriteString(serial.ToString(m.From))
@@ -47,6 +60,9 @@ func (m *AccessMessage) String() string {
 
 	if reason := serial.ToString(m.Reason); len(reason) > 0 {
 		builder.WriteString(" ")
+		if _, ok := m.Reason.(*RouteReason); ok {
+			builder.WriteString("reason: ")
+		}
 		builder.WriteString(reason)
 	}
 
@@ -58,6 +74,40 @@ func (m *AccessMessage) String() string {
 	return builder.String()
 }
 
+// RouteReason tells why an accepted connection is dispatched to its outbound.
+type RouteReason struct {
+	// Rule is set if a routing rule is matched.
+	Rule bool
+	// RuleTag is the tag of the routing rule matched, if it has one.
+	RuleTag string
+	// Default is set if no rule is matched, so the default outbound is taken.
+	Default bool
+	// Balancer is the tag of the balancer picking the outbound.
+	Balancer string
+	// SniffedDomain is the domain overriding the destination by sniffing, if any.
+	SniffedDomain string
+}
+
+// String returns the reasons separated by commas, like "rule:direct,sniffed:example.com", "default" or
+// "rule,balancer:proxies". A rule tag always follows "rule:", so that no tag reads as an untagged rule or the default.
+func (r *RouteReason) String() string {
+	var reasons []string
+	if r.Default {
+		reasons = append(reasons, "default")
+	} else if r.RuleTag != "" {
+		reasons = append(reasons, "rule:"+r.RuleTag)
+	} else if r.Rule {
+		reasons = append(reasons, "rule")
+	}
+	if r.Balancer != "" {
+		reasons = append(reasons, "balancer:"+r.Balancer)
+	}
+	if r.SniffedDomain != "" {
+		reasons = append(reasons, "sniffed:"+r.SniffedDomain)
+	}
+	return strings.Join(reasons, ",")
+}
+
 func ContextWithAccessMessage(ctx context.Context, accessMessage *AccessMessage) context.Context {
 	return context.WithValue(ctx, accessMessageKey, accessMessage)
 }
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"strings"
+	"time"
+)
+
+// AuthFailureMessage is the log of a client failing the authentication of an inbound.
+type AuthFailureMessage struct {
+	Time       time.Time
+	InboundTag string
+	Protocol   string
+	// IP address of the client.
+	Source string
+	Reason string
+}
+
+func (m *AuthFailureMessage) String() string {
+	builder := strings.Builder{}
+	builder.WriteString("auth failure from ")
+	builder.WriteString(m.Source)
+	if len(m.InboundTag) > 0 {
+		builder.WriteString(" [")
+		builder.WriteString(m.InboundTag)
+		builder.WriteByte(']')
+	}
+	builder.WriteByte(' ')
+	builder.WriteString(m.Protocol)
+	if len(m.Reason) > 0 {
+		builder.WriteString(": ")
+		builder.WriteString(m.Reason)
+	}
+	return builder.String()
+}
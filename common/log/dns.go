@@ -2,28 +2,35 @@ package log
 
 import (
 	"net"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type DNSLog struct {
-	Server  string
-	Domain  string
-	Result  []net.IP
-	Status  dnsStatus
-	Elapsed time.Duration
-	Error   error
+	Server    string
+	Domain    string
+	QueryType string
+	Result    []net.IP
+	Status    dnsStatus
+	Elapsed   time.Duration
+	Error     error
+	RCode     uint16
 }
 
 func (l *DNSLog) String() string {
 	builder := &strings.Builder{}
 
-	// Server got answer: domain -> [ip1, ip2] 23ms
+	// Server got answer: domain A -> [ip1, ip2] 23ms
 	builder.WriteString(l.Server)
 	builder.WriteString(" ")
 	builder.WriteString(string(l.Status))
 	builder.WriteString(" ")
 	builder.WriteString(l.Domain)
+	if len(l.QueryType) > 0 {
+		builder.WriteString(" ")
+		builder.WriteString(l.QueryType)
+	}
 	builder.WriteString(" -> [")
 	builder.WriteString(joinNetIP(l.Result))
 	builder.WriteString("]")
@@ -46,8 +53,37 @@ var (
 	DNSQueried        = dnsStatus("got answer:")
 	DNSCacheHit       = dnsStatus("cache HIT:")
 	DNSCacheOptimiste = dnsStatus("cache OPTIMISTE:")
+	DNSFake           = dnsStatus("fake answer:")
 )
 
+// cache returns whether the answer came from the cache, for the JSON format.
+func (s dnsStatus) cache() string {
+	switch s {
+	case DNSCacheHit:
+		return "hit"
+	case DNSCacheOptimiste:
+		return "stale"
+	default:
+		return "miss"
+	}
+}
+
+var rcodeNames = map[uint16]string{
+	0: "NOERROR",
+	1: "FORMERR",
+	2: "SERVFAIL",
+	3: "NXDOMAIN",
+	4: "NOTIMP",
+	5: "REFUSED",
+}
+
+func rcodeName(rcode uint16) string {
+	if name, found := rcodeNames[rcode]; found {
+		return name
+	}
+	return strconv.Itoa(int(rcode))
+}
+
 func joinNetIP(ips []net.IP) string {
 	if len(ips) == 0 {
 		return ""
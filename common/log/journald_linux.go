@@ -0,0 +1,79 @@
+//go:build linux
+
+package log
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+type journaldWriter struct {
+	conn   *net.UnixConn
+	tag    string
+	buffer []byte
+}
+
+func (w *journaldWriter) Write(s string) error {
+	return w.send(priorityInfo, strings.TrimRight(s, "\r\n"), nil)
+}
+
+func (w *journaldWriter) WriteMessage(msg Message, line string) error {
+	return w.send(messagePriority(msg), line, messageFields(msg))
+}
+
+// send writes an entry in the native journal protocol, one field per line.
+func (w *journaldWriter) send(priority int, message string, fields [][2]string) error {
+	b := appendJournalField(w.buffer[:0], "MESSAGE", message)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(priority))
+	if len(w.tag) > 0 {
+		b = appendJournalField(b, "SYSLOG_IDENTIFIER", w.tag)
+	}
+	for _, field := range fields {
+		b = appendJournalField(b, field[0], field[1])
+	}
+	w.buffer = b
+	_, err := w.conn.Write(b)
+	return err
+}
+
+func (w *journaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// appendJournalField appends a field as KEY=value, or in the binary form if the value spans multiple lines.
+func appendJournalField(b []byte, key string, value string) []byte {
+	b = append(b, key...)
+	if strings.IndexByte(value, '\n') < 0 {
+		b = append(b, '=')
+		b = append(b, value...)
+	} else {
+		b = append(b, '\n')
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+		b = append(b, value...)
+	}
+	return append(b, '\n')
+}
+
+func dialJournal() (*net.UnixConn, error) {
+	return net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+}
+
+// CreateJournaldLogWriter returns a LogWriterCreator that creates LogWriter for the systemd journal.
+func CreateJournaldLogWriter(tag string) (WriterCreator, error) {
+	conn, err := dialJournal()
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return func() Writer {
+		conn, err := dialJournal()
+		if err != nil {
+			return nil
+		}
+		return &journaldWriter{conn: conn, tag: tag}
+	}, nil
+}
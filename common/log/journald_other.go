@@ -0,0 +1,12 @@
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+)
+
+// CreateJournaldLogWriter returns a LogWriterCreator that creates LogWriter for the systemd journal.
+func CreateJournaldLogWriter(tag string) (WriterCreator, error) {
+	return nil, errors.New("journald is only supported on Linux")
+}
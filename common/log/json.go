@@ -0,0 +1,198 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xtls/xray-core/common/serial"
+)
+
+// JSONMessage is a Message that can be written as a JSON object.
+type JSONMessage interface {
+	Message
+
+	// AppendJSON appends the fields of the message to b, each one preceded by a comma.
+	AppendJSON(b []byte) []byte
+}
+
+var severityLevels = map[Severity]string{
+	Severity_Unknown: "unknown",
+	Severity_Error:   "error",
+	Severity_Warning: "warning",
+	Severity_Info:    "info",
+	Severity_Debug:   "debug",
+}
+
+// AppendJSON implements JSONMessage.
+func (m *GeneralMessage) AppendJSON(b []byte) []byte {
+	level, found := severityLevels[m.Severity]
+	if !found {
+		level = strings.ToLower(m.Severity.String())
+	}
+	b = AppendJSONField(b, "level", level)
+	if m.SessionID > 0 {
+		b = appendJSONUint(b, "sessionId", m.SessionID)
+	}
+	return AppendJSONField(b, "message", serial.ToString(m.Content))
+}
+
+// AppendJSON implements JSONMessage.
+func (m *AccessMessage) AppendJSON(b []byte) []byte {
+	b = AppendJSONField(b, "level", "info")
+	b = appendJSONUint(b, "sessionId", m.SessionID)
+	b = AppendJSONField(b, "status", string(m.Status))
+	b = AppendJSONField(b, "inboundTag", m.InboundTag)
+	b = AppendJSONField(b, "outboundTag", m.OutboundTag)
+	b = AppendJSONField(b, "user", m.Email)
+	b = AppendJSONField(b, "source", serial.ToString(m.From))
+	b = AppendJSONField(b, "destination", serial.ToString(m.To))
+	b = AppendJSONField(b, "detour", m.Detour)
+	b = AppendJSONField(b, "reason", serial.ToString(m.Reason))
+	if r, ok := m.Reason.(*RouteReason); ok {
+		b = r.appendJSON(b)
+	}
+	if len(m.JA3) > 0 {
+		b = AppendJSONField(b, "ja3", m.JA3)
+	}
+	if len(m.JA4) > 0 {
+		b = AppendJSONField(b, "ja4", m.JA4)
+	}
+	return AppendJSONField(b, "message", m.String())
+}
+
+// appendJSON appends the reasons as fields of their own, "route" being "rule" or "default" if routed by either.
+func (r *RouteReason) appendJSON(b []byte) []byte {
+	switch {
+	case r.Default:
+		b = AppendJSONField(b, "route", "default")
+	case r.Rule || r.RuleTag != "":
+		b = AppendJSONField(b, "route", "rule")
+		if len(r.RuleTag) > 0 {
+			b = AppendJSONField(b, "ruleTag", r.RuleTag)
+		}
+	}
+	if len(r.Balancer) > 0 {
+		b = AppendJSONField(b, "balancer", r.Balancer)
+	}
+	if len(r.SniffedDomain) > 0 {
+		b = AppendJSONField(b, "sniffedDomain", r.SniffedDomain)
+	}
+	return b
+}
+
+// AppendJSON implements JSONMessage.
+func (l *DNSLog) AppendJSON(b []byte) []byte {
+	b = AppendJSONField(b, "level", "info")
+	b = AppendJSONField(b, "server", l.Server)
+	b = AppendJSONField(b, "domain", l.Domain)
+	b = AppendJSONField(b, "queryType", l.QueryType)
+	b = append(b, `,"answers":[`...)
+	for i, ip := range l.Result {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, ip.String())
+	}
+	b = append(b, ']')
+	var rcode string
+	switch {
+	case l.RCode > 0:
+		rcode = rcodeName(l.RCode)
+	case l.Error == nil:
+		rcode = rcodeName(0)
+	}
+	b = AppendJSONField(b, "rcode", rcode)
+	b = append(b, `,"latencyMs":`...)
+	b = strconv.AppendFloat(b, float64(l.Elapsed)/float64(time.Millisecond), 'f', -1, 64)
+	b = AppendJSONField(b, "cache", l.Status.cache())
+	b = append(b, `,"fakedns":`...)
+	b = strconv.AppendBool(b, l.Status == DNSFake)
+	if l.Error != nil {
+		b = AppendJSONField(b, "error", l.Error.Error())
+	}
+	return AppendJSONField(b, "message", l.String())
+}
+
+// AppendJSON implements JSONMessage.
+func (m *AuthFailureMessage) AppendJSON(b []byte) []byte {
+	b = AppendJSONField(b, "level", "warning")
+	b = AppendJSONField(b, "inboundTag", m.InboundTag)
+	b = AppendJSONField(b, "protocol", m.Protocol)
+	b = AppendJSONField(b, "source", m.Source)
+	b = AppendJSONField(b, "reason", m.Reason)
+	return AppendJSONField(b, "message", m.String())
+}
+
+// AppendJSONField appends a string field, preceded by a comma, to a JSON object being built in b.
+func AppendJSONField(b []byte, key string, value string) []byte {
+	b = append(b, ',')
+	b = appendJSONString(b, key)
+	b = append(b, ':')
+	return appendJSONString(b, value)
+}
+
+func appendJSONUint(b []byte, key string, value uint32) []byte {
+	b = append(b, ',')
+	b = appendJSONString(b, key)
+	b = append(b, ':')
+	return strconv.AppendUint(b, uint64(value), 10)
+}
+
+// appendJSONLine formats msg as a single line JSON object, with the time as the first field.
+func appendJSONLine(b []byte, msg Message, t time.Time) []byte {
+	b = append(b, `{"ts":"`...)
+	b = t.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, '"')
+	if m, ok := msg.(JSONMessage); ok {
+		b = m.AppendJSON(b)
+	} else {
+		b = AppendJSONField(b, "level", "info")
+		b = AppendJSONField(b, "message", msg.String())
+	}
+	return append(b, '}')
+}
+
+const hexDigits = "0123456789abcdef"
+
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
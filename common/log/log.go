@@ -18,8 +18,9 @@ type Handler interface {
 
 // GeneralMessage is a general log message that can contain all kind of content.
 type GeneralMessage struct {
-	Severity Severity
-	Content  interface{}
+	Severity  Severity
+	Content   interface{}
+	SessionID uint32
 }
 
 // String implements Message.
@@ -42,6 +43,12 @@ func RegisterHandler(handler Handler) {
 	logHandler.Set(handler)
 }
 
+// ReplaceHandler registers handler as current log handler, and returns the previous one, which is nil if none
+// was registered. Unlike RegisterHandler, it accepts nil, which drops the logs.
+func ReplaceHandler(handler Handler) Handler {
+	return logHandler.Swap(handler)
+}
+
 type syncHandler struct {
 	sync.RWMutex
 	Handler
@@ -62,3 +69,12 @@ func (h *syncHandler) Set(handler Handler) {
 
 	h.Handler = handler
 }
+
+func (h *syncHandler) Swap(handler Handler) Handler {
+	h.Lock()
+	defer h.Unlock()
+
+	previous := h.Handler
+	h.Handler = handler
+	return previous
+}
@@ -1,9 +1,13 @@
 package log_test
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
 )
@@ -30,3 +34,125 @@ func TestLogRecord(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestDNSLogJSON(t *testing.T) {
+	msg := &log.DNSLog{
+		Server:    "localhost",
+		Domain:    "example.com.",
+		QueryType: "A",
+		Result:    []net.IP{net.ParseIP("1.2.3.4"), net.ParseIP("5.6.7.8")},
+		Status:    log.DNSQueried,
+		Elapsed:   1500 * time.Microsecond,
+	}
+	if diff := cmp.Diff("localhost got answer: example.com. A -> [1.2.3.4, 5.6.7.8] 1.5ms", msg.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	var fields map[string]interface{}
+	common.Must(json.Unmarshal(append(append([]byte("{\"ts\":0"), msg.AppendJSON(nil)...), '}'), &fields))
+	if diff := cmp.Diff(map[string]interface{}{
+		"ts":        float64(0),
+		"level":     "info",
+		"server":    "localhost",
+		"domain":    "example.com.",
+		"queryType": "A",
+		"answers":   []interface{}{"1.2.3.4", "5.6.7.8"},
+		"rcode":     "NOERROR",
+		"latencyMs": 1.5,
+		"cache":     "miss",
+		"fakedns":   false,
+		"message":   msg.String(),
+	}, fields); diff != "" {
+		t.Error(diff)
+	}
+
+	msg = &log.DNSLog{Server: "1.1.1.1", Domain: "none.example.", Status: log.DNSCacheHit, Error: errors.New("rcode: 3"), RCode: 3}
+	fields = nil
+	common.Must(json.Unmarshal(append(append([]byte("{\"ts\":0"), msg.AppendJSON(nil)...), '}'), &fields))
+	if fields["rcode"] != "NXDOMAIN" || fields["cache"] != "hit" || fields["error"] != "rcode: 3" {
+		t.Error("unexpected fields: ", fields)
+	}
+}
+
+func TestAuthFailureMessageJSON(t *testing.T) {
+	msg := &log.AuthFailureMessage{
+		InboundTag: "vless-in",
+		Protocol:   "vless",
+		Source:     "1.2.3.4",
+		Reason:     "invalid request user id",
+	}
+	if diff := cmp.Diff("auth failure from 1.2.3.4 [vless-in] vless: invalid request user id", msg.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	var fields map[string]interface{}
+	common.Must(json.Unmarshal(append(append([]byte("{\"ts\":0"), msg.AppendJSON(nil)...), '}'), &fields))
+	if diff := cmp.Diff(map[string]interface{}{
+		"ts":         float64(0),
+		"level":      "warning",
+		"inboundTag": "vless-in",
+		"protocol":   "vless",
+		"source":     "1.2.3.4",
+		"reason":     "invalid request user id",
+		"message":    msg.String(),
+	}, fields); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAccessMessageRouteReason(t *testing.T) {
+	msg := &log.AccessMessage{
+		From:   "1.2.3.4:40000",
+		To:     "tcp:example.com:443",
+		Status: log.AccessAccepted,
+		Reason: &log.RouteReason{Rule: true, RuleTag: "direct", Balancer: "proxies", SniffedDomain: "example.com"},
+		Detour: "in -> out",
+	}
+	if diff := cmp.Diff("from 1.2.3.4:40000 accepted tcp:example.com:443 [in -> out] reason: rule:direct,balancer:proxies,sniffed:example.com", msg.String()); diff != "" {
+		t.Error(diff)
+	}
+
+	var fields map[string]interface{}
+	common.Must(json.Unmarshal(append(append([]byte("{\"ts\":0"), msg.AppendJSON(nil)...), '}'), &fields))
+	if fields["reason"] != "rule:direct,balancer:proxies,sniffed:example.com" {
+		t.Error("unexpected reason: ", fields["reason"])
+	}
+	for key, value := range map[string]string{"route": "rule", "ruleTag": "direct", "balancer": "proxies", "sniffedDomain": "example.com"} {
+		if fields[key] != value {
+			t.Error("unexpected ", key, ": ", fields[key])
+		}
+	}
+
+	if reason := (&log.RouteReason{Default: true, RuleTag: "ignored"}).String(); reason != "default" {
+		t.Error("unexpected reason: ", reason)
+	}
+}
+
+func TestRouteReasonTagNotReserved(t *testing.T) {
+	jsonFields := func(reason *log.RouteReason) map[string]interface{} {
+		msg := &log.AccessMessage{Status: log.AccessAccepted, Reason: reason}
+		var fields map[string]interface{}
+		common.Must(json.Unmarshal(append(append([]byte("{\"ts\":0"), msg.AppendJSON(nil)...), '}'), &fields))
+		delete(fields, "message")
+		return fields
+	}
+
+	// the rules tagged "default" or "rule" do not read as the default route or an untagged rule
+	for _, tc := range []struct {
+		reserved *log.RouteReason
+		tagged   *log.RouteReason
+	}{
+		{reserved: &log.RouteReason{Default: true}, tagged: &log.RouteReason{Rule: true, RuleTag: "default"}},
+		{reserved: &log.RouteReason{Rule: true}, tagged: &log.RouteReason{Rule: true, RuleTag: "rule"}},
+	} {
+		if tc.reserved.String() == tc.tagged.String() {
+			t.Error("expected ", tc.tagged.RuleTag, " to differ from the reason ", tc.reserved.String())
+		}
+		if diff := cmp.Diff(jsonFields(tc.reserved), jsonFields(tc.tagged)); diff == "" {
+			t.Error("expected the JSON fields of ", tc.tagged.RuleTag, " to differ from the reason ", tc.reserved.String())
+		}
+	}
+	if fields := jsonFields(&log.RouteReason{Rule: true, RuleTag: "default"}); fields["route"] != "rule" || fields["ruleTag"] != "default" {
+		t.Error("unexpected fields of a rule tagged default: ", fields)
+	}
+}
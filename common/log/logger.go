@@ -17,6 +17,13 @@ type Writer interface {
 	io.Closer
 }
 
+// MessageWriter is a Writer that gets the message along with its formatted line, like the syslog and journald
+// writers, which keep the severity and fields of the message apart from the text.
+type MessageWriter interface {
+	Writer
+	WriteMessage(msg Message, line string) error
+}
+
 // WriterCreator is a function to create LogWriters.
 type WriterCreator func() Writer
 
@@ -25,6 +32,7 @@ type generalLogger struct {
 	buffer  chan Message
 	access  *semaphore.Instance
 	done    *done.Instance
+	json    bool
 }
 
 type serverityLogger struct {
@@ -42,6 +50,18 @@ func NewLogger(logWriterCreator WriterCreator) Handler {
 	}
 }
 
+// NewJSONLogger returns a log handler like NewLogger, but writes each message as a JSON object on its own line.
+// It should be used with the writers that don't add a timestamp, like the one from CreateStdoutJSONLogWriter.
+func NewJSONLogger(logWriterCreator WriterCreator) Handler {
+	return &generalLogger{
+		creator: logWriterCreator,
+		buffer:  make(chan Message, 16),
+		access:  semaphore.New(1),
+		done:    done.New(),
+		json:    true,
+	}
+}
+
 func ReplaceWithSeverityLogger(serverity Severity) {
 	w := CreateStdoutLogWriter()
 	g := &generalLogger{
@@ -81,12 +101,24 @@ func (l *generalLogger) run() {
 	}
 	defer logger.Close()
 
+	var line []byte
 	for {
 		select {
 		case <-l.done.Wait():
 			return
 		case msg := <-l.buffer:
-			logger.Write(msg.String() + platform.LineSeparator())
+			var s string
+			if l.json {
+				line = appendJSONLine(line[:0], msg, time.Now())
+				s = string(line)
+			} else {
+				s = msg.String()
+			}
+			if mw, ok := logger.(MessageWriter); ok {
+				mw.WriteMessage(msg, s)
+			} else {
+				logger.Write(s + platform.LineSeparator())
+			}
 			dataWritten = true
 		case <-ticker.C:
 			if !dataWritten {
@@ -160,8 +192,26 @@ func CreateStderrLogWriter() WriterCreator {
 	}
 }
 
+// CreateStdoutJSONLogWriter returns a LogWriterCreator that creates LogWriter for stdout, without the timestamp prefix.
+func CreateStdoutJSONLogWriter() WriterCreator {
+	return func() Writer {
+		return &consoleLogWriter{
+			logger: log.New(os.Stdout, "", 0),
+		}
+	}
+}
+
 // CreateFileLogWriter returns a LogWriterCreator that creates LogWriter for the given file.
 func CreateFileLogWriter(path string) (WriterCreator, error) {
+	return createFileLogWriter(path, log.Ldate|log.Ltime|log.Lmicroseconds)
+}
+
+// CreateFileJSONLogWriter returns a LogWriterCreator that creates LogWriter for the given file, without the timestamp prefix.
+func CreateFileJSONLogWriter(path string) (WriterCreator, error) {
+	return createFileLogWriter(path, 0)
+}
+
+func createFileLogWriter(path string, flags int) (WriterCreator, error) {
 	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
 	if err != nil {
 		return nil, err
@@ -174,7 +224,7 @@ func CreateFileLogWriter(path string) (WriterCreator, error) {
 		}
 		return &fileLogWriter{
 			file:   file,
-			logger: log.New(file, "", log.Ldate|log.Ltime|log.Lmicroseconds),
+			logger: log.New(file, "", flags),
 		}
 	}, nil
 }
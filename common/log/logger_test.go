@@ -1,6 +1,7 @@
 package log_test
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -37,3 +38,70 @@ func TestFileLogger(t *testing.T) {
 		t.Fatal("Expect log text contains 'Test Log', but actually: ", string(b))
 	}
 }
+
+func TestFileJSONLogger(t *testing.T) {
+	f, err := os.CreateTemp("", "vtest")
+	common.Must(err)
+	path := f.Name()
+	common.Must(f.Close())
+	defer os.Remove(path)
+
+	creator, err := CreateFileJSONLogWriter(path)
+	common.Must(err)
+
+	handler := NewJSONLogger(creator)
+	handler.Handle(&AccessMessage{
+		From:        "tcp:127.0.0.1:1234",
+		To:          "tcp:example.com:443",
+		Status:      AccessAccepted,
+		Email:       "user@example.com",
+		Detour:      "in -> out",
+		InboundTag:  "in",
+		OutboundTag: "out",
+		SessionID:   42,
+		JA4:         "t13d1516h2_8daaf6152771_e5627efa2ab1",
+	})
+	handler.Handle(&GeneralMessage{Severity: Severity_Warning, Content: "Test \"Log\"\n"})
+	time.Sleep(2 * time.Second)
+
+	common.Must(common.Close(handler))
+
+	b, err := os.ReadFile(path)
+	common.Must(err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected 2 lines, but got: ", string(b))
+	}
+
+	var access map[string]interface{}
+	common.Must(json.Unmarshal([]byte(lines[0]), &access))
+	for k, v := range map[string]string{
+		"level":       "info",
+		"inboundTag":  "in",
+		"outboundTag": "out",
+		"user":        "user@example.com",
+		"source":      "tcp:127.0.0.1:1234",
+		"destination": "tcp:example.com:443",
+		"detour":      "in -> out",
+		"ja4":         "t13d1516h2_8daaf6152771_e5627efa2ab1",
+	} {
+		if access[k] != v {
+			t.Error("unexpected ", k, ": ", access[k], ", wanted ", v)
+		}
+	}
+	if _, found := access["ja3"]; found {
+		t.Error("unexpected ja3 without the fingerprint")
+	}
+	if access["sessionId"] != float64(42) {
+		t.Error("unexpected sessionId: ", access["sessionId"])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, access["ts"].(string)); err != nil {
+		t.Error("invalid ts: ", access["ts"])
+	}
+
+	var general map[string]string
+	common.Must(json.Unmarshal([]byte(lines[1]), &general))
+	if general["level"] != "warning" || general["message"] != "Test \"Log\"\n" {
+		t.Error("unexpected error log: ", lines[1])
+	}
+}
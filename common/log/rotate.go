@@ -0,0 +1,261 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation is the settings for rotating a log file.
+type Rotation struct {
+	// MaxSize is the size in bytes at which the file is rotated. Zero means no limit.
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep. Zero means all of them.
+	MaxBackups int
+	// MaxAge is how long the rotated files are kept. Zero means forever.
+	MaxAge time.Duration
+	// Compress gzips the rotated files.
+	Compress bool
+}
+
+const backupTimeFormat = "2006-01-02T15-04-05.000000000"
+
+// rotatingFile is a log file shared by all the writers of the same path, so that the access and error logs
+// going to the same file don't write to the old one after the other has rotated it.
+type rotatingFile struct {
+	sync.Mutex
+	path     string
+	rotation Rotation
+	file     *os.File
+	size     int64
+	refs     int
+
+	cleanup sync.Mutex
+}
+
+var rotatingFiles = struct {
+	sync.Mutex
+	m map[string]*rotatingFile
+}{m: make(map[string]*rotatingFile)}
+
+func openRotatingFile(path string, rotation Rotation) (*rotatingFile, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rotatingFiles.Lock()
+	defer rotatingFiles.Unlock()
+
+	if f, found := rotatingFiles.m[path]; found {
+		f.refs++
+		return f, nil
+	}
+	f := &rotatingFile{
+		path:     path,
+		rotation: rotation,
+		refs:     1,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	rotatingFiles.m[path] = f
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// release closes the file once all its writers are closed.
+func (f *rotatingFile) release() error {
+	rotatingFiles.Lock()
+	defer rotatingFiles.Unlock()
+
+	f.refs--
+	if f.refs > 0 {
+		return nil
+	}
+	delete(rotatingFiles.m, f.path)
+
+	f.Lock()
+	defer f.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// Write implements io.Writer. The file is rotated before the write that would take it over the size limit.
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.rotation.MaxSize > 0 && f.size > 0 && f.size+int64(len(b)) > f.rotation.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file to a backup named after the time, and reopens the path.
+// Compressing and pruning the backups happen in the background.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	ext := filepath.Ext(f.path)
+	var backup string
+	for t := time.Now(); ; t = t.Add(time.Nanosecond) {
+		backup = strings.TrimSuffix(f.path, ext) + "-" + t.Format(backupTimeFormat) + ext
+		if !fileExists(backup) && !fileExists(backup+".gz") {
+			break
+		}
+	}
+	if err := os.Rename(f.path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	go f.cleanupBackups(backup)
+	return nil
+}
+
+func (f *rotatingFile) cleanupBackups(backup string) {
+	f.cleanup.Lock()
+	defer f.cleanup.Unlock()
+
+	if f.rotation.Compress {
+		if err := compressFile(backup); err == nil {
+			os.Remove(backup)
+		}
+	}
+
+	if f.rotation.MaxBackups <= 0 && f.rotation.MaxAge <= 0 {
+		return
+	}
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(f.path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return
+	}
+	var backups []string
+	for _, name := range matches {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	// The names sort by the time of rotation, newest first.
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	now := time.Now()
+	for i, name := range backups {
+		expired := f.rotation.MaxBackups > 0 && i >= f.rotation.MaxBackups
+		if !expired && f.rotation.MaxAge > 0 {
+			if info, err := os.Stat(name); err == nil && now.Sub(info.ModTime()) > f.rotation.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			os.Remove(name)
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(dst)
+	if _, err := io.Copy(w, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := w.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	return dst.Close()
+}
+
+type rotatingLogWriter struct {
+	file   *rotatingFile
+	logger *log.Logger
+}
+
+func (w *rotatingLogWriter) Write(s string) error {
+	w.logger.Print(s)
+	return nil
+}
+
+func (w *rotatingLogWriter) Close() error {
+	return w.file.release()
+}
+
+// CreateRotatingFileLogWriter returns a LogWriterCreator that creates LogWriter for the given file, rotating it
+// by the given settings. The timestamp prefix is left out if json is set, as the JSON lines carry their own.
+func CreateRotatingFileLogWriter(path string, rotation Rotation, json bool) (WriterCreator, error) {
+	flags := log.Ldate | log.Ltime | log.Lmicroseconds
+	if json {
+		flags = 0
+	}
+	file, err := openRotatingFile(path, rotation)
+	if err != nil {
+		return nil, err
+	}
+	file.release()
+	return func() Writer {
+		file, err := openRotatingFile(path, rotation)
+		if err != nil {
+			return nil
+		}
+		return &rotatingLogWriter{
+			file:   file,
+			logger: log.New(file, "", flags),
+		}
+	}, nil
+}
@@ -0,0 +1,61 @@
+package log_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/log"
+)
+
+func TestRotatingFileLogger(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "xray.log")
+
+	creator, err := CreateRotatingFileLogWriter(path, Rotation{
+		MaxSize:    200,
+		MaxBackups: 2,
+		Compress:   true,
+	}, true)
+	common.Must(err)
+
+	// The access and error loggers writing to the same file.
+	access := creator()
+	errorLog := creator()
+	line := strings.Repeat("a", 49) + "\n"
+	for i := 0; i < 20; i++ {
+		common.Must(access.Write(line))
+		common.Must(errorLog.Write(line))
+	}
+
+	var backups []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		backups, err = filepath.Glob(filepath.Join(dir, "xray-*.log*"))
+		common.Must(err)
+		if len(backups) == 2 && strings.HasSuffix(backups[0], ".gz") && strings.HasSuffix(backups[1], ".gz") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(backups) != 2 {
+		t.Fatal("expected 2 backups, but got ", backups)
+	}
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".gz") {
+			t.Error("backup not compressed: ", backup)
+		}
+	}
+
+	common.Must(access.Close())
+	common.Must(errorLog.Close())
+
+	b, err := os.ReadFile(path)
+	common.Must(err)
+	if len(b) == 0 || len(b) > 200 || len(b)%len(line) != 0 {
+		t.Error("unexpected size of the current file: ", len(b))
+	}
+}
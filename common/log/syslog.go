@@ -0,0 +1,76 @@
+package log
+
+import (
+	"strconv"
+)
+
+// Syslog priorities of the log messages.
+const (
+	priorityError   = 3
+	priorityWarning = 4
+	priorityNotice  = 5
+	priorityInfo    = 6
+	priorityDebug   = 7
+)
+
+// wrappedMessage is implemented by the messages wrapping another one, like the masked messages of app/log.
+type wrappedMessage interface {
+	Unwrap() Message
+}
+
+func unwrapMessage(msg Message) Message {
+	for {
+		w, ok := msg.(wrappedMessage)
+		if !ok {
+			return msg
+		}
+		msg = w.Unwrap()
+	}
+}
+
+// messagePriority maps the severity of the message onto a syslog priority. Access and DNS logs are at info.
+func messagePriority(msg Message) int {
+	m, ok := unwrapMessage(msg).(*GeneralMessage)
+	if !ok {
+		return priorityInfo
+	}
+	switch m.Severity {
+	case Severity_Error:
+		return priorityError
+	case Severity_Warning:
+		return priorityWarning
+	case Severity_Info:
+		return priorityInfo
+	case Severity_Debug:
+		return priorityDebug
+	default:
+		return priorityNotice
+	}
+}
+
+// messageFields returns the fields of the message to be attached as metadata.
+// Addresses are left out, as they may be masked in the text of the message.
+func messageFields(msg Message) [][2]string {
+	var fields [][2]string
+	switch m := unwrapMessage(msg).(type) {
+	case *GeneralMessage:
+		if m.SessionID > 0 {
+			fields = append(fields, [2]string{"SESSION_ID", strconv.FormatUint(uint64(m.SessionID), 10)})
+		}
+	case *AccessMessage:
+		if m.SessionID > 0 {
+			fields = append(fields, [2]string{"SESSION_ID", strconv.FormatUint(uint64(m.SessionID), 10)})
+		}
+		fields = append(fields, [2]string{"STATUS", string(m.Status)})
+		if m.InboundTag != "" {
+			fields = append(fields, [2]string{"INBOUND_TAG", m.InboundTag})
+		}
+		if m.OutboundTag != "" {
+			fields = append(fields, [2]string{"OUTBOUND_TAG", m.OutboundTag})
+		}
+		if m.Email != "" {
+			fields = append(fields, [2]string{"USER", m.Email})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package log
+
+import (
+	"errors"
+)
+
+// CreateSyslogLogWriter returns a LogWriterCreator that creates LogWriter for syslog.
+func CreateSyslogLogWriter(network, address, tag string) (WriterCreator, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+
+package log_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/log"
+)
+
+func TestSyslogLogger(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	common.Must(err)
+	defer conn.Close()
+
+	creator, err := CreateSyslogLogWriter("udp", conn.LocalAddr().String(), "xray")
+	common.Must(err)
+
+	handler := NewLogger(creator)
+	defer common.Close(handler)
+	handler.Handle(&GeneralMessage{Severity: Severity_Warning, Content: "Test Log"})
+
+	common.Must(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	b := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(b)
+	common.Must(err)
+
+	// LOG_DAEMON | LOG_WARNING
+	if line := string(b[:n]); !strings.HasPrefix(line, "<28>") || !strings.Contains(line, "xray") || !strings.Contains(line, "[Warning] Test Log") {
+		t.Error("unexpected syslog entry: ", line)
+	}
+}
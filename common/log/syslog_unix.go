@@ -0,0 +1,51 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"log/syslog"
+)
+
+type syslogWriter struct {
+	writer *syslog.Writer
+}
+
+func (w *syslogWriter) Write(s string) error {
+	return w.writer.Info(s)
+}
+
+func (w *syslogWriter) WriteMessage(msg Message, line string) error {
+	switch messagePriority(msg) {
+	case priorityError:
+		return w.writer.Err(line)
+	case priorityWarning:
+		return w.writer.Warning(line)
+	case priorityNotice:
+		return w.writer.Notice(line)
+	case priorityDebug:
+		return w.writer.Debug(line)
+	default:
+		return w.writer.Info(line)
+	}
+}
+
+func (w *syslogWriter) Close() error {
+	return w.writer.Close()
+}
+
+// CreateSyslogLogWriter returns a LogWriterCreator that creates LogWriter for syslog.
+// The local syslog daemon is used if network and address are empty.
+func CreateSyslogLogWriter(network, address, tag string) (WriterCreator, error) {
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	writer.Close()
+	return func() Writer {
+		writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+		if err != nil {
+			return nil
+		}
+		return &syslogWriter{writer: writer}
+	}, nil
+}
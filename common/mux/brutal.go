@@ -0,0 +1,109 @@
+package mux
+
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"golang.org/x/time/rate"
+)
+
+// Brutal is the fixed-rate congestion control: frames are paced to a configured rate,
+// whatever the loss on the link. The client advertises its rates when the connection starts,
+// so that the server paces the downlink to the rate of the client.
+type Brutal struct {
+	// Uplink rate of the client, in bytes per second. 0 for unpaced.
+	Up uint64
+	// Downlink rate of the client, in bytes per second. 0 for unpaced.
+	Down uint64
+}
+
+// Enabled returns true if any direction is paced.
+func (b Brutal) Enabled() bool {
+	return b.Up > 0 || b.Down > 0
+}
+
+// writeBrutalFrame writes a KeepAlive frame with the rates.
+func writeBrutalFrame(writer buf.Writer, b Brutal) error {
+	meta := FrameMetadata{
+		SessionStatus: SessionStatusKeepAlive,
+	}
+	meta.Option.Set(OptionData)
+	meta.Option.Set(OptionBrutal)
+
+	data := buf.New()
+	binary.BigEndian.PutUint64(data.Extend(8), b.Up)
+	binary.BigEndian.PutUint64(data.Extend(8), b.Down)
+	return writeMetaWithFrame(writer, meta, buf.MultiBuffer{data})
+}
+
+// readBrutalFrame reads the rates from the data of a KeepAlive frame.
+func readBrutalFrame(reader *buf.BufferedReader) (Brutal, error) {
+	var b Brutal
+	data, err := buf.ReadAllToBytes(&buf.BufferedReader{Reader: NewStreamReader(reader)})
+	if err != nil {
+		return b, err
+	}
+	if len(data) >= 16 {
+		b.Up = binary.BigEndian.Uint64(data[:8])
+		b.Down = binary.BigEndian.Uint64(data[8:16])
+	}
+	return b, nil
+}
+
+// PacedWriter delays the frames written to it so that they go out at a fixed rate.
+// Writers wait before the frames are handed to the underlying writer, so that
+// nothing queues up beyond the size limit of the pipes of the sessions.
+type PacedWriter struct {
+	writer  buf.Writer
+	limiter atomic.Pointer[rate.Limiter]
+}
+
+// NewPacedWriter creates a PacedWriter that is not paced until SetRate is called.
+func NewPacedWriter(writer buf.Writer) *PacedWriter {
+	return &PacedWriter{writer: writer}
+}
+
+// SetRate sets the rate in bytes per second, 0 for unpaced.
+func (w *PacedWriter) SetRate(bytesPerSec uint64) {
+	if bytesPerSec == 0 {
+		w.limiter.Store(nil)
+		return
+	}
+	// a burst of 50ms, but at least a full frame
+	burst := int(min(max(bytesPerSec/20, 16*1024), uint64(1<<30)))
+	w.limiter.Store(rate.NewLimiter(rate.Limit(bytesPerSec), burst))
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *PacedWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if limiter := w.limiter.Load(); limiter != nil {
+		burst := limiter.Burst()
+		for n := int(mb.Len()); n > 0; {
+			k := min(n, burst)
+			if err := limiter.WaitN(context.Background(), k); err != nil {
+				buf.ReleaseMulti(mb)
+				return err
+			}
+			n -= k
+		}
+	}
+	return w.writer.WriteMultiBuffer(mb)
+}
+
+// Close implements common.Closable.
+func (w *PacedWriter) Close() error {
+	return common.Close(w.writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *PacedWriter) Interrupt() {
+	common.Interrupt(w.writer)
+}
+
+// MbpsToBytes converts a rate in Mbps to bytes per second.
+func MbpsToBytes(mbps uint64) uint64 {
+	return mbps * 1000 * 1000 / 8
+}
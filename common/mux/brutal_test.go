@@ -0,0 +1,100 @@
+package mux_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/mux"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport"
+)
+
+func TestPacedWriter(t *testing.T) {
+	reader, writer := newLinkPair()
+	paced := mux.NewPacedWriter(writer.Writer)
+	paced.SetRate(100 * 1024)
+
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		b := buf.New()
+		b.Extend(buf.Size)
+		common.Must(paced.WriteMultiBuffer(buf.MultiBuffer{b}))
+	}
+	// 16K of burst, then 48K at 100K/s
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Error("not paced: ", elapsed)
+	}
+
+	paced.SetRate(0)
+	start = time.Now()
+	for i := 0; i < 64; i++ {
+		b := buf.New()
+		b.Extend(buf.Size)
+		common.Must(paced.WriteMultiBuffer(buf.MultiBuffer{b}))
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Error("still paced: ", elapsed)
+	}
+	common.Close(paced)
+	for {
+		mb, err := reader.Reader.ReadMultiBuffer()
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			break
+		}
+	}
+}
+
+func TestBrutalDownlink(t *testing.T) {
+	websiteUplink, websiteDownlink := newLinkPair()
+	dispatcher := TestDispatcher{
+		OnDispatch: func(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+			return websiteDownlink, nil
+		},
+	}
+
+	muxServerUplink, muxServerDownlink := newLinkPair()
+	_, err := mux.NewServerWorker(context.Background(), &dispatcher, muxServerUplink)
+	common.Must(err)
+
+	client, err := mux.NewClientWorker(*muxServerDownlink, mux.ClientStrategy{
+		Brutal: mux.Brutal{Down: 200 * 1024},
+	})
+	common.Must(err)
+
+	clientCtx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80),
+	}})
+	muxClientUplink, muxClientDownlink := newLinkPair()
+	if !client.Dispatch(clientCtx, muxClientUplink) {
+		t.Fatal("failed to dispatch")
+	}
+	common.Must(muxClientDownlink.Writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("hello"))}))
+	mb, err := websiteUplink.Reader.ReadMultiBuffer()
+	common.Must(err)
+	buf.ReleaseMulti(mb)
+
+	const size = 200 * 1024
+	start := time.Now()
+	go func() {
+		for i := 0; i < size/buf.Size; i++ {
+			b := buf.New()
+			b.Extend(buf.Size)
+			common.Must(websiteUplink.Writer.WriteMultiBuffer(buf.MultiBuffer{b}))
+		}
+	}()
+	for received := int32(0); received < size; {
+		mb, err := muxClientDownlink.Reader.ReadMultiBuffer()
+		common.Must(err)
+		received += mb.Len()
+		buf.ReleaseMulti(mb)
+	}
+	// the rate is exchanged before the first session, so the whole download is paced
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Error("downlink not paced: ", elapsed)
+	}
+}
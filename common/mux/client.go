@@ -41,6 +41,22 @@ func (m *ClientManager) Dispatch(ctx context.Context, link *transport.Link) erro
 	return errors.New("unable to find an available mux client").AtWarning()
 }
 
+// Streams returns the number of sessions of the workers, if the picker keeps track of them.
+func (m *ClientManager) Streams() int {
+	if p, ok := m.Picker.(interface{ Streams() int }); ok {
+		return p.Streams()
+	}
+	return 0
+}
+
+// CollectIdle implements stats.IdleCollector, if the picker does.
+func (m *ClientManager) CollectIdle() int {
+	if p, ok := m.Picker.(interface{ CollectIdle() int }); ok {
+		return p.CollectIdle()
+	}
+	return 0
+}
+
 type WorkerPicker interface {
 	PickAvailable() (*ClientWorker, error)
 }
@@ -125,6 +141,36 @@ func (p *IncrementalWorkerPicker) PickAvailable() (*ClientWorker, error) {
 	return worker, err
 }
 
+// Streams returns the number of sessions of the workers.
+func (p *IncrementalWorkerPicker) Streams() int {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	streams := 0
+	for _, w := range p.workers {
+		if !w.Closed() {
+			streams += int(w.ActiveConnections())
+		}
+	}
+	return streams
+}
+
+// CollectIdle closes the workers without sessions, instead of waiting for them to stay idle for a while,
+// and returns how many of them it closed.
+func (p *IncrementalWorkerPicker) CollectIdle() int {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	collected := 0
+	for _, w := range p.workers {
+		if !w.Closed() && w.closeIfNoSession() {
+			collected++
+		}
+	}
+	p.cleanup()
+	return collected
+}
+
 type ClientWorkerFactory interface {
 	Create() (*ClientWorker, error)
 }
@@ -171,6 +217,7 @@ func (f *DialingWorkerFactory) Create() (*ClientWorker, error) {
 type ClientStrategy struct {
 	MaxConcurrency uint32
 	MaxConnection  uint32
+	Brutal         Brutal
 }
 
 type ClientWorker struct {
@@ -196,6 +243,16 @@ func NewClientWorker(stream transport.Link, s ClientStrategy) (*ClientWorker, er
 		strategy:       s,
 	}
 
+	if s.Brutal.Enabled() {
+		// the rates go first, so that the server paces the downlink of all sessions
+		if err := writeBrutalFrame(stream.Writer, s.Brutal); err != nil {
+			return nil, err
+		}
+		paced := NewPacedWriter(stream.Writer)
+		paced.SetRate(s.Brutal.Up)
+		c.link.Writer = paced
+	}
+
 	go c.fetchOutput()
 	go c.monitor()
 
@@ -223,6 +280,15 @@ func (m *ClientWorker) Close() error {
 	return m.done.Close()
 }
 
+// closeIfNoSession closes the worker if it has no session, without racing with the sessions being allocated.
+func (m *ClientWorker) closeIfNoSession() bool {
+	if m.sessionManager.CloseIfNoSessionAndIdle(0, m.sessionManager.Count()) {
+		common.Must(m.done.Close())
+		return true
+	}
+	return false
+}
+
 func (m *ClientWorker) monitor() {
 	defer m.timer.Stop()
 
@@ -269,8 +335,11 @@ func fetchInput(ctx context.Context, s *Session, output buf.Writer) {
 		inbound = session.InboundFromContext(ctx)
 	}
 	writer := NewWriter(s.ID, ob.Target, output, transferType, xudp.GetGlobalID(ctx), inbound)
-	defer s.Close(false)
-	defer writer.Close()
+	if inbound != nil {
+		// the sniffed results go along with the inbound, for the routing of the other side
+		writer.content = session.ContentFromContext(ctx)
+	}
+	defer s.endInput(writer)
 
 	errors.LogInfo(ctx, "dispatching request to ", ob.Target)
 	if err := writeFirstPayload(s.input, writer); err != nil {
@@ -279,7 +348,7 @@ func fetchInput(ctx context.Context, s *Session, output buf.Writer) {
 		return
 	}
 
-	if err := buf.Copy(s.input, writer); err != nil {
+	if err := buf.Copy(s.input, writer, buf.UpdateActivity(s)); err != nil {
 		errors.LogInfoInner(ctx, err, "failed to fetch all input")
 		writer.hasError = true
 		return
@@ -320,6 +389,7 @@ func (m *ClientWorker) Dispatch(ctx context.Context, link *transport.Link) bool
 	}
 	s.input = link.Reader
 	s.output = link.Writer
+	s.setDrainTimeouts(ctx, false)
 	go fetchInput(ctx, s, m.link.Writer)
 	if _, ok := link.Reader.(*pipe.Reader); !ok {
 		select {
@@ -331,6 +401,14 @@ func (m *ClientWorker) Dispatch(ctx context.Context, link *transport.Link) bool
 }
 
 func (m *ClientWorker) handleStatueKeepAlive(meta *FrameMetadata, reader *buf.BufferedReader) error {
+	if meta.Option.Has(OptionData) && meta.Option.Has(OptionBrutal) {
+		b, err := readBrutalFrame(reader)
+		if err != nil {
+			return err
+		}
+		errors.LogInfo(context.Background(), "mux server paces downlink at ", b.Down, " bytes/s")
+		return nil
+	}
 	if meta.Option.Has(OptionData) {
 		return buf.Copy(NewStreamReader(reader), buf.Discard)
 	}
@@ -359,7 +437,7 @@ func (m *ClientWorker) handleStatusKeep(meta *FrameMetadata, reader *buf.Buffere
 	}
 
 	rr := s.NewReader(reader, &meta.Target)
-	err := buf.Copy(rr, s.output)
+	err := buf.Copy(rr, s.output, buf.UpdateActivity(s))
 	if err != nil && buf.IsWriteError(err) {
 		errors.LogInfoInner(context.Background(), err, "failed to write to downstream. closing session ", s.ID)
 		s.Close(false)
@@ -371,7 +449,11 @@ func (m *ClientWorker) handleStatusKeep(meta *FrameMetadata, reader *buf.Buffere
 
 func (m *ClientWorker) handleStatusEnd(meta *FrameMetadata, reader *buf.BufferedReader) error {
 	if s, found := m.sessionManager.Get(meta.SessionID); found {
-		s.Close(false)
+		if meta.Option.Has(OptionHalfClose) {
+			s.endOutput()
+		} else {
+			s.Close(false)
+		}
 	}
 	if meta.Option.Has(OptionData) {
 		return buf.Copy(NewStreamReader(reader), buf.Discard)
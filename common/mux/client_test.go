@@ -2,11 +2,13 @@ package mux_test
 
 import (
 	"context"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
@@ -114,3 +116,82 @@ func TestClientWorkerClose(t *testing.T) {
 
 	common.Must(w2.Close())
 }
+
+func TestClientManagerCollectIdle(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	r1, w1 := pipe.New(pipe.WithoutSizeLimit())
+	defer w1.Close()
+	worker, err := mux.NewClientWorker(transport.Link{
+		Reader: r1,
+		Writer: w1,
+	}, mux.ClientStrategy{})
+	common.Must(err)
+
+	factory := mocks.NewMuxClientWorkerFactory(mockCtl)
+	factory.EXPECT().Create().Return(worker, nil)
+	manager := &mux.ClientManager{
+		Picker: &mux.IncrementalWorkerPicker{
+			Factory: factory,
+		},
+	}
+
+	tr, tw := pipe.New(pipe.WithoutSizeLimit())
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80),
+	}})
+	common.Must(manager.Dispatch(ctx, &transport.Link{
+		Reader: tr,
+		Writer: tw,
+	}))
+
+	if n := manager.Streams(); n != 1 {
+		t.Error("expected 1 stream, but got ", n)
+	}
+	if n := manager.CollectIdle(); n != 0 || worker.Closed() {
+		t.Error("collected a worker with a session")
+	}
+
+	common.Must(tw.Close())
+	time.Sleep(time.Millisecond * 500)
+
+	if n := manager.Streams(); n != 0 {
+		t.Error("expected no stream, but got ", n)
+	}
+	if n := manager.CollectIdle(); n != 1 || !worker.Closed() {
+		t.Error("expected the idle worker to be collected")
+	}
+}
+
+func TestClientWorkerHalfCloseDrain(t *testing.T) {
+	serverReader, _ := pipe.New(pipe.WithoutSizeLimit())
+	_, clientWriter := pipe.New(pipe.WithoutSizeLimit())
+	worker, err := mux.NewClientWorker(transport.Link{Reader: serverReader, Writer: clientWriter}, mux.ClientStrategy{})
+	common.Must(err)
+
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80),
+	}})
+	if !worker.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}) {
+		t.Fatal("failed to dispatch")
+	}
+
+	// the uplink ends, and the downlink lasts for the downlinkOnly of the default policy without activity
+	b := buf.New()
+	common.Must2(b.WriteString("request"))
+	common.Must(uplinkWriter.WriteMultiBuffer(buf.MultiBuffer{b}))
+	common.Must(uplinkWriter.Close())
+	if _, err := downlinkReader.ReadMultiBufferTimeout(time.Millisecond * 500); err != buf.ErrReadTimeout {
+		t.Error("the downlink ended with the uplink: ", err)
+	}
+	start := time.Now()
+	if _, err := downlinkReader.ReadMultiBufferTimeout(time.Second * 5); err != io.EOF {
+		t.Error("the downlink did not end: ", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*3 {
+		t.Error("the downlink ended after ", elapsed)
+	}
+}
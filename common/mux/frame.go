@@ -26,6 +26,11 @@ const (
 const (
 	OptionData  bitmask.Byte = 0x01
 	OptionError bitmask.Byte = 0x02
+	// OptionBrutal marks a KeepAlive frame carrying the rates of the brutal congestion control.
+	OptionBrutal bitmask.Byte = 0x04
+	// OptionHalfClose marks an End frame of a stream that ends only the data from its sender, which still reads the data of the session.
+	// The peers that do not know it end the whole session, like before.
+	OptionHalfClose bitmask.Byte = 0x08
 )
 
 type TargetNetwork byte
@@ -62,6 +67,8 @@ type FrameMetadata struct {
 	SessionStatus SessionStatus
 	GlobalID      [8]byte
 	Inbound       *session.Inbound
+	// Content is sent after the source and local of Inbound, for its sniffed protocol and domain.
+	Content *session.Content
 }
 
 func (f FrameMetadata) WriteTo(b *buf.Buffer) error {
@@ -95,6 +102,7 @@ func (f FrameMetadata) WriteTo(b *buf.Buffer) error {
 					if err := addrParser.WriteAddressPort(b, f.Inbound.Local.Address, f.Inbound.Local.Port); err != nil {
 						return err
 					}
+					writeSniffed(b, f.Content, b.Len()-len0)
 				}
 			}
 		} else if b.UDP != nil { // make sure it's user's proxy request
@@ -209,6 +217,7 @@ func (f *FrameMetadata) UnmarshalFromBuffer(b *buf.Buffer, readSourceAndLocal bo
 			return errors.New("reading local: unknown network type: ", network)
 		}
 
+		f.Content = readSniffed(b)
 		return nil
 	}
 
@@ -220,3 +229,93 @@ func (f *FrameMetadata) UnmarshalFromBuffer(b *buf.Buffer, readSourceAndLocal bo
 
 	return nil
 }
+
+// writeSniffed writes the sniffed protocol and domain of the content, each with a byte of its length, then a byte of
+// the number of its attributes and the attributes, each name and value with a byte of its length. Only the attributes
+// that fit in the metadata of metaLen bytes so far are written, and nothing if the protocol and domain do not.
+// Older readers ignore them after the local address.
+func writeSniffed(b *buf.Buffer, content *session.Content, metaLen int32) {
+	if content == nil || !content.Sniffed || content.Protocol == "" {
+		return
+	}
+	size := metaLen + 3 + int32(len(content.Protocol)+len(content.Domain))
+	if len(content.Protocol) > 255 || len(content.Domain) > 255 || size > 512 {
+		return
+	}
+	common.Must(b.WriteByte(byte(len(content.Protocol))))
+	common.Must2(b.WriteString(content.Protocol))
+	common.Must(b.WriteByte(byte(len(content.Domain))))
+	common.Must2(b.WriteString(content.Domain))
+
+	names := make([]string, 0, len(content.Attributes))
+	for name, value := range content.Attributes {
+		attrSize := int32(2 + len(name) + len(value))
+		if len(name) > 255 || len(value) > 255 || size+attrSize > 512 || len(names) == 255 {
+			continue
+		}
+		size += attrSize
+		names = append(names, name)
+	}
+	common.Must(b.WriteByte(byte(len(names))))
+	for _, name := range names {
+		value := content.Attributes[name]
+		common.Must(b.WriteByte(byte(len(name))))
+		common.Must2(b.WriteString(name))
+		common.Must(b.WriteByte(byte(len(value))))
+		common.Must2(b.WriteString(value))
+	}
+}
+
+// readSniffed reads the content written by writeSniffed, or returns nil if there is none.
+func readSniffed(b *buf.Buffer) *session.Content {
+	if b.Len() == 0 {
+		return nil
+	}
+	protocolLen := int32(b.Byte(0))
+	if protocolLen == 0 || b.Len() < 2+protocolLen {
+		return nil
+	}
+	domainLen := int32(b.Byte(1 + protocolLen))
+	if b.Len() < 2+protocolLen+domainLen {
+		return nil
+	}
+	content := &session.Content{
+		Protocol: string(b.BytesRange(1, 1+protocolLen)),
+		Domain:   string(b.BytesRange(2+protocolLen, 2+protocolLen+domainLen)),
+		Sniffed:  true,
+	}
+	b.Advance(2 + protocolLen + domainLen)
+
+	// the attributes, which writers before them do not write
+	if b.Len() == 0 {
+		return content
+	}
+	count := int(b.Byte(0))
+	b.Advance(1)
+	for i := 0; i < count; i++ {
+		name, ok := readSniffedString(b)
+		if !ok {
+			break
+		}
+		value, ok := readSniffedString(b)
+		if !ok {
+			break
+		}
+		content.SetAttribute(name, value)
+	}
+	return content
+}
+
+// readSniffedString reads a string with a byte of its length.
+func readSniffedString(b *buf.Buffer) (string, bool) {
+	if b.Len() == 0 {
+		return "", false
+	}
+	n := int32(b.Byte(0))
+	if b.Len() < 1+n {
+		return "", false
+	}
+	s := string(b.BytesRange(1, 1+n))
+	b.Advance(1 + n)
+	return s, true
+}
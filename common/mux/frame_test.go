@@ -1,12 +1,14 @@
 package mux_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
 )
 
 func BenchmarkFrameWrite(b *testing.B) {
@@ -23,3 +25,45 @@ func BenchmarkFrameWrite(b *testing.B) {
 		writer.Clear()
 	}
 }
+
+func TestFrameSniffedContent(t *testing.T) {
+	frame := mux.FrameMetadata{
+		Target:        net.TCPDestination(net.DomainAddress("www.example.com"), net.Port(443)),
+		SessionID:     1,
+		SessionStatus: mux.SessionStatusNew,
+		Inbound: &session.Inbound{
+			Source: net.TCPDestination(net.ParseAddress("10.0.0.1"), 40000),
+			Local:  net.TCPDestination(net.ParseAddress("10.0.0.2"), 443),
+		},
+		Content: &session.Content{
+			Protocol:   "tls",
+			Domain:     "www.example.com",
+			Sniffed:    true,
+			Attributes: map[string]string{":method": "GET", "too-long": strings.Repeat("a", 256)},
+		},
+	}
+	b := buf.New()
+	defer b.Release()
+	common.Must(frame.WriteTo(b))
+
+	var meta mux.FrameMetadata
+	common.Must(meta.Unmarshal(b, true))
+	if meta.Inbound.Local.Port != 443 {
+		t.Error("unexpected local ", meta.Inbound.Local)
+	}
+	if meta.Content == nil || meta.Content.Protocol != "tls" || meta.Content.Domain != "www.example.com" {
+		t.Error("unexpected content ", meta.Content)
+	}
+	if meta.Content != nil && (len(meta.Content.Attributes) != 1 || meta.Content.Attribute(":method") != "GET") {
+		t.Error("unexpected attributes ", meta.Content.Attributes)
+	}
+
+	frame.Content = &session.Content{Protocol: "tls"}
+	b.Clear()
+	common.Must(frame.WriteTo(b))
+	meta = mux.FrameMetadata{}
+	common.Must(meta.Unmarshal(b, true))
+	if meta.Content != nil {
+		t.Error("expected no content not sniffed, but got ", meta.Content)
+	}
+}
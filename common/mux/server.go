@@ -3,6 +3,7 @@ package mux
 import (
 	"context"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -21,6 +22,9 @@ import (
 
 type Server struct {
 	dispatcher routing.Dispatcher
+
+	access  sync.Mutex
+	workers map[*ServerWorker]struct{}
 }
 
 // NewServer creates a new mux.Server.
@@ -47,13 +51,16 @@ func (s *Server) Dispatch(ctx context.Context, dest net.Destination) (*transport
 	uplinkReader, uplinkWriter := pipe.New(opts...)
 	downlinkReader, downlinkWriter := pipe.New(opts...)
 
-	_, err := NewServerWorker(ctx, s.dispatcher, &transport.Link{
+	ctx, link, done := s.trackLink(ctx, &transport.Link{
 		Reader: uplinkReader,
 		Writer: downlinkWriter,
-	})
+	}, dest)
+	worker, err := NewServerWorker(ctx, s.dispatcher, link)
 	if err != nil {
+		done()
 		return nil, err
 	}
+	s.track(worker, done)
 
 	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
 }
@@ -66,10 +73,13 @@ func (s *Server) DispatchLink(ctx context.Context, dest net.Destination, link *t
 	if d, ok := s.dispatcher.(routing.WrapLinkDispatcher); ok {
 		link = d.WrapLink(ctx, link)
 	}
+	ctx, link, done := s.trackLink(ctx, link, dest)
 	worker, err := NewServerWorker(ctx, s.dispatcher, link)
 	if err != nil {
+		done()
 		return err
 	}
+	s.track(worker, done)
 	select {
 	case <-ctx.Done():
 	case <-worker.done.Wait():
@@ -77,6 +87,45 @@ func (s *Server) DispatchLink(ctx context.Context, dest net.Destination, link *t
 	return nil
 }
 
+// trackLink lists the mux connection in the connection table of the dispatcher, if it keeps one.
+// The sessions of the connection are listed on their own when dispatched.
+func (s *Server) trackLink(ctx context.Context, link *transport.Link, dest net.Destination) (context.Context, *transport.Link, func()) {
+	if d, ok := s.dispatcher.(routing.TrackLinkDispatcher); ok {
+		return d.TrackLink(ctx, link, dest)
+	}
+	return ctx, link, func() {}
+}
+
+// track keeps the worker until it is closed, for counting its sessions, and then calls done.
+func (s *Server) track(worker *ServerWorker, done func()) {
+	s.access.Lock()
+	if s.workers == nil {
+		s.workers = make(map[*ServerWorker]struct{})
+	}
+	s.workers[worker] = struct{}{}
+	s.access.Unlock()
+
+	go func() {
+		<-worker.WaitClosed()
+		s.access.Lock()
+		delete(s.workers, worker)
+		s.access.Unlock()
+		done()
+	}()
+}
+
+// Streams returns the number of sessions of the mux connections being served.
+func (s *Server) Streams() int {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	streams := 0
+	for worker := range s.workers {
+		streams += int(worker.ActiveConnections())
+	}
+	return streams
+}
+
 // Start implements common.Runnable.
 func (s *Server) Start() error {
 	return nil
@@ -93,6 +142,8 @@ type ServerWorker struct {
 	sessionManager *SessionManager
 	done           *done.Instance
 	timer          *time.Ticker
+	// output is the writer of the sessions, paced if the client asks for brutal.
+	output *PacedWriter
 }
 
 func NewServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.Link) (*ServerWorker, error) {
@@ -102,6 +153,7 @@ func NewServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.
 		sessionManager: NewSessionManager(),
 		done:           done.New(),
 		timer:          time.NewTicker(60 * time.Second),
+		output:         NewPacedWriter(link.Writer),
 	}
 	if inbound := session.InboundFromContext(ctx); inbound != nil {
 		inbound.CanSpliceCopy = 3
@@ -113,13 +165,12 @@ func NewServerWorker(ctx context.Context, d routing.Dispatcher, link *transport.
 
 func handle(ctx context.Context, s *Session, output buf.Writer) {
 	writer := NewResponseWriter(s.ID, output, s.transferType)
-	if err := buf.Copy(s.input, writer); err != nil {
+	if err := buf.Copy(s.input, writer, buf.UpdateActivity(s)); err != nil {
 		errors.LogInfoInner(ctx, err, "session ", s.ID, " ends.")
 		writer.hasError = true
 	}
 
-	writer.Close()
-	s.Close(false)
+	s.endInput(writer)
 }
 
 func (w *ServerWorker) monitor() {
@@ -159,6 +210,16 @@ func (w *ServerWorker) Close() error {
 }
 
 func (w *ServerWorker) handleStatusKeepAlive(meta *FrameMetadata, reader *buf.BufferedReader) error {
+	if meta.Option.Has(OptionData) && meta.Option.Has(OptionBrutal) {
+		b, err := readBrutalFrame(reader)
+		if err != nil {
+			return err
+		}
+		errors.LogInfo(context.Background(), "pacing mux downlink at ", b.Down, " bytes/s")
+		w.output.SetRate(b.Down)
+		// tell the client the rates are taken
+		return writeBrutalFrame(w.link.Writer, b)
+	}
 	if meta.Option.Has(OptionData) {
 		return buf.Copy(NewStreamReader(reader), buf.Discard)
 	}
@@ -175,6 +236,15 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 			ctx = session.ContextWithInbound(ctx, &newInbound)
 		}
 	}
+	if meta.Content != nil {
+		content := session.ContentFromContext(ctx)
+		content.Protocol = meta.Content.Protocol
+		content.Domain = meta.Content.Domain
+		content.Sniffed = true
+		for name, value := range meta.Content.Attributes {
+			content.SetAttribute(name, value)
+		}
+	}
 	errors.LogInfo(ctx, "received request for ", meta.Target)
 	{
 		msg := &log.AccessMessage{
@@ -260,7 +330,7 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 			x.Mux.Close(false)
 			return errors.New("failed to add new session")
 		}
-		go handle(ctx, x.Mux, w.link.Writer)
+		go handle(ctx, x.Mux, w.output)
 		return nil
 	}
 
@@ -281,11 +351,12 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 	if meta.Target.Network == net.Network_UDP {
 		s.transferType = protocol.TransferTypePacket
 	}
+	s.setDrainTimeouts(ctx, true)
 	if !w.sessionManager.Add(s) {
 		s.Close(false)
 		return errors.New("failed to add new session")
 	}
-	go handle(ctx, s, w.link.Writer)
+	go handle(ctx, s, w.output)
 	if !meta.Option.Has(OptionData) {
 		return nil
 	}
@@ -308,14 +379,14 @@ func (w *ServerWorker) handleStatusKeep(meta *FrameMetadata, reader *buf.Buffere
 	s, found := w.sessionManager.Get(meta.SessionID)
 	if !found {
 		// Notify remote peer to close this session.
-		closingWriter := NewResponseWriter(meta.SessionID, w.link.Writer, protocol.TransferTypeStream)
+		closingWriter := NewResponseWriter(meta.SessionID, w.output, protocol.TransferTypeStream)
 		closingWriter.Close()
 
 		return buf.Copy(NewStreamReader(reader), buf.Discard)
 	}
 
 	rr := s.NewReader(reader, &meta.Target)
-	err := buf.Copy(rr, s.output)
+	err := buf.Copy(rr, s.output, buf.UpdateActivity(s))
 
 	if err != nil && buf.IsWriteError(err) {
 		errors.LogInfoInner(context.Background(), err, "failed to write to downstream writer. closing session ", s.ID)
@@ -328,7 +399,11 @@ func (w *ServerWorker) handleStatusKeep(meta *FrameMetadata, reader *buf.Buffere
 
 func (w *ServerWorker) handleStatusEnd(meta *FrameMetadata, reader *buf.BufferedReader) error {
 	if s, found := w.sessionManager.Get(meta.SessionID); found {
-		s.Close(false)
+		if meta.Option.Has(OptionHalfClose) {
+			s.endOutput()
+		} else {
+			s.Close(false)
+		}
 	}
 	if meta.Option.Has(OptionData) {
 		return buf.Copy(NewStreamReader(reader), buf.Discard)
@@ -5,6 +5,7 @@ import (
 	"io"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtls/xray-core/common"
@@ -12,7 +13,11 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
 	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/transport/pipe"
 )
 
@@ -161,8 +166,94 @@ type Session struct {
 	ID           uint16
 	transferType protocol.TransferType
 	closed       bool
-	done         *done.Instance
-	XUDP         *XUDP
+	// inputDone and outputDone are the directions of a stream that have ended by half closes.
+	inputDone  bool
+	outputDone bool
+	// inputOnly and outputOnly are how long the session lasts without activity once only its input or its output
+	// is left, the uplinkOnly and downlinkOnly of the policy of its user.
+	inputOnly  time.Duration
+	outputOnly time.Duration
+	drain      atomic.Pointer[signal.ActivityTimer]
+	done       *done.Instance
+	XUDP       *XUDP
+}
+
+// setDrainTimeouts sets the timeouts of the session after half closes from the policy of the user in ctx.
+// The input of a session of the client is the uplink, and that of the server the downlink.
+func (s *Session) setDrainTimeouts(ctx context.Context, server bool) {
+	p := policy.SessionDefault()
+	if v := core.FromContext(ctx); v != nil {
+		if m, ok := v.GetFeature(policy.ManagerType()).(policy.Manager); ok {
+			var level uint32
+			if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.User != nil {
+				level = inbound.User.Level
+			}
+			p = m.ForLevel(level)
+		}
+	}
+	s.inputOnly, s.outputOnly = p.Timeouts.UplinkOnly, p.Timeouts.DownlinkOnly
+	if server {
+		s.inputOnly, s.outputOnly = p.Timeouts.DownlinkOnly, p.Timeouts.UplinkOnly
+	}
+}
+
+// drainFor closes the session once the direction left goes without activity for timeout.
+// It must be called with the lock of the parent.
+func (s *Session) drainFor(timeout time.Duration) {
+	if timeout == 0 {
+		s.Close(true)
+		return
+	}
+	s.drain.Store(signal.CancelAfterInactivity(context.Background(), func() {
+		s.Close(false)
+	}, timeout))
+}
+
+// Update implements signal.ActivityUpdater, for the direction left after a half close.
+func (s *Session) Update() {
+	if timer := s.drain.Load(); timer != nil {
+		timer.Update()
+	}
+}
+
+// endInput sends the end of the input of the session through writer. A stream whose input ends without errors
+// is half closed, so the session goes on until its output ends too, or goes without activity for outputOnly.
+func (s *Session) endInput(writer *Writer) {
+	if writer.hasError || s.transferType != protocol.TransferTypeStream || s.XUDP != nil {
+		writer.Close()
+		s.Close(false)
+		return
+	}
+	writer.halfClose = true
+	writer.Close()
+
+	s.parent.Lock()
+	defer s.parent.Unlock()
+	s.inputDone = true
+	if s.outputDone {
+		s.Close(true)
+		return
+	}
+	if !s.closed {
+		s.drainFor(s.outputOnly)
+	}
+}
+
+// endOutput ends the output of the session on a half close from the peer, after which its input lasts
+// without activity for inputOnly.
+func (s *Session) endOutput() {
+	s.parent.Lock()
+	defer s.parent.Unlock()
+	if s.closed {
+		return
+	}
+	s.outputDone = true
+	if s.inputDone {
+		s.Close(true)
+		return
+	}
+	common.Close(s.output)
+	s.drainFor(s.inputOnly)
 }
 
 // Close closes all resources associated with this session.
@@ -15,9 +15,11 @@ type Writer struct {
 	id           uint16
 	followup     bool
 	hasError     bool
+	halfClose    bool
 	transferType protocol.TransferType
 	globalID     [8]byte
 	inbound      *session.Inbound
+	content      *session.Content
 }
 
 func NewWriter(id uint16, dest net.Destination, writer buf.Writer, transferType protocol.TransferType, globalID [8]byte, inbound *session.Inbound) *Writer {
@@ -47,6 +49,7 @@ func (w *Writer) getNextFrameMeta() FrameMetadata {
 		Target:    w.dest,
 		GlobalID:  w.globalID,
 		Inbound:   w.inbound,
+		Content:   w.content,
 	}
 
 	if w.followup {
@@ -68,6 +71,19 @@ func (w *Writer) writeMetaOnly() error {
 	return w.writer.WriteMultiBuffer(buf.MultiBuffer{b})
 }
 
+// WriteKeepAlive writes a KeepAlive frame without data, which the other side reads and discards.
+func WriteKeepAlive(writer buf.Writer) error {
+	meta := FrameMetadata{
+		SessionStatus: SessionStatusKeepAlive,
+	}
+	b := buf.New()
+	if err := meta.WriteTo(b); err != nil {
+		b.Release()
+		return err
+	}
+	return writer.WriteMultiBuffer(buf.MultiBuffer{b})
+}
+
 func writeMetaWithFrame(writer buf.Writer, meta FrameMetadata, data buf.MultiBuffer) error {
 	frame := buf.New()
 	if len(data) == 1 {
@@ -126,6 +142,8 @@ func (w *Writer) Close() error {
 	}
 	if w.hasError {
 		meta.Option.Set(OptionError)
+	} else if w.halfClose {
+		meta.Option.Set(OptionHalfClose)
 	}
 
 	frame := buf.New()
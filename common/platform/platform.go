@@ -24,6 +24,10 @@ const (
 	BrowserDialerAddress = "xray.browser.dialer"
 	XUDPLog              = "xray.xudp.show"
 	XUDPBaseKey          = "xray.xudp.basekey"
+	UDPBatchSize         = "xray.udp.batch"
+	TunFd                = "xray.tun.fd"
+
+	FeatureStartTimeout = "xray.feature.start.timeout"
 )
 
 type EnvFlag struct {
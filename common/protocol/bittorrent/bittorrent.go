@@ -1,13 +1,12 @@
 package bittorrent
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
-	"math"
-	"time"
+	"strings"
 
 	"github.com/xtls/xray-core/common"
-	"github.com/xtls/xray-core/common/buf"
 )
 
 type SniffHeader struct{}
@@ -34,57 +33,121 @@ func SniffBittorrent(b []byte) (*SniffHeader, error) {
 	return nil, errNotBittorrent
 }
 
-func SniffUTP(b []byte) (*SniffHeader, error) {
-	if len(b) < 20 {
-		return nil, common.ErrNoClue
+// SniffDHT sniffs the bencoded queries and responses of the mainline DHT, which begin with the 20 bytes node ID.
+func SniffDHT(b []byte) (*SniffHeader, error) {
+	var kind string
+	switch {
+	case len(b) < len(dhtQueryPrefix):
+		if strings.HasPrefix(dhtQueryPrefix, string(b)) || strings.HasPrefix(dhtResponsePrefix, string(b)) {
+			return nil, common.ErrNoClue
+		}
+		return nil, errNotBittorrent
+	case string(b[:len(dhtQueryPrefix)]) == dhtQueryPrefix:
+		kind = "1:y1:q"
+	case string(b[:len(dhtResponsePrefix)]) == dhtResponsePrefix:
+		kind = "1:y1:r"
+	default:
+		return nil, errNotBittorrent
+	}
+	if len(b) < len(dhtQueryPrefix)+20 || !bytes.Contains(b[len(dhtQueryPrefix)+20:], []byte(kind)) {
+		return nil, errNotBittorrent
 	}
+	return &SniffHeader{}, nil
+}
 
-	buffer := buf.FromBytes(b)
+const (
+	dhtQueryPrefix    = "d1:ad2:id20:"
+	dhtResponsePrefix = "d1:rd2:id20:"
 
-	var typeAndVersion uint8
+	utpHeaderLen = 20
+	utpVersion   = 1
+	utpTypeSyn   = 4
 
-	if binary.Read(buffer, binary.BigEndian, &typeAndVersion) != nil {
-		return nil, common.ErrNoClue
-	} else if b[0]>>4&0xF > 4 || b[0]&0xF != 1 {
-		return nil, errNotBittorrent
-	}
+	// Bounds of how far apart the first packets of a uTP connection can be.
+	maxUTPSeqGap       = 16
+	maxUTPTimestampGap = 10 * 1000 * 1000 // microseconds
+)
 
-	var extension uint8
+type utpHeader struct {
+	typ       uint8
+	connID    uint16
+	timestamp uint32
+	seq       uint16
+	// length is the length of the header with the extensions.
+	length int
+}
 
-	if binary.Read(buffer, binary.BigEndian, &extension) != nil {
+func parseUTPHeader(b []byte) (*utpHeader, error) {
+	if len(b) < utpHeaderLen {
 		return nil, common.ErrNoClue
-	} else if extension != 0 && extension != 1 {
+	}
+	if b[0]>>4 > utpTypeSyn || b[0]&0xF != utpVersion {
 		return nil, errNotBittorrent
 	}
-
-	for extension != 0 {
-		if extension != 1 {
+	h := &utpHeader{
+		typ:       b[0] >> 4,
+		connID:    binary.BigEndian.Uint16(b[2:]),
+		timestamp: binary.BigEndian.Uint32(b[4:]),
+		seq:       binary.BigEndian.Uint16(b[16:]),
+		length:    utpHeaderLen,
+	}
+	// The sender always sets its timestamp, and a SYN has not received a packet yet to measure the difference to.
+	if h.timestamp == 0 || (h.typ == utpTypeSyn && binary.BigEndian.Uint32(b[8:]) != 0) {
+		return nil, errNotBittorrent
+	}
+	// The extensions are the selective ACK and the extension bits, chained by the type of the next one.
+	for extension := b[1]; extension != 0; {
+		if extension > 2 || len(b) < h.length+2 {
 			return nil, errNotBittorrent
 		}
-		if binary.Read(buffer, binary.BigEndian, &extension) != nil {
-			return nil, common.ErrNoClue
+		next, length := b[h.length], int(b[h.length+1])
+		if (extension == 1 && (length == 0 || length%4 != 0)) || (extension == 2 && length != 8) {
+			return nil, errNotBittorrent
 		}
+		h.length += 2 + length
+		if len(b) < h.length {
+			return nil, errNotBittorrent
+		}
+		extension = next
+	}
+	return h, nil
+}
 
-		var length uint8
-		if err := binary.Read(buffer, binary.BigEndian, &length); err != nil {
-			return nil, common.ErrNoClue
+// UTPSniffer sniffs the uTP connections over UDP. A single header is too easy to be matched by chance,
+// so it takes two packets of the same connection, with the connection ID echoed and the sequence and timestamp close.
+// It keeps the first packet between the calls, so Sniff must be given the payload of one session as it grows.
+type UTPSniffer struct {
+	first *utpHeader
+	// next is the offset of the packets after the first one.
+	next int
+}
+
+func (s *UTPSniffer) Sniff(b []byte) (*SniffHeader, error) {
+	if s.first == nil {
+		first, err := parseUTPHeader(b)
+		if err != nil {
+			return nil, err
 		}
-		if common.Error2(buffer.ReadBytes(int32(length))) != nil {
-			return nil, common.ErrNoClue
+		s.first = first
+		s.next = len(b)
+		// A SYN carries no data, so the next packet may follow it right away.
+		if first.typ == utpTypeSyn {
+			s.next = first.length
 		}
 	}
-
-	if common.Error2(buffer.ReadBytes(2)) != nil {
+	if len(b) <= s.next {
 		return nil, common.ErrNoClue
 	}
 
-	var timestamp uint32
-	if err := binary.Read(buffer, binary.BigEndian, &timestamp); err != nil {
-		return nil, common.ErrNoClue
+	second, err := parseUTPHeader(b[s.next:])
+	if err != nil {
+		return nil, errNotBittorrent
 	}
-	if math.Abs(float64(time.Now().UnixMicro()-int64(timestamp))) > float64(24*time.Hour) {
+	if second.connID != s.first.connID && second.connID != s.first.connID+1 {
+		return nil, errNotBittorrent
+	}
+	if second.seq-s.first.seq >= maxUTPSeqGap || second.timestamp-s.first.timestamp >= maxUTPTimestampGap {
 		return nil, errNotBittorrent
 	}
-
 	return &SniffHeader{}, nil
 }
@@ -0,0 +1,117 @@
+package bittorrent_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	. "github.com/xtls/xray-core/common/protocol/bittorrent"
+)
+
+func utpPacket(typ uint8, connID uint16, timestamp uint32, seq uint16, payload string) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	b[0] = typ<<4 | 1
+	binary.BigEndian.PutUint16(b[2:], connID)
+	binary.BigEndian.PutUint32(b[4:], timestamp)
+	binary.BigEndian.PutUint32(b[12:], 1<<20)
+	binary.BigEndian.PutUint16(b[16:], seq)
+	return append(b, payload...)
+}
+
+func TestUTPSniff(t *testing.T) {
+	concat := func(packets ...[]byte) [][]byte {
+		var b []byte
+		var r [][]byte
+		for _, p := range packets {
+			b = append(b, p...)
+			r = append(r, b)
+		}
+		return r
+	}
+	synWithTimestampDiff := utpPacket(4, 1000, 5000, 1, "")
+	binary.BigEndian.PutUint32(synWithTimestampDiff[8:], 300)
+
+	cases := []struct {
+		name   string
+		reads  [][]byte
+		result bool
+	}{
+		{
+			name:   "syn retransmit",
+			reads:  concat(utpPacket(4, 1000, 5000, 1, ""), utpPacket(4, 1000, 1005000, 1, "")),
+			result: true,
+		},
+		{
+			name:   "syn and data in one read",
+			reads:  [][]byte{append(utpPacket(4, 1000, 5000, 1, ""), utpPacket(0, 1001, 6000, 2, "hello")...)},
+			result: true,
+		},
+		{
+			name:   "data",
+			reads:  concat(utpPacket(0, 7, 5000, 100, "hello"), utpPacket(0, 7, 5100, 101, "world")),
+			result: true,
+		},
+		{
+			name:  "single packet",
+			reads: concat(utpPacket(4, 1000, 5000, 1, "")),
+		},
+		{
+			name:  "other connection",
+			reads: concat(utpPacket(0, 7, 5000, 100, "hello"), utpPacket(0, 9, 5100, 101, "world")),
+		},
+		{
+			name:  "sequence jump",
+			reads: concat(utpPacket(0, 7, 5000, 100, "hello"), utpPacket(0, 7, 5100, 3000, "world")),
+		},
+		{
+			name:  "no timestamp",
+			reads: concat(utpPacket(0, 7, 0, 100, "hello"), utpPacket(0, 7, 5100, 101, "world")),
+		},
+		{
+			name:  "syn with timestamp difference",
+			reads: concat(synWithTimestampDiff, utpPacket(4, 1000, 1005000, 1, "")),
+		},
+		{
+			name:  "not utp",
+			reads: concat([]byte("\x17\xfe\xfd\x00\x01\x00\x00\x00\x00\x00\x01\x00\x30abcdefghijklmnop")),
+		},
+	}
+
+	for _, test := range cases {
+		sniffer := new(UTPSniffer)
+		var (
+			result *SniffHeader
+			err    error
+		)
+		for _, b := range test.reads {
+			result, err = sniffer.Sniff(b)
+			if err != common.ErrNoClue {
+				break
+			}
+		}
+		if test.result != (err == nil && result != nil) {
+			t.Error(test.name, ": unexpected result ", result, " ", err)
+		}
+	}
+}
+
+func TestDHTSniff(t *testing.T) {
+	cases := []struct {
+		input  string
+		result bool
+	}{
+		{input: "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe", result: true},
+		{input: "d1:ad2:id20:abcdefghij01234567896:target20:mnopqrstuvwxyz123456e1:q9:find_node1:t2:aa1:y1:qe", result: true},
+		{input: "d1:rd2:id20:mnopqrstuvwxyz123456e1:t2:aa1:y1:re", result: true},
+		{input: "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:re"},
+		{input: "d1:ad2:id"},
+		{input: "d4:spaml1:a1:bee"},
+	}
+
+	for _, test := range cases {
+		result, err := SniffDHT([]byte(test.input))
+		if test.result != (err == nil && result != nil) {
+			t.Error(test.input, ": unexpected result ", result, " ", err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"golang.org/x/net/http2/hpack"
+)
+
+const (
+	http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+	http2FrameHeaders      = 0x1
+	http2FrameContinuation = 0x9
+
+	http2FlagEndHeaders = 0x4
+	http2FlagPadded     = 0x8
+	http2FlagPriority   = 0x20
+
+	// The client sends its SETTINGS and maybe a few other frames before the first HEADERS,
+	// anything beyond these bounds is not worth waiting for.
+	maxHTTP2SniffFrames = 8
+	maxHTTP2SniffLen    = 16384
+)
+
+var (
+	errNotHTTP2          = errors.New("not an HTTP/2 connection")
+	errInvalidHTTP2Frame = errors.New("invalid HTTP/2 frame")
+	errInvalidHPACK      = errors.New("invalid HPACK header block")
+)
+
+// The pseudo-headers in the HPACK static table. The pseudo-headers come first in a request,
+// so the other entries are never needed.
+var http2StaticPseudoHeaders = [...][2]string{
+	1: {":authority", ""},
+	2: {":method", "GET"},
+	3: {":method", "POST"},
+	4: {":path", "/"},
+	5: {":path", "/index.html"},
+	6: {":scheme", "http"},
+	7: {":scheme", "https"},
+}
+
+const http2StaticTableLen = 61
+
+// SniffHTTP2 sniffs the pseudo-headers of the first request on an HTTP/2 connection with prior knowledge, aka h2c.
+func SniffHTTP2(b []byte, c context.Context) (*SniffHeader, error) {
+	if len(b) < len(http2Preface) {
+		if strings.HasPrefix(http2Preface, string(b)) {
+			// No HTTP method begins with "PR", so only a shorter prefix may still be HTTP/1.
+			if len(b) < 2 {
+				return nil, common.ErrNoClue
+			}
+			return nil, protocol.ErrProtoNeedMoreData
+		}
+		return nil, errNotHTTP2
+	}
+	if string(b[:len(http2Preface)]) != http2Preface {
+		return nil, errNotHTTP2
+	}
+
+	block, err := readHTTP2HeaderBlock(b[len(http2Preface):])
+	if err != nil {
+		return nil, err
+	}
+	headers, err := decodeHTTP2PseudoHeaders(block)
+	if err != nil {
+		return nil, err
+	}
+
+	content := session.ContentFromContext(c)
+	if content != nil && len(content.Attributes) == 0 {
+		for _, h := range headers {
+			switch h[0] {
+			case ":method", ":path":
+				content.SetAttribute(h[0], h[1])
+			case ":authority":
+				content.SetAttribute("host", h[1])
+			}
+		}
+	}
+
+	for _, h := range headers {
+		if h[0] == ":authority" && h[1] != "" {
+			dest, err := ParseHost(strings.ToLower(h[1]), net.Port(80))
+			if err != nil {
+				return nil, err
+			}
+			return &SniffHeader{version: HTTP2, host: dest.Address.String()}, nil
+		}
+	}
+	return nil, errNotHTTP2
+}
+
+// readHTTP2HeaderBlock returns the header block of the first HEADERS frame, along with its CONTINUATION frames.
+func readHTTP2HeaderBlock(b []byte) ([]byte, error) {
+	var block []byte
+	inHeaders := false
+	for i := 0; ; i++ {
+		if i >= maxHTTP2SniffFrames || len(block) > maxHTTP2SniffLen {
+			return nil, errNotHTTP2
+		}
+		if len(b) < 9 {
+			return nil, protocol.ErrProtoNeedMoreData
+		}
+		length := int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		typ, flags := b[3], b[4]
+		streamID := binary.BigEndian.Uint32(b[5:9]) & 0x7fffffff
+		if length > maxHTTP2SniffLen {
+			return nil, errNotHTTP2
+		}
+		if len(b) < 9+length {
+			return nil, protocol.ErrProtoNeedMoreData
+		}
+		payload := b[9 : 9+length]
+		b = b[9+length:]
+
+		switch {
+		case inHeaders:
+			if typ != http2FrameContinuation {
+				return nil, errInvalidHTTP2Frame
+			}
+		case typ == http2FrameHeaders:
+			if streamID == 0 {
+				return nil, errInvalidHTTP2Frame
+			}
+			padding := 0
+			if flags&http2FlagPadded != 0 {
+				if len(payload) < 1 {
+					return nil, errInvalidHTTP2Frame
+				}
+				padding = int(payload[0])
+				payload = payload[1:]
+			}
+			if flags&http2FlagPriority != 0 {
+				if len(payload) < 5 {
+					return nil, errInvalidHTTP2Frame
+				}
+				payload = payload[5:]
+			}
+			if padding > len(payload) {
+				return nil, errInvalidHTTP2Frame
+			}
+			payload = payload[:len(payload)-padding]
+			inHeaders = true
+		default:
+			// SETTINGS, WINDOW_UPDATE, PRIORITY and such before the request.
+			continue
+		}
+
+		block = append(block, payload...)
+		if flags&http2FlagEndHeaders != 0 {
+			return block, nil
+		}
+	}
+}
+
+// decodeHTTP2PseudoHeaders decodes the header block up to the first regular header.
+// The dynamic table is empty at the beginning of a connection, so it only holds the entries added by this block.
+func decodeHTTP2PseudoHeaders(b []byte) ([][2]string, error) {
+	var headers, dynamic [][2]string
+	lookup := func(index uint64) ([2]string, bool, error) {
+		switch {
+		case index == 0:
+			return [2]string{}, false, errInvalidHPACK
+		case index < uint64(len(http2StaticPseudoHeaders)):
+			return http2StaticPseudoHeaders[index], true, nil
+		case index <= http2StaticTableLen:
+			return [2]string{}, false, nil
+		case index-http2StaticTableLen <= uint64(len(dynamic)):
+			return dynamic[uint64(len(dynamic))-(index-http2StaticTableLen)], true, nil
+		default:
+			return [2]string{}, false, errInvalidHPACK
+		}
+	}
+
+	for len(b) > 0 {
+		var (
+			h       [2]string
+			index   uint64
+			prefix  uint8
+			indexed bool
+			err     error
+		)
+		switch {
+		case b[0]&0x80 != 0:
+			index, b, err = readHPACKInt(b, 7)
+			if err != nil {
+				return nil, err
+			}
+			entry, pseudo, err := lookup(index)
+			if err != nil {
+				return nil, err
+			}
+			if !pseudo {
+				return headers, nil
+			}
+			headers = append(headers, entry)
+			continue
+		case b[0]&0xe0 == 0x20:
+			// Dynamic table size update.
+			if _, b, err = readHPACKInt(b, 5); err != nil {
+				return nil, err
+			}
+			continue
+		case b[0]&0xc0 == 0x40:
+			prefix, indexed = 6, true
+		default:
+			prefix = 4
+		}
+
+		if index, b, err = readHPACKInt(b, prefix); err != nil {
+			return nil, err
+		}
+		if index == 0 {
+			if h[0], b, err = readHPACKString(b); err != nil {
+				return nil, err
+			}
+		} else {
+			entry, pseudo, err := lookup(index)
+			if err != nil {
+				return nil, err
+			}
+			if !pseudo {
+				return headers, nil
+			}
+			h[0] = entry[0]
+		}
+		if !strings.HasPrefix(h[0], ":") {
+			return headers, nil
+		}
+		if h[1], b, err = readHPACKString(b); err != nil {
+			return nil, err
+		}
+		if indexed {
+			dynamic = append(dynamic, h)
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
+
+func readHPACKInt(b []byte, prefix uint8) (uint64, []byte, error) {
+	mask := uint64(1)<<prefix - 1
+	v := uint64(b[0]) & mask
+	b = b[1:]
+	if v < mask {
+		return v, b, nil
+	}
+	for shift := uint(0); len(b) > 0 && shift < 28; shift += 7 {
+		c := b[0]
+		b = b[1:]
+		v += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, b, nil
+		}
+	}
+	return 0, nil, errInvalidHPACK
+}
+
+func readHPACKString(b []byte) (string, []byte, error) {
+	if len(b) == 0 {
+		return "", nil, errInvalidHPACK
+	}
+	huffman := b[0]&0x80 != 0
+	length, b, err := readHPACKInt(b, 7)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(b)) < length {
+		return "", nil, errInvalidHPACK
+	}
+	s := b[:length]
+	b = b[length:]
+	if !huffman {
+		return string(s), b, nil
+	}
+	decoded, err := hpack.HuffmanDecodeToString(s)
+	if err != nil {
+		return "", nil, errInvalidHPACK
+	}
+	return decoded, b, nil
+}
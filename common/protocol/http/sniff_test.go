@@ -1,10 +1,15 @@
 package http_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol"
 	. "github.com/xtls/xray-core/common/protocol/http"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
 )
 
 func TestHTTPHeaders(t *testing.T) {
@@ -104,3 +109,87 @@ first_name=John&last_name=Doe&action=Submit`,
 		}
 	}
 }
+
+func http2Request(padded bool, split bool, fields ...hpack.HeaderField) []byte {
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	for _, f := range fields {
+		common.Must(encoder.WriteField(f))
+	}
+
+	var b bytes.Buffer
+	b.WriteString(http2.ClientPreface)
+	framer := http2.NewFramer(&b, nil)
+	common.Must(framer.WriteSettings(http2.Setting{ID: http2.SettingInitialWindowSize, Val: 1 << 20}))
+	common.Must(framer.WriteWindowUpdate(0, 1<<20))
+	fragment := block.Bytes()
+	var rest []byte
+	if split {
+		fragment, rest = fragment[:len(fragment)/2], fragment[len(fragment)/2:]
+	}
+	param := http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: fragment,
+		EndHeaders:    !split,
+	}
+	if padded {
+		param.PadLength = 7
+		param.Priority = http2.PriorityParam{StreamDep: 0, Weight: 15}
+	}
+	common.Must(framer.WriteHeaders(param))
+	if split {
+		common.Must(framer.WriteContinuation(1, true, rest))
+	}
+	return b.Bytes()
+}
+
+func TestHTTP2Sniff(t *testing.T) {
+	fields := []hpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: "Example.com:8080"},
+		{Name: ":path", Value: "/foo"},
+		{Name: "user-agent", Value: "test"},
+	}
+	cases := []struct {
+		name     string
+		input    []byte
+		domain   string
+		noClue   bool
+		needMore bool
+		err      bool
+	}{
+		{name: "plain", input: http2Request(false, false, fields...), domain: "example.com"},
+		{name: "padded", input: http2Request(true, false, fields...), domain: "example.com"},
+		{name: "continuation", input: http2Request(false, true, fields...), domain: "example.com"},
+		{name: "preface only", input: []byte(http2.ClientPreface), needMore: true},
+		{name: "partial preface", input: []byte("PRI * HT"), needMore: true},
+		{name: "ambiguous preface", input: []byte("P"), noClue: true},
+		{name: "partial frame header", input: http2Request(false, false, fields...)[:len(http2.ClientPreface)+5], needMore: true},
+		{name: "truncated", input: http2Request(false, false, fields...)[:60], needMore: true},
+		{name: "no authority", input: http2Request(false, false, fields[0], fields[1], fields[3]), err: true},
+		{name: "http1", input: []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"), err: true},
+	}
+
+	for _, test := range cases {
+		header, err := SniffHTTP2(test.input, context.TODO())
+		switch {
+		case test.noClue:
+			if err != common.ErrNoClue {
+				t.Error(test.name, ": expected no clue, but got ", err)
+			}
+		case test.needMore:
+			if err != protocol.ErrProtoNeedMoreData {
+				t.Error(test.name, ": expected need more data, but got ", err)
+			}
+		case test.err:
+			if err == nil {
+				t.Error(test.name, ": expected error but not")
+			}
+		case err != nil:
+			t.Error(test.name, ": unexpected error ", err)
+		case header.Domain() != test.domain || header.Protocol() != "http2":
+			t.Error(test.name, ": unexpected result ", header.Protocol(), " ", header.Domain())
+		}
+	}
+}
@@ -31,11 +31,19 @@ func (s SniffHeader) Domain() string {
 const (
 	versionDraft29 uint32 = 0xff00001d
 	version1       uint32 = 0x1
+	version2       uint32 = 0x6b3343cf
+)
+
+// Budget for sniffing a connection. The ClientHello is given up on once it takes more than this.
+const (
+	maxInitialPackets = 8
+	maxCryptoDataLen  = 32767
 )
 
 var (
 	quicSaltOld  = []byte{0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97, 0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99}
 	quicSalt     = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+	quicSaltV2   = []byte{0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9}
 	initialSuite = &CipherSuiteTLS13{
 		ID:     tls.TLS_AES_128_GCM_SHA256,
 		KeyLen: 16,
@@ -44,22 +52,114 @@ var (
 	}
 	errNotQuic        = errors.New("not quic")
 	errNotQuicInitial = errors.New("not initial packet")
+	errSniffBudget    = errors.New("client hello exceeds the sniffing budget")
 )
 
+// initialParams are the parameters of the Initial packets that differ between the versions.
+type initialParams struct {
+	salt       []byte
+	labelKey   string
+	labelIV    string
+	labelHP    string
+	packetType byte
+}
+
+func getInitialParams(version uint32) (*initialParams, bool) {
+	switch version {
+	case version1:
+		return &initialParams{salt: quicSalt, labelKey: "quic key", labelIV: "quic iv", labelHP: "quic hp", packetType: 0x0}, true
+	case versionDraft29:
+		return &initialParams{salt: quicSaltOld, labelKey: "quic key", labelIV: "quic iv", labelHP: "quic hp", packetType: 0x0}, true
+	case version2: // See https://www.rfc-editor.org/rfc/rfc9369.html#section-3
+		return &initialParams{salt: quicSaltV2, labelKey: "quicv2 key", labelIV: "quicv2 iv", labelHP: "quicv2 hp", packetType: 0x1}, true
+	default:
+		return nil, false
+	}
+}
+
+// cryptoStream reassembles the CRYPTO frames of the Initial packets sent to the same destination connection ID.
+// The frames may come out of order, so the received ranges are tracked to know how much of the stream is complete.
+type cryptoStream struct {
+	data   []byte
+	ranges [][2]int // sorted and merged
+}
+
+func (s *cryptoStream) write(offset uint64, data []byte) error {
+	end := offset + uint64(len(data))
+	if end > maxCryptoDataLen {
+		return errSniffBudget
+	}
+	if int(end) > len(s.data) {
+		s.data = append(s.data, make([]byte, int(end)-len(s.data))...)
+	}
+	copy(s.data[offset:end], data)
+
+	r := [2]int{int(offset), int(end)}
+	merged := s.ranges[:0:0]
+	for _, c := range s.ranges {
+		switch {
+		case c[1] < r[0]:
+			merged = append(merged, c)
+		case r[1] < c[0]:
+			merged = append(merged, r)
+			r = c
+		default:
+			r[0] = min(r[0], c[0])
+			r[1] = max(r[1], c[1])
+		}
+	}
+	s.ranges = append(merged, r)
+	return nil
+}
+
+// complete returns the received data from the start of the stream up to the first gap.
+func (s *cryptoStream) complete() []byte {
+	if len(s.ranges) == 0 || s.ranges[0][0] != 0 {
+		return nil
+	}
+	return s.data[:s.ranges[0][1]]
+}
+
+// clientHello returns the ClientHello message once all of it has been received.
+func (s *cryptoStream) clientHello() ([]byte, error) {
+	data := s.complete()
+	if len(data) < 4 {
+		return nil, nil
+	}
+	if data[0] != 0x01 { // ClientHello
+		return nil, errNotQuicInitial
+	}
+	length := 4 + (int(data[1])<<16 | int(data[2])<<8 | int(data[3]))
+	if length > maxCryptoDataLen {
+		return nil, errSniffBudget
+	}
+	if len(data) < length {
+		return nil, nil
+	}
+	return data[:length], nil
+}
+
+// SniffQUIC sniffs the server name from the Initial packets in b. The data of multiple UDP packets may be passed
+// together, as the ClientHello may span multiple Initial packets, either coalesced or in separated datagrams.
+// Note that b is decrypted in place.
 func SniffQUIC(b []byte) (*SniffHeader, error) {
 	if len(b) == 0 {
 		return nil, common.ErrNoClue
 	}
 
-	// Crypto data separated across packets
-	cryptoLen := int32(0)
-	cryptoDataBuf := buf.NewWithSize(32767)
-	defer cryptoDataBuf.Release()
+	streams := make(map[string]*cryptoStream)
 	cache := buf.New()
 	defer cache.Release()
 
 	// Parse QUIC packets
+	initialPackets := 0
 	for len(b) > 0 {
+		if initialPackets > 0 && b[0] == 0 {
+			// Datagrams may be padded with zeros after the packets.
+			b = b[1:]
+			continue
+		}
+
 		buffer := buf.FromBytes(b)
 		typeByte, err := buffer.ReadByte()
 		if err != nil {
@@ -77,14 +177,13 @@ func SniffQUIC(b []byte) (*SniffHeader, error) {
 		}
 
 		versionNumber := binary.BigEndian.Uint32(vb)
-		if versionNumber != 0 && typeByte&0x40 == 0 {
-			return nil, errNotQuic
-		} else if versionNumber != versionDraft29 && versionNumber != version1 {
+		params, ok := getInitialParams(versionNumber)
+		if !ok {
 			return nil, errNotQuic
 		}
 
 		packetType := (typeByte & 0x30) >> 4
-		isQuicInitial := packetType == 0x0
+		isQuicInitial := packetType == params.packetType
 
 		var destConnID []byte
 		if l, err := buffer.ReadByte(); err != nil {
@@ -120,7 +219,7 @@ func SniffQUIC(b []byte) (*SniffHeader, error) {
 		}
 
 		hdrLen := len(b) - int(buffer.Len())
-		if len(b) < hdrLen+int(packetLen) {
+		if uint64(len(b)-hdrLen) < packetLen {
 			return nil, common.ErrNoClue // Not enough data to read as a QUIC packet. QUIC is UDP-based, so this is unlikely to happen.
 		}
 
@@ -130,20 +229,23 @@ func SniffQUIC(b []byte) (*SniffHeader, error) {
 			continue
 		}
 
-		var salt []byte
-		if versionNumber == version1 {
-			salt = quicSalt
-		} else {
-			salt = quicSaltOld
+		initialPackets++
+		if initialPackets > maxInitialPackets {
+			return nil, errSniffBudget
 		}
-		initialSecret := hkdf.Extract(crypto.SHA256.New, destConnID, salt)
+
+		initialSecret := hkdf.Extract(crypto.SHA256.New, destConnID, params.salt)
 		secret := hkdfExpandLabel(crypto.SHA256, initialSecret, []byte{}, "client in", crypto.SHA256.Size())
-		hpKey := hkdfExpandLabel(initialSuite.Hash, secret, []byte{}, "quic hp", initialSuite.KeyLen)
+		hpKey := hkdfExpandLabel(initialSuite.Hash, secret, []byte{}, params.labelHP, initialSuite.KeyLen)
 		block, err := aes.NewCipher(hpKey)
 		if err != nil {
 			return nil, err
 		}
 
+		// The sample for the header protection starts 4 bytes after the start of the packet number.
+		if int(packetLen) < 4+block.BlockSize() {
+			return nil, errNotQuic
+		}
 		cache.Clear()
 		mask := cache.Extend(int32(block.BlockSize()))
 		block.Encrypt(mask, b[hdrLen+4:hdrLen+4+len(mask)])
@@ -153,8 +255,8 @@ func SniffQUIC(b []byte) (*SniffHeader, error) {
 			b[hdrLen+i] ^= mask[i+1]
 		}
 
-		key := hkdfExpandLabel(crypto.SHA256, secret, []byte{}, "quic key", 16)
-		iv := hkdfExpandLabel(crypto.SHA256, secret, []byte{}, "quic iv", 12)
+		key := hkdfExpandLabel(crypto.SHA256, secret, []byte{}, params.labelKey, 16)
+		iv := hkdfExpandLabel(crypto.SHA256, secret, []byte{}, params.labelIV, 12)
 		cipher := AEADAESGCMTLS13(key, iv)
 
 		nonce := cache.Extend(int32(cipher.NonceSize()))
@@ -169,103 +271,118 @@ func SniffQUIC(b []byte) (*SniffHeader, error) {
 		if err != nil {
 			return nil, err
 		}
-		buffer = buf.FromBytes(decrypted)
-		for !buffer.IsEmpty() {
-			frameType, _ := buffer.ReadByte()
-			for frameType == 0x0 && !buffer.IsEmpty() {
-				frameType, _ = buffer.ReadByte()
-			}
-			switch frameType {
-			case 0x00: // PADDING frame
-			case 0x01: // PING frame
-			case 0x02, 0x03: // ACK frame
-				if _, err = quicvarint.Read(buffer); err != nil { // Field: Largest Acknowledged
-					return nil, io.ErrUnexpectedEOF
-				}
-				if _, err = quicvarint.Read(buffer); err != nil { // Field: ACK Delay
-					return nil, io.ErrUnexpectedEOF
-				}
-				ackRangeCount, err := quicvarint.Read(buffer) // Field: ACK Range Count
-				if err != nil {
-					return nil, io.ErrUnexpectedEOF
-				}
-				if _, err = quicvarint.Read(buffer); err != nil { // Field: First ACK Range
-					return nil, io.ErrUnexpectedEOF
-				}
-				for i := 0; i < int(ackRangeCount); i++ { // Field: ACK Range
-					if _, err = quicvarint.Read(buffer); err != nil { // Field: ACK Range -> Gap
-						return nil, io.ErrUnexpectedEOF
-					}
-					if _, err = quicvarint.Read(buffer); err != nil { // Field: ACK Range -> ACK Range Length
-						return nil, io.ErrUnexpectedEOF
-					}
-				}
-				if frameType == 0x03 {
-					if _, err = quicvarint.Read(buffer); err != nil { // Field: ECN Counts -> ECT0 Count
-						return nil, io.ErrUnexpectedEOF
-					}
-					if _, err = quicvarint.Read(buffer); err != nil { // Field: ECN Counts -> ECT1 Count
-						return nil, io.ErrUnexpectedEOF
-					}
-					if _, err = quicvarint.Read(buffer); err != nil { //nolint:misspell // Field: ECN Counts -> ECT-CE Count
-						return nil, io.ErrUnexpectedEOF
-					}
-				}
-			case 0x06: // CRYPTO frame, we will use this frame
-				offset, err := quicvarint.Read(buffer) // Field: Offset
-				if err != nil {
-					return nil, io.ErrUnexpectedEOF
-				}
-				length, err := quicvarint.Read(buffer) // Field: Length
-				if err != nil || length > uint64(buffer.Len()) {
-					return nil, io.ErrUnexpectedEOF
-				}
-				currentCryptoLen := int32(offset + length)
-				if cryptoLen < currentCryptoLen {
-					if cryptoDataBuf.Cap() < currentCryptoLen {
-						return nil, io.ErrShortBuffer
-					}
-					cryptoDataBuf.Extend(currentCryptoLen - cryptoLen)
-					cryptoLen = currentCryptoLen
-				}
-				if _, err := buffer.Read(cryptoDataBuf.BytesRange(int32(offset), currentCryptoLen)); err != nil { // Field: Crypto Data
-					return nil, io.ErrUnexpectedEOF
-				}
-			case 0x1c: // CONNECTION_CLOSE frame, only 0x1c is permitted in initial packet
-				if _, err = quicvarint.Read(buffer); err != nil { // Field: Error Code
-					return nil, io.ErrUnexpectedEOF
-				}
-				if _, err = quicvarint.Read(buffer); err != nil { // Field: Frame Type
-					return nil, io.ErrUnexpectedEOF
-				}
-				length, err := quicvarint.Read(buffer) // Field: Reason Phrase Length
-				if err != nil {
-					return nil, io.ErrUnexpectedEOF
-				}
-				if _, err := buffer.ReadBytes(int32(length)); err != nil { // Field: Reason Phrase
-					return nil, io.ErrUnexpectedEOF
-				}
-			default:
-				// Only above frame types are permitted in initial packet.
-				// See https://www.rfc-editor.org/rfc/rfc9000.html#section-17.2.2-8
-				return nil, errNotQuicInitial
-			}
+
+		stream := streams[string(destConnID)]
+		if stream == nil {
+			stream = &cryptoStream{}
+			streams[string(destConnID)] = stream
+		}
+		if err := readInitialFrames(buf.FromBytes(decrypted), stream); err != nil {
+			return nil, err
 		}
 
-		tlsHdr := &ptls.SniffHeader{}
-		err = ptls.ReadClientHello(cryptoDataBuf.BytesRange(0, cryptoLen), tlsHdr)
+		hello, err := stream.clientHello()
 		if err != nil {
-			// The crypto data may have not been fully recovered in current packets,
+			return nil, err
+		}
+		if hello == nil {
+			// The crypto data has not been fully received in current packets,
 			// So we continue to sniff rest packets.
 			b = restPayload
 			continue
 		}
+		tlsHdr := &ptls.SniffHeader{}
+		if err := ptls.ReadClientHello(hello, tlsHdr); err != nil {
+			return nil, err
+		}
 		return &SniffHeader{domain: tlsHdr.Domain()}, nil
 	}
 	// All payload is parsed as valid QUIC packets, but we need more packets for crypto data to read client hello.
 	return nil, protocol.ErrProtoNeedMoreData
 }
 
+// readInitialFrames reads the frames of a decrypted Initial packet, passing the CRYPTO frames to the stream.
+func readInitialFrames(buffer *buf.Buffer, stream *cryptoStream) error {
+	for !buffer.IsEmpty() {
+		frameType, _ := buffer.ReadByte()
+		for frameType == 0x0 && !buffer.IsEmpty() {
+			frameType, _ = buffer.ReadByte()
+		}
+		switch frameType {
+		case 0x00: // PADDING frame
+		case 0x01: // PING frame
+		case 0x02, 0x03: // ACK frame
+			if _, err := quicvarint.Read(buffer); err != nil { // Field: Largest Acknowledged
+				return io.ErrUnexpectedEOF
+			}
+			if _, err := quicvarint.Read(buffer); err != nil { // Field: ACK Delay
+				return io.ErrUnexpectedEOF
+			}
+			ackRangeCount, err := quicvarint.Read(buffer) // Field: ACK Range Count
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if _, err = quicvarint.Read(buffer); err != nil { // Field: First ACK Range
+				return io.ErrUnexpectedEOF
+			}
+			for i := 0; i < int(ackRangeCount); i++ { // Field: ACK Range
+				if _, err = quicvarint.Read(buffer); err != nil { // Field: ACK Range -> Gap
+					return io.ErrUnexpectedEOF
+				}
+				if _, err = quicvarint.Read(buffer); err != nil { // Field: ACK Range -> ACK Range Length
+					return io.ErrUnexpectedEOF
+				}
+			}
+			if frameType == 0x03 {
+				if _, err = quicvarint.Read(buffer); err != nil { // Field: ECN Counts -> ECT0 Count
+					return io.ErrUnexpectedEOF
+				}
+				if _, err = quicvarint.Read(buffer); err != nil { // Field: ECN Counts -> ECT1 Count
+					return io.ErrUnexpectedEOF
+				}
+				if _, err = quicvarint.Read(buffer); err != nil { //nolint:misspell // Field: ECN Counts -> ECT-CE Count
+					return io.ErrUnexpectedEOF
+				}
+			}
+		case 0x06: // CRYPTO frame, we will use this frame
+			offset, err := quicvarint.Read(buffer) // Field: Offset
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			length, err := quicvarint.Read(buffer) // Field: Length
+			if err != nil || length > uint64(buffer.Len()) {
+				return io.ErrUnexpectedEOF
+			}
+			data, err := buffer.ReadBytes(int32(length)) // Field: Crypto Data
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if err := stream.write(offset, data); err != nil {
+				return err
+			}
+		case 0x1c: // CONNECTION_CLOSE frame, only 0x1c is permitted in initial packet
+			if _, err := quicvarint.Read(buffer); err != nil { // Field: Error Code
+				return io.ErrUnexpectedEOF
+			}
+			if _, err := quicvarint.Read(buffer); err != nil { // Field: Frame Type
+				return io.ErrUnexpectedEOF
+			}
+			length, err := quicvarint.Read(buffer) // Field: Reason Phrase Length
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if _, err := buffer.ReadBytes(int32(length)); err != nil { // Field: Reason Phrase
+				return io.ErrUnexpectedEOF
+			}
+		default:
+			// Only above frame types are permitted in initial packet.
+			// See https://www.rfc-editor.org/rfc/rfc9000.html#section-17.2.2-8
+			return errNotQuicInitial
+		}
+	}
+	return nil
+}
+
 func hkdfExpandLabel(hash crypto.Hash, secret, context []byte, label string, length int) []byte {
 	b := make([]byte, 3, 3+6+len(label)+1+len(context))
 	binary.BigEndian.PutUint16(b, uint16(length))
@@ -285,3 +285,68 @@ func TestSniffFakeQUICPacketWithTooShortData(t *testing.T) {
 		t.Error("failed")
 	}
 }
+
+// The first flight of a client offering X25519MLKEM768, where the ClientHello is split across two Initial packets
+// in separate datagrams. Captured from quic-go, connecting to split.example.com.
+var (
+	splitHelloV1 = []string{
+		"c9000000010eb41bf863f890586911d2f48cf8b6000044e8e65baf75464ead8b141679524c47662c401f45b35ce1b10faee3fd64f4ba840e093aaa9f10d82111c728166d3bb2c7a3905f915093d2deb6cf29ee75a10cbfd5536fed40f67199c1e35612b86dd2f96dcbaa83fe76a752023d8b3cd7a3335779292937b26bb0e1d78da3c5422f27f1dba3d94f605d164d7eba262ce3595e2e70fbd3a86ad20c3f8456f6bbde004925a4097aa1721d0107c22e3bbf006ab1bb5438c531498ec5c737fa59544095181f94a5fadd8fdd6cecead2db06e550742348e29bd99c77e9f5e12325f36ebadbc1389793e923f2f4f37786c2bb5d74f5aea52ba0f9e072a2e74ca6912eb2e9edc1173a071a259b2463a3494ccfd6e92e095e922aa9fedefd843e8d6d3ba76290b95e02b2a411932fff34de62382a5cc58ca285be482819f8cd8227c218473f16696de03c93903166b64e5c5a1bcb69ea812f9afd1708cff6530863d22873e562ddd7cded2f43b3d36920b9ecb3bbda952acf2f52de9f28656e00219d66ac8f00c68bd8e0a76dc0aa9f88ac5002e4eb3eaad5a1ba3540478735dad8b417a2a066722dcd5784fb84dc75023a71f42008825a2dac976d12c5bd9c6906e6d1b47c9e30ab9e37493c542c495d1cb274566a295b5683f8e99d003e2d7c06d25f671a0afe98b342868780f9d0a2013ff4a1b5ce2acbdb93cb91946945b1a169f17417e1cb298b1bdaafd40dc295c2d17fa2c205802531f2601b4a36a94014f4b4e85488467b26523f30498a0c301612e0feebdf1fa4a1201e88b32f7a4ec152ee89ade246937da8bf8b8f2f4fe4e29845671aaedfa311e43c5095791832d0da61a30db60b20af3919c759e9dd03be31a733157abc21055e9f09d7fa94de4c6f9ab6e8021358d8db7606b260eae65b470543a570ef9b0b02be3558e5c2632711126b87d0da8415cc4eadfe65105a7244999027b31c0fe0ca17ceea4156b108acab725b5d64a67799e783fb7af94f6b40030fa39f2c514ea8f5753c791a4aa4429ec17b5a5fbcb64e6eb1d2ff9c35a1698dbc22df0e0b2f02dc6351d8920f4cac1cce647a8d7e7a794405e41ece038b04a69f852de6fe544c9f212ccf2db2d7d4576c825a66ae8216612247dfbcc805437851c4e690842257c4daa00e83dd7103c391569a15d5c7249cc4040a8bbe789dd1a2bee7933d15af63f1ad543d29064a8f88494646afe9eb56053ffd55832116525447c45fc5af7ac83d71585b9d6fd1c4d69e0e5d412ce4156d0079a9d1a8b9a40f03633e6235c403243241311fc5c15d585dda6151631c9a70c07cb8e7654a934258760de0cb5544236a121c89f40870612b8ac4093904218e942567328fd7f9614e11d611429a2af4cc22a491a81dd1eb8cce67e624c56667293b3a06851831f2d3f2f51679ddc8e7d4205b672b3a8d09f6b1787734e4b8678721af5cdfc12bba5028306aa483da99432c5b31d7a37401ec8bd49744c782b90161cf657ef5d90ecb7b8608573c1f802938b5792eb6cebee31cb082f5acdb3fef159e94a4315339c5aaf4501d4e28790e8673681e6033e8254df5961c18867a15bf9488d2059be298d34e273eb9e43e31b8da621e61c308e2ebaba12678b0c572aa8ea80b778804ed48dac324791af6d69be07d427021b98f14eb7bab167a37bd99c941c2b7e28fc4977c97234820900e9c6f9ab7d073445fc56a032f7d4ffb6c1d48d7e1a5df70a6e43994604c332bb335e10c7e2f3adf06038be052355ffd697c1611638da2414f07ee074425e64c4cb984dc",
+		"ce000000010eb41bf863f890586911d2f48cf8b6000044e8b5f8c877ff94d3d3766637808656fe876b2df2bf3b92f91978fe424c9199bcb575f1ccb59eb84f89667b1d7cd8947f49830236b4affe17de3a8537db6c2bf2d4fb86ec63f5d90d48d7b92eebf47ff4c4b57bb3bd0aaaa72e71b899f2d9011a524fe63e69516d05f6c0128ba1e09d773cf8b46bab06cef68c1712b1c8fc51d675ecb7891d6d14b346c5e762d5b909dc5c993c1104c434b6facbe381e968e4f2682e4c1c48ce11a621f2a79695e827bb13f3d6e9cb5f9af5f84147d8c6e6fa133050a6ccfdbaa4a725ca31ab31df2bc4f7a55a562cdce174b165c8b8b6c32deae9adcbb10763b66d7ba265269b135c5bc4570c2e00cf70a78950ac9e0fa34b2328a4b64e26d9067d58f8b77b9bf2a48cd6b0a25deff838f0992d51a3186699b511941f4f95964947422d8324393410f8654eda895a08b6ce1b9c0fcb3e0d21f35749774f5e00530dfdf364af87af9712748df531b0f5d53222bd6723b1d00a4afe9cd1e080526e23314110e49c36b02cff8d8e4bc201c343868ef0ddea55f33bed5f8a20a296ba6dd7df24dbdcffe59fc79b936c422a04a6e2d6c1179bc1d6d5df20c408a0b46ff42424c3ebff93f7775e3d1ef4865b006aa5a05bb2959bef3c5b1afd1e96315336a69a733e6ebe9ee3f33c45175939d19c35450d94a5b7fabd4071e95583800f26a72a6c2d86a390cc5cb4b31a9c76ba473e6320593473ab24585794d9303c346a5de76f3c3f2a743730f2ccabf22d47ef5b774900f7501bcd811105d1916c2128b326d2cdc565a2baa642ec02a12a1164f03eb100a3ef2ba1546712972b82a8ef5be876df680124b3d1b8acffd674f19ff75bf04a9ca5244839b302a878f13a4ca4475261b463caccc0d436720a1dc8bd994f21ccf2ac31a4900921654b9a4bae385c9c40ae637476c6e0205f9c7d482d338419d723bbac902f12d19bc69ea593daac609f552767c2dfba283758cad627df32b4da4ad7655a93fb1b9645174c6881c431a4749b0163e88d008a48036a9aa014928c981e625231c2e62689ddd70d624948f66495c49bf89921a771777a5c0b07358ddcaac7b4a41171a9696f2b248e48db6ec6396f5549f87bf198413fb527b45614eb4c13d417258c0d61c31f3930bb82120a1636bc98b3c29dcc17a1b34a9ca16bb534b9729fe040aabfa691fde27bac890c215a415f0ced11408521e7e1b8cb783a6a0aa5cbc99f8e8dc2f7863373591467398c0cc2f3b9a7e719abe42185087000d0d629a313e82576f40f5a9d5ba0ff487e508fcadc2334c2ee6d57084da9f38487adbc81b51dd8866c386854efeea8f64322ce01ea40dadbd47950057c747eb9d665de3df58a74068b49bb6350fcf7bbd6858316f1dfea480e375807f13b876c8efbf4da3dc34b48061c8d1d468bc5f0061e11864d71e97b2ab0c986d22349da6ca380dcf581cf9ca7bd82f8d57ab879f3b834dfb58620dc03939dc48958ddb81368050a2a35e4de2234fcf717940fe7b70fe69365be86e3ace17d073a5bf95bfd311b08bcf8a72511a9475b7558893306819aab57fe1f4ed76d0ba5c47be213833c502ff5c6588ed963accd6f87e03479bf4e3a2ef650d2c670c08afa7bc085a8b26f397b3797e88d17b0350545907680cf68790ebc5e2827f73ce7b12b1d6a846b715c38b12a12a79c90e377774723fe1663e4333db6997481da3bb90c46e1a714dee9c680835ccf4906fa3d808e465acb0cbbdb530b577a623ac61d1f0ee292903a797ddb9e19fa494d1e",
+	}
+	splitHelloV2 = []string{
+		"d86b3343cf0aebb32cc4049c18436e2e000044ecf99ec65ad5454118acb5db533c37591c5fb4f8da6de8f5772d5bb9e8cf8a2f7344c4232f4cf1b66ba8cd6a9c52e40cffadc099b89b285ed396fab78fcde50b12d28e53c76cf4a0721ae5e64db79af3e552042549f6ca110e632058c8021664a99512b3abf9e9b243a74980d0c2525ed7469b8d44cd87834e0b77ef0b9386558f951200997e51aad15df281f3ac15bd571084b5f7301c6be3fa79bd52942cacd64c022abc3439aa83c56207817b004a304faba7d7f1aeb92a254f2929e95b72704f51eabf2822b74cd9aea9006b16b9da02b24ee6052a0f3cf305d73c98211548cddbd420cb5b146a80b4b96cbde4ddb337a4507c621f86e727936f374cc56088b4c51df253bca829892af9a8b7dd7d96fc48395a2159899f7edd365c58babfeffdea658058a712004d2b534a661229a641e4b0b88def2f8c1d350627eb2dcc111fd3ce6f7de29101b7d367859dfe941725c121290490df06044ad71cb94d399eefa76f8231e2b958f98bfed26ada4be1162b7243280f96e332bd5fd15f3458137d133d17fa21291c3d6f0a553751fae7d50187ac429d4a08d0ae7fc4781f561d4916c22b824323c21bfe864371c00474a7cf0466ccdd6c89c2f91eb6d64e2e453b5376035326b75fdd5509830cae2c275be096d938e9b65e9de2a72921fa2233d003ffc944f2d95beb6332d7b6c6b95ad3359f2e2b61eca580d004a9f7ad5d1f29d4fbea2632d7e7040e27d784eb4fdba888f7e390e5d8d4ee0b59c5f7768b68fe77abc509c76be124250642ae00d3b9dbc750facc7a94ce8c43ea3e9d4f78b6aa1816004a5a893ea43fa4aa47e0a75d7c755e87aed0fbfd36788fd41a30e314ecff11328bb986b3808145a525fc5f33ddb8bd8abe2ba3837a527ef1a48c542374f22d5f061f46f7d0be22a9c9a9d3c24adc2403a79c8f8f61a3cb1c4d1653e62f74f6e92f0b09f3dcc0a677135da2a50210f2d0a1e4b344c485b280dd29f23d7380a5fcbdc3e37e389447ff89df3071656c277e65f86199182161d1f36eaba6fcc4074b284c87b0a2a1d0c1eaa521dc8007b234ca468fc3ff8ae34290c1b9b403558f7de7d394b733493d6559657cc9c3beb625df50d8b8f5e4815400c7957abaf99bb9cf403b2c07d45150ed2e3722d3c963803e62631a4ab0e7dcbe6b4bd8e83d4db838de83dfdc2e92a405066e3241460d754c4995344cf972520d5edee23bce07597a6a12bdc01ee51c6831eaeb5c59a3864edf07e725991c2eb7da14cbff72e792653d7d02b05f6384c0a9376ffb8f85428ea47c9c9d94489cde28b9b555fc15d99a5cca1bf69d700c84606caa7bb7990f9849f0e3e3e44dd18231afb1d0b489e590c3c2abdad14185aad4f361e9c6fdb82f6ffa564790abebccce09ff5a5e0b9481704df504ea6dfca588ad8e1917ae8844bdfbb28c681cdcb26e1ad5214a1b70d3d34176cf48b12c9ae11bcd3ef3e82bb26e03d8b718e5371b5d9bd0cdd36f1781d61e12efaa8637205f34d00158acc0a025b9adbe8770e92ef0e7f271f053888d390644164a2f755227789f5c92f6d761c118f8361e820d507080740b6f0aea0cd43b9fb93794438c18834bf84f5e3788904dcbacea29282e97c7732f2123de0a9a8345c512e74ae21f6b8064f1634f0470f4958ad6c0951f71ad29a8848749fc3897966619a359fece3998e1ee288b962e5941e050a6cda417597136c5b8402689e34eb60a2976ee1e9a9909315a96346b4c0892f11765cd88a0893da795d2486d03ba49184c9c",
+		"db6b3343cf0aebb32cc4049c18436e2e000044ecd90d82a4020fe07fbb206d58520c529938093262dccb0d77942abb85e2d3c302ff8c7deb6e8bb1a06da75675e3f23a7d1b73ba85bb2688272864f58d64da05fd6194fe472be31bb9d85b8d2372a26a5371a47cbb785fcb77c707c0bde1ba1d63c6d5782a565cb9e01fbc961fdfcc55d550f916fc7f5b77fd98c40f8a3f7b76eddab9d710106548e8bb63f72b258f93495ac5b6883ca2bc9738ccf49d29a5feeeac6f2c8925a1917b45046de1d981c1f4821bddcccfaebba1fafe75f9a3e367885cf07b7a6780012ab6b0681f42f20ca8ad0da598daba4db1371d4e4c3109f15a0b0d2b5c9123107c530b3a4cad85c9cccf220df0e61c326ebc7fc4177e8782194066c6cff3c606aeb34e1935e733cc952b5453ce3913381c1f5e696c66489a95d32c32d63d67621084083771150aea28396b98d372eafd2e2b4e1dfd9e3b10d5540123c9f46433b15393606d29b160c4edff8e9fa6fa781ca97338689e9b5bfe90608be00a946e6844919c05e574f0c65f03a6354fd63aaad41ce318668c69208cba1986c748edce074e2df6ef2d18f4e0e47075f75a6afe5ee4414f2b9b6d303c5f9d09f14cb874f9f37d261e2307a0c0c6d74c098ac26aefc3770418ea91787b07ac792e0cab63229cc4ebacffc73e8a9905120d2599f2ef9288c7dd66924820cfaf7ea176269391457d0f16069bd4533da9d6bc6916dd9f46b9cd44fccc883e7f3cb126b27903dc31ddd28373d5cccec8539d34f176b0585989f261dbd765032da57dd3021d9a95f44d800f8f24b8cdf71122912534901f586754003ec140373dc1f072c699bd15edfee45ef47d5253b204278b8624fc9b490b29bc14039b41618c9e5ffe55543943fd6ec8b03d857259340718031a2c3a3f252867dfe1dcea6dc5d62f74d634c495e8f376b960711f424d654884efb4f75d60c8e04801b4386dbd93b5aeecf579318c704d2751217a5976ebee44afed0c20dbefe43b187ce68e3b810e20d59e2dd6eb98d418a6a64c6dc0faf942104c12752f0e0c693c220584f8ff1995cffb6f90e1982b2a32cb753fabe1838793aa38634af15a1641b001118a7c221cfce16b2bd7e1a15b42e55a08e136a411d3e416271444e9c9d662493cd35b6fa59c3ed8ace94673b5b14fee27d87c01c9dcda4f2192eee4d4a6afdc594f30d69bf16d1e64079b45a83e2fdcb21c8af4ba247f6ed3929375c0bae8a698035069aeffedf140b0e287255198d25b9fae12ffb1b09b942ec55fd4f71490fe28758653d8fc3b8778678c89be4b1ee1136c23f6d272ac4df5e04ef5430d1ed69f77677a676534d34c26da821105a34bb6dacc05ab463033303a06fb00dcd5bd2e8cb007792eff00364e690050afcf2f5cec9f3f7ff1843d5f59d0429e16d767ecd861bfca45f2e093e583f0d0c510da4f945846004a22fe4990bfe6d5032ff7bd30ffc94326ee572f17becaa4fa0fa9c1d58cbdc98df6d04da97b75016c59d1ba12fe8b43b4426d2b1ad9ba7f7a11ab78f4c87a3aea45a5e5e0ddf9d9e74f1db6c0c0e2dfdcb28f4f5822c97dbc2203af785fa867bbc8c6cc4299aa1ead864c7977375b07552e63489e272c611a1c55c78153fa53aac1185588c441513e16f96108b5ca5f735f81e3d55170297ca73a464f5dc7f36128778ffc81b6773514e4cf6206fb9092c849461b110c248a6ae1de15956abda499d264bb57edba8cd853a5bc4eef5912b3079455c4b11928ef989788080d751dd185cc71189be8d2bad1687077ff644c8894023a",
+	}
+)
+
+func decodeDatagrams(t *testing.T, datagrams ...string) []byte {
+	var b []byte
+	for _, d := range datagrams {
+		pkt, err := hex.DecodeString(d)
+		if err != nil {
+			t.Fatalf("failed to decode hex string: %v", err)
+		}
+		b = append(b, pkt...)
+	}
+	return b
+}
+
+func TestSniffQUICSplitClientHello(t *testing.T) {
+	for name, datagrams := range map[string][]string{"v1": splitHelloV1, "v2": splitHelloV2} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := quic.SniffQUIC(decodeDatagrams(t, datagrams[0])); !errors.Is(err, protocol.ErrProtoNeedMoreData) {
+				t.Fatal("expected to need more data, but got ", err)
+			}
+
+			quicHdr, err := quic.SniffQUIC(decodeDatagrams(t, datagrams...))
+			if err != nil || quicHdr.Domain() != "split.example.com" {
+				t.Fatal("failed to sniff the split client hello: ", err)
+			}
+
+			// Out of order
+			quicHdr, err = quic.SniffQUIC(decodeDatagrams(t, datagrams[1], datagrams[0]))
+			if err != nil || quicHdr.Domain() != "split.example.com" {
+				t.Fatal("failed to sniff the reordered client hello: ", err)
+			}
+		})
+	}
+}
+
+func TestSniffQUICZeroPaddedDatagrams(t *testing.T) {
+	padding := hex.EncodeToString(make([]byte, 70))
+	quicHdr, err := quic.SniffQUIC(decodeDatagrams(t, splitHelloV1[0], padding, splitHelloV1[1], padding))
+	if err != nil || quicHdr.Domain() != "split.example.com" {
+		t.Error("failed to sniff the padded datagrams: ", err)
+	}
+}
+
+func TestSniffQUICBudget(t *testing.T) {
+	var datagrams []string
+	for i := 0; i < 9; i++ {
+		datagrams = append(datagrams, splitHelloV1[0])
+	}
+	_, err := quic.SniffQUIC(decodeDatagrams(t, datagrams...))
+	if err == nil || errors.Is(err, protocol.ErrProtoNeedMoreData) {
+		t.Error("expected to give up after too many initial packets, but got ", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 )
 
 type ServerSpec struct {
-	Destination  net.Destination
-	User         *MemoryUser
+	Destination net.Destination
+	User        *MemoryUser
+	// Alternatives are the other destinations of the same server, raced with Destination.
+	Alternatives []net.Destination
 }
 
 func NewServerSpec(dest net.Destination, user *MemoryUser) *ServerSpec {
@@ -26,5 +28,9 @@ func NewServerSpecFromPB(spec *ServerEndpoint) (*ServerSpec, error) {
 		}
 		dUser = user
 	}
-	return NewServerSpec(dest, dUser), nil
+	server := NewServerSpec(dest, dUser)
+	for _, address := range spec.AlternativeAddresses {
+		server.Alternatives = append(server.Alternatives, net.TCPDestination(address.AsAddress(), dest.Port))
+	}
+	return server, nil
 }
@@ -0,0 +1,234 @@
+package tls
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	extensionServerName          = 0x0000
+	extensionSupportedGroups     = 0x000a
+	extensionECPointFormats      = 0x000b
+	extensionSignatureAlgorithms = 0x000d
+	extensionALPN                = 0x0010
+	extensionSupportedVersions   = 0x002b
+)
+
+// clientHello is the fields of a TLS ClientHello that the fingerprints are made of, without the GREASE values.
+type clientHello struct {
+	version             uint16
+	cipherSuites        []uint16
+	extensions          []uint16
+	supportedGroups     []uint16
+	ecPointFormats      []uint8
+	signatureAlgorithms []uint16
+	supportedVersions   []uint16
+	alpn                string
+	serverName          bool
+}
+
+// isGREASE returns whether v is one of the values reserved by RFC 8701.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func readUint16List(s *cryptobyte.String, list *[]uint16) bool {
+	var l cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&l) || len(l)%2 != 0 {
+		return false
+	}
+	for !l.Empty() {
+		var v uint16
+		l.ReadUint16(&v)
+		if !isGREASE(v) {
+			*list = append(*list, v)
+		}
+	}
+	return true
+}
+
+// parseClientHello parses the ClientHello in the TLS record b.
+func parseClientHello(b []byte) (*clientHello, error) {
+	if len(b) < 5 || b[0] != 0x16 {
+		return nil, errNotTLS
+	}
+	headerLen := int(binary.BigEndian.Uint16(b[3:5]))
+	if 5+headerLen > len(b) {
+		return nil, errNotClientHello
+	}
+	s := cryptobyte.String(b[5 : 5+headerLen])
+
+	h := new(clientHello)
+	var msgType uint8
+	var body, sessionID, compressionMethods, extensions cryptobyte.String
+	if !s.ReadUint8(&msgType) || msgType != 1 ||
+		!s.ReadUint24LengthPrefixed(&body) ||
+		!body.ReadUint16(&h.version) ||
+		!body.Skip(32) ||
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!readUint16List(&body, &h.cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) {
+		return nil, errNotClientHello
+	}
+	if body.Empty() {
+		return h, nil
+	}
+	if !body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, errNotClientHello
+	}
+	for !extensions.Empty() {
+		var extension uint16
+		var data cryptobyte.String
+		if !extensions.ReadUint16(&extension) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return nil, errNotClientHello
+		}
+		if isGREASE(extension) {
+			continue
+		}
+		h.extensions = append(h.extensions, extension)
+
+		ok := true
+		switch extension {
+		case extensionServerName:
+			h.serverName = true
+		case extensionSupportedGroups:
+			ok = readUint16List(&data, &h.supportedGroups)
+		case extensionECPointFormats:
+			var formats cryptobyte.String
+			ok = data.ReadUint8LengthPrefixed(&formats)
+			h.ecPointFormats = formats
+		case extensionSignatureAlgorithms:
+			ok = readUint16List(&data, &h.signatureAlgorithms)
+		case extensionALPN:
+			var protocols, protocol cryptobyte.String
+			ok = data.ReadUint16LengthPrefixed(&protocols) && protocols.ReadUint8LengthPrefixed(&protocol)
+			h.alpn = string(protocol)
+		case extensionSupportedVersions:
+			var versions cryptobyte.String
+			ok = data.ReadUint8LengthPrefixed(&versions) && len(versions)%2 == 0
+			for ok && !versions.Empty() {
+				var v uint16
+				versions.ReadUint16(&v)
+				if !isGREASE(v) {
+					h.supportedVersions = append(h.supportedVersions, v)
+				}
+			}
+		}
+		if !ok {
+			return nil, errNotClientHello
+		}
+	}
+	return h, nil
+}
+
+func joinDecimal[T uint8 | uint16](list []T) string {
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(s, "-")
+}
+
+// JA3String returns the JA3 string of the ClientHello in the TLS record b, like "771,4865-4866,0-23-65281,29-23,0".
+func JA3String(b []byte) (string, error) {
+	h, err := parseClientHello(b)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(int(h.version)) + "," + joinDecimal(h.cipherSuites) + "," + joinDecimal(h.extensions) + "," +
+		joinDecimal(h.supportedGroups) + "," + joinDecimal(h.ecPointFormats), nil
+}
+
+// JA3 returns the JA3 fingerprint, the MD5 hash of the JA3 string, of the ClientHello in the TLS record b.
+func JA3(b []byte) (string, error) {
+	s, err := JA3String(b)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+var ja4Versions = map[uint16]string{
+	0x0304: "13",
+	0x0303: "12",
+	0x0302: "11",
+	0x0301: "10",
+	0x0300: "s3",
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// ja4Hash returns the first 12 hex digits of the SHA256 hash of the values in hex, joined with commas.
+func ja4Hash(list []uint16, suffix string) string {
+	if len(list) == 0 {
+		return "000000000000"
+	}
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = fmt.Sprintf("%04x", v)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(s, ",") + suffix))
+	return hex.EncodeToString(sum[:6])
+}
+
+// JA4 returns the JA4 fingerprint of the ClientHello in the TLS record b, like "t13d1516h2_8daaf6152771_e5627efa2ab1".
+func JA4(b []byte) (string, error) {
+	h, err := parseClientHello(b)
+	if err != nil {
+		return "", err
+	}
+
+	version := h.version
+	if len(h.supportedVersions) > 0 {
+		version = slices.Max(h.supportedVersions)
+	}
+	versionName, found := ja4Versions[version]
+	if !found {
+		versionName = "00"
+	}
+	sni := "i"
+	if h.serverName {
+		sni = "d"
+	}
+	alpn := "00"
+	if len(h.alpn) > 0 {
+		first, last := h.alpn[0], h.alpn[len(h.alpn)-1]
+		if isAlphanumeric(first) && isAlphanumeric(last) {
+			alpn = string([]byte{first, last})
+		} else {
+			alpn = hex.EncodeToString([]byte{first})[:1] + hex.EncodeToString([]byte{last})[1:]
+		}
+	}
+	a := fmt.Sprintf("t%s%s%02d%02d%s", versionName, sni, min(len(h.cipherSuites), 99), min(len(h.extensions), 99), alpn)
+
+	cipherSuites := slices.Clone(h.cipherSuites)
+	slices.Sort(cipherSuites)
+	// the server name and ALPN are in the first part already
+	extensions := make([]uint16, 0, len(h.extensions))
+	for _, e := range h.extensions {
+		if e != extensionServerName && e != extensionALPN {
+			extensions = append(extensions, e)
+		}
+	}
+	slices.Sort(extensions)
+	var signatureAlgorithms string
+	if len(h.signatureAlgorithms) > 0 {
+		s := make([]string, len(h.signatureAlgorithms))
+		for i, v := range h.signatureAlgorithms {
+			s[i] = fmt.Sprintf("%04x", v)
+		}
+		signatureAlgorithms = "_" + strings.Join(s, ",")
+	}
+	return a + "_" + ja4Hash(cipherSuites, "") + "_" + ja4Hash(extensions, signatureAlgorithms), nil
+}
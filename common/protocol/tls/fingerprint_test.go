@@ -0,0 +1,103 @@
+package tls_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+
+	. "github.com/xtls/xray-core/common/protocol/tls"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+type testExtension struct {
+	id   uint16
+	data []byte
+}
+
+// buildClientHello builds a TLS record of a ClientHello like the ones of Chrome.
+func buildClientHello(cipherSuites []uint16, extensions []testExtension) []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(0x16)
+	b.AddUint16(0x0301)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint8(1)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(make([]byte, 32))
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, s := range cipherSuites {
+					b.AddUint16(s)
+				}
+			})
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint8(0)
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, e := range extensions {
+					b.AddUint16(e.id)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddBytes(e.data)
+					})
+				}
+			})
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func TestFingerprint(t *testing.T) {
+	record := buildClientHello(
+		[]uint16{0x5a5a, 0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035},
+		[]testExtension{
+			{0x8a8a, nil},
+			{0x0000, []byte{0x00, 0x0e, 0x00, 0x00, 0x0b, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'}},
+			{0x0017, nil},
+			{0xff01, []byte{0x00}},
+			{0x000a, []byte{0x00, 0x08, 0x9a, 0x9a, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18}},
+			{0x000b, []byte{0x01, 0x00}},
+			{0x0023, nil},
+			{0x0010, []byte{0x00, 0x0c, 0x02, 'h', '2', 0x08, 'h', 't', 't', 'p', '/', '1', '.', '1'}},
+			{0x0005, []byte{0x01, 0x00, 0x00, 0x00, 0x00}},
+			{0x000d, []byte{0x00, 0x10, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03, 0x08, 0x05, 0x05, 0x01, 0x08, 0x06, 0x06, 0x01}},
+			{0x0012, nil},
+			{0x0033, []byte{0x00, 0x00}},
+			{0x002d, []byte{0x01, 0x01}},
+			{0x002b, []byte{0x06, 0x3a, 0x3a, 0x03, 0x04, 0x03, 0x03}},
+			{0x001b, []byte{0x02, 0x00, 0x02}},
+			{0x4469, []byte{0x00, 0x03, 0x02, 'h', '2'}},
+			{0x0a0a, []byte{0x00}},
+			{0x0015, make([]byte, 16)},
+		},
+	)
+
+	// the example of Chrome in the JA4 specification
+	if ja4, err := JA4(record); err != nil || ja4 != "t13d1516h2_8daaf6152771_e5627efa2ab1" {
+		t.Error("unexpected JA4: ", ja4, " ", err)
+	}
+
+	const ja3String = "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53," +
+		"0-23-65281-10-11-35-16-5-13-18-51-45-43-27-17513-21,29-23-24,0"
+	if s, err := JA3String(record); err != nil || s != ja3String {
+		t.Error("unexpected JA3 string: ", s, " ", err)
+	}
+	sum := md5.Sum([]byte(ja3String))
+	if ja3, err := JA3(record); err != nil || ja3 != hex.EncodeToString(sum[:]) {
+		t.Error("unexpected JA3: ", ja3, " ", err)
+	}
+
+	// TLS 1.2 without the server name, ALPN and signature algorithms
+	record = buildClientHello([]uint16{0xc02f, 0x009c}, []testExtension{{0x000a, []byte{0x00, 0x02, 0x00, 0x17}}})
+	if ja4, err := JA4(record); err != nil || ja4[:11] != "t12i020100_" {
+		t.Error("unexpected JA4: ", ja4, " ", err)
+	}
+	if s, err := JA3String(record); err != nil || s != "771,49199-156,10,23," {
+		t.Error("unexpected JA3 string: ", s, " ", err)
+	}
+
+	if _, err := JA4(record[:len(record)-1]); err == nil {
+		t.Error("expected error for a truncated ClientHello")
+	}
+}
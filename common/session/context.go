@@ -26,6 +26,10 @@ const (
 	fullHandlerKey            ctx.SessionKey = 10 // outbound gets full handler
 	mitmAlpn11Key             ctx.SessionKey = 11 // used by TLS dialer
 	mitmServerNameKey         ctx.SessionKey = 12 // used by TLS dialer
+	dialTimingsKey            ctx.SessionKey = 13 // used by RAW dialer to record connect and handshake time
+	loopbackDepthKey          ctx.SessionKey = 14 // used by loopback to detect routing loops
+	authFailureObserverKey    ctx.SessionKey = 15 // used by inbounds to ban the sources that fail their authentication
+	resolveViaKey             ctx.SessionKey = 16 // used by the dialer to detect resolution loops of resolveVia
 )
 
 func ContextWithInbound(ctx context.Context, inbound *Inbound) context.Context {
@@ -192,3 +196,54 @@ func MitmServerNameFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+func ContextWithDialTimings(ctx context.Context, timings *DialTimings) context.Context {
+	return context.WithValue(ctx, dialTimingsKey, timings)
+}
+
+func DialTimingsFromContext(ctx context.Context) *DialTimings {
+	if val, ok := ctx.Value(dialTimingsKey).(*DialTimings); ok {
+		return val
+	}
+	return nil
+}
+
+func ContextWithLoopbackDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, loopbackDepthKey, depth)
+}
+
+// LoopbackDepthFromContext returns how many times the session has been re-dispatched by loopback outbounds.
+func LoopbackDepthFromContext(ctx context.Context) int {
+	if val, ok := ctx.Value(loopbackDepthKey).(int); ok {
+		return val
+	}
+	return 0
+}
+
+// AuthFailureObserver is told of the sources that fail the authentication of an inbound.
+type AuthFailureObserver interface {
+	ObserveAuthFailure(source net.Address)
+}
+
+func ContextWithAuthFailureObserver(ctx context.Context, observer AuthFailureObserver) context.Context {
+	return context.WithValue(ctx, authFailureObserverKey, observer)
+}
+
+func AuthFailureObserverFromContext(ctx context.Context) AuthFailureObserver {
+	if val, ok := ctx.Value(authFailureObserverKey).(AuthFailureObserver); ok {
+		return val
+	}
+	return nil
+}
+
+// ContextWithResolveVia marks ctx as carrying the DNS queries that resolve through the outbound of the tag.
+func ContextWithResolveVia(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, resolveViaKey, tag)
+}
+
+func ResolveViaFromContext(ctx context.Context) string {
+	if val, ok := ctx.Value(resolveViaKey).(string); ok {
+		return val
+	}
+	return ""
+}
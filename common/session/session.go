@@ -4,12 +4,14 @@ package session // import "github.com/xtls/xray-core/common/session"
 import (
 	"context"
 	"math/rand"
+	"time"
 
 	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/features/stats"
 )
 
 // NewID generates a new ID. The generated ID is high likely to be unique, but not cryptographically secure.
@@ -55,6 +57,18 @@ type Inbound struct {
 	// CanSpliceCopy is a property for this connection
 	// 1 = can, 2 = after processing protocol info should be able to, 3 = cannot
 	CanSpliceCopy int
+	// Banned is set if the source is banned by the inbound, that lets it through to its fallbacks only.
+	Banned bool
+}
+
+// DialTimings holds the histograms recording how long the phases of dialing an outbound connection take, in milliseconds.
+type DialTimings struct {
+	// Resolve records the time to resolve the address of the server, when Xray resolves it instead of the system.
+	Resolve stats.Histogram
+	// Connect records the time to establish the underlying connection, including resolving the address.
+	Connect stats.Histogram
+	// Handshake records the time of the TLS or REALITY handshake.
+	Handshake stats.Histogram
 }
 
 // Outbound is the metadata of an outbound connection.
@@ -74,6 +88,15 @@ type Outbound struct {
 	// CanSpliceCopy is a property for this connection
 	// 1 = can, 2 = after processing protocol info should be able to, 3 = cannot
 	CanSpliceCopy int
+	// Resolved are the IPs that the router resolved the domain of the target to. May be nil.
+	Resolved *ResolvedIPs
+}
+
+// ResolvedIPs are the IPs that a domain is resolved to, for dialing them instead of resolving the domain again.
+type ResolvedIPs struct {
+	Domain string
+	IPs    []net.IP
+	At     time.Time
 }
 
 // SniffingRequest controls the behavior of content sniffing. They are from inbound config. Read-only
@@ -83,6 +106,13 @@ type SniffingRequest struct {
 	Enabled                        bool
 	MetadataOnly                   bool
 	RouteOnly                      bool
+	Timeout                        time.Duration
+	BufferSize                     int32
+	UDPPackets                     int
+	// Ports limits sniffing to the connections to these ports. All ports are sniffed if empty.
+	Ports net.MemoryPortList
+	// Resniff sniffs the content again when it is dispatched once more, like by loopback, rather than reusing the results.
+	Resniff bool
 }
 
 // Content is the metadata of the connection content. Mainly used for routing.
@@ -90,6 +120,13 @@ type Content struct {
 	// Protocol of current content.
 	Protocol string
 
+	// Domain is the sniffed domain of the content.
+	Domain string
+
+	// Sniffed is set once the content is sniffed, so that the results are reused when the connection is dispatched
+	// once more, like by loopback or reverse proxy, instead of sniffing it again.
+	Sniffed bool
+
 	SniffingRequest SniffingRequest
 
 	// HTTP traffic sniffed headers
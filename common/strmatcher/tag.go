@@ -0,0 +1,33 @@
+package strmatcher
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NewTagMatcher returns the matcher of an entry that lists the tags of inbounds or outbounds, like inboundTag
+// of routing rules and selector of balancers. An entry prefixed by "regexp:" is a regular expression, and an entry
+// with the wildcards * and ? is a glob of whole tags. The matcher is nil for other entries, which keep matching
+// the way of their field.
+func NewTagMatcher(entry string) (Matcher, error) {
+	if pattern, found := strings.CutPrefix(entry, "regexp:"); found {
+		return Regex.New(pattern)
+	}
+	if !strings.ContainsAny(entry, "*?") {
+		return nil, nil
+	}
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, c := range entry {
+		switch c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return Regex.New(sb.String())
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
@@ -34,11 +35,46 @@ type ConfigBuilder func(files []*ConfigSource) (*Config, error)
 // ConfigsMerger merges multiple json configs into a single one
 type ConfigsMerger func(files []*ConfigSource) (string, error)
 
+// ConfigsChecker checks multiple configs for problems that building them does not catch,
+// warning about certificates that expire within certExpiry.
+type ConfigsChecker func(files []*ConfigSource, certExpiry time.Duration) ([]*ConfigProblem, error)
+
+// ConfigProblem is a problem found in the config, at the JSON path of the setting.
+type ConfigProblem struct {
+	// File is the config file of the problem, if it is found in one of them rather than in the merged config.
+	File    string
+	Path    string
+	Message string
+	// Warning is set for problems that do not stop Xray from running, like a certificate about to expire.
+	Warning bool
+}
+
+func (p *ConfigProblem) String() string {
+	severity := "error"
+	if p.Warning {
+		severity = "warning"
+	}
+	if p.File != "" {
+		return severity + ": " + p.File + ": " + p.Path + ": " + p.Message
+	}
+	return severity + ": " + p.Path + ": " + p.Message
+}
+
 var (
 	configLoaderByName    = make(map[string]*ConfigFormat)
 	configLoaderByExt     = make(map[string]*ConfigFormat)
 	ConfigBuilderForFiles ConfigBuilder
 	ConfigMergedFormFiles ConfigsMerger
+	ConfigCheckerForFiles ConfigsChecker
+
+	// ConfigMergeStrategy is how multiple config files are merged, "override" (the default) or "deep".
+	ConfigMergeStrategy string
+
+	// ConfigNoEnv disables the substitution of ${NAME} with environment variables in config files.
+	ConfigNoEnv bool
+
+	// ConfigStrict fails loading config files with unknown fields, which are ignored otherwise.
+	ConfigStrict bool
 )
 
 // RegisterConfigLoader add a new ConfigLoader.
@@ -75,6 +111,30 @@ func GetMergedConfig(args cmdarg.Arg) (string, error) {
 	return ConfigMergedFormFiles(files)
 }
 
+// CheckConfig checks the json, yaml and toml config files for problems, like missing files,
+// expired certificates and invalid keys, and returns all of them. The formats are found like in LoadConfig.
+func CheckConfig(formatName string, args cmdarg.Arg, certExpiry time.Duration) ([]*ConfigProblem, error) {
+	var files []*ConfigSource
+	supported := []string{"json", "yaml", "toml"}
+	for _, file := range args {
+		format := formatName
+		if formatName == "auto" {
+			if file != "stdin:" {
+				format = getFormat(file)
+			} else {
+				format = "json"
+			}
+		}
+		if slices.Contains(supported, format) {
+			files = append(files, &ConfigSource{
+				Name:   file,
+				Format: format,
+			})
+		}
+	}
+	return ConfigCheckerForFiles(files, certExpiry)
+}
+
 func GetFormatByExtension(ext string) string {
 	switch strings.ToLower(ext) {
 	case "pb", "protobuf":
@@ -0,0 +1,81 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/proxyman"
+	_ "github.com/xtls/xray-core/app/proxyman/outbound"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/transport"
+)
+
+// echoOutbound is an outbound implemented by the embedding application, which sends back what it receives.
+type echoOutbound struct{}
+
+func (echoOutbound) Start() error { return nil }
+
+func (echoOutbound) Close() error { return nil }
+
+func (echoOutbound) Tag() string { return "echo" }
+
+func (echoOutbound) Dispatch(ctx context.Context, link *transport.Link) {
+	buf.Copy(link.Reader, link.Writer)
+	common.Close(link.Writer)
+}
+
+func (echoOutbound) SenderSettings() *serial.TypedMessage { return nil }
+
+func (echoOutbound) ProxySettings() *serial.TypedMessage { return nil }
+
+// quietLogger drops the logs of the instance.
+type quietLogger struct{}
+
+func (quietLogger) Handle(msg log.Message) {}
+
+func ExampleNewWithOptions() {
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+	}
+
+	server, err := core.NewWithOptions(config,
+		core.WithLogger(quietLogger{}),
+		core.WithOutboundHandler(echoOutbound{}),
+	)
+	if err != nil {
+		panic(err)
+	}
+	if err := server.Start(); err != nil {
+		panic(err)
+	}
+	defer server.Close()
+
+	fmt.Println(server.OutboundManager().GetDefaultHandler().Tag())
+
+	conn, err := core.Dial(context.Background(), server, net.TCPDestination(net.DomainAddress("example.com"), 80))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("hello"))
+	b := make([]byte, 5)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		panic(err)
+	}
+	fmt.Println(string(b))
+	// Output:
+	// echo
+	// hello
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/features"
+	"github.com/xtls/xray-core/features/dns"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+// Option customizes an Instance created by NewWithOptions.
+//
+// xray:api:beta
+type Option func(*options)
+
+type options struct {
+	ctx       context.Context
+	features  []features.Feature
+	outbounds []outbound.Handler
+	logger    log.Handler
+}
+
+// WithContext makes ctx the context of the instance, in place of context.Background().
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithFeature registers f before the features of the config. It takes the place of the feature of the config
+// with the same type, if any, or supplements them.
+func WithFeature(f features.Feature) Option {
+	return func(o *options) {
+		o.features = append(o.features, f)
+	}
+}
+
+// WithDNSClient makes the instance resolve domains with c, in place of the DNS app of the config or the local DNS.
+func WithDNSClient(c dns.Client) Option {
+	return WithFeature(c)
+}
+
+// WithOutboundHandler adds h to the outbound handlers of the instance, after the ones of the config.
+func WithOutboundHandler(h outbound.Handler) Option {
+	return func(o *options) {
+		o.outbounds = append(o.outbounds, h)
+	}
+}
+
+// WithLogger makes h the log handler from the creation of the instance on, including after the log app of the
+// config starts. As the log handler is shared by the process, at most one instance should have it; the previous
+// handler is restored when the instance closes.
+func WithLogger(h log.Handler) Option {
+	return func(o *options) {
+		o.logger = h
+	}
+}
+
+// NewWithOptions returns a new Xray instance based on given configuration, customized by opts.
+// The instance is not started at this point.
+//
+// xray:api:beta
+func NewWithOptions(config *Config, opts ...Option) (_ *Instance, err error) {
+	o := &options{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	server := &Instance{ctx: o.ctx, logger: o.logger}
+	if o.logger != nil {
+		previous := log.ReplaceHandler(o.logger)
+		server.previousLogger = previous
+		defer func() {
+			if err != nil {
+				log.ReplaceHandler(previous)
+			}
+		}()
+	}
+	for _, f := range o.features {
+		if err := server.AddFeature(f); err != nil {
+			return nil, err
+		}
+	}
+
+	done, err := initInstanceWithConfig(config, server, o.features)
+	if done {
+		return nil, err
+	}
+
+	if len(o.outbounds) > 0 {
+		outboundManager := server.OutboundManager()
+		if outboundManager == nil {
+			return nil, errors.New("outbound.Manager is not registered in Xray core")
+		}
+		for _, h := range o.outbounds {
+			if err := outboundManager.AddHandler(server.ctx, h); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return server, nil
+}
+
+// overrides returns whether the type of feature is the type of one of presets.
+func overrides(presets []features.Feature, feature features.Feature) bool {
+	t := reflect.TypeOf(feature.Type())
+	if t == nil {
+		return false
+	}
+	for _, f := range presets {
+		if reflect.TypeOf(f.Type()) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// FeatureOf returns the first feature of the instance that is a T. Unlike GetFeature, which looks up the type
+// that the features report, T may be the concrete type of a feature, or any interface that it implements.
+//
+// xray:api:beta
+func FeatureOf[T any](s *Instance) (T, bool) {
+	s.resolveLock.Lock()
+	defer s.resolveLock.Unlock()
+	for _, f := range s.features {
+		if v, ok := f.(T); ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// DNSClient returns the DNS client of the instance.
+func (s *Instance) DNSClient() dns.Client {
+	c, _ := s.GetFeature(dns.ClientType()).(dns.Client)
+	return c
+}
+
+// Router returns the router of the instance.
+func (s *Instance) Router() routing.Router {
+	r, _ := s.GetFeature(routing.RouterType()).(routing.Router)
+	return r
+}
+
+// Dispatcher returns the dispatcher of the instance, or nil if the config has none.
+func (s *Instance) Dispatcher() routing.Dispatcher {
+	d, _ := s.GetFeature(routing.DispatcherType()).(routing.Dispatcher)
+	return d
+}
+
+// InboundManager returns the inbound manager of the instance, or nil if the config has none.
+func (s *Instance) InboundManager() inbound.Manager {
+	m, _ := s.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	return m
+}
+
+// OutboundManager returns the outbound manager of the instance, or nil if the config has none.
+func (s *Instance) OutboundManager() outbound.Manager {
+	m, _ := s.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	return m
+}
+
+// PolicyManager returns the policy manager of the instance.
+func (s *Instance) PolicyManager() policy.Manager {
+	m, _ := s.GetFeature(policy.ManagerType()).(policy.Manager)
+	return m
+}
+
+// StatsManager returns the stats manager of the instance.
+func (s *Instance) StatsManager() stats.Manager {
+	m, _ := s.GetFeature(stats.ManagerType()).(stats.Manager)
+	return m
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/platform"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/features"
@@ -86,6 +88,11 @@ type Instance struct {
 	pendingOptionalResolutions []resolution
 	running                    bool
 	resolveLock                sync.Mutex
+	config                     *Config
+	// logger is the log handler that takes the place of the one of the log app on start
+	logger log.Handler
+	// previousLogger is the log handler before logger, which is restored on close
+	previousLogger log.Handler
 
 	ctx context.Context
 }
@@ -167,7 +174,7 @@ func OptionalFeatures(ctx context.Context, callback interface{}) error {
 func New(config *Config) (*Instance, error) {
 	server := &Instance{ctx: context.Background()}
 
-	done, err := initInstanceWithConfig(config, server)
+	done, err := initInstanceWithConfig(config, server, nil)
 	if done {
 		return nil, err
 	}
@@ -178,7 +185,7 @@ func New(config *Config) (*Instance, error) {
 func NewWithContext(ctx context.Context, config *Config) (*Instance, error) {
 	server := &Instance{ctx: ctx}
 
-	done, err := initInstanceWithConfig(config, server)
+	done, err := initInstanceWithConfig(config, server, nil)
 	if done {
 		return nil, err
 	}
@@ -186,7 +193,10 @@ func NewWithContext(ctx context.Context, config *Config) (*Instance, error) {
 	return server, nil
 }
 
-func initInstanceWithConfig(config *Config, server *Instance) (bool, error) {
+// initInstanceWithConfig initializes server with config, where the apps of the same types as presets, which
+// server has already registered, are left out.
+func initInstanceWithConfig(config *Config, server *Instance, presets []features.Feature) (bool, error) {
+	server.config = config
 	server.ctx = context.WithValue(server.ctx, "cone",
 		platform.NewEnvFlag(platform.UseCone).GetValue(func() string { return "" }) != "true")
 
@@ -200,6 +210,11 @@ func initInstanceWithConfig(config *Config, server *Instance) (bool, error) {
 			return true, err
 		}
 		if feature, ok := obj.(features.Feature); ok {
+			if overrides(presets, feature) {
+				errors.LogDebug(server.ctx, "feature ", featureName(feature), " of the config is overridden")
+				common.Close(feature)
+				continue
+			}
 			if err := server.AddFeature(feature); err != nil {
 				return true, err
 			}
@@ -267,6 +282,9 @@ func (s *Instance) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	if s.logger != nil {
+		log.ReplaceHandler(s.previousLogger)
+	}
 	if len(errs) > 0 {
 		return errors.New("failed to close all features").Base(errors.New(serial.Concat(errs...)))
 	}
@@ -315,6 +333,77 @@ func (s *Instance) RequireFeatures(callback interface{}, optional bool) error {
 	}
 }
 
+// startOrder returns the features in registration order, except that each feature is
+// preceded by the features it declares in features.HasStartDependencies.
+func (s *Instance) startOrder() []features.Feature {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[features.Feature]int, len(s.features))
+	order := make([]features.Feature, 0, len(s.features))
+
+	var visit func(f features.Feature)
+	visit = func(f features.Feature) {
+		switch state[f] {
+		case visited:
+			return
+		case visiting:
+			errors.LogWarning(s.ctx, "circular start dependency on feature ", featureName(f))
+			return
+		}
+		state[f] = visiting
+		if d, ok := f.(features.HasStartDependencies); ok {
+			for _, t := range d.StartDependencies() {
+				if dep := getFeature(s.features, reflect.TypeOf(t)); dep != nil {
+					visit(dep)
+				}
+			}
+		}
+		state[f] = visited
+		order = append(order, f)
+	}
+
+	for _, f := range s.features {
+		visit(f)
+	}
+	return order
+}
+
+// startFeature starts the given feature, and logs a warning if it does not return within timeout.
+func (s *Instance) startFeature(f features.Feature, timeout time.Duration) error {
+	if timeout <= 0 {
+		return f.Start()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Start()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		errors.LogWarning(s.ctx, "feature ", featureName(f), " has not started in ", timeout, ", still waiting")
+	}
+	return <-done
+}
+
+func featureName(f features.Feature) string {
+	t := reflect.TypeOf(f.Type())
+	if t == nil {
+		return reflect.TypeOf(f).String()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
 // AddFeature registers a feature into current Instance.
 func (s *Instance) AddFeature(feature features.Feature) error {
 	if s.running {
@@ -372,6 +461,12 @@ func (s *Instance) AddFeature(feature features.Feature) error {
 	return err
 }
 
+// Config returns the config the instance was created with.
+// It does not reflect the changes made at runtime, like handlers added through the API.
+func (s *Instance) Config() *Config {
+	return s.config
+}
+
 // GetFeature returns a feature of the given type, or nil if such feature is not registered.
 func (s *Instance) GetFeature(featureType interface{}) features.Feature {
 	return getFeature(s.features, reflect.TypeOf(featureType))
@@ -386,12 +481,18 @@ func (s *Instance) Start() error {
 	defer s.statusLock.Unlock()
 
 	s.running = true
-	for _, f := range s.features {
-		if err := f.Start(); err != nil {
+	timeout := time.Duration(platform.NewEnvFlag(platform.FeatureStartTimeout).GetValueAsInt(10)) * time.Second
+	for _, f := range s.startOrder() {
+		errors.LogDebug(s.ctx, "starting feature ", featureName(f))
+		if err := s.startFeature(f, timeout); err != nil {
 			return err
 		}
 	}
 
+	if s.logger != nil {
+		log.RegisterHandler(s.logger)
+	}
+
 	errors.LogWarning(s.ctx, "Xray ", Version(), " started")
 
 	return nil
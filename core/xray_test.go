@@ -4,8 +4,10 @@ import (
 	"testing"
 
 	"github.com/xtls/xray-core/app/dispatcher"
+	appdns "github.com/xtls/xray-core/app/dns"
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
@@ -84,3 +86,103 @@ func TestXrayClose(t *testing.T) {
 	common.Must(err)
 	server.Close()
 }
+
+type orderedFeature struct {
+	typ     interface{}
+	deps    []interface{}
+	started *[]interface{}
+}
+
+func (f *orderedFeature) Type() interface{}                { return f.typ }
+func (f *orderedFeature) StartDependencies() []interface{} { return f.deps }
+func (f *orderedFeature) Close() error                     { return nil }
+func (f *orderedFeature) Start() error {
+	*f.started = append(*f.started, f.typ)
+	return nil
+}
+
+type (
+	featureA struct{}
+	featureB struct{}
+)
+
+func TestXrayStartDependencies(t *testing.T) {
+	server, err := New(&Config{})
+	common.Must(err)
+
+	var started []interface{}
+	common.Must(server.AddFeature(&orderedFeature{typ: (*featureA)(nil), deps: []interface{}{(*featureB)(nil)}, started: &started}))
+	common.Must(server.AddFeature(&orderedFeature{typ: (*featureB)(nil), started: &started}))
+	common.Must(server.Start())
+	defer server.Close()
+
+	if len(started) != 2 || started[0] != (*featureB)(nil) || started[1] != (*featureA)(nil) {
+		t.Error("unexpected start order: ", started)
+	}
+}
+
+func TestXrayWithFeature(t *testing.T) {
+	config := &Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&appdns.Config{}),
+		},
+	}
+
+	client := localdns.New()
+	server, err := NewWithOptions(config, WithDNSClient(client))
+	common.Must(err)
+	defer server.Close()
+
+	if server.DNSClient() != client {
+		t.Error("expected the DNS client of the options, but got ", server.DNSClient())
+	}
+	if _, found := FeatureOf[*appdns.DNS](server); found {
+		t.Error("expected the DNS app of the config to be overridden")
+	}
+	if c, found := FeatureOf[*localdns.Client](server); !found || c != client {
+		t.Error("expected to find the DNS client by its type")
+	}
+	if server.Dispatcher() == nil || server.InboundManager() == nil || server.OutboundManager() == nil {
+		t.Error("expected the features of the config")
+	}
+}
+
+type countingLogger struct {
+	count int
+}
+
+func (l *countingLogger) Handle(msg log.Message) {
+	l.count++
+}
+
+func TestXrayWithLoggerRestored(t *testing.T) {
+	config := &Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+	}
+
+	previous := &countingLogger{}
+	log.RegisterHandler(previous)
+	defer log.ReplaceHandler(nil)
+
+	logger := &countingLogger{}
+	server, err := NewWithOptions(config, WithLogger(logger))
+	common.Must(err)
+	common.Must(server.Start())
+	log.Record(&log.GeneralMessage{Severity: log.Severity_Info, Content: "running"})
+	common.Must(server.Close())
+	log.Record(&log.GeneralMessage{Severity: log.Severity_Info, Content: "closed"})
+
+	if logger.count == 0 {
+		t.Error("expected the logs of the running instance in its logger")
+	}
+	if previous.count != 1 {
+		t.Error("expected the previous handler to be restored on close, but it got ", previous.count, " logs")
+	}
+}
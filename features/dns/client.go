@@ -12,6 +12,9 @@ type IPOption struct {
 	IPv4Enable bool
 	IPv6Enable bool
 	FakeEnable bool
+	// IgnoreQueryStrategy skips the queryStrategy of the DNS app, for callers with an IP family preference
+	// of their own. The queryStrategy of each name server still applies.
+	IgnoreQueryStrategy bool
 }
 
 // Client is a Xray feature for querying DNS information.
@@ -24,6 +27,17 @@ type Client interface {
 	LookupIP(domain string, option IPOption) ([]net.IP, uint32, error)
 }
 
+// ViaClient is a Client whose name servers can send their queries through an outbound.
+//
+// xray:api:beta
+type ViaClient interface {
+	Client
+
+	// Via returns the Client whose name servers send their queries through the outbound of the tag,
+	// regardless of the routing.
+	Via(tag string) (Client, error)
+}
+
 // ClientType returns the type of Client interface. Can be used for implementing common.HasType.
 //
 // xray:api:beta
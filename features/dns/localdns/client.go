@@ -1,12 +1,16 @@
 package localdns
 
 import (
+	"context"
+
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/features/dns"
 )
 
 // Client is an implementation of dns.Client, which queries localhost for DNS.
-type Client struct{}
+type Client struct {
+	resolver *net.Resolver
+}
 
 // Type implements common.HasType.
 func (*Client) Type() interface{} {
@@ -20,8 +24,18 @@ func (*Client) Start() error { return nil }
 func (*Client) Close() error { return nil }
 
 // LookupIP implements Client.
-func (*Client) LookupIP(host string, option dns.IPOption) ([]net.IP, uint32, error) {
-	ips, err := net.LookupIP(host)
+func (c *Client) LookupIP(host string, option dns.IPOption) ([]net.IP, uint32, error) {
+	return c.LookupIPContext(context.Background(), host, option)
+}
+
+// LookupIPContext is LookupIP that gives up when ctx is done.
+func (c *Client) LookupIPContext(ctx context.Context, host string, option dns.IPOption) ([]net.IP, uint32, error) {
+	resolver := c.resolver
+	if resolver == nil {
+		// the zero resolver is the one of the system
+		resolver = &net.Resolver{}
+	}
+	ips, err := resolver.LookupIP(ctx, "ip", host)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -64,3 +78,9 @@ func (*Client) LookupIP(host string, option dns.IPOption) ([]net.IP, uint32, err
 func New() *Client {
 	return &Client{}
 }
+
+// NewWithResolver creates a dns.Client that looks up names with the resolver, such as one that sends
+// the queries to the name servers of the system through a given interface.
+func NewWithResolver(resolver *net.Resolver) *Client {
+	return &Client{resolver: resolver}
+}
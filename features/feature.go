@@ -10,3 +10,9 @@ type Feature interface {
 	common.HasType
 	common.Runnable
 }
+
+// HasStartDependencies is implemented by features that must be started after some other features.
+type HasStartDependencies interface {
+	// StartDependencies returns the types of the features that must be started first.
+	StartDependencies() []interface{}
+}
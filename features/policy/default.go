@@ -17,6 +17,7 @@ func (DefaultManager) ForLevel(level uint32) Session {
 	p := SessionDefault()
 	if level == 1 {
 		p.Timeouts.ConnectionIdle = time.Second * 600
+		p.Timeouts.UDPIdle = time.Second * 600
 	}
 	return p
 }
@@ -19,6 +19,8 @@ type Timeout struct {
 	UplinkOnly time.Duration
 	// Timeout for an downlink only connection, i.e., the uplink of the connection has been closed.
 	DownlinkOnly time.Duration
+	// Timeout for an UDP session being idle, i.e., there is no egress or ingress packet in this session.
+	UDPIdle time.Duration
 }
 
 // Stats contains settings for stats counters.
@@ -35,6 +37,22 @@ type Stats struct {
 type Buffer struct {
 	// Size of buffer per connection, in bytes. -1 for unlimited buffer.
 	PerConnection int32
+	// Whether the size is scaled down when the process is under memory pressure.
+	Adaptive bool
+	// Queued bytes at which pipe writers block, in place of PerConnection. 0 for none.
+	HighWatermark int32
+	// Queued bytes a blocked pipe must drain to before writers resume.
+	LowWatermark int32
+}
+
+// RateLimit contains settings for bandwidth throttling.
+type RateLimit struct {
+	// Whether the limit is shared among all connections of the same user, instead of per connection.
+	PerUser bool
+	// Maximum uplink rate, in bytes per second. 0 for unlimited.
+	Uplink uint64
+	// Maximum downlink rate, in bytes per second. 0 for unlimited.
+	Downlink uint64
 }
 
 // SystemStats contains stat policy settings on system level.
@@ -47,19 +65,37 @@ type SystemStats struct {
 	OutboundUplink bool
 	// Whether or not to enable stat counter for downlink traffic in outbound handlers.
 	OutboundDownlink bool
+	// Whether or not to enable stat counter for traffic per destination host.
+	HostTraffic bool
+	// Maximum number of hosts with traffic counters, the least recently used ones are dropped beyond it.
+	HostTrafficLimit uint32
+	// Whether or not to enable histograms of connect time, handshake time and session duration in outbound handlers.
+	OutboundHistogram bool
+	// Whether or not to enable stat counter for traffic per routing rule with a rule tag.
+	RuleTraffic bool
+}
+
+// Security contains the settings that the TLS settings of all handlers must comply with.
+type Security struct {
+	// Lowest TLS version allowed, like "1.2". Empty for any.
+	MinTLSVersion string
+	// Whether or not to reject allowInsecure and the insecure cipher suites.
+	ForbidInsecure bool
 }
 
 // System contains policy settings at system level.
 type System struct {
-	Stats  SystemStats
-	Buffer Buffer
+	Stats    SystemStats
+	Buffer   Buffer
+	Security Security
 }
 
 // Session is session based settings for controlling Xray requests. It contains various settings (or limits) that may differ for different users in the context.
 type Session struct {
-	Timeouts Timeout // Timeout settings
-	Stats    Stats
-	Buffer   Buffer
+	Timeouts  Timeout // Timeout settings
+	Stats     Stats
+	Buffer    Buffer
+	RateLimit RateLimit
 }
 
 // Manager is a feature that provides Policy for the given user by its id or level.
@@ -121,6 +157,7 @@ func SessionDefault() Session {
 			ConnectionIdle: time.Second * 300,
 			UplinkOnly:     time.Second * 1,
 			DownlinkOnly:   time.Second * 1,
+			UDPIdle:        time.Second * 300,
 		},
 		Stats: Stats{
 			UserUplink:   false,
@@ -49,4 +49,7 @@ type Context interface {
 
 	// GetSkipDNSResolve returns a flag switch for weather skip dns resolve during route pick.
 	GetSkipDNSResolve() bool
+
+	// GetSessionID returns the ID of the session, as shown in the logs, if exists.
+	GetSessionID() uint32
 }
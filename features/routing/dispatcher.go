@@ -32,3 +32,10 @@ type WrapLinkDispatcher interface {
 	Dispatcher
 	WrapLink(ctx context.Context, link *transport.Link) *transport.Link
 }
+
+// TrackLinkDispatcher is a Dispatcher that lists the links served outside of it, like the mux connections.
+// TrackLink returns the context and link to serve instead, and a function to call once the link ends.
+type TrackLinkDispatcher interface {
+	Dispatcher
+	TrackLink(ctx context.Context, link *transport.Link, destination net.Destination) (context.Context, *transport.Link, func())
+}
@@ -15,6 +15,12 @@ type ResolvableContext struct {
 	dnsClient dns.Client
 	cacheIPs  []net.IP
 	hasError  bool
+	keepIPs   bool
+}
+
+// ResolvedIPsKeeper is a routing.Context that keeps the IPs its domain is resolved to, for the outbound to dial.
+type ResolvedIPsKeeper interface {
+	KeepResolvedIPs(domain string, ips []net.IP)
 }
 
 // GetTargetIPs overrides original routing.Context's implementation.
@@ -35,6 +41,9 @@ func (ctx *ResolvableContext) GetTargetIPs() []net.IP {
 		})
 		if err == nil {
 			ctx.cacheIPs = ips
+			if keeper, ok := ctx.Context.(ResolvedIPsKeeper); ok && ctx.keepIPs {
+				keeper.KeepResolvedIPs(domain, ips)
+			}
 			return ips
 		}
 		errors.LogInfoInner(context.Background(), err, "resolve ip for ", domain)
@@ -54,3 +63,9 @@ func (ctx *ResolvableContext) GetTargetIPs() []net.IP {
 func ContextWithDNSClient(ctx routing.Context, client dns.Client) routing.Context {
 	return &ResolvableContext{Context: ctx, dnsClient: client}
 }
+
+// ContextWithDNSClientKeepingIPs is ContextWithDNSClient, and the resolved domain IPs are kept by ctx
+// if it is a ResolvedIPsKeeper.
+func ContextWithDNSClientKeepingIPs(ctx routing.Context, client dns.Client) routing.Context {
+	return &ResolvableContext{Context: ctx, dnsClient: client, keepIPs: true}
+}
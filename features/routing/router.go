@@ -18,6 +18,13 @@ type Router interface {
 	RemoveRule(tag string) error
 }
 
+// AttributeRouter is a Router that tells the attributes of the content its rules match, so that the attributes costly
+// to compute, like the TLS fingerprints, are computed only if some rule needs them.
+type AttributeRouter interface {
+	// MatchesAttribute returns whether any rule matches the attribute of the given name.
+	MatchesAttribute(name string) bool
+}
+
 // Route is the routing result of Router feature.
 //
 // xray:api:stable
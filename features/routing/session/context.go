@@ -2,7 +2,9 @@ package session
 
 import (
 	"context"
+	"time"
 
+	c "github.com/xtls/xray-core/common/ctx"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/features/routing"
@@ -10,9 +12,10 @@ import (
 
 // Context is an implementation of routing.Context, which is a wrapper of context.context with session info.
 type Context struct {
-	Inbound  *session.Inbound
-	Outbound *session.Outbound
-	Content  *session.Content
+	Inbound   *session.Inbound
+	Outbound  *session.Outbound
+	Content   *session.Content
+	SessionID uint32
 }
 
 // GetInboundTag implements routing.Context.
@@ -152,13 +155,26 @@ func (ctx *Context) GetSkipDNSResolve() bool {
 	return ctx.Content.SkipDNSResolve
 }
 
+// GetSessionID implements routing.Context.
+func (ctx *Context) GetSessionID() uint32 {
+	return ctx.SessionID
+}
+
+// KeepResolvedIPs implements routing_dns.ResolvedIPsKeeper.
+func (ctx *Context) KeepResolvedIPs(domain string, ips []net.IP) {
+	if ctx.Outbound != nil {
+		ctx.Outbound.Resolved = &session.ResolvedIPs{Domain: domain, IPs: ips, At: time.Now()}
+	}
+}
+
 // AsRoutingContext creates a context from context.context with session info.
 func AsRoutingContext(ctx context.Context) routing.Context {
 	outbounds := session.OutboundsFromContext(ctx)
 	ob := outbounds[len(outbounds)-1]
 	return &Context{
-		Inbound:  session.InboundFromContext(ctx),
-		Outbound: ob,
-		Content:  session.ContentFromContext(ctx),
+		Inbound:   session.InboundFromContext(ctx),
+		Outbound:  ob,
+		Content:   session.ContentFromContext(ctx),
+		SessionID: uint32(c.IDFromContext(ctx)),
 	}
 }
@@ -0,0 +1,60 @@
+package stats
+
+// Resources are what the sessions of a handler hold at a time.
+type Resources struct {
+	TCPSessions   int64
+	UDPNATEntries int64
+	MuxStreams    int64
+	// PipeBytes are the bytes buffered in the pipes between the inbounds and the outbounds.
+	PipeBytes int64
+}
+
+// ResourceReport collects the resources of the inbound and outbound handlers, by tag.
+type ResourceReport struct {
+	Inbounds  map[string]*Resources
+	Outbounds map[string]*Resources
+}
+
+// NewResourceReport returns an empty report.
+func NewResourceReport() *ResourceReport {
+	return &ResourceReport{
+		Inbounds:  make(map[string]*Resources),
+		Outbounds: make(map[string]*Resources),
+	}
+}
+
+// Inbound returns the resources of the inbound with the given tag, adding them if missing.
+func (r *ResourceReport) Inbound(tag string) *Resources {
+	return getOrAdd(r.Inbounds, tag)
+}
+
+// Outbound returns the resources of the outbound with the given tag, adding them if missing.
+func (r *ResourceReport) Outbound(tag string) *Resources {
+	return getOrAdd(r.Outbounds, tag)
+}
+
+func getOrAdd(m map[string]*Resources, tag string) *Resources {
+	res, found := m[tag]
+	if !found {
+		res = new(Resources)
+		m[tag] = res
+	}
+	return res
+}
+
+// ResourceReporter is implemented by the structures that own the resources of sessions,
+// such as the dispatcher, the UDP workers of inbounds and the mux managers.
+//
+// xray:api:beta
+type ResourceReporter interface {
+	// ReportResources adds the resources held at the moment to the report.
+	ReportResources(report *ResourceReport)
+}
+
+// IdleCollector is implemented by the structures that expire idle sessions on their own from time to time.
+//
+// xray:api:beta
+type IdleCollector interface {
+	// CollectIdle runs the expiry scan now, and returns the number of sessions closed by it.
+	CollectIdle() int
+}
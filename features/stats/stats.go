@@ -6,6 +6,7 @@ import (
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
 	"github.com/xtls/xray-core/features"
 )
 
@@ -35,6 +36,18 @@ type OnlineMap interface {
 	IpTimeMap() map[string]time.Time
 }
 
+// Histogram is the interface for stats histograms, which count values into buckets of fixed upper bounds.
+type Histogram interface {
+	// Observe counts a value into the first bucket whose bound is not less than the value.
+	Observe(int64)
+	// Bounds returns the upper bounds of the buckets in ascending order. Values above the last bound are counted in an extra bucket.
+	Bounds() []int64
+	// Counts returns the count of each bucket, including the extra one, and the sum of all values.
+	Counts() ([]int64, int64)
+	// Reset sets all counts to zero, and returns the previous ones like Counts.
+	Reset() ([]int64, int64)
+}
+
 // Channel is the interface for stats channel.
 //
 // xray:api:stable
@@ -98,6 +111,21 @@ type Manager interface {
 	UnregisterChannel(string) error
 	// GetChannel returns a channel by its identifier.
 	GetChannel(string) Channel
+
+	// RegisterHistogram registers a new histogram to the manager. The identifier string must not be empty, and unique among other histograms.
+	RegisterHistogram(string) (Histogram, error)
+	// UnregisterHistogram unregisters a histogram from the manager by its identifier.
+	UnregisterHistogram(string) error
+	// GetHistogram returns a histogram by its identifier.
+	GetHistogram(string) Histogram
+}
+
+// AuthFailureFeed is implemented by the Managers that stream the authentication failures of the inbounds.
+type AuthFailureFeed interface {
+	// PublishAuthFailure sends the event to the subscribers. Subscribers that are behind miss it.
+	PublishAuthFailure(*log.AuthFailureMessage)
+	// SubscribeAuthFailures returns the channel of the events, and the function that stops it.
+	SubscribeAuthFailures() (<-chan *log.AuthFailureMessage, func())
 }
 
 // GetOrRegisterCounter tries to get the StatCounter first. If not exist, it then tries to create a new counter.
@@ -130,6 +158,16 @@ func GetOrRegisterChannel(m Manager, name string) (Channel, error) {
 	return m.RegisterChannel(name)
 }
 
+// GetOrRegisterHistogram tries to get the Histogram first. If not exist, it then tries to create a new histogram.
+func GetOrRegisterHistogram(m Manager, name string) (Histogram, error) {
+	histogram := m.GetHistogram(name)
+	if histogram != nil {
+		return histogram, nil
+	}
+
+	return m.RegisterHistogram(name)
+}
+
 // ManagerType returns the type of Manager interface. Can be used to implement common.HasType.
 //
 // xray:api:stable
@@ -190,6 +228,21 @@ func (NoopManager) GetChannel(string) Channel {
 	return nil
 }
 
+// RegisterHistogram implements Manager.
+func (NoopManager) RegisterHistogram(string) (Histogram, error) {
+	return nil, errors.New("not implemented")
+}
+
+// UnregisterHistogram implements Manager.
+func (NoopManager) UnregisterHistogram(string) error {
+	return nil
+}
+
+// GetHistogram implements Manager.
+func (NoopManager) GetHistogram(string) Histogram {
+	return nil
+}
+
 // Start implements common.Runnable.
 func (NoopManager) Start() error { return nil }
 
@@ -4,9 +4,11 @@ import (
 	"strings"
 
 	"github.com/xtls/xray-core/app/commander"
+	connectionservice "github.com/xtls/xray-core/app/dispatcher/command"
 	loggerservice "github.com/xtls/xray-core/app/log/command"
 	observatoryservice "github.com/xtls/xray-core/app/observatory/command"
 	handlerservice "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/app/reload"
 	routerservice "github.com/xtls/xray-core/app/router/command"
 	statsservice "github.com/xtls/xray-core/app/stats/command"
 	"github.com/xtls/xray-core/common/errors"
@@ -39,6 +41,10 @@ func (c *APIConfig) Build() (*commander.Config, error) {
 			services = append(services, serial.ToTypedMessage(&observatoryservice.Config{}))
 		case "routingservice":
 			services = append(services, serial.ToTypedMessage(&routerservice.Config{}))
+		case "connectionservice":
+			services = append(services, serial.ToTypedMessage(&connectionservice.Config{}))
+		case "reloadservice":
+			services = append(services, serial.ToTypedMessage(&reload.Config{}))
 		}
 	}
 
@@ -15,14 +15,59 @@ func (*NoneResponse) Build() (proto.Message, error) {
 	return new(blackhole.NoneResponse), nil
 }
 
-type HTTPResponse struct{}
+type HTTPResponse struct {
+	Status  uint32            `json:"status"`
+	Body    string            `json:"body"`
+	Headers map[string]string `json:"headers"`
+}
+
+func (v *HTTPResponse) Build() (proto.Message, error) {
+	if v.Status != 0 && (v.Status < 100 || v.Status > 999) {
+		return nil, errors.New("invalid HTTP status code: ", v.Status)
+	}
+	return &blackhole.HTTPResponse{
+		Status: v.Status,
+		Body:   v.Body,
+		Header: v.Headers,
+	}, nil
+}
+
+type ResetResponse struct{}
 
-func (*HTTPResponse) Build() (proto.Message, error) {
-	return new(blackhole.HTTPResponse), nil
+func (*ResetResponse) Build() (proto.Message, error) {
+	return new(blackhole.ResetResponse), nil
+}
+
+type TLSAlertResponse struct {
+	Alert uint32 `json:"alert"`
+}
+
+func (v *TLSAlertResponse) Build() (proto.Message, error) {
+	if v.Alert > 255 {
+		return nil, errors.New("invalid TLS alert: ", v.Alert)
+	}
+	return &blackhole.TLSAlertResponse{Alert: v.Alert}, nil
+}
+
+type DropLingerResponse struct {
+	Linger uint32 `json:"linger"`
+}
+
+func (v *DropLingerResponse) Build() (proto.Message, error) {
+	linger := v.Linger
+	if linger == 0 {
+		linger = 30
+	}
+	return &blackhole.DropLingerResponse{Linger: linger}, nil
 }
 
 type BlackholeConfig struct {
-	Response json.RawMessage `json:"response"`
+	Response    json.RawMessage `json:"response"`
+	UDPResponse string          `json:"udpResponse"`
+}
+
+func (v *BlackholeConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"response": configLoader.strictConfig(v.Response, "")}
 }
 
 func (v *BlackholeConfig) Build() (proto.Message, error) {
@@ -39,13 +84,24 @@ func (v *BlackholeConfig) Build() (proto.Message, error) {
 		config.Response = serial.ToTypedMessage(responseSettings)
 	}
 
+	switch v.UDPResponse {
+	case "", "drop":
+	case "empty":
+		config.UdpEmptyReply = true
+	default:
+		return nil, errors.New("unknown UDP response: ", v.UDPResponse)
+	}
+
 	return config, nil
 }
 
 var configLoader = NewJSONConfigLoader(
 	ConfigCreatorCache{
-		"none": func() interface{} { return new(NoneResponse) },
-		"http": func() interface{} { return new(HTTPResponse) },
+		"none":        func() interface{} { return new(NoneResponse) },
+		"http":        func() interface{} { return new(HTTPResponse) },
+		"reset":       func() interface{} { return new(ResetResponse) },
+		"tls":         func() interface{} { return new(TLSAlertResponse) },
+		"drop-linger": func() interface{} { return new(DropLingerResponse) },
 	},
 	"type",
 	"")
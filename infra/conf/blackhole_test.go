@@ -25,6 +25,62 @@ func TestHTTPResponseJSON(t *testing.T) {
 				Response: serial.ToTypedMessage(&blackhole.HTTPResponse{}),
 			},
 		},
+		{
+			Input: `{
+				"response": {
+					"type": "http",
+					"status": 404,
+					"body": "not here",
+					"headers": {
+						"Server": "nginx"
+					}
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &blackhole.Config{
+				Response: serial.ToTypedMessage(&blackhole.HTTPResponse{
+					Status: 404,
+					Body:   "not here",
+					Header: map[string]string{"Server": "nginx"},
+				}),
+			},
+		},
+		{
+			Input: `{
+				"response": {
+					"type": "reset"
+				},
+				"udpResponse": "empty"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &blackhole.Config{
+				Response:      serial.ToTypedMessage(&blackhole.ResetResponse{}),
+				UdpEmptyReply: true,
+			},
+		},
+		{
+			Input: `{
+				"response": {
+					"type": "tls",
+					"alert": 112
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &blackhole.Config{
+				Response: serial.ToTypedMessage(&blackhole.TLSAlertResponse{Alert: 112}),
+			},
+		},
+		{
+			Input: `{
+				"response": {
+					"type": "drop-linger"
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &blackhole.Config{
+				Response: serial.ToTypedMessage(&blackhole.DropLingerResponse{Linger: 30}),
+			},
+		},
 		{
 			Input:  `{}`,
 			Parser: loadJSON(creator),
@@ -0,0 +1,353 @@
+package conf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform/filesystem"
+	"github.com/xtls/xray-core/core"
+)
+
+// Check looks for the problems of the config that building it does not catch, or that it stops at the first of,
+// like expired certificates, invalid keys and missing geo data, and returns all of them.
+// Certificates that expire within certExpiry are warned about.
+func (c *Config) Check(certExpiry time.Duration) []*core.ConfigProblem {
+	k := &checker{
+		now:        time.Now(),
+		certExpiry: certExpiry,
+		assets:     make(map[string]asset),
+	}
+	for i, ib := range c.InboundConfigs {
+		path := fmt.Sprint("inbounds[", i, "]")
+		k.checkSettings(path, ib.Protocol, ib.Settings)
+		k.checkStream(path+".streamSettings", ib.StreamSetting)
+	}
+	for i, ob := range c.OutboundConfigs {
+		path := fmt.Sprint("outbounds[", i, "]")
+		k.checkSettings(path, ob.Protocol, ob.Settings)
+		k.checkStream(path+".streamSettings", ob.StreamSetting)
+	}
+	if c.RouterConfig != nil {
+		for i, raw := range c.RouterConfig.RuleList {
+			k.checkRule(fmt.Sprint("routing.rules[", i, "]"), raw)
+		}
+	}
+	if c.DNSConfig != nil {
+		for i, ns := range c.DNSConfig.Servers {
+			if ns == nil {
+				continue
+			}
+			path := fmt.Sprint("dns.servers[", i, "]")
+			k.checkDomains(path+".domains", ns.Domains)
+			k.checkIPs(path+".expectedIPs", ns.ExpectedIPs)
+			k.checkIPs(path+".expectIPs", ns.ExpectIPs)
+			k.checkIPs(path+".unexpectedIPs", ns.UnexpectedIPs)
+		}
+	}
+	return k.problems
+}
+
+type asset struct {
+	data []byte
+	err  error
+}
+
+type checker struct {
+	now        time.Time
+	certExpiry time.Duration
+	// assets caches the geo data files, which are referenced by many rules
+	assets   map[string]asset
+	problems []*core.ConfigProblem
+}
+
+func (k *checker) error(path string, message ...interface{}) {
+	k.problems = append(k.problems, &core.ConfigProblem{Path: path, Message: fmt.Sprint(message...)})
+}
+
+func (k *checker) warning(path string, message ...interface{}) {
+	k.problems = append(k.problems, &core.ConfigProblem{Path: path, Message: fmt.Sprint(message...), Warning: true})
+}
+
+func (k *checker) checkSettings(path, protocol string, settings *json.RawMessage) {
+	if settings == nil || strings.ToLower(protocol) != "wireguard" {
+		return
+	}
+	wg := new(WireGuardConfig)
+	if json.Unmarshal(*settings, wg) != nil {
+		return // reported by Build
+	}
+	path += ".settings"
+	k.checkWireGuardKey(path+".secretKey", wg.SecretKey)
+	for i, peer := range wg.Peers {
+		if peer == nil {
+			continue
+		}
+		peerPath := fmt.Sprint(path, ".peers[", i, "]")
+		if peer.PublicKey != "" {
+			k.checkWireGuardKey(peerPath+".publicKey", peer.PublicKey)
+		}
+		if peer.PreSharedKey != "" {
+			k.checkWireGuardKey(peerPath+".preSharedKey", peer.PreSharedKey)
+		}
+	}
+}
+
+// checkWireGuardKey checks that key is 32 bytes in hex or base64, which ParseWireGuardKey does not check the length of.
+func (k *checker) checkWireGuardKey(path, key string) {
+	hexKey, err := ParseWireGuardKey(key)
+	if err != nil {
+		k.error(path, "invalid WireGuard key: ", err)
+		return
+	}
+	if len(hexKey) != 64 {
+		k.error(path, "should be 32 bytes, not ", len(hexKey)/2)
+	}
+}
+
+func (k *checker) checkStream(path string, stream *StreamConfig) {
+	if stream == nil {
+		return
+	}
+	if stream.TLSSettings != nil {
+		k.checkTLS(path+".tlsSettings", stream.TLSSettings)
+	}
+	if stream.REALITYSettings != nil {
+		k.checkREALITY(path+".realitySettings", stream.REALITYSettings)
+	}
+	if stream.XHTTPSettings != nil {
+		k.checkStream(path+".xhttpSettings.downloadSettings", stream.XHTTPSettings.DownloadSettings)
+	}
+	if stream.SplitHTTPSettings != nil {
+		k.checkStream(path+".splithttpSettings.downloadSettings", stream.SplitHTTPSettings.DownloadSettings)
+	}
+}
+
+func (k *checker) checkTLS(path string, config *TLSConfig) {
+	for i, cert := range config.Certs {
+		if cert != nil {
+			k.checkCertificate(fmt.Sprint(path, ".certificates[", i, "]"), cert)
+		}
+	}
+	if config.ECHServerKeys != "" {
+		if _, err := base64.StdEncoding.DecodeString(config.ECHServerKeys); err != nil {
+			k.error(path+".echServerKeys", "invalid base64: ", err)
+		}
+	}
+}
+
+func (k *checker) checkCertificate(path string, config *TLSCertConfig) {
+	certPath, keyPath := path+".certificate", path+".key"
+	if config.CertFile != "" {
+		certPath = path + ".certificateFile"
+	}
+	if config.KeyFile != "" {
+		keyPath = path + ".keyFile"
+	}
+
+	certPEM, err := readFileOrString(config.CertFile, config.CertStr)
+	if err != nil {
+		k.error(certPath, "failed to read certificate: ", err)
+		return
+	}
+	var certs []*x509.Certificate
+	for rest := certPEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			k.error(certPath, "invalid certificate: ", err)
+			return
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		k.error(certPath, "no PEM certificate found")
+		return
+	}
+	for _, cert := range certs {
+		name := cert.Subject.CommonName
+		if name == "" && len(cert.DNSNames) > 0 {
+			name = cert.DNSNames[0]
+		}
+		switch {
+		case k.now.After(cert.NotAfter):
+			k.error(certPath, "certificate ", name, " expired at ", cert.NotAfter.Format(time.RFC3339))
+		case k.now.Before(cert.NotBefore):
+			k.warning(certPath, "certificate ", name, " is not valid before ", cert.NotBefore.Format(time.RFC3339))
+		case cert.NotAfter.Sub(k.now) < k.certExpiry:
+			k.warning(certPath, "certificate ", name, " expires at ", cert.NotAfter.Format(time.RFC3339))
+		}
+	}
+
+	if config.KeyFile == "" && len(config.KeyStr) == 0 {
+		if strings.ToLower(config.Usage) != "verify" {
+			k.error(keyPath, "no key for the certificate")
+		}
+		return
+	}
+	keyPEM, err := readFileOrString(config.KeyFile, config.KeyStr)
+	if err != nil {
+		k.error(keyPath, "failed to read key: ", err)
+		return
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		k.error(keyPath, "invalid key pair: ", err)
+	}
+}
+
+func (k *checker) checkREALITY(path string, config *REALITYConfig) {
+	if config.Target != nil || config.Dest != nil {
+		k.checkKey(path+".privateKey", config.PrivateKey, 32)
+		if len(config.ShortIds) == 0 {
+			k.error(path+".shortIds", "empty")
+		}
+		for i, id := range config.ShortIds {
+			k.checkShortId(fmt.Sprint(path, ".shortIds[", i, "]"), id)
+		}
+		if config.Mldsa65Seed != "" {
+			k.checkKey(path+".mldsa65Seed", config.Mldsa65Seed, 32)
+		}
+		return
+	}
+	if config.Password != "" {
+		k.checkKey(path+".password", config.Password, 32)
+	} else {
+		k.checkKey(path+".publicKey", config.PublicKey, 32)
+	}
+	k.checkShortId(path+".shortId", config.ShortId)
+	if config.Mldsa65Verify != "" {
+		k.checkKey(path+".mldsa65Verify", config.Mldsa65Verify, 1952)
+	}
+}
+
+// checkKey checks that key is the base64url encoding of size bytes, as REALITY keys are.
+func (k *checker) checkKey(path, key string, size int) {
+	if key == "" {
+		k.error(path, "empty")
+		return
+	}
+	b, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		k.error(path, "invalid base64url: ", err)
+		return
+	}
+	if len(b) != size {
+		k.error(path, "should be ", size, " bytes, not ", len(b))
+	}
+}
+
+func (k *checker) checkShortId(path, id string) {
+	if len(id) > 16 {
+		k.error(path, "longer than 16 hex digits: ", id)
+		return
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		k.error(path, "invalid hex: ", id)
+	}
+}
+
+func (k *checker) checkRule(path string, raw json.RawMessage) {
+	rule := new(struct {
+		Domain   *StringList `json:"domain"`
+		Domains  *StringList `json:"domains"`
+		IP       *StringList `json:"ip"`
+		SourceIP *StringList `json:"sourceIP"`
+		Source   *StringList `json:"source"`
+		LocalIP  *StringList `json:"localIP"`
+	})
+	if json.Unmarshal(raw, rule) != nil {
+		return // reported by Build
+	}
+	if rule.Domain != nil {
+		k.checkDomains(path+".domain", *rule.Domain)
+	}
+	if rule.Domains != nil {
+		k.checkDomains(path+".domains", *rule.Domains)
+	}
+	if rule.IP != nil {
+		k.checkIPs(path+".ip", *rule.IP)
+	}
+	if rule.SourceIP != nil {
+		k.checkIPs(path+".sourceIP", *rule.SourceIP)
+	}
+	if rule.Source != nil {
+		k.checkIPs(path+".source", *rule.Source)
+	}
+	if rule.LocalIP != nil {
+		k.checkIPs(path+".localIP", *rule.LocalIP)
+	}
+}
+
+func (k *checker) checkDomains(path string, domains []string) {
+	for i, domain := range domains {
+		itemPath := fmt.Sprint(path, "[", i, "]")
+		switch {
+		case strings.HasPrefix(domain, "geosite:"):
+			k.checkAsset(itemPath, "geosite.dat", domain[len("geosite:"):])
+		case strings.HasPrefix(domain, "ext:"), strings.HasPrefix(domain, "ext-domain:"):
+			k.checkExtAsset(itemPath, domain)
+		}
+	}
+}
+
+func (k *checker) checkIPs(path string, ips []string) {
+	for i, ip := range ips {
+		itemPath := fmt.Sprint(path, "[", i, "]")
+		switch {
+		case strings.HasPrefix(ip, "geoip:"):
+			k.checkAsset(itemPath, "geoip.dat", ip[len("geoip:"):])
+		case strings.HasPrefix(ip, "ext:"), strings.HasPrefix(ip, "ext-ip:"):
+			k.checkExtAsset(itemPath, ip)
+		}
+	}
+}
+
+// checkExtAsset checks a reference like ext:file.dat:code.
+func (k *checker) checkExtAsset(path, ref string) {
+	_, rest, _ := strings.Cut(ref, ":")
+	kv := strings.Split(rest, ":")
+	if len(kv) != 2 || kv[0] == "" {
+		k.error(path, "invalid external resource: ", ref)
+		return
+	}
+	k.checkAsset(path, kv[0], kv[1])
+}
+
+// checkAsset checks that the geo data file can be read, and that it has the list of code.
+func (k *checker) checkAsset(path, file, code string) {
+	a, found := k.assets[file]
+	if !found {
+		a.data, a.err = filesystem.ReadAsset(file)
+		if a.err == nil && len(a.data) == 0 {
+			a.err = errors.New("empty file")
+		}
+		k.assets[file] = a
+	}
+	if a.err != nil {
+		k.error(path, "failed to read ", file, ": ", a.err)
+		return
+	}
+	// the attributes of sites and the reverse match of IPs do not change the list
+	code, _, _ = strings.Cut(code, "@")
+	code = strings.ToUpper(strings.TrimPrefix(code, "!"))
+	if code == "" {
+		k.error(path, "empty list name")
+		return
+	}
+	if find(a.data, []byte(code)) == nil {
+		k.error(path, "list not found in ", file, ": ", code)
+	}
+}
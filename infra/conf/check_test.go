@@ -0,0 +1,108 @@
+package conf_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	. "github.com/xtls/xray-core/infra/conf"
+)
+
+func TestConfigCheck(t *testing.T) {
+	pemLines := func(c *cert.Certificate) (string, string) {
+		certPEM, keyPEM := c.ToPEM()
+		certLines, _ := json.Marshal(strings.Split(string(certPEM), "\n"))
+		keyLines, _ := json.Marshal(strings.Split(string(keyPEM), "\n"))
+		return string(certLines), string(keyLines)
+	}
+	now := time.Now()
+	validCert, validKey := pemLines(cert.MustGenerate(nil, cert.CommonName("valid"), cert.NotBefore(now.Add(-time.Hour)), cert.NotAfter(now.Add(365*24*time.Hour))))
+	expiredCert, expiredKey := pemLines(cert.MustGenerate(nil, cert.CommonName("expired"), cert.NotBefore(now.Add(-48*time.Hour)), cert.NotAfter(now.Add(-time.Hour))))
+	expiringCert, expiringKey := pemLines(cert.MustGenerate(nil, cert.CommonName("expiring"), cert.NotBefore(now.Add(-time.Hour)), cert.NotAfter(now.Add(48*time.Hour))))
+
+	config := new(Config)
+	common.Must(json.Unmarshal([]byte(`{
+		"inbounds": [{
+			"protocol": "vless",
+			"streamSettings": {
+				"security": "tls",
+				"tlsSettings": {
+					"certificates": [
+						{"certificate": `+validCert+`, "key": `+validKey+`},
+						{"certificate": `+expiredCert+`, "key": `+expiredKey+`},
+						{"certificate": `+expiringCert+`, "key": `+expiringKey+`},
+						{"certificate": `+validCert+`, "key": `+expiredKey+`},
+						{"certificateFile": "/nonexistent/cert.pem", "keyFile": "/nonexistent/key.pem"}
+					],
+					"echServerKeys": "not base64!"
+				}
+			}
+		}, {
+			"protocol": "vless",
+			"streamSettings": {
+				"security": "reality",
+				"realitySettings": {
+					"target": "example.com:443",
+					"serverNames": ["example.com"],
+					"privateKey": "c29tZXRoaW5n",
+					"shortIds": ["", "0123456789abcdef", "0123456789abcdef00", "xyz"]
+				}
+			}
+		}],
+		"outbounds": [{
+			"protocol": "wireguard",
+			"settings": {
+				"secretKey": "invalid",
+				"peers": [{"publicKey": "6e65ce0be17517110c17d77288ad87e7fd5252dcc7d09b95a39d61db03df832a"}]
+			}
+		}, {
+			"protocol": "vless",
+			"streamSettings": {
+				"security": "reality",
+				"realitySettings": {
+					"serverName": "example.com",
+					"password": "",
+					"shortId": "0123"
+				}
+			}
+		}],
+		"routing": {
+			"rules": [{
+				"domain": ["example.com", "ext:nonexistent.dat:cn"],
+				"outboundTag": "direct"
+			}]
+		}
+	}`), config))
+
+	expected := map[string]bool{
+		"inbounds[0].streamSettings.tlsSettings.certificates[1].certificate":     false,
+		"inbounds[0].streamSettings.tlsSettings.certificates[2].certificate":     true,
+		"inbounds[0].streamSettings.tlsSettings.certificates[3].key":             false,
+		"inbounds[0].streamSettings.tlsSettings.certificates[4].certificateFile": false,
+		"inbounds[0].streamSettings.tlsSettings.echServerKeys":                   false,
+		"inbounds[1].streamSettings.realitySettings.privateKey":                  false,
+		"inbounds[1].streamSettings.realitySettings.shortIds[2]":                 false,
+		"inbounds[1].streamSettings.realitySettings.shortIds[3]":                 false,
+		"outbounds[0].settings.secretKey":                                        false,
+		"outbounds[1].streamSettings.realitySettings.publicKey":                  false,
+		"routing.rules[0].domain[1]":                                             false,
+	}
+	problems := config.Check(7 * 24 * time.Hour)
+	for _, problem := range problems {
+		warning, found := expected[problem.Path]
+		if !found {
+			t.Error("unexpected problem ", problem)
+			continue
+		}
+		if warning != problem.Warning {
+			t.Error("unexpected severity of ", problem)
+		}
+		delete(expected, problem.Path)
+	}
+	for path := range expected {
+		t.Error("no problem found at ", path)
+	}
+}
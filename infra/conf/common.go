@@ -67,6 +67,41 @@ func (v *Address) Build() *net.IPOrDomain {
 	return net.NewIPOrDomain(v.Address)
 }
 
+// AddressList is a list of addresses, which may be a single address in JSON.
+type AddressList []*Address
+
+// MarshalJSON implements encoding/json.Marshaler.MarshalJSON
+func (v AddressList) MarshalJSON() ([]byte, error) {
+	if len(v) == 1 {
+		return json.Marshal(v[0])
+	}
+	return json.Marshal([]*Address(v))
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
+func (v *AddressList) UnmarshalJSON(data []byte) error {
+	var list []*Address
+	if err := json.Unmarshal(data, &list); err == nil {
+		*v = list
+		return nil
+	}
+	address := new(Address)
+	if err := json.Unmarshal(data, address); err != nil {
+		return err
+	}
+	*v = AddressList{address}
+	return nil
+}
+
+// buildServer builds the first address of the list, and the others as the alternatives raced with it.
+func (v AddressList) buildServer() (*net.IPOrDomain, []*net.IPOrDomain) {
+	var alternatives []*net.IPOrDomain
+	for _, address := range v[1:] {
+		alternatives = append(alternatives, address.Build())
+	}
+	return v[0].Build(), alternatives
+}
+
 type Network string
 
 func (v Network) Build() net.Network {
@@ -285,6 +320,20 @@ func (v *User) Build() *protocol.User {
 	}
 }
 
+// HeartbeatConfig is the keepalive of the idle connections of an outbound.
+type HeartbeatConfig struct {
+	// Interval is the seconds without uplink traffic before an empty frame is sent.
+	Interval uint32 `json:"interval"`
+}
+
+// Build returns the interval, or 0 if the heartbeat is not configured.
+func (c *HeartbeatConfig) Build() uint32 {
+	if c == nil {
+		return 0
+	}
+	return c.Interval
+}
+
 // Int32Range deserializes from "1-2" or 1, so can deserialize from both int and number.
 // Negative integers can be passed as sentinel values, but do not parse as ranges.
 // Value will be exchanged if From > To, use .Left and .Right to get original value if need.
@@ -31,6 +31,19 @@ type NameServerConfig struct {
 	ServeExpiredTTL *uint32    `json:"serveExpiredTTL"`
 	FinalQuery      bool       `json:"finalQuery"`
 	UnexpectedIPs   StringList `json:"unexpectedIPs"`
+	// SourceAddress, Interface and AllowMulticastNames are the options of localhost.
+	SourceAddress       *Address `json:"sourceAddress"`
+	Interface           string   `json:"interface"`
+	AllowMulticastNames bool     `json:"allowMulticastNames"`
+	// UDPGSO applies to DNS-over-QUIC.
+	UDPGSO bool `json:"udpGSO"`
+}
+
+func (c *NameServerConfig) strictValue(data []byte) interface{} {
+	if isObject(data) {
+		return c
+	}
+	return nil
 }
 
 // UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
@@ -42,21 +55,25 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) error {
 	}
 
 	var advanced struct {
-		Address         *Address   `json:"address"`
-		ClientIP        *Address   `json:"clientIp"`
-		Port            uint16     `json:"port"`
-		SkipFallback    bool       `json:"skipFallback"`
-		Domains         []string   `json:"domains"`
-		ExpectedIPs     StringList `json:"expectedIPs"`
-		ExpectIPs       StringList `json:"expectIPs"`
-		QueryStrategy   string     `json:"queryStrategy"`
-		Tag             string     `json:"tag"`
-		TimeoutMs       uint64     `json:"timeoutMs"`
-		DisableCache    bool       `json:"disableCache"`
-		ServeStale      bool       `json:"serveStale"`
-		ServeExpiredTTL *uint32    `json:"serveExpiredTTL"`
-		FinalQuery      bool       `json:"finalQuery"`
-		UnexpectedIPs   StringList `json:"unexpectedIPs"`
+		Address             *Address   `json:"address"`
+		ClientIP            *Address   `json:"clientIp"`
+		Port                uint16     `json:"port"`
+		SkipFallback        bool       `json:"skipFallback"`
+		Domains             []string   `json:"domains"`
+		ExpectedIPs         StringList `json:"expectedIPs"`
+		ExpectIPs           StringList `json:"expectIPs"`
+		QueryStrategy       string     `json:"queryStrategy"`
+		Tag                 string     `json:"tag"`
+		TimeoutMs           uint64     `json:"timeoutMs"`
+		DisableCache        bool       `json:"disableCache"`
+		ServeStale          bool       `json:"serveStale"`
+		ServeExpiredTTL     *uint32    `json:"serveExpiredTTL"`
+		FinalQuery          bool       `json:"finalQuery"`
+		UnexpectedIPs       StringList `json:"unexpectedIPs"`
+		SourceAddress       *Address   `json:"sourceAddress"`
+		Interface           string     `json:"interface"`
+		AllowMulticastNames bool       `json:"allowMulticastNames"`
+		UDPGSO              bool       `json:"udpGSO"`
 	}
 	if err := json.Unmarshal(data, &advanced); err == nil {
 		c.Address = advanced.Address
@@ -74,6 +91,10 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) error {
 		c.ServeExpiredTTL = advanced.ServeExpiredTTL
 		c.FinalQuery = advanced.FinalQuery
 		c.UnexpectedIPs = advanced.UnexpectedIPs
+		c.SourceAddress = advanced.SourceAddress
+		c.Interface = advanced.Interface
+		c.AllowMulticastNames = advanced.AllowMulticastNames
+		c.UDPGSO = advanced.UDPGSO
 		return nil
 	}
 
@@ -163,6 +184,23 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		myClientIP = []byte(c.ClientIP.IP())
 	}
 
+	var local *dns.LocalNameServerConfig
+	if c.SourceAddress != nil || len(c.Interface) > 0 || c.AllowMulticastNames {
+		if !c.Address.Family().IsDomain() || !strings.EqualFold(c.Address.Domain(), "localhost") {
+			return nil, errors.New("sourceAddress, interface and allowMulticastNames only apply to localhost")
+		}
+		local = &dns.LocalNameServerConfig{
+			Interface:           c.Interface,
+			AllowMulticastNames: c.AllowMulticastNames,
+		}
+		if c.SourceAddress != nil {
+			if !c.SourceAddress.Family().IsIP() {
+				return nil, errors.New("not an IP address:", c.SourceAddress.String())
+			}
+			local.SourceAddress = c.SourceAddress.String()
+		}
+	}
+
 	return &dns.NameServer{
 		Address: &net.Endpoint{
 			Network: net.Network_UDP,
@@ -184,6 +222,8 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		FinalQuery:        c.FinalQuery,
 		UnexpectedGeoip:   unexpectedGeoipList,
 		ActUnprior:        actUnprior,
+		Local:             local,
+		UdpGso:            c.UDPGSO,
 	}, nil
 }
 
@@ -208,6 +248,7 @@ type DNSConfig struct {
 	DisableFallbackIfMatch bool                `json:"disableFallbackIfMatch"`
 	EnableParallelQuery    bool                `json:"enableParallelQuery"`
 	UseSystemHosts         bool                `json:"useSystemHosts"`
+	LogEvery               uint32              `json:"logEvery"`
 }
 
 type HostAddress struct {
@@ -407,6 +448,7 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		DisableFallback:        c.DisableFallback,
 		DisableFallbackIfMatch: c.DisableFallbackIfMatch,
 		EnableParallelQuery:    c.EnableParallelQuery,
+		LogEvery:               c.LogEvery,
 		QueryStrategy:          resolveQueryStrategy(c.QueryStrategy),
 	}
 
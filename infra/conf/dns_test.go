@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/xtls/xray-core/app/dns"
+	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
 	. "github.com/xtls/xray-core/infra/conf"
 	"google.golang.org/protobuf/proto"
@@ -114,3 +115,37 @@ func TestDNSConfigParsing(t *testing.T) {
 		},
 	})
 }
+
+func TestNameServerLocalOptions(t *testing.T) {
+	config := new(NameServerConfig)
+	common.Must(json.Unmarshal([]byte(`{
+		"address": "localhost",
+		"sourceAddress": "192.168.1.2",
+		"interface": "eth1",
+		"allowMulticastNames": true,
+		"timeoutMs": 500
+	}`), config))
+	ns, err := config.Build()
+	common.Must(err)
+	if !proto.Equal(ns.Local, &dns.LocalNameServerConfig{SourceAddress: "192.168.1.2", Interface: "eth1", AllowMulticastNames: true}) {
+		t.Error("unexpected local options: ", ns.Local)
+	}
+	if ns.TimeoutMs != 500 {
+		t.Error("unexpected timeout: ", ns.TimeoutMs)
+	}
+
+	common.Must(json.Unmarshal([]byte(`{"address": "8.8.8.8", "interface": "eth1"}`), config))
+	if _, err := config.Build(); err == nil {
+		t.Error("expected an error for the local options of a remote server")
+	}
+}
+
+func TestNameServerUDPGSO(t *testing.T) {
+	config := new(NameServerConfig)
+	common.Must(json.Unmarshal([]byte(`{"address": "quic+local://dns.example.com", "udpGSO": true}`), config))
+	ns, err := config.Build()
+	common.Must(err)
+	if !ns.UdpGso {
+		t.Error("udpGSO is not set")
+	}
+}
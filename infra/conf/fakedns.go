@@ -32,6 +32,13 @@ func (f *FakeDNSConfig) MarshalJSON() ([]byte, error) {
 	return nil, errors.New("unexpected config state")
 }
 
+func (f *FakeDNSConfig) strictValue(data []byte) interface{} {
+	if isObject(data) {
+		return new(FakeDNSPoolElementConfig)
+	}
+	return new([]*FakeDNSPoolElementConfig)
+}
+
 // UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
 func (f *FakeDNSConfig) UnmarshalJSON(data []byte) error {
 	var pool FakeDNSPoolElementConfig
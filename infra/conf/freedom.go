@@ -23,6 +23,10 @@ type FreedomConfig struct {
 	Noise          *Noise    `json:"noise"`
 	Noises         []*Noise  `json:"noises"`
 	ProxyProtocol  uint32    `json:"proxyProtocol"`
+	// IPFamilyPreference overrides the domain strategy, and the query strategy of the DNS app.
+	IPFamilyPreference string `json:"ipFamilyPreference"`
+	// ResolveVia is the tag of the outbound that the domains to dial are resolved through.
+	ResolveVia string `json:"resolveVia"`
 }
 
 type Fragment struct {
@@ -72,6 +76,11 @@ func (c *FreedomConfig) Build() (proto.Message, error) {
 	default:
 		return nil, errors.New("unsupported domain strategy: ", targetStrategy)
 	}
+	var err error
+	if config.IpFamilyPreference, err = parseIPFamilyPreference(c.IPFamilyPreference); err != nil {
+		return nil, err
+	}
+	config.ResolveVia = c.ResolveVia
 
 	if c.Fragment != nil {
 		config.Fragment = new(freedom.Fragment)
@@ -38,5 +38,26 @@ func TestFreedomConfig(t *testing.T) {
 				UserLevel: 1,
 			},
 		},
+		{
+			Input: `{
+				"domainStrategy": "UseIPv4",
+				"ipFamilyPreference": "prefer-v6"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				DomainStrategy:     internet.DomainStrategy_USE_IP4,
+				IpFamilyPreference: internet.IPFamilyPreference_PREFER_IP6,
+			},
+		},
+		{
+			Input: `{
+				"resolveVia": "proxy"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &freedom.Config{
+				DomainStrategy: internet.DomainStrategy_AS_IS,
+				ResolveVia:     "proxy",
+			},
+		},
 	})
 }
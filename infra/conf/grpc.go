@@ -1,6 +1,7 @@
 package conf
 
 import (
+	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/transport/internet/grpc"
 	"google.golang.org/protobuf/proto"
 )
@@ -14,6 +15,10 @@ type GRPCConfig struct {
 	PermitWithoutStream bool   `json:"permit_without_stream"`
 	InitialWindowsSize  int32  `json:"initial_windows_size"`
 	UserAgent           string `json:"user_agent"`
+
+	ConnectionPoolSize        int32 `json:"connectionPoolSize"`
+	ConnectionPoolMin         int32 `json:"connectionPoolMin"`
+	ConnectionPoolIdleTimeout int32 `json:"connectionPoolIdleTimeout"`
 }
 
 func (g *GRPCConfig) Build() (proto.Message, error) {
@@ -27,6 +32,12 @@ func (g *GRPCConfig) Build() (proto.Message, error) {
 		// default window size of gRPC-go
 		g.InitialWindowsSize = 0
 	}
+	if g.ConnectionPoolSize < 0 || g.ConnectionPoolMin < 0 || g.ConnectionPoolIdleTimeout < 0 {
+		return nil, errors.New("invalid gRPC connection pool settings")
+	}
+	if g.ConnectionPoolSize > 0 && g.ConnectionPoolMin > g.ConnectionPoolSize {
+		return nil, errors.New("connectionPoolMin of gRPC is more than connectionPoolSize")
+	}
 
 	return &grpc.Config{
 		Authority:           g.Authority,
@@ -37,5 +48,9 @@ func (g *GRPCConfig) Build() (proto.Message, error) {
 		PermitWithoutStream: g.PermitWithoutStream,
 		InitialWindowsSize:  g.InitialWindowsSize,
 		UserAgent:           g.UserAgent,
+
+		ConnectionPoolSize:        g.ConnectionPoolSize,
+		ConnectionPoolMin:         g.ConnectionPoolMin,
+		ConnectionPoolIdleTimeout: g.ConnectionPoolIdleTimeout,
 	}, nil
 }
@@ -50,15 +50,45 @@ type HTTPRemoteConfig struct {
 	Users   []json.RawMessage `json:"users"`
 }
 
+func (c *HTTPRemoteConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"users": new(struct {
+		protocol.User
+		HTTPAccount
+	})}
+}
+
 type HTTPClientConfig struct {
-	Address  *Address          	 `json:"address"`
-	Port     uint16            	 `json:"port"`
-	Level    uint32              `json:"level"`
-	Email    string              `json:"email"`
-	Username string              `json:"user"`
-	Password string              `json:"pass"`
-	Servers  []*HTTPRemoteConfig `json:"servers"`
-	Headers  map[string]string   `json:"headers"`
+	Address      *Address            `json:"address"`
+	Port         uint16              `json:"port"`
+	Level        uint32              `json:"level"`
+	Email        string              `json:"email"`
+	Username     string              `json:"user"`
+	Password     string              `json:"pass"`
+	Servers      []*HTTPRemoteConfig `json:"servers"`
+	Headers      map[string]string   `json:"headers"`
+	H2PoolSize   uint32              `json:"h2PoolSize"`
+	Padding      *HTTPPaddingConfig  `json:"padding"`
+	UDPInConnect bool                `json:"udpInConnect"`
+}
+
+type HTTPPaddingConfig struct {
+	Frames  uint32 `json:"frames"`
+	MinSize uint32 `json:"minSize"`
+	MaxSize uint32 `json:"maxSize"`
+}
+
+func (c *HTTPPaddingConfig) Build() (*http.Padding, error) {
+	if c.MaxSize > 255 || c.MinSize > 255 {
+		return nil, errors.New("HTTP padding size cannot exceed 255")
+	}
+	if c.MaxSize != 0 && c.MinSize > c.MaxSize {
+		return nil, errors.New("HTTP padding minSize ", c.MinSize, " is greater than maxSize ", c.MaxSize)
+	}
+	return &http.Padding{
+		Frames:  c.Frames,
+		MinSize: c.MinSize,
+		MaxSize: c.MaxSize,
+	}, nil
 }
 
 func (v *HTTPClientConfig) Build() (proto.Message, error) {
@@ -118,5 +148,14 @@ func (v *HTTPClientConfig) Build() (proto.Message, error) {
 			Value: value,
 		})
 	}
+	config.H2PoolSize = v.H2PoolSize
+	if v.Padding != nil {
+		padding, err := v.Padding.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Padding = padding
+	}
+	config.UdpInConnect = v.UDPInConnect
 	return config, nil
 }
@@ -3,6 +3,8 @@ package conf_test
 import (
 	"testing"
 
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	. "github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/proxy/http"
 )
@@ -35,3 +37,40 @@ func TestHTTPServerConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestHTTPClientConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(HTTPClientConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"address": "127.0.0.1",
+				"port": 443,
+				"h2PoolSize": 4,
+				"padding": {
+					"frames": 4,
+					"maxSize": 200
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &http.ClientConfig{
+				Server: &protocol.ServerEndpoint{
+					Address: &net.IPOrDomain{
+						Address: &net.IPOrDomain_Ip{
+							Ip: []byte{127, 0, 0, 1},
+						},
+					},
+					Port: 443,
+				},
+				Header:     []*http.Header{},
+				H2PoolSize: 4,
+				Padding: &http.Padding{
+					Frames:  4,
+					MaxSize: 200,
+				},
+			},
+		},
+	})
+}
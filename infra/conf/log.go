@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/common/errors"
 	clog "github.com/xtls/xray-core/common/log"
 )
 
@@ -15,17 +16,94 @@ func DefaultLogConfig() *log.Config {
 	}
 }
 
+type SyslogConfig struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Tag     string `json:"tag"`
+}
+
+type LogRotationConfig struct {
+	MaxSizeMB  int32 `json:"maxSizeMB"`
+	MaxBackups int32 `json:"maxBackups"`
+	MaxAgeDays int32 `json:"maxAgeDays"`
+	Compress   bool  `json:"compress"`
+}
+
+type AccessLogFilterConfig struct {
+	Domain     *StringList `json:"domain"`
+	IP         *StringList `json:"ip"`
+	Port       *PortList   `json:"port"`
+	InboundTag *StringList `json:"inboundTag"`
+	User       *StringList `json:"user"`
+	Status     *StringList `json:"status"`
+	Drop       bool        `json:"drop"`
+	Sample     float32     `json:"sample"`
+}
+
+func (c *AccessLogFilterConfig) Build() (*log.AccessLogFilter, error) {
+	filter := &log.AccessLogFilter{
+		Drop:   c.Drop,
+		Sample: c.Sample,
+	}
+	if c.Sample < 0 || c.Sample > 1 {
+		return nil, errors.New("sample of access log filter must be between 0 and 1")
+	}
+	if c.Domain != nil {
+		for _, domain := range *c.Domain {
+			rules, err := parseDomainRule(domain)
+			if err != nil {
+				return nil, errors.New("failed to parse domain rule: ", domain).Base(err)
+			}
+			filter.Domain = append(filter.Domain, rules...)
+		}
+	}
+	if c.IP != nil {
+		geoipList, err := ToCidrList(*c.IP)
+		if err != nil {
+			return nil, err
+		}
+		filter.Geoip = geoipList
+	}
+	if c.Port != nil {
+		filter.PortList = c.Port.Build()
+	}
+	if c.InboundTag != nil {
+		filter.InboundTag = append(filter.InboundTag, *c.InboundTag...)
+	}
+	if c.User != nil {
+		filter.UserEmail = append(filter.UserEmail, *c.User...)
+	}
+	if c.Status != nil {
+		for _, s := range *c.Status {
+			switch s = strings.ToLower(s); s {
+			case "accepted", "rejected":
+				filter.Status = append(filter.Status, s)
+			default:
+				return nil, errors.New("unknown access status: ", s)
+			}
+		}
+	}
+	return filter, nil
+}
+
 type LogConfig struct {
-	AccessLog   string `json:"access"`
-	ErrorLog    string `json:"error"`
-	LogLevel    string `json:"loglevel"`
-	DNSLog      bool   `json:"dnsLog"`
-	MaskAddress string `json:"maskAddress"`
+	AccessLog   string             `json:"access"`
+	ErrorLog    string             `json:"error"`
+	LogLevel    string             `json:"loglevel"`
+	DNSLog      bool               `json:"dnsLog"`
+	MaskAddress string             `json:"maskAddress"`
+	Format      string             `json:"format"`
+	Output      string             `json:"output"`
+	Syslog      *SyslogConfig      `json:"syslog"`
+	Rotation    *LogRotationConfig `json:"rotation"`
+	AuthFailure string             `json:"authFailure"`
+
+	AccessLogFilter []*AccessLogFilterConfig `json:"accessLogFilter"`
 }
 
-func (v *LogConfig) Build() *log.Config {
+func (v *LogConfig) Build() (*log.Config, error) {
 	if v == nil {
-		return nil
+		return nil, nil
 	}
 	config := &log.Config{
 		ErrorLogType:  log.LogType_Console,
@@ -33,6 +111,35 @@ func (v *LogConfig) Build() *log.Config {
 		EnableDnsLog:  v.DNSLog,
 	}
 
+	// The output replaces the console for the logs without a file.
+	switch strings.ToLower(v.Output) {
+	case "", "console":
+	case "syslog":
+		config.ErrorLogType = log.LogType_Syslog
+		config.AccessLogType = log.LogType_Syslog
+	case "journald":
+		config.ErrorLogType = log.LogType_Journald
+		config.AccessLogType = log.LogType_Journald
+	default:
+		return nil, errors.New("unknown log output: ", v.Output)
+	}
+	if v.Rotation != nil {
+		if v.Rotation.MaxSizeMB < 0 || v.Rotation.MaxBackups < 0 || v.Rotation.MaxAgeDays < 0 {
+			return nil, errors.New("log rotation settings can't be negative")
+		}
+		config.Rotation = &log.LogRotation{
+			MaxSizeMb:  v.Rotation.MaxSizeMB,
+			MaxBackups: v.Rotation.MaxBackups,
+			MaxAgeDays: v.Rotation.MaxAgeDays,
+			Compress:   v.Rotation.Compress,
+		}
+	}
+	if v.Syslog != nil {
+		config.SyslogNetwork = v.Syslog.Network
+		config.SyslogAddress = v.Syslog.Address
+		config.SyslogTag = v.Syslog.Tag
+	}
+
 	if v.AccessLog == "none" {
 		config.AccessLogType = log.LogType_None
 	} else if len(v.AccessLog) > 0 {
@@ -46,6 +153,11 @@ func (v *LogConfig) Build() *log.Config {
 		config.ErrorLogType = log.LogType_File
 	}
 
+	if len(v.AuthFailure) > 0 && v.AuthFailure != "none" {
+		config.AuthFailureLogPath = v.AuthFailure
+		config.AuthFailureLogType = log.LogType_File
+	}
+
 	level := strings.ToLower(v.LogLevel)
 	switch level {
 	case "debug":
@@ -61,5 +173,22 @@ func (v *LogConfig) Build() *log.Config {
 		config.ErrorLogLevel = clog.Severity_Warning
 	}
 	config.MaskAddress = v.MaskAddress
-	return config
+
+	switch strings.ToLower(v.Format) {
+	case "", "plain":
+		config.Format = log.LogFormat_Plain
+	case "json":
+		config.Format = log.LogFormat_JSON
+	default:
+		return nil, errors.New("unknown log format: ", v.Format)
+	}
+
+	for _, f := range v.AccessLogFilter {
+		filter, err := f.Build()
+		if err != nil {
+			return nil, errors.New("failed to build access log filter").Base(err)
+		}
+		config.AccessLogFilter = append(config.AccessLogFilter, filter)
+	}
+	return config, nil
 }
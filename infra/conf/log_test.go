@@ -0,0 +1,55 @@
+package conf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/router"
+	clog "github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	. "github.com/xtls/xray-core/infra/conf"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLogConfigAccessLogFilter(t *testing.T) {
+	config := new(LogConfig)
+	err := json.Unmarshal([]byte(`{
+		"loglevel": "warning",
+		"accessLogFilter": [
+			{"inboundTag": ["dns-in"], "port": 53, "drop": true},
+			{"domain": ["domain:example.com"], "ip": ["10.0.0.0/8"], "user": ["love@example.com"], "status": ["Rejected"], "sample": 0.01}
+		]
+	}`), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := config.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &log.Config{
+		ErrorLogType:  log.LogType_Console,
+		ErrorLogLevel: clog.Severity_Warning,
+		AccessLogType: log.LogType_Console,
+		AccessLogFilter: []*log.AccessLogFilter{
+			{
+				InboundTag: []string{"dns-in"},
+				PortList:   &net.PortList{Range: []*net.PortRange{{From: 53, To: 53}}},
+				Drop:       true,
+			},
+			{
+				Domain: []*router.Domain{{Type: router.Domain_Domain, Value: "example.com"}},
+				Geoip: []*router.GeoIP{{
+					Cidr: []*router.CIDR{{Ip: []byte{10, 0, 0, 0}, Prefix: 8}},
+				}},
+				UserEmail: []string{"love@example.com"},
+				Status:    []string{"rejected"},
+				Sample:    0.01,
+			},
+		},
+	}
+	if !proto.Equal(actual, expected) {
+		t.Error("unexpected config: ", actual)
+	}
+}
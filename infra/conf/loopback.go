@@ -6,9 +6,15 @@ import (
 )
 
 type LoopbackConfig struct {
-	InboundTag string `json:"inboundTag"`
+	InboundTag    string `json:"inboundTag"`
+	MaxDepth      uint32 `json:"maxDepth"`
+	ClearMetadata bool   `json:"clearMetadata"`
 }
 
 func (l LoopbackConfig) Build() (proto.Message, error) {
-	return &loopback.Config{InboundTag: l.InboundTag}, nil
+	return &loopback.Config{
+		InboundTag:    l.InboundTag,
+		MaxDepth:      l.MaxDepth,
+		ClearMetadata: l.ClearMetadata,
+	}, nil
 }
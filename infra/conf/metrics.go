@@ -6,8 +6,13 @@ import (
 )
 
 type MetricsConfig struct {
-	Tag    string `json:"tag"`
-	Listen string `json:"listen"`
+	Tag      string     `json:"tag"`
+	Listen   string     `json:"listen"`
+	Token    string     `json:"token"`
+	User     string     `json:"user"`
+	Pass     string     `json:"pass"`
+	AllowIPs StringList `json:"allowIPs"`
+	Pprof    *bool      `json:"pprof"`
 }
 
 func (c *MetricsConfig) Build() (*metrics.Config, error) {
@@ -18,9 +23,25 @@ func (c *MetricsConfig) Build() (*metrics.Config, error) {
 	if c.Tag == "" {
 		c.Tag = "Metrics"
 	}
+	if c.User == "" && c.Pass != "" {
+		return nil, errors.New("Metrics has a pass without user.")
+	}
 
-	return &metrics.Config{
+	config := &metrics.Config{
 		Tag:    c.Tag,
 		Listen: c.Listen,
-	}, nil
+		Token:  c.Token,
+		User:   c.User,
+		Pass:   c.Pass,
+		// pprof is served unless disabled, as before
+		DisablePprof: c.Pprof != nil && !*c.Pprof,
+	}
+	if len(c.AllowIPs) > 0 {
+		allow, err := ToCidrList(c.AllowIPs)
+		if err != nil {
+			return nil, errors.New("invalid allowed IPs of metrics").Base(err)
+		}
+		config.AllowIps = allow
+	}
+	return config, nil
 }
@@ -1,18 +1,64 @@
 package conf
 
 import (
+	"strings"
+
 	"github.com/xtls/xray-core/app/policy"
+	"github.com/xtls/xray-core/common/errors"
+	fpolicy "github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/transport/internet/tls"
 )
 
+type RateLimitConfig struct {
+	Mode     string `json:"mode"`
+	Uplink   uint64 `json:"uplink"`
+	Downlink uint64 `json:"downlink"`
+}
+
+func (c *RateLimitConfig) Build() (*policy.Policy_RateLimit, error) {
+	config := &policy.Policy_RateLimit{
+		Uplink:   c.Uplink,
+		Downlink: c.Downlink,
+	}
+	switch strings.ToLower(c.Mode) {
+	case "", "perconnection":
+	case "peruser":
+		config.PerUser = true
+	default:
+		return nil, errors.New("unknown rate limit mode: ", c.Mode)
+	}
+	return config, nil
+}
+
 type Policy struct {
-	Handshake         *uint32 `json:"handshake"`
-	ConnectionIdle    *uint32 `json:"connIdle"`
-	UplinkOnly        *uint32 `json:"uplinkOnly"`
-	DownlinkOnly      *uint32 `json:"downlinkOnly"`
-	StatsUserUplink   bool    `json:"statsUserUplink"`
-	StatsUserDownlink bool    `json:"statsUserDownlink"`
-	StatsUserOnline   bool    `json:"statsUserOnline"`
-	BufferSize        *int32  `json:"bufferSize"`
+	Handshake         *uint32          `json:"handshake"`
+	ConnectionIdle    *uint32          `json:"connIdle"`
+	UplinkOnly        *uint32          `json:"uplinkOnly"`
+	DownlinkOnly      *uint32          `json:"downlinkOnly"`
+	UDPIdle           *uint32          `json:"udpIdle"`
+	StatsUserUplink   bool             `json:"statsUserUplink"`
+	StatsUserDownlink bool             `json:"statsUserDownlink"`
+	StatsUserOnline   bool             `json:"statsUserOnline"`
+	BufferSize        *int32           `json:"bufferSize"`
+	AdaptiveBuffer    bool             `json:"adaptiveBuffer"`
+	PipeHighWatermark *int32           `json:"pipeHighWatermark"`
+	PipeLowWatermark  *int32           `json:"pipeLowWatermark"`
+	RateLimit         *RateLimitConfig `json:"rateLimit"`
+}
+
+// buildWatermarks converts pipe watermarks in KB into bytes. The low watermark defaults to half the high one.
+func buildWatermarks(high int32, low *int32) (int32, int32, error) {
+	if high <= 0 {
+		return 0, 0, nil
+	}
+	l := high / 2
+	if low != nil {
+		l = *low
+	}
+	if l < 0 || l > high {
+		return 0, 0, errors.New("pipe low watermark ", l, " is not between 0 and the high watermark ", high)
+	}
+	return high * 1024, l * 1024, nil
 }
 
 func (t *Policy) Build() (*policy.Policy, error) {
@@ -29,6 +75,9 @@ func (t *Policy) Build() (*policy.Policy, error) {
 	if t.DownlinkOnly != nil {
 		config.DownlinkOnly = &policy.Second{Value: *t.DownlinkOnly}
 	}
+	if t.UDPIdle != nil {
+		config.UdpIdle = &policy.Second{Value: *t.UDPIdle}
+	}
 
 	p := &policy.Policy{
 		Timeout: config,
@@ -46,30 +95,86 @@ func (t *Policy) Build() (*policy.Policy, error) {
 		}
 		p.Buffer = &policy.Policy_Buffer{
 			Connection: bs,
+			Adaptive:   t.AdaptiveBuffer,
+		}
+	} else if t.AdaptiveBuffer {
+		p.Buffer = &policy.Policy_Buffer{
+			Connection: fpolicy.SessionDefault().Buffer.PerConnection,
+			Adaptive:   true,
 		}
 	}
 
+	if t.PipeHighWatermark != nil {
+		high, low, err := buildWatermarks(*t.PipeHighWatermark, t.PipeLowWatermark)
+		if err != nil {
+			return nil, err
+		}
+		if p.Buffer == nil {
+			p.Buffer = &policy.Policy_Buffer{
+				Connection: fpolicy.SessionDefault().Buffer.PerConnection,
+			}
+		}
+		p.Buffer.HighWatermark = high
+		p.Buffer.LowWatermark = low
+	}
+
+	if t.RateLimit != nil {
+		rl, err := t.RateLimit.Build()
+		if err != nil {
+			return nil, err
+		}
+		p.RateLimit = rl
+	}
+
 	return p, nil
 }
 
 type SystemPolicy struct {
-	StatsInboundUplink    bool `json:"statsInboundUplink"`
-	StatsInboundDownlink  bool `json:"statsInboundDownlink"`
-	StatsOutboundUplink   bool `json:"statsOutboundUplink"`
-	StatsOutboundDownlink bool `json:"statsOutboundDownlink"`
+	StatsInboundUplink     bool   `json:"statsInboundUplink"`
+	StatsInboundDownlink   bool   `json:"statsInboundDownlink"`
+	StatsOutboundUplink    bool   `json:"statsOutboundUplink"`
+	StatsOutboundDownlink  bool   `json:"statsOutboundDownlink"`
+	StatsHostTraffic       bool   `json:"statsHostTraffic"`
+	StatsHostTrafficLimit  uint32 `json:"statsHostTrafficLimit"`
+	StatsOutboundHistogram bool   `json:"statsOutboundHistogram"`
+	StatsByRule            bool   `json:"statsByRule"`
+	MemoryHighWaterMark    uint64 `json:"memoryHighWaterMark"`
 }
 
 func (p *SystemPolicy) Build() (*policy.SystemPolicy, error) {
 	return &policy.SystemPolicy{
 		Stats: &policy.SystemPolicy_Stats{
-			InboundUplink:    p.StatsInboundUplink,
-			InboundDownlink:  p.StatsInboundDownlink,
-			OutboundUplink:   p.StatsOutboundUplink,
-			OutboundDownlink: p.StatsOutboundDownlink,
+			InboundUplink:     p.StatsInboundUplink,
+			InboundDownlink:   p.StatsInboundDownlink,
+			OutboundUplink:    p.StatsOutboundUplink,
+			OutboundDownlink:  p.StatsOutboundDownlink,
+			HostTraffic:       p.StatsHostTraffic,
+			HostTrafficLimit:  p.StatsHostTrafficLimit,
+			OutboundHistogram: p.StatsOutboundHistogram,
+			RuleTraffic:       p.StatsByRule,
+		},
+		Buffer: &policy.SystemPolicy_Buffer{
+			HighWaterMark: p.MemoryHighWaterMark * 1024 * 1024,
 		},
 	}, nil
 }
 
+// SecurityConfig is the TLS settings that all inbounds and outbounds must comply with.
+type SecurityConfig struct {
+	MinTLSVersion  string `json:"minTLSVersion"`
+	ForbidInsecure bool   `json:"forbidInsecure"`
+}
+
+func (c *SecurityConfig) Build() (*policy.SystemPolicy_Security, error) {
+	if len(c.MinTLSVersion) > 0 && !tls.IsVersion(c.MinTLSVersion) {
+		return nil, errors.New(`unknown "minTLSVersion": `, c.MinTLSVersion)
+	}
+	return &policy.SystemPolicy_Security{
+		MinTlsVersion:  c.MinTLSVersion,
+		ForbidInsecure: c.ForbidInsecure,
+	}, nil
+}
+
 type PolicyConfig struct {
 	Levels map[uint32]*Policy `json:"levels"`
 	System *SystemPolicy      `json:"system"`
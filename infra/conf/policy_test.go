@@ -38,3 +38,23 @@ func TestBufferSize(t *testing.T) {
 		}
 	}
 }
+
+func TestRateLimit(t *testing.T) {
+	pConf := Policy{
+		RateLimit: &RateLimitConfig{
+			Mode:     "perUser",
+			Uplink:   1024,
+			Downlink: 2048,
+		},
+	}
+	p, err := pConf.Build()
+	common.Must(err)
+	if !p.RateLimit.PerUser || p.RateLimit.Uplink != 1024 || p.RateLimit.Downlink != 2048 {
+		t.Error("unexpected rate limit: ", p.RateLimit)
+	}
+
+	pConf.RateLimit.Mode = "unknown"
+	if _, err := pConf.Build(); err == nil {
+		t.Error("expected error for unknown rate limit mode")
+	}
+}
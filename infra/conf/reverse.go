@@ -6,27 +6,44 @@ import (
 )
 
 type BridgeConfig struct {
-	Tag    string `json:"tag"`
-	Domain string `json:"domain"`
+	Tag                    string `json:"tag"`
+	Domain                 string `json:"domain"`
+	ReconnectIntervalMs    uint32 `json:"reconnectIntervalMs"`
+	MaxReconnectIntervalMs uint32 `json:"maxReconnectIntervalMs"`
+	MinIdleConnections     uint32 `json:"minIdleConnections"`
+	MaxConcurrency         uint32 `json:"maxConcurrency"`
 }
 
 func (c *BridgeConfig) Build() (*reverse.BridgeConfig, error) {
 	return &reverse.BridgeConfig{
-		Tag:    c.Tag,
-		Domain: c.Domain,
+		Tag:                    c.Tag,
+		Domain:                 c.Domain,
+		ReconnectIntervalMs:    c.ReconnectIntervalMs,
+		MaxReconnectIntervalMs: c.MaxReconnectIntervalMs,
+		MinIdleConnections:     c.MinIdleConnections,
+		MaxConcurrency:         c.MaxConcurrency,
 	}, nil
 }
 
 type PortalConfig struct {
-	Tag    string `json:"tag"`
-	Domain string `json:"domain"`
+	Tag            string      `json:"tag"`
+	Domain         string      `json:"domain"`
+	Domains        *StringList `json:"domains"`
+	DomainPrefix   string      `json:"domainPrefix"`
+	MaxConcurrency uint32      `json:"maxConcurrency"`
 }
 
 func (c *PortalConfig) Build() (*reverse.PortalConfig, error) {
-	return &reverse.PortalConfig{
-		Tag:    c.Tag,
-		Domain: c.Domain,
-	}, nil
+	config := &reverse.PortalConfig{
+		Tag:            c.Tag,
+		Domain:         c.Domain,
+		DomainPrefix:   c.DomainPrefix,
+		MaxConcurrency: c.MaxConcurrency,
+	}
+	if c.Domains != nil {
+		config.Domains = *c.Domains
+	}
+	return config, nil
 }
 
 type ReverseConfig struct {
@@ -18,12 +18,20 @@ func TestReverseConfig(t *testing.T) {
 				"bridges": [{
 					"tag": "test",
 					"domain": "test.example.com"
+				}, {
+					"tag": "backoff",
+					"domain": "backoff.example.com",
+					"reconnectIntervalMs": 500,
+					"maxReconnectIntervalMs": 30000,
+					"minIdleConnections": 2,
+					"maxConcurrency": 8
 				}]
 			}`,
 			Parser: loadJSON(creator),
 			Output: &reverse.Config{
 				BridgeConfig: []*reverse.BridgeConfig{
 					{Tag: "test", Domain: "test.example.com"},
+					{Tag: "backoff", Domain: "backoff.example.com", ReconnectIntervalMs: 500, MaxReconnectIntervalMs: 30000, MinIdleConnections: 2, MaxConcurrency: 8},
 				},
 			},
 		},
@@ -41,5 +49,21 @@ func TestReverseConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"portals": [{
+					"tag": "test",
+					"domains": ["a.example.com", "b.example.com"],
+					"domainPrefix": "home-",
+					"maxConcurrency": 8
+				}]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &reverse.Config{
+				PortalConfig: []*reverse.PortalConfig{
+					{Tag: "test", Domains: []string{"a.example.com", "b.example.com"}, DomainPrefix: "home-", MaxConcurrency: 8},
+				},
+			},
+		},
 	})
 }
@@ -20,6 +20,14 @@ type StrategyConfig struct {
 	Settings *json.RawMessage `json:"settings"`
 }
 
+func (c *StrategyConfig) strictRawFields() map[string]interface{} {
+	strategy := c.Type
+	if strategy == "" {
+		strategy = strategyRandom
+	}
+	return map[string]interface{}{"settings": strategyConfigLoader.strictConfig(nil, strategy)}
+}
+
 type BalancingRule struct {
 	Tag         string         `json:"tag"`
 	Selectors   StringList     `json:"selector"`
@@ -76,6 +84,11 @@ type RouterConfig struct {
 	Balancers      []*BalancingRule  `json:"balancers"`
 }
 
+func (c *RouterConfig) strictRawFields() map[string]interface{} {
+	// "type": "field" is left in the rules of old configs
+	return map[string]interface{}{"rules": &strictLoaded{idKey: "type", config: new(fieldRule)}}
+}
+
 func (c *RouterConfig) getDomainStrategy() router.Config_DomainStrategy {
 	ds := ""
 	if c.DomainStrategy != nil {
@@ -87,6 +100,8 @@ func (c *RouterConfig) getDomainStrategy() router.Config_DomainStrategy {
 		return router.Config_IpIfNonMatch
 	case "ipondemand":
 		return router.Config_IpOnDemand
+	case "ipondemandcached":
+		return router.Config_IpOnDemandCached
 	default:
 		return router.Config_AsIs
 	}
@@ -517,26 +532,27 @@ func ToCidrList(ips StringList) ([]*router.GeoIP, error) {
 	return geoipList, nil
 }
 
+type fieldRule struct {
+	RouterRule
+	Domain     *StringList       `json:"domain"`
+	Domains    *StringList       `json:"domains"`
+	IP         *StringList       `json:"ip"`
+	Port       *PortList         `json:"port"`
+	Network    *NetworkList      `json:"network"`
+	SourceIP   *StringList       `json:"sourceIP"`
+	Source     *StringList       `json:"source"`
+	SourcePort *PortList         `json:"sourcePort"`
+	User       *StringList       `json:"user"`
+	VlessRoute *PortList         `json:"vlessRoute"`
+	InboundTag *StringList       `json:"inboundTag"`
+	Protocols  *StringList       `json:"protocol"`
+	Attributes map[string]string `json:"attrs"`
+	LocalIP    *StringList       `json:"localIP"`
+	LocalPort  *PortList         `json:"localPort"`
+}
+
 func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
-	type RawFieldRule struct {
-		RouterRule
-		Domain     *StringList       `json:"domain"`
-		Domains    *StringList       `json:"domains"`
-		IP         *StringList       `json:"ip"`
-		Port       *PortList         `json:"port"`
-		Network    *NetworkList      `json:"network"`
-		SourceIP   *StringList       `json:"sourceIP"`
-		Source     *StringList       `json:"source"`
-		SourcePort *PortList         `json:"sourcePort"`
-		User       *StringList       `json:"user"`
-		VlessRoute *PortList         `json:"vlessRoute"`
-		InboundTag *StringList       `json:"inboundTag"`
-		Protocols  *StringList       `json:"protocol"`
-		Attributes map[string]string `json:"attrs"`
-		LocalIP    *StringList       `json:"localIP"`
-		LocalPort  *PortList         `json:"localPort"`
-	}
-	rawFieldRule := new(RawFieldRule)
+	rawFieldRule := new(fieldRule)
 	err := json.Unmarshal(msg, rawFieldRule)
 	if err != nil {
 		return nil, err
@@ -3,16 +3,16 @@ package serial
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/xtls/xray-core/common/errors"
 	creflect "github.com/xtls/xray-core/common/reflect"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/infra/conf"
-	"github.com/xtls/xray-core/main/confloader"
 )
 
 func MergeConfigFromFiles(files []*core.ConfigSource) (string, error) {
-	c, err := mergeConfigs(files)
+	c, err := mergeConfigs(files, nil)
 	if err != nil {
 		return "", err
 	}
@@ -23,17 +23,38 @@ func MergeConfigFromFiles(files []*core.ConfigSource) (string, error) {
 	return "", errors.New("marshal to json failed.").AtError()
 }
 
-func mergeConfigs(files []*core.ConfigSource) (*conf.Config, error) {
+// mergeConfigs reads the config files and merges them. The unknown fields of the files go to unknown, like by readConfig.
+func mergeConfigs(files []*core.ConfigSource, unknown *[]*core.ConfigProblem) (*conf.Config, error) {
+	deep := false
+	switch core.ConfigMergeStrategy {
+	case "", MergeOverride:
+	case MergeDeep:
+		deep = true
+	default:
+		return nil, errors.New("unknown merge strategy: ", core.ConfigMergeStrategy)
+	}
+
 	cf := &conf.Config{}
+	tree := make(map[string]interface{})
 	for i, file := range files {
-		errors.LogInfo(context.Background(), "Reading config: ", file)
-		r, err := confloader.LoadConfig(file.Name)
+		errors.LogInfo(context.Background(), "Reading config: ", file.Name)
+		f, err := readConfig(file.Name, file.Format, unknown)
 		if err != nil {
-			return nil, errors.New("failed to read config: ", file).Base(err)
+			return nil, err
 		}
-		c, err := ReaderDecoderByFormat[file.Format](r)
-		if err != nil {
-			return nil, errors.New("failed to decode config: ", file).Base(err)
+		c := f.config
+		if deep || len(c.Include) > 0 {
+			t, err := resolveIncludes(f, nil, unknown)
+			if err != nil {
+				return nil, err
+			}
+			if deep {
+				mergeTree(tree, t, "")
+				continue
+			}
+			if c, err = decodeTreeConfig(t); err != nil {
+				return nil, errors.New("failed to decode ", file.Format, " config: ", file.Name).Base(err)
+			}
 		}
 		if i == 0 {
 			*cf = *c
@@ -41,17 +62,35 @@ func mergeConfigs(files []*core.ConfigSource) (*conf.Config, error) {
 		}
 		cf.Override(c, file.Name)
 	}
+	if deep {
+		c, err := decodeTreeConfig(tree)
+		if err != nil {
+			return nil, errors.New("failed to decode merged config").Base(err)
+		}
+		return c, nil
+	}
 	return cf, nil
 }
 
 func BuildConfig(files []*core.ConfigSource) (*core.Config, error) {
-	config, err := mergeConfigs(files)
+	config, err := mergeConfigs(files, nil)
 	if err != nil {
 		return nil, err
 	}
 	return config.Build()
 }
 
+// CheckConfigFromFiles merges the config files like BuildConfig and checks the result with conf.Config.Check.
+// In the strict mode, the unknown fields of all the config files are reported before the problems found by the check.
+func CheckConfigFromFiles(files []*core.ConfigSource, certExpiry time.Duration) ([]*core.ConfigProblem, error) {
+	var unknown []*core.ConfigProblem
+	config, err := mergeConfigs(files, &unknown)
+	if err != nil {
+		return nil, err
+	}
+	return append(unknown, config.Check(certExpiry)...), nil
+}
+
 type readerDecoder func(io.Reader) (*conf.Config, error)
 
 var ReaderDecoderByFormat = make(map[string]readerDecoder)
@@ -63,4 +102,5 @@ func init() {
 
 	core.ConfigBuilderForFiles = BuildConfig
 	core.ConfigMergedFormFiles = MergeConfigFromFiles
+	core.ConfigCheckerForFiles = CheckConfigFromFiles
 }
@@ -0,0 +1,98 @@
+package serial
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// expandEnv substitutes the environment variables in the string values of a config tree, in place.
+// ${NAME} is replaced by the variable, ${NAME:-default} by the default if the variable is unset or empty, and $${ is a literal ${.
+func expandEnv(v interface{}, path string) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			value, err := expandEnv(v[k], childPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			v[k] = value
+		}
+		return v, nil
+	case []interface{}:
+		for i := range v {
+			value, err := expandEnv(v[i], path+"["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return nil, err
+			}
+			v[i] = value
+		}
+		return v, nil
+	case string:
+		return expandEnvString(v, path)
+	default:
+		return v, nil
+	}
+}
+
+func expandEnvString(s string, path string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		s = s[i+2:]
+
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", errors.New("unterminated ${ at key ", path)
+		}
+		name, def, hasDefault := strings.Cut(s[:end], ":-")
+		if !isEnvName(name) {
+			return "", errors.New("invalid environment variable name ", strconv.Quote(name), " at key ", path)
+		}
+		value, found := os.LookupEnv(name)
+		if hasDefault && value == "" {
+			value = def
+		} else if !found {
+			return "", errors.New("environment variable ", name, " is not set at key ", path)
+		}
+		b.WriteString(value)
+		s = s[end+1:]
+	}
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package serial_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/app/proxyman"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/serial"
+)
+
+func TestEnvSubstitution(t *testing.T) {
+	t.Setenv("XRAY_TEST_LOG", "/var/log/xray")
+	t.Setenv("XRAY_TEST_EMPTY", "")
+
+	cases := []struct {
+		input  string
+		output string
+		error  string
+	}{
+		{input: "${XRAY_TEST_LOG}/access.log", output: "/var/log/xray/access.log"},
+		{input: "${XRAY_TEST_LOG}${XRAY_TEST_LOG}", output: "/var/log/xray/var/log/xray"},
+		{input: "${XRAY_TEST_LOG:-/tmp}", output: "/var/log/xray"},
+		{input: "${XRAY_TEST_UNSET:-/tmp}/access.log", output: "/tmp/access.log"},
+		{input: "${XRAY_TEST_EMPTY:-/tmp}", output: "/tmp"},
+		{input: "${XRAY_TEST_EMPTY}", output: ""},
+		{input: "${XRAY_TEST_UNSET:-}", output: ""},
+		{input: "$${XRAY_TEST_LOG}", output: "${XRAY_TEST_LOG}"},
+		{input: "$$${XRAY_TEST_LOG}", output: "$${XRAY_TEST_LOG}"},
+		{input: "$XRAY_TEST_LOG {}", output: "$XRAY_TEST_LOG {}"},
+		{input: "${XRAY_TEST_UNSET}", error: "environment variable XRAY_TEST_UNSET is not set at key log.access"},
+		{input: "${XRAY_TEST_LOG", error: "unterminated ${ at key log.access"},
+		{input: "${1X}", error: "invalid environment variable name"},
+		{input: "${}", error: "invalid environment variable name"},
+	}
+
+	for _, test := range cases {
+		value, _ := json.Marshal(test.input)
+		dir := writeConfigs(t, map[string]string{"config.json": `{"log": {"access": ` + string(value) + `}}`})
+		name := filepath.Join(dir, "config.json")
+
+		merged, err := serial.MergeConfigFromFiles([]*core.ConfigSource{{Name: name, Format: "json"}})
+		if test.error != "" {
+			if err == nil || !strings.Contains(err.Error(), test.error) || !strings.Contains(err.Error(), name) {
+				t.Error(test.input, ": expected ", test.error, ", but got ", err)
+			}
+			continue
+		}
+		common.Must(err)
+		var config struct {
+			Log struct {
+				Access string `json:"access"`
+			} `json:"log"`
+		}
+		common.Must(json.Unmarshal([]byte(merged), &config))
+		if config.Log.Access != test.output {
+			t.Error(test.input, ": unexpected result ", config.Log.Access)
+		}
+	}
+}
+
+func TestEnvSubstitutionFields(t *testing.T) {
+	t.Setenv("XRAY_TEST_PORT", "10808")
+	t.Setenv("XRAY_TEST_ID", "27848739-7e62-4138-9fd3-098a63964b6b")
+	dir := writeConfigs(t, map[string]string{
+		"config.yaml": "inbounds:\n  - tag: in\n    protocol: vless\n    port: ${XRAY_TEST_PORT}\n    settings:\n      decryption: none\n      clients:\n        - id: ${XRAY_TEST_ID}\n",
+		"broken.toml": "[[inbounds]]\ntag = \"in\"\nprotocol = \"vless\"\n[inbounds.settings]\nclients = [{id = \"${XRAY_TEST_MISSING}\"}]\n",
+	})
+
+	config, err := serial.BuildConfig([]*core.ConfigSource{{Name: filepath.Join(dir, "config.yaml"), Format: "yaml"}})
+	common.Must(err)
+	if len(config.Inbound) != 1 {
+		t.Fatal("unexpected inbounds: ", config.Inbound)
+	}
+	settings, err := config.Inbound[0].ReceiverSettings.GetInstance()
+	common.Must(err)
+	if port := settings.(*proxyman.ReceiverConfig).PortList.Range[0].From; port != 10808 {
+		t.Error("unexpected port: ", port)
+	}
+
+	_, err = serial.BuildConfig([]*core.ConfigSource{{Name: filepath.Join(dir, "broken.toml"), Format: "toml"}})
+	if err == nil || !strings.Contains(err.Error(), "XRAY_TEST_MISSING") || !strings.Contains(err.Error(), "inbounds[0].settings.clients[0].id") {
+		t.Error("expected the variable and key in the error, but got ", err)
+	}
+
+	core.ConfigNoEnv = true
+	defer func() { core.ConfigNoEnv = false }()
+	merged, err := serial.MergeConfigFromFiles([]*core.ConfigSource{{Name: filepath.Join(dir, "broken.toml"), Format: "toml"}})
+	common.Must(err)
+	if !strings.Contains(merged, "${XRAY_TEST_MISSING}") {
+		t.Error("expected no substitution, but got ", merged)
+	}
+}
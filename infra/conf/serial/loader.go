@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/pelletier/go-toml"
@@ -43,6 +46,11 @@ func findOffset(b []byte, o int) *offset {
 // DecodeJSONConfig reads from reader and decode the config into *conf.Config
 // syntax error could be detected.
 func DecodeJSONConfig(reader io.Reader) (*conf.Config, error) {
+	return decodeJSONConfig(reader, true)
+}
+
+// decodeJSONConfig decodes the config, telling where it fails by the key path, and by the position in the JSON text if it is not converted from another format.
+func decodeJSONConfig(reader io.Reader, withPosition bool) (*conf.Config, error) {
 	jsonConfig := &conf.Config{}
 
 	jsonContent := bytes.NewBuffer(make([]byte, 0, 10240))
@@ -60,15 +68,125 @@ func DecodeJSONConfig(reader io.Reader) (*conf.Config, error) {
 		case *json.UnmarshalTypeError:
 			pos = findOffset(jsonContent.Bytes(), int(tErr.Offset))
 		}
-		if pos != nil {
-			return nil, errors.New("failed to read config file at line ", pos.line, " char ", pos.char).Base(err)
+		// Read the rest, so that the key path can be found after the failing value.
+		io.Copy(io.Discard, jsonReader)
+		path := findKeyPath(reflect.TypeOf(jsonConfig), jsonContent.Bytes())
+
+		var location []interface{}
+		if pos != nil && withPosition {
+			location = append(location, " at line ", pos.line, " char ", pos.char)
 		}
-		return nil, errors.New("failed to read config file").Base(err)
+		if path != "" && len(location) > 0 {
+			location = append(location, ", key ", path)
+		} else if path != "" {
+			location = append(location, " at key ", path)
+		}
+		return nil, errors.New(append([]interface{}{"failed to read config file"}, location...)...).Base(err)
 	}
 
 	return jsonConfig, nil
 }
 
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// findKeyPath returns the key path, like "inbounds[0].port", of the value in data that fails to decode into typ.
+// It decodes the value piece by piece, following the json tags of the structs down to the failing value.
+func findKeyPath(typ reflect.Type, data []byte) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if reflect.PointerTo(typ).Implements(jsonUnmarshalerType) {
+		return ""
+	}
+	fails := func(typ reflect.Type, data []byte) bool {
+		return json.Unmarshal(data, reflect.New(typ).Interface()) != nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map:
+		keys, values := decodeObject(data)
+		for i, key := range keys {
+			valueType := typ
+			if typ.Kind() == reflect.Map {
+				valueType = typ.Elem()
+			} else if field, found := fieldByJSONName(typ, key); found {
+				valueType = field.Type
+			} else {
+				continue
+			}
+			if fails(valueType, values[i]) {
+				return joinKeyPath(key, findKeyPath(valueType, values[i]))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var array []json.RawMessage
+		if json.Unmarshal(data, &array) != nil {
+			return ""
+		}
+		for i, value := range array {
+			if fails(typ.Elem(), value) {
+				return joinKeyPath("["+strconv.Itoa(i)+"]", findKeyPath(typ.Elem(), value))
+			}
+		}
+	}
+	return ""
+}
+
+// decodeObject returns the keys and values of the JSON object, in the order of the text, or nothing if it is not an object.
+func decodeObject(data []byte) ([]string, []json.RawMessage) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if t, err := decoder.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil
+	}
+	var keys []string
+	var values []json.RawMessage
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+		keys = append(keys, t.(string))
+		values = append(values, value)
+	}
+	return keys, values
+}
+
+func joinKeyPath(key string, rest string) string {
+	if rest == "" || strings.HasPrefix(rest, "[") {
+		return key + rest
+	}
+	return key + "." + rest
+}
+
+// fieldByJSONName finds the field decoded from the key, the same way as encoding/json, preferring an exact match of the name.
+func fieldByJSONName(typ reflect.Type, key string) (reflect.StructField, bool) {
+	var match reflect.StructField
+	found := false
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if name == key {
+			return field, true
+		}
+		if !found && strings.EqualFold(name, key) {
+			match, found = field, true
+		}
+	}
+	return match, found
+}
+
 func LoadJSONConfig(reader io.Reader) (*core.Config, error) {
 	jsonConfig, err := DecodeJSONConfig(reader)
 	if err != nil {
@@ -101,7 +219,7 @@ func DecodeTOMLConfig(reader io.Reader) (*conf.Config, error) {
 		return nil, errors.New("failed to convert map to json").Base(err)
 	}
 
-	return DecodeJSONConfig(bytes.NewReader(jsonFile))
+	return decodeJSONConfig(bytes.NewReader(jsonFile), false)
 }
 
 func LoadTOMLConfig(reader io.Reader) (*core.Config, error) {
@@ -131,7 +249,7 @@ func DecodeYAMLConfig(reader io.Reader) (*conf.Config, error) {
 		return nil, errors.New("failed to convert yaml to json").Base(err)
 	}
 
-	return DecodeJSONConfig(bytes.NewReader(jsonFile))
+	return decodeJSONConfig(bytes.NewReader(jsonFile), false)
 }
 
 func LoadYAMLConfig(reader io.Reader) (*core.Config, error) {
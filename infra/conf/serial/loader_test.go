@@ -2,10 +2,17 @@ package serial_test
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/infra/conf/serial"
+	_ "github.com/xtls/xray-core/main/confloader/external"
 )
 
 func TestLoaderError(t *testing.T) {
@@ -61,3 +68,74 @@ func TestLoaderError(t *testing.T) {
 		}
 	}
 }
+
+func TestLoaderKeyPath(t *testing.T) {
+	testCases := []struct {
+		Decoder func(io.Reader) (*conf.Config, error)
+		Input   string
+		Output  string
+	}{
+		{
+			Decoder: serial.DecodeJSONConfig,
+			Input: `{
+				"inbounds": [{
+					"port": 1,
+					"protocol": "test"
+				}, {
+					"port": "abc",
+					"listen": 0
+				}]
+			}`,
+			Output: "key inbounds[1].port",
+		},
+		{
+			Decoder: serial.DecodeYAMLConfig,
+			Input: `
+log:
+  loglevel: info
+inbounds:
+  - port: 1
+    listen: [1]
+`,
+			Output: "at key inbounds[0].listen",
+		},
+		{
+			Decoder: serial.DecodeTOMLConfig,
+			Input: `
+[log]
+loglevel = 1
+`,
+			Output: "at key log.loglevel",
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := testCase.Decoder(strings.NewReader(testCase.Input))
+		if err == nil || !strings.Contains(err.Error(), testCase.Output) {
+			t.Error("expected ", testCase.Output, ", but actually ", err)
+		}
+	}
+}
+
+func TestMergeFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := []*core.ConfigSource{
+		{Name: filepath.Join(dir, "base.json"), Format: "json"},
+		{Name: filepath.Join(dir, "inbounds.yaml"), Format: "yaml"},
+		{Name: filepath.Join(dir, "outbounds.toml"), Format: "toml"},
+	}
+	common.Must(os.WriteFile(files[0].Name, []byte(`{"log": {"loglevel": "debug"}}`), 0o600))
+	common.Must(os.WriteFile(files[1].Name, []byte("inbounds:\n  - tag: in\n    protocol: socks\n    port: 1080\n"), 0o600))
+	common.Must(os.WriteFile(files[2].Name, []byte("[[outbounds]]\ntag = \"out\"\nprotocol = \"freedom\"\n"), 0o600))
+
+	config, err := serial.BuildConfig(files)
+	common.Must(err)
+	if len(config.Inbound) != 1 || config.Inbound[0].Tag != "in" || len(config.Outbound) != 1 || config.Outbound[0].Tag != "out" {
+		t.Error("unexpected merged config: ", config)
+	}
+
+	common.Must(os.WriteFile(files[1].Name, []byte("inbounds:\n  - tag: in\n    port: [1080]\n"), 0o600))
+	_, err = serial.BuildConfig(files)
+	if err == nil || !strings.Contains(err.Error(), files[1].Name) || !strings.Contains(err.Error(), "inbounds[0].port") {
+		t.Error("expected the file and key in the error, but got ", err)
+	}
+}
@@ -0,0 +1,356 @@
+package serial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pelletier/go-toml"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+	json_reader "github.com/xtls/xray-core/infra/conf/json"
+	"github.com/xtls/xray-core/main/confloader"
+)
+
+const (
+	MergeOverride = "override"
+	MergeDeep     = "deep"
+)
+
+const (
+	includeKey = "include"
+	deleteKey  = "_del"
+)
+
+// taggedArrays are the arrays whose items are merged by the given key, instead of being replaced as a whole.
+var taggedArrays = map[string]string{
+	"inbounds":          "tag",
+	"outbounds":         "tag",
+	"routing.rules":     "ruleTag",
+	"routing.balancers": "tag",
+}
+
+// mergeTree returns src deep-merged onto dst, which may be modified.
+// Objects are merged key by key, the arrays in taggedArrays are merged by tag, and the other values are replaced.
+// An object with "_del": true deletes the key or the tagged item it is merged onto.
+func mergeTree(dst, src interface{}, path string) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		d, ok := dst.(map[string]interface{})
+		if !ok {
+			d = make(map[string]interface{}, len(s))
+		}
+		for k, v := range s {
+			if k == deleteKey {
+				continue
+			}
+			if isDeleted(v) {
+				delete(d, k)
+				continue
+			}
+			d[k] = mergeTree(d[k], v, childPath(path, k))
+		}
+		return d
+	case []interface{}:
+		if key, found := taggedArrays[path]; found {
+			d, _ := dst.([]interface{})
+			return mergeTagged(d, s, key, path)
+		}
+		d := make([]interface{}, 0, len(s))
+		for _, v := range s {
+			if !isDeleted(v) {
+				d = append(d, mergeTree(nil, v, path+"[]"))
+			}
+		}
+		return d
+	default:
+		return src
+	}
+}
+
+func childPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// mergeTagged merges each item of src onto the item of dst with the same tag, or appends it if there is none.
+// Items without a tag are always appended.
+func mergeTagged(dst, src []interface{}, key string, path string) []interface{} {
+	for _, v := range src {
+		found := -1
+		if item, ok := v.(map[string]interface{}); ok {
+			if tag, _ := item[key].(string); tag != "" {
+				found = findTagged(dst, key, tag)
+			}
+		}
+		switch {
+		case found >= 0 && isDeleted(v):
+			dst = append(dst[:found], dst[found+1:]...)
+		case found >= 0:
+			dst[found] = mergeTree(dst[found], v, path+"[]")
+		case !isDeleted(v):
+			dst = append(dst, mergeTree(nil, v, path+"[]"))
+		}
+	}
+	return dst
+}
+
+func findTagged(items []interface{}, key string, tag string) int {
+	for i, v := range items {
+		if item, ok := v.(map[string]interface{}); ok && item[key] == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+func isDeleted(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	del, _ := m[deleteKey].(bool)
+	return del
+}
+
+// decodeTree decodes a config file into a generic tree, converting YAML and TOML the same way as their decoders do.
+func decodeTree(data []byte, format string) (map[string]interface{}, error) {
+	switch format {
+	case "json":
+	case "yaml":
+		var err error
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, errors.New("failed to convert yaml to json").Base(err)
+		}
+	case "toml":
+		tree := make(map[string]interface{})
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return nil, errors.New("failed to convert toml to map").Base(err)
+		}
+		jsonFile, err := json.Marshal(&tree)
+		if err != nil {
+			return nil, errors.New("failed to convert map to json").Base(err)
+		}
+		data = jsonFile
+	default:
+		return nil, errors.New("unknown format ", format)
+	}
+
+	var tree map[string]interface{}
+	decoder := json.NewDecoder(&json_reader.Reader{Reader: bytes.NewReader(data)})
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, errors.New("failed to decode config").Base(err)
+	}
+	if tree == nil {
+		return nil, errors.New("config is not an object")
+	}
+	return tree, nil
+}
+
+// decodeTreeConfig decodes a merged tree into *conf.Config.
+func decodeTreeConfig(tree map[string]interface{}) (*conf.Config, error) {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, errors.New("failed to convert map to json").Base(err)
+	}
+	return decodeJSONConfig(bytes.NewReader(data), false)
+}
+
+// configFile is a config file read by readConfig.
+type configFile struct {
+	name   string
+	format string
+	data   []byte
+	config *conf.Config
+}
+
+// readConfig reads a config file and decodes it, so that its own errors are reported with their position.
+// In the strict mode, the unknown fields of the file are appended to unknown if it is set, or returned as an error otherwise.
+func readConfig(name string, format string, unknown *[]*core.ConfigProblem) (*configFile, error) {
+	decode, found := ReaderDecoderByFormat[format]
+	if !found {
+		return nil, errors.New("unknown format ", format, " of config: ", name)
+	}
+	r, err := confloader.LoadConfig(name)
+	if err != nil {
+		return nil, errors.New("failed to read config: ", name).Base(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("failed to read config: ", name).Base(err)
+	}
+	if !core.ConfigNoEnv && bytes.Contains(data, []byte("${")) {
+		// A config that fails to decode is left to the decoder below, which tells where.
+		if tree, err := decodeTree(data, format); err == nil {
+			if _, err := expandEnv(tree, ""); err != nil {
+				return nil, errors.New("failed to substitute environment variables in config: ", name).Base(err)
+			}
+			if data, err = json.Marshal(tree); err != nil {
+				return nil, errors.New("failed to convert map to json").Base(err)
+			}
+			format, decode = "json", DecodeJSONConfig
+		}
+	}
+	c, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("failed to decode ", format, " config: ", name).Base(err)
+	}
+	if core.ConfigStrict {
+		problems, err := checkUnknownFields(name, format, data)
+		if err != nil {
+			return nil, err
+		}
+		if len(problems) > 0 {
+			if unknown == nil {
+				return nil, &unknownFieldsError{problems: problems}
+			}
+			*unknown = append(*unknown, problems...)
+		}
+	}
+	return &configFile{name: name, format: format, data: data, config: c}, nil
+}
+
+// unknownFieldsError is the error of a config file with unknown fields, in the strict mode.
+type unknownFieldsError struct {
+	problems []*core.ConfigProblem
+}
+
+func (e *unknownFieldsError) Error() string {
+	var b strings.Builder
+	b.WriteString("unknown fields in config: ")
+	b.WriteString(e.problems[0].File)
+	for _, p := range e.problems {
+		b.WriteString("\n\t" + p.Path + ": " + p.Message)
+	}
+	return b.String()
+}
+
+// checkUnknownFields returns the problems of the keys of the config file that no field is decoded from.
+func checkUnknownFields(name string, format string, data []byte) ([]*core.ConfigProblem, error) {
+	tree, err := decodeTree(data, format)
+	if err != nil {
+		return nil, errors.New("failed to decode ", format, " config: ", name).Base(err)
+	}
+	j, err := json.Marshal(tree)
+	if err != nil {
+		return nil, errors.New("failed to convert map to json").Base(err)
+	}
+	var problems []*core.ConfigProblem
+	for _, p := range conf.CheckUnknownFields(j) {
+		// the key of deep merging
+		if p.Path == deleteKey || strings.HasSuffix(p.Path, "."+deleteKey) {
+			continue
+		}
+		p.File = name
+		problems = append(problems, p)
+	}
+	return problems, nil
+}
+
+// resolveIncludes returns the tree of a config file deep-merged onto the files it includes, in order.
+// stack holds the including files, to detect include cycles. The unknown fields of the included files go to unknown, like by readConfig.
+func resolveIncludes(file *configFile, stack []string, unknown *[]*core.ConfigProblem) (map[string]interface{}, error) {
+	tree, err := decodeTree(file.data, file.format)
+	if err != nil {
+		return nil, errors.New("failed to decode ", file.format, " config: ", file.name).Base(err)
+	}
+	includes, err := includesOf(tree)
+	if err != nil {
+		return nil, errors.New("invalid include in config: ", file.name).Base(err)
+	}
+	delete(tree, includeKey)
+	if len(includes) == 0 {
+		return tree, nil
+	}
+
+	id := includeID(file.name)
+	for i, s := range stack {
+		if s == id {
+			return nil, errors.New("include cycle: ", strings.Join(append(stack[i:], id), " -> "))
+		}
+	}
+	stack = append(stack, id)
+
+	base := make(map[string]interface{})
+	for _, include := range includes {
+		name := resolveInclude(file.name, include)
+		format := core.GetFormatByExtension(strings.TrimPrefix(filepath.Ext(name), "."))
+		if format == "" {
+			format = file.format
+		}
+		errors.LogInfo(context.Background(), "Reading included config: ", name)
+		included, err := readConfig(name, format, unknown)
+		if err != nil {
+			return nil, errors.New("failed to include config in ", file.name).Base(err)
+		}
+		t, err := resolveIncludes(included, stack, unknown)
+		if err != nil {
+			return nil, err
+		}
+		mergeTree(base, t, "")
+	}
+	return mergeTree(base, tree, "").(map[string]interface{}), nil
+}
+
+func includesOf(tree map[string]interface{}) ([]string, error) {
+	switch v := tree[includeKey].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Split(v, ","), nil
+	case []interface{}:
+		includes := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, errors.New("not a file name: ", item)
+			}
+			includes = append(includes, s)
+		}
+		return includes, nil
+	default:
+		return nil, errors.New("not a list of file names: ", v)
+	}
+}
+
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// resolveInclude resolves an included file relative to the including one. A config from stdin includes files relative to the working directory.
+func resolveInclude(name string, include string) string {
+	if isURL(include) {
+		return include
+	}
+	if isURL(name) {
+		if base, err := url.Parse(name); err == nil {
+			if ref, err := url.Parse(include); err == nil {
+				return base.ResolveReference(ref).String()
+			}
+		}
+		return include
+	}
+	if filepath.IsAbs(include) || name == "stdin:" {
+		return include
+	}
+	return filepath.Join(filepath.Dir(name), include)
+}
+
+func includeID(name string) string {
+	if isURL(name) || name == "stdin:" {
+		return name
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return name
+}
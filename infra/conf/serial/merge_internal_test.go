@@ -0,0 +1,166 @@
+package serial
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeTree(t *testing.T) {
+	cases := []struct {
+		name   string
+		trees  []string
+		output string
+	}{
+		{
+			name:   "objects",
+			trees:  []string{`{"log": {"loglevel": "debug", "access": "a.log"}, "dns": {"servers": ["1.1.1.1"]}}`, `{"log": {"loglevel": "info"}, "dns": {"servers": ["8.8.8.8"]}}`},
+			output: `{"log": {"loglevel": "info", "access": "a.log"}, "dns": {"servers": ["8.8.8.8"]}}`,
+		},
+		{
+			name:   "delete a key",
+			trees:  []string{`{"log": {"loglevel": "debug"}, "api": {"tag": "api"}}`, `{"api": {"_del": true}}`},
+			output: `{"log": {"loglevel": "debug"}}`,
+		},
+		{
+			name:   "delete a missing key",
+			trees:  []string{`{"log": {"loglevel": "debug"}}`, `{"api": {"_del": true}}`},
+			output: `{"log": {"loglevel": "debug"}}`,
+		},
+		{
+			name:   "value replaced by object",
+			trees:  []string{`{"log": "none"}`, `{"log": {"loglevel": "info"}}`},
+			output: `{"log": {"loglevel": "info"}}`,
+		},
+		{
+			name: "override an outbound",
+			trees: []string{
+				`{"outbounds": [{"tag": "direct", "protocol": "freedom"}, {"tag": "proxy", "protocol": "vless", "settings": {"address": "a.example.com", "port": 443}}]}`,
+				`{"outbounds": [{"tag": "proxy", "settings": {"address": "b.example.com"}}]}`,
+			},
+			output: `{"outbounds": [{"tag": "direct", "protocol": "freedom"}, {"tag": "proxy", "protocol": "vless", "settings": {"address": "b.example.com", "port": 443}}]}`,
+		},
+		{
+			name: "append outbounds",
+			trees: []string{
+				`{"outbounds": [{"tag": "direct", "protocol": "freedom"}]}`,
+				`{"outbounds": [{"tag": "block", "protocol": "blackhole"}, {"protocol": "dns"}]}`,
+			},
+			output: `{"outbounds": [{"tag": "direct", "protocol": "freedom"}, {"tag": "block", "protocol": "blackhole"}, {"protocol": "dns"}]}`,
+		},
+		{
+			name: "untagged items are appended",
+			trees: []string{
+				`{"inbounds": [{"port": 1080}]}`,
+				`{"inbounds": [{"port": 1080}]}`,
+			},
+			output: `{"inbounds": [{"port": 1080}, {"port": 1080}]}`,
+		},
+		{
+			name: "delete an inbound",
+			trees: []string{
+				`{"inbounds": [{"tag": "socks", "port": 1080}, {"tag": "http", "port": 8080}]}`,
+				`{"inbounds": [{"tag": "socks", "_del": true}, {"tag": "missing", "_del": true}]}`,
+			},
+			output: `{"inbounds": [{"tag": "http", "port": 8080}]}`,
+		},
+		{
+			name: "delete and add back",
+			trees: []string{
+				`{"inbounds": [{"tag": "socks", "port": 1080, "settings": {"udp": true}}]}`,
+				`{"inbounds": [{"tag": "socks", "_del": true}, {"tag": "socks", "port": 1081}]}`,
+			},
+			output: `{"inbounds": [{"tag": "socks", "port": 1081}]}`,
+		},
+		{
+			name: "rules by ruleTag",
+			trees: []string{
+				`{"routing": {"domainStrategy": "AsIs", "rules": [{"ruleTag": "ads", "domain": ["geosite:ads"], "outboundTag": "block"}, {"ip": ["geoip:private"], "outboundTag": "direct"}]}}`,
+				`{"routing": {"rules": [{"ruleTag": "ads", "outboundTag": "direct"}, {"ruleTag": "cn", "ip": ["geoip:cn"], "outboundTag": "direct"}]}}`,
+			},
+			output: `{"routing": {"domainStrategy": "AsIs", "rules": [{"ruleTag": "ads", "domain": ["geosite:ads"], "outboundTag": "direct"}, {"ip": ["geoip:private"], "outboundTag": "direct"}, {"ruleTag": "cn", "ip": ["geoip:cn"], "outboundTag": "direct"}]}}`,
+		},
+		{
+			name: "rules are not merged by tag",
+			trees: []string{
+				`{"routing": {"rules": [{"tag": "a", "outboundTag": "block"}]}}`,
+				`{"routing": {"rules": [{"tag": "a", "outboundTag": "direct"}]}}`,
+			},
+			output: `{"routing": {"rules": [{"tag": "a", "outboundTag": "block"}, {"tag": "a", "outboundTag": "direct"}]}}`,
+		},
+		{
+			name: "balancers",
+			trees: []string{
+				`{"routing": {"balancers": [{"tag": "b", "selector": ["a"]}]}}`,
+				`{"routing": {"balancers": [{"tag": "b", "selector": ["b", "c"]}]}}`,
+			},
+			output: `{"routing": {"balancers": [{"tag": "b", "selector": ["b", "c"]}]}}`,
+		},
+		{
+			name: "other arrays are replaced",
+			trees: []string{
+				`{"dns": {"servers": [{"address": "1.1.1.1", "tag": "a"}]}, "outbounds": [{"tag": "proxy", "settings": {"vnext": [{"address": "a"}, {"address": "b"}]}}]}`,
+				`{"dns": {"servers": [{"address": "8.8.8.8", "tag": "a"}]}, "outbounds": [{"tag": "proxy", "settings": {"vnext": [{"address": "c"}]}}]}`,
+			},
+			output: `{"dns": {"servers": [{"address": "8.8.8.8", "tag": "a"}]}, "outbounds": [{"tag": "proxy", "settings": {"vnext": [{"address": "c"}]}}]}`,
+		},
+		{
+			name: "markers are dropped from new values",
+			trees: []string{
+				`{}`,
+				`{"log": {"access": {"_del": true}, "loglevel": "info"}, "inbounds": [{"tag": "a", "_del": true}, {"tag": "b", "_del": false}], "dns": {"servers": [{"_del": true}, "1.1.1.1"]}}`,
+			},
+			output: `{"log": {"loglevel": "info"}, "inbounds": [{"tag": "b"}], "dns": {"servers": ["1.1.1.1"]}}`,
+		},
+		{
+			name: "three files",
+			trees: []string{
+				`{"outbounds": [{"tag": "proxy", "protocol": "vless"}]}`,
+				`{"outbounds": [{"tag": "proxy", "_del": true}]}`,
+				`{"outbounds": [{"tag": "proxy", "protocol": "trojan"}]}`,
+			},
+			output: `{"outbounds": [{"tag": "proxy", "protocol": "trojan"}]}`,
+		},
+	}
+
+	for _, test := range cases {
+		var tree interface{} = map[string]interface{}{}
+		for _, s := range test.trees {
+			var src interface{}
+			if err := json.Unmarshal([]byte(s), &src); err != nil {
+				t.Fatal(test.name, ": ", err)
+			}
+			tree = mergeTree(tree, src, "")
+		}
+		var output interface{}
+		if err := json.Unmarshal([]byte(test.output), &output); err != nil {
+			t.Fatal(test.name, ": ", err)
+		}
+		if !reflect.DeepEqual(tree, output) {
+			b, _ := json.Marshal(tree)
+			t.Error(test.name, ": unexpected result ", string(b))
+		}
+	}
+}
+
+func TestResolveInclude(t *testing.T) {
+	cases := []struct {
+		name    string
+		include string
+		output  string
+	}{
+		{name: "conf/base.json", include: "outbounds.json", output: "conf/outbounds.json"},
+		{name: "conf/base.json", include: "../common/log.json", output: "common/log.json"},
+		{name: "conf/base.json", include: "/etc/xray/log.json", output: "/etc/xray/log.json"},
+		{name: "stdin:", include: "log.json", output: "log.json"},
+		{name: "https://example.com/conf/base.json", include: "outbounds.json", output: "https://example.com/conf/outbounds.json"},
+		{name: "https://example.com/conf/base.json", include: "/log.json", output: "https://example.com/log.json"},
+		{name: "conf/base.json", include: "https://example.com/log.json", output: "https://example.com/log.json"},
+	}
+
+	for _, test := range cases {
+		if output := resolveInclude(test.name, test.include); output != test.output {
+			t.Error(test.name, " includes ", test.include, ": unexpected result ", output)
+		}
+	}
+}
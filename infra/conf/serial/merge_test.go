@@ -0,0 +1,255 @@
+package serial_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf/serial"
+	_ "github.com/xtls/xray-core/main/confloader/external"
+)
+
+func writeConfigs(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		common.Must(os.MkdirAll(filepath.Dir(path), 0o700))
+		common.Must(os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func setMergeStrategy(t *testing.T, strategy string) {
+	core.ConfigMergeStrategy = strategy
+	t.Cleanup(func() { core.ConfigMergeStrategy = "" })
+}
+
+func outboundTags(config *core.Config) []string {
+	var tags []string
+	for _, ob := range config.Outbound {
+		tags = append(tags, ob.Tag)
+	}
+	return tags
+}
+
+func TestDeepMerge(t *testing.T) {
+	setMergeStrategy(t, serial.MergeDeep)
+	dir := writeConfigs(t, map[string]string{
+		"base.json": `{
+			"log": {"loglevel": "debug"},
+			"inbounds": [{"tag": "socks", "protocol": "socks", "port": 1080}, {"tag": "http", "protocol": "http", "port": 8080}],
+			"outbounds": [{"tag": "proxy", "protocol": "freedom", "settings": {"domainStrategy": "UseIP"}}, {"tag": "direct", "protocol": "freedom"}]
+		}`,
+		"override.yaml": "inbounds:\n  - tag: http\n    _del: true\noutbounds:\n  - tag: proxy\n    protocol: blackhole\n    settings: {_del: true}\n  - tag: block\n    protocol: blackhole\n",
+		"tail.toml":     "[[inbounds]]\ntag = \"socks\"\nport = 1081\n",
+	})
+
+	config, err := serial.BuildConfig([]*core.ConfigSource{
+		{Name: filepath.Join(dir, "base.json"), Format: "json"},
+		{Name: filepath.Join(dir, "override.yaml"), Format: "yaml"},
+		{Name: filepath.Join(dir, "tail.toml"), Format: "toml"},
+	})
+	common.Must(err)
+
+	if len(config.Inbound) != 1 || config.Inbound[0].Tag != "socks" || config.Inbound[0].ReceiverSettings == nil {
+		t.Fatal("unexpected inbounds: ", config.Inbound)
+	}
+	if tags := outboundTags(config); strings.Join(tags, ",") != "proxy,direct,block" {
+		t.Error("unexpected outbounds: ", tags)
+	}
+	if config.Outbound[0].ProxySettings.Type != "xray.proxy.blackhole.Config" {
+		t.Error("expected the proxy outbound overridden, but got ", config.Outbound[0].ProxySettings.Type)
+	}
+}
+
+func TestDeepMergeInboundPort(t *testing.T) {
+	setMergeStrategy(t, serial.MergeDeep)
+	dir := writeConfigs(t, map[string]string{
+		"base.json":     `{"inbounds": [{"tag": "socks", "protocol": "socks", "port": 1080, "listen": "127.0.0.1"}]}`,
+		"override.json": `{"inbounds": [{"tag": "socks", "port": 1081}]}`,
+	})
+
+	merged, err := serial.MergeConfigFromFiles([]*core.ConfigSource{
+		{Name: filepath.Join(dir, "base.json"), Format: "json"},
+		{Name: filepath.Join(dir, "override.json"), Format: "json"},
+	})
+	common.Must(err)
+	if !strings.Contains(merged, "1081") || strings.Contains(merged, "1080") || !strings.Contains(merged, "127.0.0.1") {
+		t.Error("unexpected merged config: ", merged)
+	}
+}
+
+func TestOverrideMerge(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"base.json":     `{"log": {"loglevel": "debug", "access": "none"}, "outbounds": [{"tag": "direct", "protocol": "freedom"}]}`,
+		"override.json": `{"log": {"loglevel": "info"}, "outbounds": [{"tag": "block", "protocol": "blackhole"}]}`,
+	})
+	files := []*core.ConfigSource{
+		{Name: filepath.Join(dir, "base.json"), Format: "json"},
+		{Name: filepath.Join(dir, "override.json"), Format: "json"},
+	}
+
+	// The default strategy replaces whole sections and prepends the new outbounds.
+	merged, err := serial.MergeConfigFromFiles(files)
+	common.Must(err)
+	if strings.Contains(merged, `"none"`) {
+		t.Error("expected the log section replaced, but got ", merged)
+	}
+	config, err := serial.BuildConfig(files)
+	common.Must(err)
+	if tags := outboundTags(config); strings.Join(tags, ",") != "block,direct" {
+		t.Error("unexpected outbounds: ", tags)
+	}
+
+	setMergeStrategy(t, "shallow")
+	if _, err := serial.BuildConfig(files); err == nil || !strings.Contains(err.Error(), "unknown merge strategy") {
+		t.Error("expected an unknown strategy error, but got ", err)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"config.json": `{
+			"include": ["common/outbounds.json", "common/log.yaml"],
+			"outbounds": [{"tag": "proxy", "settings": {"domainStrategy": "UseIPv4"}}, {"tag": "block", "_del": true}]
+		}`,
+		"common/outbounds.json": `{
+			"include": "../base/direct.json",
+			"outbounds": [{"tag": "proxy", "protocol": "freedom", "settings": {"domainStrategy": "UseIP"}}, {"tag": "block", "protocol": "blackhole"}]
+		}`,
+		"common/log.yaml":  "log:\n  loglevel: warning\n",
+		"base/direct.json": `{"log": {"loglevel": "debug"}, "outbounds": [{"tag": "direct", "protocol": "freedom"}]}`,
+	})
+	files := []*core.ConfigSource{{Name: filepath.Join(dir, "config.json"), Format: "json"}}
+
+	for _, strategy := range []string{"", serial.MergeDeep} {
+		setMergeStrategy(t, strategy)
+		merged, err := serial.MergeConfigFromFiles(files)
+		common.Must(err)
+		if !strings.Contains(merged, "UseIPv4") || strings.Contains(merged, "blackhole") || !strings.Contains(merged, "warning") || strings.Contains(merged, "include") {
+			t.Error(strategy, ": unexpected merged config: ", merged)
+		}
+		config, err := serial.BuildConfig(files)
+		common.Must(err)
+		if tags := outboundTags(config); strings.Join(tags, ",") != "direct,proxy" {
+			t.Error(strategy, ": unexpected outbounds: ", tags)
+		}
+	}
+}
+
+func TestIncludeShared(t *testing.T) {
+	setMergeStrategy(t, serial.MergeDeep)
+	dir := writeConfigs(t, map[string]string{
+		"a.json":      `{"include": ["shared.json"], "outbounds": [{"tag": "a", "protocol": "freedom"}]}`,
+		"b.json":      `{"include": ["shared.json"], "outbounds": [{"tag": "b", "protocol": "freedom"}]}`,
+		"shared.json": `{"outbounds": [{"tag": "shared", "protocol": "freedom"}]}`,
+	})
+
+	config, err := serial.BuildConfig([]*core.ConfigSource{
+		{Name: filepath.Join(dir, "a.json"), Format: "json"},
+		{Name: filepath.Join(dir, "b.json"), Format: "json"},
+	})
+	common.Must(err)
+	if tags := outboundTags(config); strings.Join(tags, ",") != "shared,a,b" {
+		t.Error("unexpected outbounds: ", tags)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"self.json":    `{"include": ["self.json"]}`,
+		"a.json":       `{"include": ["sub/b.json"]}`,
+		"sub/b.json":   `{"include": ["../a.json"]}`,
+		"missing.json": `{"include": ["nothing.json"]}`,
+		"invalid.json": `{"include": [1]}`,
+		"bad.json":     `{"include": ["broken.json"]}`,
+		"broken.json":  `{"inbounds": [{"port": [1080]}]}`,
+	})
+
+	cases := []struct {
+		file  string
+		error []string
+	}{
+		{file: "self.json", error: []string{"include cycle", "self.json -> "}},
+		{file: "a.json", error: []string{"include cycle", filepath.Join("sub", "b.json")}},
+		{file: "missing.json", error: []string{"nothing.json"}},
+		{file: "invalid.json", error: []string{"invalid.json", "key include"}},
+		{file: "bad.json", error: []string{"broken.json", "inbounds[0].port"}},
+	}
+
+	for _, strategy := range []string{"", serial.MergeDeep} {
+		setMergeStrategy(t, strategy)
+		for _, test := range cases {
+			_, err := serial.BuildConfig([]*core.ConfigSource{{Name: filepath.Join(dir, test.file), Format: "json"}})
+			if err == nil {
+				t.Error(test.file, ": expected an error")
+				continue
+			}
+			for _, s := range test.error {
+				if !strings.Contains(err.Error(), s) {
+					t.Error(test.file, ": expected ", s, " in the error, but got ", err)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckStrict(t *testing.T) {
+	core.ConfigStrict = true
+	t.Cleanup(func() { core.ConfigStrict = false })
+	dir := writeConfigs(t, map[string]string{
+		"a.json": `{
+			"include": ["common.json"],
+			"outbounds": [{"tag": "proxy", "protocol": "freedom", "severSettings": {}}]
+		}`,
+		"b.json": `{
+			"log": {"loglevl": "warning"},
+			"inbounds": [{"port": 1080, "protocol": "http", "streamSettings": {"security": "tls", "tlsSettings": {"certificates": [{"certificateFile": "missing.crt", "keyFile": "missing.key"}]}}}]
+		}`,
+		"common.json": `{"outbounds": [{"tag": "direct", "protocol": "freedom", "sendThrough": "0.0.0.0", "mxu": {}}]}`,
+	})
+
+	problems, err := serial.CheckConfigFromFiles([]*core.ConfigSource{
+		{Name: filepath.Join(dir, "a.json"), Format: "json"},
+		{Name: filepath.Join(dir, "b.json"), Format: "json"},
+	}, 0)
+	common.Must(err)
+	var found []string
+	for _, p := range problems {
+		found = append(found, p.String())
+	}
+	for _, s := range []string{"severSettings", "mxu", "loglevl", "missing.crt"} {
+		if !strings.Contains(strings.Join(found, "\n"), s) {
+			t.Error("expected a problem of ", s, ", but got ", found)
+		}
+	}
+}
+
+func TestCheckConfigStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	common.Must(err)
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+	common.Must2(w.WriteString(`{
+		"inbounds": [{"port": 1080, "protocol": "http", "streamSettings": {"security": "tls", "tlsSettings": {"certificates": [{"certificateFile": "missing.crt", "keyFile": "missing.key"}]}}}]
+	}`))
+	common.Must(w.Close())
+
+	// run -test checks the config, and then loads it again to build it
+	for i := 0; i < 2; i++ {
+		problems, err := core.CheckConfig("auto", cmdarg.Arg{"stdin:"}, 0)
+		common.Must(err)
+		var found []string
+		for _, p := range problems {
+			found = append(found, p.String())
+		}
+		if !strings.Contains(strings.Join(found, "\n"), "missing.crt") {
+			t.Error("expected a problem of missing.crt, but got ", found)
+		}
+	}
+}
@@ -23,6 +23,8 @@ func cipherFromString(c string) shadowsocks.CipherType {
 		return shadowsocks.CipherType_CHACHA20_POLY1305
 	case "xchacha20-poly1305", "aead_xchacha20_poly1305", "xchacha20-ietf-poly1305":
 		return shadowsocks.CipherType_XCHACHA20_POLY1305
+	case "aes-256-gcm-siv", "aead_aes_256_gcm_siv":
+		return shadowsocks.CipherType_AES_256_GCM_SIV
 	case "none", "plain":
 		return shadowsocks.CipherType_NONE
 	default:
@@ -67,8 +69,8 @@ func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 			if account.Password == "" {
 				return nil, errors.New("Shadowsocks password is not specified.")
 			}
-			if account.CipherType < shadowsocks.CipherType_AES_128_GCM ||
-				account.CipherType > shadowsocks.CipherType_XCHACHA20_POLY1305 {
+			if account.CipherType == shadowsocks.CipherType_UNKNOWN ||
+				account.CipherType == shadowsocks.CipherType_NONE {
 				return nil, errors.New("unsupported cipher method: ", user.Cipher)
 			}
 			config.Users = append(config.Users, &protocol.User{
@@ -160,19 +162,19 @@ func buildShadowsocks2022(v *ShadowsocksServerConfig) (proto.Message, error) {
 }
 
 type ShadowsocksServerTarget struct {
-	Address    *Address `json:"address"`
-	Port       uint16   `json:"port"`
-	Level      byte     `json:"level"`
-	Email      string   `json:"email"`
-	Cipher     string   `json:"method"`
-	Password   string   `json:"password"`
-	IVCheck    bool     `json:"ivCheck"`
-	UoT        bool     `json:"uot"`
-	UoTVersion int      `json:"uotVersion"`
+	Address    AddressList `json:"address"`
+	Port       uint16      `json:"port"`
+	Level      byte        `json:"level"`
+	Email      string      `json:"email"`
+	Cipher     string      `json:"method"`
+	Password   string      `json:"password"`
+	IVCheck    bool        `json:"ivCheck"`
+	UoT        bool        `json:"uot"`
+	UoTVersion int         `json:"uotVersion"`
 }
 
 type ShadowsocksClientConfig struct {
-	Address    *Address                   `json:"address"`
+	Address    AddressList                `json:"address"`
 	Port       uint16                     `json:"port"`
 	Level      byte                       `json:"level"`
 	Email      string                     `json:"email"`
@@ -185,7 +187,7 @@ type ShadowsocksClientConfig struct {
 }
 
 func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
-	if v.Address != nil {
+	if len(v.Address) > 0 {
 		v.Servers = []*ShadowsocksServerTarget{
 			{
 				Address:    v.Address,
@@ -207,9 +209,12 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 	if len(v.Servers) == 1 {
 		server := v.Servers[0]
 		if C.Contains(shadowaead_2022.List, server.Cipher) {
-			if server.Address == nil {
+			if len(server.Address) == 0 {
 				return nil, errors.New("Shadowsocks server address is not set.")
 			}
+			if len(server.Address) > 1 {
+				return nil, errors.New("Shadowsocks 2022 accept only one server address")
+			}
 			if server.Port == 0 {
 				return nil, errors.New("Invalid Shadowsocks port.")
 			}
@@ -218,7 +223,7 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 			}
 
 			config := new(shadowsocks_2022.ClientConfig)
-			config.Address = server.Address.Build()
+			config.Address = server.Address[0].Build()
 			config.Port = uint32(server.Port)
 			config.Method = server.Cipher
 			config.Key = server.Password
@@ -233,7 +238,7 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 		if C.Contains(shadowaead_2022.List, server.Cipher) {
 			return nil, errors.New("Shadowsocks 2022 accept no multi servers")
 		}
-		if server.Address == nil {
+		if len(server.Address) == 0 {
 			return nil, errors.New("Shadowsocks server address is not set.")
 		}
 		if server.Port == 0 {
@@ -252,10 +257,12 @@ func (v *ShadowsocksClientConfig) Build() (proto.Message, error) {
 
 		account.IvCheck = server.IVCheck
 
+		address, alternatives := server.Address.buildServer()
 		ss := &protocol.ServerEndpoint{
-			Address: server.Address.Build(),
-			Port:    uint32(server.Port),
-			User:    &protocol.User{
+			Address:              address,
+			Port:                 uint32(server.Port),
+			AlternativeAddresses: alternatives,
+			User: &protocol.User{
 				Level:   uint32(server.Level),
 				Email:   server.Email,
 				Account: serial.ToTypedMessage(account),
@@ -32,5 +32,31 @@ func TestShadowsocksServerConfigParsing(t *testing.T) {
 				Network: []net.Network{net.Network_TCP},
 			},
 		},
+		{
+			Input: `{
+				"clients": [
+					{"method": "aes-256-gcm-siv", "password": "xray-password"},
+					{"method": "xchacha20-ietf-poly1305", "password": "xray-password-2"}
+				]
+			}`,
+			Parser: loadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				Users: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_AES_256_GCM_SIV,
+							Password:   "xray-password",
+						}),
+					},
+					{
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_XCHACHA20_POLY1305,
+							Password:   "xray-password-2",
+						}),
+					},
+				},
+				Network: []net.Network{net.Network_TCP},
+			},
+		},
 	})
 }
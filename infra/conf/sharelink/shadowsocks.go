@@ -0,0 +1,72 @@
+package sharelink
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// parseShadowsocks parses the SIP002 links, ss://userinfo@host:port?plugin=...#name, where userinfo is
+// the base64 encoded method:password, or the percent encoded one for Shadowsocks 2022.
+// The legacy links, of which the whole method:password@host:port is base64 encoded, are parsed as well.
+func parseShadowsocks(rest string) (*Link, error) {
+	rest, fragment, _ := strings.Cut(rest, "#")
+	name, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, errors.New("invalid Shadowsocks link name").Base(err)
+	}
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	rest = strings.TrimSuffix(rest, "/")
+
+	var userinfo, hostport string
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		userinfo, hostport = rest[:i], rest[i+1:]
+		if !strings.Contains(userinfo, ":") {
+			b, err := decodeBase64(userinfo)
+			if err != nil {
+				return nil, errors.New("invalid Shadowsocks link").Base(err)
+			}
+			userinfo = string(b)
+		} else if userinfo, err = url.PathUnescape(userinfo); err != nil {
+			return nil, errors.New("invalid Shadowsocks link").Base(err)
+		}
+	} else {
+		b, err := decodeBase64(rest)
+		if err != nil {
+			return nil, errors.New("invalid Shadowsocks link").Base(err)
+		}
+		i := strings.LastIndex(string(b), "@")
+		if i < 0 {
+			return nil, errors.New("Shadowsocks link has no server")
+		}
+		userinfo, hostport = string(b[:i]), string(b[i+1:])
+	}
+	method, password, ok := strings.Cut(userinfo, ":")
+	if !ok {
+		return nil, errors.New("Shadowsocks link has no password")
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, errors.New("invalid Shadowsocks link").Base(err)
+	}
+	return newLink("shadowsocks", name, hostport, server{Method: method, Password: password}, query)
+}
+
+// formatShadowsocks returns the SIP002 link of the server.
+func formatShadowsocks(u *url.URL, srv server, query url.Values) string {
+	u.Scheme = "ss"
+	if strings.HasPrefix(srv.Method, "2022-") {
+		u.User = url.UserPassword(srv.Method, srv.Password)
+	} else {
+		u.User = url.User(base64.RawURLEncoding.EncodeToString([]byte(srv.Method + ":" + srv.Password)))
+	}
+	// links of plain Shadowsocks have no transport
+	if query.Get("type") == "tcp" {
+		query.Del("type")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
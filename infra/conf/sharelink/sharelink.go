@@ -0,0 +1,211 @@
+// Package sharelink converts the share links of VLESS, VMess, Trojan and Shadowsocks servers,
+// like vless://id@example.com:443?security=reality&pbk=...#name, to outbound configs and back.
+package sharelink
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// Link is a share link parsed into an outbound config.
+type Link struct {
+	Outbound *conf.OutboundDetourConfig
+	// Extra holds the query parameters of the link that have no place in the outbound config,
+	// so that they are not lost when the link is formatted again.
+	Extra url.Values
+}
+
+// server is the part of the outbound settings that a share link carries.
+type server struct {
+	Address    string   `json:"address,omitempty"`
+	Port       uint16   `json:"port,omitempty"`
+	ID         string   `json:"id,omitempty"`
+	Flow       string   `json:"flow,omitempty"`
+	Encryption string   `json:"encryption,omitempty"`
+	Security   string   `json:"security,omitempty"`
+	Method     string   `json:"method,omitempty"`
+	Password   string   `json:"password,omitempty"`
+	Users      []server `json:"users,omitempty"`
+}
+
+type settings struct {
+	server
+	Vnext   []server `json:"vnext,omitempty"`
+	Servers []server `json:"servers,omitempty"`
+}
+
+// Parse parses a vless://, vmess://, trojan:// or ss:// share link.
+func Parse(link string) (*Link, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(link), "://")
+	if !ok {
+		return nil, errors.New("invalid share link: ", link)
+	}
+	switch strings.ToLower(scheme) {
+	case "vless", "trojan":
+		return parseURL(link)
+	case "vmess":
+		// base64 has no @, which separates the user of the standard format
+		if strings.Contains(rest, "@") {
+			return parseURL(link)
+		}
+		return parseVMess(rest)
+	case "ss":
+		return parseShadowsocks(rest)
+	default:
+		return nil, errors.New("unsupported share link scheme: ", scheme)
+	}
+}
+
+// Format returns the share link of the outbound.
+func Format(l *Link) (string, error) {
+	ob := l.Outbound
+	if ob == nil || ob.Settings == nil {
+		return "", errors.New("outbound has no settings")
+	}
+	var s settings
+	if err := json.Unmarshal(*ob.Settings, &s); err != nil {
+		return "", errors.New("invalid outbound settings").Base(err)
+	}
+	srv := s.resolve()
+	if srv.Address == "" || srv.Port == 0 {
+		return "", errors.New("outbound has no server")
+	}
+
+	query := url.Values{}
+	for key, values := range l.Extra {
+		query[key] = append([]string(nil), values...)
+	}
+	if err := formatStream(ob.StreamSetting, query); err != nil {
+		return "", err
+	}
+
+	u := &url.URL{
+		Scheme:   ob.Protocol,
+		Host:     net.JoinHostPort(srv.Address, strconv.Itoa(int(srv.Port))),
+		Fragment: ob.Tag,
+	}
+	switch ob.Protocol {
+	case "vless":
+		u.User = url.User(srv.ID)
+		query.Set("encryption", srv.Encryption)
+		if srv.Encryption == "" {
+			query.Set("encryption", "none")
+		}
+		setIfNotEmpty(query, "flow", srv.Flow)
+	case "vmess":
+		return formatVMess(ob.Tag, srv, query)
+	case "trojan":
+		u.User = url.User(srv.Password)
+		setIfNotEmpty(query, "flow", srv.Flow)
+	case "shadowsocks":
+		return formatShadowsocks(u, srv, query), nil
+	default:
+		return "", errors.New("unsupported protocol: ", ob.Protocol)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// resolve returns the only server of the settings, which may be given in the list of servers.
+func (s *settings) resolve() server {
+	srv := s.server
+	if srv.Address == "" && len(s.Vnext) > 0 {
+		srv = s.Vnext[0]
+	}
+	if srv.Address == "" && len(s.Servers) > 0 {
+		srv = s.Servers[0]
+	}
+	if len(srv.Users) > 0 {
+		user := srv.Users[0]
+		srv.ID, srv.Flow, srv.Encryption, srv.Security = user.ID, user.Flow, user.Encryption, user.Security
+	}
+	return srv
+}
+
+// parseURL parses the links of the standard format, scheme://user@host:port?params#name.
+func parseURL(link string) (*Link, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, errors.New("invalid share link").Base(err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("share link has no user")
+	}
+	query := u.Query()
+	srv := server{}
+	protocol := strings.ToLower(u.Scheme)
+	switch protocol {
+	case "vless":
+		srv.ID = u.User.Username()
+		srv.Flow = take(query, "flow")
+		srv.Encryption = take(query, "encryption")
+		if srv.Encryption == "" {
+			srv.Encryption = "none"
+		}
+	case "vmess":
+		srv.ID = u.User.Username()
+		srv.Security = take(query, "encryption")
+	case "trojan":
+		srv.Password = u.User.Username()
+		srv.Flow = take(query, "flow")
+	}
+	return newLink(protocol, u.Fragment, u.Host, srv, query)
+}
+
+// newLink builds the outbound of the server at hostport, with the stream settings in query.
+func newLink(protocol, name, hostport string, srv server, query url.Values) (*Link, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, errors.New("invalid server address: ", hostport).Base(err)
+	}
+	if host == "" {
+		return nil, errors.New("share link has no server address")
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || p == 0 {
+		return nil, errors.New("invalid server port: ", port)
+	}
+	srv.Address = host
+	srv.Port = uint16(p)
+
+	stream, err := parseStream(query)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(srv)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(b)
+	l := &Link{
+		Outbound: &conf.OutboundDetourConfig{
+			Protocol:      protocol,
+			Tag:           name,
+			Settings:      &raw,
+			StreamSetting: stream,
+		},
+	}
+	if len(query) > 0 {
+		l.Extra = query
+	}
+	return l, nil
+}
+
+// take removes the parameter from query and returns its value.
+func take(query url.Values, key string) string {
+	value := query.Get(key)
+	query.Del(key)
+	return value
+}
+
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,144 @@
+package sharelink_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/infra/conf"
+	. "github.com/xtls/xray-core/infra/conf/sharelink"
+)
+
+func TestRoundTrip(t *testing.T) {
+	links := []string{
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?encryption=none&flow=xtls-rprx-vision&fp=chrome&pbk=Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw&security=reality&sid=6ba85179e30d4fc2&sni=www.microsoft.com&spx=%2F&type=tcp#reality",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@[2001:db8::1]:8443?encryption=none&host=cdn.example.com&mode=stream-up&path=%2Fxhttp&security=tls&sni=cdn.example.com&type=xhttp#xhttp%20v6",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?encryption=none&extra=%7B%22xPaddingBytes%22%3A%22100-1000%22%7D&path=%2Fx&security=tls&type=xhttp",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?alpn=h2%2Chttp%2F1.1&encryption=none&fp=firefox&host=example.com&path=%2Fws%3Fed%3D2048&security=tls&type=ws",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?authority=grpc.example.com&encryption=none&mode=multi&security=tls&serviceName=tunnel&type=grpc",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:80?encryption=none&host=example.com&path=%2Fup&type=httpupgrade",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:8080?encryption=none&headerType=http&host=a.example.com%2Cb.example.com&path=%2F&type=tcp",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:1000?encryption=none&headerType=wechat-video&seed=secret&type=kcp",
+		"vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?encryption=none&pbk=Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw&security=reality&sni=example.com&type=tcp&unknown=kept",
+		"trojan://password@example.com:443?allowInsecure=1&security=tls&sni=example.com&type=tcp#trojan",
+		"trojan://p%40ss@example.com:443?path=%2Fws&security=tls&type=ws",
+		"vmess://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?encryption=aes-128-gcm&security=tls&type=tcp",
+		"ss://YWVzLTI1Ni1nY206cGFzc3dvcmQ@example.com:8388#ss",
+		"ss://2022-blake3-aes-128-gcm:YctPZ6U7xPPcU%2BgP3A%3D%3D@example.com:8388?plugin=obfs-local%3Bobfs%3Dhttp#ss2022",
+	}
+	for _, link := range links {
+		t.Run(link, func(t *testing.T) {
+			l, err := Parse(link)
+			common.Must(err)
+			formatted, err := Format(l)
+			common.Must(err)
+			l2, err := Parse(formatted)
+			common.Must(err)
+			if a, b := toJSON(l), toJSON(l2); a != b {
+				t.Error("round trip changes the outbound from\n", a, "\nto\n", b)
+			}
+			if formatted2, _ := Format(l2); formatted2 != formatted {
+				t.Error("unstable link ", formatted, " ", formatted2)
+			}
+			if _, err := l.Outbound.Build(); err != nil {
+				t.Error("invalid outbound: ", err)
+			}
+		})
+	}
+}
+
+func TestVMessJSON(t *testing.T) {
+	// {"v":"2","ps":"vmess","add":"example.com","port":443,"id":"27848739-7e62-4138-9fd3-098a63964b6b","aid":"0","scy":"auto","net":"grpc","type":"multi","host":"","path":"tunnel","tls":"tls","sni":"example.com","fp":"chrome"}
+	link := "vmess://eyJ2IjoiMiIsInBzIjoidm1lc3MiLCJhZGQiOiJleGFtcGxlLmNvbSIsInBvcnQiOjQ0MywiaWQiOiIyNzg0ODczOS03ZTYyLTQxMzgtOWZkMy0wOThhNjM5NjRiNmIiLCJhaWQiOiIwIiwic2N5IjoiYXV0byIsIm5ldCI6ImdycGMiLCJ0eXBlIjoibXVsdGkiLCJob3N0IjoiIiwicGF0aCI6InR1bm5lbCIsInRscyI6InRscyIsInNuaSI6ImV4YW1wbGUuY29tIiwiZnAiOiJjaHJvbWUifQ=="
+	l, err := Parse(link)
+	common.Must(err)
+	if l.Outbound.Protocol != "vmess" || l.Outbound.Tag != "vmess" {
+		t.Error("unexpected outbound ", toJSON(l))
+	}
+	stream := l.Outbound.StreamSetting
+	if grpc := stream.GRPCSettings; grpc == nil || grpc.ServiceName != "tunnel" || !grpc.MultiMode {
+		t.Error("unexpected gRPC settings ", toJSON(l))
+	}
+	if tls := stream.TLSSettings; tls == nil || tls.ServerName != "example.com" || tls.Fingerprint != "chrome" {
+		t.Error("unexpected TLS settings ", toJSON(l))
+	}
+	if len(l.Extra) != 0 {
+		t.Error("unexpected extra ", l.Extra)
+	}
+
+	formatted, err := Format(l)
+	common.Must(err)
+	l2, err := Parse(formatted)
+	common.Must(err)
+	if a, b := toJSON(l), toJSON(l2); a != b {
+		t.Error("round trip changes the outbound from\n", a, "\nto\n", b)
+	}
+}
+
+func TestParse(t *testing.T) {
+	l, err := Parse("vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?security=reality&pbk=key&sid=ab&spx=%2Fpath&flow=xtls-rprx-vision&foo=bar#name")
+	common.Must(err)
+	var settings map[string]interface{}
+	common.Must(json.Unmarshal(*l.Outbound.Settings, &settings))
+	if settings["address"] != "example.com" || settings["port"] != float64(443) || settings["flow"] != "xtls-rprx-vision" || settings["encryption"] != "none" {
+		t.Error("unexpected settings ", settings)
+	}
+	if r := l.Outbound.StreamSetting.REALITYSettings; r.PublicKey != "key" || r.ShortId != "ab" || r.SpiderX != "/path" {
+		t.Error("unexpected REALITY settings ", r)
+	}
+	if l.Extra.Get("foo") != "bar" {
+		t.Error("unknown parameter is lost: ", l.Extra)
+	}
+
+	// legacy Shadowsocks link, base64 of aes-128-gcm:test@192.168.100.1:8888
+	l, err = Parse("ss://YWVzLTEyOC1nY206dGVzdEAxOTIuMTY4LjEwMC4xOjg4ODg#legacy")
+	common.Must(err)
+	common.Must(json.Unmarshal(*l.Outbound.Settings, &settings))
+	if settings["method"] != "aes-128-gcm" || settings["password"] != "test" || settings["address"] != "192.168.100.1" {
+		t.Error("unexpected settings ", settings)
+	}
+
+	for _, link := range []string{
+		"vless://example.com:443",
+		"vless://id@example.com",
+		"vless://id@example.com:443?type=quic",
+		"vless://id@example.com:443?security=xtls",
+		"socks://example.com:1080",
+	} {
+		if _, err := Parse(link); err == nil {
+			t.Error("expect error for ", link)
+		}
+	}
+}
+
+func TestFormatOutbound(t *testing.T) {
+	var ob conf.OutboundDetourConfig
+	common.Must(json.Unmarshal([]byte(`{
+		"protocol": "vless",
+		"tag": "proxy",
+		"settings": {
+			"vnext": [{
+				"address": "example.com",
+				"port": 443,
+				"users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "encryption": "none", "flow": "xtls-rprx-vision"}]
+			}]
+		},
+		"streamSettings": {
+			"network": "raw",
+			"security": "reality",
+			"realitySettings": {"serverName": "example.com", "publicKey": "key", "shortId": "ab", "fingerprint": "chrome"}
+		}
+	}`), &ob))
+	link, err := Format(&Link{Outbound: &ob})
+	common.Must(err)
+	expected := "vless://27848739-7e62-4138-9fd3-098a63964b6b@example.com:443?encryption=none&flow=xtls-rprx-vision&fp=chrome&pbk=key&security=reality&sid=ab&sni=example.com&type=tcp#proxy"
+	if link != expected {
+		t.Error("unexpected link ", link)
+	}
+}
+
+func toJSON(l *Link) string {
+	b, err := json.Marshal(l)
+	common.Must(err)
+	return string(b)
+}
@@ -0,0 +1,254 @@
+package sharelink
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// tcpHeader is the HTTP header obfuscation of RAW, of which links carry the host and path.
+type tcpHeader struct {
+	Type    string            `json:"type"`
+	Request *tcpHeaderRequest `json:"request,omitempty"`
+}
+
+type tcpHeaderRequest struct {
+	Path    []string            `json:"path,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+}
+
+// parseStream takes the transport and security parameters out of query.
+func parseStream(query url.Values) (*conf.StreamConfig, error) {
+	network := strings.ToLower(take(query, "type"))
+	switch network {
+	case "", "raw", "tcp":
+		network = "tcp"
+	case "mkcp", "kcp":
+		network = "kcp"
+	case "websocket", "ws":
+		network = "ws"
+	case "splithttp", "xhttp":
+		network = "xhttp"
+	case "httpupgrade", "grpc":
+	default:
+		return nil, errors.New("unsupported transport: ", network)
+	}
+	protocol := conf.TransportProtocol(network)
+	stream := &conf.StreamConfig{Network: &protocol}
+
+	switch network {
+	case "tcp":
+		if headerType := take(query, "headerType"); headerType == "http" {
+			header := tcpHeader{Type: "http", Request: new(tcpHeaderRequest)}
+			if path := take(query, "path"); path != "" {
+				header.Request.Path = strings.Split(path, ",")
+			}
+			if host := take(query, "host"); host != "" {
+				header.Request.Headers = map[string][]string{"Host": strings.Split(host, ",")}
+			}
+			b, err := json.Marshal(header)
+			if err != nil {
+				return nil, err
+			}
+			stream.RAWSettings = &conf.TCPConfig{HeaderConfig: b}
+		} else if headerType != "" && headerType != "none" {
+			return nil, errors.New("unsupported RAW header: ", headerType)
+		}
+	case "kcp":
+		kcp := new(conf.KCPConfig)
+		if headerType := take(query, "headerType"); headerType != "" && headerType != "none" {
+			kcp.HeaderConfig = json.RawMessage(`{"type":` + quote(headerType) + `}`)
+		}
+		if seed := take(query, "seed"); seed != "" {
+			kcp.Seed = &seed
+		}
+		stream.KCPSettings = kcp
+	case "ws":
+		stream.WSSettings = &conf.WebSocketConfig{
+			Host: take(query, "host"),
+			Path: take(query, "path"),
+		}
+	case "httpupgrade":
+		stream.HTTPUPGRADESettings = &conf.HttpUpgradeConfig{
+			Host: take(query, "host"),
+			Path: take(query, "path"),
+		}
+	case "xhttp":
+		xhttp := &conf.SplitHTTPConfig{
+			Host: take(query, "host"),
+			Path: take(query, "path"),
+			Mode: take(query, "mode"),
+		}
+		if extra := take(query, "extra"); extra != "" {
+			if !json.Valid([]byte(extra)) {
+				return nil, errors.New("invalid XHTTP extra: ", extra)
+			}
+			xhttp.Extra = json.RawMessage(extra)
+		}
+		stream.XHTTPSettings = xhttp
+	case "grpc":
+		stream.GRPCSettings = &conf.GRPCConfig{
+			ServiceName: take(query, "serviceName"),
+			Authority:   take(query, "authority"),
+			MultiMode:   take(query, "mode") == "multi",
+		}
+	}
+
+	switch security := take(query, "security"); security {
+	case "", "none":
+	case "tls":
+		stream.Security = security
+		tls := &conf.TLSConfig{
+			ServerName:    take(query, "sni"),
+			Fingerprint:   take(query, "fp"),
+			ECHConfigList: take(query, "ech"),
+		}
+		if alpn := take(query, "alpn"); alpn != "" {
+			tls.ALPN = conf.NewStringList(strings.Split(alpn, ","))
+		}
+		switch insecure := take(query, "allowInsecure"); insecure {
+		case "", "0", "false":
+		case "1", "true":
+			tls.Insecure = true
+		default:
+			return nil, errors.New("invalid allowInsecure: ", insecure)
+		}
+		stream.TLSSettings = tls
+	case "reality":
+		stream.Security = security
+		stream.REALITYSettings = &conf.REALITYConfig{
+			ServerName:    take(query, "sni"),
+			Fingerprint:   take(query, "fp"),
+			PublicKey:     take(query, "pbk"),
+			ShortId:       take(query, "sid"),
+			SpiderX:       take(query, "spx"),
+			Mldsa65Verify: take(query, "pqv"),
+		}
+	default:
+		return nil, errors.New("unsupported security: ", security)
+	}
+	return stream, nil
+}
+
+// formatStream sets the transport and security parameters of stream in query.
+// Settings that links have no parameter for are left out.
+func formatStream(stream *conf.StreamConfig, query url.Values) error {
+	if stream == nil {
+		stream = new(conf.StreamConfig)
+	}
+	network := "tcp"
+	if stream.Network != nil {
+		network = strings.ToLower(string(*stream.Network))
+	}
+
+	switch network {
+	case "", "raw", "tcp":
+		query.Set("type", "tcp")
+		raw := stream.RAWSettings
+		if raw == nil {
+			raw = stream.TCPSettings
+		}
+		if raw != nil && len(raw.HeaderConfig) > 0 {
+			var header tcpHeader
+			if err := json.Unmarshal(raw.HeaderConfig, &header); err != nil {
+				return errors.New("invalid RAW header").Base(err)
+			}
+			if header.Type == "http" {
+				query.Set("headerType", "http")
+				if header.Request != nil {
+					setIfNotEmpty(query, "path", strings.Join(header.Request.Path, ","))
+					setIfNotEmpty(query, "host", strings.Join(header.Request.Headers["Host"], ","))
+				}
+			}
+		}
+	case "mkcp", "kcp":
+		query.Set("type", "kcp")
+		if kcp := stream.KCPSettings; kcp != nil {
+			if len(kcp.HeaderConfig) > 0 {
+				var header struct {
+					Type string `json:"type"`
+				}
+				if err := json.Unmarshal(kcp.HeaderConfig, &header); err != nil {
+					return errors.New("invalid mKCP header").Base(err)
+				}
+				setIfNotEmpty(query, "headerType", header.Type)
+			}
+			if kcp.Seed != nil {
+				setIfNotEmpty(query, "seed", *kcp.Seed)
+			}
+		}
+	case "websocket", "ws":
+		query.Set("type", "ws")
+		if ws := stream.WSSettings; ws != nil {
+			setIfNotEmpty(query, "host", ws.Host)
+			setIfNotEmpty(query, "path", ws.Path)
+		}
+	case "httpupgrade":
+		query.Set("type", "httpupgrade")
+		if hu := stream.HTTPUPGRADESettings; hu != nil {
+			setIfNotEmpty(query, "host", hu.Host)
+			setIfNotEmpty(query, "path", hu.Path)
+		}
+	case "splithttp", "xhttp":
+		query.Set("type", "xhttp")
+		xhttp := stream.XHTTPSettings
+		if xhttp == nil {
+			xhttp = stream.SplitHTTPSettings
+		}
+		if xhttp != nil {
+			setIfNotEmpty(query, "host", xhttp.Host)
+			setIfNotEmpty(query, "path", xhttp.Path)
+			setIfNotEmpty(query, "mode", xhttp.Mode)
+			setIfNotEmpty(query, "extra", string(xhttp.Extra))
+		}
+	case "grpc":
+		query.Set("type", "grpc")
+		if grpc := stream.GRPCSettings; grpc != nil {
+			setIfNotEmpty(query, "serviceName", grpc.ServiceName)
+			setIfNotEmpty(query, "authority", grpc.Authority)
+			if grpc.MultiMode {
+				query.Set("mode", "multi")
+			}
+		}
+	default:
+		return errors.New("unsupported transport: ", network)
+	}
+
+	switch stream.Security {
+	case "", "none":
+	case "tls":
+		query.Set("security", "tls")
+		if tls := stream.TLSSettings; tls != nil {
+			setIfNotEmpty(query, "sni", tls.ServerName)
+			setIfNotEmpty(query, "fp", tls.Fingerprint)
+			setIfNotEmpty(query, "ech", tls.ECHConfigList)
+			if tls.ALPN != nil {
+				setIfNotEmpty(query, "alpn", strings.Join(*tls.ALPN, ","))
+			}
+			if tls.Insecure {
+				query.Set("allowInsecure", "1")
+			}
+		}
+	case "reality":
+		query.Set("security", "reality")
+		if reality := stream.REALITYSettings; reality != nil {
+			setIfNotEmpty(query, "sni", reality.ServerName)
+			setIfNotEmpty(query, "fp", reality.Fingerprint)
+			setIfNotEmpty(query, "pbk", reality.PublicKey)
+			setIfNotEmpty(query, "sid", reality.ShortId)
+			setIfNotEmpty(query, "spx", reality.SpiderX)
+			setIfNotEmpty(query, "pqv", reality.Mldsa65Verify)
+		}
+	default:
+		return errors.New("unsupported security: ", stream.Security)
+	}
+	return nil
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
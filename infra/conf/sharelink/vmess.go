@@ -0,0 +1,134 @@
+package sharelink
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// vmessKeys maps the keys of the JSON of v2rayN links to the parameters of the standard format.
+var vmessKeys = map[string]string{
+	"net":  "type",
+	"type": "headerType",
+	"tls":  "security",
+}
+
+// parseVMess parses the base64 encoded JSON of v2rayN links.
+func parseVMess(encoded string) (*Link, error) {
+	b, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, errors.New("invalid VMess link").Base(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, errors.New("invalid VMess link").Base(err)
+	}
+
+	query := url.Values{}
+	for key, value := range fields {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			continue
+		}
+		if s == "" {
+			continue
+		}
+		if param, ok := vmessKeys[key]; ok {
+			key = param
+		}
+		query.Set(key, s)
+	}
+
+	name := take(query, "ps")
+	hostport := net.JoinHostPort(take(query, "add"), take(query, "port"))
+	srv := server{
+		ID:       take(query, "id"),
+		Security: take(query, "scy"),
+	}
+	if srv.ID == "" {
+		return nil, errors.New("VMess link has no id")
+	}
+	query.Del("v")
+	// only AEAD is supported, alterId must be 0 and is kept otherwise
+	if aid := query.Get("aid"); aid == "0" {
+		query.Del("aid")
+	}
+	if query.Get("headerType") == "none" {
+		query.Del("headerType")
+	}
+	switch query.Get("type") {
+	case "grpc":
+		if query.Get("serviceName") == "" {
+			query.Set("serviceName", take(query, "path"))
+		}
+		if mode := take(query, "headerType"); mode != "" {
+			query.Set("mode", mode)
+		}
+	case "kcp", "mkcp":
+		if query.Get("seed") == "" {
+			setIfNotEmpty(query, "seed", take(query, "path"))
+		}
+	}
+	return newLink("vmess", name, hostport, srv, query)
+}
+
+// formatVMess returns the v2rayN link of the server.
+func formatVMess(name string, srv server, query url.Values) (string, error) {
+	fields := map[string]string{
+		"v":    "2",
+		"ps":   name,
+		"add":  srv.Address,
+		"port": strconv.Itoa(int(srv.Port)),
+		"id":   srv.ID,
+		"aid":  "0",
+		"scy":  srv.Security,
+		"type": "none",
+	}
+	if srv.Security == "" {
+		fields["scy"] = "auto"
+	}
+	switch query.Get("type") {
+	case "grpc":
+		if mode := take(query, "mode"); mode != "" {
+			query.Set("headerType", mode)
+		}
+		setIfNotEmpty(query, "path", take(query, "serviceName"))
+	case "kcp":
+		setIfNotEmpty(query, "path", take(query, "seed"))
+	}
+	for key := range query {
+		field := key
+		for k, param := range vmessKeys {
+			if param == key {
+				field = k
+			}
+		}
+		fields[field] = query.Get(key)
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// decodeBase64 decodes both the standard and URL encodings, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -69,6 +69,13 @@ type SocksRemoteConfig struct {
 	Users   []json.RawMessage `json:"users"`
 }
 
+func (c *SocksRemoteConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"users": new(struct {
+		protocol.User
+		SocksAccount
+	})}
+}
+
 type SocksClientConfig struct {
 	Address  *Address             `json:"address"`
 	Port     uint16               `json:"port"`
@@ -0,0 +1,286 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/xtls/xray-core/core"
+)
+
+// strictUnmarshaler is implemented by the configs with a custom UnmarshalJSON.
+// strictValue returns a pointer to the value that data decodes like, which is checked for unknown fields instead,
+// or nil if there are none to check, like in a string. Returning the config itself checks its own fields.
+type strictUnmarshaler interface {
+	strictValue(data []byte) interface{}
+}
+
+// strictRawFields is implemented by the configs with json.RawMessage fields, which are decoded in Build.
+// strictRawFields returns pointers to the values they decode into by their JSON names, which may depend on
+// the other fields, decoded already. The elements of a []json.RawMessage field decode into the value each.
+type strictRawFields interface {
+	strictRawFields() map[string]interface{}
+}
+
+// strictLoaded is a config loaded by a JSONConfigLoader without a config key, from an object which has the ID key
+// besides the fields of the config.
+type strictLoaded struct {
+	idKey  string
+	config interface{}
+}
+
+var (
+	rawMessageType        = reflect.TypeOf(json.RawMessage{})
+	rawMessageSliceType   = reflect.TypeOf([]json.RawMessage{})
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	strictUnmarshalerType = reflect.TypeOf((*strictUnmarshaler)(nil)).Elem()
+	strictRawFieldsType   = reflect.TypeOf((*strictRawFields)(nil)).Elem()
+)
+
+// CheckUnknownFields looks for the keys in the JSON config that no field is decoded from, which are ignored otherwise,
+// and returns them at their JSON paths, with the field each is likely a typo of.
+// It follows the settings of the protocols and the other raw JSON decoded in Build, and the custom decoded configs.
+func CheckUnknownFields(data []byte) []*core.ConfigProblem {
+	k := new(fieldsChecker)
+	k.check("", reflect.TypeOf(Config{}), data)
+	return k.problems
+}
+
+type fieldsChecker struct {
+	problems []*core.ConfigProblem
+}
+
+func (k *fieldsChecker) check(path string, typ reflect.Type, data []byte) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == rawMessageType {
+		return // decoded by the config it is in, with strictRawFields
+	}
+	ptr := reflect.PointerTo(typ)
+	if ptr.Implements(strictUnmarshalerType) {
+		value := reflect.New(typ).Interface().(strictUnmarshaler).strictValue(data)
+		if value != nil && reflect.TypeOf(value) == ptr {
+			k.checkFields(path, typ, data, "")
+		} else {
+			k.checkValue(path, value, data)
+		}
+		return
+	}
+	if ptr.Implements(jsonUnmarshalerType) {
+		return // like a string or a list of them
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		k.checkFields(path, typ, data, "")
+	case reflect.Map:
+		keys, values := decodeObject(data)
+		for i, key := range keys {
+			k.check(joinPath(path, key), typ.Elem(), values[i])
+		}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		var array []json.RawMessage
+		if json.Unmarshal(data, &array) != nil {
+			return
+		}
+		for i, element := range array {
+			k.check(fmt.Sprint(path, "[", i, "]"), typ.Elem(), element)
+		}
+	}
+}
+
+// checkValue checks data against value, a pointer returned by strictValue or strictRawFields.
+func (k *fieldsChecker) checkValue(path string, value interface{}, data []byte) {
+	switch v := value.(type) {
+	case nil:
+	case *strictLoaded:
+		k.checkFields(path, reflect.TypeOf(v.config).Elem(), data, v.idKey)
+	default:
+		k.check(path, reflect.TypeOf(value), data)
+	}
+}
+
+// checkFields checks the keys of the object in data against the fields of the struct typ, where idKey is known as well.
+func (k *fieldsChecker) checkFields(path string, typ reflect.Type, data []byte, idKey string) {
+	keys, values := decodeObject(data)
+	if keys == nil {
+		return
+	}
+	var raw map[string]interface{}
+	if reflect.PointerTo(typ).Implements(strictRawFieldsType) {
+		config := reflect.New(typ).Interface()
+		if json.Unmarshal(data, config) != nil {
+			return // reported by decoding
+		}
+		raw = config.(strictRawFields).strictRawFields()
+	}
+
+	for i, key := range keys {
+		keyPath := joinPath(path, key)
+		field, found := fieldByJSONName(typ, key)
+		if !found {
+			if key != idKey {
+				k.unknown(keyPath, typ, key)
+			}
+			continue
+		}
+		value, isRaw := raw[jsonName(field)]
+		switch {
+		case !isRaw:
+			k.check(keyPath, field.Type, values[i])
+		case field.Type == rawMessageSliceType:
+			var array []json.RawMessage
+			if json.Unmarshal(values[i], &array) != nil {
+				continue
+			}
+			for j, element := range array {
+				k.checkValue(fmt.Sprint(keyPath, "[", j, "]"), value, element)
+			}
+		default:
+			k.checkValue(keyPath, value, values[i])
+		}
+	}
+}
+
+func (k *fieldsChecker) unknown(path string, typ reflect.Type, key string) {
+	message := "unknown field"
+	if name := closestField(typ, key); name != "" {
+		message += `, did you mean "` + name + `"?`
+	}
+	k.problems = append(k.problems, &core.ConfigProblem{Path: path, Message: message})
+}
+
+// closestField returns the JSON name of the field of typ that key is the most likely a typo of, or "" if none is close.
+func closestField(typ reflect.Type, key string) string {
+	closest, best := "", -1
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		d := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if d <= max(1, len(name)/3) && (best < 0 || d < best) {
+			closest, best = name, d
+		}
+	}
+	return closest
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions of adjacent letters
+// that turn a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fieldByJSONName finds the field decoded from the key, the same way as encoding/json, preferring an exact match of the name.
+func fieldByJSONName(typ reflect.Type, key string) (reflect.StructField, bool) {
+	var match reflect.StructField
+	found := false
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if name == key {
+			return field, true
+		}
+		if !found && strings.EqualFold(name, key) {
+			match, found = field, true
+		}
+	}
+	return match, found
+}
+
+// decodeObject returns the keys and values of the JSON object, in the order of the text, or nothing if it is not an object.
+func decodeObject(data []byte) ([]string, []json.RawMessage) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if t, err := decoder.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil
+	}
+	keys := []string{}
+	var values []json.RawMessage
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+		keys = append(keys, t.(string))
+		values = append(values, value)
+	}
+	return keys, values
+}
+
+func isObject(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// strictConfig returns the config of id that the loader loads, for CheckUnknownFields, or nil if id is unknown.
+// Without a config key, id is read from raw, the object with the fields of the config.
+func (v *JSONConfigLoader) strictConfig(raw []byte, id string) interface{} {
+	if v.configKey == "" {
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) != nil || json.Unmarshal(obj[v.idKey], &id) != nil {
+			return nil
+		}
+	}
+	config, err := v.cache.CreateConfig(strings.ToLower(id))
+	if err != nil {
+		return nil
+	}
+	if v.configKey == "" {
+		return &strictLoaded{idKey: v.idKey, config: config}
+	}
+	return config
+}
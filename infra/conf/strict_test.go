@@ -0,0 +1,77 @@
+package conf_test
+
+import (
+	"testing"
+
+	. "github.com/xtls/xray-core/infra/conf"
+)
+
+func TestCheckUnknownFields(t *testing.T) {
+	problems := CheckUnknownFields([]byte(`{
+		"log": {"loglevel": "warning", "acess": "/var/log/access.log"},
+		"dns": {
+			"servers": ["1.1.1.1", {"address": "8.8.8.8", "domians": ["example.com"]}]
+		},
+		"fakedns": [{"ipPool": "198.18.0.0/15", "poolSise": 65535}],
+		"inbounds": [{
+			"protocol": "vless",
+			"settings": {
+				"decryption": "none",
+				"clients": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "flow": "xtls-rprx-vision", "emial": "a@example.com"}]
+			},
+			"streamSettings": {
+				"rawSettings": {"header": {"type": "http", "reqest": {}}},
+				"sockopt": {"happyEyeballs": {"interlave": 2}}
+			}
+		}],
+		"outbounds": [{
+			"protocol": "freedom",
+			"setings": {}
+		}, {
+			"protocol": "vmess",
+			"settings": {
+				"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "27848739-7e62-4138-9fd3-098a63964b6b", "securty": "auto"}]}]
+			},
+			"streamSettings": {
+				"network": "xhttp",
+				"xhttpSettings": {"extra": {"noGRPCHeadr": true}}
+			}
+		}, {
+			"protocol": "blackhole",
+			"settings": {"response": {"type": "http", "unknown": true}}
+		}],
+		"routing": {
+			"rules": [{"type": "field", "ip": ["1.1.1.1"], "ouboundTag": "direct"}],
+			"balancers": [{"tag": "b", "selector": ["a"], "strategy": {"type": "leastLoad", "settings": {"tolerence": 0.1}}}]
+		}
+	}`))
+
+	expected := map[string]string{
+		"log.acess":                                                   `unknown field, did you mean "access"?`,
+		"dns.servers[1].domians":                                      `unknown field, did you mean "domains"?`,
+		"fakedns[0].poolSise":                                         `unknown field, did you mean "poolSize"?`,
+		"inbounds[0].settings.clients[0].emial":                       `unknown field, did you mean "email"?`,
+		"inbounds[0].streamSettings.rawSettings.header.reqest":        `unknown field, did you mean "request"?`,
+		"inbounds[0].streamSettings.sockopt.happyEyeballs.interlave":  `unknown field, did you mean "interleave"?`,
+		"outbounds[0].setings":                                        `unknown field, did you mean "settings"?`,
+		"outbounds[1].settings.vnext[0].users[0].securty":             `unknown field, did you mean "security"?`,
+		"outbounds[1].streamSettings.xhttpSettings.extra.noGRPCHeadr": `unknown field, did you mean "noGRPCHeader"?`,
+		"outbounds[2].settings.response.unknown":                      `unknown field`,
+		"routing.rules[0].ouboundTag":                                 `unknown field, did you mean "outboundTag"?`,
+		"routing.balancers[0].strategy.settings.tolerence":            `unknown field, did you mean "tolerance"?`,
+	}
+	for _, problem := range problems {
+		message, found := expected[problem.Path]
+		if !found {
+			t.Error("unexpected problem ", problem)
+			continue
+		}
+		if message != problem.Message {
+			t.Error("unexpected message of ", problem)
+		}
+		delete(expected, problem.Path)
+	}
+	for path := range expected {
+		t.Error("no problem found at ", path)
+	}
+}
@@ -147,10 +147,12 @@ func (v *AuthenticatorRequest) Build() (*http.RequestConfig, error) {
 }
 
 type AuthenticatorResponse struct {
-	Version string                 `json:"version"`
-	Status  string                 `json:"status"`
-	Reason  string                 `json:"reason"`
-	Headers map[string]*StringList `json:"headers"`
+	Version       string                 `json:"version"`
+	Status        string                 `json:"status"`
+	Reason        string                 `json:"reason"`
+	Headers       map[string]*StringList `json:"headers"`
+	ExpectStatus  string                 `json:"expectStatus"`
+	ExpectHeaders map[string]*StringList `json:"expectHeaders"`
 }
 
 func (v *AuthenticatorResponse) Build() (*http.ResponseConfig, error) {
@@ -211,6 +213,15 @@ func (v *AuthenticatorResponse) Build() (*http.ResponseConfig, error) {
 		}
 	}
 
+	config.ExpectStatus = v.ExpectStatus
+	for _, key := range sortMapKeys(v.ExpectHeaders) {
+		header := &http.Header{Name: key}
+		if value := v.ExpectHeaders[key]; value != nil {
+			header.Value = append([]string(nil), (*value)...)
+		}
+		config.ExpectHeader = append(config.ExpectHeader, header)
+	}
+
 	return config, nil
 }
 
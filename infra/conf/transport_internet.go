@@ -1,12 +1,16 @@
 package conf
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"math"
+	gonet "net"
 	"net/url"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -55,6 +59,10 @@ type KCPConfig struct {
 	Seed            *string         `json:"seed"`
 }
 
+func (c *KCPConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"header": kcpHeaderLoader.strictConfig(c.HeaderConfig, "")}
+}
+
 // Build implements Buildable.
 func (c *KCPConfig) Build() (proto.Message, error) {
 	config := new(kcp.Config)
@@ -122,6 +130,10 @@ type TCPConfig struct {
 	AcceptProxyProtocol bool            `json:"acceptProxyProtocol"`
 }
 
+func (c *TCPConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"header": tcpHeaderLoader.strictConfig(c.HeaderConfig, "")}
+}
+
 // Build implements Buildable.
 func (c *TCPConfig) Build() (proto.Message, error) {
 	config := new(tcp.Config)
@@ -142,12 +154,45 @@ func (c *TCPConfig) Build() (proto.Message, error) {
 	return config, nil
 }
 
+// checkHost checks the "host" of the transports over HTTP, which is a domain or an IP, with an optional port.
+func checkHost(host string) error {
+	if host == "" {
+		return nil
+	}
+	if u, err := url.Parse("//" + host); err != nil || u.Host != host || u.User != nil {
+		return errors.New(`invalid "host": `, host, `, expected a domain or an IP, with an optional port`)
+	}
+	return nil
+}
+
+// buildPathHandlers builds the inbounds, by the paths, that the listener of the transport hands the connections
+// requesting the paths to.
+func buildPathHandlers(handlers map[string]string) ([]*internet.PathHandler, error) {
+	paths := make([]string, 0, len(handlers))
+	for path, tag := range handlers {
+		if !strings.HasPrefix(path, "/") {
+			return nil, errors.New(`invalid path in "pathHandlers": `, path, `, expected to start with "/"`)
+		}
+		if tag == "" {
+			return nil, errors.New(`empty inbound tag in "pathHandlers" for path `, path)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var config []*internet.PathHandler
+	for _, path := range paths {
+		config = append(config, &internet.PathHandler{Path: path, Tag: handlers[path]})
+	}
+	return config, nil
+}
+
 type WebSocketConfig struct {
 	Host                string            `json:"host"`
 	Path                string            `json:"path"`
 	Headers             map[string]string `json:"headers"`
 	AcceptProxyProtocol bool              `json:"acceptProxyProtocol"`
 	HeartbeatPeriod     uint32            `json:"heartbeatPeriod"`
+	PathHandlers        map[string]string `json:"pathHandlers"`
 }
 
 // Build implements Buildable.
@@ -173,6 +218,9 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 			delete(c.Headers, k)
 		}
 	}
+	if err := checkHost(c.Host); err != nil {
+		return nil, err
+	}
 	config := &websocket.Config{
 		Path:                path,
 		Host:                c.Host,
@@ -181,6 +229,10 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 		Ed:                  ed,
 		HeartbeatPeriod:     c.HeartbeatPeriod,
 	}
+	var err error
+	if config.PathHandlers, err = buildPathHandlers(c.PathHandlers); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
@@ -210,6 +262,9 @@ func (c *HttpUpgradeConfig) Build() (proto.Message, error) {
 			return nil, errors.New(`"headers" can't contain "host"`)
 		}
 	}
+	if err := checkHost(c.Host); err != nil {
+		return nil, err
+	}
 	config := &httpupgrade.Config{
 		Path:                path,
 		Host:                c.Host,
@@ -234,7 +289,21 @@ type SplitHTTPConfig struct {
 	ScStreamUpServerSecs Int32Range        `json:"scStreamUpServerSecs"`
 	Xmux                 XmuxConfig        `json:"xmux"`
 	DownloadSettings     *StreamConfig     `json:"downloadSettings"`
-	Extra                json.RawMessage   `json:"extra"`
+	// the outbound to dial the download through, to the same server as the upload
+	DownloadDialerProxy string          `json:"downloadDialerProxy"`
+	Extra               json.RawMessage `json:"extra"`
+	// the WebSocket to move the upload to when the posts are blocked
+	FallbackTransport      string `json:"fallbackTransport"`
+	FallbackPath           string `json:"fallbackPath"`
+	FallbackUploadFailures int32  `json:"fallbackUploadFailures"`
+	FallbackWindowSecs     int64  `json:"fallbackWindowSecs"`
+	FallbackPinSecs        int64  `json:"fallbackPinSecs"`
+	// the inbounds, by the paths, that the connections requesting the paths are handed to
+	PathHandlers map[string]string `json:"pathHandlers"`
+}
+
+func (c *SplitHTTPConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"extra": new(SplitHTTPConfig)}
 }
 
 type XmuxConfig struct {
@@ -263,6 +332,7 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 		extra.Host = c.Host
 		extra.Path = c.Path
 		extra.Mode = c.Mode
+		extra.PathHandlers = c.PathHandlers
 		c = &extra
 	}
 
@@ -281,6 +351,10 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 		}
 	}
 
+	if err := checkHost(c.Host); err != nil {
+		return nil, err
+	}
+
 	if c.XPaddingBytes != (Int32Range{}) && (c.XPaddingBytes.From <= 0 || c.XPaddingBytes.To <= 0) {
 		return nil, errors.New("xPaddingBytes cannot be disabled")
 	}
@@ -297,6 +371,19 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 		c.Xmux.HMaxReusableSecs.To = 3000
 	}
 
+	switch c.FallbackTransport {
+	case "":
+	case "ws":
+		if c.FallbackPath == "" {
+			return nil, errors.New(`"fallbackPath" is required by "fallbackTransport"`)
+		}
+		if strings.TrimSuffix(c.FallbackPath, "/") == strings.TrimSuffix(c.Path, "/") {
+			return nil, errors.New(`"fallbackPath" can't be the same as "path"`)
+		}
+	default:
+		return nil, errors.New("unsupported fallbackTransport: " + c.FallbackTransport)
+	}
+
 	config := &splithttp.Config{
 		Host:                 c.Host,
 		Path:                 c.Path,
@@ -317,21 +404,63 @@ func (c *SplitHTTPConfig) Build() (proto.Message, error) {
 			HMaxReusableSecs: newRangeConfig(c.Xmux.HMaxReusableSecs),
 			HKeepAlivePeriod: c.Xmux.HKeepAlivePeriod,
 		},
+		FallbackTransport:      c.FallbackTransport,
+		FallbackPath:           c.FallbackPath,
+		FallbackUploadFailures: c.FallbackUploadFailures,
+		FallbackWindowSecs:     c.FallbackWindowSecs,
+		FallbackPinSecs:        c.FallbackPinSecs,
+		DownloadDialerProxy:    c.DownloadDialerProxy,
+	}
+
+	var err error
+	if config.PathHandlers, err = buildPathHandlers(c.PathHandlers); err != nil {
+		return nil, err
+	}
+
+	if c.DownloadDialerProxy != "" {
+		if c.Mode == "stream-one" {
+			return nil, errors.New(`Can not use "downloadDialerProxy" in "stream-one" mode.`)
+		}
+		if c.DownloadSettings != nil {
+			return nil, errors.New(`Can not use "downloadDialerProxy" with "downloadSettings", set "dialerProxy" in its "sockopt" instead.`)
+		}
 	}
 
 	if c.DownloadSettings != nil {
 		if c.Mode == "stream-one" {
 			return nil, errors.New(`Can not use "downloadSettings" in "stream-one" mode.`)
 		}
-		var err error
 		if config.DownloadSettings, err = c.DownloadSettings.Build(); err != nil {
 			return nil, errors.New(`Failed to build "downloadSettings".`).Base(err)
 		}
+		if err := checkDownloadSettings(c, config.DownloadSettings); err != nil {
+			return nil, err
+		}
 	}
 
 	return config, nil
 }
 
+// checkDownloadSettings checks that the download of c reaches the same XHTTP server as the upload, which correlates
+// them by the session ID. Another path only warns, as a CDN in front of the server may rewrite it.
+func checkDownloadSettings(c *SplitHTTPConfig, download *internet.StreamConfig) error {
+	if download.Address == nil || download.Port == 0 {
+		return errors.New(`"downloadSettings" requires "address" and "port"`)
+	}
+	if download.GetEffectiveProtocol() != "splithttp" {
+		return errors.New(`"downloadSettings" requires "network" to be "xhttp", the same as the upload`)
+	}
+	settings, err := download.GetEffectiveTransportSettings()
+	if err != nil {
+		return errors.New(`Failed to build "downloadSettings".`).Base(err)
+	}
+	path := (&splithttp.Config{Path: c.Path}).GetNormalizedPath()
+	if path2 := settings.(*splithttp.Config).GetNormalizedPath(); path2 != path {
+		errors.LogWarning(context.Background(), `"path" of "downloadSettings" is `, path2, `, but the upload is to `, path, `, while both must reach the same XHTTP server`)
+	}
+	return nil
+}
+
 func readFileOrString(f string, s []string) ([]byte, error) {
 	if len(f) > 0 {
 		return filesystem.ReadCert(f)
@@ -394,12 +523,29 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 	return certificate, nil
 }
 
+// parsePinnedSha256 decodes the base64 SHA-256 hashes of the pins of the leaf certificate.
+func parsePinnedSha256(name string, pins []string) ([][]byte, error) {
+	var hashes [][]byte
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, errors.New(`invalid "`, name, `": `, pin, `, expected a base64 SHA-256 hash`)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
 type TLSConfig struct {
 	Insecure                             bool             `json:"allowInsecure"`
 	Certs                                []*TLSCertConfig `json:"certificates"`
 	ServerName                           string           `json:"serverName"`
+	ServerNameOverride                   string           `json:"serverNameOverride"`
+	NoSNI                                bool             `json:"noSNI"`
 	ALPN                                 *StringList      `json:"alpn"`
 	EnableSessionResumption              bool             `json:"enableSessionResumption"`
+	SessionCacheSize                     uint32           `json:"sessionCacheSize"`
+	DisableSessionResumption             bool             `json:"disableSessionResumption"`
 	DisableSystemRoot                    bool             `json:"disableSystemRoot"`
 	MinVersion                           string           `json:"minVersion"`
 	MaxVersion                           string           `json:"maxVersion"`
@@ -408,6 +554,9 @@ type TLSConfig struct {
 	RejectUnknownSNI                     bool             `json:"rejectUnknownSni"`
 	PinnedPeerCertificateChainSha256     *[]string        `json:"pinnedPeerCertificateChainSha256"`
 	PinnedPeerCertificatePublicKeySha256 *[]string        `json:"pinnedPeerCertificatePublicKeySha256"`
+	PinnedPeerCertSha256                 []string         `json:"pinnedPeerCertSha256"`
+	PinnedPeerSPKISha256                 []string         `json:"pinnedPeerSPKISha256"`
+	VerifyPinnedPeerChain                bool             `json:"verifyPinnedPeerChain"`
 	CurvePreferences                     *StringList      `json:"curvePreferences"`
 	MasterKeyLog                         string           `json:"masterKeyLog"`
 	ServerNameToVerify                   string           `json:"serverNameToVerify"`
@@ -448,6 +597,8 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 		config.CurvePreferences = []string(*c.CurvePreferences)
 	}
 	config.EnableSessionResumption = c.EnableSessionResumption
+	config.SessionCacheSize = c.SessionCacheSize
+	config.DisableSessionResumption = c.DisableSessionResumption
 	config.DisableSystemRoot = c.DisableSystemRoot
 	config.MinVersion = c.MinVersion
 	config.MaxVersion = c.MaxVersion
@@ -480,12 +631,30 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 		}
 	}
 
+	var err error
+	if config.PinnedPeerCertSha256, err = parsePinnedSha256("pinnedPeerCertSha256", c.PinnedPeerCertSha256); err != nil {
+		return nil, err
+	}
+	if config.PinnedPeerSpkiSha256, err = parsePinnedSha256("pinnedPeerSPKISha256", c.PinnedPeerSPKISha256); err != nil {
+		return nil, err
+	}
+	config.VerifyPinnedPeerChain = c.VerifyPinnedPeerChain
+
 	config.MasterKeyLog = c.MasterKeyLog
 
 	if c.ServerNameToVerify != "" {
 		return nil, errors.PrintRemovedFeatureError(`"serverNameToVerify"`, `"verifyPeerCertInNames"`)
 	}
 	config.VerifyPeerCertInNames = c.VerifyPeerCertInNames
+	if c.NoSNI && len(c.ServerNameOverride) > 0 {
+		return nil, errors.New(`"noSNI" can not be used with "serverNameOverride"`)
+	}
+	// IP addresses are never sent in the SNI, so it would be the same as "noSNI"
+	if net.ParseAddress(c.ServerNameOverride).Family().IsIP() {
+		return nil, errors.New(`"serverNameOverride" can't be an IP address, use "noSNI" instead`)
+	}
+	config.ServerNameOverride = c.ServerNameOverride
+	config.NoSni = c.NoSNI
 
 	if c.ECHServerKeys != "" {
 		EchPrivateKey, err := base64.StdEncoding.DecodeString(c.ECHServerKeys)
@@ -534,6 +703,9 @@ type REALITYConfig struct {
 	ShortIds     []string        `json:"shortIds"`
 	Mldsa65Seed  string          `json:"mldsa65Seed"`
 
+	ShortIdSeed   string `json:"shortIdSeed"`
+	ShortIdPeriod uint64 `json:"shortIdPeriod"`
+
 	LimitFallbackUpload   LimitFallback `json:"limitFallbackUpload"`
 	LimitFallbackDownload LimitFallback `json:"limitFallbackDownload"`
 
@@ -544,6 +716,10 @@ type REALITYConfig struct {
 	ShortId       string `json:"shortId"`
 	Mldsa65Verify string `json:"mldsa65Verify"`
 	SpiderX       string `json:"spiderX"`
+
+	SpiderDisable     bool   `json:"spiderDisable"`
+	SpiderConcurrency uint32 `json:"spiderConcurrency"`
+	SpiderTimeout     uint32 `json:"spiderTimeout"`
 }
 
 func (c *REALITYConfig) Build() (proto.Message, error) {
@@ -623,7 +799,7 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 				}
 			}
 		}
-		if len(c.ShortIds) == 0 {
+		if len(c.ShortIds) == 0 && c.ShortIdSeed == "" {
 			return nil, errors.New(`empty "shortIds"`)
 		}
 		config.ShortIds = make([][]byte, len(c.ShortIds))
@@ -636,6 +812,8 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 				return nil, errors.New(`invalid "shortIds[`, i, `]": `, s)
 			}
 		}
+		config.ShortIdSeed = []byte(c.ShortIdSeed)
+		config.ShortIdPeriod = c.ShortIdPeriod
 		config.Dest = s
 		config.Type = c.Type
 		config.Xver = c.Xver
@@ -685,9 +863,17 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 		if len(c.ShortIds) > 16 {
 			return nil, errors.New(`too long "shortId": `, c.ShortId)
 		}
-		config.ShortId = make([]byte, 8)
-		if _, err = hex.Decode(config.ShortId, []byte(c.ShortId)); err != nil {
-			return nil, errors.New(`invalid "shortId": `, c.ShortId)
+		if c.ShortIdSeed != "" {
+			if c.ShortId != "" {
+				return nil, errors.New(`"shortId" and "shortIdSeed" can not be both set`)
+			}
+			config.ShortIdSeed = []byte(c.ShortIdSeed)
+			config.ShortIdPeriod = c.ShortIdPeriod
+		} else {
+			config.ShortId = make([]byte, 8)
+			if _, err = hex.Decode(config.ShortId, []byte(c.ShortId)); err != nil {
+				return nil, errors.New(`invalid "shortId": `, c.ShortId)
+			}
 		}
 		if c.Mldsa65Verify != "" {
 			if config.Mldsa65Verify, err = base64.RawURLEncoding.DecodeString(c.Mldsa65Verify); err != nil || len(config.Mldsa65Verify) != 1952 {
@@ -723,6 +909,9 @@ func (c *REALITYConfig) Build() (proto.Message, error) {
 		parse("r", 8) // return
 		u.RawQuery = q.Encode()
 		config.SpiderX = u.String()
+		config.SpiderDisable = c.SpiderDisable
+		config.SpiderConcurrency = c.SpiderConcurrency
+		config.SpiderTimeout = c.SpiderTimeout
 		config.ServerName = c.ServerName
 	}
 	return config, nil
@@ -773,6 +962,10 @@ type HappyEyeballsConfig struct {
 	MaxConcurrentTry uint32 `json:"maxConcurrentTry"`
 }
 
+func (h *HappyEyeballsConfig) strictValue(data []byte) interface{} {
+	return h
+}
+
 func (h *HappyEyeballsConfig) UnmarshalJSON(data []byte) error {
 	var innerHappyEyeballsConfig = struct {
 		PrioritizeIPv6   bool   `json:"prioritizeIPv6"`
@@ -811,6 +1004,13 @@ type SocketConfig struct {
 	AddressPortStrategy   string                 `json:"addressPortStrategy"`
 	HappyEyeballsSettings *HappyEyeballsConfig   `json:"happyEyeballs"`
 	TrustedXForwardedFor  []string               `json:"trustedXForwardedFor"`
+	UDPGSO                bool                   `json:"udpGSO"`
+	PipeHighWatermark     int32                  `json:"pipeHighWatermark"`
+	PipeLowWatermark      *int32                 `json:"pipeLowWatermark"`
+	IPFamilyPreference    string                 `json:"ipFamilyPreference"`
+	ServerAddressTTL      uint32                 `json:"serverAddressTTL"`
+	PinnedIPs             []string               `json:"pinnedIPs"`
+	ResolveVia            string                 `json:"resolveVia"`
 }
 
 // Build implements Buildable.
@@ -902,6 +1102,11 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		return nil, errors.New("unsupported address and port strategy: ", c.AddressPortStrategy)
 	}
 
+	ipFamilyPreference, err := parseIPFamilyPreference(c.IPFamilyPreference)
+	if err != nil {
+		return nil, err
+	}
+
 	var happyEyeballs = &internet.HappyEyeballsConfig{Interleave: 1, PrioritizeIpv6: false, TryDelayMs: 0, MaxConcurrentTry: 4}
 	if c.HappyEyeballsSettings != nil {
 		happyEyeballs.PrioritizeIpv6 = c.HappyEyeballsSettings.PrioritizeIPv6
@@ -910,6 +1115,17 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		happyEyeballs.MaxConcurrentTry = c.HappyEyeballsSettings.MaxConcurrentTry
 	}
 
+	pipeHigh, pipeLow, err := buildWatermarks(c.PipeHighWatermark, c.PipeLowWatermark)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pin := range c.PinnedIPs {
+		if _, _, err := gonet.ParseCIDR(pin); err != nil && gonet.ParseIP(pin) == nil {
+			return nil, errors.New("invalid pinned IP: ", pin)
+		}
+	}
+
 	return &internet.SocketConfig{
 		Mark:                 c.Mark,
 		Tfo:                  tfo,
@@ -931,9 +1147,33 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		AddressPortStrategy:  addressPortStrategy,
 		HappyEyeballs:        happyEyeballs,
 		TrustedXForwardedFor: c.TrustedXForwardedFor,
+		UdpGso:               c.UDPGSO,
+		PipeHighWatermark:    pipeHigh,
+		PipeLowWatermark:     pipeLow,
+		IpFamilyPreference:   ipFamilyPreference,
+		ServerAddressTtl:     c.ServerAddressTTL,
+		PinnedIps:            c.PinnedIPs,
+		ResolveVia:           c.ResolveVia,
 	}, nil
 }
 
+func parseIPFamilyPreference(s string) (internet.IPFamilyPreference, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return internet.IPFamilyPreference_IP_FAMILY_DEFAULT, nil
+	case "prefer-v4":
+		return internet.IPFamilyPreference_PREFER_IP4, nil
+	case "prefer-v6":
+		return internet.IPFamilyPreference_PREFER_IP6, nil
+	case "only-v4":
+		return internet.IPFamilyPreference_ONLY_IP4, nil
+	case "only-v6":
+		return internet.IPFamilyPreference_ONLY_IP6, nil
+	default:
+		return 0, errors.New("unsupported IP family preference: ", s)
+	}
+}
+
 type StreamConfig struct {
 	Address             *Address           `json:"address"`
 	Port                uint16             `json:"port"`
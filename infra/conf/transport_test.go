@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/xtls/xray-core/common"
 	. "github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/reality"
+	"github.com/xtls/xray-core/transport/internet/splithttp"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/websocket"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -156,3 +161,142 @@ func TestSocketConfig(t *testing.T) {
 		t.Fatalf("unexpected parsed TFO value, which should be -1")
 	}
 }
+
+func TestServerNamesConfig(t *testing.T) {
+	for _, host := range []string{"example.com", "example.com:8443", "[2001:db8::1]:443"} {
+		if _, err := (&WebSocketConfig{Host: host}).Build(); err != nil {
+			t.Error("failed to build host ", host, ": ", err)
+		}
+	}
+	for _, host := range []string{"https://example.com", "example.com/path", "user@example.com"} {
+		if _, err := (&WebSocketConfig{Host: host}).Build(); err == nil {
+			t.Error("expected an error of host ", host)
+		}
+		if _, err := (&HttpUpgradeConfig{Host: host}).Build(); err == nil {
+			t.Error("expected an error of host ", host)
+		}
+		if _, err := (&SplitHTTPConfig{Host: host}).Build(); err == nil {
+			t.Error("expected an error of host ", host)
+		}
+	}
+
+	if _, err := (&TLSConfig{ServerNameOverride: "front.example.com"}).Build(); err != nil {
+		t.Error("failed to build serverNameOverride: ", err)
+	}
+	if _, err := (&TLSConfig{ServerNameOverride: "192.0.2.1"}).Build(); err == nil {
+		t.Error("expected an error of IP in serverNameOverride")
+	}
+}
+
+func TestPinnedPeerCertConfig(t *testing.T) {
+	pin := "P73/HhHUG8grALJrR03RUQKSjlYI8tnXEgTb6eenkAc="
+	config, err := (&TLSConfig{PinnedPeerCertSha256: []string{pin}, PinnedPeerSPKISha256: []string{pin}, VerifyPinnedPeerChain: true}).Build()
+	common.Must(err)
+	tlsConfig := config.(*tls.Config)
+	if len(tlsConfig.PinnedPeerCertSha256) != 1 || len(tlsConfig.PinnedPeerSpkiSha256) != 1 || !tlsConfig.VerifyPinnedPeerChain {
+		t.Error("unexpected pins: ", tlsConfig)
+	}
+	for _, pin := range []string{"not base64", "c2hvcnQ="} {
+		if _, err := (&TLSConfig{PinnedPeerCertSha256: []string{pin}}).Build(); err == nil {
+			t.Error("expected an error of pin ", pin)
+		}
+		if _, err := (&TLSConfig{PinnedPeerSPKISha256: []string{pin}}).Build(); err == nil {
+			t.Error("expected an error of pin ", pin)
+		}
+	}
+}
+
+func TestREALITYSpiderConfig(t *testing.T) {
+	config, err := (&REALITYConfig{
+		ServerName:        "example.com",
+		PublicKey:         "Ra9QkQs6K5sNBWsKwwT9fd9gB8XHHXbzgEUMlSeV9x4",
+		SpiderX:           "/?c=4",
+		SpiderDisable:     true,
+		SpiderConcurrency: 8,
+		SpiderTimeout:     5,
+	}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.(*reality.Config)
+	if !c.SpiderDisable || c.SpiderConcurrency != 8 || c.SpiderTimeout != 5 || c.SpiderY[2] != 4 {
+		t.Error("unexpected spider settings: ", c)
+	}
+}
+
+func TestPathHandlersConfig(t *testing.T) {
+	handlers := map[string]string{"/vless": "vless-in", "/trojan/": "trojan-in"}
+	expected := []*internet.PathHandler{
+		{Path: "/trojan/", Tag: "trojan-in"},
+		{Path: "/vless", Tag: "vless-in"},
+	}
+
+	ws, err := (&WebSocketConfig{Path: "/ws", PathHandlers: handlers}).Build()
+	common.Must(err)
+	xhttp, err := (&SplitHTTPConfig{Path: "/xhttp", PathHandlers: handlers}).Build()
+	common.Must(err)
+	for _, got := range [][]*internet.PathHandler{ws.(*websocket.Config).PathHandlers, xhttp.(*splithttp.Config).PathHandlers} {
+		if len(got) != len(expected) {
+			t.Fatal("unexpected path handlers: ", got)
+		}
+		for i := range got {
+			if !proto.Equal(got[i], expected[i]) {
+				t.Error("unexpected path handler: ", got[i])
+			}
+		}
+	}
+
+	for _, handlers := range []map[string]string{{"vless": "vless-in"}, {"/vless": ""}} {
+		if _, err := (&WebSocketConfig{PathHandlers: handlers}).Build(); err == nil {
+			t.Error("expected an error of path handlers ", handlers)
+		}
+	}
+
+	// the inbound of a path handler needs no port, but must exist
+	build := func(tag string) error {
+		config := new(Config)
+		common.Must(json.Unmarshal([]byte(`{"inbounds": [{
+			"tag": "ws", "port": 443, "protocol": "vless",
+			"settings": {"decryption": "none", "clients": []},
+			"streamSettings": {"network": "ws", "wsSettings": {"path": "/ws", "pathHandlers": {"/vmess": "`+tag+`"}}}
+		}, {
+			"tag": "vmess-in", "protocol": "vmess"
+		}]}`), config))
+		_, err := config.Build()
+		return err
+	}
+	common.Must(build("vmess-in"))
+	if err := build("unknown"); err == nil {
+		t.Error("expected an error of a path handler of an unknown inbound")
+	}
+}
+
+func TestXHTTPDownloadConfig(t *testing.T) {
+	build := func(s string) (proto.Message, error) {
+		config := new(SplitHTTPConfig)
+		common.Must(json.Unmarshal([]byte(s), config))
+		return config.Build()
+	}
+
+	config, err := build(`{"path": "/xhttp", "downloadDialerProxy": "cdn"}`)
+	common.Must(err)
+	if tag := config.(*splithttp.Config).DownloadDialerProxy; tag != "cdn" {
+		t.Error("unexpected downloadDialerProxy: ", tag)
+	}
+	_, err = build(`{"path": "/xhttp/", "downloadSettings": {"address": "example.com", "port": 443, "network": "xhttp", "xhttpSettings": {"path": "/xhttp"}}}`)
+	common.Must(err)
+	// a CDN may rewrite the path of the download
+	_, err = build(`{"path": "/up", "downloadSettings": {"address": "example.com", "port": 443, "network": "xhttp", "xhttpSettings": {"path": "/down"}}}`)
+	common.Must(err)
+
+	for _, s := range []string{
+		`{"mode": "stream-one", "downloadDialerProxy": "cdn"}`,
+		`{"downloadDialerProxy": "cdn", "downloadSettings": {"address": "example.com", "port": 443, "network": "xhttp"}}`,
+		`{"downloadSettings": {"network": "xhttp"}}`,
+		`{"downloadSettings": {"address": "example.com", "port": 443, "network": "ws"}}`,
+	} {
+		if _, err := build(s); err == nil {
+			t.Error("expected an error of ", s)
+		}
+	}
+}
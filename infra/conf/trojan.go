@@ -18,28 +18,29 @@ import (
 
 // TrojanServerTarget is configuration of a single trojan server
 type TrojanServerTarget struct {
-	Address  *Address `json:"address"`
-	Port     uint16   `json:"port"`
-	Level    byte     `json:"level"`
-	Email    string   `json:"email"`
-	Password string   `json:"password"`
-	Flow     string   `json:"flow"`
+	Address  AddressList `json:"address"`
+	Port     uint16      `json:"port"`
+	Level    byte        `json:"level"`
+	Email    string      `json:"email"`
+	Password string      `json:"password"`
+	Flow     string      `json:"flow"`
 }
 
 // TrojanClientConfig is configuration of trojan servers
 type TrojanClientConfig struct {
-	Address  *Address              `json:"address"`
-	Port     uint16                `json:"port"`
-	Level    byte                  `json:"level"`
-	Email    string                `json:"email"`
-	Password string                `json:"password"`
-	Flow     string                `json:"flow"`
-	Servers  []*TrojanServerTarget `json:"servers"`
+	Address   AddressList           `json:"address"`
+	Port      uint16                `json:"port"`
+	Level     byte                  `json:"level"`
+	Email     string                `json:"email"`
+	Password  string                `json:"password"`
+	Flow      string                `json:"flow"`
+	Servers   []*TrojanServerTarget `json:"servers"`
+	Heartbeat *HeartbeatConfig      `json:"heartbeat"`
 }
 
 // Build implements Buildable
 func (c *TrojanClientConfig) Build() (proto.Message, error) {
-	if c.Address != nil {
+	if len(c.Address) > 0 {
 		c.Servers = []*TrojanServerTarget{
 			{
 				Address:  c.Address,
@@ -55,10 +56,12 @@ func (c *TrojanClientConfig) Build() (proto.Message, error) {
 		return nil, errors.New(`Trojan settings: "servers" should have one and only one member. Multiple endpoints in "servers" should use multiple Trojan outbounds and routing balancer instead`)
 	}
 
-	config := &trojan.ClientConfig{}
+	config := &trojan.ClientConfig{
+		HeartbeatInterval: c.Heartbeat.Build(),
+	}
 
 	for _, rec := range c.Servers {
-		if rec.Address == nil {
+		if len(rec.Address) == 0 {
 			return nil, errors.New("Trojan server address is not set.")
 		}
 		if rec.Port == 0 {
@@ -71,10 +74,12 @@ func (c *TrojanClientConfig) Build() (proto.Message, error) {
 			return nil, errors.PrintRemovedFeatureError(`Flow for Trojan`, ``)
 		}
 
+		address, alternatives := rec.Address.buildServer()
 		config.Server = &protocol.ServerEndpoint{
-			Address: rec.Address.Build(),
-			Port:    uint32(rec.Port),
-			User:    &protocol.User{
+			Address:              address,
+			Port:                 uint32(rec.Port),
+			AlternativeAddresses: alternatives,
+			User: &protocol.User{
 				Level: uint32(rec.Level),
 				Email: rec.Email,
 				Account: serial.ToTypedMessage(&trojan.Account{
@@ -101,22 +106,26 @@ type TrojanInboundFallback struct {
 
 // TrojanUserConfig is user configuration
 type TrojanUserConfig struct {
-	Password string `json:"password"`
-	Level    byte   `json:"level"`
-	Email    string `json:"email"`
-	Flow     string `json:"flow"`
+	Password         string    `json:"password"`
+	Level            byte      `json:"level"`
+	Email            string    `json:"email"`
+	Flow             string    `json:"flow"`
+	AllowUDP         *bool     `json:"allowUDP"`
+	AllowedDestPorts *PortList `json:"allowedDestPorts"`
 }
 
 // TrojanServerConfig is Inbound configuration
 type TrojanServerConfig struct {
-	Clients   []*TrojanUserConfig      `json:"clients"`
-	Fallbacks []*TrojanInboundFallback `json:"fallbacks"`
+	Clients              []*TrojanUserConfig      `json:"clients"`
+	Fallbacks            []*TrojanInboundFallback `json:"fallbacks"`
+	FallbackMaxPrebuffer uint32                   `json:"fallbackMaxPrebuffer"`
 }
 
 // Build implements Buildable
 func (c *TrojanServerConfig) Build() (proto.Message, error) {
 	config := &trojan.ServerConfig{
-		Users: make([]*protocol.User, len(c.Clients)),
+		Users:                make([]*protocol.User, len(c.Clients)),
+		FallbackMaxPrebuffer: c.FallbackMaxPrebuffer,
 	}
 
 	for idx, rawUser := range c.Clients {
@@ -124,15 +133,24 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 			return nil, errors.PrintRemovedFeatureError(`Flow for Trojan`, ``)
 		}
 
+		account := &trojan.Account{
+			Password:   rawUser.Password,
+			DisableUdp: rawUser.AllowUDP != nil && !*rawUser.AllowUDP,
+		}
+		if rawUser.AllowedDestPorts != nil {
+			account.AllowedDestPorts = rawUser.AllowedDestPorts.Build()
+		}
 		config.Users[idx] = &protocol.User{
-			Level: uint32(rawUser.Level),
-			Email: rawUser.Email,
-			Account: serial.ToTypedMessage(&trojan.Account{
-				Password: rawUser.Password,
-			}),
+			Level:   uint32(rawUser.Level),
+			Email:   rawUser.Email,
+			Account: serial.ToTypedMessage(account),
 		}
 	}
 
+	if c.FallbackMaxPrebuffer != 0 && c.FallbackMaxPrebuffer < 64 {
+		return nil, errors.New(`Trojan settings: "fallbackMaxPrebuffer" must be at least 64`)
+	}
+
 	for _, fb := range c.Fallbacks {
 		var i uint16
 		var s string
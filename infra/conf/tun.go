@@ -0,0 +1,33 @@
+package conf
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/proxy/tun"
+	"google.golang.org/protobuf/proto"
+)
+
+type TunConfig struct {
+	Name      string   `json:"name"`
+	MTU       uint32   `json:"mtu"`
+	Address   []string `json:"address"`
+	AutoRoute bool     `json:"autoRoute"`
+	FD        int32    `json:"fd"`
+	UserLevel uint32   `json:"userLevel"`
+}
+
+func (c *TunConfig) Build() (proto.Message, error) {
+	if c.MTU != 0 && c.MTU < 576 {
+		return nil, errors.New("TUN MTU ", c.MTU, " is below the minimum of 576")
+	}
+	if c.FD < 0 {
+		return nil, errors.New("invalid TUN fd ", c.FD)
+	}
+	return &tun.Config{
+		Name:      c.Name,
+		Mtu:       c.MTU,
+		Address:   c.Address,
+		AutoRoute: c.AutoRoute,
+		Fd:        c.FD,
+		UserLevel: c.UserLevel,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package conf_test
+
+import (
+	"testing"
+
+	. "github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/proxy/tun"
+)
+
+func TestTunConfig(t *testing.T) {
+	creator := func() Buildable {
+		return new(TunConfig)
+	}
+
+	runMultiTestCase(t, []TestCase{
+		{
+			Input: `{
+				"name": "xray1",
+				"mtu": 9000,
+				"address": ["172.19.0.1/30", "fdfe:dcba:9876::1/126"],
+				"userLevel": 1
+			}`,
+			Parser: loadJSON(creator),
+			Output: &tun.Config{
+				Name:      "xray1",
+				Mtu:       9000,
+				Address:   []string{"172.19.0.1/30", "fdfe:dcba:9876::1/126"},
+				UserLevel: 1,
+			},
+		},
+		{
+			Input: `{
+				"fd": 42,
+				"autoRoute": true
+			}`,
+			Parser: loadJSON(creator),
+			Output: &tun.Config{
+				AutoRoute: true,
+				Fd:        42,
+			},
+		},
+	})
+}
@@ -30,10 +30,18 @@ type VLessInboundFallback struct {
 }
 
 type VLessInboundConfig struct {
-	Clients    []json.RawMessage       `json:"clients"`
-	Decryption string                  `json:"decryption"`
-	Fallbacks  []*VLessInboundFallback `json:"fallbacks"`
-	Flow       string                  `json:"flow"`
+	Clients              []json.RawMessage       `json:"clients"`
+	Decryption           string                  `json:"decryption"`
+	Fallbacks            []*VLessInboundFallback `json:"fallbacks"`
+	FallbackMaxPrebuffer uint32                  `json:"fallbackMaxPrebuffer"`
+	Flow                 string                  `json:"flow"`
+}
+
+func (c *VLessInboundConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"clients": new(struct {
+		protocol.User
+		vless.Account
+	})}
 }
 
 // Build implements Buildable
@@ -140,6 +148,11 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		return nil, errors.New(`VLESS settings: "fallbacks" can not be used together with "decryption"`)
 	}
 
+	if c.FallbackMaxPrebuffer != 0 && c.FallbackMaxPrebuffer < 64 {
+		return nil, errors.New(`VLESS settings: "fallbackMaxPrebuffer" must be at least 64`)
+	}
+	config.FallbackMaxPrebuffer = c.FallbackMaxPrebuffer
+
 	for _, fb := range c.Fallbacks {
 		var i uint16
 		var s string
@@ -197,13 +210,20 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 }
 
 type VLessOutboundVnext struct {
-	Address *Address          `json:"address"`
+	Address AddressList       `json:"address"`
 	Port    uint16            `json:"port"`
 	Users   []json.RawMessage `json:"users"`
 }
 
+func (c *VLessOutboundVnext) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"users": new(struct {
+		protocol.User
+		vless.Account
+	})}
+}
+
 type VLessOutboundConfig struct {
-	Address    *Address              `json:"address"`
+	Address    AddressList           `json:"address"`
 	Port       uint16                `json:"port"`
 	Level      uint32                `json:"level"`
 	Email      string                `json:"email"`
@@ -213,12 +233,14 @@ type VLessOutboundConfig struct {
 	Encryption string                `json:"encryption"`
 	Reverse    *vless.Reverse        `json:"reverse"`
 	Vnext      []*VLessOutboundVnext `json:"vnext"`
+	Heartbeat  *HeartbeatConfig      `json:"heartbeat"`
 }
 
 // Build implements Buildable
 func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 	config := new(outbound.Config)
-	if c.Address != nil {
+	config.HeartbeatInterval = c.Heartbeat.Build()
+	if len(c.Address) > 0 {
 		c.Vnext = []*VLessOutboundVnext{
 			{
 				Address: c.Address,
@@ -231,19 +253,21 @@ func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 		return nil, errors.New(`VLESS settings: "vnext" should have one and only one member. Multiple endpoints in "vnext" should use multiple VLESS outbounds and routing balancer instead`)
 	}
 	for _, rec := range c.Vnext {
-		if rec.Address == nil {
+		if len(rec.Address) == 0 {
 			return nil, errors.New(`VLESS vnext: "address" is not set`)
 		}
 		if len(rec.Users) != 1 {
 			return nil, errors.New(`VLESS vnext: "users" should have one and only one member. Multiple members in "users" should use multiple VLESS outbounds and routing balancer instead`)
 		}
+		address, alternatives := rec.Address.buildServer()
 		spec := &protocol.ServerEndpoint{
-			Address: rec.Address.Build(),
-			Port:    uint32(rec.Port),
+			Address:              address,
+			Port:                 uint32(rec.Port),
+			AlternativeAddresses: alternatives,
 		}
 		for _, rawUser := range rec.Users {
 			user := new(protocol.User)
-			if c.Address != nil {
+			if len(c.Address) > 0 {
 				user.Level = c.Level
 				user.Email = c.Email
 			} else {
@@ -252,7 +276,7 @@ func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 				}
 			}
 			account := new(vless.Account)
-			if c.Address != nil {
+			if len(c.Address) > 0 {
 				account.Id = c.Id
 				account.Flow = c.Flow
 				//account.Seed = c.Seed
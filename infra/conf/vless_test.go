@@ -82,6 +82,63 @@ func TestVLessOutbound(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"address": ["1.2.3.4", "2001:db8::1"],
+				"port": 443,
+				"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+				"encryption": "none"
+			}`,
+			Parser: loadJSON(creator),
+			Output: &outbound.Config{
+				Vnext: &protocol.ServerEndpoint{
+					Address: &net.IPOrDomain{
+						Address: &net.IPOrDomain_Ip{
+							Ip: []byte{1, 2, 3, 4},
+						},
+					},
+					Port: 443,
+					AlternativeAddresses: []*net.IPOrDomain{
+						net.NewIPOrDomain(net.ParseAddress("2001:db8::1")),
+					},
+					User: &protocol.User{
+						Account: serial.ToTypedMessage(&vless.Account{
+							Id:         "27848739-7e62-4138-9fd3-098a63964b6b",
+							Encryption: "none",
+						}),
+					},
+				},
+			},
+		},
+		{
+			Input: `{
+				"address": "example.com",
+				"port": 443,
+				"id": "27848739-7e62-4138-9fd3-098a63964b6b",
+				"encryption": "none",
+				"heartbeat": {
+					"interval": 50
+				}
+			}`,
+			Parser: loadJSON(creator),
+			Output: &outbound.Config{
+				Vnext: &protocol.ServerEndpoint{
+					Address: &net.IPOrDomain{
+						Address: &net.IPOrDomain_Domain{
+							Domain: "example.com",
+						},
+					},
+					Port: 443,
+					User: &protocol.User{
+						Account: serial.ToTypedMessage(&vless.Account{
+							Id:         "27848739-7e62-4138-9fd3-098a63964b6b",
+							Encryption: "none",
+						}),
+					},
+				},
+				HeartbeatInterval: 50,
+			},
+		},
 	})
 }
 
@@ -155,5 +212,29 @@ func TestVLessInbound(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"clients": [],
+				"decryption": "none",
+				"fallbacks": [
+					{
+						"dest": 80
+					}
+				],
+				"fallbackMaxPrebuffer": 4096
+			}`,
+			Parser: loadJSON(creator),
+			Output: &inbound.Config{
+				Clients:    []*protocol.User{},
+				Decryption: "none",
+				Fallbacks: []*inbound.Fallback{
+					{
+						Type: "tcp",
+						Dest: "localhost:80",
+					},
+				},
+				FallbackMaxPrebuffer: 4096,
+			},
+		},
 	})
 }
@@ -62,6 +62,13 @@ type VMessInboundConfig struct {
 	Defaults     *VMessDefaultConfig `json:"default"`
 }
 
+func (c *VMessInboundConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"clients": new(struct {
+		protocol.User
+		VMessAccount
+	})}
+}
+
 // Build implements Buildable
 func (c *VMessInboundConfig) Build() (proto.Message, error) {
 	config := &inbound.Config{}
@@ -95,13 +102,20 @@ func (c *VMessInboundConfig) Build() (proto.Message, error) {
 }
 
 type VMessOutboundTarget struct {
-	Address *Address          `json:"address"`
+	Address AddressList       `json:"address"`
 	Port    uint16            `json:"port"`
 	Users   []json.RawMessage `json:"users"`
 }
 
+func (c *VMessOutboundTarget) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"users": new(struct {
+		protocol.User
+		VMessAccount
+	})}
+}
+
 type VMessOutboundConfig struct {
-	Address     *Address               `json:"address"`
+	Address     AddressList            `json:"address"`
 	Port        uint16                 `json:"port"`
 	Level       uint32                 `json:"level"`
 	Email       string                 `json:"email"`
@@ -114,7 +128,7 @@ type VMessOutboundConfig struct {
 // Build implements Buildable
 func (c *VMessOutboundConfig) Build() (proto.Message, error) {
 	config := new(outbound.Config)
-	if c.Address != nil {
+	if len(c.Address) > 0 {
 		c.Receivers = []*VMessOutboundTarget{
 			{
 				Address: c.Address,
@@ -130,16 +144,18 @@ func (c *VMessOutboundConfig) Build() (proto.Message, error) {
 		if len(rec.Users) != 1 {
 			return nil, errors.New(`VMess vnext: "users" should have one and only one member. Multiple members in "users" should use multiple VMess outbounds and routing balancer instead`)
 		}
-		if rec.Address == nil {
+		if len(rec.Address) == 0 {
 			return nil, errors.New(`VMess vnext: "address" is not set`)
 		}
+		address, alternatives := rec.Address.buildServer()
 		spec := &protocol.ServerEndpoint{
-			Address: rec.Address.Build(),
-			Port:    uint32(rec.Port),
+			Address:              address,
+			Port:                 uint32(rec.Port),
+			AlternativeAddresses: alternatives,
 		}
 		for _, rawUser := range rec.Users {
 			user := new(protocol.User)
-			if c.Address != nil {
+			if len(c.Address) > 0 {
 				user.Level = c.Level
 				user.Email = c.Email
 			} else {
@@ -148,7 +164,7 @@ func (c *VMessOutboundConfig) Build() (proto.Message, error) {
 				}
 			}
 			account := new(VMessAccount)
-			if c.Address != nil {
+			if len(c.Address) > 0 {
 				account.ID = c.ID
 				account.Security = c.Security
 				account.Experiments = c.Experiments
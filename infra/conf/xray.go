@@ -6,16 +6,26 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/xtls/xray-core/app/dispatcher"
+	"github.com/xtls/xray-core/app/policy"
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/app/stats"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
 	core "github.com/xtls/xray-core/core"
+	fpolicy "github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/proxy/blackhole"
+	"github.com/xtls/xray-core/proxy/dns"
+	"github.com/xtls/xray-core/proxy/freedom"
+	"github.com/xtls/xray-core/proxy/http"
+	"github.com/xtls/xray-core/proxy/wireguard"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
 )
 
 var (
@@ -29,6 +39,7 @@ var (
 		"vless":         func() interface{} { return new(VLessInboundConfig) },
 		"vmess":         func() interface{} { return new(VMessInboundConfig) },
 		"trojan":        func() interface{} { return new(TrojanServerConfig) },
+		"tun":           func() interface{} { return new(TunConfig) },
 		"wireguard":     func() interface{} { return &WireGuardConfig{IsClient: false} },
 	}, "protocol", "settings")
 
@@ -52,11 +63,16 @@ var (
 )
 
 type SniffingConfig struct {
-	Enabled         bool        `json:"enabled"`
-	DestOverride    *StringList `json:"destOverride"`
-	DomainsExcluded *StringList `json:"domainsExcluded"`
-	MetadataOnly    bool        `json:"metadataOnly"`
-	RouteOnly       bool        `json:"routeOnly"`
+	Enabled           bool        `json:"enabled"`
+	DestOverride      *StringList `json:"destOverride"`
+	DomainsExcluded   *StringList `json:"domainsExcluded"`
+	MetadataOnly      bool        `json:"metadataOnly"`
+	RouteOnly         bool        `json:"routeOnly"`
+	TimeoutMs         uint32      `json:"sniffTimeoutMs"`
+	BufferSize        uint32      `json:"sniffBufferSize"`
+	UDPPackets        uint32      `json:"sniffUdpPackets"`
+	DestOverridePorts *PortList   `json:"destOverridePorts"`
+	Resniff           bool        `json:"resniff"`
 }
 
 // Build implements Buildable.
@@ -86,20 +102,50 @@ func (c *SniffingConfig) Build() (*proxyman.SniffingConfig, error) {
 		}
 	}
 
+	var ports *net.PortList
+	if c.DestOverridePorts != nil {
+		ports = c.DestOverridePorts.Build()
+	}
+
 	return &proxyman.SniffingConfig{
-		Enabled:             c.Enabled,
-		DestinationOverride: p,
-		DomainsExcluded:     d,
-		MetadataOnly:        c.MetadataOnly,
-		RouteOnly:           c.RouteOnly,
+		Enabled:                  c.Enabled,
+		DestinationOverride:      p,
+		DomainsExcluded:          d,
+		MetadataOnly:             c.MetadataOnly,
+		RouteOnly:                c.RouteOnly,
+		TimeoutMs:                c.TimeoutMs,
+		BufferSize:               c.BufferSize,
+		UdpPackets:               c.UDPPackets,
+		Resniff:                  c.Resniff,
+		DestinationOverridePorts: ports,
 	}, nil
 }
 
+// MuxConfig sets which traffic goes through Mux. "tcp" and "udp" tell it explicitly,
+// otherwise a negative "concurrency" or "xudpConcurrency" sends its traffic directly,
+// and "xudpConcurrency" of 0 sends UDP through the Mux of TCP.
 type MuxConfig struct {
-	Enabled         bool   `json:"enabled"`
-	Concurrency     int16  `json:"concurrency"`
-	XudpConcurrency int16  `json:"xudpConcurrency"`
-	XudpProxyUDP443 string `json:"xudpProxyUDP443"`
+	Enabled         bool                 `json:"enabled"`
+	TCP             string               `json:"tcp"`
+	UDP             string               `json:"udp"`
+	Concurrency     int16                `json:"concurrency"`
+	XudpConcurrency int16                `json:"xudpConcurrency"`
+	XudpProxyUDP443 string               `json:"xudpProxyUDP443"`
+	Congestion      *MuxCongestionConfig `json:"congestion"`
+	Adaptive        *MuxAdaptiveConfig   `json:"adaptive"`
+}
+
+// MuxCongestionConfig paces the frames of mux, "brutal" sends at the fixed rates whatever the loss.
+type MuxCongestionConfig struct {
+	Type     string `json:"type"`
+	UpMbps   uint64 `json:"upMbps"`
+	DownMbps uint64 `json:"downMbps"`
+}
+
+// MuxAdaptiveConfig sends the connections to the destinations of bulk flows without mux.
+type MuxAdaptiveConfig struct {
+	Enabled           bool   `json:"enabled"`
+	BulkThresholdKBps uint32 `json:"bulkThresholdKBps"`
 }
 
 // Build creates MultiplexingConfig, Concurrency < 0 completely disables mux.
@@ -111,53 +157,238 @@ func (m *MuxConfig) Build() (*proxyman.MultiplexingConfig, error) {
 	default:
 		return nil, errors.New(`unknown "xudpProxyUDP443": `, m.XudpProxyUDP443)
 	}
-	return &proxyman.MultiplexingConfig{
+	concurrency, xudpConcurrency := m.Concurrency, m.XudpConcurrency
+	switch strings.ToLower(m.TCP) {
+	case "":
+	case "on":
+		if concurrency < 0 {
+			return nil, errors.New(`mux "tcp": "on" conflicts with a negative "concurrency"`)
+		}
+	case "off":
+		if concurrency > 0 {
+			return nil, errors.New(`mux "tcp": "off" conflicts with a positive "concurrency"`)
+		}
+		concurrency = -1
+	default:
+		return nil, errors.New(`unknown mux "tcp": `, m.TCP)
+	}
+	switch strings.ToLower(m.UDP) {
+	case "":
+	case "xudp":
+		if xudpConcurrency < 0 {
+			return nil, errors.New(`mux "udp": "xudp" conflicts with a negative "xudpConcurrency"`)
+		}
+		if xudpConcurrency == 0 {
+			xudpConcurrency = 16
+		}
+	case "off":
+		if xudpConcurrency > 0 {
+			return nil, errors.New(`mux "udp": "off" conflicts with a positive "xudpConcurrency"`)
+		}
+		xudpConcurrency = -1
+	default:
+		return nil, errors.New(`unknown mux "udp": `, m.UDP)
+	}
+	if m.Enabled && (m.TCP != "" || m.UDP != "") && concurrency < 0 && xudpConcurrency < 0 {
+		return nil, errors.New("mux is enabled for neither TCP nor UDP")
+	}
+	if m.Enabled && strings.ToLower(m.TCP) == "off" && xudpConcurrency == 0 {
+		// UDP would go through the Mux of TCP, which is off, so nothing would use Mux at all
+		return nil, errors.New(`mux "tcp": "off" requires "udp": "xudp" or a positive "xudpConcurrency"`)
+	}
+	config := &proxyman.MultiplexingConfig{
 		Enabled:         m.Enabled,
-		Concurrency:     int32(m.Concurrency),
-		XudpConcurrency: int32(m.XudpConcurrency),
+		Concurrency:     int32(concurrency),
+		XudpConcurrency: int32(xudpConcurrency),
 		XudpProxyUDP443: m.XudpProxyUDP443,
+	}
+	if c := m.Congestion; c != nil {
+		switch strings.ToLower(c.Type) {
+		case "":
+		case "brutal":
+			if c.UpMbps == 0 && c.DownMbps == 0 {
+				return nil, errors.New(`mux congestion "brutal" requires "upMbps" or "downMbps"`)
+			}
+			config.Congestion = "brutal"
+			config.UpMbps = c.UpMbps
+			config.DownMbps = c.DownMbps
+		default:
+			return nil, errors.New("unknown mux congestion type: ", c.Type)
+		}
+	}
+	if a := m.Adaptive; a != nil && a.Enabled {
+		config.Adaptive = &proxyman.AdaptiveMuxConfig{
+			Enabled:           true,
+			BulkThresholdKbps: a.BulkThresholdKBps,
+		}
+	}
+	return config, nil
+}
+
+// PrewarmConfig keeps connections to the server of an outbound dialed ahead of the sessions.
+type PrewarmConfig struct {
+	Connections uint32 `json:"connections"`
+	MaxIdleTime uint32 `json:"maxIdleTime"`
+}
+
+// Build implements Buildable.
+func (c *PrewarmConfig) Build() (*proxyman.PrewarmConfig, error) {
+	if c.Connections > 64 {
+		return nil, errors.New("too many prewarm connections: ", c.Connections)
+	}
+	return &proxyman.PrewarmConfig{
+		Connections: c.Connections,
+		MaxIdleTime: c.MaxIdleTime,
 	}, nil
 }
 
+// BandwidthLimitConfig limits the traffic of all connections of an outbound together, in bytes per second.
+type BandwidthLimitConfig struct {
+	Uplink   uint64 `json:"uplink"`
+	Downlink uint64 `json:"downlink"`
+}
+
+// Build implements Buildable.
+func (c *BandwidthLimitConfig) Build() (*proxyman.BandwidthLimitConfig, error) {
+	return &proxyman.BandwidthLimitConfig{
+		Uplink:   c.Uplink,
+		Downlink: c.Downlink,
+	}, nil
+}
+
+// AutoBanConfig bans the sources of an inbound that fail its authentication threshold times within window seconds,
+// for duration seconds.
+type AutoBanConfig struct {
+	Threshold  uint32     `json:"threshold"`
+	Window     uint32     `json:"window"`
+	Duration   uint32     `json:"duration"`
+	Exempt     StringList `json:"exempt"`
+	Fallback   bool       `json:"fallback"`
+	MaxSources uint32     `json:"maxSources"`
+}
+
+// Build implements Buildable.
+func (c *AutoBanConfig) Build() (*proxyman.AutoBanConfig, error) {
+	config := &proxyman.AutoBanConfig{
+		Threshold:  c.Threshold,
+		Window:     c.Window,
+		Duration:   c.Duration,
+		Fallback:   c.Fallback,
+		MaxSources: c.MaxSources,
+	}
+	if len(c.Exempt) > 0 {
+		exempt, err := ToCidrList(c.Exempt)
+		if err != nil {
+			return nil, errors.New("invalid exempt sources").Base(err)
+		}
+		config.Exempt = exempt
+	}
+	return config, nil
+}
+
+// SourceIPConfig drops the connections of an inbound by source, in CIDRs or geoip: references.
+// The X-Forwarded-For of HTTP based transports is only taken as the source from "trustedProxies".
+type SourceIPConfig struct {
+	Allow          StringList `json:"allow"`
+	Deny           StringList `json:"deny"`
+	TrustedProxies StringList `json:"trustedProxies"`
+}
+
+// Build implements Buildable.
+func (c *SourceIPConfig) Build() (*proxyman.SourceIPConfig, error) {
+	config := new(proxyman.SourceIPConfig)
+	if len(c.Allow) > 0 {
+		allow, err := ToCidrList(c.Allow)
+		if err != nil {
+			return nil, errors.New("invalid allowed source IPs").Base(err)
+		}
+		config.Allow = allow
+	}
+	if len(c.Deny) > 0 {
+		deny, err := ToCidrList(c.Deny)
+		if err != nil {
+			return nil, errors.New("invalid denied source IPs").Base(err)
+		}
+		config.Deny = deny
+	}
+	if len(c.TrustedProxies) > 0 {
+		trusted, err := ToCidrList(c.TrustedProxies)
+		if err != nil {
+			return nil, errors.New("invalid trusted proxies").Base(err)
+		}
+		config.TrustedProxies = trusted
+	}
+	return config, nil
+}
+
 type InboundDetourConfig struct {
 	Protocol       string                         `json:"protocol"`
 	PortList       *PortList                      `json:"port"`
-	ListenOn       *Address                       `json:"listen"`
+	ListenOn       AddressList                    `json:"listen"`
 	Settings       *json.RawMessage               `json:"settings"`
 	Tag            string                         `json:"tag"`
 	StreamSetting  *StreamConfig                  `json:"streamSettings"`
 	SniffingConfig *SniffingConfig                `json:"sniffing"`
+	SourceIPs      *SourceIPConfig                `json:"sourceIPs"`
+	AutoBan        *AutoBanConfig                 `json:"autoBan"`
+	UDPWorkers     uint32                         `json:"udpWorkers"`
+}
+
+func (c *InboundDetourConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"settings": inboundConfigLoader.strictConfig(nil, c.Protocol)}
+}
+
+func isListenIP(address *Address) bool {
+	return address.Family().IsIP() || (address.Family().IsDomain() && address.Domain() == "localhost")
 }
 
 // Build implements Buildable.
 func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
+	return c.build(false)
+}
+
+// build builds the inbound, which needs no port if handed is set, as the path handlers of other inbounds
+// hand connections to it.
+func (c *InboundDetourConfig) build(handed bool) (*core.InboundHandlerConfig, error) {
 	receiverSettings := &proxyman.ReceiverConfig{}
 
-	if c.ListenOn == nil {
-		// Listen on anyip, must set PortList
-		if c.PortList == nil {
+	if len(c.ListenOn) == 0 {
+		// Listen on anyip, must set PortList, except for a TUN device or an inbound of path handlers,
+		// which need no listener
+		if c.PortList != nil {
+			receiverSettings.PortList = c.PortList.Build()
+		} else if c.Protocol != "tun" && !handed {
 			return nil, errors.New("Listen on AnyIP but no Port(s) set in InboundDetour.")
 		}
-		receiverSettings.PortList = c.PortList.Build()
 	} else {
-		// Listen on specific IP or Unix Domain Socket
-		receiverSettings.Listen = c.ListenOn.Build()
-		listenDS := c.ListenOn.Family().IsDomain() && (filepath.IsAbs(c.ListenOn.Domain()) || c.ListenOn.Domain()[0] == '@')
-		listenIP := c.ListenOn.Family().IsIP() || (c.ListenOn.Family().IsDomain() && c.ListenOn.Domain() == "localhost")
-		if listenIP {
+		// Listen on specific IPs or Unix Domain Socket
+		listenOn := c.ListenOn[0]
+		receiverSettings.Listen = listenOn.Build()
+		listenDS := listenOn.Family().IsDomain() && (filepath.IsAbs(listenOn.Domain()) || listenOn.Domain()[0] == '@')
+		if isListenIP(listenOn) {
 			// Listen on specific IP, must set PortList
 			if c.PortList == nil {
 				return nil, errors.New("Listen on specific ip without port in InboundDetour.")
 			}
 			// Listen on IP:Port
 			receiverSettings.PortList = c.PortList.Build()
+			// Every other IP listens on the same ports
+			for _, address := range c.ListenOn[1:] {
+				if !isListenIP(address) {
+					return nil, errors.New("unable to listen on ", address, " together with other addresses")
+				}
+				receiverSettings.ExtraListen = append(receiverSettings.ExtraListen, address.Build())
+			}
 		} else if listenDS {
+			if len(c.ListenOn) > 1 {
+				return nil, errors.New("unable to listen on Unix Domain Socket ", listenOn, " together with other addresses")
+			}
 			if c.PortList != nil {
 				// Listen on Unix Domain Socket, PortList should be nil
 				receiverSettings.PortList = nil
 			}
 		} else {
-			return nil, errors.New("unable to listen on domain address: ", c.ListenOn.Domain())
+			return nil, errors.New("unable to listen on domain address: ", listenOn.Domain())
 		}
 	}
 
@@ -175,6 +406,24 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		}
 		receiverSettings.SniffingSettings = s
 	}
+	if c.SourceIPs != nil {
+		s, err := c.SourceIPs.Build()
+		if err != nil {
+			return nil, errors.New("failed to build source IPs config").Base(err)
+		}
+		receiverSettings.SourceIps = s
+	}
+	if c.AutoBan != nil {
+		if c.AutoBan.Fallback && c.Protocol != "vless" && c.Protocol != "trojan" {
+			return nil, errors.New(`"autoBan" falls back only in VLESS and Trojan`)
+		}
+		s, err := c.AutoBan.Build()
+		if err != nil {
+			return nil, errors.New("failed to build auto ban config").Base(err)
+		}
+		receiverSettings.AutoBan = s
+	}
+	receiverSettings.UdpWorkers = c.UDPWorkers
 
 	settings := []byte("{}")
 	if c.Settings != nil {
@@ -208,6 +457,20 @@ type OutboundDetourConfig struct {
 	ProxySettings  *ProxyConfig     `json:"proxySettings"`
 	MuxSettings    *MuxConfig       `json:"mux"`
 	TargetStrategy string           `json:"targetStrategy"`
+	Prewarm        *PrewarmConfig   `json:"prewarm"`
+	Chain          []string         `json:"chain"`
+
+	// FallbackOutbound is the tag of the outbound that a session is retried through once, if dialing fails.
+	FallbackOutbound string `json:"fallbackOutbound"`
+	// BandwidthLimit caps the traffic of all connections of the outbound together, in bytes per second.
+	BandwidthLimit *BandwidthLimitConfig `json:"bandwidthLimit"`
+	// SendThroughTTL is the seconds that a destination keeps the random address from the CIDR of SendThrough
+	// after its last connection.
+	SendThroughTTL uint32 `json:"sendThroughTTL"`
+}
+
+func (c *OutboundDetourConfig) strictRawFields() map[string]interface{} {
+	return map[string]interface{}{"settings": outboundConfigLoader.strictConfig(nil, c.Protocol)}
 }
 
 func (c *OutboundDetourConfig) checkChainProxyConfig() error {
@@ -220,6 +483,29 @@ func (c *OutboundDetourConfig) checkChainProxyConfig() error {
 	return nil
 }
 
+func (c *OutboundDetourConfig) checkChain() error {
+	if c.ProxySettings != nil && len(c.ProxySettings.Tag) > 0 {
+		return errors.New("chain is conflicted with proxySettings.tag")
+	}
+	if c.StreamSetting != nil && c.StreamSetting.SocketSettings != nil && len(c.StreamSetting.SocketSettings.DialerProxy) > 0 {
+		return errors.New("chain is conflicted with sockopt.dialerProxy")
+	}
+	hops := make(map[string]bool)
+	for _, hop := range c.Chain {
+		if len(hop) == 0 {
+			return errors.New("empty outbound tag in chain")
+		}
+		if hop == c.Tag {
+			return errors.New("outbound ", c.Tag, " can not be in its own chain")
+		}
+		if hops[hop] {
+			return errors.New("outbound ", hop, " is in chain more than once")
+		}
+		hops[hop] = true
+	}
+	return nil
+}
+
 // Build implements Buildable.
 func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 	senderSettings := &proxyman.SenderConfig{}
@@ -253,15 +539,31 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 		return nil, err
 	}
 
+	if c.SendThroughTTL > 0 && (c.SendThrough == nil || !strings.Contains(*c.SendThrough, "/")) {
+		return nil, errors.New("sendThroughTTL requires a CIDR in sendThrough")
+	}
 	if c.SendThrough != nil {
 		address := ParseSendThough(c.SendThrough)
 		//Check if CIDR exists
 		if strings.Contains(*c.SendThrough, "/") {
 			senderSettings.ViaCidr = strings.Split(*c.SendThrough, "/")[1]
+			bits := 32
+			if address.Family().IsIPv6() {
+				bits = 128
+			}
+			if ones, err := strconv.Atoi(senderSettings.ViaCidr); err != nil || !address.Family().IsIP() || ones < 0 || ones > bits {
+				return nil, errors.New("unable to send through: invalid CIDR: " + *c.SendThrough)
+			}
+			senderSettings.ViaCidrTtl = c.SendThroughTTL
 		} else {
 			if address.Family().IsDomain() {
 				domain := address.Address.Domain()
-				if domain != "origin" && domain != "srcip" {
+				if probe, found := strings.CutPrefix(domain, "auto:"); found {
+					// the probe is routed by the kernel to find the address to send through
+					if !net.ParseAddress(probe).Family().IsIP() {
+						return nil, errors.New("unable to send through: the probe of auto is not an IP: " + probe)
+					}
+				} else if domain != "origin" && domain != "srcip" && domain != "auto" {
 					return nil, errors.New("unable to send through: " + address.String())
 				}
 			}
@@ -275,6 +577,15 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 			return nil, errors.New("failed to build stream settings for outbound detour").Base(err)
 		}
 		senderSettings.StreamSettings = ss
+		if sockopt := ss.SocketSettings; sockopt != nil && len(sockopt.ResolveVia) > 0 && sockopt.ResolveVia == c.Tag {
+			return nil, errors.New("outbound ", c.Tag, " can not resolve through itself")
+		}
+		if sockopt := ss.SocketSettings; sockopt != nil && (sockopt.ServerAddressTtl > 0 || len(sockopt.PinnedIps) > 0) {
+			// these resolve the servers of the proxies, not the destinations of freedom
+			if strings.ToLower(c.Protocol) == "freedom" {
+				return nil, errors.New("serverAddressTTL and pinnedIPs are not supported by outbound protocol ", c.Protocol)
+			}
+		}
 	}
 
 	if c.ProxySettings != nil {
@@ -297,6 +608,22 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 		senderSettings.ProxySettings = ps
 	}
 
+	if len(c.Chain) > 0 {
+		if err := c.checkChain(); err != nil {
+			return nil, err
+		}
+		// the outbound dials through the last hop, which dials through the hops before it in turn
+		if senderSettings.StreamSettings == nil {
+			senderSettings.StreamSettings = &internet.StreamConfig{}
+		}
+		if senderSettings.StreamSettings.SocketSettings == nil {
+			senderSettings.StreamSettings.SocketSettings = &internet.SocketConfig{}
+		}
+		sockopt := senderSettings.StreamSettings.SocketSettings
+		sockopt.DialerProxy = c.Chain[len(c.Chain)-1]
+		sockopt.DialerProxyChain = append([]string(nil), c.Chain[:len(c.Chain)-1]...)
+	}
+
 	if c.MuxSettings != nil {
 		ms, err := c.MuxSettings.Build()
 		if err != nil {
@@ -305,6 +632,33 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 		senderSettings.MultiplexSettings = ms
 	}
 
+	if len(c.FallbackOutbound) > 0 {
+		if c.FallbackOutbound == c.Tag {
+			return nil, errors.New("outbound ", c.Tag, " can not fall back to itself")
+		}
+		senderSettings.FallbackTag = c.FallbackOutbound
+	}
+
+	if c.BandwidthLimit != nil {
+		bl, err := c.BandwidthLimit.Build()
+		if err != nil {
+			return nil, errors.New("failed to build bandwidth limit config").Base(err)
+		}
+		senderSettings.BandwidthLimit = bl
+	}
+
+	if c.Prewarm != nil {
+		switch strings.ToLower(c.Protocol) {
+		case "freedom", "blackhole", "dns", "loopback":
+			return nil, errors.New("prewarm is not supported by outbound protocol ", c.Protocol)
+		}
+		pc, err := c.Prewarm.Build()
+		if err != nil {
+			return nil, errors.New("failed to build prewarm config").Base(err)
+		}
+		senderSettings.Prewarm = pc
+	}
+
 	settings := []byte("{}")
 	if c.Settings != nil {
 		settings = ([]byte)(*c.Settings)
@@ -325,11 +679,31 @@ func (c *OutboundDetourConfig) Build() (*core.OutboundHandlerConfig, error) {
 	}, nil
 }
 
-type StatsConfig struct{}
+type StatsPersistConfig struct {
+	Path     string `json:"path"`
+	Interval uint32 `json:"interval"`
+}
+
+type StatsConfig struct {
+	HistogramBounds []int64             `json:"histogramBounds"`
+	Persist         *StatsPersistConfig `json:"persist"`
+}
 
 // Build implements Buildable.
 func (c *StatsConfig) Build() (*stats.Config, error) {
-	return &stats.Config{}, nil
+	config := &stats.Config{
+		HistogramBounds: c.HistogramBounds,
+	}
+	if c.Persist != nil {
+		if len(c.Persist.Path) == 0 {
+			return nil, errors.New("path of stats persist is empty")
+		}
+		config.Persist = &stats.PersistConfig{
+			Path:     c.Persist.Path,
+			Interval: c.Persist.Interval,
+		}
+	}
+	return config, nil
 }
 
 type Config struct {
@@ -351,6 +725,40 @@ type Config struct {
 	Observatory      *ObservatoryConfig      `json:"observatory"`
 	BurstObservatory *BurstObservatoryConfig `json:"burstObservatory"`
 	Version          *VersionConfig          `json:"version"`
+	Security         *SecurityConfig         `json:"security"`
+
+	// Include lists the files this one is deep-merged onto, relative to it.
+	Include StringList `json:"include"`
+}
+
+// pathHandlerTags returns the tags of the inbounds that the path handlers of the inbounds hand connections to,
+// each of which must be an inbound.
+func pathHandlerTags(inbounds []InboundDetourConfig) (map[string]bool, error) {
+	tags := make(map[string]bool)
+	for _, ib := range inbounds {
+		s := ib.StreamSetting
+		if s == nil {
+			continue
+		}
+		var handlers []map[string]string
+		if s.WSSettings != nil {
+			handlers = append(handlers, s.WSSettings.PathHandlers)
+		}
+		for _, xhttp := range []*SplitHTTPConfig{s.XHTTPSettings, s.SplitHTTPSettings} {
+			if xhttp != nil {
+				handlers = append(handlers, xhttp.PathHandlers)
+			}
+		}
+		for _, h := range handlers {
+			for path, tag := range h {
+				if !slices.ContainsFunc(inbounds, func(ib InboundDetourConfig) bool { return ib.Tag == tag }) {
+					return nil, errors.New(`unknown inbound "`, tag, `" in "pathHandlers" for path `, path)
+				}
+				tags[tag] = true
+			}
+		}
+	}
+	return tags, nil
 }
 
 func (c *Config) findInboundTag(tag string) int {
@@ -423,6 +831,10 @@ func (c *Config) Override(o *Config, fn string) {
 		c.Version = o.Version
 	}
 
+	if o.Security != nil {
+		c.Security = o.Security
+	}
+
 	// update the Inbound in slice if the only one in override config has same tag
 	if len(o.InboundConfigs) > 0 {
 		for i := range o.InboundConfigs {
@@ -499,7 +911,11 @@ func (c *Config) Build() (*core.Config, error) {
 
 	var logConfMsg *serial.TypedMessage
 	if c.LogConfig != nil {
-		logConfMsg = serial.ToTypedMessage(c.LogConfig.Build())
+		logConf, err := c.LogConfig.Build()
+		if err != nil {
+			return nil, errors.New("failed to build log configuration").Base(err)
+		}
+		logConfMsg = serial.ToTypedMessage(logConf)
 	} else {
 		logConfMsg = serial.ToTypedMessage(DefaultLogConfig())
 	}
@@ -523,11 +939,28 @@ func (c *Config) Build() (*core.Config, error) {
 		config.App = append(config.App, serial.ToTypedMessage(dnsApp))
 	}
 
+	var pc *policy.Config
 	if c.Policy != nil {
-		pc, err := c.Policy.Build()
+		var err error
+		pc, err = c.Policy.Build()
 		if err != nil {
 			return nil, errors.New("failed to build policy configuration").Base(err)
 		}
+	}
+	if c.Security != nil {
+		sc, err := c.Security.Build()
+		if err != nil {
+			return nil, errors.New("failed to build security configuration").Base(err)
+		}
+		if pc == nil {
+			pc = new(policy.Config)
+		}
+		if pc.System == nil {
+			pc.System, _ = new(SystemPolicy).Build()
+		}
+		pc.System.Security = sc
+	}
+	if pc != nil {
 		config.App = append(config.App, serial.ToTypedMessage(pc))
 	}
 
@@ -581,8 +1014,12 @@ func (c *Config) Build() (*core.Config, error) {
 		return nil, errors.PrintRemovedFeatureError("Global transport config", "streamSettings in inbounds and outbounds")
 	}
 
+	handed, err := pathHandlerTags(inbounds)
+	if err != nil {
+		return nil, err
+	}
 	for _, rawInboundConfig := range inbounds {
-		ic, err := rawInboundConfig.Build()
+		ic, err := rawInboundConfig.build(handed[rawInboundConfig.Tag])
 		if err != nil {
 			return nil, errors.New("failed to build inbound config with tag ", rawInboundConfig.Tag).Base(err)
 		}
@@ -602,10 +1039,228 @@ func (c *Config) Build() (*core.Config, error) {
 		}
 		config.Outbound = append(config.Outbound, oc)
 	}
+	if err := checkResolveVia(config.Outbound); err != nil {
+		return nil, err
+	}
+	if err := checkFallback(config.Outbound); err != nil {
+		return nil, err
+	}
+	for _, rawOutboundConfig := range outbounds {
+		if err := checkChain(rawOutboundConfig.Tag, rawOutboundConfig.Chain, config.Outbound); err != nil {
+			return nil, err
+		}
+	}
+	if c.Security != nil {
+		if err := checkSecurity(pc.System.ToCorePolicy().Security, config.Inbound, config.Outbound); err != nil {
+			return nil, err
+		}
+	}
 
 	return config, nil
 }
 
+// checkSecurity checks that the TLS settings of the inbounds and outbounds comply with the security policy.
+func checkSecurity(p fpolicy.Security, inbounds []*core.InboundHandlerConfig, outbounds []*core.OutboundHandlerConfig) error {
+	for i, ib := range inbounds {
+		if ib.ReceiverSettings == nil {
+			continue
+		}
+		if r, err := ib.ReceiverSettings.GetInstance(); err == nil {
+			if v := proxyman.CheckStreamSecurity(r.(*proxyman.ReceiverConfig).StreamSettings, p); v != nil {
+				return errors.New("inbounds[", i, "].", v.Field, ": ", v.Message)
+			}
+		}
+	}
+	for i, ob := range outbounds {
+		if ob.SenderSettings == nil {
+			continue
+		}
+		if s, err := ob.SenderSettings.GetInstance(); err == nil {
+			if v := proxyman.CheckStreamSecurity(s.(*proxyman.SenderConfig).StreamSettings, p); v != nil {
+				return errors.New("outbounds[", i, "].", v.Field, ": ", v.Message)
+			}
+		}
+	}
+	return nil
+}
+
+// checkResolveVia checks that the outbounds resolve through outbounds that exist,
+// and that no outbound ends up resolving or dialing through itself.
+func checkResolveVia(outbounds []*core.OutboundHandlerConfig) error {
+	tags := make(map[string]bool)
+	for _, ob := range outbounds {
+		tags[ob.Tag] = true
+	}
+	// the outbounds each outbound resolves or dials through
+	next := make(map[string][]string)
+	for _, ob := range outbounds {
+		var resolveVias, dialVias []string
+		if ob.SenderSettings != nil {
+			if s, err := ob.SenderSettings.GetInstance(); err == nil {
+				sender := s.(*proxyman.SenderConfig)
+				sockopt := sender.GetStreamSettings().GetSocketSettings()
+				resolveVias = append(resolveVias, sockopt.GetResolveVia())
+				dialVias = append(dialVias, sockopt.GetDialerProxy(), sender.GetProxySettings().GetTag())
+				dialVias = append(dialVias, sockopt.GetDialerProxyChain()...)
+			}
+		}
+		if ob.ProxySettings != nil {
+			if p, err := ob.ProxySettings.GetInstance(); err == nil {
+				if f, ok := p.(*freedom.Config); ok {
+					resolveVias = append(resolveVias, f.ResolveVia)
+				}
+			}
+		}
+		for _, via := range resolveVias {
+			if len(via) > 0 && !tags[via] {
+				return errors.New("outbound ", ob.Tag, " resolves through outbound ", via, ", which does not exist")
+			}
+		}
+		if len(ob.Tag) == 0 {
+			continue
+		}
+		for _, via := range append(resolveVias, dialVias...) {
+			if len(via) > 0 {
+				next[ob.Tag] = append(next[ob.Tag], via)
+			}
+		}
+	}
+
+	// a depth-first search finds the loops, as an outbound reached again while its own search is going on
+	const (
+		searching = iota + 1
+		searched
+	)
+	state := make(map[string]int)
+	var path []string
+	var search func(tag string) error
+	search = func(tag string) error {
+		switch state[tag] {
+		case searching:
+			for i := range path {
+				if path[i] == tag {
+					return errors.New("outbounds resolve or dial through each other in a loop: ", strings.Join(append(path[i:], tag), " -> "))
+				}
+			}
+		case searched:
+			return nil
+		}
+		state[tag] = searching
+		path = append(path, tag)
+		for _, via := range next[tag] {
+			if err := search(via); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[tag] = searched
+		return nil
+	}
+	for _, ob := range outbounds {
+		if err := search(ob.Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFallback checks that the outbounds fall back to outbounds that exist.
+func checkFallback(outbounds []*core.OutboundHandlerConfig) error {
+	tags := make(map[string]bool)
+	for _, ob := range outbounds {
+		tags[ob.Tag] = true
+	}
+	for _, ob := range outbounds {
+		if ob.SenderSettings == nil {
+			continue
+		}
+		s, err := ob.SenderSettings.GetInstance()
+		if err != nil {
+			continue
+		}
+		if tag := s.(*proxyman.SenderConfig).GetFallbackTag(); len(tag) > 0 && !tags[tag] {
+			return errors.New("outbound ", ob.Tag, " falls back to outbound ", tag, ", which does not exist")
+		}
+	}
+	return nil
+}
+
+// checkChain checks that the hops of the chain of an outbound exist, and that every hop carries
+// the network that the outbound over it dials with.
+func checkChain(tag string, chain []string, outbounds []*core.OutboundHandlerConfig) error {
+	if len(chain) == 0 {
+		return nil
+	}
+	byTag := make(map[string]*core.OutboundHandlerConfig)
+	for _, ob := range outbounds {
+		byTag[ob.Tag] = ob
+	}
+	path := make([]*core.OutboundHandlerConfig, 0, len(chain)+1)
+	for _, hop := range chain {
+		ob := byTag[hop]
+		if ob == nil {
+			return errors.New("outbound ", tag, " is chained through outbound ", hop, ", which does not exist")
+		}
+		path = append(path, ob)
+	}
+	path = append(path, byTag[tag])
+	for i := 1; i < len(path); i++ {
+		hop, next := path[i-1], path[i]
+		network, transport := chainNetwork(next)
+		if !chainCarries(hop, network) {
+			return errors.New("outbound ", next.Tag, " (", transport, ") can not be chained over outbound ", hop.Tag, ", which does not carry ", network)
+		}
+	}
+	return nil
+}
+
+// chainNetwork returns the network that the outbound dials its server with, and the transport that decides it.
+func chainNetwork(ob *core.OutboundHandlerConfig) (net.Network, string) {
+	if ob.ProxySettings != nil {
+		if p, err := ob.ProxySettings.GetInstance(); err == nil {
+			if _, ok := p.(*wireguard.DeviceConfig); ok {
+				return net.Network_UDP, "wireguard"
+			}
+		}
+	}
+	var stream *internet.StreamConfig
+	if ob.SenderSettings != nil {
+		if s, err := ob.SenderSettings.GetInstance(); err == nil {
+			stream = s.(*proxyman.SenderConfig).GetStreamSettings()
+		}
+	}
+	switch stream.GetEffectiveProtocol() {
+	case "mkcp":
+		return net.Network_UDP, "mkcp"
+	case "splithttp":
+		if s, err := stream.GetEffectiveSecuritySettings(); err == nil {
+			if t, ok := s.(*tls.Config); ok && len(t.NextProtocol) == 1 && t.NextProtocol[0] == "h3" {
+				return net.Network_UDP, "xhttp over h3"
+			}
+		}
+		return net.Network_TCP, "xhttp"
+	}
+	return net.Network_TCP, stream.GetEffectiveProtocol()
+}
+
+// chainCarries returns whether the outbound carries the connections of the network for the outbound chained over it.
+func chainCarries(ob *core.OutboundHandlerConfig, network net.Network) bool {
+	if ob.ProxySettings == nil {
+		return true
+	}
+	p, err := ob.ProxySettings.GetInstance()
+	if err != nil {
+		return true
+	}
+	switch p.(type) {
+	case *http.ClientConfig:
+		return network == net.Network_TCP
+	case *blackhole.Config, *dns.Config:
+		return false
+	}
+	return true
+}
+
 // Convert string to Address.
 func ParseSendThough(Addr *string) *Address {
 	var addr Address
@@ -3,11 +3,13 @@ package conf_test
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/xtls/xray-core/app/dispatcher"
 	"github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/policy"
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/app/router"
 	"github.com/xtls/xray-core/common"
@@ -23,6 +25,7 @@ import (
 	"github.com/xtls/xray-core/transport/internet/tls"
 	"github.com/xtls/xray-core/transport/internet/websocket"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
 func TestXrayConfig(t *testing.T) {
@@ -187,6 +190,51 @@ func TestMuxConfig_Build(t *testing.T) {
 			XudpConcurrency: 0,
 			XudpProxyUDP443: "reject",
 		}},
+		{"udp only", `{"enabled": true, "tcp": "off", "udp": "xudp"}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			Concurrency:     -1,
+			XudpConcurrency: 16,
+			XudpProxyUDP443: "reject",
+		}},
+		{"tcp only", `{"enabled": true, "tcp": "on", "udp": "off", "concurrency": 8}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			Concurrency:     8,
+			XudpConcurrency: -1,
+			XudpProxyUDP443: "reject",
+		}},
+		{"tcp and xudp", `{"enabled": true, "tcp": "on", "udp": "xudp", "xudpConcurrency": 32}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			Concurrency:     0,
+			XudpConcurrency: 32,
+			XudpProxyUDP443: "reject",
+		}},
+		{"neither tcp nor udp", `{"enabled": true, "tcp": "off", "udp": "off"}`, nil},
+		{"tcp off with concurrency", `{"enabled": true, "tcp": "off", "concurrency": 8}`, nil},
+		{"tcp off without udp", `{"enabled": true, "tcp": "off"}`, nil},
+		{"tcp off with xudp concurrency", `{"enabled": true, "tcp": "off", "xudpConcurrency": 8}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			Concurrency:     -1,
+			XudpConcurrency: 8,
+			XudpProxyUDP443: "reject",
+		}},
+		{"xudp with negative concurrency", `{"enabled": true, "udp": "xudp", "xudpConcurrency": -1}`, nil},
+		{"unknown udp", `{"enabled": true, "udp": "mux"}`, nil},
+		{"brutal", `{"enabled": true, "congestion": {"type": "brutal", "upMbps": 20, "downMbps": 100}}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			XudpProxyUDP443: "reject",
+			Congestion:      "brutal",
+			UpMbps:          20,
+			DownMbps:        100,
+		}},
+		{"brutal without rates", `{"enabled": true, "congestion": {"type": "brutal"}}`, nil},
+		{"adaptive", `{"enabled": true, "adaptive": {"enabled": true, "bulkThresholdKBps": 512}}`, &proxyman.MultiplexingConfig{
+			Enabled:         true,
+			XudpProxyUDP443: "reject",
+			Adaptive: &proxyman.AdaptiveMuxConfig{
+				Enabled:           true,
+				BulkThresholdKbps: 512,
+			},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -199,6 +247,326 @@ func TestMuxConfig_Build(t *testing.T) {
 	}
 }
 
+func TestInboundListenList(t *testing.T) {
+	parse := func(listen string) (*proxyman.ReceiverConfig, error) {
+		c := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(`{"protocol": "socks", "port": "443,8443", "listen": `+listen+`}`), c))
+		config, err := c.Build()
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := config.ReceiverSettings.GetInstance()
+		common.Must(err)
+		return receiver.(*proxyman.ReceiverConfig), nil
+	}
+
+	receiver, err := parse(`"127.0.0.1"`)
+	common.Must(err)
+	if receiver.Listen.AsAddress().String() != "127.0.0.1" || len(receiver.ExtraListen) != 0 {
+		t.Error("unexpected listen ", receiver.Listen, receiver.ExtraListen)
+	}
+
+	receiver, err = parse(`["127.0.0.1", "::1"]`)
+	common.Must(err)
+	if receiver.Listen.AsAddress().String() != "127.0.0.1" || len(receiver.ExtraListen) != 1 || receiver.ExtraListen[0].AsAddress().String() != "[::1]" {
+		t.Error("unexpected listen ", receiver.Listen, receiver.ExtraListen)
+	}
+	if len(receiver.PortList.Range) != 2 {
+		t.Error("unexpected ports ", receiver.PortList)
+	}
+
+	if _, err := parse(`["127.0.0.1", "/tmp/xray.sock"]`); err == nil {
+		t.Error("expected error for Unix Domain Socket with another address")
+	}
+}
+
+func TestInboundSourceIPs(t *testing.T) {
+	c := new(InboundDetourConfig)
+	common.Must(json.Unmarshal([]byte(`{
+		"protocol": "socks",
+		"port": 1080,
+		"sourceIPs": {
+			"allow": ["10.0.0.0/8", "2001:db8::/32"],
+			"deny": ["10.0.0.1"],
+			"trustedProxies": ["192.0.2.0/24"]
+		}
+	}`), c))
+	config, err := c.Build()
+	common.Must(err)
+	receiver, err := config.ReceiverSettings.GetInstance()
+	common.Must(err)
+	sourceIPs := receiver.(*proxyman.ReceiverConfig).SourceIps
+	if len(sourceIPs.Allow) != 1 || len(sourceIPs.Allow[0].Cidr) != 2 || len(sourceIPs.Deny) != 1 || sourceIPs.Deny[0].Cidr[0].Prefix != 32 || len(sourceIPs.TrustedProxies) != 1 {
+		t.Error("unexpected source IPs ", sourceIPs)
+	}
+
+	common.Must(json.Unmarshal([]byte(`{"protocol": "socks", "port": 1080, "sourceIPs": {"deny": ["10.0.0.0/33"]}}`), c))
+	if _, err := c.Build(); err == nil {
+		t.Error("expected error for an invalid CIDR")
+	}
+}
+
+func TestInboundAutoBan(t *testing.T) {
+	build := func(inbound string) (*proxyman.ReceiverConfig, error) {
+		c := new(InboundDetourConfig)
+		common.Must(json.Unmarshal([]byte(inbound), c))
+		config, err := c.Build()
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := config.ReceiverSettings.GetInstance()
+		common.Must(err)
+		return receiver.(*proxyman.ReceiverConfig), nil
+	}
+
+	receiver, err := build(`{
+		"protocol": "trojan",
+		"port": 443,
+		"settings": {"clients": [{"password": "password"}], "fallbacks": [{"dest": 80}]},
+		"autoBan": {"threshold": 3, "window": 60, "duration": 3600, "exempt": ["192.0.2.1"], "fallback": true}
+	}`)
+	common.Must(err)
+	if r := cmp.Diff(receiver.AutoBan, &proxyman.AutoBanConfig{
+		Threshold: 3,
+		Window:    60,
+		Duration:  3600,
+		Exempt:    []*router.GeoIP{{Cidr: []*router.CIDR{{Ip: []byte{192, 0, 2, 1}, Prefix: 32}}}},
+		Fallback:  true,
+	}, protocmp.Transform()); r != "" {
+		t.Error(r)
+	}
+
+	if _, err := build(`{"protocol": "socks", "port": 1080, "autoBan": {"fallback": true}}`); err == nil {
+		t.Error("expected error for fallback without fallbacks")
+	}
+}
+
+func TestOutboundResolveVia(t *testing.T) {
+	build := func(outbounds string) error {
+		c := new(Config)
+		common.Must(json.Unmarshal([]byte(`{"outbounds": `+outbounds+`}`), c))
+		_, err := c.Build()
+		return err
+	}
+
+	common.Must(build(`[
+		{"protocol": "freedom", "tag": "direct", "settings": {"resolveVia": "proxy"}},
+		{"protocol": "freedom", "tag": "proxy", "streamSettings": {"sockopt": {"resolveVia": "dns"}}},
+		{"protocol": "freedom", "tag": "dns"}
+	]`))
+	if err := build(`[
+		{"protocol": "freedom", "tag": "direct", "settings": {"resolveVia": "proxy"}},
+		{"protocol": "freedom", "tag": "proxy", "streamSettings": {"sockopt": {"resolveVia": "direct"}}}
+	]`); err == nil {
+		t.Error("expected error for outbounds resolving through each other")
+	}
+	if err := build(`[
+		{"protocol": "freedom", "tag": "a", "settings": {"resolveVia": "b"}},
+		{"protocol": "freedom", "tag": "b", "streamSettings": {"sockopt": {"dialerProxy": "c"}}},
+		{"protocol": "freedom", "tag": "c", "proxySettings": {"tag": "a"}}
+	]`); err == nil {
+		t.Error("expected error for an outbound resolving through the outbounds dialing through it")
+	}
+	if err := build(`[{"protocol": "freedom", "tag": "direct", "settings": {"resolveVia": "proxy"}}]`); err == nil {
+		t.Error("expected error for resolving through an outbound that does not exist")
+	}
+	if err := build(`[{"protocol": "freedom", "tag": "proxy", "streamSettings": {"sockopt": {"resolveVia": "proxy"}}}]`); err == nil {
+		t.Error("expected error for an outbound resolving through itself")
+	}
+}
+
+func TestOutboundChain(t *testing.T) {
+	build := func(outbounds string) (*core.Config, error) {
+		c := new(Config)
+		common.Must(json.Unmarshal([]byte(`{"outbounds": `+outbounds+`}`), c))
+		return c.Build()
+	}
+
+	config, err := build(`[
+		{"protocol": "freedom", "tag": "out", "chain": ["first", "second"]},
+		{"protocol": "socks", "tag": "first", "settings": {"servers": [{"address": "127.0.0.1", "port": 1080}]}},
+		{"protocol": "http", "tag": "second", "settings": {"servers": [{"address": "127.0.0.1", "port": 8080}]}}
+	]`)
+	common.Must(err)
+	sender, err := config.Outbound[0].SenderSettings.GetInstance()
+	common.Must(err)
+	sockopt := sender.(*proxyman.SenderConfig).StreamSettings.SocketSettings
+	if sockopt.DialerProxy != "second" || len(sockopt.DialerProxyChain) != 1 || sockopt.DialerProxyChain[0] != "first" {
+		t.Error("unexpected dialer proxy of chain: ", sockopt.DialerProxy, " ", sockopt.DialerProxyChain)
+	}
+
+	if _, err := build(`[
+		{"protocol": "freedom", "tag": "out", "chain": ["http-up"], "streamSettings": {"network": "kcp"}},
+		{"protocol": "http", "tag": "http-up", "settings": {"servers": [{"address": "127.0.0.1", "port": 8080}]}}
+	]`); err == nil || !strings.Contains(err.Error(), "outbound out (mkcp) can not be chained over outbound http-up") {
+		t.Error("expected error for a UDP transport over an outbound of TCP only, but got ", err)
+	}
+	if _, err := build(`[{"protocol": "freedom", "tag": "out", "chain": ["missing"]}]`); err == nil {
+		t.Error("expected error for a chain through an outbound that does not exist")
+	}
+	if _, err := build(`[
+		{"protocol": "freedom", "tag": "out", "chain": ["up"], "streamSettings": {"sockopt": {"dialerProxy": "up"}}},
+		{"protocol": "freedom", "tag": "up"}
+	]`); err == nil {
+		t.Error("expected error for chain with dialerProxy")
+	}
+}
+
+func TestOutboundFallback(t *testing.T) {
+	build := func(outbounds string) (*core.Config, error) {
+		c := new(Config)
+		common.Must(json.Unmarshal([]byte(`{"outbounds": `+outbounds+`}`), c))
+		return c.Build()
+	}
+
+	config, err := build(`[
+		{"protocol": "freedom", "tag": "primary", "fallbackOutbound": "backup"},
+		{"protocol": "freedom", "tag": "backup"}
+	]`)
+	common.Must(err)
+	sender, err := config.Outbound[0].SenderSettings.GetInstance()
+	common.Must(err)
+	if tag := sender.(*proxyman.SenderConfig).FallbackTag; tag != "backup" {
+		t.Error("unexpected fallback tag: ", tag)
+	}
+
+	if _, err := build(`[{"protocol": "freedom", "tag": "primary", "fallbackOutbound": "missing"}]`); err == nil {
+		t.Error("expected error for falling back to an outbound that does not exist")
+	}
+	if _, err := build(`[{"protocol": "freedom", "tag": "primary", "fallbackOutbound": "primary"}]`); err == nil {
+		t.Error("expected error for an outbound falling back to itself")
+	}
+}
+
+func TestOutboundBandwidthLimit(t *testing.T) {
+	c := new(OutboundDetourConfig)
+	common.Must(json.Unmarshal([]byte(`{"protocol": "freedom", "bandwidthLimit": {"uplink": 25000000}}`), c))
+	config, err := c.Build()
+	common.Must(err)
+	sender, err := config.SenderSettings.GetInstance()
+	common.Must(err)
+	if limit := sender.(*proxyman.SenderConfig).BandwidthLimit; limit.GetUplink() != 25000000 || limit.GetDownlink() != 0 {
+		t.Error("unexpected bandwidth limit: ", limit)
+	}
+}
+
+func TestOutboundSendThroughAuto(t *testing.T) {
+	build := func(sendThrough string) error {
+		_, err := (&OutboundDetourConfig{Protocol: "freedom", SendThrough: &sendThrough}).Build()
+		return err
+	}
+
+	common.Must(build("auto"))
+	common.Must(build("auto:9.9.9.9"))
+	common.Must(build("auto:2620:fe::fe"))
+	if err := build("auto:dns.quad9.net"); err == nil {
+		t.Error("expected error for a probe of auto that is not an IP")
+	}
+}
+
+func TestOutboundSendThroughCIDR(t *testing.T) {
+	build := func(sendThrough string, ttl uint32) (*proxyman.SenderConfig, error) {
+		config, err := (&OutboundDetourConfig{Protocol: "freedom", SendThrough: &sendThrough, SendThroughTTL: ttl}).Build()
+		if err != nil {
+			return nil, err
+		}
+		sender, err := config.SenderSettings.GetInstance()
+		common.Must(err)
+		return sender.(*proxyman.SenderConfig), nil
+	}
+
+	sender, err := build("2001:db8:abcd::/64", 600)
+	common.Must(err)
+	if sender.ViaCidr != "64" || sender.ViaCidrTtl != 600 {
+		t.Error("unexpected sender settings: ", sender)
+	}
+	for _, sendThrough := range []string{"2001:db8:abcd::/129", "192.0.2.0/33", "192.0.2.0/x", "example.com/24"} {
+		if _, err := build(sendThrough, 0); err == nil {
+			t.Error("expected error for ", sendThrough)
+		}
+	}
+	if _, err := build("192.0.2.1", 600); err == nil {
+		t.Error("expected error for sendThroughTTL without a CIDR")
+	}
+}
+
+func TestSecurityPolicy(t *testing.T) {
+	build := func(config string) (*core.Config, error) {
+		c := new(Config)
+		common.Must(json.Unmarshal([]byte(config), c))
+		return c.Build()
+	}
+
+	config, err := build(`{
+		"security": {"minTLSVersion": "1.2", "forbidInsecure": true},
+		"inbounds": [{"protocol": "vless", "port": 443, "settings": {"decryption": "none"}, "streamSettings": {"security": "tls", "tlsSettings": {"minVersion": "1.3"}}}],
+		"outbounds": [{"protocol": "freedom", "streamSettings": {"security": "tls", "tlsSettings": {"cipherSuites": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}}]
+	}`)
+	common.Must(err)
+	var security *policy.SystemPolicy_Security
+	for _, app := range config.App {
+		if p, err := app.GetInstance(); err == nil {
+			if p, ok := p.(*policy.Config); ok {
+				security = p.GetSystem().GetSecurity()
+			}
+		}
+	}
+	if security.GetMinTlsVersion() != "1.2" || !security.GetForbidInsecure() {
+		t.Error("unexpected security policy: ", security)
+	}
+
+	for _, test := range []struct {
+		config string
+		path   string
+	}{
+		{
+			`{
+				"security": {"minTLSVersion": "1.2"},
+				"inbounds": [{"protocol": "vless", "port": 443, "settings": {"decryption": "none"}, "streamSettings": {"security": "tls", "tlsSettings": {"minVersion": "1.1"}}}]
+			}`,
+			"inbounds[0].streamSettings.tlsSettings.minVersion",
+		},
+		{
+			`{
+				"security": {"minTLSVersion": "1.3"},
+				"outbounds": [{"protocol": "freedom"}, {"protocol": "freedom", "streamSettings": {"security": "tls"}}]
+			}`,
+			"outbounds[1].streamSettings.tlsSettings.minVersion",
+		},
+		{
+			`{
+				"security": {"minTLSVersion": "1.3"},
+				"outbounds": [{"protocol": "freedom", "streamSettings": {"security": "tls", "tlsSettings": {"minVersion": "1.3", "maxVersion": "1.2"}}}]
+			}`,
+			"outbounds[0].streamSettings.tlsSettings.maxVersion",
+		},
+		{
+			`{
+				"security": {"forbidInsecure": true},
+				"outbounds": [{"protocol": "freedom", "streamSettings": {"security": "tls", "tlsSettings": {"allowInsecure": true}}}]
+			}`,
+			"outbounds[0].streamSettings.tlsSettings.allowInsecure",
+		},
+		{
+			`{
+				"security": {"forbidInsecure": true},
+				"outbounds": [{"protocol": "freedom", "streamSettings": {"network": "xhttp", "xhttpSettings": {"downloadSettings": {
+					"address": "example.com", "port": 443, "network": "xhttp", "security": "tls", "tlsSettings": {"cipherSuites": "TLS_ECDHE_RSA_WITH_RC4_128_SHA"}
+				}}}}]
+			}`,
+			"outbounds[0].streamSettings.xhttpSettings.downloadSettings.tlsSettings.cipherSuites",
+		},
+	} {
+		if _, err := build(test.config); err == nil || !strings.Contains(err.Error(), test.path+": ") {
+			t.Error("expected error at ", test.path, ", but got ", err)
+		}
+	}
+
+	if _, err := build(`{"security": {"minTLSVersion": "1.4"}}`); err == nil {
+		t.Error("expected error for an unknown TLS version")
+	}
+}
+
 func TestConfig_Override(t *testing.T) {
 	tests := []struct {
 		name string
@@ -14,6 +14,8 @@ var CmdAPI = &base.Command{
 		cmdRestartLogger,
 		cmdGetStats,
 		cmdQueryStats,
+		cmdBatchQueryStats,
+		cmdTopStats,
 		cmdSysStats,
 		cmdBalancerInfo,
 		cmdBalancerOverride,
@@ -22,15 +24,24 @@ var CmdAPI = &base.Command{
 		cmdRemoveInbounds,
 		cmdRemoveOutbounds,
 		cmdListInbounds,
+		cmdPauseInbounds,
+		cmdResumeInbounds,
 		cmdListOutbounds,
+		cmdDialOutbound,
 		cmdAddInboundUsers,
 		cmdRemoveInboundUsers,
 		cmdInboundUser,
 		cmdInboundUserCount,
+		cmdInboundBans,
+		cmdInboundNat,
+		cmdResources,
 		cmdAddRules,
 		cmdRemoveRules,
 		cmdSourceIpBlock,
 		cmdOnlineStats,
 		cmdOnlineStatsIpList,
+		cmdListConnections,
+		cmdCloseConnection,
+		cmdReloadConfig,
 	},
 }
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"strconv"
+
+	connectionService "github.com/xtls/xray-core/app/dispatcher/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdListConnections = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api conns [--server=127.0.0.1:8080] [-email ''] [-inbound ''] [-outbound '']",
+	Short:       "List live connections",
+	Long: `
+List live connections going through Xray. Requires ConnectionService.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-email
+		Only list connections of this user.
+
+	-inbound
+		Only list connections from this inbound tag.
+
+	-outbound
+		Only list connections to this outbound tag.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -email "user1@example.com"
+`,
+	Run: executeListConnections,
+}
+
+func executeListConnections(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	email := cmd.Flag.String("email", "", "")
+	inbound := cmd.Flag.String("inbound", "", "")
+	outbound := cmd.Flag.String("outbound", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := connectionService.NewConnectionServiceClient(conn)
+	r := &connectionService.ListConnectionsRequest{
+		Email:       *email,
+		InboundTag:  *inbound,
+		OutboundTag: *outbound,
+	}
+	resp, err := client.ListConnections(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to list connections: %s", err)
+	}
+	showJSONResponse(resp)
+}
+
+var cmdCloseConnection = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api connclose [--server=127.0.0.1:8080] <id>...",
+	Short:       "Close live connections",
+	Long: `
+Close live connections by their ids, as listed by "{{.Exec}} api conns". Requires ConnectionService.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 12 34
+`,
+	Run: executeCloseConnection,
+}
+
+func executeCloseConnection(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+	unnamedArgs := cmd.Flag.Args()
+	if len(unnamedArgs) == 0 {
+		base.Fatalf("no connection to close")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := connectionService.NewConnectionServiceClient(conn)
+	for _, arg := range unnamedArgs {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			base.Fatalf("invalid connection id %s: %s", arg, err)
+		}
+		resp, err := client.CloseConnection(ctx, &connectionService.CloseConnectionRequest{Id: id})
+		if err != nil {
+			base.Fatalf("failed to close connection %d: %s", id, err)
+		}
+		showJSONResponse(resp)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdInboundBans = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api inboundbans [--server=127.0.0.1:8080] -tag=tag [-clear] [ip]...",
+	Short:       "List or clear the bans of an inbound",
+	Long: `
+List the sources banned by the autoBan of an inbound, or clear them.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag
+
+	-clear
+		Unban the IPs given as arguments, or all of the sources if none.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name"
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -clear 1.2.3.4
+`,
+	Run: executeInboundBans,
+}
+
+func executeInboundBans(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	var clear bool
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.BoolVar(&clear, "clear", false, "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	if clear {
+		r := &handlerService.ClearInboundBansRequest{
+			Tag: tag,
+			Ips: cmd.Flag.Args(),
+		}
+		resp, err := client.ClearInboundBans(ctx, r)
+		if err != nil {
+			base.Fatalf("failed to clear inbound bans: %s", err)
+		}
+		showJSONResponse(resp)
+		return
+	}
+	r := &handlerService.GetInboundBansRequest{
+		Tag: tag,
+	}
+	resp, err := client.GetInboundBans(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to get inbound bans: %s", err)
+	}
+	showJSONResponse(resp)
+}
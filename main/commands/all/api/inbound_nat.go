@@ -0,0 +1,54 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdInboundNat = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api inboundnat [--server=127.0.0.1:8080] -tag=tag",
+	Short:       "List the UDP NAT table of an inbound",
+	Long: `
+List the UDP NAT table of an inbound receiving original destinations, such as
+a dokodemo-door with followRedirect over TPROXY: each source, original
+destination and outbound, with the number of the packets dropped as their
+original destinations could not be recovered.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Inbound tag
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name"
+`,
+	Run: executeInboundNat,
+}
+
+func executeInboundNat(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var tag string
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	r := &handlerService.GetInboundNatRequest{
+		Tag: tag,
+	}
+	resp, err := client.GetInboundNat(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to get inbound NAT table: %s", err)
+	}
+	showJSONResponse(resp)
+}
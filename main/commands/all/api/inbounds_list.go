@@ -7,7 +7,7 @@ import (
 
 var cmdListInbounds = &base.Command{
 	CustomFlags: true,
-	UsageLine:   "{{.Exec}} api lsi [--server=127.0.0.1:8080] [--isOnlyTags=true]",
+	UsageLine:   "{{.Exec}} api lsi [--server=127.0.0.1:8080] [--isOnlyTags=true] [-tag tag] [-redact]",
 	Short:       "List inbounds",
 	Long: `
 List inbounds in Xray.
@@ -20,9 +20,16 @@ Arguments:
 	-t, -timeout <seconds>
 		Timeout in seconds for calling API. Default 3
 
+	-tag
+		Only show the inbound with this tag.
+
+	-redact
+		Clear secrets, like user ids, passwords and private keys, from the output.
+
 Example:
 
 	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag "vless-in" -redact
 `,
 	Run: executeListInbounds,
 }
@@ -31,6 +38,8 @@ func executeListInbounds(cmd *base.Command, args []string) {
 	setSharedFlags(cmd)
 	var isOnlyTagsStr string
 	cmd.Flag.StringVar(&isOnlyTagsStr, "isOnlyTags", "", "")
+	tag := cmd.Flag.String("tag", "", "")
+	redact := cmd.Flag.Bool("redact", false, "")
 	cmd.Flag.Parse(args)
 	isOnlyTags := isOnlyTagsStr == "true"
 
@@ -39,7 +48,16 @@ func executeListInbounds(cmd *base.Command, args []string) {
 
 	client := handlerService.NewHandlerServiceClient(conn)
 
-	resp, err := client.ListInbounds(ctx, &handlerService.ListInboundsRequest{IsOnlyTags: isOnlyTags})
+	if *tag != "" {
+		resp, err := client.GetInbound(ctx, &handlerService.GetInboundRequest{Tag: *tag, Redact: *redact})
+		if err != nil {
+			base.Fatalf("failed to get inbound: %s", err)
+		}
+		showJSONResponse(resp)
+		return
+	}
+
+	resp, err := client.ListInbounds(ctx, &handlerService.ListInboundsRequest{IsOnlyTags: isOnlyTags, Redact: *redact})
 	if err != nil {
 		base.Fatalf("failed to list inbounds: %s", err)
 	}
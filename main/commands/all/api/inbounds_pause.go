@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdPauseInbounds = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api pausei [--server=127.0.0.1:8080] <tag>...",
+	Short:       "Pause inbounds",
+	Long: `
+Pause inbounds in Xray. A paused inbound accepts no new connections,
+while it keeps its users and the connections it has accepted.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 "tag name"
+`,
+	Run: executePauseInbounds,
+}
+
+var cmdResumeInbounds = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api resumei [--server=127.0.0.1:8080] <tag>...",
+	Short:       "Resume inbounds",
+	Long: `
+Resume paused inbounds in Xray, to accept connections with the same settings again.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 "tag name"
+`,
+	Run: executeResumeInbounds,
+}
+
+func executePauseInbounds(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+	tags := cmd.Flag.Args()
+	if len(tags) == 0 {
+		base.Fatalf("no inbound to pause")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	for _, tag := range tags {
+		fmt.Println("pausing:", tag)
+		resp, err := client.PauseInbound(ctx, &handlerService.PauseInboundRequest{Tag: tag})
+		if err != nil {
+			base.Fatalf("failed to pause inbound: %s", err)
+		}
+		showJSONResponse(resp)
+	}
+}
+
+func executeResumeInbounds(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	cmd.Flag.Parse(args)
+	tags := cmd.Flag.Args()
+	if len(tags) == 0 {
+		base.Fatalf("no inbound to resume")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	for _, tag := range tags {
+		fmt.Println("resuming:", tag)
+		resp, err := client.ResumeInbound(ctx, &handlerService.ResumeInboundRequest{Tag: tag})
+		if err != nil {
+			base.Fatalf("failed to resume inbound: %s", err)
+		}
+		showJSONResponse(resp)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdDialOutbound = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api dialo [--server=127.0.0.1:8080] -tag=tag <target>",
+	Short:       "Test dialing a target through an outbound",
+	Long: `
+Dial a target through an outbound, the way the traffic routed to it is dialed,
+and show the time of each stage, or the stage that fails with its error.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-tag
+		Outbound tag
+
+	-dialtimeout <milliseconds>
+		Timeout of the test. Default 2000, which should be shorter than -timeout
+
+	-tls
+		Perform a TLS handshake with the target
+
+	-http
+		Send an HTTP GET to the target, and wait for the first byte of the response
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag="tag name" -tls -http example.com:443
+`,
+	Run: executeDialOutbound,
+}
+
+func executeDialOutbound(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var (
+		tag         string
+		dialTimeout uint
+		expectTLS   bool
+		httpGet     bool
+	)
+	cmd.Flag.StringVar(&tag, "tag", "", "")
+	cmd.Flag.UintVar(&dialTimeout, "dialtimeout", 2000, "")
+	cmd.Flag.BoolVar(&expectTLS, "tls", false, "")
+	cmd.Flag.BoolVar(&httpGet, "http", false, "")
+	cmd.Flag.Parse(args)
+	if cmd.Flag.NArg() != 1 {
+		base.Fatalf("one target is required")
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	r := &handlerService.TestOutboundRequest{
+		Tag:       tag,
+		Target:    cmd.Flag.Arg(0),
+		TimeoutMs: uint32(dialTimeout),
+		ExpectTls: expectTLS,
+		HttpGet:   httpGet,
+	}
+	resp, err := client.TestOutbound(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to test outbound: %s", err)
+	}
+	showJSONResponse(resp)
+}
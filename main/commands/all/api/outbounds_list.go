@@ -7,7 +7,7 @@ import (
 
 var cmdListOutbounds = &base.Command{
 	CustomFlags: true,
-	UsageLine:   "{{.Exec}} api lso [--server=127.0.0.1:8080]",
+	UsageLine:   "{{.Exec}} api lso [--server=127.0.0.1:8080] [-tag tag] [-redact]",
 	Short:       "List outbounds",
 	Long: `
 List outbounds in Xray.
@@ -20,22 +20,40 @@ Arguments:
 	-t, -timeout <seconds>
 		Timeout in seconds for calling API. Default 3
 
+	-tag
+		Only show the outbound with this tag.
+
+	-redact
+		Clear secrets, like user ids, passwords and private keys, from the output.
+
 Example:
 
 	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -tag "proxy" -redact
 `,
 	Run: executeListOutbounds,
 }
 
 func executeListOutbounds(cmd *base.Command, args []string) {
 	setSharedFlags(cmd)
+	tag := cmd.Flag.String("tag", "", "")
+	redact := cmd.Flag.Bool("redact", false, "")
 	cmd.Flag.Parse(args)
 
 	conn, ctx, close := dialAPIServer()
 	defer close()
 
 	client := handlerService.NewHandlerServiceClient(conn)
-	resp, err := client.ListOutbounds(ctx, &handlerService.ListOutboundsRequest{})
+	if *tag != "" {
+		resp, err := client.GetOutbound(ctx, &handlerService.GetOutboundRequest{Tag: *tag, Redact: *redact})
+		if err != nil {
+			base.Fatalf("failed to get outbound: %s", err)
+		}
+		showJSONResponse(resp)
+		return
+	}
+
+	resp, err := client.ListOutbounds(ctx, &handlerService.ListOutboundsRequest{Redact: *redact})
 	if err != nil {
 		base.Fatalf("failed to list outbounds: %s", err)
 	}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"github.com/xtls/xray-core/app/reload"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdReloadConfig = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api reload [--server=127.0.0.1:8080] [-dry] [-remote] <c1.json> [c2.json]...",
+	Short:       "Reload config",
+	Long: `
+Reload the whole config of Xray without restarting it. Requires ReloadService.
+
+Inbounds and outbounds are added, removed or replaced as needed, routing rules
+and DNS are swapped. The changes that can't be applied at runtime are reported
+as requiring a restart.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-dry
+		Only show the changes, without applying them.
+
+	-remote
+		Load the config files on the machine running Xray, instead of sending
+		the config built locally.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -dry config.json
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -remote /etc/xray/config.json
+`,
+	Run: executeReloadConfig,
+}
+
+func executeReloadConfig(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	dryRun := cmd.Flag.Bool("dry", false, "")
+	remote := cmd.Flag.Bool("remote", false, "")
+	cmd.Flag.Parse(args)
+	unnamedArgs := cmd.Flag.Args()
+	if len(unnamedArgs) == 0 {
+		base.Fatalf("no config file specified")
+	}
+
+	r := &reload.ReloadConfigRequest{
+		DryRun: *dryRun,
+	}
+	if *remote {
+		r.Files = unnamedArgs
+	} else {
+		config, err := core.LoadConfig("auto", cmdarg.Arg(unnamedArgs))
+		if err != nil {
+			base.Fatalf("failed to load config: %s", err)
+		}
+		r.Config = config
+	}
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := reload.NewReloadServiceClient(conn)
+	resp, err := client.ReloadConfig(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to reload config: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	handlerService "github.com/xtls/xray-core/app/proxyman/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdResources = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api resources [--server=127.0.0.1:8080] [-gc]",
+	Short:       "Show the resources held by the handlers",
+	Long: `
+Show the TCP sessions, UDP NAT entries, mux streams and bytes buffered in
+pipes of every inbound and outbound, or expire their idle sessions.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-gc
+		Run the expiry scans of the NAT tables and mux connections now,
+		instead of showing the resources.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -gc
+`,
+	Run: executeResources,
+}
+
+func executeResources(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var gc bool
+	cmd.Flag.BoolVar(&gc, "gc", false, "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := handlerService.NewHandlerServiceClient(conn)
+	if gc {
+		resp, err := client.RunGC(ctx, &handlerService.RunGCRequest{})
+		if err != nil {
+			base.Fatalf("failed to run gc: %s", err)
+		}
+		showJSONResponse(resp)
+		return
+	}
+	resp, err := client.GetResourceStats(ctx, &handlerService.GetResourceStatsRequest{})
+	if err != nil {
+		base.Fatalf("failed to get resource stats: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	statsService "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdBatchQueryStats = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api statsbatch [--server=127.0.0.1:8080] [-pattern ''...]",
+	Short:       "Query statistics of multiple patterns at once",
+	Long: `
+Query statistics of multiple patterns from Xray in a single call.
+Counters are fetched (and reset) only once even if they match several patterns.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for calling API. Default 3
+
+	-pattern
+		Filter pattern for the statistics query. Can be specified multiple times.
+
+	-reset
+		Reset the counters after fetching their values. Default false
+
+	-sum
+		Only show the sum of each pattern. Default false
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -pattern "user>>>" -pattern "inbound>>>" -reset
+`,
+	Run: executeBatchQueryStats,
+}
+
+func executeBatchQueryStats(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	var patterns cmdarg.Arg
+	cmd.Flag.Var(&patterns, "pattern", "")
+	reset := cmd.Flag.Bool("reset", false, "")
+	sumOnly := cmd.Flag.Bool("sum", false, "")
+	cmd.Flag.Parse(args)
+
+	conn, ctx, close := dialAPIServer()
+	defer close()
+
+	client := statsService.NewStatsServiceClient(conn)
+	r := &statsService.BatchQueryStatsRequest{
+		Patterns: patterns,
+		Reset_:   *reset,
+		SumOnly:  *sumOnly,
+	}
+	resp, err := client.BatchQueryStats(ctx, r)
+	if err != nil {
+		base.Fatalf("failed to query stats: %s", err)
+	}
+	showJSONResponse(resp)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	statsService "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdTopStats = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} api top [--server=127.0.0.1:8080] [-sort total] [-interval 1] [-limit 10]",
+	Short:       "Show the users and outbounds of the highest traffic rates",
+	Long: `
+Show the users and outbounds of the highest traffic rates, refreshed every interval until interrupted.
+The rates are the deltas of the traffic counters between two queries, which leave the counters as they are.
+A counter that decreases, like after a reset by others or a restart of Xray, counts again from zero.
+
+Arguments:
+
+	-s, -server <server:port>
+		The API server address. Default 127.0.0.1:8080
+
+	-t, -timeout <seconds>
+		Timeout in seconds for each call of the API. Default 3
+
+	-sort <total|up|down|name>
+		Sort by the total, uplink or downlink rate, or by the name. Default total
+
+	-interval <seconds>
+		Seconds between two queries. Default 1
+
+	-limit <n>
+		Number of rows to show, 0 for all. Default 10
+
+	-json
+		Print a line of JSON with the rates in bytes per second for each interval, instead of the table.
+
+Example:
+
+	{{.Exec}} {{.LongName}} --server=127.0.0.1:8080 -sort down -interval 2
+`,
+	Run: executeTopStats,
+}
+
+// topPatterns match the traffic counters of users and outbounds.
+var topPatterns = []string{"user>>>", "outbound>>>"}
+
+type topRow struct {
+	Type     string  `json:"type"`
+	Name     string  `json:"name"`
+	Uplink   float64 `json:"uplink"`
+	Downlink float64 `json:"downlink"`
+}
+
+func executeTopStats(cmd *base.Command, args []string) {
+	setSharedFlags(cmd)
+	sortBy := cmd.Flag.String("sort", "total", "")
+	interval := cmd.Flag.Int("interval", 1, "")
+	limit := cmd.Flag.Int("limit", 10, "")
+	cmd.Flag.Parse(args)
+
+	switch *sortBy {
+	case "total", "up", "down", "name":
+	default:
+		base.Fatalf("unknown sort %s", *sortBy)
+	}
+	if *interval <= 0 {
+		base.Fatalf("invalid interval %d", *interval)
+	}
+
+	conn, _, close := dialAPIServer()
+	defer close()
+	client := statsService.NewStatsServiceClient(conn)
+
+	last, err := queryTraffic(client)
+	if err != nil {
+		base.Fatalf("failed to query stats: %s", err)
+	}
+	lastTime := time.Now()
+	ticker := time.NewTicker(time.Duration(*interval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		current, err := queryTraffic(client)
+		if err != nil {
+			base.Fatalf("failed to query stats: %s", err)
+		}
+		now := time.Now()
+		rows := topRows(last, current, now.Sub(lastTime))
+		last, lastTime = current, now
+
+		sortTopRows(rows, *sortBy)
+		if *limit > 0 && len(rows) > *limit {
+			rows = rows[:*limit]
+		}
+		if apiJSON {
+			printTopJSON(now, rows)
+		} else {
+			printTopTable(now, rows)
+		}
+	}
+}
+
+// queryTraffic returns the values of the traffic counters of users and outbounds.
+func queryTraffic(client statsService.StatsServiceClient) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(apiTimeout)*time.Second)
+	defer cancel()
+	resp, err := client.BatchQueryStats(ctx, &statsService.BatchQueryStatsRequest{Patterns: topPatterns})
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]int64)
+	for _, result := range resp.Result {
+		for _, stat := range result.Stat {
+			values[stat.Name] = stat.Value
+		}
+	}
+	return values, nil
+}
+
+// topRows returns the rates of the users and outbounds, from the values of their counters in two queries.
+func topRows(last, current map[string]int64, elapsed time.Duration) []*topRow {
+	rows := make(map[string]*topRow)
+	var list []*topRow
+	for name, value := range current {
+		// user>>>email>>>traffic>>>uplink, outbound>>>tag>>>traffic>>>downlink
+		parts := strings.Split(name, ">>>")
+		if len(parts) != 4 || parts[2] != "traffic" || (parts[0] != "user" && parts[0] != "outbound") {
+			continue
+		}
+		delta := value - last[name]
+		if delta < 0 {
+			// the counter has been reset since the last query
+			delta = value
+		}
+		key := parts[0] + ">>>" + parts[1]
+		row := rows[key]
+		if row == nil {
+			row = &topRow{Type: parts[0], Name: parts[1]}
+			rows[key] = row
+			list = append(list, row)
+		}
+		rate := float64(delta) / elapsed.Seconds()
+		switch parts[3] {
+		case "uplink":
+			row.Uplink += rate
+		case "downlink":
+			row.Downlink += rate
+		}
+	}
+	return list
+}
+
+func sortTopRows(rows []*topRow, by string) {
+	key := func(r *topRow) float64 {
+		switch by {
+		case "up":
+			return r.Uplink
+		case "down":
+			return r.Downlink
+		}
+		return r.Uplink + r.Downlink
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if by != "name" {
+			if ki, kj := key(rows[i]), key(rows[j]); ki != kj {
+				return ki > kj
+			}
+		}
+		if rows[i].Type != rows[j].Type {
+			return rows[i].Type < rows[j].Type
+		}
+		return rows[i].Name < rows[j].Name
+	})
+}
+
+func printTopJSON(now time.Time, rows []*topRow) {
+	b, err := json.Marshal(struct {
+		Time int64     `json:"time"`
+		Rows []*topRow `json:"rows"`
+	}{now.Unix(), rows})
+	if err != nil {
+		base.Fatalf("error encode json: %s", err)
+	}
+	fmt.Println(string(b))
+}
+
+func printTopTable(now time.Time, rows []*topRow) {
+	// clears the terminal, like watch
+	fmt.Print("\033[H\033[2J")
+	fmt.Println(now.Format(time.TimeOnly))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tNAME\tUPLINK\tDOWNLINK\tTOTAL")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Type, r.Name, formatRate(r.Uplink), formatRate(r.Downlink), formatRate(r.Uplink+r.Downlink))
+	}
+	w.Flush()
+}
+
+func formatRate(rate float64) string {
+	units := []string{"B/s", "KiB/s", "MiB/s", "GiB/s"}
+	i := 0
+	for ; rate >= 1024 && i < len(units)-1; i++ {
+		rate /= 1024
+	}
+	return fmt.Sprintf("%.1f %s", rate, units[i])
+}
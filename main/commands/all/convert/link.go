@@ -0,0 +1,149 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/infra/conf/sharelink"
+	"github.com/xtls/xray-core/main/commands/base"
+	"github.com/xtls/xray-core/main/confloader"
+)
+
+var cmdLink = &base.Command{
+	CustomFlags: true,
+	UsageLine:   "{{.Exec}} convert link [-r] [link...] [stdin:] [outbounds file]",
+	Short:       "Convert share links to outbounds and back",
+	Long: `
+Convert vless://, vmess://, trojan:// and ss:// share links to outbounds.
+
+With -r, convert the outbounds of a file to share links instead. The file
+may hold a config with "outbounds", a list of outbounds or one outbound.
+
+Arguments:
+
+	-r, -reverse
+		Convert outbounds to share links.
+
+Examples:
+
+    {{.Exec}} convert link "vless://id@example.com:443?security=tls&type=ws#proxy"
+    {{.Exec}} convert link -r config.json
+	`,
+	Run: executeLink,
+}
+
+func executeLink(cmd *base.Command, args []string) {
+	var reverse bool
+	cmd.Flag.BoolVar(&reverse, "r", false, "")
+	cmd.Flag.BoolVar(&reverse, "reverse", false, "")
+	cmd.Flag.Parse(args)
+
+	if cmd.Flag.NArg() < 1 {
+		base.Fatalf("empty input list")
+	}
+
+	if reverse {
+		for _, ob := range loadOutbounds(cmd.Flag.Arg(0)) {
+			link, err := sharelink.Format(&sharelink.Link{Outbound: ob})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "skipping outbound %q: %s\n", ob.Tag, err)
+				continue
+			}
+			fmt.Println(link)
+		}
+		return
+	}
+
+	var outbounds []interface{}
+	for _, arg := range cmd.Flag.Args() {
+		l, err := sharelink.Parse(arg)
+		if err != nil {
+			base.Fatalf("failed to parse %s: %s", arg, err)
+		}
+		if len(l.Extra) > 0 {
+			fmt.Fprintf(os.Stderr, "parameters without a place in the outbound: %s\n", l.Extra.Encode())
+		}
+		outbounds = append(outbounds, compact(l.Outbound))
+	}
+	b, err := json.MarshalIndent(map[string]interface{}{"outbounds": outbounds}, "", "  ")
+	if err != nil {
+		base.Fatalf("failed to marshal outbounds: %s", err)
+	}
+	fmt.Println(string(b))
+}
+
+func loadOutbounds(arg string) []*conf.OutboundDetourConfig {
+	reader, err := confloader.LoadConfig(arg)
+	if err != nil {
+		base.Fatalf("failed to load outbounds: %s", err)
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		base.Fatalf("failed to read outbounds: %s", err)
+	}
+
+	var config struct {
+		Outbounds []*conf.OutboundDetourConfig `json:"outbounds"`
+	}
+	if err := json.Unmarshal(b, &config); err == nil && config.Outbounds != nil {
+		return config.Outbounds
+	}
+	var outbounds []*conf.OutboundDetourConfig
+	if err := json.Unmarshal(b, &outbounds); err == nil {
+		return outbounds
+	}
+	ob := new(conf.OutboundDetourConfig)
+	if err := json.Unmarshal(b, ob); err != nil {
+		base.Fatalf("failed to unmarshal outbounds: %s", err)
+	}
+	return []*conf.OutboundDetourConfig{ob}
+}
+
+// compact returns v as JSON values without the unset fields, which the config structs have no omitempty for.
+func compact(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		base.Fatalf("failed to marshal outbound: %s", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		base.Fatalf("failed to marshal outbound: %s", err)
+	}
+	return prune(value)
+}
+
+func prune(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if field = prune(field); field == nil {
+				delete(v, key)
+			} else {
+				v[key] = field
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	}
+	return value
+}
@@ -1,12 +1,10 @@
 package all
 
 import (
-	"crypto/ecdh"
-	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 
-	"lukechampine.com/blake3"
+	"github.com/xtls/xray-core/common/keygen"
 )
 
 func Curve25519Genkey(StdEncoding bool, input_base64 string) {
@@ -37,27 +35,14 @@ func Curve25519Genkey(StdEncoding bool, input_base64 string) {
 }
 
 func genCurve25519(inputPrivateKey []byte) (privateKey []byte, password []byte, hash32 [32]byte, returnErr error) {
+	var key *keygen.X25519Key
 	if len(inputPrivateKey) > 0 {
-		privateKey = inputPrivateKey
-	}
-	if privateKey == nil {
-		privateKey = make([]byte, 32)
-		rand.Read(privateKey)
+		key, returnErr = keygen.NewX25519Key(inputPrivateKey)
+	} else {
+		key, returnErr = keygen.New().X25519()
 	}
-
-	// Modify random bytes using algorithm described at:
-	// https://cr.yp.to/ecdh.html
-	// (Just to make sure printing the real private key)
-	privateKey[0] &= 248
-	privateKey[31] &= 127
-	privateKey[31] |= 64
-
-	key, err := ecdh.X25519().NewPrivateKey(privateKey)
-	if err != nil {
-		returnErr = err
+	if returnErr != nil {
 		return
 	}
-	password = key.PublicKey().Bytes()
-	hash32 = blake3.Sum256(password)
-	return
+	return key.PrivateKey, key.PublicKey, key.Hash32(), nil
 }
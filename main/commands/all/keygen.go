@@ -0,0 +1,226 @@
+package all
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xtls/xray-core/common/keygen"
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/infra/conf/sharelink"
+	"github.com/xtls/xray-core/main/commands/base"
+)
+
+var cmdKeygen = &base.Command{
+	UsageLine: `{{.Exec}} keygen [-type reality|vlessenc|wg] [-format json|env] [-client -address example.com]`,
+	Short:     `Generate the keys of REALITY, VLESS Encryption or WireGuard`,
+	Long: `
+Generate the keys of REALITY, VLESS Encryption or WireGuard, optionally with
+the outbound and the share link of a client of the server they are for.
+
+Arguments:
+
+	-type reality|vlessenc|wg
+		The keys to generate. Default reality
+
+	-format json|env
+		Print the keys in JSON, or as the variables of an env file. Default json
+
+	-shortids <count>
+		The number of the short IDs of REALITY. Default 1
+
+	-shortidlen <length>
+		The length of the short IDs of REALITY in hex digits, even and up to 16. Default 16
+
+	-auth x25519|mlkem768
+		The authentication of VLESS Encryption. Default x25519
+
+	-client
+		Also print a user ID, and the outbound and the share link of a client with it,
+		for REALITY or VLESS Encryption
+
+	-address, -port
+		The address and the port of the server for -client. Default port 443
+
+	-sni
+		The server name of REALITY for -client, which is one of its serverNames
+
+	-seed
+		Derive the keys from the seed rather than randomly, for tests only
+
+Example:
+
+	{{.Exec}} {{.LongName}} -shortids 2 -client -address example.com -sni www.example.org
+	{{.Exec}} {{.LongName}} -type vlessenc -auth mlkem768 -format env
+`,
+}
+
+func init() {
+	cmdKeygen.Run = executeKeygen // break init loop
+}
+
+var (
+	keygenType       = cmdKeygen.Flag.String("type", "reality", "")
+	keygenFormat     = cmdKeygen.Flag.String("format", "json", "")
+	keygenShortIds   = cmdKeygen.Flag.Int("shortids", 1, "")
+	keygenShortIdLen = cmdKeygen.Flag.Int("shortidlen", 16, "")
+	keygenAuth       = cmdKeygen.Flag.String("auth", "x25519", "")
+	keygenClient     = cmdKeygen.Flag.Bool("client", false, "")
+	keygenAddress    = cmdKeygen.Flag.String("address", "", "")
+	keygenPort       = cmdKeygen.Flag.Uint("port", 443, "")
+	keygenSNI        = cmdKeygen.Flag.String("sni", "", "")
+	keygenSeed       = cmdKeygen.Flag.String("seed", "", "")
+)
+
+// keygenOutput is the output of keygen, in JSON or as the variables of an env file.
+type keygenOutput struct {
+	PrivateKey string          `json:"privateKey,omitempty"`
+	Password   string          `json:"password,omitempty"`
+	PublicKey  string          `json:"publicKey,omitempty"`
+	ShortIds   []string        `json:"shortIds,omitempty"`
+	Decryption string          `json:"decryption,omitempty"`
+	Encryption string          `json:"encryption,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	Outbound   json.RawMessage `json:"outbound,omitempty"`
+	Link       string          `json:"link,omitempty"`
+}
+
+func executeKeygen(cmd *base.Command, args []string) {
+	g := keygen.New()
+	if *keygenSeed != "" {
+		g = keygen.NewSeeded(*keygenSeed)
+	}
+
+	var out keygenOutput
+	var prefix string
+	var err error
+	switch *keygenType {
+	case "reality":
+		prefix = "REALITY_"
+		key, err := g.X25519()
+		if err != nil {
+			base.Fatalf("failed to generate X25519 key: %s", err)
+		}
+		out.PrivateKey = base64.RawURLEncoding.EncodeToString(key.PrivateKey)
+		out.Password = base64.RawURLEncoding.EncodeToString(key.PublicKey)
+		if out.ShortIds, err = g.ShortIds(*keygenShortIds, *keygenShortIdLen); err != nil {
+			base.Fatalf("%s", err)
+		}
+	case "vlessenc":
+		prefix = "VLESS_"
+		if out.Decryption, out.Encryption, err = g.VLESSEncryption(*keygenAuth); err != nil {
+			base.Fatalf("%s", err)
+		}
+	case "wg":
+		prefix = "WG_"
+		key, err := g.X25519()
+		if err != nil {
+			base.Fatalf("failed to generate X25519 key: %s", err)
+		}
+		out.PrivateKey = base64.StdEncoding.EncodeToString(key.PrivateKey)
+		out.PublicKey = base64.StdEncoding.EncodeToString(key.PublicKey)
+	default:
+		base.Fatalf("unknown type: %s", *keygenType)
+	}
+
+	if *keygenClient {
+		if err := keygenClientOf(g, &out); err != nil {
+			base.Fatalf("%s", err)
+		}
+	}
+
+	switch *keygenFormat {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			base.Fatalf("failed to marshal output: %s", err)
+		}
+	case "env":
+		fmt.Print(out.env(prefix))
+	default:
+		base.Fatalf("unknown format: %s", *keygenFormat)
+	}
+}
+
+// keygenClientOf sets the user ID, the outbound and the share link of a client of the server with the keys of out.
+func keygenClientOf(g *keygen.Generator, out *keygenOutput) error {
+	if *keygenAddress == "" || *keygenPort == 0 || *keygenPort > 65535 {
+		return fmt.Errorf("-client requires -address and a valid -port")
+	}
+	id, err := g.UUID()
+	if err != nil {
+		return err
+	}
+	user := map[string]interface{}{"id": id, "encryption": "none", "flow": "xtls-rprx-vision"}
+	stream := map[string]interface{}{"network": "raw"}
+	switch *keygenType {
+	case "reality":
+		if *keygenSNI == "" {
+			return fmt.Errorf("-client of REALITY requires -sni")
+		}
+		reality := map[string]interface{}{
+			"serverName":  *keygenSNI,
+			"fingerprint": "chrome",
+			"publicKey":   out.Password,
+		}
+		if len(out.ShortIds) > 0 {
+			reality["shortId"] = out.ShortIds[0]
+		}
+		stream["security"] = "reality"
+		stream["realitySettings"] = reality
+	case "vlessenc":
+		user["encryption"] = out.Encryption
+	default:
+		return fmt.Errorf("-client is for REALITY and VLESS Encryption only")
+	}
+	outbound, err := json.Marshal(map[string]interface{}{
+		"protocol": "vless",
+		"tag":      "proxy",
+		"settings": map[string]interface{}{
+			"vnext": []interface{}{map[string]interface{}{
+				"address": *keygenAddress,
+				"port":    *keygenPort,
+				"users":   []interface{}{user},
+			}},
+		},
+		"streamSettings": stream,
+	})
+	if err != nil {
+		return err
+	}
+
+	var ob conf.OutboundDetourConfig
+	if err := json.Unmarshal(outbound, &ob); err != nil {
+		return err
+	}
+	link, err := sharelink.Format(&sharelink.Link{Outbound: &ob})
+	if err != nil {
+		return err
+	}
+	out.ID, out.Outbound, out.Link = id, outbound, link
+	return nil
+}
+
+// env returns the fields of o as the variables of an env file, where the keys are prefixed with prefix.
+func (o *keygenOutput) env(prefix string) string {
+	var b strings.Builder
+	set := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s='%s'\n", name, value)
+		}
+	}
+	set(prefix+"PRIVATE_KEY", o.PrivateKey)
+	set(prefix+"PASSWORD", o.Password)
+	set(prefix+"PUBLIC_KEY", o.PublicKey)
+	set(prefix+"SHORT_IDS", strings.Join(o.ShortIds, ","))
+	set(prefix+"DECRYPTION", o.Decryption)
+	set(prefix+"ENCRYPTION", o.Encryption)
+	set("CLIENT_ID", o.ID)
+	set("CLIENT_OUTBOUND", string(o.Outbound))
+	set("CLIENT_LINK", o.Link)
+	return b.String()
+}
@@ -1,13 +1,13 @@
 package all
 
 import (
-	"crypto/mlkem"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/keygen"
 	"github.com/xtls/xray-core/main/commands/base"
-	"lukechampine.com/blake3"
 )
 
 var cmdMLKEM768 = &base.Command{
@@ -48,13 +48,11 @@ func executeMLKEM768(cmd *base.Command, args []string) {
 }
 
 func genMLKEM768(inputSeed *[64]byte) (seed [64]byte, client []byte, hash32 [32]byte) {
+	var key *keygen.MLKEM768Key
 	if inputSeed == nil {
-		rand.Read(seed[:])
+		key = common.Must2(keygen.New().MLKEM768())
 	} else {
-		seed = *inputSeed
+		key = common.Must2(keygen.NewMLKEM768Key(*inputSeed))
 	}
-	key, _ := mlkem.NewDecapsulationKey768(seed[:])
-	client = key.EncapsulationKey().Bytes()
-	hash32 = blake3.Sum256(client)
-	return
+	return key.Seed, key.Client, key.Hash32()
 }
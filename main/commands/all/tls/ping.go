@@ -1,12 +1,15 @@
 package tls
 
 import (
+	"context"
 	gotls "crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/main/commands/base"
 	. "github.com/xtls/xray-core/transport/internet/tls"
@@ -14,7 +17,7 @@ import (
 
 // cmdPing is the tls ping command
 var cmdPing = &base.Command{
-	UsageLine: "{{.Exec}} tls ping [-ip <ip>] <domain>",
+	UsageLine: "{{.Exec}} tls ping [-ip <ip>] [-ech <dns>] [-h3] [-fingerprint <name>] <domain>",
 	Short:     "Ping the domain with TLS handshake",
 	Long: `
 Ping the domain with TLS handshake.
@@ -23,6 +26,19 @@ Arguments:
 
 	-ip
 		The IP address of the domain.
+
+	-ech
+		Fetch the ECH config of the domain from the DNS server, like
+		"https://1.1.1.1/dns-query" or "udp://1.1.1.1", and ping with it.
+		The format "example.com+https://1.1.1.1/dns-query" queries the
+		config of another domain.
+
+	-h3
+		Ping over QUIC with h3 as well.
+
+	-fingerprint
+		Handshake with the uTLS fingerprint, like "chrome", so that the
+		result reflects what the proxy sends. It does not apply to QUIC.
 `,
 }
 
@@ -30,7 +46,12 @@ func init() {
 	cmdPing.Run = executePing // break init loop
 }
 
-var pingIPStr = cmdPing.Flag.String("ip", "", "")
+var (
+	pingIPStr       = cmdPing.Flag.String("ip", "", "")
+	pingECH         = cmdPing.Flag.String("ech", "", "")
+	pingH3          = cmdPing.Flag.Bool("h3", false, "")
+	pingFingerprint = cmdPing.Flag.String("fingerprint", "", "")
+)
 
 func executePing(cmd *base.Command, args []string) {
 	if cmdPing.Flag.NArg() < 1 {
@@ -61,64 +82,87 @@ func executePing(cmd *base.Command, args []string) {
 		}
 		ip = v.IP
 	}
-	fmt.Println("Using IP: ", ip.String()+":"+strconv.Itoa(TargetPort))
-
-	fmt.Println("-------------------")
-	fmt.Println("Pinging without SNI")
-	{
-		tcpConn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: ip, Port: TargetPort})
-		if err != nil {
-			base.Fatalf("Failed to dial tcp: %s", err)
+	address := net.JoinHostPort(ip.String(), strconv.Itoa(TargetPort))
+	fmt.Println("Using IP: ", address)
+
+	var echConfigList []byte
+	if len(*pingECH) > 0 {
+		nameToQuery, server := domain, *pingECH
+		if parts := strings.SplitN(server, "+", 2); len(parts) == 2 {
+			nameToQuery, server = parts[0], parts[1]
 		}
-		tlsConn := gotls.Client(tcpConn, &gotls.Config{
-			InsecureSkipVerify: true,
-			NextProtos:         []string{"h2", "http/1.1"},
-			MaxVersion:         gotls.VersionTLS13,
-			MinVersion:         gotls.VersionTLS12,
-			// Do not release tool before v5's refactor
-			// VerifyPeerCertificate: showCert(),
-		})
-		err = tlsConn.Handshake()
+		echConfigList, err = QueryRecord(nameToQuery, server, "full", nil)
 		if err != nil {
-			fmt.Println("Handshake failure: ", err)
-		} else {
-			fmt.Println("Handshake succeeded")
-			printTLSConnDetail(tlsConn)
-			printCertificates(tlsConn.ConnectionState().PeerCertificates)
+			base.Fatalf("Failed to query ECH config: %s", err)
 		}
-		tlsConn.Close()
+		fmt.Println("Using ECH config of ", nameToQuery, ": ", base64.StdEncoding.EncodeToString(echConfigList))
+	}
+	if len(*pingFingerprint) > 0 {
+		fmt.Println("Using fingerprint: ", *pingFingerprint)
 	}
 
-	fmt.Println("-------------------")
-	fmt.Println("Pinging with SNI")
-	{
-		tcpConn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: ip, Port: TargetPort})
-		if err != nil {
-			base.Fatalf("Failed to dial tcp: %s", err)
+	networks := []bool{false}
+	if *pingH3 {
+		networks = append(networks, true)
+	}
+	for _, quic := range networks {
+		network := "TCP"
+		if quic {
+			network = "QUIC"
 		}
-		tlsConn := gotls.Client(tcpConn, &gotls.Config{
-			ServerName: domain,
-			NextProtos: []string{"h2", "http/1.1"},
-			MaxVersion: gotls.VersionTLS13,
-			MinVersion: gotls.VersionTLS12,
-			// Do not release tool before v5's refactor
-			// VerifyPeerCertificate: showCert(),
+
+		fmt.Println("-------------------")
+		fmt.Println("Pinging without SNI over", network)
+		ping(&PingConfig{
+			Address:            address,
+			Fingerprint:        *pingFingerprint,
+			QUIC:               quic,
+			InsecureSkipVerify: true,
 		})
-		err = tlsConn.Handshake()
-		if err != nil {
-			fmt.Println("Handshake failure: ", err)
-		} else {
-			fmt.Println("Handshake succeeded")
-			printTLSConnDetail(tlsConn)
-			printCertificates(tlsConn.ConnectionState().PeerCertificates)
+
+		fmt.Println("-------------------")
+		fmt.Println("Pinging with SNI over", network)
+		ping(&PingConfig{
+			Address:     address,
+			ServerName:  domain,
+			Fingerprint: *pingFingerprint,
+			QUIC:        quic,
+		})
+
+		if len(echConfigList) > 0 {
+			fmt.Println("-------------------")
+			fmt.Println("Pinging with ECH over", network)
+			ping(&PingConfig{
+				Address:       address,
+				ServerName:    domain,
+				Fingerprint:   *pingFingerprint,
+				ECHConfigList: echConfigList,
+				QUIC:          quic,
+			})
 		}
-		tlsConn.Close()
 	}
 
 	fmt.Println("-------------------")
 	fmt.Println("TLS ping finished")
 }
 
+func ping(config *PingConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := Ping(ctx, config)
+	if err != nil {
+		fmt.Println("Handshake failure: ", err)
+		return
+	}
+	fmt.Println("Handshake succeeded")
+	if result.Connect > 0 {
+		fmt.Println("Connect latency: ", result.Connect)
+	}
+	fmt.Println("Handshake latency: ", result.Handshake)
+	printTLSConnDetail(result, config.Fingerprint != "" && !config.QUIC)
+	printCertificates(result.PeerCertificates)
+}
+
 func printCertificates(certs []*x509.Certificate) {
 	var leaf *x509.Certificate
 	var length int
@@ -134,19 +178,28 @@ func printCertificates(certs []*x509.Certificate) {
 		fmt.Println("Cert's publicKey algorithm: ", leaf.PublicKeyAlgorithm.String())
 		fmt.Println("Cert's allowed domains: ", leaf.DNSNames)
 	}
+	for i, cert := range certs {
+		fmt.Println("Cert", i, "subject: ", cert.Subject.String())
+		fmt.Println("Cert", i, "SHA-256 fingerprint: ", CertificateFingerprint(cert))
+		fmt.Println("Cert", i, "validity: ", cert.NotBefore.Format(time.RFC3339), "-", cert.NotAfter.Format(time.RFC3339))
+	}
 }
 
-func printTLSConnDetail(tlsConn *gotls.Conn) {
-	connectionState := tlsConn.ConnectionState()
+func printTLSConnDetail(result *PingResult, utls bool) {
 	var tlsVersion string
-	if connectionState.Version == gotls.VersionTLS13 {
+	if result.Version == gotls.VersionTLS13 {
 		tlsVersion = "TLS 1.3"
-	} else if connectionState.Version == gotls.VersionTLS12 {
+	} else if result.Version == gotls.VersionTLS12 {
 		tlsVersion = "TLS 1.2"
 	}
 	fmt.Println("TLS Version: ", tlsVersion)
-	curveID := connectionState.CurveID
-	if curveID != 0 {
+	fmt.Println("TLS Cipher suite: ", gotls.CipherSuiteName(result.CipherSuite))
+	fmt.Println("TLS ALPN: ", result.NegotiatedProtocol)
+	fmt.Println("TLS ECH accepted: ", result.ECHAccepted)
+	curveID := result.CurveID
+	if utls {
+		fmt.Println("TLS Post-Quantum key exchange:  unknown (uTLS)")
+	} else if curveID != 0 {
 		PostQuantum := (curveID == gotls.X25519MLKEM768)
 		fmt.Println("TLS Post-Quantum key exchange: ", PostQuantum, "("+curveID.String()+")")
 	} else {
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/xtls/xray-core/common/buf"
@@ -27,7 +28,7 @@ func ConfigLoader(arg string) (out io.Reader, err error) {
 		data, err = FetchHTTPContent(arg)
 
 	case arg == "stdin:":
-		data, err = io.ReadAll(os.Stdin)
+		data, err = readStdin()
 
 	default:
 		data, err = os.ReadFile(arg)
@@ -40,6 +41,20 @@ func ConfigLoader(arg string) (out io.Reader, err error) {
 	return
 }
 
+// stdin is the config read from stdin, which is loaded more than once by run -test.
+var stdin struct {
+	sync.Once
+	data []byte
+	err  error
+}
+
+func readStdin() ([]byte, error) {
+	stdin.Do(func() {
+		stdin.data, stdin.err = io.ReadAll(os.Stdin)
+	})
+	return stdin.data, stdin.err
+}
+
 func FetchHTTPContent(target string) ([]byte, error) {
 	parsedTarget, err := url.Parse(target)
 	if err != nil {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -33,11 +34,38 @@ Xray. Multiple assign is accepted.
 
 The -confdir=dir flag sets a dir with multiple json config
 
+The -merge=deep flag deep-merges multiple config files: objects
+are merged key by key, inbounds, outbounds, routing rules and
+balancers are merged by tag (ruleTag for rules), and an item
+with "_del": true deletes the one with the same tag. Default
+"override", where a later file replaces whole sections.
+
 The -format=json flag sets the format of config files. 
 Default "auto".
 
+The -noenv flag turns off the substitution of ${NAME} and
+${NAME:-default} with environment variables in the string
+values of config files. Use $${ for a literal ${ otherwise.
+
+The -watch flag tells Xray to watch the config files, and to
+apply their changes at runtime once they are validated like
+with -test. The current config is kept if any step fails, and
+changes that can't be applied at runtime need a restart.
+
 The -test flag tells Xray to test config files only, 
-without launching the server.
+without launching the server. Besides building the config, it
+loads the certificates and keys, checks REALITY and WireGuard
+keys and the geo data files referenced, and reports all the
+problems found with their JSON paths.
+
+The -strict flag tells Xray to fail on the unknown fields in
+config files, like typos of field names, which are ignored
+otherwise. They are reported with their JSON paths and the
+fields they are likely typos of. On by default with -test,
+where -strict=false turns it off.
+
+The -expiry=duration flag sets how long before expiry -test
+warns about a certificate. Default "168h".
 
 The -dump flag tells Xray to print the merged config.
 	`,
@@ -54,6 +82,11 @@ var (
 	dump        = cmdRun.Flag.Bool("dump", false, "Dump merged config only, without launching Xray server.")
 	test        = cmdRun.Flag.Bool("test", false, "Test config file only, without launching Xray server.")
 	format      = cmdRun.Flag.String("format", "auto", "Format of input file.")
+	merge       = cmdRun.Flag.String("merge", "override", "Strategy to merge multiple config files, override or deep.")
+	noenv       = cmdRun.Flag.Bool("noenv", false, "Do not substitute environment variables in config files.")
+	strict      = cmdRun.Flag.Bool("strict", false, "Fail on unknown fields in config files, on by default with -test.")
+	watch       = cmdRun.Flag.Bool("watch", false, "Reload the config when the config files change.")
+	certExpiry  = cmdRun.Flag.Duration("expiry", 7*24*time.Hour, "Warn about certificates expiring within the duration in -test.")
 
 	/* We have to do this here because Golang's Test will also need to parse flag, before
 	 * main func in this file is run.
@@ -68,6 +101,12 @@ var (
 )
 
 func executeRun(cmd *base.Command, args []string) {
+	core.ConfigMergeStrategy = *merge
+	core.ConfigNoEnv = *noenv
+	core.ConfigStrict = *strict
+	if *test && !flagSet(&cmd.Flag, "strict") {
+		core.ConfigStrict = true
+	}
 	if *dump {
 		clog.ReplaceWithSeverityLogger(clog.Severity_Warning)
 		errCode := dumpConfig()
@@ -75,24 +114,27 @@ func executeRun(cmd *base.Command, args []string) {
 	}
 
 	printVersion()
-	server, err := startXray()
+	if *test {
+		os.Exit(testConfig())
+	}
+
+	server, files, err := startXray()
 	if err != nil {
 		fmt.Println("Failed to start:", err)
 		// Configuration error. Exit with a special value to prevent systemd from restarting.
 		os.Exit(23)
 	}
 
-	if *test {
-		fmt.Println("Configuration OK.")
-		os.Exit(0)
-	}
-
 	if err := server.Start(); err != nil {
 		fmt.Println("Failed to start:", err)
 		os.Exit(-1)
 	}
 	defer server.Close()
 
+	if *watch {
+		go watchConfig(server, files)
+	}
+
 	/*
 		conf.FileCache = nil
 		conf.IPCache = nil
@@ -122,6 +164,16 @@ func dumpConfig() int {
 	return 0
 }
 
+func flagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func fileExists(file string) bool {
 	info, err := os.Stat(file)
 	return err == nil && !info.IsDir()
@@ -215,9 +267,16 @@ func getConfigFormat() string {
 	return f
 }
 
-func startXray() (core.Server, error) {
+func startXray() (core.Server, cmdarg.Arg, error) {
 	configFiles := getConfigFilePath(true)
+	server, err := newServer(configFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	return server, configFiles, nil
+}
 
+func newServer(configFiles cmdarg.Arg) (core.Server, error) {
 	// config, err := core.LoadConfig(getConfigFormat(), configFiles[0], configFiles)
 
 	c, err := core.LoadConfig(getConfigFormat(), configFiles)
@@ -232,3 +291,34 @@ func startXray() (core.Server, error) {
 
 	return server, nil
 }
+
+// testConfig checks and builds the config files, printing all the problems found,
+// and returns the exit code.
+func testConfig() int {
+	configFiles := getConfigFilePath(true)
+	failed := false
+	problems, err := core.CheckConfig(getConfigFormat(), configFiles, *certExpiry)
+	if err != nil {
+		fmt.Println("Failed to check:", err)
+		return 23
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+		if !problem.Warning {
+			failed = true
+		}
+	}
+
+	// the unknown fields are reported above, so that the config is built for the other problems
+	core.ConfigStrict = false
+	if _, err := newServer(configFiles); err != nil {
+		fmt.Println("Failed to start:", err)
+		failed = true
+	}
+	if failed {
+		// Configuration error. Exit with a special value to prevent systemd from restarting.
+		return 23
+	}
+	fmt.Println("Configuration OK.")
+	return 0
+}
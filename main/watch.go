@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	applog "github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/app/reload"
+	"github.com/xtls/xray-core/common/cmdarg"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	watchInterval = time.Second
+	// The files are reloaded once they have not changed for a while, as editors and deploy tools often write them in several steps.
+	watchDebounce = 2 * time.Second
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statConfigFiles(files []string) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			states[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
+
+func sameFileStates(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, state := range a {
+		if b[file] != state {
+			return false
+		}
+	}
+	return true
+}
+
+// watchConfig reloads the config files whenever they change, until the process exits.
+func watchConfig(server core.Server, configFiles cmdarg.Arg) {
+	instance, ok := server.(*core.Instance)
+	if !ok {
+		errors.LogWarning(context.Background(), "config watch is not supported by this server")
+		return
+	}
+	var files []string
+	for _, file := range configFiles {
+		if file == "stdin:" || strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		errors.LogWarning(context.Background(), "no local config file to watch")
+		return
+	}
+
+	reloader := reload.NewReloadServer(instance)
+	states := statConfigFiles(files)
+	var changed time.Time
+	for range time.Tick(watchInterval) {
+		if current := statConfigFiles(files); !sameFileStates(states, current) {
+			states = current
+			changed = time.Now()
+			continue
+		}
+		if changed.IsZero() || time.Since(changed) < watchDebounce {
+			continue
+		}
+		changed = time.Time{}
+		if err := reloadConfig(instance, reloader, configFiles); err != nil {
+			errors.LogWarningInner(context.Background(), err, "config files changed, but the current config is kept")
+		}
+	}
+}
+
+// reloadConfig loads and validates the config files like -test does, then applies them to the running instance.
+func reloadConfig(instance *core.Instance, reloader reload.ReloadServiceServer, files cmdarg.Arg) error {
+	c, err := core.LoadConfig(getConfigFormat(), files)
+	if err != nil {
+		return errors.New("failed to load config files: [", files.String(), "]").Base(err)
+	}
+	if err := validateConfig(c); err != nil {
+		return err
+	}
+
+	resp, err := reloader.ReloadConfig(context.Background(), &reload.ReloadConfigRequest{Config: c})
+	if err != nil {
+		return err
+	}
+	errors.LogWarning(context.Background(), "config files reloaded: ", reload.Summary(resp.Changes))
+	return nil
+}
+
+// validateConfig creates an instance of the config without starting it.
+// The log settings are left out, as the logger of a new instance replaces the running one.
+func validateConfig(c *core.Config) error {
+	c = proto.Clone(c).(*core.Config)
+	logType := serial.GetMessageType((*applog.Config)(nil))
+	apps := c.App[:0]
+	for _, app := range c.App {
+		if app.Type != logType {
+			apps = append(apps, app)
+		}
+	}
+	c.App = apps
+
+	server, err := core.New(c)
+	if err != nil {
+		return errors.New("failed to create server").Base(err)
+	}
+	return server.Close()
+}
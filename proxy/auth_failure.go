@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	// authFailuresPerSource is the number of events of a source in an authFailureWindow, the others are dropped.
+	authFailuresPerSource = 5
+	authFailureWindow     = 10 * time.Second
+	// maxAuthFailureSources bounds the sources counted in a window, new sources are dropped beyond it.
+	maxAuthFailureSources = 4096
+)
+
+// AuthFailures reports the clients of an inbound failing its authentication. Each inbound has its own,
+// so that the failures at one inbound do not use up the events of the others. The zero value is ready to use.
+type AuthFailures struct {
+	sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+// allow returns false if the source has had too many events recently.
+func (f *AuthFailures) allow(source string, now time.Time) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.counts == nil || now.Sub(f.windowStart) >= authFailureWindow {
+		f.windowStart = now
+		f.counts = make(map[string]int)
+	}
+	count, found := f.counts[source]
+	if !found && len(f.counts) >= maxAuthFailureSources {
+		return false
+	}
+	if count >= authFailuresPerSource {
+		return false
+	}
+	f.counts[source] = count + 1
+	return true
+}
+
+// Record reports a client of the inbound in ctx failing the authentication of protocol,
+// to the observer of the inbound, and to the auth failure log and the subscribers of the stats manager.
+// The events of each source are rate limited, so that a flood of failures cannot flood the last two.
+func (f *AuthFailures) Record(ctx context.Context, protocol string, reason interface{}) {
+	msg := &log.AuthFailureMessage{
+		Time:     time.Now(),
+		Protocol: protocol,
+		Reason:   serial.ToString(reason),
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		msg.InboundTag = inbound.Tag
+		if inbound.Source.IsValid() {
+			msg.Source = inbound.Source.Address.String()
+			if observer := session.AuthFailureObserverFromContext(ctx); observer != nil {
+				observer.ObserveAuthFailure(inbound.Source.Address)
+			}
+		}
+	}
+	if !f.allow(msg.Source, msg.Time) {
+		return
+	}
+
+	log.Record(msg)
+	if instance := core.FromContext(ctx); instance != nil {
+		if feed, ok := instance.GetFeature(stats.ManagerType()).(stats.AuthFailureFeed); ok {
+			feed.PublishAuthFailure(msg)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthFailuresPerInbound(t *testing.T) {
+	var a, b AuthFailures
+	now := time.Now()
+	for i := 0; i < authFailuresPerSource; i++ {
+		if !a.allow("192.0.2.1", now) {
+			t.Fatal("expected event ", i, " of the source to be allowed")
+		}
+	}
+	if a.allow("192.0.2.1", now) {
+		t.Error("expected the events of the source beyond the limit to be dropped")
+	}
+	// the failures at one inbound do not use up the events of the others
+	if !b.allow("192.0.2.1", now) {
+		t.Error("expected the event of the source at another inbound to be allowed")
+	}
+	if !a.allow("192.0.2.1", now.Add(authFailureWindow)) {
+		t.Error("expected the events of the source to be allowed again in the next window")
+	}
+}
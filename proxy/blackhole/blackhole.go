@@ -6,14 +6,19 @@ import (
 	"time"
 
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
 // Handler is an outbound connection that silently swallow the entire payload.
 type Handler struct {
-	response ResponseConfig
+	response      ResponseConfig
+	udpEmptyReply bool
 }
 
 // New creates a new blackhole handler.
@@ -23,7 +28,8 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		return nil, err
 	}
 	return &Handler{
-		response: response,
+		response:      response,
+		udpEmptyReply: config.UdpEmptyReply,
 	}, nil
 }
 
@@ -33,15 +39,90 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	ob := outbounds[len(outbounds)-1]
 	ob.Name = "blackhole"
 
-	nBytes := h.response.WriteTo(link.Writer)
-	if nBytes > 0 {
-		// Sleep a little here to make sure the response is sent to client.
-		time.Sleep(time.Second)
+	if h.udpEmptyReply && ob.Target.Network == net.Network_UDP {
+		// packets must be written to the inbound as they are, without any framing
+		if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Conn != nil && inbound.CanSpliceCopy == 1 {
+			replyEmpty(ctx, link, inbound.Conn)
+			common.Interrupt(link.Writer)
+			return nil
+		}
+		errors.LogDebug(ctx, "empty UDP replies need an inbound without framing, dropping the packets instead")
+	}
+
+	switch response := h.response.(type) {
+	case *ResetResponse:
+		if conn := plainInboundConn(ctx); conn != nil {
+			reset(conn)
+		}
+	case *DropLingerResponse:
+		linger(ctx, link, time.Duration(response.Linger)*time.Second)
+	default:
+		nBytes := h.response.WriteTo(link.Writer)
+		if nBytes > 0 {
+			// Sleep a little here to make sure the response is sent to client.
+			time.Sleep(time.Second)
+		}
 	}
 	common.Interrupt(link.Writer)
 	return nil
 }
 
+// plainInboundConn returns the inbound connection, if it carries nothing but this request.
+func plainInboundConn(ctx context.Context) stat.Connection {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.Conn == nil || inbound.CanSpliceCopy == 3 {
+		return nil
+	}
+	return inbound.Conn
+}
+
+// reset closes conn, with an RST if it is a TCP connection, so that the client fails at once.
+func reset(conn stat.Connection) {
+	raw := conn
+	if counter, ok := raw.(*stat.CounterConnection); ok {
+		raw = counter.Connection
+	}
+	if tcpConn, ok := raw.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// linger reads and discards the requests for d, or until the client gives up.
+func linger(ctx context.Context, link *transport.Link, d time.Duration) {
+	drained := make(chan struct{})
+	go func() {
+		buf.Copy(link.Reader, buf.Discard)
+		close(drained)
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-drained:
+	case <-ctx.Done():
+	}
+	common.Interrupt(link.Reader)
+}
+
+// replyEmpty answers each UDP packet with an empty one, written to the inbound connection directly,
+// because links never carry empty packets.
+func replyEmpty(ctx context.Context, link *transport.Link, conn stat.Connection) {
+	for ctx.Err() == nil {
+		mb, err := link.Reader.ReadMultiBuffer()
+		for range mb {
+			if _, err := conn.Write(nil); err != nil {
+				buf.ReleaseMulti(mb)
+				return
+			}
+		}
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			return
+		}
+	}
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		return New(ctx, config.(*Config))
@@ -2,10 +2,13 @@ package blackhole_test
 
 import (
 	"context"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/proxy/blackhole"
@@ -40,3 +43,96 @@ func TestBlackholeHTTPResponse(t *testing.T) {
 		t.Error("expect http response, but nothing")
 	}
 }
+
+func TestBlackholeReset(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer client.Close()
+	server, err := listener.Accept()
+	common.Must(err)
+
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{}})
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{Conn: server, CanSpliceCopy: 1})
+	handler, err := blackhole.New(ctx, &blackhole.Config{
+		Response: serial.ToTypedMessage(&blackhole.ResetResponse{}),
+	})
+	common.Must(err)
+
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	common.Must(handler.Process(ctx, &transport.Link{Reader: reader, Writer: writer}, nil))
+
+	// an RST fails the read, where a FIN would end it with EOF
+	b := make([]byte, 16)
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := client.Read(b)
+	if n != 0 || err == nil || err == io.EOF {
+		t.Error("expect connection reset, but got ", n, " bytes, ", err)
+	}
+}
+
+func TestBlackholeDropLinger(t *testing.T) {
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{}})
+	handler, err := blackhole.New(ctx, &blackhole.Config{
+		Response: serial.ToTypedMessage(&blackhole.DropLingerResponse{Linger: 1}),
+	})
+	common.Must(err)
+
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithSizeLimit(1024))
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+
+	// requests are swallowed, even beyond the size of the pipe
+	go func() {
+		for i := 0; i < 16; i++ {
+			if uplinkWriter.WriteMultiBuffer(buf.MergeBytes(nil, make([]byte, 1024))) != nil {
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	common.Must(handler.Process(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter}, nil))
+	if d := time.Since(start); d < time.Second {
+		t.Error("connection is closed after ", d)
+	}
+	mb, err := downlinkReader.ReadMultiBuffer()
+	if !mb.IsEmpty() || err == nil {
+		t.Error("expect nothing, but got ", mb.Len(), " bytes, ", err)
+	}
+}
+
+func TestBlackholeUDPEmptyReply(t *testing.T) {
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	common.Must(err)
+	defer client.Close()
+	server, err := net.DialUDP("udp", nil, client.LocalAddr().(*net.UDPAddr))
+	common.Must(err)
+	defer server.Close()
+
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Target: net.UDPDestination(net.LocalHostIP, 53),
+	}})
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{Conn: server, CanSpliceCopy: 1})
+	handler, err := blackhole.New(ctx, &blackhole.Config{
+		UdpEmptyReply: true,
+	})
+	common.Must(err)
+
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("query1")), buf.FromBytes([]byte("query2"))}))
+	common.Must(writer.Close())
+	common.Must(handler.Process(ctx, &transport.Link{Reader: reader, Writer: writer}, nil))
+
+	b := make([]byte, 16)
+	for i := 0; i < 2; i++ {
+		client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := client.ReadFrom(b)
+		common.Must(err)
+		if n != 0 {
+			t.Error("expect an empty packet, but got ", b[:n])
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package blackhole
 
 import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 )
@@ -13,6 +18,8 @@ Content-Length: 0
 
 
 `
+	// alertHandshakeFailure is the TLS alert sent if none is configured.
+	alertHandshakeFailure = 40
 )
 
 // ResponseConfig is the configuration for blackhole responses.
@@ -25,14 +32,68 @@ type ResponseConfig interface {
 func (*NoneResponse) WriteTo(buf.Writer) int32 { return 0 }
 
 // WriteTo implements ResponseConfig.WriteTo().
-func (*HTTPResponse) WriteTo(writer buf.Writer) int32 {
+func (r *HTTPResponse) WriteTo(writer buf.Writer) int32 {
+	if r.GetStatus() == 0 && r.GetBody() == "" && len(r.GetHeader()) == 0 {
+		b := buf.New()
+		common.Must2(b.WriteString(http403response))
+		n := b.Len()
+		writer.WriteMultiBuffer(buf.MultiBuffer{b})
+		return n
+	}
+	mb := buf.MergeBytes(nil, []byte(r.response()))
+	n := mb.Len()
+	writer.WriteMultiBuffer(mb)
+	return n
+}
+
+// response returns the configured HTTP response. The connection is closed after it,
+// so Connection and Content-Length are always set by the response itself.
+func (r *HTTPResponse) response() string {
+	status := int(r.GetStatus())
+	if status == 0 {
+		status = http.StatusForbidden
+	}
+	keys := make([]string, 0, len(r.GetHeader()))
+	for key := range r.GetHeader() {
+		switch strings.ToLower(key) {
+		case "connection", "content-length":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	sb.WriteString("Connection: close\r\n")
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "%s: %s\r\n", key, r.GetHeader()[key])
+	}
+	fmt.Fprintf(&sb, "Content-Length: %d\r\n\r\n", len(r.GetBody()))
+	sb.WriteString(r.GetBody())
+	return sb.String()
+}
+
+// WriteTo implements ResponseConfig.WriteTo(). The connection is reset by the handler instead.
+func (*ResetResponse) WriteTo(buf.Writer) int32 { return 0 }
+
+// WriteTo implements ResponseConfig.WriteTo().
+func (r *TLSAlertResponse) WriteTo(writer buf.Writer) int32 {
+	alert := byte(r.GetAlert())
+	if alert == 0 {
+		alert = alertHandshakeFailure
+	}
 	b := buf.New()
-	common.Must2(b.WriteString(http403response))
+	// alert record of TLS 1.2, with a fatal alert
+	common.Must2(b.Write([]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, alert}))
 	n := b.Len()
 	writer.WriteMultiBuffer(buf.MultiBuffer{b})
 	return n
 }
 
+// WriteTo implements ResponseConfig.WriteTo(). The connection is held by the handler instead.
+func (*DropLingerResponse) WriteTo(buf.Writer) int32 { return 0 }
+
 // GetInternalResponse converts response settings from proto to internal data structure.
 func (c *Config) GetInternalResponse() (ResponseConfig, error) {
 	if c.GetResponse() == nil {
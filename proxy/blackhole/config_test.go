@@ -2,6 +2,7 @@ package blackhole_test
 
 import (
 	"bufio"
+	"bytes"
 	"net/http"
 	"testing"
 
@@ -24,3 +25,45 @@ func TestHTTPResponse(t *testing.T) {
 		t.Error("expected status code 403, but got ", response.StatusCode)
 	}
 }
+
+func TestCustomHTTPResponse(t *testing.T) {
+	buffer := buf.New()
+
+	httpResponse := &HTTPResponse{
+		Status: 404,
+		Body:   "not here",
+		Header: map[string]string{
+			"Server":         "nginx",
+			"Content-Type":   "text/plain",
+			"Content-Length": "1000",
+		},
+	}
+	httpResponse.WriteTo(buf.NewWriter(buffer))
+
+	expected := "HTTP/1.1 404 Not Found\r\n" +
+		"Connection: close\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Server: nginx\r\n" +
+		"Content-Length: 8\r\n" +
+		"\r\n" +
+		"not here"
+	if r := buffer.String(); r != expected {
+		t.Error("unexpected response ", r)
+	}
+}
+
+func TestTLSAlertResponse(t *testing.T) {
+	for _, c := range []struct {
+		alert    uint32
+		expected []byte
+	}{
+		{0, []byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 40}},
+		{112, []byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 112}},
+	} {
+		buffer := buf.New()
+		(&TLSAlertResponse{Alert: c.alert}).WriteTo(buf.NewWriter(buffer))
+		if r := buffer.Bytes(); !bytes.Equal(r, c.expected) {
+			t.Error("unexpected alert ", r)
+		}
+	}
+}
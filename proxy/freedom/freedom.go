@@ -66,6 +66,20 @@ func (h *Handler) policy() policy.Session {
 	return p
 }
 
+// resolvesDomains returns whether the handler resolves the domains to dial itself.
+func (h *Handler) resolvesDomains() bool {
+	return h.config.DomainStrategy.HasStrategy() || len(h.config.ResolveVia) > 0
+}
+
+// domainStrategy returns the strategy to resolve the domains with, which is UseIP for resolveVia alone.
+// The domains that fail to resolve through resolveVia are not dialed, so that their queries do not leak elsewhere.
+func (h *Handler) domainStrategy() internet.DomainStrategy {
+	if !h.config.DomainStrategy.HasStrategy() {
+		return internet.DomainStrategy_USE_IP
+	}
+	return h.config.DomainStrategy
+}
+
 func isValidAddress(addr *net.IPOrDomain) bool {
 	if addr == nil {
 		return false
@@ -112,15 +126,30 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	var conn stat.Connection
 	err := retry.ExponentialBackoff(5, 100).On(func() error {
 		dialDest := destination
-		if h.config.DomainStrategy.HasStrategy() && dialDest.Address.Family().IsDomain() {
-			strategy := h.config.DomainStrategy
+		if h.config.IpFamilyPreference.HasPreference() && dialDest.Address.Family().IsDomain() {
+			ips, err := internet.LookupWithPreferenceVia(ctx, h.config.ResolveVia, dialDest.Address.Domain(), h.config.IpFamilyPreference, outGateway)
+			if err != nil {
+				errors.LogInfoInner(ctx, err, "failed to get IP address for domain ", dialDest.Address.Domain())
+				if h.config.IpFamilyPreference.Only() || len(h.config.ResolveVia) > 0 {
+					return err
+				}
+			} else {
+				dialDest = net.Destination{
+					Network: dialDest.Network,
+					Address: net.IPAddress(internet.PickIP(ips)),
+					Port:    dialDest.Port,
+				}
+				errors.LogInfo(ctx, "dialing to ", dialDest)
+			}
+		} else if h.resolvesDomains() && dialDest.Address.Family().IsDomain() {
+			strategy := h.domainStrategy()
 			if destination.Network == net.Network_UDP && origTargetAddr != nil && outGateway == nil {
 				strategy = strategy.GetDynamicStrategy(origTargetAddr.Family())
 			}
-			ips, err := internet.LookupForIP(dialDest.Address.Domain(), strategy, outGateway)
+			ips, err := internet.LookupForIPVia(ctx, h.config.ResolveVia, dialDest.Address.Domain(), strategy, outGateway)
 			if err != nil {
 				errors.LogInfoInner(ctx, err, "failed to get IP address for domain ", dialDest.Address.Domain())
-				if h.config.DomainStrategy.ForceIP() {
+				if h.config.DomainStrategy.ForceIP() || len(h.config.ResolveVia) > 0 {
 					return err
 				}
 			} else {
@@ -165,13 +194,17 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	}
 
 	plcy := h.policy()
+	idleTimeout := plcy.Timeouts.ConnectionIdle
+	if destination.Network == net.Network_UDP {
+		idleTimeout = plcy.Timeouts.UDPIdle
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, func() {
 		cancel()
 		if newCancel != nil {
 			newCancel()
 		}
-	}, plcy.Timeouts.ConnectionIdle)
+	}, idleTimeout)
 
 	requestDone := func() error {
 		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
@@ -205,6 +238,10 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		if err := buf.Copy(input, writer, buf.UpdateActivity(timer)); err != nil {
 			return errors.New("failed to process request").Base(err)
 		}
+		if destination.Network == net.Network_TCP {
+			// the server may still respond to a half closed request
+			proxy.CloseWrite(conn, false)
+		}
 
 		return nil
 	}
@@ -372,11 +409,23 @@ func (w *PacketWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 					b.UDP.Address = ip
 				} else {
 					ShouldUseSystemResolver := true
-					if w.Handler.config.DomainStrategy.HasStrategy() {
-						ips, err := internet.LookupForIP(b.UDP.Address.Domain(), w.Handler.config.DomainStrategy, w.LocalAddr)
+					if w.Handler.config.IpFamilyPreference.HasPreference() {
+						ips, err := internet.LookupWithPreferenceVia(context.Background(), w.Handler.config.ResolveVia, b.UDP.Address.Domain(), w.Handler.config.IpFamilyPreference, w.LocalAddr)
+						if err != nil {
+							// drop packet if resolve failed when only one family is allowed
+							if w.Handler.config.IpFamilyPreference.Only() || len(w.Handler.config.ResolveVia) > 0 {
+								b.Release()
+								continue
+							}
+						} else {
+							ip = net.IPAddress(internet.PickIP(ips))
+							ShouldUseSystemResolver = false
+						}
+					} else if w.Handler.resolvesDomains() {
+						ips, err := internet.LookupForIPVia(context.Background(), w.Handler.config.ResolveVia, b.UDP.Address.Domain(), w.Handler.domainStrategy(), w.LocalAddr)
 						if err != nil {
 							// drop packet if resolve failed when forceIP
-							if w.Handler.config.DomainStrategy.ForceIP() {
+							if w.Handler.config.DomainStrategy.ForceIP() || len(w.Handler.config.ResolveVia) > 0 {
 								b.Release()
 								continue
 							}
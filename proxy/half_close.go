@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// CloseWrite closes the write side of conn, so its peer reads the end of the stream while it may still send.
+// If direct, the writes of Vision have switched to the raw connection under the security layer, whose write side is closed instead.
+// It returns false if conn is not able to, like the connections of the transports over HTTP.
+func CloseWrite(conn net.Conn, direct bool) bool {
+	if direct {
+		conn, _, _ = UnwrapRawConn(conn)
+	} else if statConn, ok := conn.(*stat.CounterConnection); ok {
+		conn = statConn.Connection
+	}
+	if pc, ok := conn.(*proxyproto.Conn); ok {
+		conn = pc.Raw()
+	}
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		return c.CloseWrite() == nil
+	}
+	return false
+}
+
+// HalfCloseWriter is the writer of a link to Conn. Closing it closes the write side of Conn,
+// instead of leaving Conn open until both directions end.
+type HalfCloseWriter struct {
+	buf.Writer
+	Conn net.Conn
+	// Direct returns whether the writes of Vision have switched to the raw connection.
+	Direct func() bool
+}
+
+// Close implements common.Closable.
+func (w *HalfCloseWriter) Close() error {
+	err := common.Close(w.Writer)
+	if err == nil {
+		CloseWrite(w.Conn, w.Direct != nil && w.Direct())
+	}
+	return err
+}
+
+// Interrupt implements common.Interruptible.
+func (w *HalfCloseWriter) Interrupt() {
+	common.Interrupt(w.Writer)
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+)
+
+// NewHeartbeatReader returns a reader of the uplink payload that calls heartbeat whenever nothing is read for interval,
+// so that the NATs and firewalls on the way do not drop a connection that is idle for long.
+// heartbeat writes an empty frame that the other side discards, or nothing if the stream can not carry one.
+// It is called in the goroutine that reads, between the writes of the payload, so its frames never split them.
+// It returns reader itself if interval is 0 or reader can not time out.
+func NewHeartbeatReader(reader buf.Reader, interval time.Duration, heartbeat func() error) buf.Reader {
+	timeoutReader, ok := reader.(buf.TimeoutReader)
+	if interval <= 0 || !ok {
+		return reader
+	}
+	return &heartbeatReader{
+		reader:    timeoutReader,
+		interval:  interval,
+		heartbeat: heartbeat,
+	}
+}
+
+type heartbeatReader struct {
+	reader    buf.TimeoutReader
+	interval  time.Duration
+	heartbeat func() error
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *heartbeatReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	for {
+		mb, err := r.reader.ReadMultiBufferTimeout(r.interval)
+		if err != buf.ErrReadTimeout {
+			return mb, err
+		}
+		if err := r.heartbeat(); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/mux"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/uuid"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+// copyWithHeartbeat copies random payloads, written to source with random pauses, to writer while heartbeats are sent
+// on idle. It returns the payloads and the number of heartbeats.
+func copyWithHeartbeat(rnd *rand.Rand, source func(buf.Writer) buf.Writer, writer buf.Writer, heartbeat func() error) ([]byte, int) {
+	pReader, pWriter := pipe.New(pipe.WithoutSizeLimit())
+	var payload []byte
+	go func() {
+		w := source(pWriter)
+		for i := 0; i < 16; i++ {
+			if rnd.Intn(2) == 0 {
+				time.Sleep(time.Duration(rnd.Intn(20)) * time.Millisecond)
+			}
+			b := make([]byte, rnd.Intn(3000)+1)
+			rnd.Read(b)
+			payload = append(payload, b...)
+			common.Must(w.WriteMultiBuffer(buf.MergeBytes(nil, b)))
+		}
+		common.Close(w)
+		common.Must(pWriter.Close())
+	}()
+
+	heartbeats := 0
+	reader := proxy.NewHeartbeatReader(pReader, 5*time.Millisecond, func() error {
+		heartbeats++
+		return heartbeat()
+	})
+	common.Must(buf.Copy(reader, writer))
+	return payload, heartbeats
+}
+
+func TestHeartbeatVision(t *testing.T) {
+	id := uuid.New()
+	for round := 0; round < 8; round++ {
+		rnd := rand.New(rand.NewSource(int64(round)))
+		var stream bytes.Buffer
+		visionWriter := proxy.NewVisionWriter(buf.NewWriter(&stream), proxy.NewTrafficState(id.Bytes()), true, context.Background(), nil, nil)
+		payload, _ := copyWithHeartbeat(rnd, func(w buf.Writer) buf.Writer { return w }, visionWriter, visionWriter.Heartbeat)
+
+		visionReader := proxy.NewVisionReader(buf.NewReader(&stream), proxy.NewTrafficState(id.Bytes()), true, context.Background(), nil, nil, nil, nil)
+		received, err := buf.ReadAllToBytes(&buf.BufferedReader{Reader: visionReader})
+		common.Must(err)
+		if !bytes.Equal(received, payload) {
+			t.Fatal("round ", round, ": the payload is corrupted")
+		}
+	}
+}
+
+func TestHeartbeatMux(t *testing.T) {
+	dest := net.TCPDestination(net.DomainAddress("example.com"), 80)
+	sent := 0
+	for round := 0; round < 8; round++ {
+		rnd := rand.New(rand.NewSource(int64(round)))
+		var stream bytes.Buffer
+		streamWriter := buf.NewWriter(&stream)
+		payload, heartbeats := copyWithHeartbeat(rnd, func(w buf.Writer) buf.Writer {
+			return mux.NewWriter(1, dest, w, protocol.TransferTypeStream, [8]byte{}, &session.Inbound{})
+		}, streamWriter, func() error { return mux.WriteKeepAlive(streamWriter) })
+		sent += heartbeats
+
+		var received []byte
+		keepAlives := 0
+		reader := &buf.BufferedReader{Reader: buf.NewReader(&stream)}
+		for {
+			var meta mux.FrameMetadata
+			if err := meta.Unmarshal(reader, false); err != nil {
+				if err != io.EOF {
+					t.Fatal(err)
+				}
+				break
+			}
+			if meta.SessionStatus == mux.SessionStatusKeepAlive {
+				keepAlives++
+			}
+			if meta.Option.Has(mux.OptionData) {
+				data, err := buf.ReadAllToBytes(&buf.BufferedReader{Reader: mux.NewStreamReader(reader)})
+				common.Must(err)
+				received = append(received, data...)
+			}
+		}
+		if !bytes.Equal(received, payload) {
+			t.Fatal("round ", round, ": the payload is corrupted")
+		}
+		if keepAlives != heartbeats {
+			t.Error("round ", round, ": ", heartbeats, " heartbeats, but ", keepAlives, " KeepAlive frames")
+		}
+	}
+	if sent == 0 {
+		t.Error("no heartbeats sent")
+	}
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/quic-go/quic-go/quicvarint"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const (
+	// capsuleTypeDatagram is the type of the DATAGRAM capsule of RFC 9297.
+	capsuleTypeDatagram = 0x00
+	// contextIDUDP is the context ID of RFC 9298 for datagrams that carry a UDP payload.
+	contextIDUDP = 0
+)
+
+// connectUDPPath returns the path of the default URI template of RFC 9298,
+// /.well-known/masque/udp/{target_host}/{target_port}/, for target.
+func connectUDPPath(target net.Destination) *url.URL {
+	host := target.Address.String()
+	if target.Address.Family().IsIP() {
+		host = target.Address.IP().String()
+	}
+	port := strconv.Itoa(int(target.Port))
+	// the colons of IPv6 addresses are percent-encoded
+	return &url.URL{
+		Path:    "/.well-known/masque/udp/" + host + "/" + port + "/",
+		RawPath: "/.well-known/masque/udp/" + strings.ReplaceAll(url.PathEscape(host), ":", "%3A") + "/" + port + "/",
+	}
+}
+
+// appendDatagramCapsule appends to b a DATAGRAM capsule that carries a UDP payload:
+// | type, varint | length, varint | context ID 0, varint | payload |
+func appendDatagramCapsule(b []byte, payload []byte) []byte {
+	b = quicvarint.Append(b, capsuleTypeDatagram)
+	b = quicvarint.Append(b, uint64(quicvarint.Len(contextIDUDP)+len(payload)))
+	b = quicvarint.Append(b, contextIDUDP)
+	return append(b, payload...)
+}
+
+// readDatagramCapsule reads capsules until a DATAGRAM capsule that carries a UDP payload, and returns the payload.
+// Capsules of other types or contexts are skipped as RFC 9297 requires, and so are datagrams too large for a buffer.
+func readDatagramCapsule(r *bufio.Reader) (*buf.Buffer, error) {
+	for {
+		capsuleType, err := quicvarint.Read(r)
+		if err != nil {
+			return nil, err
+		}
+		length, err := quicvarint.Read(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if capsuleType != capsuleTypeDatagram || length == 0 || length > buf.Size {
+			if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			continue
+		}
+		b := buf.New()
+		if _, err := b.ReadFullFrom(r, int32(length)); err != nil {
+			b.Release()
+			return nil, err
+		}
+		contextID, n, err := quicvarint.Parse(b.Bytes())
+		if err != nil || contextID != contextIDUDP {
+			b.Release()
+			continue
+		}
+		b.Advance(int32(n))
+		return b, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// capsuleWriter writes each buffer as a datagram of a connect-udp tunnel to target.
+// A tunnel carries the datagrams of its target only, so the buffers to other destinations, in cone mode, are dropped.
+type capsuleWriter struct {
+	w      io.Writer
+	target net.Destination
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *capsuleWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	defer buf.ReleaseMulti(mb)
+
+	var capsules []byte
+	for _, b := range mb {
+		if b.UDP != nil && b.UDP.NetAddr() != w.target.NetAddr() {
+			continue
+		}
+		capsules = appendDatagramCapsule(capsules, b.Bytes())
+	}
+	if len(capsules) == 0 {
+		return nil
+	}
+	_, err := w.w.Write(capsules)
+	return err
+}
+
+// capsuleReader reads the datagrams of a connect-udp tunnel to target, each into a buffer from target.
+type capsuleReader struct {
+	r      *bufio.Reader
+	target net.Destination
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *capsuleReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	b, err := readDatagramCapsule(r.r)
+	if err != nil {
+		return nil, err
+	}
+	target := r.target
+	b.UDP = &target
+	return buf.MultiBuffer{b}, nil
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestAppendDatagramCapsule(t *testing.T) {
+	for _, c := range []struct {
+		payload []byte
+		capsule string
+	}{
+		{[]byte{}, "000100"},
+		{[]byte("hello"), "00060068656c6c6f"},
+		// lengths over 63 take two bytes, as 0x7bbd is 15293 in RFC 9000
+		{bytes.Repeat([]byte{1}, 15292), "007bbd00" + hex.EncodeToString(bytes.Repeat([]byte{1}, 15292))},
+	} {
+		if r := cmp.Diff(hex.EncodeToString(appendDatagramCapsule(nil, c.payload)), c.capsule); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+func TestReadDatagramCapsule(t *testing.T) {
+	wire, err := hex.DecodeString("" +
+		// an unknown capsule, skipped
+		"17" + "03" + "616263" +
+		// a datagram of another context, skipped
+		"00" + "03" + "02" + "6162" +
+		// a datagram of UDP with lengths in 2 bytes
+		"4000" + "4006" + "00" + "68656c6c6f" +
+		// a datagram of UDP with the context ID in 4 bytes
+		"00" + "07" + "80000000" + "616263")
+	common.Must(err)
+	r := bufio.NewReader(bytes.NewReader(wire))
+
+	for _, expected := range []string{"hello", "abc"} {
+		b, err := readDatagramCapsule(r)
+		common.Must(err)
+		if r := cmp.Diff(string(b.Bytes()), expected); r != "" {
+			t.Error(r)
+		}
+		b.Release()
+	}
+	if _, err := readDatagramCapsule(r); err != io.EOF {
+		t.Error("expected EOF, but got ", err)
+	}
+
+	// a capsule cut short
+	r = bufio.NewReader(bytes.NewReader([]byte{0x00, 0x06, 0x00, 'h'}))
+	if _, err := readDatagramCapsule(r); err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF, but got ", err)
+	}
+}
+
+func TestConnectUDPPath(t *testing.T) {
+	for _, c := range []struct {
+		target net.Destination
+		path   string
+	}{
+		{net.UDPDestination(net.ParseAddress("192.0.2.6"), 443), "/.well-known/masque/udp/192.0.2.6/443/"},
+		{net.UDPDestination(net.ParseAddress("2001:db8::42"), 53), "/.well-known/masque/udp/2001%3Adb8%3A%3A42/53/"},
+		{net.UDPDestination(net.ParseAddress("example.com"), 3478), "/.well-known/masque/udp/example.com/3478/"},
+	} {
+		if r := cmp.Diff(connectUDPPath(c.target).EscapedPath(), c.path); r != "" {
+			t.Error(r)
+		}
+	}
+}
+
+func TestCapsuleTarget(t *testing.T) {
+	target := net.UDPDestination(net.ParseAddress("192.0.2.6"), 443)
+	other := net.UDPDestination(net.ParseAddress("192.0.2.7"), 443)
+
+	var tunnel bytes.Buffer
+	w := &capsuleWriter{w: &tunnel, target: target}
+	mb := buf.MergeBytes(nil, []byte("to target"))
+	mb[0].UDP = &target
+	b := buf.New()
+	b.WriteString("to other")
+	b.UDP = &other
+	common.Must(w.WriteMultiBuffer(append(mb, b)))
+	if r := cmp.Diff(hex.EncodeToString(appendDatagramCapsule(nil, []byte("to target"))), hex.EncodeToString(tunnel.Bytes())); r != "" {
+		t.Error(r)
+	}
+
+	r := &capsuleReader{r: bufio.NewReader(&tunnel), target: target}
+	mb, err := r.ReadMultiBuffer()
+	common.Must(err)
+	if mb[0].String() != "to target" || mb[0].UDP == nil || *mb[0].UDP != target {
+		t.Error("unexpected datagram ", mb[0].String(), " from ", mb[0].UDP)
+	}
+}
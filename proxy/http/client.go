@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"text/template"
 
@@ -23,6 +24,7 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/stats"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -34,18 +36,23 @@ type Client struct {
 	server        *protocol.ServerSpec
 	policyManager policy.Manager
 	header        []*Header
+	padding       *Padding
+	h2PoolSize    int
+	udpInConnect  bool
+
+	h2Access sync.Mutex
+	h2Conns  []*h2Conn
 }
 
+// h2Conn is an HTTP/2 connection to the server, shared by many tunnels.
 type h2Conn struct {
 	rawConn net.Conn
 	h2Conn  *http2.ClientConn
+	// counters of the outbound, which count the bytes of each tunnel instead of the shared connection
+	uplink   stats.Counter
+	downlink stats.Counter
 }
 
-var (
-	cachedH2Mutex sync.Mutex
-	cachedH2Conns map[net.Destination]h2Conn
-)
-
 // NewClient create a new http client based on the given config.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	if config.Server == nil {
@@ -56,11 +63,19 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 		return nil, errors.New("failed to get server spec").Base(err)
 	}
 
+	poolSize := int(config.H2PoolSize)
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+
 	v := core.MustFromContext(ctx)
 	return &Client{
 		server:        server,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 		header:        config.Header,
+		padding:       config.Padding,
+		h2PoolSize:    poolSize,
+		udpInConnect:  config.UdpInConnect,
 	}, nil
 }
 
@@ -76,43 +91,60 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	target := ob.Target
 	targetAddr := target.NetAddr()
 
-	if target.Network == net.Network_UDP {
-		return errors.New("UDP is not supported by HTTP outbound")
+	if target.Network == net.Network_UDP && !c.udpInConnect {
+		return errors.New("UDP is not supported by HTTP outbound, unless udpInConnect is enabled")
 	}
 
 	server := c.server
 	dest := server.Destination
 	user := server.User
 	var conn stat.Connection
+	var reader buf.Reader
+	var writer buf.Writer
 
-	mbuf, _ := link.Reader.ReadMultiBuffer()
-	len := mbuf.Len()
-	firstPayload := bytespool.Alloc(len)
-	mbuf, _ = buf.SplitBytes(mbuf, firstPayload)
-	firstPayload = firstPayload[:len]
-
-	buf.ReleaseMulti(mbuf)
-	defer bytespool.Free(firstPayload)
-
-	header, err := fillRequestHeader(ctx, c.header)
-	if err != nil {
-		return errors.New("failed to fill out header").Base(err)
-	}
+	if target.Network == net.Network_UDP {
+		ob.CanSpliceCopy = 3
+		header, err := fillRequestHeader(ctx, c.header)
+		if err != nil {
+			return errors.New("failed to fill out header").Base(err)
+		}
+		if err := retry.ExponentialBackoff(5, 100).On(func() error {
+			netConn, err := c.setUpUDPTunnel(ctx, dest, target, user, dialer, header)
+			if netConn != nil {
+				conn = stat.Connection(netConn)
+			}
+			return err
+		}); err != nil {
+			return errors.New("failed to open a connect-udp tunnel").Base(err)
+		}
+		reader = &capsuleReader{r: bufio.NewReader(conn), target: target}
+		writer = &capsuleWriter{w: conn, target: target}
+	} else {
+		mbuf, _ := link.Reader.ReadMultiBuffer()
+		len := mbuf.Len()
+		firstPayload := bytespool.Alloc(len)
+		mbuf, _ = buf.SplitBytes(mbuf, firstPayload)
+		firstPayload = firstPayload[:len]
+
+		buf.ReleaseMulti(mbuf)
+		defer bytespool.Free(firstPayload)
+
+		header, err := fillRequestHeader(ctx, c.header)
+		if err != nil {
+			return errors.New("failed to fill out header").Base(err)
+		}
 
-	if err := retry.ExponentialBackoff(5, 100).On(func() error {
-		netConn, err := setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, header, firstPayload)
-		if netConn != nil {
-			if _, ok := netConn.(*http2Conn); !ok {
-				if _, err := netConn.Write(firstPayload); err != nil {
-					netConn.Close()
-					return err
-				}
+		if err := retry.ExponentialBackoff(5, 100).On(func() error {
+			netConn, err := c.setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, header, firstPayload)
+			if netConn != nil {
+				conn = stat.Connection(netConn)
 			}
-			conn = stat.Connection(netConn)
+			return err
+		}); err != nil {
+			return errors.New("failed to find an available destination").Base(err)
 		}
-		return err
-	}); err != nil {
-		return errors.New("failed to find an available destination").Base(err)
+		reader = buf.NewReader(conn)
+		writer = buf.NewWriter(conn)
 	}
 
 	defer func() {
@@ -142,12 +174,14 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 
 	requestFunc := func() error {
 		defer timer.SetTimeout(p.Timeouts.DownlinkOnly)
-		return buf.Copy(link.Reader, buf.NewWriter(conn), buf.UpdateActivity(timer))
+		return buf.Copy(link.Reader, writer, buf.UpdateActivity(timer))
 	}
 	responseFunc := func() error {
-		ob.CanSpliceCopy = 1
+		if target.Network == net.Network_TCP {
+			ob.CanSpliceCopy = 1
+		}
 		defer timer.SetTimeout(p.Timeouts.UplinkOnly)
-		return buf.Copy(buf.NewReader(conn), link.Writer, buf.UpdateActivity(timer))
+		return buf.Copy(reader, link.Writer, buf.UpdateActivity(timer))
 	}
 
 	if newCtx != nil {
@@ -201,24 +235,16 @@ func fillRequestHeader(ctx context.Context, header []*Header) ([]*Header, error)
 	return filled, nil
 }
 
-// setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method
-func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, header []*Header, firstPayload []byte) (net.Conn, error) {
+// setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method, and send firstPayload through it.
+// HTTP/2 tunnels are multiplexed over a pool of connections to the server.
+func (c *Client) setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, header []*Header, firstPayload []byte) (net.Conn, error) {
 	req := &http.Request{
 		Method: http.MethodConnect,
 		URL:    &url.URL{Host: target},
 		Header: make(http.Header),
 		Host:   target,
 	}
-
-	if user != nil && user.Account != nil {
-		account := user.Account.(*Account)
-		auth := account.GetUsername() + ":" + account.GetPassword()
-		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
-	}
-
-	for _, h := range header {
-		req.Header.Set(h.Key, h.Value)
-	}
+	setRequestHeader(req, user, header)
 
 	connectHTTP1 := func(rawConn net.Conn) (net.Conn, error) {
 		req.Header.Set("Proxy-Connection", "Keep-Alive")
@@ -240,63 +266,184 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			rawConn.Close()
 			return nil, errors.New("Proxy responded with non 200 code: " + resp.Status)
 		}
+		if _, err := rawConn.Write(firstPayload); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
 		return rawConn, nil
 	}
 
-	connectHTTP2 := func(rawConn net.Conn, h2clientConn *http2.ClientConn) (net.Conn, error) {
+	if c.padding != nil {
+		req.Header.Set("Padding", paddingHeader())
+	}
+
+	// connectHTTP2 opens a tunnel as a stream of cc. Errors of the stream leave the shared connection alone.
+	// If owned is set, cc is not pooled and closes along with the tunnel.
+	connectHTTP2 := func(cc *h2Conn, owned bool) (net.Conn, error) {
 		pr, pw := io.Pipe()
 		req.Body = pr
 
+		// without padding, the first payload is sent along with the request
 		var pErr error
 		var wg sync.WaitGroup
-		wg.Add(1)
-
-		go func() {
-			_, pErr = pw.Write(firstPayload)
-			wg.Done()
-		}()
+		if c.padding == nil {
+			wg.Add(1)
+			go func() {
+				_, pErr = pw.Write(firstPayload)
+				wg.Done()
+			}()
+		}
 
-		resp, err := h2clientConn.RoundTrip(req)
+		resp, err := cc.h2Conn.RoundTrip(req)
 		if err != nil {
-			rawConn.Close()
+			pw.CloseWithError(err)
 			return nil, err
 		}
 
 		wg.Wait()
 		if pErr != nil {
-			rawConn.Close()
+			pw.Close()
+			resp.Body.Close()
 			return nil, pErr
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			rawConn.Close()
+			pw.Close()
+			resp.Body.Close()
 			return nil, errors.New("Proxy responded with non 200 code: " + resp.Status)
 		}
-		return newHTTP2Conn(rawConn, pw, resp.Body), nil
-	}
 
-	cachedH2Mutex.Lock()
-	cachedConn, cachedConnFound := cachedH2Conns[dest]
-	cachedH2Mutex.Unlock()
-
-	if cachedConnFound {
-		rc, cc := cachedConn.rawConn, cachedConn.h2Conn
-		if cc.CanTakeNewRequest() {
-			proxyConn, err := connectHTTP2(rc, cc)
-			if err != nil {
+		conn := newHTTP2Conn(cc.rawConn, pw, resp.Body)
+		if owned {
+			conn.owner = cc.h2Conn
+		}
+		// the server agrees to padding by sending the header back
+		if c.padding != nil {
+			if resp.Header.Get("Padding") != "" {
+				conn.in = newPaddedWriter(pw, c.padding)
+				conn.out = newPaddedReader(resp.Body, c.padding)
+			}
+			if _, err := conn.Write(firstPayload); err != nil {
+				conn.Close()
 				return nil, err
 			}
+		}
+		return cc.countTunnel(conn), nil
+	}
+
+	if cc := c.pooledH2Conn(); cc != nil {
+		return connectHTTP2(cc, false)
+	}
+
+	rawConn, nextProto, err := dialServer(ctx, dest, dialer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch nextProto {
+	case "", "http/1.1":
+		return connectHTTP1(rawConn)
+	case "h2":
+		cc, err := newH2Conn(rawConn)
+		if err != nil {
+			rawConn.Close()
+			return nil, err
+		}
 
-			return proxyConn, nil
+		proxyConn, err := connectHTTP2(cc, !c.addH2Conn(cc))
+		if err != nil {
+			rawConn.Close()
+			return nil, err
 		}
+		return proxyConn, nil
+	default:
+		rawConn.Close()
+		return nil, errors.New("negotiated unsupported application layer protocol: " + nextProto)
 	}
+}
 
+// setUpUDPTunnel opens a connect-udp tunnel of RFC 9298 to target, as an extended CONNECT stream of an HTTP/2 connection.
+// The datagrams go through the tunnel in capsules.
+func (c *Client) setUpUDPTunnel(ctx context.Context, dest net.Destination, target net.Destination, user *protocol.MemoryUser, dialer internet.Dialer, header []*Header) (net.Conn, error) {
+	u := connectUDPPath(target)
+	u.Scheme = "https"
+	u.Host = dest.NetAddr()
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    u,
+		Header: make(http.Header),
+		Host:   u.Host,
+	}
+	req.Header.Set(":protocol", "connect-udp")
+	req.Header.Set("Capsule-Protocol", "?1")
+	setRequestHeader(req, user, header)
+
+	cc := c.pooledH2Conn()
+	owned := false
+	if cc == nil {
+		rawConn, nextProto, err := dialServer(ctx, dest, dialer)
+		if err != nil {
+			return nil, err
+		}
+		if nextProto != "h2" {
+			rawConn.Close()
+			return nil, errors.New("UDP needs a server of HTTP/2, but it negotiated ", strconv.Quote(nextProto))
+		}
+		if cc, err = newH2Conn(rawConn); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+		owned = !c.addH2Conn(cc)
+	}
+
+	pr, pw := io.Pipe()
+	req.Body = pr
+	// the server tells whether it supports extended CONNECT in its first SETTINGS frame, which RoundTrip waits for
+	resp, err := cc.h2Conn.RoundTrip(req)
+	if err != nil {
+		pw.CloseWithError(err)
+		if owned {
+			cc.h2Conn.Close()
+		}
+		return nil, errors.New("the server does not accept connect-udp").Base(err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		pw.Close()
+		resp.Body.Close()
+		if owned {
+			cc.h2Conn.Close()
+		}
+		return nil, errors.New("Proxy responded to connect-udp with non 2xx code: " + resp.Status)
+	}
+
+	conn := newHTTP2Conn(cc.rawConn, pw, resp.Body)
+	if owned {
+		conn.owner = cc.h2Conn
+	}
+	return cc.countTunnel(conn), nil
+}
+
+// setRequestHeader sets the credentials of user and the filled out header on a request to the server.
+func setRequestHeader(req *http.Request, user *protocol.MemoryUser, header []*Header) {
+	if user != nil && user.Account != nil {
+		account := user.Account.(*Account)
+		auth := account.GetUsername() + ":" + account.GetPassword()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+
+	for _, h := range header {
+		req.Header.Set(h.Key, h.Value)
+	}
+}
+
+// dialServer dials the server, and returns the application protocol that it negotiated in the TLS handshake, if any.
+func dialServer(ctx context.Context, dest net.Destination, dialer internet.Dialer) (stat.Connection, string, error) {
 	rawConn, err := dialer.Dial(ctx, dest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	iConn := rawConn
+	iConn := net.Conn(rawConn)
 	if statConn, ok := iConn.(*stat.CounterConnection); ok {
 		iConn = statConn.Connection
 	}
@@ -305,59 +452,107 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 	if tlsConn, ok := iConn.(*tls.Conn); ok {
 		if err := tlsConn.HandshakeContext(ctx); err != nil {
 			rawConn.Close()
-			return nil, err
+			return nil, "", err
 		}
 		nextProto = tlsConn.ConnectionState().NegotiatedProtocol
 	} else if tlsConn, ok := iConn.(*tls.UConn); ok {
 		if err := tlsConn.HandshakeContext(ctx); err != nil {
 			rawConn.Close()
-			return nil, err
+			return nil, "", err
 		}
 		nextProto = tlsConn.ConnectionState().NegotiatedProtocol
 	}
+	return rawConn, nextProto, nil
+}
 
-	switch nextProto {
-	case "", "http/1.1":
-		return connectHTTP1(rawConn)
-	case "h2":
-		t := http2.Transport{}
-		h2clientConn, err := t.NewClientConn(rawConn)
-		if err != nil {
-			rawConn.Close()
-			return nil, err
-		}
+// newH2Conn starts an HTTP/2 connection over rawConn.
+func newH2Conn(rawConn stat.Connection) (*h2Conn, error) {
+	iConn := net.Conn(rawConn)
+	var uplink, downlink stats.Counter
+	if statConn, ok := iConn.(*stat.CounterConnection); ok {
+		iConn = statConn.Connection
+		uplink, downlink = statConn.WriteCounter, statConn.ReadCounter
+	}
 
-		proxyConn, err := connectHTTP2(rawConn, h2clientConn)
-		if err != nil {
-			rawConn.Close()
-			return nil, err
-		}
+	t := http2.Transport{}
+	h2clientConn, err := t.NewClientConn(iConn)
+	if err != nil {
+		return nil, err
+	}
+	return &h2Conn{
+		rawConn:  iConn,
+		h2Conn:   h2clientConn,
+		uplink:   uplink,
+		downlink: downlink,
+	}, nil
+}
 
-		cachedH2Mutex.Lock()
-		if cachedH2Conns == nil {
-			cachedH2Conns = make(map[net.Destination]h2Conn)
+// countTunnel counts the bytes of a tunnel of cc with the counters of the outbound.
+func (cc *h2Conn) countTunnel(conn net.Conn) net.Conn {
+	if cc.uplink != nil || cc.downlink != nil {
+		return &stat.CounterConnection{
+			Connection:   conn,
+			ReadCounter:  cc.downlink,
+			WriteCounter: cc.uplink,
 		}
+	}
+	return conn
+}
 
-		cachedH2Conns[dest] = h2Conn{
-			rawConn: rawConn,
-			h2Conn:  h2clientConn,
+// pooledH2Conn returns the pooled HTTP/2 connection with the fewest tunnels that can take a new one.
+// It returns nil if a new connection should be dialed instead, as long as the pool is not full.
+func (c *Client) pooledH2Conn() *h2Conn {
+	c.h2Access.Lock()
+	defer c.h2Access.Unlock()
+
+	var best *h2Conn
+	bestStreams := 0
+	alive := c.h2Conns[:0]
+	for _, cc := range c.h2Conns {
+		state := cc.h2Conn.State()
+		if state.Closed || state.Closing {
+			continue
 		}
-		cachedH2Mutex.Unlock()
+		alive = append(alive, cc)
+		if cc.h2Conn.CanTakeNewRequest() && (best == nil || state.StreamsActive < bestStreams) {
+			best, bestStreams = cc, state.StreamsActive
+		}
+	}
+	clear(c.h2Conns[len(alive):])
+	c.h2Conns = alive
 
-		return proxyConn, err
-	default:
-		return nil, errors.New("negotiated unsupported application layer protocol: " + nextProto)
+	if best != nil && bestStreams > 0 && len(c.h2Conns) < c.h2PoolSize {
+		return nil
+	}
+	return best
+}
+
+// addH2Conn adds a new HTTP/2 connection to the pool if there is room, and reports whether it did.
+func (c *Client) addH2Conn(cc *h2Conn) bool {
+	c.h2Access.Lock()
+	defer c.h2Access.Unlock()
+
+	if len(c.h2Conns) >= c.h2PoolSize {
+		return false
 	}
+	c.h2Conns = append(c.h2Conns, cc)
+	return true
 }
 
-func newHTTP2Conn(c net.Conn, pipedReqBody *io.PipeWriter, respBody io.ReadCloser) net.Conn {
-	return &http2Conn{Conn: c, in: pipedReqBody, out: respBody}
+func newHTTP2Conn(c net.Conn, pipedReqBody *io.PipeWriter, respBody io.ReadCloser) *http2Conn {
+	return &http2Conn{Conn: c, in: pipedReqBody, out: respBody, reqBody: pipedReqBody, respBody: respBody}
 }
 
+// http2Conn is a tunnel over a stream of an HTTP/2 connection.
 type http2Conn struct {
 	net.Conn
-	in  *io.PipeWriter
-	out io.ReadCloser
+	in  io.Writer
+	out io.Reader
+
+	reqBody  *io.PipeWriter
+	respBody io.ReadCloser
+	// connection of the tunnel alone, closed along with it
+	owner *http2.ClientConn
 }
 
 func (h *http2Conn) Read(p []byte) (n int, err error) {
@@ -369,8 +564,12 @@ func (h *http2Conn) Write(p []byte) (n int, err error) {
 }
 
 func (h *http2Conn) Close() error {
-	h.in.Close()
-	return h.out.Close()
+	h.reqBody.Close()
+	err := h.respBody.Close()
+	if h.owner != nil {
+		h.owner.Close()
+	}
+	return err
 }
 
 func init() {
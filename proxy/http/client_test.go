@@ -0,0 +1,215 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	gotls "crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/tls"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// tlsDialer dials the test server with TLS, counting the connections.
+type tlsDialer struct {
+	addr  string
+	dials atomic.Int32
+}
+
+func (d *tlsDialer) Dial(ctx context.Context, dest net.Destination) (stat.Connection, error) {
+	d.dials.Add(1)
+	conn, err := net.Dial("tcp", d.addr)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Client(conn, &gotls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2"}}).(stat.Connection), nil
+}
+
+func (d *tlsDialer) DestIpAddress() net.IP { return nil }
+
+func (d *tlsDialer) SetOutboundGateway(ctx context.Context, ob *session.Outbound) {}
+
+// connectEcho echoes CONNECT tunnels, padding them like the Caddy forwardproxy if asked to.
+func connectEcho(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in io.Reader = r.Body
+	var out io.Writer = w
+	padding := &Padding{}
+	if r.Header.Get("Padding") != "" {
+		w.Header().Set("Padding", paddingHeader())
+		in = newPaddedReader(r.Body, padding)
+		out = newPaddedWriter(w, padding)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	b := make([]byte, 1024)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			common.Must2(out.Write(b[:n]))
+			w.(http.Flusher).Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestHTTP2TunnelPool(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(connectEcho))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, padding := range []*Padding{nil, {Frames: 2, MaxSize: 32}} {
+		c := &Client{padding: padding, h2PoolSize: 2}
+		dialer := &tlsDialer{addr: server.Listener.Addr().String()}
+		dest := net.DestinationFromAddr(server.Listener.Addr())
+
+		var conns []net.Conn
+		for i := 0; i < 4; i++ {
+			conn, err := c.setUpHTTPTunnel(context.Background(), dest, "example.com:443", nil, dialer, nil, []byte("hello"))
+			common.Must(err)
+			conns = append(conns, conn)
+
+			common.Must2(conn.Write([]byte(" world")))
+			b := make([]byte, 11)
+			common.Must2(io.ReadFull(conn, b))
+			if string(b) != "hello world" {
+				t.Error("unexpected echo ", string(b))
+			}
+		}
+		// tunnels are spread over the pool, and no more connections are dialed
+		if n := dialer.dials.Load(); n != 2 {
+			t.Error("dialed ", n, " connections for a pool of 2")
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+}
+
+// serveConnectUDP serves connect-udp tunnels on an HTTP/2 connection, echoing their data. It is written with the framer,
+// as the HTTP/2 server of x/net only supports extended CONNECT with GODEBUG=http2xconnect=1.
+func serveConnectUDP(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	preface := make([]byte, len(http2.ClientPreface))
+	if _, err := io.ReadFull(conn, preface); err != nil {
+		return
+	}
+	framer := http2.NewFramer(conn, conn)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	common.Must(framer.WriteSettings(http2.Setting{ID: http2.SettingEnableConnectProtocol, Val: 1}))
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return
+		}
+		switch frame := frame.(type) {
+		case *http2.SettingsFrame:
+			if !frame.IsAck() {
+				common.Must(framer.WriteSettingsAck())
+			}
+		case *http2.MetaHeadersFrame:
+			status := "200"
+			if frame.PseudoValue("method") != http.MethodConnect || frame.PseudoValue("protocol") != "connect-udp" ||
+				frame.PseudoValue("path") != "/.well-known/masque/udp/192.0.2.6/53/" {
+				t.Error("unexpected request ", frame.Fields)
+				status = "400"
+			}
+			var header bytes.Buffer
+			encoder := hpack.NewEncoder(&header)
+			common.Must(encoder.WriteField(hpack.HeaderField{Name: ":status", Value: status}))
+			common.Must(encoder.WriteField(hpack.HeaderField{Name: "capsule-protocol", Value: "?1"}))
+			common.Must(framer.WriteHeaders(http2.HeadersFrameParam{
+				StreamID:      frame.StreamID,
+				BlockFragment: header.Bytes(),
+				EndHeaders:    true,
+			}))
+		case *http2.DataFrame:
+			// the capsules are echoed as they are
+			if data := frame.Data(); len(data) > 0 {
+				data = bytes.Clone(data)
+				common.Must(framer.WriteWindowUpdate(0, uint32(len(data))))
+				common.Must(framer.WriteWindowUpdate(frame.StreamID, uint32(len(data))))
+				common.Must(framer.WriteData(frame.StreamID, false, data))
+			}
+		}
+	}
+}
+
+func TestConnectUDP(t *testing.T) {
+	certificate, err := gotls.X509KeyPair(cert.MustGenerate(nil, cert.DNSNames("example.com")).ToPEM())
+	common.Must(err)
+	listener, err := gotls.Listen("tcp", "127.0.0.1:0", &gotls.Config{
+		Certificates: []gotls.Certificate{certificate},
+		NextProtos:   []string{"h2"},
+	})
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveConnectUDP(t, conn)
+		}
+	}()
+
+	c := &Client{h2PoolSize: 1}
+	dialer := &tlsDialer{addr: listener.Addr().String()}
+	dest := net.DestinationFromAddr(listener.Addr())
+	target := net.UDPDestination(net.ParseAddress("192.0.2.6"), 53)
+
+	conn, err := c.setUpUDPTunnel(context.Background(), dest, target, nil, dialer, nil)
+	common.Must(err)
+	defer conn.Close()
+
+	writer := &capsuleWriter{w: conn}
+	reader := &capsuleReader{r: bufio.NewReader(conn)}
+	// datagrams keep their boundaries
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("hello")), buf.FromBytes([]byte("world!"))}))
+	for _, expected := range []string{"hello", "world!"} {
+		mb, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		if mb.String() != expected {
+			t.Error("unexpected datagram ", mb.String())
+		}
+		buf.ReleaseMulti(mb)
+	}
+}
+
+func TestConnectUDPUnsupported(t *testing.T) {
+	// the HTTP/2 server of net/http does not support extended CONNECT
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	c := &Client{h2PoolSize: 1}
+	dialer := &tlsDialer{addr: server.Listener.Addr().String()}
+	dest := net.DestinationFromAddr(server.Listener.Addr())
+	target := net.UDPDestination(net.ParseAddress("192.0.2.6"), 53)
+
+	if _, err := c.setUpUDPTunnel(context.Background(), dest, target, nil, dialer, nil); err == nil {
+		t.Error("expected connect-udp to be rejected")
+	}
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/xtls/xray-core/common/dice"
+)
+
+const (
+	defaultPaddingFrames = 8
+	maxPaddingSize       = 255
+	maxPaddedPayload     = 1<<16 - 1
+	// payloads shorter than this are padded to at least this length if the range allows, like NaiveProxy does,
+	// so that short writes do not stand out by their length
+	smallPayload = 100
+	// characters of the padding header, which HPACK cannot compress well
+	paddingHeaderChars = "!#$()+<>?@[]^`{}"
+)
+
+// paddingHeader returns a value of random length for the Padding header, which asks the server to pad its frames.
+func paddingHeader() string {
+	b := make([]byte, 16+dice.Roll(17))
+	for i := range b {
+		b[i] = paddingHeaderChars[dice.Roll(len(paddingHeaderChars))]
+	}
+	return string(b)
+}
+
+// paddingRange returns the number of padded frames and the range of padding lengths of p.
+func paddingRange(p *Padding) (frames, min, max int) {
+	frames = int(p.GetFrames())
+	if frames == 0 {
+		frames = defaultPaddingFrames
+	}
+	min, max = int(p.GetMinSize()), int(p.GetMaxSize())
+	if max == 0 || max > maxPaddingSize {
+		max = maxPaddingSize
+	}
+	if min > max {
+		min = max
+	}
+	return
+}
+
+// paddedWriter frames the first writes of a tunnel as
+// | payload length, 2 bytes | padding length, 1 byte | payload | zero padding |
+// and passes the following ones through.
+type paddedWriter struct {
+	w        io.Writer
+	frames   int
+	min, max int
+	written  int
+}
+
+func newPaddedWriter(w io.Writer, p *Padding) *paddedWriter {
+	frames, min, max := paddingRange(p)
+	return &paddedWriter{w: w, frames: frames, min: min, max: max}
+}
+
+// paddingSize returns a random padding length within the range of w for a payload of size bytes.
+func (w *paddedWriter) paddingSize(size int) int {
+	min := w.min
+	if size < smallPayload && smallPayload-size > min {
+		min = smallPayload - size
+		if min > w.max {
+			min = w.max
+		}
+	}
+	return min + dice.Roll(w.max-min+1)
+}
+
+func (w *paddedWriter) Write(b []byte) (int, error) {
+	n := 0
+	for len(b) > 0 && w.written < w.frames {
+		chunk := b
+		if len(chunk) > maxPaddedPayload {
+			chunk = chunk[:maxPaddedPayload]
+		}
+		pad := w.paddingSize(len(chunk))
+		frame := make([]byte, 3+len(chunk)+pad)
+		binary.BigEndian.PutUint16(frame, uint16(len(chunk)))
+		frame[2] = byte(pad)
+		copy(frame[3:], chunk)
+		if _, err := w.w.Write(frame); err != nil {
+			return n, err
+		}
+		w.written++
+		n += len(chunk)
+		b = b[len(chunk):]
+	}
+	if len(b) == 0 {
+		return n, nil
+	}
+	m, err := w.w.Write(b)
+	return n + m, err
+}
+
+// paddedReader strips the framing of paddedWriter from the first frames of a tunnel.
+type paddedReader struct {
+	r      io.Reader
+	frames int
+	read   int
+	remain int // unread payload of the current frame
+	pad    int // padding after the current frame
+}
+
+func newPaddedReader(r io.Reader, p *Padding) *paddedReader {
+	frames, _, _ := paddingRange(p)
+	return &paddedReader{r: r, frames: frames}
+}
+
+func (r *paddedReader) Read(b []byte) (int, error) {
+	for r.remain == 0 {
+		if r.pad > 0 {
+			if _, err := io.CopyN(io.Discard, r.r, int64(r.pad)); err != nil {
+				return 0, err
+			}
+			r.pad = 0
+		}
+		if r.read >= r.frames {
+			return r.r.Read(b)
+		}
+		var header [3]byte
+		if _, err := io.ReadFull(r.r, header[:]); err != nil {
+			return 0, err
+		}
+		r.remain = int(binary.BigEndian.Uint16(header[:2]))
+		r.pad = int(header[2])
+		r.read++
+	}
+	if len(b) > r.remain {
+		b = b[:r.remain]
+	}
+	n, err := r.r.Read(b)
+	r.remain -= n
+	return n, err
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/xtls/xray-core/common"
+)
+
+func TestPaddingRoundTrip(t *testing.T) {
+	padding := &Padding{Frames: 3, MinSize: 10, MaxSize: 20}
+
+	var wire bytes.Buffer
+	w := newPaddedWriter(&wire, padding)
+	var payload []byte
+	for i := 0; i < 5; i++ {
+		chunk := bytes.Repeat([]byte{byte('a' + i)}, 100*(i+1))
+		common.Must2(w.Write(chunk))
+		payload = append(payload, chunk...)
+	}
+
+	// three framed writes, each with 3 header bytes and 10 to 20 bytes of padding
+	overhead := wire.Len() - len(payload)
+	if overhead < 3*13 || overhead > 3*23 {
+		t.Error("unexpected padding overhead ", overhead)
+	}
+
+	// read in small pieces, so that frames are split across reads
+	r := newPaddedReader(&wire, padding)
+	var got []byte
+	b := make([]byte, 7)
+	for {
+		n, err := r.Read(b)
+		got = append(got, b[:n]...)
+		if err == io.EOF {
+			break
+		}
+		common.Must(err)
+	}
+	if r := cmp.Diff(got, payload); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestPaddingSize(t *testing.T) {
+	w := newPaddedWriter(io.Discard, &Padding{MinSize: 10, MaxSize: 200})
+	for _, size := range []int{0, 40, 99, 100, 1000} {
+		floor := 10
+		if size < smallPayload && smallPayload-size > floor {
+			floor = smallPayload - size
+		}
+		sizes := make(map[int]bool)
+		for i := 0; i < 1000; i++ {
+			pad := w.paddingSize(size)
+			if pad < floor || pad > 200 {
+				t.Fatal("padding ", pad, " of a payload of ", size, " bytes is out of [", floor, ", 200]")
+			}
+			sizes[pad] = true
+		}
+		// the lengths spread over the range instead of repeating
+		if len(sizes) < 50 {
+			t.Error("only ", len(sizes), " padding lengths for a payload of ", size, " bytes")
+		}
+	}
+}
@@ -17,6 +17,9 @@ import (
 	"github.com/xtls/xray-core/transport/internet"
 )
 
+// defaultMaxDepth is the number of loopback passes allowed if the config does not set it.
+const defaultMaxDepth = 4
+
 type Loopback struct {
 	config             *Config
 	dispatcherInstance routing.Dispatcher
@@ -31,26 +34,23 @@ func (l *Loopback) Process(ctx context.Context, link *transport.Link, _ internet
 	ob.Name = "loopback"
 	destination := ob.Target
 
+	depth := session.LoopbackDepthFromContext(ctx) + 1
+	if maxDepth := l.maxDepth(); depth > maxDepth {
+		return errors.New("routing loop detected: connection to ", destination, " is looped back more than ", maxDepth, " times").AtWarning()
+	}
+
 	errors.LogInfo(ctx, "opening connection to ", destination)
 
 	input := link.Reader
 	output := link.Writer
 
+	ctx = l.contextForPass(ctx, ob)
+	ctx = session.ContextWithLoopbackDepth(ctx, depth)
+
 	var conn net.Conn
 	err := retry.ExponentialBackoff(2, 100).On(func() error {
 		dialDest := destination
 
-		content := new(session.Content)
-		content.SkipDNSResolve = true
-
-		ctx = session.ContextWithContent(ctx, content)
-
-		inbound := session.InboundFromContext(ctx)
-
-		inbound.Tag = l.config.InboundTag
-
-		ctx = session.ContextWithInbound(ctx, inbound)
-
 		rawConn, err := l.dispatcherInstance.Dispatch(ctx, dialDest)
 		if err != nil {
 			return err
@@ -107,6 +107,47 @@ func (l *Loopback) Process(ctx context.Context, link *transport.Link, _ internet
 	return nil
 }
 
+func (l *Loopback) maxDepth() int {
+	if l.config.MaxDepth == 0 {
+		return defaultMaxDepth
+	}
+	return int(l.config.MaxDepth)
+}
+
+// contextForPass returns the context for the next routing pass of the connection. The inbound is
+// copied with the configured tag, and keeps its user, source and sniffing results unless they
+// are cleared by the config. The sniffing request is kept as well, so that the next pass overrides
+// the destination the same way with the results, without sniffing again unless it resniffs.
+func (l *Loopback) contextForPass(ctx context.Context, ob *session.Outbound) context.Context {
+	inbound := new(session.Inbound)
+	if original := session.InboundFromContext(ctx); original != nil {
+		*inbound = *original
+	}
+	inbound.Tag = l.config.InboundTag
+	// the new pass reads from the link, not from the connection of the inbound
+	inbound.CanSpliceCopy = 3
+
+	content := new(session.Content)
+	content.SkipDNSResolve = true
+	if l.config.ClearMetadata {
+		inbound.User = nil
+		inbound.Source = net.Destination{}
+		// the sniffed domain of route only sniffing
+		ob.RouteTarget = net.Destination{}
+	} else if original := session.ContentFromContext(ctx); original != nil {
+		content.SniffingRequest = original.SniffingRequest
+		content.Protocol = original.Protocol
+		content.Domain = original.Domain
+		content.Sniffed = original.Sniffed
+		for name, value := range original.Attributes {
+			content.SetAttribute(name, value)
+		}
+	}
+
+	ctx = session.ContextWithInbound(ctx, inbound)
+	return session.ContextWithContent(ctx, content)
+}
+
 func (l *Loopback) init(config *Config, dispatcherInstance routing.Dispatcher) error {
 	l.dispatcherInstance = dispatcherInstance
 	l.config = config
@@ -0,0 +1,110 @@
+package loopback
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/pipe"
+)
+
+// recordDispatcher records the context of the dispatched connections, and closes them at once.
+type recordDispatcher struct {
+	routing.Dispatcher
+	ctx context.Context
+}
+
+func (d *recordDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	d.ctx = ctx
+	reader, writer := pipe.New()
+	common.Must(writer.Close())
+	return &transport.Link{Reader: reader, Writer: writer}, nil
+}
+
+func process(l *Loopback, ctx context.Context) error {
+	reader, writer := pipe.New()
+	common.Must(writer.Close())
+	return l.Process(ctx, &transport.Link{Reader: reader, Writer: writer}, nil)
+}
+
+func newContext() context.Context {
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Tag:    "in",
+		Source: net.TCPDestination(net.ParseAddress("10.0.0.1"), 40000),
+		User:   &protocol.MemoryUser{Email: "love@example.com"},
+	})
+	ctx = session.ContextWithContent(ctx, &session.Content{
+		Protocol:        "tls",
+		Domain:          "example.com",
+		Sniffed:         true,
+		SniffingRequest: session.SniffingRequest{Enabled: true, OverrideDestinationForProtocol: []string{"tls"}},
+	})
+	return session.ContextWithOutbounds(ctx, []*session.Outbound{{
+		Target:      net.TCPDestination(net.ParseAddress("1.1.1.1"), 443),
+		RouteTarget: net.TCPDestination(net.ParseAddress("example.com"), 443),
+	}})
+}
+
+func TestLoopbackMetadata(t *testing.T) {
+	dispatcher := new(recordDispatcher)
+	l := new(Loopback)
+	common.Must(l.init(&Config{InboundTag: "loop"}, dispatcher))
+
+	ctx := newContext()
+	common.Must(process(l, ctx))
+
+	inbound := session.InboundFromContext(dispatcher.ctx)
+	if inbound.Tag != "loop" || inbound.User.Email != "love@example.com" || inbound.Source.Address.String() != "10.0.0.1" {
+		t.Error("unexpected inbound ", inbound)
+	}
+	if tag := session.InboundFromContext(ctx).Tag; tag != "in" {
+		t.Error("original inbound is changed to ", tag)
+	}
+	if content := session.ContentFromContext(dispatcher.ctx); content.Protocol != "tls" || !content.SkipDNSResolve {
+		t.Error("unexpected content ", content)
+	}
+	// the next pass reuses the sniffing results instead of sniffing again
+	if content := session.ContentFromContext(dispatcher.ctx); content.Domain != "example.com" || !content.Sniffed || !content.SniffingRequest.Enabled {
+		t.Error("sniffing results are lost: ", content)
+	}
+	if ob := session.OutboundsFromContext(dispatcher.ctx)[0]; ob.RouteTarget.Address.String() != "example.com" {
+		t.Error("sniffed domain is lost: ", ob.RouteTarget)
+	}
+
+	common.Must(l.init(&Config{InboundTag: "loop", ClearMetadata: true}, dispatcher))
+	common.Must(process(l, newContext()))
+	inbound = session.InboundFromContext(dispatcher.ctx)
+	if inbound.User != nil || inbound.Source.IsValid() {
+		t.Error("inbound is not cleared: ", inbound)
+	}
+	if content := session.ContentFromContext(dispatcher.ctx); content.Protocol != "" || content.Sniffed {
+		t.Error("content is not cleared: ", content)
+	}
+	if ob := session.OutboundsFromContext(dispatcher.ctx)[0]; ob.RouteTarget.IsValid() {
+		t.Error("sniffed domain is not cleared: ", ob.RouteTarget)
+	}
+}
+
+func TestLoopbackDepth(t *testing.T) {
+	dispatcher := new(recordDispatcher)
+	l := new(Loopback)
+	common.Must(l.init(&Config{InboundTag: "loop", MaxDepth: 2}, dispatcher))
+
+	ctx := newContext()
+	for i := 1; i <= 2; i++ {
+		common.Must(process(l, ctx))
+		ctx = dispatcher.ctx
+		if depth := session.LoopbackDepthFromContext(ctx); depth != i {
+			t.Error("depth ", depth, " after ", i, " passes")
+		}
+	}
+	if err := process(l, ctx); err == nil || !strings.Contains(err.Error(), "routing loop detected") {
+		t.Error("expect routing loop, but got ", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"crypto/rand"
 	"io"
 	"math/big"
+	"reflect"
 	"runtime"
 	"strconv"
 	"time"
@@ -66,6 +67,17 @@ type Inbound interface {
 	Process(context.Context, net.Network, stat.Connection, routing.Dispatcher) error
 }
 
+// A DeviceInbound is an Inbound that receives connections from a network device it runs itself, such as a TUN interface, instead of from listeners.
+type DeviceInbound interface {
+	Inbound
+
+	// Serve opens the device, and passes every connection on it to handler along with its original destination.
+	Serve(handler func(conn stat.Connection, dest net.Destination)) error
+
+	// Close closes the device.
+	Close() error
+}
+
 // An Outbound process outbound connections.
 type Outbound interface {
 	// Process processes the given connection. The given dialer may be used to dial a system outbound connection.
@@ -386,8 +398,34 @@ func (w *VisionWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 	return w.Writer.WriteMultiBuffer(mb)
 }
 
+// Heartbeat writes a frame of padding only, which VisionReader discards, as long as the writer is padding.
+// Once the padding ends, the stream can not carry an empty frame any more, and it writes nothing.
+func (w *VisionWriter) Heartbeat() error {
+	isPadding := w.trafficState.Inbound.IsPadding
+	if w.isUplink {
+		isPadding = w.trafficState.Outbound.IsPadding
+	}
+	if !isPadding {
+		return nil
+	}
+	return w.Writer.WriteMultiBuffer(buf.MultiBuffer{XtlsPadding(nil, CommandPaddingContinue, &w.writeOnceUserUUID, false, w.ctx)})
+}
+
 // ReshapeMultiBuffer prepare multi buffer for padding structure (max 21 bytes)
 func ReshapeMultiBuffer(ctx context.Context, buffer buf.MultiBuffer) buf.MultiBuffer {
+	// padding only fits into regular buffers, so break large ones up first
+	for _, b := range buffer {
+		if b.Len() > buf.Size {
+			var mb buf.MultiBuffer
+			for !buffer.IsEmpty() {
+				var chunk buf.MultiBuffer
+				buffer, chunk = buf.SplitSize(buffer, buf.Size)
+				mb = append(mb, chunk...)
+			}
+			buffer = mb
+			break
+		}
+	}
 	needReshape := 0
 	for _, b := range buffer {
 		if b.Len() >= buf.Size-21 {
@@ -486,6 +524,10 @@ func XtlsUnpadding(b *buf.Buffer, s *TrafficState, isUplink bool, ctx context.Co
 		}
 	}
 	newbuffer := buf.New()
+	if b.Len() > buf.Size {
+		newbuffer.Release()
+		newbuffer = buf.NewLarge()
+	}
 	for b.Len() > 0 {
 		if *remainingCommand > 0 {
 			data, err := b.ReadByte()
@@ -644,11 +686,10 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 	readerConn, readCounter, _ := UnwrapRawConn(readerConn)
 	writerConn, _, writeCounter := UnwrapRawConn(writerConn)
 	reader := buf.NewReader(readerConn)
-	if runtime.GOOS != "linux" && runtime.GOOS != "android" {
-		return readV(ctx, reader, writer, timer, readCounter)
-	}
-	tc, ok := writerConn.(*net.TCPConn)
-	if !ok || readerConn == nil || writerConn == nil {
+	if !buf.CanSplice(readerConn, writerConn) {
+		if readerConn != nil && writerConn != nil && (runtime.GOOS == "linux" || runtime.GOOS == "android") {
+			errors.LogDebug(ctx, "CopyRawConn can't splice from ", reflect.TypeOf(readerConn), " to ", reflect.TypeOf(writerConn))
+		}
 		return readV(ctx, reader, writer, timer, readCounter)
 	}
 	inbound := session.InboundFromContext(ctx)
@@ -683,20 +724,12 @@ func CopyRawConnIfExist(ctx context.Context, readerConn net.Conn, writerConn net
 			if inTimer != nil {
 				inTimer.SetTimeout(24 * time.Hour)
 			}
-			w, err := tc.ReadFrom(readerConn)
-			if readCounter != nil {
-				readCounter.Add(w) // outbound stats
-			}
-			if writeCounter != nil {
-				writeCounter.Add(w) // inbound stats
-			}
+			// outbound, inbound and user stats
+			options := []buf.CopyOption{buf.UpdateActivity(timer), buf.AddToStatCounter(readCounter), buf.AddToStatCounter(writeCounter)}
 			if statWriter != nil {
-				statWriter.Counter.Add(w) // user stats
-			}
-			if err != nil && errors.Cause(err) != io.EOF {
-				return err
+				options = append(options, buf.AddToStatCounter(statWriter.Counter))
 			}
-			return nil
+			return buf.Copy(reader, buf.NewWriter(writerConn), options...)
 		}
 		buffer, err := reader.ReadMultiBuffer()
 		if !buffer.IsEmpty() {
@@ -733,5 +766,6 @@ func IsRAWTransportWithoutSecurity(conn stat.Connection) bool {
 	_, ok1 := iConn.(*proxyproto.Conn)
 	_, ok2 := iConn.(*net.TCPConn)
 	_, ok3 := iConn.(*internet.UnixConnWrapper)
-	return ok1 || ok2 || ok3
+	_, ok4 := iConn.(*net.UnixConn)
+	return ok1 || ok2 || ok3 || ok4
 }
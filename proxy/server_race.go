@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"context"
+	gonet "net"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+const (
+	// serverRaceDelay is the time an attempt has before the next address is dialed too, as in RFC 8305.
+	serverRaceDelay = 250 * time.Millisecond
+	// serverWinnerTTL is how long the address that won a race is dialed alone.
+	serverWinnerTTL = 10 * time.Minute
+)
+
+type serverWinner struct {
+	dest   net.Destination
+	expire time.Time
+}
+
+var serverWinners struct {
+	sync.Mutex
+	winners map[string]serverWinner
+}
+
+func getServerWinner(key string) (net.Destination, bool) {
+	serverWinners.Lock()
+	defer serverWinners.Unlock()
+
+	w, found := serverWinners.winners[key]
+	if found && time.Now().After(w.expire) {
+		delete(serverWinners.winners, key)
+		return net.Destination{}, false
+	}
+	return w.dest, found
+}
+
+func setServerWinner(key string, dest net.Destination) {
+	serverWinners.Lock()
+	defer serverWinners.Unlock()
+
+	if serverWinners.winners == nil {
+		serverWinners.winners = make(map[string]serverWinner)
+	}
+	serverWinners.winners[key] = serverWinner{dest: dest, expire: time.Now().Add(serverWinnerTTL)}
+}
+
+func forgetServerWinner(key string) {
+	serverWinners.Lock()
+	defer serverWinners.Unlock()
+
+	delete(serverWinners.winners, key)
+}
+
+// sourceNetwork identifies the network that the connections go out from: the gateway of the outbound if it has one,
+// or else the local address that the system routes to the internet from. Finding it sends no packet.
+func sourceNetwork(ctx context.Context) string {
+	if outbounds := session.OutboundsFromContext(ctx); len(outbounds) > 0 {
+		if gateway := outbounds[len(outbounds)-1].Gateway; gateway != nil {
+			return gateway.String()
+		}
+	}
+	for _, probe := range []string{"8.8.8.8:53", "[2001:4860:4860::8888]:53"} {
+		conn, err := gonet.Dial("udp", probe)
+		if err != nil {
+			continue
+		}
+		local := conn.LocalAddr().(*gonet.UDPAddr).IP.String()
+		conn.Close()
+		return local
+	}
+	return ""
+}
+
+// DialServer dials the server at dest, or at any of its alternative addresses. The addresses are raced:
+// each one is dialed serverRaceDelay after the previous one, or as soon as it has failed, and the first connection wins.
+// The winner is remembered for the source network, and dialed alone until it fails or serverWinnerTTL passes.
+func DialServer(ctx context.Context, dialer internet.Dialer, dest net.Destination, alternatives []net.Destination) (stat.Connection, error) {
+	if len(alternatives) == 0 || dest.Network != net.Network_TCP {
+		return dialer.Dial(ctx, dest)
+	}
+
+	key := dest.NetAddr() + " from " + sourceNetwork(ctx)
+	if winner, found := getServerWinner(key); found {
+		conn, err := dialer.Dial(ctx, winner)
+		if err == nil {
+			return conn, nil
+		}
+		errors.LogInfoInner(ctx, err, "failed to dial the last winner ", winner, ", racing all the addresses again")
+		forgetServerWinner(key)
+	}
+
+	dests := append([]net.Destination{dest}, alternatives...)
+	conn, winner, err := raceServer(ctx, dialer, dests)
+	if err != nil {
+		return nil, err
+	}
+	errors.LogDebug(ctx, "won the race of the addresses of ", dest, " with ", winner)
+	setServerWinner(key, winner)
+	return conn, nil
+}
+
+type raceResult struct {
+	index int
+	conn  stat.Connection
+	err   error
+	ob    *session.Outbound
+}
+
+func raceServer(ctx context.Context, dialer internet.Dialer, dests []net.Destination) (stat.Connection, net.Destination, error) {
+	// each attempt has its own outbound, as the dialer records the gateway and the connection in it
+	outbounds := session.OutboundsFromContext(ctx)
+	ob := outbounds[len(outbounds)-1]
+	parents := outbounds[:len(outbounds)-1 : len(outbounds)-1]
+
+	results := make(chan raceResult, len(dests))
+	cancels := make([]context.CancelFunc, 0, len(dests))
+	start := func(index int) {
+		attemptOb := *ob
+		attemptCtx, cancel := context.WithCancel(session.ContextWithOutbounds(ctx, append(parents, &attemptOb)))
+		cancels = append(cancels, cancel)
+		go func() {
+			conn, err := dialer.Dial(attemptCtx, dests[index])
+			results <- raceResult{index: index, conn: conn, err: err, ob: &attemptOb}
+		}()
+	}
+	// the losers are aborted, and closed if they connect anyway
+	abort := func(winner int, active int) {
+		for i, cancel := range cancels {
+			if i != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for ; active > 0; active-- {
+				if r := <-results; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var lastErr error
+	for next, active := 0, 0; ; {
+		select {
+		case <-timer.C:
+			start(next)
+			next++
+			active++
+			if next < len(dests) {
+				timer.Reset(serverRaceDelay)
+			}
+		case r := <-results:
+			active--
+			if r.err == nil {
+				abort(r.index, active)
+				ob.Gateway = r.ob.Gateway
+				ob.Conn = r.ob.Conn
+				return r.conn, dests[r.index], nil
+			}
+			errors.LogInfoInner(ctx, r.err, "failed to dial ", dests[r.index])
+			lastErr = r.err
+			if next < len(dests) {
+				timer.Reset(0)
+			} else if active == 0 {
+				return nil, net.Destination{}, errors.New("failed to dial any address of the server").Base(lastErr)
+			}
+		case <-ctx.Done():
+			abort(-1, active)
+			return nil, net.Destination{}, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package proxy_test
+
+import (
+	"context"
+	gonet "net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// raceDialer connects to each address after its delay, or fails if the delay is negative.
+type raceDialer struct {
+	sync.Mutex
+	delays map[string]time.Duration
+	dialed []string
+}
+
+func (d *raceDialer) Dial(ctx context.Context, dest net.Destination) (stat.Connection, error) {
+	d.Lock()
+	d.dialed = append(d.dialed, dest.Address.String())
+	delay := d.delays[dest.Address.String()]
+	d.Unlock()
+
+	if delay < 0 {
+		return nil, errors.New("unreachable")
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	client, server := gonet.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func (d *raceDialer) DestIpAddress() net.IP {
+	return nil
+}
+
+func (d *raceDialer) SetOutboundGateway(ctx context.Context, ob *session.Outbound) {}
+
+func (d *raceDialer) reset() []string {
+	d.Lock()
+	defer d.Unlock()
+	dialed := d.dialed
+	d.dialed = nil
+	return dialed
+}
+
+func TestDialServer(t *testing.T) {
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{
+		Gateway: net.ParseAddress("127.0.0.1"),
+	}})
+	dest := net.TCPDestination(net.ParseAddress("10.0.0.1"), 443)
+	alternatives := []net.Destination{net.TCPDestination(net.ParseAddress("10.0.0.2"), 443)}
+	dialer := &raceDialer{delays: map[string]time.Duration{
+		"10.0.0.1": time.Second,
+		"10.0.0.2": 0,
+	}}
+
+	start := time.Now()
+	conn, err := proxy.DialServer(ctx, dialer, dest, alternatives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Error("the second address did not win the race: ", elapsed)
+	}
+	if dialed := dialer.reset(); len(dialed) != 2 {
+		t.Error("expected both addresses to be dialed, but got ", dialed)
+	}
+
+	// the winner is dialed alone
+	conn, err = proxy.DialServer(ctx, dialer, dest, alternatives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if dialed := dialer.reset(); len(dialed) != 1 || dialed[0] != "10.0.0.2" {
+		t.Error("expected the winner to be dialed alone, but got ", dialed)
+	}
+
+	// a failure of the winner races again
+	dialer.Lock()
+	dialer.delays["10.0.0.2"] = -1
+	dialer.delays["10.0.0.1"] = 0
+	dialer.Unlock()
+	conn, err = proxy.DialServer(ctx, dialer, dest, alternatives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if dialed := dialer.reset(); len(dialed) != 2 || dialed[1] != "10.0.0.1" {
+		t.Error("expected the addresses to be raced again, but got ", dialed)
+	}
+
+	dialer.Lock()
+	dialer.delays["10.0.0.1"] = -1
+	dialer.Unlock()
+	if _, err := proxy.DialServer(ctx, dialer, dest, alternatives); err == nil {
+		t.Error("expected an error when no address is reachable")
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -62,7 +63,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	var conn stat.Connection
 
 	err := retry.ExponentialBackoff(5, 100).On(func() error {
-		rawConn, err := dialer.Dial(ctx, dest)
+		rawConn, err := proxy.DialServer(ctx, dialer, dest, server.Alternatives)
 		if err != nil {
 			return err
 		}
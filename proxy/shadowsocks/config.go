@@ -60,6 +60,10 @@ func createAesGcm(key []byte) cipher.AEAD {
 	return crypto.NewAesGcm(key)
 }
 
+func createAesGcmSiv(key []byte) cipher.AEAD {
+	return crypto.NewAesGcmSiv(key)
+}
+
 func createChaCha20Poly1305(key []byte) cipher.AEAD {
 	ChaChaPoly1305, err := chacha20poly1305.New(key)
 	common.Must(err)
@@ -98,6 +102,12 @@ func (a *Account) getCipher() (Cipher, error) {
 			IVBytes:         32,
 			AEADAuthCreator: createXChaCha20Poly1305,
 		}, nil
+	case CipherType_AES_256_GCM_SIV:
+		return &AEADCipher{
+			KeyBytes:        32,
+			IVBytes:         32,
+			AEADAuthCreator: createAesGcmSiv,
+		}, nil
 	case CipherType_NONE:
 		return NoneCipher{}, nil
 	default:
@@ -1,13 +1,19 @@
 package shadowsocks_test
 
 import (
+	"bytes"
 	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/crypto"
 	"github.com/xtls/xray-core/proxy/shadowsocks"
+	"golang.org/x/crypto/hkdf"
 )
 
 func TestAEADCipherUDP(t *testing.T) {
@@ -36,3 +42,57 @@ func TestAEADCipherUDP(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestAEADCipherUDPAesGcmSiv(t *testing.T) {
+	rawAccount := &shadowsocks.Account{
+		CipherType: shadowsocks.CipherType_AES_256_GCM_SIV,
+		Password:   "test",
+	}
+	account, err := rawAccount.AsAccount()
+	common.Must(err)
+
+	cipher := account.(*shadowsocks.MemoryAccount).Cipher
+
+	key := make([]byte, cipher.KeySize())
+	common.Must2(rand.Read(key))
+
+	payload := make([]byte, 1024)
+	common.Must2(rand.Read(payload))
+
+	b1 := buf.New()
+	common.Must2(b1.ReadFullFrom(rand.Reader, cipher.IVSize()))
+	common.Must2(b1.Write(payload))
+	common.Must(cipher.EncodePacket(key, b1))
+
+	common.Must(cipher.DecodePacket(key, b1))
+	if diff := cmp.Diff(b1.Bytes(), payload); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// the packet is the salt, and the payload sealed by the subkey of the salt with the zero nonce
+func TestAesGcmSivPacketFormat(t *testing.T) {
+	account := common.Must2((&shadowsocks.Account{
+		CipherType: shadowsocks.CipherType_AES_256_GCM_SIV,
+		Password:   "test",
+	}).AsAccount()).(*shadowsocks.MemoryAccount)
+	// EVP_BytesToKey of "test"
+	if hex.EncodeToString(account.Key) != "098f6bcd4621d373cade4e832627b4f60a9172716ae6428409885b8b829ccb05" {
+		t.Error("unexpected key: ", hex.EncodeToString(account.Key))
+	}
+
+	salt := make([]byte, 32)
+	common.Must2(rand.Read(salt))
+	payload := []byte("payload")
+	b := buf.New()
+	common.Must2(b.Write(salt))
+	common.Must2(b.Write(payload))
+	common.Must(account.Cipher.EncodePacket(account.Key, b))
+
+	subkey := make([]byte, 32)
+	common.Must2(io.ReadFull(hkdf.New(sha1.New, account.Key, salt, []byte("ss-subkey")), subkey))
+	expected := append(salt, crypto.NewAesGcmSiv(subkey).Seal(nil, make([]byte, 12), payload, nil)...)
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("packet = %x, want %x", b.Bytes(), expected)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/udp"
 )
@@ -26,6 +27,7 @@ type Server struct {
 	validator     *Validator
 	policyManager policy.Manager
 	cone          bool
+	authFailures  proxy.AuthFailures
 }
 
 // NewServer create a new Shadowsocks server.
@@ -127,7 +129,7 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 
 		conn.Write(data.Bytes())
 		data.Release()
-	})
+	}, udp.DispatcherPolicy(s.policyManager))
 	defer udpServer.RemoveRay()
 
 	inbound := session.InboundFromContext(ctx)
@@ -164,6 +166,7 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 						Status: log.AccessRejected,
 						Reason: err,
 					})
+					s.authFailures.Record(ctx, "shadowsocks", err)
 				}
 				payload.Release()
 				continue
@@ -212,6 +215,7 @@ func (s *Server) handleConnection(ctx context.Context, conn stat.Connection, dis
 			Status: log.AccessRejected,
 			Reason: err,
 		})
+		s.authFailures.Record(ctx, "shadowsocks", err)
 		return errors.New("failed to create request from: ", conn.RemoteAddr()).Base(err)
 	}
 	conn.SetReadDeadline(time.Time{})
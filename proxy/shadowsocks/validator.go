@@ -47,7 +47,7 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 // Del a Shadowsocks user with a non-empty Email.
 func (v *Validator) Del(email string) error {
 	if email == "" {
-		return errors.New("Email must not be empty.")
+		return errors.New("Email must not be empty.").WithCode(errors.CodeInvalidConfig)
 	}
 
 	v.Lock()
@@ -63,7 +63,7 @@ func (v *Validator) Del(email string) error {
 	}
 
 	if idx == -1 {
-		return errors.New("User ", email, " not found.")
+		return errors.New("User ", email, " not found.").WithCode(errors.CodeNotFound)
 	}
 	ulen := len(v.users)
 
@@ -20,6 +20,7 @@ import (
 	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/singbridge"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
@@ -34,6 +35,8 @@ type Inbound struct {
 	service  shadowsocks.Service
 	email    string
 	level    int
+
+	authFailures proxy.AuthFailures
 }
 
 func NewServer(ctx context.Context, config *ServerConfig) (*Inbound, error) {
@@ -77,7 +80,12 @@ func (i *Inbound) Process(ctx context.Context, network net.Network, connection s
 	ctx = session.ContextWithDispatcher(ctx, dispatcher)
 
 	if network == net.Network_TCP {
-		return singbridge.ReturnError(i.service.NewConnection(ctx, connection, metadata))
+		err := i.service.NewConnection(ctx, connection, metadata)
+		// the user is set once the service hands the authenticated connection over
+		if err != nil && inbound.User == nil {
+			i.authFailures.Record(ctx, "shadowsocks-2022", err)
+		}
+		return singbridge.ReturnError(err)
 	} else {
 		reader := buf.NewReader(connection)
 		pc := &natPacketConn{connection}
@@ -92,6 +100,7 @@ func (i *Inbound) Process(ctx context.Context, network net.Network, connection s
 				buffer.Release()
 				err = i.service.NewPacket(ctx, pc, packet, metadata)
 				if err != nil {
+					i.authFailures.Record(ctx, "shadowsocks-2022", err)
 					packet.Release()
 					buf.ReleaseMulti(mb)
 					return err
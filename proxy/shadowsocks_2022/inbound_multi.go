@@ -25,6 +25,7 @@ import (
 	"github.com/xtls/xray-core/common/singbridge"
 	"github.com/xtls/xray-core/common/uuid"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
@@ -39,6 +40,8 @@ type MultiUserInbound struct {
 	networks []net.Network
 	users    []*protocol.MemoryUser
 	service  *shadowaead_2022.MultiService[int]
+
+	authFailures proxy.AuthFailures
 }
 
 func NewMultiServer(ctx context.Context, config *MultiUserServerConfig) (*MultiUserInbound, error) {
@@ -97,7 +100,7 @@ func (i *MultiUserInbound) AddUser(ctx context.Context, u *protocol.MemoryUser)
 	if u.Email != "" {
 		for idx := range i.users {
 			if i.users[idx].Email == u.Email {
-				return errors.New("User ", u.Email, " already exists.")
+				return errors.New("User ", u.Email, " already exists.").WithCode(errors.CodeAlreadyExists)
 			}
 		}
 	}
@@ -116,7 +119,7 @@ func (i *MultiUserInbound) AddUser(ctx context.Context, u *protocol.MemoryUser)
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (i *MultiUserInbound) RemoveUser(ctx context.Context, email string) error {
 	if email == "" {
-		return errors.New("Email must not be empty.")
+		return errors.New("Email must not be empty.").WithCode(errors.CodeInvalidConfig)
 	}
 
 	i.Lock()
@@ -131,7 +134,7 @@ func (i *MultiUserInbound) RemoveUser(ctx context.Context, email string) error {
 	}
 
 	if idx == -1 {
-		return errors.New("User ", email, " not found.")
+		return errors.New("User ", email, " not found.").WithCode(errors.CodeNotFound)
 	}
 
 	ulen := len(i.users)
@@ -200,7 +203,12 @@ func (i *MultiUserInbound) Process(ctx context.Context, network net.Network, con
 	ctx = session.ContextWithDispatcher(ctx, dispatcher)
 
 	if network == net.Network_TCP {
-		return singbridge.ReturnError(i.service.NewConnection(ctx, connection, metadata))
+		err := i.service.NewConnection(ctx, connection, metadata)
+		// the user is set once the service hands the authenticated connection over
+		if err != nil && inbound.User == nil {
+			i.authFailures.Record(ctx, "shadowsocks-2022", err)
+		}
+		return singbridge.ReturnError(err)
 	} else {
 		reader := buf.NewReader(connection)
 		pc := &natPacketConn{connection}
@@ -215,6 +223,7 @@ func (i *MultiUserInbound) Process(ctx context.Context, network net.Network, con
 				buffer.Release()
 				err = i.service.NewPacket(ctx, pc, packet, metadata)
 				if err != nil {
+					i.authFailures.Record(ctx, "shadowsocks-2022", err)
 					packet.Release()
 					buf.ReleaseMulti(mb)
 					return err
@@ -23,6 +23,7 @@ import (
 	"github.com/xtls/xray-core/common/singbridge"
 	"github.com/xtls/xray-core/common/uuid"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/stat"
 )
 
@@ -36,6 +37,7 @@ type RelayInbound struct {
 	networks     []net.Network
 	destinations []*RelayDestination
 	service      *shadowaead_2022.RelayService[int]
+	authFailures proxy.AuthFailures
 }
 
 func NewRelayServer(ctx context.Context, config *RelayServerConfig) (*RelayInbound, error) {
@@ -98,7 +100,12 @@ func (i *RelayInbound) Process(ctx context.Context, network net.Network, connect
 	ctx = session.ContextWithDispatcher(ctx, dispatcher)
 
 	if network == net.Network_TCP {
-		return singbridge.ReturnError(i.service.NewConnection(ctx, connection, metadata))
+		err := i.service.NewConnection(ctx, connection, metadata)
+		// the user is set once the service hands the authenticated connection over
+		if err != nil && inbound.User == nil {
+			i.authFailures.Record(ctx, "shadowsocks-2022", err)
+		}
+		return singbridge.ReturnError(err)
 	} else {
 		reader := buf.NewReader(connection)
 		pc := &natPacketConn{connection}
@@ -113,6 +120,7 @@ func (i *RelayInbound) Process(ctx context.Context, network net.Network, connect
 				buffer.Release()
 				err = i.service.NewPacket(ctx, pc, packet, metadata)
 				if err != nil {
+					i.authFailures.Record(ctx, "shadowsocks-2022", err)
 					packet.Release()
 					buf.ReleaseMulti(mb)
 					return err
@@ -0,0 +1,53 @@
+package shadowsocks_2022
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	gonet "net"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+)
+
+type failureRecorder struct {
+	sources []net.Address
+}
+
+func (r *failureRecorder) ObserveAuthFailure(source net.Address) {
+	r.sources = append(r.sources, source)
+}
+
+func TestInboundAuthFailure(t *testing.T) {
+	key := make([]byte, 16)
+	common.Must2(rand.Read(key))
+	server, err := NewServer(context.Background(), &ServerConfig{
+		Method: "2022-blake3-aes-128-gcm",
+		Key:    base64.StdEncoding.EncodeToString(key),
+	})
+	common.Must(err)
+
+	recorder := new(failureRecorder)
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Tag:    "ss",
+		Source: net.TCPDestination(net.ParseAddress("192.0.2.1"), 40000),
+	})
+	ctx = session.ContextWithAuthFailureObserver(ctx, recorder)
+
+	conn, client := gonet.Pipe()
+	go func() {
+		// a header sealed with another key
+		header := make([]byte, 16+16+11+16)
+		rand.Read(header)
+		client.Write(header)
+		client.Close()
+	}()
+	if err := server.Process(ctx, net.Network_TCP, conn, nil); err == nil {
+		t.Error("expected the connection with a wrong key to fail")
+	}
+	if len(recorder.sources) != 1 || recorder.sources[0].String() != "192.0.2.1" {
+		t.Error("expected an auth failure of the client, but got ", recorder.sources)
+	}
+}
@@ -162,7 +162,7 @@ func (s *Server) processTCP(ctx context.Context, conn stat.Connection, dispatche
 		}
 		if err := dispatcher.DispatchLink(ctx, dest, &transport.Link{
 			Reader: reader,
-			Writer: buf.NewWriter(conn)},
+			Writer: &proxy.HalfCloseWriter{Writer: buf.NewWriter(conn), Conn: conn}},
 		); err != nil {
 			return errors.New("failed to dispatch request").Base(err)
 		}
@@ -218,7 +218,7 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn stat.Connection, dis
 
 		conn.Write(udpMessage.Bytes())
 		udpMessage.Release()
-	})
+	}, udp.DispatcherIdleTimeout(s.policy().Timeouts.UDPIdle))
 	defer udpServer.RemoveRay()
 
 	inbound := session.InboundFromContext(ctx)
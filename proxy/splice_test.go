@@ -0,0 +1,75 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/common/signal"
+	"github.com/xtls/xray-core/proxy"
+)
+
+// connPair returns the two ends of a connection over the listener.
+func connPair(listener net.Listener) (net.Conn, net.Conn) {
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		accepted <- conn
+	}()
+	conn, err := net.Dial(listener.Addr().Network(), listener.Addr().String())
+	common.Must(err)
+	return conn, <-accepted
+}
+
+func TestCopyRawConnUnix(t *testing.T) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer tcpListener.Close()
+	unixListener, err := net.Listen("unix", filepath.Join(t.TempDir(), "splice.sock"))
+	common.Must(err)
+	defer unixListener.Close()
+
+	// TCP to Unix, like the response of the target to a client connected over a Unix domain socket, and the reverse
+	for _, tcpToUnix := range []bool{true, false} {
+		tcpClient, tcpServer := connPair(tcpListener)
+		unixClient, unixServer := connPair(unixListener)
+		source, reader, writer, sink := tcpClient, tcpServer, unixServer, unixClient
+		if !tcpToUnix {
+			source, reader, writer, sink = unixClient, unixServer, tcpServer, tcpClient
+		}
+
+		payload := make([]byte, 1024*1024)
+		common.Must2(rand.Read(payload))
+		go func() {
+			common.Must2(source.Write(payload))
+			source.Close()
+		}()
+		received := make(chan []byte, 1)
+		go func() {
+			b, _ := io.ReadAll(sink)
+			received <- b
+		}()
+
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{CanSpliceCopy: 1})
+		ctx = session.ContextWithOutbounds(ctx, []*session.Outbound{{CanSpliceCopy: 1}})
+		timer := signal.CancelAfterInactivity(ctx, func() {}, time.Minute)
+		if err := proxy.CopyRawConnIfExist(ctx, reader, writer, buf.Discard, timer, nil); err != nil {
+			t.Error(err)
+		}
+		writer.Close()
+		if b := <-received; !bytes.Equal(b, payload) {
+			t.Error("tcpToUnix ", tcpToUnix, ": received ", len(b), " bytes, not the payload")
+		}
+		reader.Close()
+		sink.Close()
+	}
+}
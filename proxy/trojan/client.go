@@ -7,6 +7,7 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/mux"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/retry"
@@ -15,6 +16,7 @@ import (
 	"github.com/xtls/xray-core/common/task"
 	core "github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -24,6 +26,7 @@ import (
 type Client struct {
 	server        *protocol.ServerSpec
 	policyManager policy.Manager
+	heartbeat     time.Duration
 }
 
 // NewClient create a new trojan client.
@@ -40,6 +43,7 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	client := &Client{
 		server:        server,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		heartbeat:     time.Duration(config.HeartbeatInterval) * time.Second,
 	}
 	return client, nil
 }
@@ -60,7 +64,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	var conn stat.Connection
 
 	err := retry.ExponentialBackoff(5, 100).On(func() error {
-		rawConn, err := dialer.Dial(ctx, server.Destination)
+		rawConn, err := proxy.DialServer(ctx, dialer, server.Destination, server.Alternatives)
 		if err != nil {
 			return err
 		}
@@ -129,7 +133,12 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 			return err.(*errors.Error).AtWarning()
 		}
 
-		if err = buf.Copy(link.Reader, bodyWriter, buf.UpdateActivity(timer)); err != nil {
+		reader := link.Reader
+		if c.heartbeat > 0 && destination.Address.Family().IsDomain() && destination.Address.Domain() == "v1.mux.cool" {
+			// a stream of mux frames is the only payload of Trojan that can carry an empty frame
+			reader = proxy.NewHeartbeatReader(reader, c.heartbeat, func() error { return mux.WriteKeepAlive(bodyWriter) })
+		}
+		if err = buf.Copy(reader, bodyWriter, buf.UpdateActivity(timer)); err != nil {
 			return errors.New("failed to transfer request payload").Base(err).AtInfo()
 		}
 
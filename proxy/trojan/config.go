@@ -7,13 +7,17 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol"
 )
 
 // MemoryAccount is an account type converted from Account.
 type MemoryAccount struct {
-	Password string
-	Key      []byte
+	Password         string
+	Key              []byte
+	DisableUDP       bool
+	AllowedDestPorts *net.PortList
 }
 
 // AsAccount implements protocol.AsAccount.
@@ -21,8 +25,10 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 	password := a.GetPassword()
 	key := hexSha224(password)
 	return &MemoryAccount{
-		Password: password,
-		Key:      key,
+		Password:         password,
+		Key:              key,
+		DisableUDP:       a.DisableUdp,
+		AllowedDestPorts: a.AllowedDestPorts,
 	}, nil
 }
 
@@ -36,10 +42,20 @@ func (a *MemoryAccount) Equals(another protocol.Account) bool {
 
 func (a *MemoryAccount) ToProto() proto.Message {
 	return &Account{
-		Password: a.Password,
+		Password:         a.Password,
+		DisableUdp:       a.DisableUDP,
+		AllowedDestPorts: a.AllowedDestPorts,
 	}
 }
 
+// CheckPort returns an error if the user may not connect to the destination port.
+func (a *MemoryAccount) CheckPort(port net.Port) error {
+	if len(a.AllowedDestPorts.GetRange()) > 0 && !net.PortListFromProto(a.AllowedDestPorts).Contains(port) {
+		return errors.New("destination port ", port, " is not allowed for the user")
+	}
+	return nil
+}
+
 func hexSha224(password string) []byte {
 	buf := make([]byte, 56)
 	hash := sha256.New224()
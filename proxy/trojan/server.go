@@ -21,6 +21,7 @@ import (
 	"github.com/xtls/xray-core/core"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
@@ -39,6 +40,9 @@ type Server struct {
 	validator     *Validator
 	fallbacks     map[string]map[string]map[string]*Fallback // or nil
 	cone          bool
+	// prebuffer is the most bytes read from a client before it is checked, and possibly sent to a fallback.
+	prebuffer    int32
+	authFailures proxy.AuthFailures
 }
 
 // NewServer creates a new trojan inbound handler.
@@ -60,6 +64,10 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 		validator:     validator,
 		cone:          ctx.Value("cone").(bool),
+		prebuffer:     buf.Size,
+	}
+	if config.FallbackMaxPrebuffer > 0 {
+		server.prebuffer = int32(config.FallbackMaxPrebuffer)
 	}
 
 	if config.Fallbacks != nil {
@@ -158,7 +166,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 		return errors.New("unable to set read deadline").Base(err).AtWarning()
 	}
 
-	first := buf.FromBytes(make([]byte, buf.Size))
+	first := buf.FromBytes(make([]byte, s.prebuffer))
 	first.Clear()
 	firstLen, err := first.ReadFrom(conn)
 	if err != nil {
@@ -177,7 +185,11 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	isfb := napfb != nil
 
 	shouldFallback := false
-	if firstLen < 58 || first.Byte(56) != '\r' {
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Banned {
+		// the source is banned, it gets the fallbacks whatever it sends
+		err = errors.New("banned source")
+		shouldFallback = true
+	} else if firstLen < 58 || first.Byte(56) != '\r' {
 		// invalid protocol
 		err = errors.New("not trojan protocol")
 		log.Record(&log.AccessMessage{
@@ -199,6 +211,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 				Status: log.AccessRejected,
 				Reason: err,
 			})
+			s.authFailures.Record(ctx, "trojan", err)
 
 			shouldFallback = true
 		}
@@ -232,9 +245,20 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 	inbound.User = user
 	sessionPolicy = s.policyManager.ForLevel(user.Level)
 
+	account := user.Account.(*MemoryAccount)
 	if destination.Network == net.Network_UDP { // handle udp request
+		if account.DisableUDP {
+			err := errors.New("UDP is disabled for the user")
+			recordDenied(conn.RemoteAddr(), destination, user, err)
+			return errors.New("denied request of ", user.Email).Base(err)
+		}
+		// the ports of UDP are checked by packet, as every packet has its own destination
 		return s.handleUDPPayload(ctx, sessionPolicy, &PacketReader{Reader: clientReader}, &PacketWriter{Writer: conn}, dispatcher)
 	}
+	if err := account.CheckPort(destination.Port); err != nil {
+		recordDenied(conn.RemoteAddr(), destination, user, err)
+		return errors.New("denied request of ", user.Email).Base(err)
+	}
 
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   conn.RemoteAddr(),
@@ -251,7 +275,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 func (s *Server) handleUDPPayload(ctx context.Context, sessionPolicy policy.Session, clientReader *PacketReader, clientWriter *PacketWriter, dispatcher routing.Dispatcher) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
+	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.UDPIdle)
 	defer timer.SetTimeout(0)
 	udpServer := udp.NewDispatcher(dispatcher, func(ctx context.Context, packet *udp_proto.Packet) {
 		udpPayload := packet.Payload
@@ -265,11 +289,12 @@ func (s *Server) handleUDPPayload(ctx context.Context, sessionPolicy policy.Sess
 		} else {
 			timer.Update()
 		}
-	})
+	}, udp.DispatcherIdleTimeout(sessionPolicy.Timeouts.UDPIdle))
 	defer udpServer.RemoveRay()
 
 	inbound := session.InboundFromContext(ctx)
 	user := inbound.User
+	account := user.Account.(*MemoryAccount)
 
 	var dest *net.Destination
 
@@ -309,6 +334,12 @@ func (s *Server) handleUDPPayload(ctx context.Context, sessionPolicy policy.Sess
 				if !s.cone || dest == nil {
 					dest = &destination
 				}
+				if err := account.CheckPort(dest.Port); err != nil {
+					recordDenied(inbound.Source, *dest, user, err)
+					b.Release()
+					buf.ReleaseMulti(mb2)
+					continue
+				}
 
 				udpServer.Dispatch(currentPacketCtx, *dest, b) // first packet
 				for _, payload := range mb2 {
@@ -552,3 +583,14 @@ func (s *Server) fallback(ctx context.Context, err error, sessionPolicy policy.S
 
 	return nil
 }
+
+// recordDenied logs the request of the user to a destination it may not relay to.
+func recordDenied(from interface{}, destination net.Destination, user *protocol.MemoryUser, err error) {
+	log.Record(&log.AccessMessage{
+		From:   from,
+		To:     destination,
+		Status: log.AccessRejected,
+		Reason: err,
+		Email:  user.Email,
+	})
+}
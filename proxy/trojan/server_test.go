@@ -0,0 +1,139 @@
+package trojan
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/policy"
+)
+
+// countingConn counts the bytes the server has read from the client.
+type countingConn struct {
+	net.Conn
+	read atomic.Int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func tcpPair() (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := l.Accept()
+		common.Must(err)
+		accepted <- c
+	}()
+	c, err := net.Dial("tcp", l.Addr().String())
+	common.Must(err)
+	return c, <-accepted
+}
+
+func TestFallbackMaxPrebuffer(t *testing.T) {
+	const prebuffer = 4096
+	const streamSize = 100 * 1024 * 1024
+
+	fallback, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer fallback.Close()
+
+	client, serverConn := tcpPair()
+	conn := &countingConn{Conn: serverConn}
+
+	// the bytes read from the client by the time the fallback is dialed
+	readAtFallback := make(chan int64, 1)
+	received := make(chan int64, 1)
+	go func() {
+		c, err := fallback.Accept()
+		common.Must(err)
+		defer c.Close()
+		readAtFallback <- conn.read.Load()
+		n, _ := io.Copy(io.Discard, c)
+		received <- n
+	}()
+
+	s := &Server{
+		policyManager: policy.DefaultManager{},
+		validator:     new(Validator),
+		fallbacks: map[string]map[string]map[string]*Fallback{
+			"": {"": {"": {Type: "tcp", Dest: fallback.Addr().String()}}},
+		},
+		prebuffer: prebuffer,
+	}
+
+	go func() {
+		chunk := make([]byte, 64*1024)
+		for written := 0; written < streamSize; written += len(chunk) {
+			if _, err := client.Write(chunk); err != nil {
+				break
+			}
+		}
+		client.(*net.TCPConn).CloseWrite()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Process(context.Background(), net.Network_TCP, conn, nil)
+	}()
+
+	if n := <-readAtFallback; n > prebuffer {
+		t.Error("read ", n, " bytes before dialing fallback, more than ", prebuffer)
+	}
+	if n := <-received; n != streamSize {
+		t.Error("fallback received ", n, " bytes, expected ", streamSize)
+	}
+	client.Close()
+	<-done
+}
+
+func TestDeniedDestination(t *testing.T) {
+	account := common.Must2((&Account{
+		Password:         "password",
+		DisableUdp:       true,
+		AllowedDestPorts: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(80), net.SinglePortRange(443)}},
+	}).AsAccount()).(*MemoryAccount)
+	validator := new(Validator)
+	common.Must(validator.Add(&protocol.MemoryUser{Email: "user", Account: account}))
+	s := &Server{
+		policyManager: policy.DefaultManager{},
+		validator:     validator,
+		prebuffer:     8192,
+	}
+
+	for _, target := range []net.Destination{
+		net.TCPDestination(net.DomainAddress("example.com"), 22),
+		net.UDPDestination(net.DomainAddress("example.com"), 443),
+	} {
+		client, serverConn := tcpPair()
+		w := &ConnWriter{Writer: client, Target: target, Account: account}
+		common.Must2(w.Write([]byte("request")))
+
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{})
+		err := s.Process(ctx, net.Network_TCP, serverConn, nil)
+		if err == nil || !strings.Contains(err.Error(), "denied request of user") {
+			t.Error(target, ": expected the request to be denied, but got ", err)
+		}
+		client.Close()
+		serverConn.Close()
+	}
+
+	if err := account.CheckPort(443); err != nil {
+		t.Error(err)
+	}
+	if err := common.Must2((&Account{Password: "password"}).AsAccount()).(*MemoryAccount).CheckPort(22); err != nil {
+		t.Error("expected any port without allowed ports, but got ", err)
+	}
+}
@@ -20,7 +20,7 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 	if u.Email != "" {
 		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
 		if loaded {
-			return errors.New("User ", u.Email, " already exists.")
+			return errors.New("User ", u.Email, " already exists.").WithCode(errors.CodeAlreadyExists)
 		}
 	}
 	v.users.Store(hexString(u.Account.(*MemoryAccount).Key), u)
@@ -30,12 +30,12 @@ func (v *Validator) Add(u *protocol.MemoryUser) error {
 // Del a trojan user with a non-empty Email.
 func (v *Validator) Del(e string) error {
 	if e == "" {
-		return errors.New("Email must not be empty.")
+		return errors.New("Email must not be empty.").WithCode(errors.CodeInvalidConfig)
 	}
 	le := strings.ToLower(e)
 	u, _ := v.email.Load(le)
 	if u == nil {
-		return errors.New("User ", e, " not found.")
+		return errors.New("User ", e, " not found.").WithCode(errors.CodeNotFound)
 	}
 	v.email.Delete(le)
 	v.users.Delete(hexString(u.(*protocol.MemoryUser).Account.(*MemoryAccount).Key))
@@ -0,0 +1,51 @@
+package tun
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/platform"
+)
+
+const (
+	defaultName = "xray0"
+	defaultMTU  = 1500
+)
+
+func (c *Config) mtu() int {
+	if c.Mtu == 0 {
+		return defaultMTU
+	}
+	return int(c.Mtu)
+}
+
+// fd returns the file descriptor of the TUN device passed by the host app,
+// either in the config or in the xray.tun.fd environment variable. It returns 0 if there is none.
+func (c *Config) fd() int {
+	if c.Fd > 0 {
+		return int(c.Fd)
+	}
+	return platform.NewEnvFlag(platform.TunFd).GetValueAsInt(0)
+}
+
+// prefixes parses the addresses of the interface. A bare IP is taken as a single address prefix.
+func (c *Config) prefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.Address))
+	for _, s := range c.Address {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, errors.New("invalid address ", s).Base(err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, errors.New("invalid address ", s).Base(err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
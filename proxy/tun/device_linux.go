@@ -0,0 +1,88 @@
+//go:build linux
+
+package tun
+
+import (
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/vishvananda/netlink"
+	"github.com/xtls/xray-core/common/errors"
+	wgtun "golang.zx2c4.com/wireguard/tun"
+)
+
+// routes that together cover all addresses, and win over a default route of the system
+var autoRoutes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/1"),
+	netip.MustParsePrefix("128.0.0.0/1"),
+	netip.MustParsePrefix("::/1"),
+	netip.MustParsePrefix("8000::/1"),
+}
+
+// openDevice opens the TUN device passed by the host app, or creates one with the addresses and routes of c.
+func openDevice(c *Config) (wgtun.Device, error) {
+	if fd := c.fd(); fd > 0 {
+		// the host app has configured the interface
+		return wgtun.CreateTUNFromFile(os.NewFile(uintptr(fd), "tun"), c.mtu())
+	}
+
+	prefixes, err := c.prefixes()
+	if err != nil {
+		return nil, err
+	}
+	name := c.Name
+	if name == "" {
+		name = defaultName
+	}
+	dev, err := wgtun.CreateTUN(name, c.mtu())
+	if err != nil {
+		return nil, errors.New("failed to create TUN interface ", name).Base(err)
+	}
+	if err := configure(name, prefixes, c.AutoRoute); err != nil {
+		dev.Close()
+		return nil, errors.New("failed to configure TUN interface ", name).Base(err)
+	}
+	return dev, nil
+}
+
+// configure sets the addresses of the interface and brings it up.
+// Its routes are removed by the kernel along with the interface.
+func configure(name string, prefixes []netip.Prefix, autoRoute bool) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	var hasV4, hasV6 bool
+	for _, prefix := range prefixes {
+		addr := &netlink.Addr{IPNet: toIPNet(prefix)}
+		if err := netlink.AddrAdd(link, addr); err != nil {
+			return errors.New("failed to add address ", prefix).Base(err)
+		}
+		hasV4 = hasV4 || prefix.Addr().Is4()
+		hasV6 = hasV6 || prefix.Addr().Is6()
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return err
+	}
+	if !autoRoute {
+		return nil
+	}
+	for _, prefix := range autoRoutes {
+		if prefix.Addr().Is4() && !hasV4 || prefix.Addr().Is6() && !hasV6 {
+			continue
+		}
+		route := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: toIPNet(prefix)}
+		if err := netlink.RouteAdd(route); err != nil {
+			return errors.New("failed to add route ", prefix).Base(err)
+		}
+	}
+	return nil
+}
+
+func toIPNet(prefix netip.Prefix) *net.IPNet {
+	return &net.IPNet{
+		IP:   prefix.Addr().AsSlice(),
+		Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !windows
+
+package tun
+
+import (
+	"os"
+
+	"github.com/xtls/xray-core/common/errors"
+	wgtun "golang.zx2c4.com/wireguard/tun"
+)
+
+// openDevice opens the TUN device passed by the host app. Creating and configuring an interface is only supported on Linux.
+func openDevice(c *Config) (wgtun.Device, error) {
+	if fd := c.fd(); fd > 0 {
+		return wgtun.CreateTUNFromFile(os.NewFile(uintptr(fd), "tun"), c.mtu())
+	}
+	return nil, errors.New("creating a TUN interface is not supported on this platform, pass the fd of an existing one")
+}
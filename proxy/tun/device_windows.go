@@ -0,0 +1,10 @@
+package tun
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	wgtun "golang.zx2c4.com/wireguard/tun"
+)
+
+func openDevice(c *Config) (wgtun.Device, error) {
+	return nil, errors.New("TUN inbound is not supported on Windows yet")
+}
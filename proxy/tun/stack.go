@@ -0,0 +1,186 @@
+package tun
+
+import (
+	"context"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	wgtun "golang.zx2c4.com/wireguard/tun"
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	nicID = 1
+	// deviceOffset is the headroom before each packet that TUN devices need for their own headers.
+	deviceOffset = 16
+)
+
+// connHandler is called with every TCP connection or UDP flow that arrives on the device,
+// and the destination it was sent to.
+type connHandler func(conn stat.Connection, dest net.Destination)
+
+// netStack terminates the TCP and UDP flows of the IP packets of a TUN device.
+// It is the reverse of the netstack of the WireGuard outbound: all destinations are accepted,
+// and each flow is handed to the handler instead of being dialed.
+type netStack struct {
+	dev    wgtun.Device
+	ep     *channel.Endpoint
+	stack  *stack.Stack
+	mtu    int
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newNetStack(dev wgtun.Device, mtu int, handler connHandler) (*netStack, error) {
+	// ICMP is not registered, so that pings are not forwarded anywhere; they are left to the
+	// network layer of the stack, and never reach the handler.
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol},
+	})
+	ep := channel.New(1024, uint32(mtu), "")
+	if err := s.CreateNIC(nicID, ep); err != nil {
+		s.Close()
+		return nil, errors.New("failed to create NIC: ", err.String())
+	}
+	// accept packets to any address, and reply from it
+	s.SetPromiscuousMode(nicID, true)
+	s.SetSpoofing(nicID, true)
+	s.SetRouteTable([]tcpip.Route{
+		{Destination: header.IPv4EmptySubnet, NIC: nicID},
+		{Destination: header.IPv6EmptySubnet, NIC: nicID},
+	})
+
+	sack := tcpip.TCPSACKEnabled(true)
+	s.SetTransportProtocolOption(tcp.ProtocolNumber, &sack)
+	cc := tcpip.CongestionControlOption("cubic")
+	s.SetTransportProtocolOption(tcp.ProtocolNumber, &cc)
+
+	tcpForwarder := tcp.NewForwarder(s, 0, 65535, func(r *tcp.ForwarderRequest) {
+		var wq waiter.Queue
+		id := r.ID()
+		ep, err := r.CreateEndpoint(&wq)
+		if err != nil {
+			errors.LogInfo(context.Background(), "failed to accept TCP connection to ", id.LocalAddress, ":", id.LocalPort, ": ", err.String())
+			r.Complete(true)
+			return
+		}
+		r.Complete(false)
+		// enable tcp keep-alive to prevent hanging connections
+		ep.SocketOptions().SetKeepAlive(true)
+
+		// local address is actually destination
+		go handler(gonet.NewTCPConn(&wq, ep), net.TCPDestination(net.IPAddress(id.LocalAddress.AsSlice()), net.Port(id.LocalPort)))
+	})
+	s.SetTransportProtocolHandler(tcp.ProtocolNumber, tcpForwarder.HandlePacket)
+
+	udpForwarder := udp.NewForwarder(s, func(r *udp.ForwarderRequest) {
+		var wq waiter.Queue
+		id := r.ID()
+		ep, err := r.CreateEndpoint(&wq)
+		if err != nil {
+			errors.LogInfo(context.Background(), "failed to accept UDP flow to ", id.LocalAddress, ":", id.LocalPort, ": ", err.String())
+			return
+		}
+		// prevents hanging connections and ensure timely release
+		ep.SocketOptions().SetLinger(tcpip.LingerOption{
+			Enabled: true,
+			Timeout: 15 * time.Second,
+		})
+
+		go handler(gonet.NewUDPConn(&wq, ep), net.UDPDestination(net.IPAddress(id.LocalAddress.AsSlice()), net.Port(id.LocalPort)))
+	})
+	s.SetTransportProtocolHandler(udp.ProtocolNumber, udpForwarder.HandlePacket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &netStack{
+		dev:    dev,
+		ep:     ep,
+		stack:  s,
+		mtu:    mtu,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go n.readDevice()
+	go n.writeDevice()
+	return n, nil
+}
+
+// readDevice injects the packets read from the device into the stack.
+func (n *netStack) readDevice() {
+	batch := n.dev.BatchSize()
+	bufs := make([][]byte, batch)
+	for i := range bufs {
+		bufs[i] = make([]byte, deviceOffset+n.mtu)
+	}
+	sizes := make([]int, batch)
+	for {
+		count, err := n.dev.Read(bufs, sizes, deviceOffset)
+		for i := 0; i < count; i++ {
+			n.inject(bufs[i][deviceOffset : deviceOffset+sizes[i]])
+		}
+		if err != nil {
+			if n.ctx.Err() == nil {
+				errors.LogInfoInner(context.Background(), err, "failed to read from TUN device")
+			}
+			return
+		}
+	}
+}
+
+func (n *netStack) inject(packet []byte) {
+	if len(packet) == 0 {
+		return
+	}
+	var proto tcpip.NetworkProtocolNumber
+	switch header.IPVersion(packet) {
+	case header.IPv4Version:
+		proto = header.IPv4ProtocolNumber
+	case header.IPv6Version:
+		proto = header.IPv6ProtocolNumber
+	default:
+		return
+	}
+	pkb := stack.NewPacketBuffer(stack.PacketBufferOptions{Payload: buffer.MakeWithData(packet)})
+	n.ep.InjectInbound(proto, pkb)
+	pkb.DecRef()
+}
+
+// writeDevice writes the packets sent by the stack to the device.
+func (n *netStack) writeDevice() {
+	for {
+		pkt := n.ep.ReadContext(n.ctx)
+		if pkt == nil {
+			return
+		}
+		view := pkt.ToView()
+		pkt.DecRef()
+
+		b := make([]byte, deviceOffset+view.Size())
+		view.Read(b[deviceOffset:])
+		view.Release()
+		if _, err := n.dev.Write([][]byte{b}, deviceOffset); err != nil && n.ctx.Err() == nil {
+			errors.LogDebugInner(context.Background(), err, "failed to write to TUN device")
+		}
+	}
+}
+
+// Close stops the stack and closes the device.
+func (n *netStack) Close() error {
+	n.cancel()
+	n.ep.Close()
+	n.stack.Close()
+	return n.dev.Close()
+}
@@ -0,0 +1,155 @@
+package tun
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	wgtun "golang.zx2c4.com/wireguard/tun"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/checksum"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+)
+
+// memDevice is a TUN device whose packets are sent and received over channels.
+type memDevice struct {
+	in     chan []byte
+	out    chan []byte
+	closed chan struct{}
+}
+
+func newMemDevice() *memDevice {
+	return &memDevice{
+		in:     make(chan []byte, 16),
+		out:    make(chan []byte, 16),
+		closed: make(chan struct{}),
+	}
+}
+
+func (d *memDevice) File() *os.File { return nil }
+
+func (d *memDevice) Read(bufs [][]byte, sizes []int, offset int) (int, error) {
+	select {
+	case p := <-d.in:
+		sizes[0] = copy(bufs[0][offset:], p)
+		return 1, nil
+	case <-d.closed:
+		return 0, os.ErrClosed
+	}
+}
+
+func (d *memDevice) Write(bufs [][]byte, offset int) (int, error) {
+	for _, b := range bufs {
+		d.out <- append([]byte(nil), b[offset:]...)
+	}
+	return len(bufs), nil
+}
+
+func (d *memDevice) MTU() (int, error)          { return 1500, nil }
+func (d *memDevice) Name() (string, error)      { return "mem", nil }
+func (d *memDevice) Events() <-chan wgtun.Event { return nil }
+func (d *memDevice) BatchSize() int             { return 1 }
+func (d *memDevice) Close() error               { close(d.closed); return nil }
+
+func ipv4Packet(src, dst netip.Addr, proto tcpip.TransportProtocolNumber, payload []byte) []byte {
+	b := make([]byte, header.IPv4MinimumSize+len(payload))
+	ip := header.IPv4(b)
+	ip.Encode(&header.IPv4Fields{
+		TotalLength: uint16(len(b)),
+		TTL:         64,
+		Protocol:    uint8(proto),
+		SrcAddr:     tcpip.AddrFrom4(src.As4()),
+		DstAddr:     tcpip.AddrFrom4(dst.As4()),
+	})
+	ip.SetChecksum(^ip.CalculateChecksum())
+	copy(b[header.IPv4MinimumSize:], payload)
+	return b
+}
+
+func udpPacket(src, dst netip.AddrPort, data []byte) []byte {
+	u := header.UDP(make([]byte, header.UDPMinimumSize+len(data)))
+	u.Encode(&header.UDPFields{
+		SrcPort: src.Port(),
+		DstPort: dst.Port(),
+		Length:  uint16(len(u)),
+	})
+	copy(u.Payload(), data)
+	xsum := header.PseudoHeaderChecksum(header.UDPProtocolNumber, tcpip.AddrFrom4(src.Addr().As4()), tcpip.AddrFrom4(dst.Addr().As4()), uint16(len(u)))
+	u.SetChecksum(^u.CalculateChecksum(checksum.Checksum(data, xsum)))
+	return ipv4Packet(src.Addr(), dst.Addr(), header.UDPProtocolNumber, u)
+}
+
+func icmpEcho(src, dst netip.Addr) []byte {
+	icmp := header.ICMPv4(make([]byte, header.ICMPv4MinimumSize+4))
+	icmp.SetType(header.ICMPv4Echo)
+	icmp.SetIdent(1)
+	icmp.SetSequence(1)
+	copy(icmp.Payload(), "ping")
+	icmp.SetChecksum(^checksum.Checksum(icmp, 0))
+	return ipv4Packet(src, dst, header.ICMPv4ProtocolNumber, icmp)
+}
+
+func TestNetStackUDP(t *testing.T) {
+	type flow struct {
+		conn stat.Connection
+		dest net.Destination
+	}
+	flows := make(chan flow, 1)
+	dev := newMemDevice()
+	s, err := newNetStack(dev, 1500, func(conn stat.Connection, dest net.Destination) {
+		flows <- flow{conn, dest}
+	})
+	common.Must(err)
+	defer s.Close()
+
+	client := netip.MustParseAddrPort("172.19.0.2:40000")
+	server := netip.MustParseAddrPort("1.2.3.4:53")
+
+	// ICMP is not forwarded, and must not break the stack
+	dev.in <- icmpEcho(client.Addr(), server.Addr())
+	dev.in <- udpPacket(client, server, []byte("query"))
+
+	var f flow
+	select {
+	case f = <-flows:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no UDP flow")
+	}
+	defer f.conn.Close()
+	if f.dest != net.UDPDestination(net.ParseAddress("1.2.3.4"), 53) {
+		t.Error("unexpected destination ", f.dest)
+	}
+
+	b := make([]byte, 64)
+	n, err := f.conn.Read(b)
+	common.Must(err)
+	if string(b[:n]) != "query" {
+		t.Error("unexpected payload ", string(b[:n]))
+	}
+
+	common.Must2(f.conn.Write([]byte("answer")))
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case p := <-dev.out:
+			ip := header.IPv4(p)
+			if ip.Protocol() != uint8(header.UDPProtocolNumber) {
+				continue
+			}
+			if ip.SourceAddress() != tcpip.AddrFrom4(server.Addr().As4()) {
+				t.Error("reply is not sent from the original destination: ", ip.SourceAddress())
+			}
+			if u := header.UDP(ip.Payload()); !bytes.Equal(u.Payload(), []byte("answer")) || u.DestinationPort() != client.Port() {
+				t.Error("unexpected reply ", u.DestinationPort(), " ", string(u.Payload()))
+			}
+			return
+		case <-deadline:
+			t.Fatal("no reply on the device")
+		}
+	}
+}
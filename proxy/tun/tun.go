@@ -0,0 +1,135 @@
+// Package tun implements an inbound that reads IP packets from a TUN device, and turns their TCP and UDP flows into sessions.
+package tun
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/policy"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		h := new(Handler)
+		err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+			return h.Init(config.(*Config), pm)
+		})
+		return h, err
+	}))
+}
+
+// Handler is the TUN inbound.
+type Handler struct {
+	config        *Config
+	policyManager policy.Manager
+
+	access sync.Mutex
+	stack  *netStack
+}
+
+// Init initializes the Handler with necessary parameters.
+func (h *Handler) Init(config *Config, pm policy.Manager) error {
+	if _, err := config.prefixes(); err != nil {
+		return err
+	}
+	h.config = config
+	h.policyManager = pm
+	return nil
+}
+
+// Network implements proxy.Inbound. Connections come from the device, so no listener is needed.
+func (h *Handler) Network() []net.Network {
+	return nil
+}
+
+// Serve implements proxy.DeviceInbound.
+func (h *Handler) Serve(handler func(conn stat.Connection, dest net.Destination)) error {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.stack != nil {
+		return errors.New("TUN device is already open")
+	}
+	dev, err := openDevice(h.config)
+	if err != nil {
+		return err
+	}
+	s, err := newNetStack(dev, h.config.mtu(), handler)
+	if err != nil {
+		dev.Close()
+		return err
+	}
+	h.stack = s
+	if name, err := dev.Name(); err == nil {
+		errors.LogInfo(context.Background(), "TUN inbound started on ", name)
+	}
+	return nil
+}
+
+// Close implements proxy.DeviceInbound.
+func (h *Handler) Close() error {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.stack == nil {
+		return nil
+	}
+	err := h.stack.Close()
+	h.stack = nil
+	return err
+}
+
+// Process implements proxy.Inbound. The destination of the connection is the target of the first outbound in ctx.
+func (h *Handler) Process(ctx context.Context, network net.Network, conn stat.Connection, dispatcher routing.Dispatcher) error {
+	var dest net.Destination
+	if outbounds := session.OutboundsFromContext(ctx); len(outbounds) > 0 {
+		dest = outbounds[0].Target
+	}
+	if !dest.IsValid() {
+		return errors.New("unable to get destination")
+	}
+
+	inbound := session.InboundFromContext(ctx)
+	inbound.Name = "tun"
+	inbound.CanSpliceCopy = 3
+	inbound.User = &protocol.MemoryUser{
+		Level: h.config.UserLevel,
+	}
+
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   conn.RemoteAddr(),
+		To:     dest,
+		Status: log.AccessAccepted,
+		Reason: "",
+	})
+	errors.LogInfo(ctx, "received request for ", dest)
+
+	var reader buf.Reader
+	var writer buf.Writer
+	if network == net.Network_TCP {
+		reader = buf.NewReader(conn)
+		writer = buf.NewWriter(conn)
+	} else {
+		reader = buf.NewPacketReader(conn)
+		writer = &buf.SequentialWriter{Writer: conn}
+	}
+
+	if err := dispatcher.DispatchLink(ctx, dest, &transport.Link{
+		Reader: reader,
+		Writer: writer,
+	}); err != nil {
+		return errors.New("failed to dispatch request").Base(err)
+	}
+	return nil
+}
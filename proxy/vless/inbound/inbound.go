@@ -78,6 +78,9 @@ type Handler struct {
 	wrapLink               func(ctx context.Context, link *transport.Link) *transport.Link
 	ctx                    context.Context
 	fallbacks              map[string]map[string]map[string]*Fallback // or nil
+	// prebuffer is the most bytes read from a client before it is checked, and possibly sent to a fallback.
+	prebuffer    int32
+	authFailures proxy.AuthFailures
 	// regexps               map[string]*regexp.Regexp       // or nil
 }
 
@@ -95,6 +98,10 @@ func New(ctx context.Context, config *Config, dc dns.Client, validator vless.Val
 		outboundHandlerManager: v.GetFeature(outbound.ManagerType()).(outbound.Manager),
 		wrapLink:               wrapLinkFunc,
 		ctx:                    ctx,
+		prebuffer:              buf.Size,
+	}
+	if config.FallbackMaxPrebuffer > 0 {
+		handler.prebuffer = int32(config.FallbackMaxPrebuffer)
 	}
 
 	if config.Decryption != "" && config.Decryption != "none" {
@@ -282,7 +289,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 		return errors.New("unable to set read deadline").Base(err).AtWarning()
 	}
 
-	first := buf.FromBytes(make([]byte, buf.Size))
+	first := buf.FromBytes(make([]byte, h.prebuffer))
 	first.Clear()
 	firstLen, errR := first.ReadFrom(connection)
 	if errR != nil {
@@ -303,7 +310,13 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	napfb := h.fallbacks
 	isfb := napfb != nil
 
-	if isfb && firstLen < 18 {
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Banned {
+		// the source is banned, it gets the fallbacks whatever it sends
+		if !isfb {
+			return errors.New("banned source ", connection.RemoteAddr())
+		}
+		err = errors.New("banned source")
+	} else if isfb && firstLen < 18 {
 		err = errors.New("fallback directly")
 	} else {
 		userSentID, request, requestAddons, isfb, err = encoding.DecodeRequestHeader(isfb, first, reader, h.validator)
@@ -518,6 +531,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 				Status: log.AccessRejected,
 				Reason: err,
 			})
+			h.authFailures.Record(ctx, "vless", err)
 			err = errors.New("invalid request from ", connection.RemoteAddr()).Base(err).AtInfo()
 		}
 		return err
@@ -620,6 +634,15 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 	}
 	clientWriter := encoding.EncodeBodyAddons(bufferWriter, request, requestAddons, trafficState, false, ctx, connection, nil)
 	bufferWriter.SetFlushNext()
+	if request.Command == protocol.RequestCommandTCP {
+		clientWriter = &proxy.HalfCloseWriter{
+			Writer: clientWriter,
+			Conn:   connection,
+			Direct: func() bool {
+				return trafficState.Inbound.DownlinkWriterDirectCopy
+			},
+		}
+	}
 
 	if request.Command == protocol.RequestCommandRvs {
 		r, err := h.GetReverse(account)
@@ -656,7 +679,7 @@ func (r *Reverse) NewMux(ctx context.Context, link *transport.Link) error {
 	if err != nil {
 		return errors.New("failed to create mux client worker").Base(err).AtWarning()
 	}
-	worker, err := reverse.NewPortalWorker(muxClient)
+	worker, err := reverse.NewPortalWorker(muxClient, "")
 	if err != nil {
 		return errors.New("failed to create portal worker").Base(err).AtWarning()
 	}
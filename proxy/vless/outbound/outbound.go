@@ -52,6 +52,7 @@ type Handler struct {
 	cone          bool
 	encryption    *encryption.ClientInstance
 	reverse       *Reverse
+	heartbeat     time.Duration
 }
 
 // New creates a new VLess outbound handler.
@@ -69,6 +70,7 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		server:        server,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 		cone:          ctx.Value("cone").(bool),
+		heartbeat:     time.Duration(config.HeartbeatInterval) * time.Second,
 	}
 
 	a := handler.server.User.Account.(*vless.MemoryAccount)
@@ -130,7 +132,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 
 	if err := retry.ExponentialBackoff(5, 200).On(func() error {
 		var err error
-		conn, err = dialer.Dial(ctx, rec.Destination)
+		conn, err = proxy.DialServer(ctx, dialer, rec.Destination, rec.Alternatives)
 		if err != nil {
 			return err
 		}
@@ -267,6 +269,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 
 		// default: serverWriter := bufferWriter
 		serverWriter := encoding.EncodeBodyAddons(bufferWriter, request, requestAddons, trafficState, true, ctx, conn, ob)
+		frameWriter := serverWriter
 		if request.Command == protocol.RequestCommandMux && request.Port == 666 {
 			serverWriter = xudp.NewPacketWriter(serverWriter, target, xudp.GetGlobalID(ctx))
 		}
@@ -305,14 +308,27 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 				}
 			}
 		}
-		err := buf.Copy(clientReader, serverWriter, buf.UpdateActivity(timer))
+		reader := clientReader
+		if h.heartbeat > 0 {
+			var heartbeat func() error
+			if request.Command == protocol.RequestCommandMux {
+				// the mux frames of the payload are written whole, and a KeepAlive frame goes in between
+				heartbeat = func() error { return mux.WriteKeepAlive(frameWriter) }
+			} else if visionWriter, ok := frameWriter.(*proxy.VisionWriter); ok {
+				heartbeat = visionWriter.Heartbeat
+			}
+			if heartbeat != nil {
+				reader = proxy.NewHeartbeatReader(clientReader, h.heartbeat, heartbeat)
+			}
+		}
+		err := buf.Copy(reader, serverWriter, buf.UpdateActivity(timer))
 		if err != nil {
 			return errors.New("failed to transfer request payload").Base(err).AtInfo()
 		}
 
 		// Indicates the end of request payload.
-		switch requestAddons.Flow {
-		default:
+		if request.Command == protocol.RequestCommandTCP {
+			proxy.CloseWrite(conn, trafficState.Outbound.UplinkWriterDirectCopy)
 		}
 		return nil
 	}
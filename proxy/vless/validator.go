@@ -36,7 +36,7 @@ func (v *MemoryValidator) Add(u *protocol.MemoryUser) error {
 	if u.Email != "" {
 		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
 		if loaded {
-			return errors.New("User ", u.Email, " already exists.")
+			return errors.New("User ", u.Email, " already exists.").WithCode(errors.CodeAlreadyExists)
 		}
 	}
 	v.users.Store(ProcessUUID(u.Account.(*MemoryAccount).ID.UUID()), u)
@@ -46,12 +46,12 @@ func (v *MemoryValidator) Add(u *protocol.MemoryUser) error {
 // Del a VLESS user with a non-empty Email.
 func (v *MemoryValidator) Del(e string) error {
 	if e == "" {
-		return errors.New("Email must not be empty.")
+		return errors.New("Email must not be empty.").WithCode(errors.CodeInvalidConfig)
 	}
 	le := strings.ToLower(e)
 	u, _ := v.email.Load(le)
 	if u == nil {
-		return errors.New("User ", e, " not found.")
+		return errors.New("User ", e, " not found.").WithCode(errors.CodeNotFound)
 	}
 	v.email.Delete(le)
 	v.users.Delete(ProcessUUID(u.(*protocol.MemoryUser).Account.(*MemoryAccount).ID.UUID()))
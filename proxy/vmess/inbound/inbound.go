@@ -21,6 +21,7 @@ import (
 	feature_inbound "github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
 	"github.com/xtls/xray-core/proxy/vmess"
 	"github.com/xtls/xray-core/proxy/vmess/encoding"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -107,6 +108,7 @@ type Handler struct {
 	clients               *vmess.TimedUserValidator
 	usersByEmail          *userByEmail
 	sessionHistory        *encoding.SessionHistory
+	authFailures          proxy.AuthFailures
 }
 
 // New creates a new VMess inbound handler.
@@ -168,17 +170,17 @@ func (h *Handler) GetUsersCount(context.Context) int64 {
 
 func (h *Handler) AddUser(ctx context.Context, user *protocol.MemoryUser) error {
 	if len(user.Email) > 0 && !h.usersByEmail.Add(user) {
-		return errors.New("User ", user.Email, " already exists.")
+		return errors.New("User ", user.Email, " already exists.").WithCode(errors.CodeAlreadyExists)
 	}
 	return h.clients.Add(user)
 }
 
 func (h *Handler) RemoveUser(ctx context.Context, email string) error {
 	if email == "" {
-		return errors.New("Email must not be empty.")
+		return errors.New("Email must not be empty.").WithCode(errors.CodeInvalidConfig)
 	}
 	if !h.usersByEmail.Remove(email) {
-		return errors.New("User ", email, " not found.")
+		return errors.New("User ", email, " not found.").WithCode(errors.CodeNotFound)
 	}
 	h.clients.Remove(email)
 	return nil
@@ -249,6 +251,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection s
 				Status: log.AccessRejected,
 				Reason: err,
 			})
+			h.authFailures.Record(ctx, "vmess", err)
 			err = errors.New("invalid request from ", connection.RemoteAddr()).Base(err).AtInfo()
 		}
 		return err
@@ -13,15 +13,18 @@ import (
 	xnet "github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/udp"
+	"golang.org/x/net/ipv4"
 )
 
 type netReadInfo struct {
 	// status
 	waiter sync.WaitGroup
 	// param
-	buff []byte
+	buffs [][]byte
 	// result
-	bytes    int
+	sizes    []int
+	count    int
 	endpoint conn.Endpoint
 	err      error
 }
@@ -32,6 +35,7 @@ type netBind struct {
 	dnsOption dns.IPOption
 
 	workers   int
+	batchSize int
 	readQueue chan *netReadInfo
 }
 
@@ -75,6 +79,9 @@ func (n *netBind) ParseEndpoint(s string) (conn.Endpoint, error) {
 
 // BatchSize implements conn.Bind
 func (bind *netBind) BatchSize() int {
+	if bind.batchSize > 1 {
+		return bind.batchSize
+	}
 	return 1
 }
 
@@ -91,13 +98,16 @@ func (bind *netBind) Open(uport uint16) ([]conn.ReceiveFunc, uint16, error) {
 		}()
 
 		r := &netReadInfo{
-			buff: bufs[0],
+			buffs: bufs,
+			sizes: sizes,
 		}
 		r.waiter.Add(1)
 		bind.readQueue <- r
 		r.waiter.Wait() // wait read goroutine done, or we will miss the result
-		sizes[0], eps[0] = r.bytes, r.endpoint
-		return 1, r.err
+		for i := 0; i < r.count; i++ {
+			eps[i] = r.endpoint
+		}
+		return r.count, r.err
 	}
 	workers := bind.workers
 	if workers <= 0 {
@@ -125,6 +135,15 @@ type netBindClient struct {
 	ctx      context.Context
 	dialer   internet.Dialer
 	reserved []byte
+	offload  bool
+}
+
+// udpGSO reports whether the udpGSO sockopt is set on the outbound that dialer dials with.
+func udpGSO(dialer internet.Dialer) bool {
+	d, ok := dialer.(interface {
+		StreamSettings() *internet.MemoryStreamConfig
+	})
+	return ok && d.StreamSettings() != nil && d.StreamSettings().SocketSettings.GetUdpGso()
 }
 
 func (bind *netBindClient) connectTo(endpoint *netEndpoint) error {
@@ -132,23 +151,45 @@ func (bind *netBindClient) connectTo(endpoint *netEndpoint) error {
 	if err != nil {
 		return err
 	}
+	batch, dest := batchConn(c)
+	if batch != nil && bind.offload {
+		batch.EnableOffload()
+	}
 	endpoint.conn = c
+	endpoint.batch = batch
+	endpoint.batchDest = dest
 
 	go func(readQueue <-chan *netReadInfo, endpoint *netEndpoint) {
+		var msgs []ipv4.Message
 		for {
 			v, ok := <-readQueue
 			if !ok {
 				return
 			}
-			i, err := c.Read(v.buff)
 
-			if i > 3 {
-				v.buff[1] = 0
-				v.buff[2] = 0
-				v.buff[3] = 0
+			var err error
+			if batch != nil && len(v.buffs) > 1 {
+				msgs = msgs[:0]
+				for _, b := range v.buffs {
+					msgs = append(msgs, ipv4.Message{Buffers: [][]byte{b}})
+				}
+				v.count, err = batch.ReadBatch(msgs)
+				for i := 0; i < v.count; i++ {
+					v.sizes[i] = msgs[i].N
+				}
+			} else {
+				v.sizes[0], err = c.Read(v.buffs[0])
+				v.count = 1
+			}
+
+			for i := 0; i < v.count; i++ {
+				if v.sizes[i] > 3 {
+					v.buffs[i][1] = 0
+					v.buffs[i][2] = 0
+					v.buffs[i][3] = 0
+				}
 			}
 
-			v.bytes = i
 			v.endpoint = endpoint
 			v.err = err
 			v.waiter.Done()
@@ -181,6 +222,17 @@ func (bind *netBindClient) Send(buff [][]byte, endpoint conn.Endpoint) error {
 		if len(buff) > 3 && len(bind.reserved) == 3 {
 			copy(buff[1:], bind.reserved)
 		}
+	}
+
+	if nend.batch != nil && len(buff) > 1 {
+		msgs := make([]ipv4.Message, len(buff))
+		for i, b := range buff {
+			msgs[i] = ipv4.Message{Buffers: [][]byte{b}, Addr: nend.batchDest}
+		}
+		return nend.batch.WriteBatch(msgs)
+	}
+
+	for _, buff := range buff {
 		if _, err = nend.conn.Write(buff); err != nil {
 			return err
 		}
@@ -188,6 +240,20 @@ func (bind *netBindClient) Send(buff [][]byte, endpoint conn.Endpoint) error {
 	return nil
 }
 
+// batchConn returns batched I/O on the UDP socket under c, and the address to send to,
+// if c is a plain UDP connection. Connections with stats counters are left alone.
+func batchConn(c net.Conn) (*udp.BatchConn, net.Addr) {
+	switch c := c.(type) {
+	case *net.UDPConn:
+		return udp.NewBatchConn(c), nil
+	case *internet.PacketConnWrapper:
+		if uc, ok := c.Conn.(*net.UDPConn); ok {
+			return udp.NewBatchConn(uc), c.Dest
+		}
+	}
+	return nil, nil
+}
+
 type netBindServer struct {
 	netBind
 }
@@ -216,6 +282,10 @@ func (bind *netBindServer) Send(buff [][]byte, endpoint conn.Endpoint) error {
 type netEndpoint struct {
 	dst  xnet.Destination
 	conn net.Conn
+
+	// batched I/O on conn, if it is a plain UDP socket
+	batch     *udp.BatchConn
+	batchDest net.Addr
 }
 
 func (netEndpoint) ClearSrc() {}
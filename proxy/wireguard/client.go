@@ -42,6 +42,7 @@ import (
 	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/udp"
 )
 
 // Handler is an outbound connection that silently swallow the entire payload.
@@ -121,8 +122,10 @@ func (h *Handler) processWireGuard(ctx context.Context, dialer internet.Dialer)
 				IPv4Enable: h.hasIPv4,
 				IPv6Enable: h.hasIPv6,
 			},
-			workers: int(h.conf.NumWorkers),
+			workers:   int(h.conf.NumWorkers),
+			batchSize: udp.DefaultBatchSize,
 		},
+		offload:  udpGSO(dialer),
 		ctx:      ctx,
 		dialer:   dialer,
 		reserved: h.conf.Reserved,
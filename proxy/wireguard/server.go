@@ -105,9 +105,10 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn stat.Con
 			if !ok {
 				return nil
 			}
-			i, err := payload.Read(v.buff)
+			i, err := payload.Read(v.buffs[0])
 
-			v.bytes = i
+			v.sizes[0] = i
+			v.count = 1
 			v.endpoint = nep
 			v.err = err
 			v.waiter.Done()
@@ -2,8 +2,10 @@ package scenarios
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -29,8 +31,12 @@ import (
 	"github.com/xtls/xray-core/proxy/vmess/inbound"
 	"github.com/xtls/xray-core/proxy/vmess/outbound"
 	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/tls"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/testing/protocmp"
 )
 
@@ -476,6 +482,22 @@ func TestCommanderAddRemoveUser(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	_, err = hsClient.AlterInbound(context.Background(), &command.AlterInboundRequest{
+		Tag: "v",
+		Operation: serial.ToTypedMessage(
+			&command.AddUserOperation{
+				User: &protocol.User{
+					Email: "test@example.com",
+					Account: serial.ToTypedMessage(&vmess.Account{
+						Id: u2.String(),
+					}),
+				},
+			}),
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatal("expected AlreadyExists, but got ", err)
+	}
+
 	resp, err = hsClient.AlterInbound(context.Background(), &command.AlterInboundRequest{
 		Tag:       "v",
 		Operation: serial.ToTypedMessage(&command.RemoveUserOperation{Email: "test@example.com"}),
@@ -484,6 +506,22 @@ func TestCommanderAddRemoveUser(t *testing.T) {
 	if resp == nil {
 		t.Fatal("nil response")
 	}
+
+	_, err = hsClient.AlterInbound(context.Background(), &command.AlterInboundRequest{
+		Tag:       "v",
+		Operation: serial.ToTypedMessage(&command.RemoveUserOperation{Email: "test@example.com"}),
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("expected NotFound, but got ", err)
+	}
+
+	_, err = hsClient.AlterInbound(context.Background(), &command.AlterInboundRequest{
+		Tag:       "missing",
+		Operation: serial.ToTypedMessage(&command.RemoveUserOperation{Email: "test@example.com"}),
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("expected NotFound, but got ", err)
+	}
 }
 
 func TestCommanderStats(t *testing.T) {
@@ -662,3 +700,868 @@ func TestCommanderStats(t *testing.T) {
 		t.Error("value < 10240*1024: ", sresp.Stat.Value)
 	}
 }
+
+func TestCommanderRuleStats(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	userID := protocol.NewID(uuid.New())
+	serverPort := tcp.PickPort()
+	cmdPort := tcp.PickPort()
+
+	serverConfig := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&stats.Config{}),
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&statscmd.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+					{
+						InboundTag: []string{"vmess"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "direct",
+						},
+						RuleTag: "premium",
+					},
+				},
+			}),
+			serial.ToTypedMessage(&policy.Config{
+				Level: map[uint32]*policy.Policy{
+					0: {
+						Timeout: &policy.Policy_Timeout{
+							UplinkOnly:   &policy.Second{Value: 0},
+							DownlinkOnly: &policy.Second{Value: 0},
+						},
+					},
+					1: {
+						Stats: &policy.Policy_Stats{
+							UserUplink:   true,
+							UserDownlink: true,
+						},
+					},
+				},
+				System: &policy.SystemPolicy{
+					Stats: &policy.SystemPolicy_Stats{
+						InboundUplink: true,
+						RuleTraffic:   true,
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "vmess",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					User: []*protocol.User{
+						{
+							Level: 1,
+							Email: "test",
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+				}),
+			},
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(cmdPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Receiver: &protocol.ServerEndpoint{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    uint32(serverPort),
+						User:    &protocol.User{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id: userID.String(),
+								SecuritySettings: &protocol.SecurityConfig{
+									Type: protocol.SecurityType_AES128_GCM,
+								},
+							}),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	if err != nil {
+		t.Fatal("Failed to create all servers", err)
+	}
+	defer CloseAllServers(servers)
+
+	if err := testTCPConn(clientPort, 10240*1024, time.Second*20)(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+
+	sClient := statscmd.NewStatsServiceClient(cmdConn)
+
+	for _, name := range []string{"rule>>>premium>>>traffic>>>uplink", "rule>>>premium>>>traffic>>>downlink"} {
+		sresp, err := sClient.GetStats(context.Background(), &statscmd.GetStatsRequest{
+			Name:   name,
+			Reset_: true,
+		})
+		common.Must(err)
+		if sresp.Stat.Value != 10240*1024 {
+			t.Error(name, " = ", sresp.Stat.Value)
+		}
+	}
+
+	qresp, err := sClient.QueryStats(context.Background(), &statscmd.QueryStatsRequest{
+		Pattern: "rule>>>",
+	})
+	common.Must(err)
+	for _, stat := range qresp.Stat {
+		if stat.Value != 0 {
+			t.Error(stat.Name, " is not reset: ", stat.Value)
+		}
+	}
+	if len(qresp.Stat) != 2 {
+		t.Error("unexpected rule counters: ", qresp.Stat)
+	}
+}
+
+func TestCommanderAuthFailures(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	serverPort := tcp.PickPort()
+	cmdPort := tcp.PickPort()
+
+	serverConfig := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&stats.Config{}),
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&statscmd.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "vmess",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id: protocol.NewID(uuid.New()).String(),
+							}),
+						},
+					},
+				}),
+			},
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(cmdPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Receiver: &protocol.ServerEndpoint{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    uint32(serverPort),
+						User: &protocol.User{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								// not a user of the server
+								Id: protocol.NewID(uuid.New()).String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	if err != nil {
+		t.Fatal("Failed to create all servers", err)
+	}
+	defer CloseAllServers(servers)
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	stream, err := statscmd.NewStatsServiceClient(cmdConn).SubscribeAuthFailures(ctx, &statscmd.SubscribeAuthFailuresRequest{})
+	common.Must(err)
+
+	events := make(chan *statscmd.AuthFailure, 1)
+	go func() {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Error(err)
+			close(events)
+			return
+		}
+		events <- event
+	}()
+
+	// the subscription may not have reached the server yet, keep failing until an event comes
+	for {
+		if err := testTCPConn(clientPort, 1024, time.Second)(); err == nil {
+			t.Fatal("connection with an unknown user succeeded")
+		}
+		select {
+		case event := <-events:
+			if event == nil {
+				return
+			}
+			if event.Protocol != "vmess" || event.InboundTag != "vmess" || event.Source != "127.0.0.1" || event.Reason == "" {
+				t.Error("unexpected event: ", event)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("no auth failure event")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestCommanderInboundBans(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	serverPort := tcp.PickPort()
+	cmdPort := tcp.PickPort()
+
+	serverConfig := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&command.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "vmess",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					AutoBan: &proxyman.AutoBanConfig{
+						Threshold: 2,
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id: protocol.NewID(uuid.New()).String(),
+							}),
+						},
+					},
+				}),
+			},
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(cmdPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Receiver: &protocol.ServerEndpoint{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    uint32(serverPort),
+						User: &protocol.User{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								// not a user of the server
+								Id: protocol.NewID(uuid.New()).String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	if err != nil {
+		t.Fatal("Failed to create all servers", err)
+	}
+	defer CloseAllServers(servers)
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+	hsClient := command.NewHandlerServiceClient(cmdConn)
+
+	for i := 0; i < 2; i++ {
+		if err := testTCPConn(clientPort, 1024, time.Second)(); err == nil {
+			t.Fatal("connection with an unknown user succeeded")
+		}
+	}
+	// the failures are counted once the server gives up on the requests, after the client
+	var resp *command.GetInboundBansResponse
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		resp, err = hsClient.GetInboundBans(context.Background(), &command.GetInboundBansRequest{Tag: "vmess"})
+		common.Must(err)
+		if len(resp.Bans) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(resp.Bans) != 1 || resp.Bans[0].Ip != "127.0.0.1" || resp.Bans[0].Expire <= time.Now().Unix() {
+		t.Fatal("unexpected bans: ", resp.Bans)
+	}
+
+	// a banned source is closed before the proxy waits for its request
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", serverPort))
+	common.Must(err)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Error("expected the connection of a banned source to be closed, but got ", err)
+	}
+	conn.Close()
+
+	_, err = hsClient.ClearInboundBans(context.Background(), &command.ClearInboundBansRequest{Tag: "vmess"})
+	common.Must(err)
+	resp, err = hsClient.GetInboundBans(context.Background(), &command.GetInboundBansRequest{Tag: "vmess"})
+	common.Must(err)
+	if len(resp.Bans) != 0 {
+		t.Error("unexpected bans after clearing: ", resp.Bans)
+	}
+
+	_, err = hsClient.GetInboundBans(context.Background(), &command.GetInboundBansRequest{Tag: "api"})
+	if status.Code(err) != codes.Unavailable {
+		t.Error("expected UNAVAILABLE for an inbound without auto ban, but got ", err)
+	}
+}
+
+func TestCommanderPauseInbound(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	clientPort := tcp.PickPort()
+	cmdPort := tcp.PickPort()
+
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&command.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "d",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(cmdPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "default-outbound",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(config)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+	hsClient := command.NewHandlerServiceClient(cmdConn)
+
+	// accepted before the pause, and goes on after it
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", clientPort))
+	common.Must(err)
+	defer conn.Close()
+	if err := testTCPConn2(conn, 1024, time.Second*5)(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := hsClient.PauseInbound(context.Background(), &command.PauseInboundRequest{Tag: "d"}); err != nil {
+			t.Fatal("failed to pause inbound: ", err)
+		}
+	}
+	// the listener stays open, but the connections it accepts are closed at once
+	if pausedConn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", clientPort)); err == nil {
+		pausedConn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		if _, err := pausedConn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Error("a paused inbound served a connection")
+		}
+		pausedConn.Close()
+	}
+	if err := testTCPConn2(conn, 1024, time.Second*5)(); err != nil {
+		t.Error("the connection accepted before the pause failed: ", err)
+	}
+	resp, err := hsClient.ListInbounds(context.Background(), &command.ListInboundsRequest{IsOnlyTags: true})
+	common.Must(err)
+	if len(resp.Paused) != 1 || resp.Paused[0] != "d" {
+		t.Error("unexpected paused inbounds: ", resp.Paused)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := hsClient.ResumeInbound(context.Background(), &command.ResumeInboundRequest{Tag: "d"}); err != nil {
+			t.Fatal("failed to resume inbound: ", err)
+		}
+	}
+	if err := testTCPConn(clientPort, 1024, time.Second*5)(); err != nil {
+		t.Error("the resumed inbound failed: ", err)
+	}
+	resp, err = hsClient.ListInbounds(context.Background(), &command.ListInboundsRequest{IsOnlyTags: true})
+	common.Must(err)
+	if len(resp.Paused) != 0 {
+		t.Error("unexpected paused inbounds after resuming: ", resp.Paused)
+	}
+
+	_, err = hsClient.PauseInbound(context.Background(), &command.PauseInboundRequest{Tag: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Error("expected NOT_FOUND for a missing inbound, but got ", err)
+	}
+}
+
+func TestCommanderSecurityPolicy(t *testing.T) {
+	cmdPort := tcp.PickPort()
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&command.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+				},
+			}),
+			serial.ToTypedMessage(&policy.Config{
+				System: &policy.SystemPolicy{
+					Stats: &policy.SystemPolicy_Stats{},
+					Security: &policy.SystemPolicy_Security{
+						MinTlsVersion:  "1.2",
+						ForbidInsecure: true,
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(cmdPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(net.LocalHostIP),
+					Port:     uint32(cmdPort),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "default-outbound",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(config)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+	hsClient := command.NewHandlerServiceClient(cmdConn)
+
+	tlsStream := func(config *tls.Config) *internet.StreamConfig {
+		return &internet.StreamConfig{
+			SecurityType:     serial.GetMessageType(config),
+			SecuritySettings: []*serial.TypedMessage{serial.ToTypedMessage(config)},
+		}
+	}
+	addOutbound := func(tag string, config *tls.Config) error {
+		_, err := hsClient.AddOutbound(context.Background(), &command.AddOutboundRequest{
+			Outbound: &core.OutboundHandlerConfig{
+				Tag:            tag,
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{StreamSettings: tlsStream(config)}),
+				ProxySettings:  serial.ToTypedMessage(&freedom.Config{}),
+			},
+		})
+		return err
+	}
+
+	if err := addOutbound("insecure", &tls.Config{AllowInsecure: true}); status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "streamSettings.tlsSettings.allowInsecure") {
+		t.Error("expected INVALID_ARGUMENT for allowInsecure, but got ", err)
+	}
+	if err := addOutbound("secure", &tls.Config{MinVersion: "1.3"}); err != nil {
+		t.Error("failed to add an outbound that complies with the security policy: ", err)
+	}
+
+	_, err = hsClient.AddInbound(context.Background(), &command.AddInboundRequest{
+		Inbound: &core.InboundHandlerConfig{
+			Tag: "tls10",
+			ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+				PortList:       &net.PortList{Range: []*net.PortRange{net.SinglePortRange(tcp.PickPort())}},
+				Listen:         net.NewIPOrDomain(net.LocalHostIP),
+				StreamSettings: tlsStream(&tls.Config{MinVersion: "1.0"}),
+			}),
+			ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+				Address:  net.NewIPOrDomain(net.LocalHostIP),
+				Port:     uint32(cmdPort),
+				Networks: []net.Network{net.Network_TCP},
+			}),
+		},
+	})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "streamSettings.tlsSettings.minVersion") {
+		t.Error("expected INVALID_ARGUMENT for TLS 1.0, but got ", err)
+	}
+	resp, err := hsClient.ListInbounds(context.Background(), &command.ListInboundsRequest{IsOnlyTags: true})
+	common.Must(err)
+	for _, ib := range resp.Inbounds {
+		if ib.Tag == "tls10" {
+			t.Error("the inbound that violates the security policy is added")
+		}
+	}
+}
+
+func TestCommanderTestOutbound(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	cmdPort := tcp.PickPort()
+	serverPort := tcp.PickPort()
+	userID := protocol.NewID(uuid.New())
+
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*serial.TypedMessage{
+					serial.ToTypedMessage(&command.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(cmdPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+			{
+				Tag: "vmess",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+			{
+				// dials with its own context, so the dial timings of the test are not observed
+				Tag: "mux",
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					MultiplexSettings: &proxyman.MultiplexingConfig{
+						Enabled:     true,
+						Concurrency: 4,
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Receiver: &protocol.ServerEndpoint{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    uint32(serverPort),
+						User: &protocol.User{
+							Account: serial.ToTypedMessage(&vmess.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(config)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+	hsClient := command.NewHandlerServiceClient(cmdConn)
+
+	resp, err := hsClient.TestOutbound(context.Background(), &command.TestOutboundRequest{
+		Tag:    "direct",
+		Target: dest.NetAddr(),
+	})
+	common.Must(err)
+	if resp.FailedStage != "" {
+		t.Error("failed to dial the server: ", resp)
+	}
+
+	resp, err = hsClient.TestOutbound(context.Background(), &command.TestOutboundRequest{
+		Tag:       "mux",
+		Target:    dest.NetAddr(),
+		TimeoutMs: 3000,
+	})
+	common.Must(err)
+	if resp.FailedStage != "" {
+		t.Error("failed to dial the server through Mux: ", resp)
+	}
+
+	// the server echoes the request, so the response starts at once
+	resp, err = hsClient.TestOutbound(context.Background(), &command.TestOutboundRequest{
+		Tag:     "direct",
+		Target:  dest.NetAddr(),
+		HttpGet: true,
+	})
+	common.Must(err)
+	if resp.FailedStage != "" || resp.TotalMs < resp.FirstByteMs {
+		t.Error("failed to get the first byte: ", resp)
+	}
+
+	resp, err = hsClient.TestOutbound(context.Background(), &command.TestOutboundRequest{
+		Tag:       "direct",
+		Target:    fmt.Sprintf("127.0.0.1:%d", tcp.PickPort()),
+		TimeoutMs: 2000,
+	})
+	common.Must(err)
+	if resp.FailedStage != "connect" || resp.Error == "" {
+		t.Error("expected the connect stage to fail: ", resp)
+	}
+
+	_, err = hsClient.TestOutbound(context.Background(), &command.TestOutboundRequest{Tag: "missing", Target: dest.NetAddr()})
+	if status.Code(err) != codes.NotFound {
+		t.Error("expected NOT_FOUND for a missing outbound, but got ", err)
+	}
+	_, err = hsClient.TestOutbound(context.Background(), &command.TestOutboundRequest{Tag: "direct", Target: "example.com"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("expected INVALID_ARGUMENT for a target without port, but got ", err)
+	}
+}
@@ -1,6 +1,8 @@
 package scenarios
 
 import (
+	"encoding/binary"
+	"fmt"
 	"testing"
 	"time"
 
@@ -220,3 +222,100 @@ func TestDokodemoUDP(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDokodemoUDPFlowOrdering(t *testing.T) {
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	serverPort := udp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList:   &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:     net.NewIPOrDomain(net.LocalHostIP),
+					UdpWorkers: 4,
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_UDP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+	server, err := InitializeServerConfig(serverConfig)
+	common.Must(err)
+	defer CloseServer(server)
+	if !WaitConnAvailableWithTest(t, testUDPConn(serverPort, 1024, time.Second*2)) {
+		t.Fatal("server not available")
+	}
+
+	const flows = 16
+	const packets = 500
+	var errg errgroup.Group
+	for i := 0; i < flows; i++ {
+		errg.Go(func() error {
+			conn, err := net.DialUDP("udp", nil, &net.UDPAddr{
+				IP:   []byte{127, 0, 0, 1},
+				Port: int(serverPort),
+			})
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			received := make(chan error, 1)
+			go func() {
+				last := -1
+				count := 0
+				b := make([]byte, 16)
+				for count < packets {
+					conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+					n, err := conn.Read(b)
+					if err != nil {
+						// the rest is lost
+						break
+					}
+					seq := int(binary.BigEndian.Uint32(xor(b[:n])))
+					if seq <= last {
+						received <- fmt.Errorf("packet %d received after %d", seq, last)
+						return
+					}
+					last = seq
+					count++
+				}
+				// UDP may drop packets under the load, but not reorder them
+				if count == 0 {
+					received <- fmt.Errorf("no packets received")
+					return
+				}
+				received <- nil
+			}()
+
+			b := make([]byte, 4)
+			for seq := 0; seq < packets; seq++ {
+				binary.BigEndian.PutUint32(b, uint32(seq))
+				if _, err := conn.Write(b); err != nil {
+					return err
+				}
+				if seq%10 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			return <-received
+		})
+	}
+	if err := errg.Wait(); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,12 +2,17 @@ package scenarios
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
+	gonet "net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/xtls/xray-core/app/dispatcher"
 	"github.com/xtls/xray-core/app/log"
 	"github.com/xtls/xray-core/app/proxyman"
@@ -25,6 +30,7 @@ import (
 	"github.com/xtls/xray-core/proxy/dokodemo"
 	"github.com/xtls/xray-core/proxy/freedom"
 	v2http "github.com/xtls/xray-core/proxy/http"
+	"github.com/xtls/xray-core/proxy/loopback"
 	"github.com/xtls/xray-core/proxy/socks"
 	"github.com/xtls/xray-core/proxy/vmess"
 	"github.com/xtls/xray-core/proxy/vmess/inbound"
@@ -701,3 +707,123 @@ func TestDialXray(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type recordingLogHandler struct {
+	sync.Mutex
+	messages []string
+}
+
+func (h *recordingLogHandler) Handle(msg clog.Message) {
+	h.Lock()
+	defer h.Unlock()
+	h.messages = append(h.messages, msg.String())
+}
+
+func (h *recordingLogHandler) count(substr string) int {
+	h.Lock()
+	defer h.Unlock()
+	n := 0
+	for _, msg := range h.messages {
+		if strings.Contains(msg, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLoopbackSniffOnce(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	serverPort := tcp.PickPort()
+	config := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					SniffingSettings: &proxyman.SniffingConfig{
+						Enabled:             true,
+						DestinationOverride: []string{"tls"},
+						RouteOnly:           true,
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				Tag:           "block",
+				ProxySettings: serial.ToTypedMessage(&blackhole.Config{}),
+			},
+			{
+				Tag:           "loop",
+				ProxySettings: serial.ToTypedMessage(&loopback.Config{InboundTag: "loop-in"}),
+			},
+			{
+				Tag:           "direct",
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						TargetTag:  &router.RoutingRule_Tag{Tag: "loop"},
+						InboundTag: []string{"in"},
+					},
+					{
+						TargetTag:  &router.RoutingRule_Tag{Tag: "direct"},
+						InboundTag: []string{"loop-in"},
+						Protocol:   []string{"tls"},
+					},
+				},
+			}),
+		},
+	}
+
+	handler := &recordingLogHandler{}
+	server, err := core.NewWithOptions(withDefaultApps(config), core.WithLogger(handler))
+	common.Must(err)
+	common.Must(server.Start())
+	defer server.Close()
+
+	// a real ClientHello, for the sniffer to find
+	clientConn, serverConn := gonet.Pipe()
+	go tls.Client(clientConn, &tls.Config{ServerName: "example.com"}).Handshake()
+	hello := make([]byte, 4096)
+	n, err := serverConn.Read(hello)
+	common.Must(err)
+	hello = hello[:n]
+	clientConn.Close()
+	serverConn.Close()
+
+	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: int(serverPort)})
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write(hello))
+	common.Must(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	response := make([]byte, len(hello))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		t.Fatal("the second rule did not route the sniffed connection to direct: ", err)
+	}
+	if r := cmp.Diff(response, xor(hello)); r != "" {
+		t.Error(r)
+	}
+
+	if n := handler.count("sniffed content of protocol tls"); n != 1 {
+		t.Error("sniffed ", n, " times, want once")
+	}
+	if n := handler.count("reusing sniffed protocol tls"); n != 1 {
+		t.Error("reused the sniffed protocol ", n, " times, want once")
+	}
+}
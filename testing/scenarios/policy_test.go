@@ -21,6 +21,7 @@ import (
 	"github.com/xtls/xray-core/proxy/vmess/inbound"
 	"github.com/xtls/xray-core/proxy/vmess/outbound"
 	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/testing/servers/udp"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -248,3 +249,102 @@ func TestZeroBuffer(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestUDPIdle(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	tcpDest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	// the UDP server tells the ports the datagrams come from, which change when the session is renewed
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: []byte{127, 0, 0, 1}})
+	common.Must(err)
+	defer udpConn.Close()
+	sources := make(chan int, 16)
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, addr, err := udpConn.ReadFromUDP(b)
+			if err != nil {
+				return
+			}
+			sources <- addr.Port
+			udpConn.WriteToUDP(xor(b[:n]), addr)
+		}
+	}()
+	udpDest := net.DestinationFromAddr(udpConn.LocalAddr())
+
+	tcpPort := tcp.PickPort()
+	udpPort := udp.PickPort()
+	config := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&policy.Config{
+				Level: map[uint32]*policy.Policy{
+					0: {
+						Timeout: &policy.Policy_Timeout{
+							ConnectionIdle: &policy.Second{Value: 10},
+							UdpIdle:        &policy.Second{Value: 1},
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(tcpPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(tcpDest.Address),
+					Port:     uint32(tcpDest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(udpPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(udpDest.Address),
+					Port:     uint32(udpDest.Port),
+					Networks: []net.Network{net.Network_UDP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(config)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	tcpConn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: int(tcpPort)})
+	common.Must(err)
+	defer tcpConn.Close()
+	clientConn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: []byte{127, 0, 0, 1}, Port: int(udpPort)})
+	common.Must(err)
+	defer clientConn.Close()
+
+	common.Must(testTCPConn2(tcpConn, 1024, time.Second*2)())
+	common.Must(testTCPConn2(clientConn, 1024, time.Second*2)())
+	first := <-sources
+
+	// both sessions idle past udpIdle, but within connIdle
+	time.Sleep(time.Second * 3)
+
+	if err := testTCPConn2(tcpConn, 1024, time.Second*2)(); err != nil {
+		t.Error("the TCP session did not survive the udpIdle: ", err)
+	}
+	common.Must(testTCPConn2(clientConn, 1024, time.Second*2)())
+	if second := <-sources; second == first {
+		t.Error("the UDP session did not expire at the udpIdle")
+	}
+}
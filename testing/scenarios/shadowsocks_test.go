@@ -465,3 +465,108 @@ func TestShadowsocksNone(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestShadowsocksXChaCha20AndAESGCMSIV(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	tcpDest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	udpDest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	for _, cipherType := range []shadowsocks.CipherType{
+		shadowsocks.CipherType_XCHACHA20_POLY1305,
+		shadowsocks.CipherType_AES_256_GCM_SIV,
+	} {
+		account := serial.ToTypedMessage(&shadowsocks.Account{
+			Password:   "shadowsocks-password",
+			CipherType: cipherType,
+		})
+
+		serverPort := tcp.PickPort()
+		serverConfig := &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&shadowsocks.ServerConfig{
+						Users: []*protocol.User{{
+							Account: account,
+							Level:   1,
+						}},
+						Network: []net.Network{net.Network_TCP, net.Network_UDP},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				},
+			},
+		}
+
+		tcpPort := tcp.PickPort()
+		udpPort := udp.PickPort()
+		clientConfig := &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(tcpPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+						Address:  net.NewIPOrDomain(tcpDest.Address),
+						Port:     uint32(tcpDest.Port),
+						Networks: []net.Network{net.Network_TCP},
+					}),
+				},
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(udpPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+						Address:  net.NewIPOrDomain(udpDest.Address),
+						Port:     uint32(udpDest.Port),
+						Networks: []net.Network{net.Network_UDP},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					ProxySettings: serial.ToTypedMessage(&shadowsocks.ClientConfig{
+						Server: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+							User: &protocol.User{
+								Account: account,
+							},
+						},
+					}),
+				},
+			},
+		}
+
+		servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+		common.Must(err)
+
+		var errGroup errgroup.Group
+		for range 3 {
+			errGroup.Go(testTCPConn(tcpPort, 1024*1024, time.Second*20))
+			errGroup.Go(testUDPConn(udpPort, 1024, time.Second*5))
+		}
+		if err := errGroup.Wait(); err != nil {
+			t.Error(cipherType, ": ", err)
+		}
+		CloseAllServers(servers)
+	}
+}
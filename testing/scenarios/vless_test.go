@@ -1,11 +1,16 @@
 package scenarios
 
 import (
+	"bytes"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"io"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/xtls/xray-core/app/dispatcher"
 	"github.com/xtls/xray-core/app/log"
 	"github.com/xtls/xray-core/app/proxyman"
 	"github.com/xtls/xray-core/common"
@@ -22,6 +27,7 @@ import (
 	"github.com/xtls/xray-core/proxy/vless/inbound"
 	"github.com/xtls/xray-core/proxy/vless/outbound"
 	"github.com/xtls/xray-core/testing/servers/tcp"
+	"github.com/xtls/xray-core/testing/servers/udp"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/reality"
 	transtcp "github.com/xtls/xray-core/transport/internet/tcp"
@@ -493,3 +499,537 @@ func TestVlessXtlsVisionReality(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestVlessHalfClose(t *testing.T) {
+	for _, mux := range []bool{false, true} {
+		listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: []byte{127, 0, 0, 1}})
+		common.Must(err)
+		defer listener.Close()
+		dest := net.DestinationFromAddr(listener.Addr())
+
+		userID := protocol.NewID(uuid.New())
+		serverPort := tcp.PickPort()
+		serverConfig := &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&inbound.Config{
+						Clients: []*protocol.User{
+							{
+								Account: serial.ToTypedMessage(&vless.Account{
+									Id: userID.String(),
+								}),
+							},
+						},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				},
+			},
+		}
+
+		clientPort := tcp.PickPort()
+		clientConfig := &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+						Address:  net.NewIPOrDomain(dest.Address),
+						Port:     uint32(dest.Port),
+						Networks: []net.Network{net.Network_TCP},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+						MultiplexSettings: &proxyman.MultiplexingConfig{
+							Enabled:     mux,
+							Concurrency: 4,
+						},
+					}),
+					ProxySettings: serial.ToTypedMessage(&outbound.Config{
+						Vnext: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+							User: &protocol.User{
+								Account: serial.ToTypedMessage(&vless.Account{
+									Id: userID.String(),
+								}),
+							},
+						},
+					}),
+				},
+			},
+		}
+
+		servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+		common.Must(err)
+
+		dial := func() *net.TCPConn {
+			conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: int(clientPort)})
+			common.Must(err)
+			common.Must(conn.SetDeadline(time.Now().Add(10 * time.Second)))
+			return conn
+		}
+		accept := func() *net.TCPConn {
+			conn, err := listener.AcceptTCP()
+			common.Must(err)
+			common.Must(conn.SetDeadline(time.Now().Add(10 * time.Second)))
+			return conn
+		}
+		payload := make([]byte, 64*1024)
+		common.Must2(rand.Read(payload))
+
+		// the client ends its request, and the server responds after reading all of it
+		conn := dial()
+		common.Must2(conn.Write(payload))
+		common.Must(conn.CloseWrite())
+		serverConn := accept()
+		request, err := io.ReadAll(serverConn)
+		if err != nil || !bytes.Equal(request, payload) {
+			t.Error("mux: ", mux, ", the server did not read the whole request: ", len(request), " ", err)
+		}
+		common.Must2(serverConn.Write(xor(request)))
+		serverConn.Close()
+		if response, err := io.ReadAll(conn); err != nil || !bytes.Equal(response, xor(payload)) {
+			t.Error("mux: ", mux, ", the client did not read the whole response: ", len(response), " ", err)
+		}
+		conn.Close()
+
+		// the server ends its response, and the client sends more after reading all of it
+		conn = dial()
+		common.Must2(conn.Write([]byte("hello")))
+		serverConn = accept()
+		common.Must2(io.ReadFull(serverConn, make([]byte, 5)))
+		common.Must2(serverConn.Write(payload))
+		common.Must(serverConn.CloseWrite())
+		if response, err := io.ReadAll(conn); err != nil || !bytes.Equal(response, payload) {
+			t.Error("mux: ", mux, ", the client did not read the whole response: ", len(response), " ", err)
+		}
+		common.Must2(conn.Write(xor(payload)))
+		common.Must(conn.CloseWrite())
+		if request, err := io.ReadAll(serverConn); err != nil || !bytes.Equal(request, xor(payload)) {
+			t.Error("mux: ", mux, ", the server did not read the rest of the request: ", len(request), " ", err)
+		}
+		serverConn.Close()
+		conn.Close()
+
+		// the client ends its request, and the server never responds, so the response ends after the
+		// downlinkOnly of the policy rather than the connIdle
+		conn = dial()
+		common.Must2(conn.Write([]byte("hello")))
+		common.Must(conn.CloseWrite())
+		serverConn = accept()
+		start := time.Now()
+		if _, err := io.ReadAll(conn); err != nil {
+			t.Error("mux: ", mux, ", the response did not end: ", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Error("mux: ", mux, ", the response ended after ", elapsed)
+		}
+		serverConn.Close()
+		conn.Close()
+
+		CloseAllServers(servers)
+	}
+}
+
+func TestVlessMuxNetworks(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	tcpDest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	udpDest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	for _, tt := range []struct {
+		name        string
+		mux         *proxyman.MultiplexingConfig
+		connections int32 // to the server, of 3 TCP and 3 UDP flows
+	}{
+		{"off", &proxyman.MultiplexingConfig{Enabled: false}, 6},
+		{"shared", &proxyman.MultiplexingConfig{Enabled: true, Concurrency: 8}, 1},
+		{"tcp only", &proxyman.MultiplexingConfig{Enabled: true, Concurrency: 8, XudpConcurrency: -1}, 4},
+		{"udp only", &proxyman.MultiplexingConfig{Enabled: true, Concurrency: -1, XudpConcurrency: 8}, 4},
+		{"tcp and xudp", &proxyman.MultiplexingConfig{Enabled: true, Concurrency: 8, XudpConcurrency: 8}, 2},
+	} {
+		userID := protocol.NewID(uuid.New())
+		serverPort := tcp.PickPort()
+		serverConfig := &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&inbound.Config{
+						Clients: []*protocol.User{
+							{
+								Account: serial.ToTypedMessage(&vless.Account{
+									Id: userID.String(),
+								}),
+							},
+						},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				},
+			},
+		}
+
+		// counts the connections from the client to the server
+		var connections atomic.Int32
+		listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: []byte{127, 0, 0, 1}})
+		common.Must(err)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				connections.Add(1)
+				go func() {
+					defer conn.Close()
+					serverConn, err := net.Dial("tcp", net.TCPDestination(net.LocalHostIP, serverPort).NetAddr())
+					if err != nil {
+						return
+					}
+					defer serverConn.Close()
+					go io.Copy(serverConn, conn)
+					io.Copy(conn, serverConn)
+				}()
+			}
+		}()
+
+		tcpPort := tcp.PickPort()
+		udpPort := udp.PickPort()
+		clientConfig := &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(tcpPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+						Address:  net.NewIPOrDomain(tcpDest.Address),
+						Port:     uint32(tcpDest.Port),
+						Networks: []net.Network{net.Network_TCP},
+					}),
+				},
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(udpPort)}},
+						Listen:   net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+						Address:  net.NewIPOrDomain(udpDest.Address),
+						Port:     uint32(udpDest.Port),
+						Networks: []net.Network{net.Network_UDP},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+						MultiplexSettings: tt.mux,
+					}),
+					ProxySettings: serial.ToTypedMessage(&outbound.Config{
+						Vnext: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+							User: &protocol.User{
+								Account: serial.ToTypedMessage(&vless.Account{
+									Id: userID.String(),
+								}),
+							},
+						},
+					}),
+				},
+			},
+		}
+
+		servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+		common.Must(err)
+
+		// the flows stay open together, so that Mux carries them over the same connection
+		var conns []net.Conn
+		for i := 0; i < 3; i++ {
+			conn, err := net.Dial("tcp", net.TCPDestination(net.LocalHostIP, tcpPort).NetAddr())
+			common.Must(err)
+			conns = append(conns, conn)
+			if err := testTCPConn2(conn, 1024, time.Second*10)(); err != nil {
+				t.Error(tt.name, ": TCP: ", err)
+			}
+		}
+		for i := 0; i < 3; i++ {
+			conn, err := net.Dial("udp", net.UDPDestination(net.LocalHostIP, udpPort).NetAddr())
+			common.Must(err)
+			conns = append(conns, conn)
+			if err := testTCPConn2(conn, 1024, time.Second*10)(); err != nil {
+				t.Error(tt.name, ": UDP: ", err)
+			}
+		}
+		if n := connections.Load(); n != tt.connections {
+			t.Error(tt.name, ": expected ", tt.connections, " connections to the server, got ", n)
+		}
+
+		for _, conn := range conns {
+			conn.Close()
+		}
+		listener.Close()
+		CloseAllServers(servers)
+	}
+}
+
+func TestVlessMuxHeartbeat(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	tcpDest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	udpDest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	userID := protocol.NewID(uuid.New())
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					Clients: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vless.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	tcpPort := tcp.PickPort()
+	udpPort := udp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(tcpPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(tcpDest.Address),
+					Port:     uint32(tcpDest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(udpPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(udpDest.Address),
+					Port:     uint32(udpDest.Port),
+					Networks: []net.Network{net.Network_UDP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					MultiplexSettings: &proxyman.MultiplexingConfig{Enabled: true, Concurrency: 8, XudpConcurrency: 8},
+				}),
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Vnext: &protocol.ServerEndpoint{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    uint32(serverPort),
+						User: &protocol.User{
+							Account: serial.ToTypedMessage(&vless.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+					HeartbeatInterval: 1,
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	tcpConn, err := net.Dial("tcp", net.TCPDestination(net.LocalHostIP, tcpPort).NetAddr())
+	common.Must(err)
+	defer tcpConn.Close()
+	udpConn, err := net.Dial("udp", net.UDPDestination(net.LocalHostIP, udpPort).NetAddr())
+	common.Must(err)
+	defer udpConn.Close()
+
+	// the KeepAlive frames sent while the flows are idle are discarded by the server
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(time.Millisecond * 2500)
+		}
+		if err := testTCPConn2(tcpConn, 1024, time.Second*5)(); err != nil {
+			t.Error("TCP: ", err)
+		}
+		if err := testTCPConn2(udpConn, 1024, time.Second*5)(); err != nil {
+			t.Error("UDP: ", err)
+		}
+	}
+}
+
+func TestVlessMuxConnectionTable(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	userID := protocol.NewID(uuid.New())
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				Tag: "in",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(serverPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&inbound.Config{
+					Clients: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vless.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+	server, err := core.New(withDefaultApps(serverConfig))
+	common.Must(err)
+	server.Dispatcher().(*dispatcher.DefaultDispatcher).EnableConnectionTable(16)
+	common.Must(server.Start())
+	defer server.Close()
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortList: &net.PortList{Range: []*net.PortRange{net.SinglePortRange(clientPort)}},
+					Listen:   net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address:  net.NewIPOrDomain(dest.Address),
+					Port:     uint32(dest.Port),
+					Networks: []net.Network{net.Network_TCP},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					MultiplexSettings: &proxyman.MultiplexingConfig{
+						Enabled:     true,
+						Concurrency: 4,
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&outbound.Config{
+					Vnext: &protocol.ServerEndpoint{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    uint32(serverPort),
+						User: &protocol.User{
+							Account: serial.ToTypedMessage(&vless.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+				}),
+			},
+		},
+	}
+	servers, err := InitializeServerConfigs(clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: int(clientPort)})
+	common.Must(err)
+	defer conn.Close()
+	payload := make([]byte, 1024)
+	common.Must2(rand.Read(payload))
+	common.Must2(conn.Write(payload))
+	response := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, response))
+
+	// both the mux connection and its session are listed
+	var carrier, stream *dispatcher.Connection
+	for _, c := range server.Dispatcher().(*dispatcher.DefaultDispatcher).ListConnections() {
+		switch c.Target.Address.String() {
+		case "v1.mux.cool":
+			carrier = &c
+		case dest.Address.String():
+			stream = &c
+		}
+	}
+	if carrier == nil || stream == nil {
+		t.Fatal("expected the mux connection and its session to be listed, but got ", server.Dispatcher().(*dispatcher.DefaultDispatcher).ListConnections())
+	}
+	if carrier.InboundTag != "in" || stream.InboundTag != "in" {
+		t.Error("unexpected inbound tags ", carrier.InboundTag, " and ", stream.InboundTag)
+	}
+
+	// closing the mux connection ends its session
+	if !server.Dispatcher().(*dispatcher.DefaultDispatcher).CloseConnection(carrier.ID) {
+		t.Fatal("failed to close the mux connection")
+	}
+	common.Must(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	if _, err := conn.Read(response); err != io.EOF {
+		t.Error("expected the session to end with the mux connection, but got ", err)
+	}
+}
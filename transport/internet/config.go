@@ -150,3 +150,16 @@ func (s DomainStrategy) GetDynamicStrategy(addrFamily net.AddressFamily) DomainS
 	}
 	return s
 }
+
+func (p IPFamilyPreference) HasPreference() bool {
+	return p != IPFamilyPreference_IP_FAMILY_DEFAULT
+}
+
+// Only returns whether the IPs of the other family must not be dialed.
+func (p IPFamilyPreference) Only() bool {
+	return p == IPFamilyPreference_ONLY_IP4 || p == IPFamilyPreference_ONLY_IP6
+}
+
+func (p IPFamilyPreference) PreferIP6() bool {
+	return p == IPFamilyPreference_PREFER_IP6 || p == IPFamilyPreference_ONLY_IP6
+}
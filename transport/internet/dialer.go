@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	gonet "net"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/dice"
@@ -85,15 +87,47 @@ var (
 	obm       outbound.Manager
 )
 
-func LookupForIP(domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
+// resolver returns the DNS client that sends its queries through the outbound of the via tag,
+// or the DNS client itself if there is no tag.
+func resolver(ctx context.Context, via string) (dns.Client, error) {
 	if dnsClient == nil {
 		return nil, errors.New("DNS client not initialized").AtError()
 	}
+	if len(via) == 0 {
+		return dnsClient, nil
+	}
+	// the queries through the outbound can not wait for the outbound to resolve through itself
+	if session.ResolveViaFromContext(ctx) == via {
+		return nil, errors.New("resolution loop: outbound ", via, " has to resolve through itself to send the queries resolving through it").AtError()
+	}
+	c, ok := dnsClient.(dns.ViaClient)
+	if !ok {
+		return nil, errors.New("the DNS client can not resolve through outbound ", via, ", as there is no DNS app").AtError()
+	}
+	return c.Via(via)
+}
 
-	ips, _, err := dnsClient.LookupIP(domain, dns.IPOption{
+func LookupForIP(domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
+	return LookupForIPVia(context.Background(), "", domain, strategy, localAddr)
+}
+
+// LookupForIPVia is LookupForIP with the queries sent through the outbound of the via tag, if there is one.
+func LookupForIPVia(ctx context.Context, via string, domain string, strategy DomainStrategy, localAddr net.Address) ([]net.IP, error) {
+	dnsClient, err := resolver(ctx, via)
+	if err != nil {
+		return nil, err
+	}
+
+	option := dns.IPOption{
 		IPv4Enable: (localAddr == nil && strategy.PreferIP4()) || (localAddr != nil && localAddr.Family().IsIPv4() && (strategy.PreferIP4() || strategy.FallbackIP4())),
 		IPv6Enable: (localAddr == nil && strategy.PreferIP6()) || (localAddr != nil && localAddr.Family().IsIPv6() && (strategy.PreferIP6() || strategy.FallbackIP6())),
-	})
+	}
+	if len(via) == 0 {
+		if ips := routedIPs(ctx, domain, option); len(ips) > 0 {
+			return ips, nil
+		}
+	}
+	ips, _, err := dnsClient.LookupIP(domain, option)
 	{ // Resolve fallback
 		if (len(ips) == 0 || err != nil) && strategy.HasFallback() && localAddr == nil {
 			ips, _, err = dnsClient.LookupIP(domain, dns.IPOption{
@@ -109,6 +143,103 @@ func LookupForIP(domain string, strategy DomainStrategy, localAddr net.Address)
 	return ips, err
 }
 
+// LookupWithPreference resolves the domain for the IP family preference, regardless of the query strategy
+// of the DNS app, and returns the IPs of the preferred family first.
+func LookupWithPreference(domain string, preference IPFamilyPreference, localAddr net.Address) ([]net.IP, error) {
+	return LookupWithPreferenceVia(context.Background(), "", domain, preference, localAddr)
+}
+
+// LookupWithPreferenceVia is LookupWithPreference with the queries sent through the outbound of the via tag, if there is one.
+func LookupWithPreferenceVia(ctx context.Context, via string, domain string, preference IPFamilyPreference, localAddr net.Address) ([]net.IP, error) {
+	dnsClient, err := resolver(ctx, via)
+	if err != nil {
+		return nil, err
+	}
+
+	option := dns.IPOption{
+		IPv4Enable:          preference != IPFamilyPreference_ONLY_IP6,
+		IPv6Enable:          preference != IPFamilyPreference_ONLY_IP4,
+		IgnoreQueryStrategy: true,
+	}
+	if localAddr != nil {
+		// the IP sent through can only reach the IPs of its family
+		option.IPv4Enable = option.IPv4Enable && localAddr.Family().IsIPv4()
+		option.IPv6Enable = option.IPv6Enable && localAddr.Family().IsIPv6()
+	}
+	var ips []net.IP
+	if len(via) == 0 {
+		ips = routedIPs(ctx, domain, option)
+	}
+	if len(ips) == 0 {
+		ips, _, err = dnsClient.LookupIP(domain, option)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, dns.ErrEmptyResponse
+		}
+	}
+	preferIP6 := preference.PreferIP6()
+	sort.SliceStable(ips, func(i, j int) bool {
+		return (ips[i].To4() == nil) == preferIP6 && (ips[j].To4() == nil) != preferIP6
+	})
+	return ips, nil
+}
+
+// routedIPsTTL is how long the IPs that the router resolved a domain to are dialed instead of resolving it again.
+const routedIPsTTL = 30 * time.Second
+
+// routedIPs returns the IPs of the families in option that the router resolved the domain of the target to,
+// so the outbound dials the IPs it is routed by, without another query.
+func routedIPs(ctx context.Context, domain string, option dns.IPOption) []net.IP {
+	outbounds := session.OutboundsFromContext(ctx)
+	if len(outbounds) == 0 {
+		return nil
+	}
+	resolved := outbounds[len(outbounds)-1].Resolved
+	if resolved == nil || resolved.Domain != domain || time.Since(resolved.At) > routedIPsTTL {
+		return nil
+	}
+	var ips []net.IP
+	for _, ip := range resolved.IPs {
+		if (ip.To4() != nil && option.IPv4Enable) || (ip.To4() == nil && option.IPv6Enable) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// routedDestination replaces the domain of dest, which is dialed as is, with an IP the router resolved it to,
+// so that the system resolver does not query it again.
+func routedDestination(ctx context.Context, dest net.Destination, src net.Address) net.Destination {
+	if !dest.Address.Family().IsDomain() {
+		return dest
+	}
+	ips := routedIPs(ctx, dest.Address.Domain(), dns.IPOption{
+		IPv4Enable: src == nil || src.Family().IsIPv4(),
+		IPv6Enable: src == nil || src.Family().IsIPv6(),
+	})
+	if len(ips) > 0 {
+		dest.Address = net.IPAddress(ips[dice.Roll(len(ips))])
+		errors.LogInfo(ctx, "replace destination with "+dest.String())
+	}
+	return dest
+}
+
+// PickIP returns a random IP of the family of the first one, which LookupWithPreference puts the preferred family at.
+func PickIP(ips []net.IP) net.IP {
+	n := 1
+	for n < len(ips) && (ips[n].To4() == nil) == (ips[0].To4() == nil) {
+		n++
+	}
+	return ips[dice.Roll(n)]
+}
+
+// raceDial returns whether to race dial the IPs with happy eyeballs.
+func raceDial(sockopt *SocketConfig, dest net.Destination, ips []net.IP) bool {
+	return sockopt.HappyEyeballs != nil && sockopt.HappyEyeballs.TryDelayMs != 0 && sockopt.HappyEyeballs.MaxConcurrentTry != 0 && len(ips) >= 2 && len(sockopt.DialerProxy) == 0 && dest.Network == net.Network_TCP
+}
+
 func redirect(ctx context.Context, dst net.Destination, obt string, h outbound.Handler) net.Conn {
 	errors.LogInfo(ctx, "redirecting request "+dst.String()+" to "+obt)
 	outbounds := session.OutboundsFromContext(ctx)
@@ -224,11 +355,82 @@ func checkAddressPortStrategy(ctx context.Context, dest net.Destination, sockopt
 	return nil, nil
 }
 
+type dialerChainKey struct{}
+
+// ContextWithDialerChain returns a context whose dials go through the hops of a dialer chain, from the last one.
+func ContextWithDialerChain(ctx context.Context, hops []string) context.Context {
+	if len(hops) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, dialerChainKey{}, hops)
+}
+
+// DialerChainFromContext returns the hops of the dialer chain that the dials of ctx go through.
+func DialerChainFromContext(ctx context.Context) []string {
+	hops, _ := ctx.Value(dialerChainKey{}).([]string)
+	return hops
+}
+
+// DialerChainKey returns a key of the dialer chain of ctx, for the transports pooling their connections
+// not to share a connection dialed through a chain with the dials of another chain, or of no chain.
+func DialerChainKey(ctx context.Context) string {
+	return strings.Join(DialerChainFromContext(ctx), "\x00")
+}
+
+// dialThroughProxy dials dest through the outbound of tag.
+func dialThroughProxy(ctx context.Context, dest net.Destination, tag string) (net.Conn, error) {
+	if obm == nil {
+		return nil, errors.New("there is no outbound manager for dialerProxy").AtError()
+	}
+	h := obm.GetHandler(tag)
+	if h == nil {
+		return nil, errors.New("there is no outbound handler for dialerProxy ", tag).AtError()
+	}
+	// the outbound of the tag records its own dial
+	ctx = session.ContextWithDialTimings(ctx, nil)
+	return redirect(ctx, dest, tag, h), nil
+}
+
 // DialSystem calls system dialer to create a network connection.
 func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	timings := session.DialTimingsFromContext(ctx)
+	// only the connect time of the streams is recorded
+	if timings == nil || timings.Connect == nil || dest.Network != net.Network_TCP {
+		return dialSystem(ctx, dest, sockopt)
+	}
+	start := time.Now()
+	conn, err := dialSystem(ctx, dest, sockopt)
+	if err == nil {
+		timings.Connect.Observe(time.Since(start).Milliseconds())
+	}
+	return conn, err
+}
+
+// observeResolve records the time since start to resolve the address of the server, if ctx holds the dial timings.
+func observeResolve(ctx context.Context, start time.Time) {
+	if timings := session.DialTimingsFromContext(ctx); timings != nil && timings.Resolve != nil {
+		timings.Resolve.Observe(time.Since(start).Milliseconds())
+	}
+}
+
+// ObserveHandshake records the time since start of the TLS or REALITY handshake, if ctx holds the dial timings.
+func ObserveHandshake(ctx context.Context, start time.Time) {
+	if timings := session.DialTimingsFromContext(ctx); timings != nil && timings.Handshake != nil {
+		timings.Handshake.Observe(time.Since(start).Milliseconds())
+	}
+}
+
+func dialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	// The outbound is a hop of a chain, so it dials through the hop before it, whatever its own dialerProxy.
+	if hops := DialerChainFromContext(ctx); len(hops) > 0 {
+		ctx = context.WithValue(ctx, dialerChainKey{}, hops[:len(hops)-1])
+		return dialThroughProxy(ctx, dest, hops[len(hops)-1])
+	}
+
 	var src net.Address
 	outbounds := session.OutboundsFromContext(ctx)
 	var outboundName string
+	var outboundTag string
 	var origTargetAddr net.Address
 	if len(outbounds) > 0 {
 		ob := outbounds[len(outbounds)-1]
@@ -236,13 +438,14 @@ func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig
 			src = ob.Gateway
 		}
 		outboundName = ob.Name
+		outboundTag = ob.Tag
 		origTargetAddr = ob.OriginalTarget.Address
 		if origTargetAddr == nil {
 			origTargetAddr = ob.Target.Address
 		}
 	}
 	if sockopt == nil {
-		return effectiveSystemDialer.Dial(ctx, src, dest, sockopt)
+		return effectiveSystemDialer.Dial(ctx, src, routedDestination(ctx, dest, src), sockopt)
 	}
 
 	if newDest, err := checkAddressPortStrategy(ctx, dest, sockopt); err == nil && newDest != nil {
@@ -250,34 +453,59 @@ func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig
 		dest = *newDest
 	}
 
-	if sockopt.DomainStrategy.HasStrategy() && dest.Address.Family().IsDomain() {
+	resolveStart := time.Now()
+	if sockopt.cachesServerAddress() && dest.Address.Family().IsDomain() {
+		ips, err := resolveServerAddress(ctx, outboundTag, dest.Address.Domain(), sockopt, src)
+		if err != nil {
+			return nil, errors.New("failed to resolve the server ", dest.Address).Base(err)
+		}
+		observeResolve(ctx, resolveStart)
+		if raceDial(sockopt, dest, ips) {
+			return TcpRaceDial(ctx, src, ips, dest.Port, sockopt, dest.Address.String())
+		}
+		dest.Address = net.IPAddress(pickServerIP(ips, sockopt))
+		errors.LogInfo(ctx, "replace destination with "+dest.String())
+	} else if sockopt.IpFamilyPreference.HasPreference() && dest.Address.Family().IsDomain() {
+		ips, err := LookupWithPreferenceVia(ctx, sockopt.ResolveVia, dest.Address.Domain(), sockopt.IpFamilyPreference, src)
+		if err != nil {
+			errors.LogErrorInner(ctx, err, "failed to resolve ip")
+			if sockopt.IpFamilyPreference.Only() || len(sockopt.ResolveVia) > 0 {
+				return nil, err
+			}
+		} else if observeResolve(ctx, resolveStart); !raceDial(sockopt, dest, ips) {
+			dest.Address = net.IPAddress(PickIP(ips))
+			errors.LogInfo(ctx, "replace destination with "+dest.String())
+		} else {
+			return TcpRaceDial(ctx, src, ips, dest.Port, sockopt, dest.Address.String())
+		}
+	} else if (sockopt.DomainStrategy.HasStrategy() || len(sockopt.ResolveVia) > 0) && dest.Address.Family().IsDomain() {
 		finalStrategy := sockopt.DomainStrategy
+		if !finalStrategy.HasStrategy() {
+			// resolveVia alone resolves the domains, instead of leaving them to the system
+			finalStrategy = DomainStrategy_USE_IP
+		}
 		if outboundName == "freedom" && dest.Network == net.Network_UDP && origTargetAddr != nil && src == nil {
 			finalStrategy = finalStrategy.GetDynamicStrategy(origTargetAddr.Family())
 		}
-		ips, err := LookupForIP(dest.Address.Domain(), finalStrategy, src)
+		ips, err := LookupForIPVia(ctx, sockopt.ResolveVia, dest.Address.Domain(), finalStrategy, src)
 		if err != nil {
 			errors.LogErrorInner(ctx, err, "failed to resolve ip")
-			if sockopt.DomainStrategy.ForceIP() {
+			if sockopt.DomainStrategy.ForceIP() || len(sockopt.ResolveVia) > 0 {
 				return nil, err
 			}
-		} else if sockopt.HappyEyeballs == nil || sockopt.HappyEyeballs.TryDelayMs == 0 || sockopt.HappyEyeballs.MaxConcurrentTry == 0 || len(ips) < 2 || len(sockopt.DialerProxy) > 0 || dest.Network != net.Network_TCP {
+		} else if observeResolve(ctx, resolveStart); !raceDial(sockopt, dest, ips) {
 			dest.Address = net.IPAddress(ips[dice.Roll(len(ips))])
 			errors.LogInfo(ctx, "replace destination with "+dest.String())
 		} else {
 			return TcpRaceDial(ctx, src, ips, dest.Port, sockopt, dest.Address.String())
 		}
+	} else {
+		dest = routedDestination(ctx, dest, src)
 	}
 
 	if len(sockopt.DialerProxy) > 0 {
-		if obm == nil {
-			return nil, errors.New("there is no outbound manager for dialerProxy").AtError()
-		}
-		h := obm.GetHandler(sockopt.DialerProxy)
-		if h == nil {
-			return nil, errors.New("there is no outbound handler for dialerProxy").AtError()
-		}
-		return redirect(ctx, dest, sockopt.DialerProxy, h), nil
+		ctx = ContextWithDialerChain(ctx, sockopt.DialerProxyChain)
+		return dialThroughProxy(ctx, dest, sockopt.DialerProxy)
 	}
 
 	return effectiveSystemDialer.Dial(ctx, src, dest, sockopt)
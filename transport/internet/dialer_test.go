@@ -2,11 +2,15 @@ package internet_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/dns"
 	"github.com/xtls/xray-core/testing/servers/tcp"
 	. "github.com/xtls/xray-core/transport/internet"
 )
@@ -24,3 +28,160 @@ func TestDialWithLocalAddr(t *testing.T) {
 	}
 	conn.Close()
 }
+
+// fakeDNS resolves every domain to two IPv4 and two IPv6 addresses, of the families enabled.
+type fakeDNS struct {
+	option dns.IPOption
+}
+
+func (*fakeDNS) Type() interface{} { return dns.ClientType() }
+func (*fakeDNS) Start() error      { return nil }
+func (*fakeDNS) Close() error      { return nil }
+
+func (d *fakeDNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, uint32, error) {
+	d.option = option
+	var ips []net.IP
+	for _, s := range []string{"10.0.0.1", "2001:db8::1", "10.0.0.2", "2001:db8::2"} {
+		ip := net.ParseIP(s)
+		if (ip.To4() != nil && option.IPv4Enable) || (ip.To4() == nil && option.IPv6Enable) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, 0, nil
+}
+
+func TestLookupWithPreference(t *testing.T) {
+	d := new(fakeDNS)
+	InitSystemDialer(d, nil)
+	defer InitSystemDialer(nil, nil)
+
+	ips, err := LookupWithPreference("example.com", IPFamilyPreference_PREFER_IP6, nil)
+	common.Must(err)
+	if r := cmp.Diff(fmt.Sprint(ips), "[2001:db8::1 2001:db8::2 10.0.0.1 10.0.0.2]"); r != "" {
+		t.Error(r)
+	}
+	if !d.option.IgnoreQueryStrategy {
+		t.Error("query strategy of the DNS app not ignored")
+	}
+	for i := 0; i < 10; i++ {
+		if ip := PickIP(ips); ip.To4() != nil {
+			t.Error("picked ", ip, " with IPv6 preferred")
+		}
+	}
+
+	ips, err = LookupWithPreference("example.com", IPFamilyPreference_PREFER_IP4, nil)
+	common.Must(err)
+	if r := cmp.Diff(fmt.Sprint(ips), "[10.0.0.1 10.0.0.2 2001:db8::1 2001:db8::2]"); r != "" {
+		t.Error(r)
+	}
+
+	ips, err = LookupWithPreference("example.com", IPFamilyPreference_ONLY_IP6, nil)
+	common.Must(err)
+	if r := cmp.Diff(fmt.Sprint(ips), "[2001:db8::1 2001:db8::2]"); r != "" {
+		t.Error(r)
+	}
+
+	// an IPv4 address sent through can't reach IPv6
+	ips, err = LookupWithPreference("example.com", IPFamilyPreference_PREFER_IP6, net.LocalHostIP)
+	common.Must(err)
+	if r := cmp.Diff(fmt.Sprint(ips), "[10.0.0.1 10.0.0.2]"); r != "" {
+		t.Error(r)
+	}
+}
+
+// staticDNS resolves every domain to its IPs, counting the lookups.
+type staticDNS struct {
+	ips     []net.IP
+	lookups int
+}
+
+func (*staticDNS) Type() interface{} { return dns.ClientType() }
+func (*staticDNS) Start() error      { return nil }
+func (*staticDNS) Close() error      { return nil }
+
+func (d *staticDNS) LookupIP(domain string, option dns.IPOption) ([]net.IP, uint32, error) {
+	d.lookups++
+	return d.ips, 0, nil
+}
+
+func TestDialServerAddressPinned(t *testing.T) {
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	common.Must(err)
+	defer server.Close()
+
+	d := &staticDNS{ips: []net.IP{net.LocalHostIP.IP()}}
+	InitSystemDialer(d, nil)
+	defer InitSystemDialer(nil, nil)
+
+	ctx := session.ContextWithOutbounds(context.Background(), []*session.Outbound{{Tag: "pinned"}})
+	sockopt := &SocketConfig{ServerAddressTtl: 1, PinnedIps: []string{"127.0.0.0/8"}}
+	dial := func() {
+		conn, err := DialSystem(ctx, net.TCPDestination(net.DomainAddress("server.example.com"), dest.Port), sockopt)
+		common.Must(err)
+		if r := cmp.Diff(conn.RemoteAddr().String(), "127.0.0.1:"+dest.Port.String()); r != "" {
+			t.Error(r)
+		}
+		conn.Close()
+	}
+
+	dial()
+	dial()
+	if d.lookups != 1 {
+		t.Error("expected the server to be resolved once in its TTL, but got ", d.lookups, " lookups")
+	}
+
+	// a poisoned answer is ignored, and the last good IP is kept
+	d.ips = []net.IP{net.ParseIP("192.0.2.1")}
+	time.Sleep(1100 * time.Millisecond)
+	dial()
+	if d.lookups != 2 {
+		t.Error("expected the server to be resolved again after its TTL, but got ", d.lookups, " lookups")
+	}
+	addresses := ServerAddresses("pinned")
+	if len(addresses) != 1 || !addresses[0].Stale || fmt.Sprint(addresses[0].IPs) != "[127.0.0.1]" {
+		t.Error("unexpected server addresses ", addresses)
+	}
+	if addresses := ServerAddresses("other"); len(addresses) != 0 {
+		t.Error("unexpected server addresses of another outbound ", addresses)
+	}
+}
+
+// viaDNS resolves through outbounds to the IPs of their tags.
+type viaDNS struct {
+	staticDNS
+	vias map[string]*staticDNS
+}
+
+func (d *viaDNS) Via(tag string) (dns.Client, error) {
+	return d.vias[tag], nil
+}
+
+func TestLookupForIPVia(t *testing.T) {
+	d := &viaDNS{
+		staticDNS: staticDNS{ips: []net.IP{net.ParseIP("10.0.0.1")}},
+		vias: map[string]*staticDNS{
+			"proxy": {ips: []net.IP{net.ParseIP("10.0.0.2")}},
+		},
+	}
+	InitSystemDialer(d, nil)
+	defer InitSystemDialer(nil, nil)
+
+	ips, err := LookupForIPVia(context.Background(), "proxy", "example.com", DomainStrategy_USE_IP, nil)
+	common.Must(err)
+	if r := cmp.Diff(fmt.Sprint(ips), "[10.0.0.2]"); r != "" {
+		t.Error(r)
+	}
+	if d.lookups != 0 {
+		t.Error("expected no lookup as routed")
+	}
+
+	// the queries through proxy can't wait for proxy to resolve through itself
+	ctx := session.ContextWithResolveVia(context.Background(), "proxy")
+	if _, err := LookupForIPVia(ctx, "proxy", "example.com", DomainStrategy_USE_IP, nil); err == nil {
+		t.Error("expected an error for the resolution loop")
+	}
+	if _, err := LookupForIPVia(ctx, "", "example.com", DomainStrategy_USE_IP, nil); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+}
@@ -0,0 +1,31 @@
+package internet
+
+import (
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// ForwardedConnection is implemented by the connections of HTTP based transports, whose RemoteAddr may be
+// taken from the X-Forwarded-For header of the request rather than from the peer that sent it.
+type ForwardedConnection interface {
+	// PeerAddr returns the address of the peer that sent the request.
+	PeerAddr() net.Addr
+}
+
+// PeerAddr returns the address of the peer that conn was accepted from, looking through the counter
+// and handed connections over it. ok is false if conn does not tell it apart from its RemoteAddr.
+func PeerAddr(conn net.Conn) (peer net.Addr, ok bool) {
+	for conn != nil {
+		switch c := conn.(type) {
+		case ForwardedConnection:
+			return c.PeerAddr(), true
+		case *stat.CounterConnection:
+			conn = c.Connection
+		case *HandedConnection:
+			conn = c.Connection
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
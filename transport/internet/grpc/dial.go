@@ -18,7 +18,6 @@ import (
 	"github.com/xtls/xray-core/transport/internet/tls"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
-	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 )
@@ -40,17 +39,18 @@ func init() {
 type dialerConf struct {
 	net.Destination
 	*internet.MemoryStreamConfig
+	chain string
 }
 
 var (
-	globalDialerMap    map[dialerConf]*grpc.ClientConn
+	globalDialerMap    map[dialerConf]*clientPool
 	globalDialerAccess sync.Mutex
 )
 
 func dialgRPC(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
 	grpcSettings := streamSettings.ProtocolSettings.(*Config)
 
-	conn, err := getGrpcClient(ctx, dest, streamSettings)
+	conn, release, err := getGrpcClient(ctx, dest, streamSettings)
 	if err != nil {
 		return nil, errors.New("Cannot dial gRPC").Base(err)
 	}
@@ -59,36 +59,47 @@ func dialgRPC(ctx context.Context, dest net.Destination, streamSettings *interne
 		errors.LogDebug(ctx, "using gRPC multi mode service name: `"+grpcSettings.getServiceName()+"` stream name: `"+grpcSettings.getTunMultiStreamName()+"`")
 		grpcService, err := client.(encoding.GRPCServiceClientX).TunMultiCustomName(ctx, grpcSettings.getServiceName(), grpcSettings.getTunMultiStreamName())
 		if err != nil {
+			release()
 			return nil, errors.New("Cannot dial gRPC").Base(err)
 		}
-		return encoding.NewMultiHunkConn(grpcService, nil), nil
+		return encoding.NewMultiHunkConn(grpcService, release), nil
 	}
 
 	errors.LogDebug(ctx, "using gRPC tun mode service name: `"+grpcSettings.getServiceName()+"` stream name: `"+grpcSettings.getTunStreamName()+"`")
 	grpcService, err := client.(encoding.GRPCServiceClientX).TunCustomName(ctx, grpcSettings.getServiceName(), grpcSettings.getTunStreamName())
 	if err != nil {
+		release()
 		return nil, errors.New("Cannot dial gRPC").Base(err)
 	}
 
-	return encoding.NewHunkConn(grpcService, nil), nil
+	return encoding.NewHunkConn(grpcService, release), nil
 }
 
-func getGrpcClient(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (*grpc.ClientConn, error) {
+// getGrpcClient returns a connection of the pool to dest for a new stream, and the function to call when the stream ends.
+func getGrpcClient(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (*grpc.ClientConn, func(), error) {
 	globalDialerAccess.Lock()
 	defer globalDialerAccess.Unlock()
 
 	if globalDialerMap == nil {
-		globalDialerMap = make(map[dialerConf]*grpc.ClientConn)
+		globalDialerMap = make(map[dialerConf]*clientPool)
 	}
+	key := dialerConf{dest, streamSettings, internet.DialerChainKey(ctx)}
+	pool, found := globalDialerMap[key]
+	if !found {
+		pool = newClientPool(dest, streamSettings.ProtocolSettings.(*Config), func(ctx context.Context) (*grpc.ClientConn, error) {
+			return dialClient(ctx, dest, streamSettings)
+		})
+		globalDialerMap[key] = pool
+	}
+	return pool.get(ctx)
+}
+
+func dialClient(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (*grpc.ClientConn, error) {
 	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
 	realityConfig := reality.ConfigFromStreamSettings(streamSettings)
 	sockopt := streamSettings.SocketSettings
 	grpcSettings := streamSettings.ProtocolSettings.(*Config)
 
-	if client, found := globalDialerMap[dialerConf{dest, streamSettings}]; found && client.GetState() != connectivity.Shutdown {
-		return client, nil
-	}
-
 	dialOptions := []grpc.DialOption{
 		grpc.WithConnectParams(grpc.ConnectParams{
 			Backoff: backoff.Config{
@@ -121,23 +132,39 @@ func getGrpcClient(ctx context.Context, dest net.Destination, streamSettings *in
 
 			gctx = c.ContextWithID(gctx, c.IDFromContext(ctx))
 			gctx = session.ContextWithOutbounds(gctx, session.OutboundsFromContext(ctx))
+			gctx = internet.ContextWithDialerChain(gctx, internet.DialerChainFromContext(ctx))
 			gctx = session.ContextWithTimeoutOnly(gctx, true)
+			gctx = session.ContextWithDialTimings(gctx, session.DialTimingsFromContext(ctx))
 
 			c, err := internet.DialSystem(gctx, net.TCPDestination(address, port), sockopt)
 			if err == nil {
 				if tlsConfig != nil {
-					config := tlsConfig.GetTLSConfig()
-					if config.ServerName == "" && address.Family().IsDomain() {
-						config.ServerName = address.Domain()
+					var opts []tls.Option
+					if address.Family().IsDomain() {
+						opts = append(opts, tls.WithDestination(net.TCPDestination(address, port)))
 					}
+					config := tlsConfig.GetTLSConfig(opts...)
+					start := time.Now()
 					if fingerprint := tls.GetFingerprint(tlsConfig.Fingerprint); fingerprint != nil {
-						return tls.UClient(c, config, fingerprint), nil
+						c = tls.UClient(c, config, fingerprint)
+						err = c.(*tls.UConn).HandshakeContext(gctx)
 					} else { // Fallback to normal gRPC TLS
-						return tls.Client(c, config), nil
+						c = tls.Client(c, config)
+						err = c.(*tls.Conn).HandshakeContext(gctx)
+					}
+					if err != nil {
+						return nil, err
 					}
+					internet.ObserveHandshake(gctx, start)
+					return c, nil
 				}
 				if realityConfig != nil {
-					return reality.UClient(c, realityConfig, gctx, dest)
+					start := time.Now()
+					if c, err = reality.UClient(c, realityConfig, gctx, dest); err != nil {
+						return nil, err
+					}
+					internet.ObserveHandshake(gctx, start)
+					return c, nil
 				}
 			}
 			return c, err
@@ -179,10 +206,8 @@ func getGrpcClient(ctx context.Context, dest net.Destination, streamSettings *in
 		grpcDestHost = dest.Address.IP().String()
 	}
 
-	conn, err := grpc.Dial(
+	return grpc.Dial(
 		gonet.JoinHostPort(grpcDestHost, dest.Port.String()),
 		dialOptions...,
 	)
-	globalDialerMap[dialerConf{dest, streamSettings}] = conn
-	return conn, err
 }
@@ -41,9 +41,17 @@ func NewHunkReadWriter(hc HunkConn, cancel context.CancelFunc) *HunkReaderWriter
 
 func NewHunkConn(hc HunkConn, cancel context.CancelFunc) net.Conn {
 	var rAddr net.Addr
+	// the local address is the destination in the PROXY protocol header if the listener accepts it
+	lAddr := net.Addr(&net.TCPAddr{
+		IP:   []byte{0, 0, 0, 0},
+		Port: 0,
+	})
 	pr, ok := peer.FromContext(hc.Context())
 	if ok {
 		rAddr = pr.Addr
+		if pr.LocalAddr != nil {
+			lAddr = pr.LocalAddr
+		}
 	} else {
 		rAddr = &net.TCPAddr{
 			IP:   []byte{0, 0, 0, 0},
@@ -70,6 +78,7 @@ func NewHunkConn(hc HunkConn, cancel context.CancelFunc) net.Conn {
 		cnc.ConnectionOutput(wrc),
 		cnc.ConnectionOnClose(wrc),
 		cnc.ConnectionRemoteAddr(rAddr),
+		cnc.ConnectionLocalAddr(lAddr),
 	)
 }
 
@@ -36,9 +36,17 @@ func NewMultiHunkReadWriter(hc MultiHunkConn, cancel context.CancelFunc) *MultiH
 
 func NewMultiHunkConn(hc MultiHunkConn, cancel context.CancelFunc) net.Conn {
 	var rAddr net.Addr
+	// the local address is the destination in the PROXY protocol header if the listener accepts it
+	lAddr := net.Addr(&net.TCPAddr{
+		IP:   []byte{0, 0, 0, 0},
+		Port: 0,
+	})
 	pr, ok := peer.FromContext(hc.Context())
 	if ok {
 		rAddr = pr.Addr
+		if pr.LocalAddr != nil {
+			lAddr = pr.LocalAddr
+		}
 	} else {
 		rAddr = &net.TCPAddr{
 			IP:   []byte{0, 0, 0, 0},
@@ -65,6 +73,7 @@ func NewMultiHunkConn(hc MultiHunkConn, cancel context.CancelFunc) net.Conn {
 		cnc.ConnectionOutputMulti(wrc),
 		cnc.ConnectionOnClose(wrc),
 		cnc.ConnectionRemoteAddr(rAddr),
+		cnc.ConnectionLocalAddr(lAddr),
 	)
 }
 
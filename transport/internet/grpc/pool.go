@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// clientPool spreads the streams to a gRPC server over up to size connections.
+// Its members are guarded by globalDialerAccess.
+type clientPool struct {
+	dest        net.Destination
+	size        int
+	min         int
+	idleTimeout time.Duration
+	dial        func(ctx context.Context) (*grpc.ClientConn, error)
+
+	members []*poolMember
+	// the next member to look at first, so that members of the same load take turns
+	next   int
+	dialed int
+}
+
+type poolMember struct {
+	id        int
+	conn      *grpc.ClientConn
+	streams   int
+	idleSince time.Time
+}
+
+func newClientPool(dest net.Destination, config *Config, dial func(ctx context.Context) (*grpc.ClientConn, error)) *clientPool {
+	return &clientPool{
+		dest:        dest,
+		size:        max(int(config.ConnectionPoolSize), 1),
+		min:         max(int(config.ConnectionPoolMin), 1),
+		idleTimeout: time.Duration(config.ConnectionPoolIdleTimeout) * time.Second,
+		dial:        dial,
+	}
+}
+
+// get returns the connection of the least streams for a new stream, and the function to call when the stream ends.
+// It dials a new connection if every member has streams and the pool is not full.
+// The ClientConn reconnects a broken member with backoff by itself, and closed members are replaced.
+func (p *clientPool) get(ctx context.Context) (*grpc.ClientConn, func(), error) {
+	for i := 0; i < len(p.members); {
+		if p.members[i].conn.GetState() == connectivity.Shutdown {
+			errors.LogDebug(ctx, "gRPC pool: removing closed connection ", p.members[i].id, " to ", p.dest)
+			p.members = append(p.members[:i], p.members[i+1:]...)
+		} else {
+			i++
+		}
+	}
+
+	var member *poolMember
+	for i := range p.members {
+		m := p.members[(p.next+i)%len(p.members)]
+		if member == nil || p.less(m, member) {
+			member = m
+		}
+	}
+	p.next++
+
+	if member == nil || (len(p.members) < p.size && (member.streams > 0 || unhealthy(member))) {
+		conn, err := p.dial(ctx)
+		if err != nil {
+			if member == nil {
+				return nil, nil, err
+			}
+			errors.LogDebugInner(ctx, err, "gRPC pool: failed to dial a new connection to ", p.dest)
+		} else {
+			p.dialed++
+			member = &poolMember{id: p.dialed, conn: conn}
+			p.members = append(p.members, member)
+			errors.LogDebug(ctx, "gRPC pool: dialed connection ", member.id, " to ", p.dest, ", connections = ", len(p.members))
+			go p.watch(member)
+		}
+	}
+
+	member.streams++
+	var once sync.Once
+	return member.conn, func() {
+		once.Do(func() { p.release(member) })
+	}, nil
+}
+
+// less returns whether a is better for a new stream than b.
+func (p *clientPool) less(a, b *poolMember) bool {
+	if ua, ub := unhealthy(a), unhealthy(b); ua != ub {
+		return ub
+	}
+	return a.streams < b.streams
+}
+
+func unhealthy(m *poolMember) bool {
+	return m.conn.GetState() == connectivity.TransientFailure
+}
+
+func (p *clientPool) release(m *poolMember) {
+	globalDialerAccess.Lock()
+	defer globalDialerAccess.Unlock()
+
+	m.streams--
+	if m.streams == 0 {
+		m.idleSince = time.Now()
+		if p.idleTimeout > 0 {
+			time.AfterFunc(p.idleTimeout, p.reap)
+		}
+	}
+}
+
+// reap closes the members without streams for idleTimeout, while there are more than min of them.
+func (p *clientPool) reap() {
+	globalDialerAccess.Lock()
+	defer globalDialerAccess.Unlock()
+
+	now := time.Now()
+	for i := len(p.members) - 1; i >= 0 && len(p.members) > p.min; i-- {
+		m := p.members[i]
+		if m.streams == 0 && now.Sub(m.idleSince) >= p.idleTimeout {
+			errors.LogDebug(context.Background(), "gRPC pool: closing idle connection ", m.id, " to ", p.dest)
+			m.conn.Close()
+			p.members = append(p.members[:i], p.members[i+1:]...)
+		}
+	}
+}
+
+// watch logs the changes of the state of the member, until it is closed.
+func (p *clientPool) watch(m *poolMember) {
+	state := m.conn.GetState()
+	for state != connectivity.Shutdown {
+		if !m.conn.WaitForStateChange(context.Background(), state) {
+			return
+		}
+		state = m.conn.GetState()
+		errors.LogDebug(context.Background(), "gRPC pool: connection ", m.id, " to ", p.dest, " is ", state)
+	}
+}
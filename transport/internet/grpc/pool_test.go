@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/net"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newTestPool(t *testing.T, size, min int32) *clientPool {
+	pool := newClientPool(net.TCPDestination(net.LocalHostIP, 1), &Config{
+		ConnectionPoolSize: size,
+		ConnectionPoolMin:  min,
+	}, func(context.Context) (*grpc.ClientConn, error) {
+		// never connects, as there are no RPCs
+		return grpc.Dial("127.0.0.1:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	})
+	t.Cleanup(func() {
+		for _, m := range pool.members {
+			m.conn.Close()
+		}
+	})
+	return pool
+}
+
+func TestClientPool(t *testing.T) {
+	pool := newTestPool(t, 2, 0)
+	ctx := context.Background()
+
+	conn1, release1, err := pool.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn2, release2, err := pool.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn1 == conn2 {
+		t.Fatal("expected a new connection for the second stream")
+	}
+
+	// the pool is full, so the streams share the connections
+	conn3, release3, err := pool.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.members) != 2 {
+		t.Fatal("expected 2 connections, but got ", len(pool.members))
+	}
+
+	release1()
+	release1()
+	if conn3 == conn1 {
+		release3()
+	} else {
+		release2()
+	}
+	// one connection is without streams now
+	conn4, _, err := pool.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range pool.members {
+		if m.conn == conn4 && m.streams != 1 {
+			t.Fatal("expected the connection of the least streams")
+		}
+	}
+}
+
+func TestClientPoolReap(t *testing.T) {
+	pool := newTestPool(t, 3, 1)
+	pool.idleTimeout = 100 * time.Millisecond
+	ctx := context.Background()
+
+	var releases []func()
+	for i := 0; i < 3; i++ {
+		_, release, err := pool.get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	for _, release := range releases {
+		release()
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	globalDialerAccess.Lock()
+	defer globalDialerAccess.Unlock()
+	if len(pool.members) != 1 {
+		t.Fatal("expected 1 connection left, but got ", len(pool.members))
+	}
+}
@@ -19,6 +19,9 @@ func TcpRaceDial(ctx context.Context, src net.Address, ips []net.IP, port net.Po
 	}
 
 	prioritizeIPv6 := sockopt.HappyEyeballs.PrioritizeIpv6
+	if sockopt.IpFamilyPreference.HasPreference() {
+		prioritizeIPv6 = sockopt.IpFamilyPreference.PreferIP6()
+	}
 	interleave := sockopt.HappyEyeballs.Interleave
 	tryDelayMs := time.Duration(sockopt.HappyEyeballs.TryDelayMs) * time.Millisecond
 	maxConcurrentTry := sockopt.HappyEyeballs.MaxConcurrentTry
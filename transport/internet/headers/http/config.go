@@ -1,9 +1,16 @@
 package http
 
 import (
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/uuid"
 )
 
 func pickString(arr []string) string {
@@ -18,6 +25,28 @@ func pickString(arr []string) string {
 	}
 }
 
+// expandHeader replaces the variables in a header: {{remoteIP}} by the IP of the other end of the connection,
+// {{date}} by the current date and {{uuid}} by a random UUID.
+func expandHeader(header string, remote net.Addr) string {
+	if !strings.Contains(header, "{{") {
+		return header
+	}
+	remoteIP := ""
+	if remote != nil {
+		remoteIP = remote.String()
+		if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+			remoteIP = host
+		}
+	}
+	header = strings.ReplaceAll(header, "{{remoteIP}}", remoteIP)
+	header = strings.ReplaceAll(header, "{{date}}", time.Now().UTC().Format(http.TimeFormat))
+	for strings.Contains(header, "{{uuid}}") {
+		id := uuid.New()
+		header = strings.Replace(header, "{{uuid}}", id.String(), 1)
+	}
+	return header
+}
+
 func (v *RequestConfig) PickURI() string {
 	return pickString(v.Uri)
 }
@@ -98,3 +127,32 @@ func (v *ResponseConfig) GetStatusValue() *Status {
 	}
 	return v.Status
 }
+
+// HasExpectation returns true if the client should check the response.
+func (v *ResponseConfig) HasExpectation() bool {
+	return v != nil && (len(v.ExpectStatus) > 0 || len(v.ExpectHeader) > 0)
+}
+
+// Check checks the status and the headers of the response against the expectation.
+func (v *ResponseConfig) Check(resp *http.Response) error {
+	if len(v.ExpectStatus) > 0 && strconv.Itoa(resp.StatusCode) != v.ExpectStatus {
+		return errors.New("unexpected HTTP response status: ", resp.Status)
+	}
+	for _, expected := range v.ExpectHeader {
+		values := resp.Header.Values(expected.Name)
+		if len(values) == 0 {
+			return errors.New("HTTP response header missing: ", expected.Name)
+		}
+		if len(expected.Value) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(values, func(value string) bool {
+			return slices.ContainsFunc(expected.Value, func(prefix string) bool {
+				return strings.HasPrefix(value, prefix)
+			})
+		}) {
+			return errors.New("unexpected HTTP response header ", expected.Name, ": ", values[0])
+		}
+	}
+	return nil
+}
@@ -62,10 +62,11 @@ func (h *HeaderReader) ExpectThisRequest(expectedHeader *RequestConfig) *HeaderR
 	return h
 }
 
-func (h *HeaderReader) Read(reader io.Reader) (*buf.Buffer, error) {
+// readHeader reads up to the end of the header, and returns the header and the bytes read after it.
+// parse is called on the incomplete header to fail early, it returns io.ErrUnexpectedEOF if the header is valid so far.
+func readHeader(reader io.Reader, parse func(*bufio.Reader) error) ([]byte, *buf.Buffer, error) {
 	buffer := buf.New()
 	totalBytes := int32(0)
-	endingDetected := false
 
 	var headerBuf bytes.Buffer
 
@@ -73,13 +74,12 @@ func (h *HeaderReader) Read(reader io.Reader) (*buf.Buffer, error) {
 		_, err := buffer.ReadFrom(reader)
 		if err != nil {
 			buffer.Release()
-			return nil, err
+			return nil, nil, err
 		}
 		if n := bytes.Index(buffer.Bytes(), []byte(ENDING)); n != -1 {
 			headerBuf.Write(buffer.BytesRange(0, int32(n+len(ENDING))))
 			buffer.Advance(int32(n + len(ENDING)))
-			endingDetected = true
-			break
+			return headerBuf.Bytes(), buffer, nil
 		}
 		lenEnding := int32(len(ENDING))
 		if buffer.Len() >= lenEnding {
@@ -89,15 +89,23 @@ func (h *HeaderReader) Read(reader io.Reader) (*buf.Buffer, error) {
 			buffer.Clear()
 			copy(buffer.Extend(lenEnding), leftover)
 
-			if _, err := readRequest(bufio.NewReader(bytes.NewReader(headerBuf.Bytes()))); err != io.ErrUnexpectedEOF {
-				return nil, err
+			if err := parse(bufio.NewReader(bytes.NewReader(headerBuf.Bytes()))); err != io.ErrUnexpectedEOF {
+				return nil, nil, err
 			}
 		}
 	}
 
-	if !endingDetected {
-		buffer.Release()
-		return nil, ErrHeaderToLong
+	buffer.Release()
+	return nil, nil, ErrHeaderToLong
+}
+
+func (h *HeaderReader) Read(reader io.Reader) (*buf.Buffer, error) {
+	header, buffer, err := readHeader(reader, func(b *bufio.Reader) error {
+		_, err := readRequest(b)
+		return err
+	})
+	if err != nil || header == nil {
+		return nil, err
 	}
 
 	if h.expectedHeader == nil {
@@ -109,7 +117,7 @@ func (h *HeaderReader) Read(reader io.Reader) (*buf.Buffer, error) {
 	}
 
 	// Parse the request
-	if req, err := readRequest(bufio.NewReader(bytes.NewReader(headerBuf.Bytes()))); err != nil {
+	if req, err := readRequest(bufio.NewReader(bytes.NewReader(header))); err != nil {
 		return nil, err
 	} else {
 		h.req = req
@@ -136,6 +144,44 @@ func (h *HeaderReader) Read(reader io.Reader) (*buf.Buffer, error) {
 	return buffer, nil
 }
 
+// ResponseReader reads the response on the client, and fails if it is not the expected one.
+type ResponseReader struct {
+	expected *ResponseConfig
+}
+
+func (r *ResponseReader) Read(reader io.Reader) (*buf.Buffer, error) {
+	malformed := false
+	header, buffer, err := readHeader(reader, func(b *bufio.Reader) error {
+		_, err := http.ReadResponse(b, nil)
+		malformed = err != io.ErrUnexpectedEOF
+		return err
+	})
+	switch {
+	case err == ErrHeaderToLong:
+		return nil, err
+	case malformed:
+		return nil, errors.New("malformed HTTP response").Base(err)
+	case err != nil:
+		return nil, errors.New("no HTTP response").Base(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(header)), nil)
+	if err != nil {
+		buffer.Release()
+		return nil, errors.New("malformed HTTP response").Base(err)
+	}
+	if err := r.expected.Check(resp); err != nil {
+		buffer.Release()
+		return nil, err
+	}
+
+	if buffer.IsEmpty() {
+		buffer.Release()
+		return nil, nil
+	}
+	return buffer, nil
+}
+
 type HeaderWriter struct {
 	header *buf.Buffer
 }
@@ -235,14 +281,14 @@ func (c *Conn) Close() error {
 	return c.Conn.Close()
 }
 
-func formResponseHeader(config *ResponseConfig) *HeaderWriter {
+func formResponseHeader(config *ResponseConfig, remote net.Addr) *HeaderWriter {
 	header := buf.New()
 	common.Must2(header.WriteString(strings.Join([]string{config.GetFullVersion(), config.GetStatusValue().Code, config.GetStatusValue().Reason}, " ")))
 	common.Must2(header.WriteString(CRLF))
 
 	headers := config.PickHeaders()
 	for _, h := range headers {
-		common.Must2(header.WriteString(h))
+		common.Must2(header.WriteString(expandHeader(h, remote)))
 		common.Must2(header.WriteString(CRLF))
 	}
 	if !config.HasHeader("Date") {
@@ -261,6 +307,10 @@ type Authenticator struct {
 }
 
 func (a Authenticator) GetClientWriter() *HeaderWriter {
+	return a.clientWriter(nil)
+}
+
+func (a Authenticator) clientWriter(remote net.Addr) *HeaderWriter {
 	header := buf.New()
 	config := a.config.Request
 	common.Must2(header.WriteString(strings.Join([]string{config.GetMethodValue(), config.PickURI(), config.GetFullVersion()}, " ")))
@@ -268,7 +318,7 @@ func (a Authenticator) GetClientWriter() *HeaderWriter {
 
 	headers := config.PickHeaders()
 	for _, h := range headers {
-		common.Must2(header.WriteString(h))
+		common.Must2(header.WriteString(expandHeader(h, remote)))
 		common.Must2(header.WriteString(CRLF))
 	}
 	common.Must2(header.WriteString(CRLF))
@@ -278,7 +328,7 @@ func (a Authenticator) GetClientWriter() *HeaderWriter {
 }
 
 func (a Authenticator) GetServerWriter() *HeaderWriter {
-	return formResponseHeader(a.config.Response)
+	return formResponseHeader(a.config.Response, nil)
 }
 
 func (a Authenticator) Client(conn net.Conn) net.Conn {
@@ -286,13 +336,15 @@ func (a Authenticator) Client(conn net.Conn) net.Conn {
 		return conn
 	}
 	var reader Reader = NoOpReader{}
-	if a.config.Request != nil {
+	if a.config.Response.HasExpectation() {
+		reader = &ResponseReader{expected: a.config.Response}
+	} else if a.config.Request != nil {
 		reader = new(HeaderReader)
 	}
 
 	var writer Writer = NoOpWriter{}
 	if a.config.Response != nil {
-		writer = a.GetClientWriter()
+		writer = a.clientWriter(conn.RemoteAddr())
 	}
 	return NewConn(conn, reader, writer, NoOpWriter{}, NoOpWriter{}, NoOpWriter{})
 }
@@ -301,10 +353,10 @@ func (a Authenticator) Server(conn net.Conn) net.Conn {
 	if a.config.Request == nil && a.config.Response == nil {
 		return conn
 	}
-	return NewConn(conn, new(HeaderReader).ExpectThisRequest(a.config.Request), a.GetServerWriter(),
-		formResponseHeader(resp400),
-		formResponseHeader(resp404),
-		formResponseHeader(resp400))
+	return NewConn(conn, new(HeaderReader).ExpectThisRequest(a.config.Request), formResponseHeader(a.config.Response, conn.RemoteAddr()),
+		formResponseHeader(resp400, nil),
+		formResponseHeader(resp404, nil),
+		formResponseHeader(resp400, nil))
 }
 
 func NewAuthenticator(ctx context.Context, config *Config) (Authenticator, error) {
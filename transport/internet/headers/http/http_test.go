@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/uuid"
 	. "github.com/xtls/xray-core/transport/internet/headers/http"
 )
 
@@ -303,3 +305,138 @@ func TestConnectionInvReq(t *testing.T) {
 		t.Error("Resp to non http conn", string(l))
 	}
 }
+
+func TestHeaderTemplate(t *testing.T) {
+	auth, err := NewAuthenticator(context.Background(), &Config{
+		Request: &RequestConfig{
+			Uri: []string{"/"},
+			Header: []*Header{
+				{
+					Name:  "X-Request-Id",
+					Value: []string{"{{uuid}}"},
+				},
+				{
+					Name:  "X-Date",
+					Value: []string{"{{date}}"},
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	cache := buf.New()
+	common.Must(auth.GetClientWriter().Write(cache))
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(cache.String())))
+	common.Must(err)
+	if _, err := uuid.ParseString(req.Header.Get("X-Request-Id")); err != nil {
+		t.Error("uuid: ", req.Header.Get("X-Request-Id"))
+	}
+	if _, err := http.ParseTime(req.Header.Get("X-Date")); err != nil {
+		t.Error("date: ", req.Header.Get("X-Date"))
+	}
+}
+
+func TestConnectionExpectResponse(t *testing.T) {
+	newConfig := func(status string, server string) *Config {
+		return &Config{
+			Request: &RequestConfig{
+				Uri: []string{"/"},
+			},
+			Response: &ResponseConfig{
+				Status: &Status{
+					Code:   status,
+					Reason: "Whatever",
+				},
+				Header: []*Header{
+					{
+						Name:  "Server",
+						Value: []string{server},
+					},
+					{
+						Name:  "X-Forwarded-For",
+						Value: []string{"{{remoteIP}}"},
+					},
+				},
+				ExpectStatus: "200",
+				ExpectHeader: []*Header{
+					{
+						Name:  "Server",
+						Value: []string{"nginx/"},
+					},
+					{
+						Name: "X-Forwarded-For",
+					},
+				},
+			},
+		}
+	}
+	serve := func(config *Config) net.Addr {
+		auth, err := NewAuthenticator(context.Background(), config)
+		common.Must(err)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		common.Must(err)
+		go func() {
+			conn, err := listener.Accept()
+			common.Must(err)
+			listener.Close()
+			authConn := auth.Server(conn)
+			b := make([]byte, 256)
+			for {
+				n, err := authConn.Read(b)
+				if err != nil {
+					authConn.Close()
+					break
+				}
+				authConn.Write(b[:n])
+			}
+		}()
+		return listener.Addr()
+	}
+	roundTrip := func(server net.Addr, config *Config) (string, error) {
+		auth, err := NewAuthenticator(context.Background(), config)
+		common.Must(err)
+		conn, err := net.Dial("tcp", server.String())
+		common.Must(err)
+		authConn := auth.Client(conn)
+		defer authConn.Close()
+		authConn.Write([]byte("Test payload"))
+		b := make([]byte, 256)
+		n, err := authConn.Read(b)
+		return string(b[:n]), err
+	}
+
+	if res, err := roundTrip(serve(newConfig("200", "nginx/1.25")), newConfig("200", "nginx/1.25")); err != nil || res != "Test payload" {
+		t.Error("response: ", res, " ", err)
+	}
+	if _, err := roundTrip(serve(newConfig("403", "nginx/1.25")), newConfig("200", "nginx/1.25")); err == nil || !strings.Contains(err.Error(), "status") {
+		t.Error("expect status error, but got ", err)
+	}
+	if _, err := roundTrip(serve(newConfig("200", "cloudflare")), newConfig("200", "nginx/1.25")); err == nil || !strings.Contains(err.Error(), "Server") {
+		t.Error("expect header error, but got ", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		listener.Close()
+		conn.Write([]byte("garbage\r\n\r\n"))
+		conn.Close()
+	}()
+	if _, err := roundTrip(listener.Addr(), newConfig("200", "nginx/1.25")); err == nil || !strings.Contains(err.Error(), "malformed HTTP response") {
+		t.Error("expect malformed response error, but got ", err)
+	}
+
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		listener.Close()
+		conn.Close()
+	}()
+	if _, err := roundTrip(listener.Addr(), newConfig("200", "nginx/1.25")); err == nil || !strings.Contains(err.Error(), "no HTTP response") {
+		t.Error("expect missing response error, but got ", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
@@ -56,6 +57,7 @@ func dialhttpUpgrade(ctx context.Context, dest net.Destination, streamSettings *
 	tConfig := tls.ConfigFromStreamSettings(streamSettings)
 	if tConfig != nil {
 		tlsConfig := tConfig.GetTLSConfig(tls.WithDestination(dest), tls.WithNextProto("http/1.1"))
+		start := time.Now()
 		if fingerprint := tls.GetFingerprint(tConfig.Fingerprint); fingerprint != nil {
 			conn = tls.UClient(pconn, tlsConfig, fingerprint)
 			if err := conn.(*tls.UConn).WebsocketHandshakeContext(ctx); err != nil {
@@ -63,20 +65,18 @@ func dialhttpUpgrade(ctx context.Context, dest net.Destination, streamSettings *
 			}
 		} else {
 			conn = tls.Client(pconn, tlsConfig)
+			if err := conn.(*tls.Conn).HandshakeContext(ctx); err != nil {
+				return nil, err
+			}
 		}
+		internet.ObserveHandshake(ctx, start)
 		requestURL.Scheme = "https"
 	} else {
 		conn = pconn
 		requestURL.Scheme = "http"
 	}
 
-	requestURL.Host = transportConfiguration.Host
-	if requestURL.Host == "" && tConfig != nil {
-		requestURL.Host = tConfig.ServerName
-	}
-	if requestURL.Host == "" {
-		requestURL.Host = dest.Address.String()
-	}
+	requestURL.Host = tls.RequestHost(transportConfiguration.Host, tConfig.GetServerName(), dest)
 	requestURL.Path = transportConfiguration.GetNormalizedPath()
 	req := &http.Request{
 		Method: http.MethodGet,
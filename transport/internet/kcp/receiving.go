@@ -200,7 +200,8 @@ func (w *ReceivingWorker) ReadMultiBuffer() buf.MultiBuffer {
 		seg.Release()
 	}
 
-	return mb
+	// segments carry at most one MTU each, so merge them to save per-buffer overhead downstream
+	return buf.CompactLarge(mb)
 }
 
 func (w *ReceivingWorker) Read(b []byte) int {
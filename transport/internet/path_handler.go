@@ -0,0 +1,30 @@
+package internet
+
+import (
+	"strings"
+
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// HandedConnection is a connection accepted by the listener of an inbound, to be served by the inbound with Tag
+// as its path is handled by it.
+type HandedConnection struct {
+	stat.Connection
+	Tag string
+}
+
+// MatchPathHandler returns the handler of path with the longest prefix of it, or nil if there is none. A handler
+// matches its path and the paths under it.
+func MatchPathHandler(handlers []*PathHandler, path string) *PathHandler {
+	var matched *PathHandler
+	for _, h := range handlers {
+		prefix := strings.TrimSuffix(h.Path, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if matched == nil || len(h.Path) > len(matched.Path) {
+			matched = h
+		}
+	}
+	return matched
+}
@@ -0,0 +1,31 @@
+package internet_test
+
+import (
+	"testing"
+
+	. "github.com/xtls/xray-core/transport/internet"
+)
+
+func TestMatchPathHandler(t *testing.T) {
+	handlers := []*PathHandler{
+		{Path: "/a", Tag: "a"},
+		{Path: "/a/b/", Tag: "b"},
+		{Path: "/", Tag: "root"},
+	}
+	cases := map[string]string{
+		"/a":     "a",
+		"/a/c":   "a",
+		"/ab":    "root",
+		"/a/b":   "b",
+		"/a/b/c": "b",
+		"/x":     "root",
+	}
+	for path, tag := range cases {
+		if h := MatchPathHandler(handlers, path); h == nil || h.Tag != tag {
+			t.Error("unexpected handler of ", path, ": ", h)
+		}
+	}
+	if h := MatchPathHandler(handlers[:2], "/b"); h != nil {
+		t.Error("expected no handler, but got ", h)
+	}
+}
@@ -0,0 +1,30 @@
+package internet
+
+import (
+	"github.com/pires/go-proxyproto"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet/stat"
+)
+
+// ProxyProtocolAddrs returns the source and destination carried by the PROXY protocol header of conn,
+// looking through the TLS, REALITY and counter connections over it. ok is false if conn was not accepted
+// with PROXY protocol, or the header has no addresses, like the LOCAL command of v2 and UNKNOWN of v1.
+func ProxyProtocolAddrs(conn net.Conn) (source net.Addr, destination net.Addr, ok bool) {
+	for conn != nil {
+		switch c := conn.(type) {
+		case *proxyproto.Conn:
+			header := c.ProxyHeader()
+			if header == nil || header.Command.IsLocal() || header.SourceAddr == nil || header.DestinationAddr == nil {
+				return nil, nil, false
+			}
+			return header.SourceAddr, header.DestinationAddr, true
+		case *stat.CounterConnection:
+			conn = c.Connection
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil, nil, false
+		}
+	}
+	return nil, nil, false
+}
@@ -12,7 +12,6 @@ import (
 	"crypto/x509"
 	"encoding/binary"
 	"fmt"
-	"io"
 	"net/http"
 	"reflect"
 	"regexp"
@@ -144,7 +143,7 @@ func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destinati
 		hello.SessionId[2] = core.Version_z
 		hello.SessionId[3] = 0 // reserved
 		binary.BigEndian.PutUint32(hello.SessionId[4:], uint32(time.Now().Unix()))
-		copy(hello.SessionId[8:], config.ShortId)
+		copy(hello.SessionId[8:], config.currentShortId())
 		if config.Show {
 			fmt.Printf("REALITY localAddr: %v\thello.SessionId[:16]: %v\n", localAddr, hello.SessionId[:16])
 		}
@@ -179,7 +178,13 @@ func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destinati
 	if config.Show {
 		fmt.Printf("REALITY localAddr: %v\tuConn.Verified: %v\n", localAddr, uConn.Verified)
 	}
+	if !uConn.Verified && config.SpiderDisable {
+		uConn.Close()
+		time.Sleep(time.Duration(crypto.RandBetween(config.SpiderY[8], config.SpiderY[9])) * time.Millisecond) // return
+		return nil, errors.New("REALITY: processed invalid connection").AtWarning()
+	}
 	if !uConn.Verified {
+		spider := getSpider(ctx, config)
 		go func() {
 			client := &http.Client{
 				Transport: &http2.Transport{
@@ -205,9 +210,8 @@ func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destinati
 			get := func(first bool) {
 				var (
 					req  *http.Request
-					resp *http.Response
-					err  error
 					body []byte
+					ok   bool
 				)
 				if first {
 					req, _ = http.NewRequest("GET", firstURL, nil)
@@ -232,14 +236,10 @@ func UClient(c net.Conn, config *Config, ctx context.Context, dest net.Destinati
 						req.Header.Set("Referer", firstURL)
 					}
 					req.AddCookie(&http.Cookie{Name: "padding", Value: strings.Repeat("0", int(crypto.RandBetween(config.SpiderY[0], config.SpiderY[1])))})
-					if resp, err = client.Do(req); err != nil {
+					if body, ok = spider.do(client, req); !ok {
 						break
 					}
-					defer resp.Body.Close()
 					req.Header.Set("Referer", req.URL.String())
-					if body, err = io.ReadAll(resp.Body); err != nil {
-						break
-					}
 					maps.Lock()
 					for _, m := range href.FindAllSubmatch(body, -1) {
 						m[1] = bytes.TrimPrefix(m[1], prefix)
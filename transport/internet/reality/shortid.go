@@ -0,0 +1,155 @@
+package reality
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtls/reality"
+	"github.com/xtls/xray-core/common/errors"
+)
+
+// DefaultShortIdPeriod is the period of the derived short IDs if it is not set, a day.
+const DefaultShortIdPeriod = 24 * 60 * 60
+
+// DeriveShortId returns the short ID of the period, derived from the seed.
+func DeriveShortId(seed []byte, period int64) [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(period))
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte("REALITY shortId "))
+	mac.Write(b[:])
+	var shortId [8]byte
+	copy(shortId[:], mac.Sum(nil))
+	return shortId
+}
+
+// periodOf returns the period of t, for periods of the seconds.
+func periodOf(t time.Time, seconds uint64) int64 {
+	if seconds == 0 {
+		seconds = DefaultShortIdPeriod
+	}
+	return t.Unix() / int64(seconds)
+}
+
+// currentShortId returns the short ID that the client sends now.
+func (c *Config) currentShortId() []byte {
+	if len(c.ShortIdSeed) == 0 {
+		return c.ShortId
+	}
+	shortId := DeriveShortId(c.ShortIdSeed, periodOf(time.Now(), c.ShortIdPeriod))
+	return shortId[:]
+}
+
+// ServerConfig is the REALITY config of a server. If there is a seed, it accepts the short IDs derived for the current period,
+// and for the periods before and after it in case of clock skew, beside the static short IDs.
+type ServerConfig struct {
+	config *Config
+	base   *reality.Config
+
+	access  sync.Mutex
+	current atomic.Pointer[rotation]
+}
+
+// rotation is the REALITY config of a period.
+type rotation struct {
+	period int64
+	config *reality.Config
+}
+
+// GetServerConfig returns the REALITY config of a server, whose derived short IDs rotate.
+func (c *Config) GetServerConfig() *ServerConfig {
+	return &ServerConfig{
+		config: c,
+		base:   c.GetREALITYConfig(),
+	}
+}
+
+// Get returns the REALITY config to accept a connection with now.
+func (s *ServerConfig) Get() *reality.Config {
+	return s.at(time.Now())
+}
+
+// at returns the REALITY config of the period of now. The config is built again once the period ends.
+func (s *ServerConfig) at(now time.Time) *reality.Config {
+	if len(s.config.ShortIdSeed) == 0 {
+		return s.base
+	}
+	period := periodOf(now, s.config.ShortIdPeriod)
+	if r := s.current.Load(); r != nil && r.period == period {
+		return r.config
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+	if r := s.current.Load(); r != nil && r.period == period {
+		return r.config
+	}
+	// the config must not be modified once used, so it is built for every period
+	config := s.base.Clone()
+	config.Mldsa65Key = s.base.Mldsa65Key
+	config.ShortIds = make(map[[8]byte]bool, len(s.base.ShortIds)+3)
+	for shortId := range s.base.ShortIds {
+		config.ShortIds[shortId] = true
+	}
+	for p := period - 1; p <= period+1; p++ {
+		config.ShortIds[DeriveShortId(s.config.ShortIdSeed, p)] = true
+	}
+	s.current.Store(&rotation{period: period, config: config})
+	errors.LogInfo(context.Background(), "REALITY: rotated the derived short IDs to period ", period)
+	return config
+}
+
+// Base returns the REALITY config without the derived short IDs, which the settings of all periods are the same as.
+func (s *ServerConfig) Base() *reality.Config {
+	return s.base
+}
+
+// listener accepts the REALITY connections of an inner listener, with the config of the time of each connection.
+type listener struct {
+	net.Listener
+	config *ServerConfig
+	conns  chan net.Conn
+	err    error
+}
+
+// NewListener creates a listener like the one of REALITY, whose derived short IDs rotate.
+func NewListener(inner net.Listener, config *ServerConfig) net.Listener {
+	go reality.DetectPostHandshakeRecordsLens(config.Base())
+	l := &listener{
+		Listener: inner,
+		config:   config,
+		conns:    make(chan net.Conn),
+	}
+	go func() {
+		for {
+			c, err := l.Listener.Accept()
+			if err != nil {
+				l.err = err
+				close(l.conns)
+				return
+			}
+			go func() {
+				defer func() { recover() }()
+				c, err := reality.Server(context.Background(), c, l.config.Get())
+				if err == nil {
+					l.conns <- c
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// Accept implements net.Listener.
+func (l *listener) Accept() (net.Conn, error) {
+	if c, ok := <-l.conns; ok {
+		return c, nil
+	}
+	return nil, l.err
+}
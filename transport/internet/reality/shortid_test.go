@@ -0,0 +1,68 @@
+package reality
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerConfigShortIdRotation(t *testing.T) {
+	static := [8]byte{0x12, 0x34}
+	config := &Config{
+		PrivateKey:    make([]byte, 32),
+		ShortIds:      [][]byte{static[:]},
+		ShortIdSeed:   []byte("seed"),
+		ShortIdPeriod: 3600,
+	}
+	server := config.GetServerConfig()
+
+	const period = 500000
+	start := time.Unix(period*3600, 0)
+	accepted := server.at(start).ShortIds
+	if !accepted[static] {
+		t.Error("static short ID not accepted")
+	}
+	for p := int64(period - 1); p <= period+1; p++ {
+		if !accepted[DeriveShortId(config.ShortIdSeed, p)] {
+			t.Error("short ID of period ", p, " not accepted in period ", period)
+		}
+	}
+	for _, p := range []int64{period - 2, period + 2} {
+		if accepted[DeriveShortId(config.ShortIdSeed, p)] {
+			t.Error("short ID of period ", p, " accepted in period ", period)
+		}
+	}
+
+	// the last second of the period before
+	accepted = server.at(start.Add(-time.Second)).ShortIds
+	if !accepted[DeriveShortId(config.ShortIdSeed, period-2)] || accepted[DeriveShortId(config.ShortIdSeed, period+1)] {
+		t.Error("unexpected short IDs at the end of period ", period-1)
+	}
+	// the last second of the period
+	end := start.Add(3599 * time.Second)
+	if server.at(end) != server.at(start) {
+		t.Error("expected the same config within a period")
+	}
+	accepted = server.at(end.Add(time.Second)).ShortIds
+	if !accepted[DeriveShortId(config.ShortIdSeed, period+2)] || accepted[DeriveShortId(config.ShortIdSeed, period-1)] {
+		t.Error("unexpected short IDs at the start of period ", period+1)
+	}
+}
+
+func TestServerConfigStaticShortIds(t *testing.T) {
+	config := &Config{
+		PrivateKey: make([]byte, 32),
+		ShortIds:   [][]byte{make([]byte, 8)},
+	}
+	server := config.GetServerConfig()
+	if server.Get() != server.Base() || len(server.Get().ShortIds) != 1 {
+		t.Error("expected the static short IDs only")
+	}
+}
+
+func TestClientShortId(t *testing.T) {
+	config := &Config{ShortIdSeed: []byte("seed")}
+	expected := DeriveShortId(config.ShortIdSeed, time.Now().Unix()/DefaultShortIdPeriod)
+	if shortId := config.currentShortId(); string(shortId) != string(expected[:]) {
+		t.Error("unexpected short ID of the client: ", shortId)
+	}
+}
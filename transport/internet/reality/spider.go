@@ -0,0 +1,102 @@
+package reality
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	// DefaultSpiderConcurrency is the most requests of the spider of an outbound at a time if not configured.
+	DefaultSpiderConcurrency = 16
+	// DefaultSpiderTimeout is the timeout of a request of the spider if not configured.
+	DefaultSpiderTimeout = 10 * time.Second
+)
+
+// spider bounds the requests that the crawlers of an outbound send to the target site.
+type spider struct {
+	slots   chan struct{}
+	timeout time.Duration
+	success stats.Counter
+	failure stats.Counter
+}
+
+// spiders is the spider of each outbound, by the Config of it. The outbound releases it when it goes away.
+var spiders sync.Map
+
+// ReleaseSpider forgets the spider of the outbound with config, whose handler is closed or removed.
+func ReleaseSpider(config *Config) {
+	spiders.Delete(config)
+}
+
+// SpiderStatName returns the name of the stats counter of the requests of the spider of the outbound with the
+// given tag, where result is "success" or "failure".
+func SpiderStatName(tag string, result string) string {
+	return "outbound>>>" + tag + ">>>spider>>>" + result
+}
+
+func getSpider(ctx context.Context, config *Config) *spider {
+	if s, found := spiders.Load(config); found {
+		return s.(*spider)
+	}
+	s := &spider{
+		slots:   make(chan struct{}, DefaultSpiderConcurrency),
+		timeout: DefaultSpiderTimeout,
+	}
+	if config.SpiderConcurrency > 0 {
+		s.slots = make(chan struct{}, config.SpiderConcurrency)
+	}
+	if config.SpiderTimeout > 0 {
+		s.timeout = time.Duration(config.SpiderTimeout) * time.Second
+	}
+	if outbounds := session.OutboundsFromContext(ctx); len(outbounds) > 0 && len(outbounds[len(outbounds)-1].Tag) > 0 {
+		if v := core.FromContext(ctx); v != nil {
+			if sm, ok := v.GetFeature(stats.ManagerType()).(stats.Manager); ok {
+				tag := outbounds[len(outbounds)-1].Tag
+				s.success, _ = stats.GetOrRegisterCounter(sm, SpiderStatName(tag, "success"))
+				s.failure, _ = stats.GetOrRegisterCounter(sm, SpiderStatName(tag, "failure"))
+			}
+		}
+	}
+	actual, _ := spiders.LoadOrStore(config, s)
+	return actual.(*spider)
+}
+
+// do sends req with client and returns the body of the response. It returns false without sending req if the
+// spider has too many requests outstanding.
+func (s *spider) do(client *http.Client, req *http.Request) ([]byte, bool) {
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return nil, false
+	}
+	defer func() { <-s.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		s.count(s.failure)
+		return nil, false
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		s.count(s.failure)
+		return nil, false
+	}
+	s.count(s.success)
+	return body, true
+}
+
+func (s *spider) count(c stats.Counter) {
+	if c != nil {
+		c.Add(1)
+	}
+}
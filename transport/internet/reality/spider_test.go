@@ -0,0 +1,76 @@
+package reality
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/app/stats"
+)
+
+func TestSpider(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := &Config{SpiderConcurrency: 1, SpiderTimeout: 1}
+	s := getSpider(context.Background(), config)
+	if s != getSpider(context.Background(), config) {
+		t.Error("expected the same spider for the same config")
+	}
+	if cap(s.slots) != 1 || s.timeout != time.Second {
+		t.Error("unexpected spider limits: ", cap(s.slots), " ", s.timeout)
+	}
+	s.success = new(stats.Counter)
+	s.failure = new(stats.Counter)
+
+	req, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if body, ok := s.do(server.Client(), req); !ok || string(body) != "ok" {
+		t.Error("unexpected response: ", string(body), " ", ok)
+	}
+
+	// a request over the limit is skipped while the slow one is outstanding
+	done := make(chan bool)
+	go func() {
+		req, _ := http.NewRequest("GET", server.URL+"/slow", nil)
+		_, ok := s.do(server.Client(), req)
+		done <- ok
+	}()
+	for len(s.slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := s.do(server.Client(), req); ok {
+		t.Error("expected the request over the limit to be skipped")
+	}
+	if ok := <-done; ok {
+		t.Error("expected the slow request to time out")
+	}
+
+	if s.success.Value() != 1 || s.failure.Value() != 1 {
+		t.Error("unexpected counters: ", s.success.Value(), " ", s.failure.Value())
+	}
+}
+
+func TestReleaseSpider(t *testing.T) {
+	config := &Config{}
+	s := getSpider(context.Background(), config)
+	ReleaseSpider(config)
+	if _, found := spiders.Load(config); found {
+		t.Fatal("the spider is still kept")
+	}
+	if getSpider(context.Background(), config) == s {
+		t.Error("expected a new spider")
+	}
+	ReleaseSpider(config)
+}
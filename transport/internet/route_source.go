@@ -0,0 +1,55 @@
+package internet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/cache"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// routeSourceTTL is how long the source of a route is kept, unless the routes change before.
+const routeSourceTTL = 10 * time.Second
+
+// routeSourceCapacity is how many sources of routes are kept at most, as sendThrough "auto" looks up the route to every destination.
+const routeSourceCapacity = 1024
+
+type routeSource struct {
+	src    net.Address
+	expire time.Time
+}
+
+var routeSources = struct {
+	sync.Mutex
+	entries cache.Lru
+}{entries: cache.NewLru(routeSourceCapacity)}
+
+// RouteSource returns the source address that the system routes the packets to dest from right now.
+// It is only supported on Linux.
+func RouteSource(dest net.IP) (net.Address, error) {
+	key := dest.String()
+	now := time.Now()
+
+	routeSources.Lock()
+	entries := routeSources.entries
+	routeSources.Unlock()
+	if s, found := entries.Get(key); found && now.Before(s.(*routeSource).expire) {
+		return s.(*routeSource).src, nil
+	}
+
+	src, err := lookupRouteSource(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sources are not kept if the routes changed during the lookup
+	entries.Put(key, &routeSource{src: src, expire: now.Add(routeSourceTTL)})
+	return src, nil
+}
+
+func forgetRouteSources() {
+	routeSources.Lock()
+	defer routeSources.Unlock()
+
+	routeSources.entries = cache.NewLru(routeSourceCapacity)
+}
@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package internet
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+var watchRoutesOnce sync.Once
+
+// watchRoutes forgets the sources of the routes whenever the routing table changes.
+func watchRoutes() {
+	updates := make(chan netlink.RouteUpdate)
+	if err := netlink.RouteSubscribe(updates, nil); err != nil {
+		errors.LogInfoInner(context.Background(), err, "failed to watch the route changes, the sources of the routes expire by time only")
+		return
+	}
+	go func() {
+		for range updates {
+			forgetRouteSources()
+		}
+	}()
+}
+
+func lookupRouteSource(dest net.IP) (net.Address, error) {
+	watchRoutesOnce.Do(watchRoutes)
+
+	routes, err := netlink.RouteGet(dest)
+	if err != nil {
+		return nil, errors.New("failed to get the route to ", dest).Base(err)
+	}
+	family := netlink.FAMILY_V4
+	if dest.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+	for _, route := range routes {
+		if route.Src != nil {
+			return net.IPAddress(route.Src), nil
+		}
+		// the route has no preferred source, so take an address of its interface
+		link, err := netlink.LinkByIndex(route.LinkIndex)
+		if err != nil {
+			continue
+		}
+		if addrs, err := netlink.AddrList(link, family); err == nil && len(addrs) > 0 {
+			return net.IPAddress(addrs[0].IP), nil
+		}
+	}
+	return nil, errors.New("no source address of the route to ", dest)
+}
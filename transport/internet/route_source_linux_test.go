@@ -0,0 +1,20 @@
+package internet
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestRouteSourceCapacity(t *testing.T) {
+	forgetRouteSources()
+	first := net.IPAddress([]byte{127, 0, 0, 1}).IP()
+	common.Must2(RouteSource(first))
+	for i := 0; i < routeSourceCapacity; i++ {
+		common.Must2(RouteSource(net.IPAddress([]byte{127, 1, byte(i >> 8), byte(i)}).IP()))
+	}
+	if _, found := routeSources.entries.Get(first.String()); found {
+		t.Error("expected the least recently used source to be evicted")
+	}
+}
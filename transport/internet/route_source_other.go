@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package internet
+
+import (
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+func lookupRouteSource(dest net.IP) (net.Address, error) {
+	return nil, errors.New("the source of the route to ", dest, " is only supported on Linux")
+}
@@ -0,0 +1,179 @@
+package internet
+
+import (
+	"context"
+	gonet "net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/dice"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+const (
+	// defaultServerAddressTTL is how long the IPs of a server are cached if only pinnedIPs is set.
+	defaultServerAddressTTL = 10 * time.Minute
+	// serverAddressRetry is how long the last good IPs are kept after a failed resolution, before it is tried again.
+	serverAddressRetry = 30 * time.Second
+	// serverAddressIdle is how long the expired IPs of a server are kept without a dial to it, such as
+	// the servers of removed outbounds, before they are evicted.
+	serverAddressIdle = time.Hour
+)
+
+// ServerAddress is a domain of a server of an outbound, as resolved by the dialer.
+type ServerAddress struct {
+	Domain string
+	IPs    []net.IP
+	Expire time.Time
+	// Stale is set when the last resolution failed, or returned no pinned IP, and the IPs are the last good ones.
+	Stale bool
+
+	used time.Time
+}
+
+type serverAddressKey struct {
+	tag    string
+	domain string
+}
+
+// serverAddresses caches the IPs of the servers of the outbounds that set serverAddressTTL or pinnedIPs,
+// apart from the DNS app, whose cache and answers are shared with the routed traffic.
+var serverAddresses struct {
+	sync.Mutex
+	entries map[serverAddressKey]*ServerAddress
+}
+
+// cachesServerAddress returns whether the dialer resolves the domains of the servers itself.
+func (c *SocketConfig) cachesServerAddress() bool {
+	return c.ServerAddressTtl > 0 || len(c.PinnedIps) > 0
+}
+
+// resolveServerAddress returns the IPs of the server domain of the outbound tag, resolving it if they have expired.
+// If the resolution fails, or none of its IPs is pinned, the last good IPs are used until it is tried again.
+func resolveServerAddress(ctx context.Context, tag string, domain string, sockopt *SocketConfig, src net.Address) ([]net.IP, error) {
+	key := serverAddressKey{tag: tag, domain: domain}
+	serverAddresses.Lock()
+	entry := serverAddresses.entries[key]
+	if now := time.Now(); entry != nil && now.Before(entry.Expire) {
+		entry.used = now
+		ips := entry.IPs
+		serverAddresses.Unlock()
+		return ips, nil
+	}
+	serverAddresses.Unlock()
+
+	ips, err := lookupServerAddress(ctx, domain, sockopt, src)
+	if err == nil {
+		ips, err = pinIPs(ips, sockopt.PinnedIps)
+	}
+
+	serverAddresses.Lock()
+	defer serverAddresses.Unlock()
+
+	now := time.Now()
+	evictServerAddresses(now)
+	if err != nil {
+		if entry == nil {
+			return nil, err
+		}
+		errors.LogWarningInner(ctx, err, "failed to resolve the server ", domain, ", keeping its last good IPs ", entry.IPs)
+		entry.Expire = now.Add(serverAddressRetry)
+		entry.Stale = true
+		entry.used = now
+		serverAddresses.entries[key] = entry
+		return entry.IPs, nil
+	}
+
+	ttl := time.Duration(sockopt.ServerAddressTtl) * time.Second
+	if ttl == 0 {
+		ttl = defaultServerAddressTTL
+	}
+	if serverAddresses.entries == nil {
+		serverAddresses.entries = make(map[serverAddressKey]*ServerAddress)
+	}
+	serverAddresses.entries[key] = &ServerAddress{
+		Domain: domain,
+		IPs:    ips,
+		Expire: now.Add(ttl),
+		used:   now,
+	}
+	return ips, nil
+}
+
+// evictServerAddresses removes the entries that have expired and have not been dialed for serverAddressIdle.
+// It runs when a server is resolved, with serverAddresses locked.
+func evictServerAddresses(now time.Time) {
+	for key, entry := range serverAddresses.entries {
+		if now.After(entry.Expire) && now.Sub(entry.used) > serverAddressIdle {
+			delete(serverAddresses.entries, key)
+		}
+	}
+}
+
+func lookupServerAddress(ctx context.Context, domain string, sockopt *SocketConfig, src net.Address) ([]net.IP, error) {
+	if sockopt.IpFamilyPreference.HasPreference() {
+		return LookupWithPreferenceVia(ctx, sockopt.ResolveVia, domain, sockopt.IpFamilyPreference, src)
+	}
+	strategy := sockopt.DomainStrategy
+	if !strategy.HasStrategy() {
+		strategy = DomainStrategy_USE_IP
+	}
+	return LookupForIPVia(ctx, sockopt.ResolveVia, domain, strategy, src)
+}
+
+// pinIPs returns the IPs that are in the pinned IPs and CIDRs, or an error if there is none. No pin lets any IP through.
+func pinIPs(ips []net.IP, pinned []string) ([]net.IP, error) {
+	if len(pinned) == 0 {
+		return ips, nil
+	}
+	var kept []net.IP
+	for _, ip := range ips {
+		for _, pin := range pinned {
+			if pinMatches(pin, ip) {
+				kept = append(kept, ip)
+				break
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return nil, errors.New("none of the IPs ", ips, " is pinned")
+	}
+	return kept, nil
+}
+
+func pinMatches(pin string, ip net.IP) bool {
+	if strings.Contains(pin, "/") {
+		_, ipNet, err := gonet.ParseCIDR(pin)
+		return err == nil && ipNet.Contains(ip)
+	}
+	pinIP := gonet.ParseIP(pin)
+	return pinIP != nil && pinIP.Equal(ip)
+}
+
+// pickServerIP picks an IP to dial, of the preferred family if there is a preference.
+func pickServerIP(ips []net.IP, sockopt *SocketConfig) net.IP {
+	if sockopt.IpFamilyPreference.HasPreference() {
+		return PickIP(ips)
+	}
+	return ips[dice.Roll(len(ips))]
+}
+
+// ServerAddresses returns the server domains of the outbound tag that the dialer has resolved, for debugging.
+func ServerAddresses(tag string) []ServerAddress {
+	serverAddresses.Lock()
+	defer serverAddresses.Unlock()
+
+	var addresses []ServerAddress
+	for key, entry := range serverAddresses.entries {
+		if key.tag == tag {
+			addresses = append(addresses, *entry)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Domain < addresses[j].Domain
+	})
+	return addresses
+}
@@ -0,0 +1,33 @@
+package internet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common/net"
+)
+
+func TestEvictServerAddresses(t *testing.T) {
+	now := time.Now()
+	ips := []net.IP{net.LocalHostIP.IP()}
+	serverAddresses.Lock()
+	serverAddresses.entries = map[serverAddressKey]*ServerAddress{
+		{tag: "removed", domain: "idle.example.com"}:  {Domain: "idle.example.com", IPs: ips, Expire: now.Add(-time.Minute), used: now.Add(-2 * serverAddressIdle)},
+		{tag: "proxy", domain: "expired.example.com"}: {Domain: "expired.example.com", IPs: ips, Expire: now.Add(-time.Minute), used: now.Add(-time.Minute)},
+		{tag: "proxy", domain: "valid.example.com"}:   {Domain: "valid.example.com", IPs: ips, Expire: now.Add(time.Minute), used: now.Add(-2 * serverAddressIdle)},
+	}
+	evictServerAddresses(now)
+	serverAddresses.Unlock()
+	defer func() {
+		serverAddresses.Lock()
+		serverAddresses.entries = nil
+		serverAddresses.Unlock()
+	}()
+
+	if addresses := ServerAddresses("removed"); len(addresses) != 0 {
+		t.Error("expected the idle server to be evicted, but got ", addresses)
+	}
+	if addresses := ServerAddresses("proxy"); len(addresses) != 2 {
+		t.Error("expected the servers dialed recently or not expired to be kept, but got ", addresses)
+	}
+}
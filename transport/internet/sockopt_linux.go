@@ -53,6 +53,16 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		}
 	}
 
+	if config.Freebind {
+		level, opt := syscall.SOL_IP, unix.IP_FREEBIND
+		if strings.HasSuffix(network, "6") {
+			level, opt = syscall.SOL_IPV6, unix.IPV6_FREEBIND
+		}
+		if err := syscall.SetsockoptInt(int(fd), level, opt, 1); err != nil {
+			return errors.New("failed to set FREEBIND").Base(err)
+		}
+	}
+
 	if isTCPSocket(network) {
 		tfo := config.ParseTFOValue()
 		if tfo > 0 {
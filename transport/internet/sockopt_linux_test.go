@@ -2,6 +2,7 @@ package internet_test
 
 import (
 	"context"
+	"strings"
 	"syscall"
 	"testing"
 
@@ -40,3 +41,38 @@ func TestSockOptMark(t *testing.T) {
 	})
 	common.Must(err)
 }
+
+func TestRouteSource(t *testing.T) {
+	src, err := RouteSource(net.ParseAddress("127.0.0.1").IP())
+	common.Must(err)
+	if src.String() != "127.0.0.1" {
+		t.Error("unexpected source of the route to loopback: ", src)
+	}
+}
+
+func TestSockOptFreebind(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	// an address on no interface of the host
+	src := net.ParseAddress("192.0.2.1")
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), src, dest, &SocketConfig{Freebind: true})
+	if err == nil {
+		// the kernel binds to it, while the server can not answer it
+		conn.Close()
+	} else if strings.Contains(err.Error(), "failed to bind") {
+		t.Error("failed to bind with FREEBIND: ", err)
+	}
+
+	_, err = dialer.Dial(context.Background(), src, dest, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to bind to source address 192.0.2.1") {
+		t.Error("expected an error of binding without FREEBIND, but got ", err)
+	}
+}
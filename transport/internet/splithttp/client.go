@@ -122,6 +122,10 @@ func (c *DefaultDialerClient) PostPacket(ctx context.Context, url string, body i
 		requestBuff := new(bytes.Buffer)
 		common.Must(req.Write(requestBuff))
 
+		if waitResponse, _ := ctx.Value(waitResponseKey{}).(bool); waitResponse {
+			return c.postAndWaitResponse(ctx, req, requestBuff.Bytes())
+		}
+
 		var uploadConn any
 		var h1UploadConn *H1Conn
 
@@ -172,6 +176,39 @@ func (c *DefaultDialerClient) PostPacket(ctx context.Context, url string, body i
 	return nil
 }
 
+type waitResponseKey struct{}
+
+// withWaitResponse makes the HTTP/1.1 posts wait for their responses, which they do not by default.
+func withWaitResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, waitResponseKey{}, true)
+}
+
+// postAndWaitResponse posts the request on a new connection, as the pooled ones may have unread responses.
+func (c *DefaultDialerClient) postAndWaitResponse(ctx context.Context, req *http.Request, request []byte) error {
+	newConn, err := c.dialUploadConn(context.WithoutCancel(ctx))
+	if err != nil {
+		return err
+	}
+	h1UploadConn := NewH1Conn(newConn)
+	if _, err := h1UploadConn.Write(request); err != nil {
+		h1UploadConn.Close()
+		return err
+	}
+	resp, err := http.ReadResponse(h1UploadConn.RespBufReader, req)
+	if err != nil {
+		h1UploadConn.Close()
+		return fmt.Errorf("error while reading response: %s", err.Error())
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		h1UploadConn.Close()
+		return fmt.Errorf("got non-200 error response code: %d", resp.StatusCode)
+	}
+	c.uploadRawPool.Put(h1UploadConn)
+	return nil
+}
+
 type WaitReadCloser struct {
 	Wait chan struct{}
 	io.ReadCloser
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/crypto"
@@ -11,7 +12,19 @@ import (
 )
 
 func (c *Config) GetNormalizedPath() string {
-	pathAndQuery := strings.SplitN(c.Path, "?", 2)
+	return normalizePath(c.Path)
+}
+
+// GetNormalizedFallbackPath returns the path of the WebSocket fallback, which is empty if it is not enabled.
+func (c *Config) GetNormalizedFallbackPath() string {
+	if c.FallbackTransport != "ws" {
+		return ""
+	}
+	return normalizePath(c.FallbackPath)
+}
+
+func normalizePath(rawPath string) string {
+	pathAndQuery := strings.SplitN(rawPath, "?", 2)
 	path := pathAndQuery[0]
 
 	if path == "" || path[0] != '/' {
@@ -121,6 +134,30 @@ func (c *Config) GetNormalizedScStreamUpServerSecs() RangeConfig {
 	return *c.ScMinPostsIntervalMs
 }
 
+func (c *Config) GetNormalizedFallbackUploadFailures() int {
+	if c.FallbackUploadFailures == 0 {
+		return 3
+	}
+
+	return int(c.FallbackUploadFailures)
+}
+
+func (c *Config) GetNormalizedFallbackWindow() time.Duration {
+	if c.FallbackWindowSecs == 0 {
+		return 10 * time.Second
+	}
+
+	return time.Duration(c.FallbackWindowSecs) * time.Second
+}
+
+func (c *Config) GetNormalizedFallbackPin() time.Duration {
+	if c.FallbackPinSecs == 0 {
+		return 10 * time.Minute
+	}
+
+	return time.Duration(c.FallbackPinSecs) * time.Second
+}
+
 func (m *XmuxConfig) GetNormalizedMaxConcurrency() RangeConfig {
 	if m.MaxConcurrency == nil {
 		return RangeConfig{
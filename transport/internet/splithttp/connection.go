@@ -3,13 +3,17 @@ package splithttp
 import (
 	"io"
 	"net"
+	"sync"
 	"time"
+
+	"github.com/xtls/xray-core/common/buf"
 )
 
 type splitConn struct {
 	writer     io.WriteCloser
 	reader     io.ReadCloser
 	remoteAddr net.Addr
+	peerAddr   net.Addr
 	localAddr  net.Addr
 	onClose    func()
 }
@@ -22,6 +26,13 @@ func (c *splitConn) Read(b []byte) (int, error) {
 	return c.reader.Read(b)
 }
 
+// ReadMultiBuffer implements buf.Reader.
+// Uploaded packets and download response bodies are usually larger than a regular buffer.
+func (c *splitConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	b, err := buf.ReadLargeBuffer(c.reader)
+	return buf.MultiBuffer{b}, err
+}
+
 func (c *splitConn) Close() error {
 	if c.onClose != nil {
 		c.onClose()
@@ -48,6 +59,14 @@ func (c *splitConn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
 
+// PeerAddr implements internet.ForwardedConnection.
+func (c *splitConn) PeerAddr() net.Addr {
+	if c.peerAddr == nil {
+		return c.remoteAddr
+	}
+	return c.peerAddr
+}
+
 func (c *splitConn) SetDeadline(t time.Time) error {
 	// TODO cannot do anything useful
 	return nil
@@ -62,3 +81,19 @@ func (c *splitConn) SetWriteDeadline(t time.Time) error {
 	// TODO cannot do anything useful
 	return nil
 }
+
+// downloadReader is the download of a connection whose upload goes over another path. It calls onEnd once the
+// download ends, to tear down the upload with it.
+type downloadReader struct {
+	io.ReadCloser
+	onEnd func()
+	once  sync.Once
+}
+
+func (r *downloadReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if err != nil && r.onEnd != nil {
+		r.once.Do(r.onEnd)
+	}
+	return n, err
+}
@@ -26,13 +26,16 @@ import (
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/udp"
 	"github.com/xtls/xray-core/transport/pipe"
 	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/proto"
 )
 
 type dialerConf struct {
 	net.Destination
 	*internet.MemoryStreamConfig
+	chain string
 }
 
 var (
@@ -54,7 +57,7 @@ func getHTTPClient(ctx context.Context, dest net.Destination, streamSettings *in
 		globalDialerMap = make(map[dialerConf]*XmuxManager)
 	}
 
-	key := dialerConf{dest, streamSettings}
+	key := dialerConf{dest, streamSettings, internet.DialerChainKey(ctx)}
 
 	xmuxManager, found := globalDialerMap[key]
 
@@ -117,8 +120,13 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 			return nil, err
 		}
 
+		start := time.Now()
 		if realityConfig != nil {
-			return reality.UClient(conn, realityConfig, ctxInner, dest)
+			if conn, err = reality.UClient(conn, realityConfig, ctxInner, dest); err != nil {
+				return nil, err
+			}
+			internet.ObserveHandshake(ctxInner, start)
+			return conn, nil
 		}
 
 		if gotlsConfig != nil {
@@ -129,7 +137,11 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 				}
 			} else {
 				conn = tls.Client(conn, gotlsConfig)
+				if err := conn.(*tls.Conn).HandshakeContext(ctxInner); err != nil {
+					return nil, err
+				}
 			}
+			internet.ObserveHandshake(ctxInner, start)
 		}
 
 		return conn, nil
@@ -172,17 +184,17 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 
 				switch c := conn.(type) {
 				case *internet.PacketConnWrapper:
-					var ok bool
-					udpConn, ok = c.Conn.(*net.UDPConn)
+					uc, ok := c.Conn.(*net.UDPConn)
 					if !ok {
 						return nil, errors.New("PacketConnWrapper does not contain a UDP connection")
 					}
+					udpConn = offloadConn(uc, streamSettings)
 					udpAddr, err = net.ResolveUDPAddr("udp", c.Dest.String())
 					if err != nil {
 						return nil, err
 					}
 				case *net.UDPConn:
-					udpConn = c
+					udpConn = offloadConn(c, streamSettings)
 					udpAddr, err = net.ResolveUDPAddr("udp", c.RemoteAddr().String())
 					if err != nil {
 						return nil, err
@@ -195,6 +207,8 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 					}
 				}
 
+				// http3 sets the SNI to the Host if it is empty, which is not the case with noSNI
+				tlsCfg.ServerName = gotlsConfig.ServerName
 				return quic.DialEarly(ctx, udpConn, udpAddr, tlsCfg, cfg)
 			},
 		}
@@ -240,10 +254,28 @@ func createHTTPClient(dest net.Destination, streamSettings *internet.MemoryStrea
 	return client
 }
 
+// offloadConn lets quic-go read UDP_GRO coalesced datagrams if udpGSO is set.
+// quic-go segments its sends with UDP_SEGMENT on its own.
+func offloadConn(conn *net.UDPConn, streamSettings *internet.MemoryStreamConfig) net.PacketConn {
+	if streamSettings.SocketSettings.GetUdpGso() {
+		return udp.NewOffloadConn(conn)
+	}
+	return conn
+}
+
 func init() {
 	common.Must(internet.RegisterTransportDialer(protocolName, Dial))
 }
 
+// requestHost returns the Host of the requests to dest, which the server name of REALITY is used as like the one of TLS.
+func requestHost(config *Config, tlsConfig *tls.Config, realityConfig *reality.Config, dest net.Destination) string {
+	serverName := tlsConfig.GetServerName()
+	if serverName == "" {
+		serverName = realityConfig.GetServerName()
+	}
+	return tls.RequestHost(config.Host, serverName, dest)
+}
+
 func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (stat.Connection, error) {
 	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
 	realityConfig := reality.ConfigFromStreamSettings(streamSettings)
@@ -261,16 +293,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	} else {
 		requestURL.Scheme = "http"
 	}
-	requestURL.Host = transportConfiguration.Host
-	if requestURL.Host == "" && tlsConfig != nil {
-		requestURL.Host = tlsConfig.ServerName
-	}
-	if requestURL.Host == "" && realityConfig != nil {
-		requestURL.Host = realityConfig.ServerName
-	}
-	if requestURL.Host == "" {
-		requestURL.Host = dest.Address.String()
-	}
+	requestURL.Host = requestHost(transportConfiguration, tlsConfig, realityConfig, dest)
 
 	sessionIdUuid := uuid.New()
 	requestURL.Path = transportConfiguration.GetNormalizedPath() + sessionIdUuid.String()
@@ -278,32 +301,39 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 
 	httpClient, xmuxClient := getHTTPClient(ctx, dest, streamSettings)
 
+	// the download goes over another path than the upload, which the server correlates by the session ID.
+	// The posts of packet-up carry their sequence numbers, so the server reorders the upload, up to
+	// scMaxBufferedPosts ahead, whichever path they take, and the download is a single stream in order.
+	// Only the two directions are not ordered against each other, which the proxies do not rely on.
+	splitDownload := transportConfiguration.DownloadSettings != nil || transportConfiguration.DownloadDialerProxy != ""
+
 	mode := transportConfiguration.Mode
 	if mode == "" || mode == "auto" {
 		mode = "packet-up"
 		if realityConfig != nil {
 			mode = "stream-one"
-			if transportConfiguration.DownloadSettings != nil {
+			if splitDownload {
 				mode = "stream-up"
 			}
 		}
 	}
 
+	// the fallback is a WebSocket over HTTP/1.1, which can not be made over REALITY, h3 or the browser dialer
+	fallbackKey := dialerConf{dest, streamSettings, internet.DialerChainKey(ctx)}
+	useFallback := mode == "packet-up" && transportConfiguration.GetNormalizedFallbackPath() != "" &&
+		realityConfig == nil && httpVersion != "3" && !browser_dialer.HasBrowserDialer()
+	if useFallback && isFallbackPinned(fallbackKey) {
+		errors.LogInfo(ctx, "XHTTP is dialing to ", dest, " over WebSocket fallback")
+		return dialFallback(ctx, dest, streamSettings, "")
+	}
+
 	errors.LogInfo(ctx, fmt.Sprintf("XHTTP is dialing to %s, mode %s, HTTP version %s, host %s", dest, mode, httpVersion, requestURL.Host))
 
 	requestURL2 := requestURL
 	httpClient2 := httpClient
 	xmuxClient2 := xmuxClient
-	if transportConfiguration.DownloadSettings != nil {
-		globalDialerAccess.Lock()
-		if streamSettings.DownloadSettings == nil {
-			streamSettings.DownloadSettings = common.Must2(internet.ToMemoryStreamConfig(transportConfiguration.DownloadSettings))
-			if streamSettings.SocketSettings != nil && streamSettings.SocketSettings.Penetrate {
-				streamSettings.DownloadSettings.SocketSettings = streamSettings.SocketSettings
-			}
-		}
-		globalDialerAccess.Unlock()
-		memory2 := streamSettings.DownloadSettings
+	if splitDownload {
+		memory2 := downloadStreamSettings(dest, streamSettings)
 		dest2 := *memory2.Destination // just panic
 		tlsConfig2 := tls.ConfigFromStreamSettings(memory2)
 		realityConfig2 := reality.ConfigFromStreamSettings(memory2)
@@ -317,16 +347,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 			requestURL2.Scheme = "http"
 		}
 		config2 := memory2.ProtocolSettings.(*Config)
-		requestURL2.Host = config2.Host
-		if requestURL2.Host == "" && tlsConfig2 != nil {
-			requestURL2.Host = tlsConfig2.ServerName
-		}
-		if requestURL2.Host == "" && realityConfig2 != nil {
-			requestURL2.Host = realityConfig2.ServerName
-		}
-		if requestURL2.Host == "" {
-			requestURL2.Host = dest2.Address.String()
-		}
+		requestURL2.Host = requestHost(config2, tlsConfig2, realityConfig2, dest2)
 		requestURL2.Path = config2.GetNormalizedPath() + sessionIdUuid.String()
 		requestURL2.RawQuery = config2.GetNormalizedQuery()
 		httpClient2, xmuxClient2 = getHTTPClient(ctx, dest2, memory2)
@@ -377,14 +398,27 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 			return nil, err
 		}
 	}
+	// over separate paths, one may fail while the other goes on, so both are torn down once either ends
+	var download *downloadReader
+	if splitDownload {
+		download = &downloadReader{ReadCloser: conn.reader}
+		conn.reader = download
+	}
 	if mode == "stream-up" {
 		if xmuxClient != nil {
 			xmuxClient.LeftRequests.Add(-1)
 		}
-		_, _, _, err = httpClient.OpenStream(ctx, requestURL.String(), reader, true)
+		upload, _, _, err := httpClient.OpenStream(ctx, requestURL.String(), reader, true)
 		if err != nil { // browser dialer only
 			return nil, err
 		}
+		if download != nil {
+			download.onEnd = func() { writer.Close() }
+			go func() {
+				io.Copy(io.Discard, upload)
+				download.Close()
+			}()
+		}
 		return stat.Connection(&conn), nil
 	}
 
@@ -407,6 +441,15 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		maxUploadSize,
 	}
 
+	if download != nil {
+		download.onEnd = uploadPipeReader.Interrupt
+	}
+
+	var fallback *uploadFallback
+	if useFallback {
+		fallback = newUploadFallback(ctx, fallbackKey, sessionIdUuid.String(), uploadPipeReader.Interrupt)
+	}
+
 	go func() {
 		var seq int64
 		var lastWrite time.Time
@@ -439,6 +482,16 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 				break
 			}
 
+			if fallback != nil {
+				if sent, err := fallback.Write(chunk); sent {
+					if err != nil {
+						errors.LogInfoInner(ctx, err, "failed to send upload over WebSocket fallback")
+						uploadPipeReader.Interrupt()
+					}
+					continue
+				}
+			}
+
 			lastWrite = time.Now()
 
 			if xmuxClient != nil && (xmuxClient.LeftRequests.Add(-1) <= 0 ||
@@ -446,17 +499,28 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 				httpClient, xmuxClient = getHTTPClient(ctx, dest, streamSettings)
 			}
 
+			postCtx := ctx
+			if fallback != nil && fallback.Probing() {
+				postCtx = withWaitResponse(ctx)
+			}
+
 			go func() {
 				err := httpClient.PostPacket(
-					ctx,
+					postCtx,
 					url.String(),
 					&buf.MultiBufferContainer{MultiBuffer: chunk},
 					int64(chunk.Len()),
 				)
 				wroteRequest.Close()
+				if fallback != nil && fallback.PostDone(err) {
+					return
+				}
 				if err != nil {
 					errors.LogInfoInner(ctx, err, "failed to send upload")
 					uploadPipeReader.Interrupt()
+					if download != nil {
+						download.Close()
+					}
 				}
 			}()
 
@@ -464,11 +528,44 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 				<-wroteRequest.Wait()
 			}
 		}
+
+		if fallback != nil {
+			fallback.Close()
+		}
 	}()
 
 	return stat.Connection(&conn), nil
 }
 
+// downloadStreamSettings returns the settings of the download of streamSettings to dest, which are downloadSettings,
+// or the ones of the upload dialed through downloadDialerProxy.
+func downloadStreamSettings(dest net.Destination, streamSettings *internet.MemoryStreamConfig) *internet.MemoryStreamConfig {
+	transportConfiguration := streamSettings.ProtocolSettings.(*Config)
+
+	globalDialerAccess.Lock()
+	defer globalDialerAccess.Unlock()
+	if streamSettings.DownloadSettings != nil {
+		return streamSettings.DownloadSettings
+	}
+	if transportConfiguration.DownloadSettings != nil {
+		streamSettings.DownloadSettings = common.Must2(internet.ToMemoryStreamConfig(transportConfiguration.DownloadSettings))
+		if streamSettings.SocketSettings != nil && streamSettings.SocketSettings.Penetrate {
+			streamSettings.DownloadSettings.SocketSettings = streamSettings.SocketSettings
+		}
+		return streamSettings.DownloadSettings
+	}
+	memory2 := *streamSettings
+	memory2.Destination = &dest
+	memory2.SocketSettings = &internet.SocketConfig{}
+	if streamSettings.SocketSettings != nil {
+		memory2.SocketSettings = proto.Clone(streamSettings.SocketSettings).(*internet.SocketConfig)
+	}
+	memory2.SocketSettings.DialerProxy = transportConfiguration.DownloadDialerProxy
+	memory2.SocketSettings.DialerProxyChain = nil
+	streamSettings.DownloadSettings = &memory2
+	return streamSettings.DownloadSettings
+}
+
 // A wrapper around pipe that ensures the size limit is exactly honored.
 //
 // The MultiBuffer pipe accepts any single WriteMultiBuffer call even if that
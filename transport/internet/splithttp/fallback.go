@@ -0,0 +1,207 @@
+package splithttp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"github.com/xtls/xray-core/transport/internet/websocket"
+)
+
+// Some middleboxes let the GET of the download through but kill the POSTs of the upload.
+// If none of the first posts of a packet-up session succeeds, the upload of the session
+// is moved to a WebSocket, and the destination is pinned to WebSocket for a while.
+
+var (
+	fallbackPins   map[dialerConf]time.Time
+	fallbackAccess sync.Mutex
+)
+
+func isFallbackPinned(key dialerConf) bool {
+	fallbackAccess.Lock()
+	defer fallbackAccess.Unlock()
+
+	until, found := fallbackPins[key]
+	if found && time.Now().After(until) {
+		delete(fallbackPins, key)
+		return false
+	}
+	return found
+}
+
+func pinFallback(key dialerConf, ttl time.Duration) {
+	fallbackAccess.Lock()
+	defer fallbackAccess.Unlock()
+
+	if fallbackPins == nil {
+		fallbackPins = make(map[dialerConf]time.Time)
+	}
+	fallbackPins[key] = time.Now().Add(ttl)
+}
+
+// dialFallback dials the WebSocket fallback of the XHTTP config, at the fallback path followed by subpath.
+func dialFallback(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig, subpath string) (stat.Connection, error) {
+	transportConfig := streamSettings.ProtocolSettings.(*Config)
+	return websocket.Dial(ctx, dest, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &websocket.Config{
+			Host:   transportConfig.Host,
+			Path:   transportConfig.GetNormalizedFallbackPath() + subpath,
+			Header: transportConfig.Headers,
+		},
+		SecurityType:     streamSettings.SecurityType,
+		SecuritySettings: streamSettings.SecuritySettings,
+		SocketSettings:   streamSettings.SocketSettings,
+	})
+}
+
+// uploadFallback keeps the chunks of a packet-up upload until a post succeeds,
+// so that they can be sent again over the WebSocket if the posts are blocked.
+type uploadFallback struct {
+	sync.Mutex
+	ctx            context.Context
+	key            dialerConf
+	sessionId      string
+	maxFailures    int
+	window         time.Duration
+	chunks         [][]byte
+	failures       int
+	acked          bool
+	conn           net.Conn
+	err            error
+	windowTimer    *time.Timer
+	interruptWrite func()
+}
+
+func newUploadFallback(ctx context.Context, key dialerConf, sessionId string, interruptWrite func()) *uploadFallback {
+	transportConfig := key.ProtocolSettings.(*Config)
+	return &uploadFallback{
+		ctx:            ctx,
+		key:            key,
+		sessionId:      sessionId,
+		maxFailures:    transportConfig.GetNormalizedFallbackUploadFailures(),
+		window:         transportConfig.GetNormalizedFallbackWindow(),
+		interruptWrite: interruptWrite,
+	}
+}
+
+// Write sends the chunk over the WebSocket if the upload has been moved to it, and returns false otherwise.
+// Until a post succeeds, it keeps the chunk.
+func (f *uploadFallback) Write(chunk buf.MultiBuffer) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.err != nil {
+		return true, f.err
+	}
+	if f.conn != nil {
+		defer buf.ReleaseMulti(chunk)
+		for _, b := range chunk {
+			if _, err := f.conn.Write(b.Bytes()); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+	if !f.acked {
+		b := make([]byte, chunk.Len())
+		chunk.Copy(b)
+		f.chunks = append(f.chunks, b)
+		if f.windowTimer == nil {
+			f.windowTimer = time.AfterFunc(f.window, func() {
+				f.Lock()
+				defer f.Unlock()
+				if !f.acked {
+					f.switchLocked("no post succeeded in time")
+				}
+			})
+		}
+	}
+	return false, nil
+}
+
+// Probing returns true until a post succeeds or the upload is moved to the WebSocket.
+func (f *uploadFallback) Probing() bool {
+	f.Lock()
+	defer f.Unlock()
+
+	return !f.acked && f.conn == nil
+}
+
+// PostDone records the result of a post. It returns false if the failure should end the upload as usual.
+func (f *uploadFallback) PostDone(err error) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	if err == nil {
+		if f.acked || f.conn != nil {
+			return true
+		}
+		if f.failures > 0 {
+			// the server is waiting for the posts that failed, send them all again
+			f.switchLocked("some posts failed")
+			return f.err == nil
+		}
+		f.acked = true
+		f.chunks = nil
+		if f.windowTimer != nil {
+			f.windowTimer.Stop()
+		}
+		return true
+	}
+	if f.acked {
+		return false
+	}
+	f.failures++
+	if f.failures >= f.maxFailures {
+		f.switchLocked("the first posts failed")
+	}
+	return f.err == nil
+}
+
+func (f *uploadFallback) switchLocked(reason string) {
+	if f.conn != nil || f.err != nil {
+		return
+	}
+	if f.windowTimer != nil {
+		f.windowTimer.Stop()
+	}
+	errors.LogWarning(f.ctx, "XHTTP upload to ", f.key.Destination, " seems to be blocked (", reason, "), falling back to WebSocket")
+
+	conn, err := dialFallback(f.ctx, f.key.Destination, f.key.MemoryStreamConfig, f.sessionId)
+	if err != nil {
+		f.err = errors.New("failed to dial WebSocket fallback").Base(err)
+		f.interruptWrite()
+		return
+	}
+	for _, b := range f.chunks {
+		if _, err := conn.Write(b); err != nil {
+			conn.Close()
+			f.err = errors.New("failed to resend upload over WebSocket fallback").Base(err)
+			f.interruptWrite()
+			return
+		}
+	}
+	f.chunks = nil
+	f.conn = conn
+	pinFallback(f.key, f.key.ProtocolSettings.(*Config).GetNormalizedFallbackPin())
+}
+
+func (f *uploadFallback) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.windowTimer != nil {
+		f.windowTimer.Stop()
+	}
+	f.chunks = nil
+	if f.conn != nil {
+		return f.conn.Close()
+	}
+	return nil
+}
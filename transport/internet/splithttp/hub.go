@@ -12,9 +12,9 @@ import (
 	"sync"
 	"time"
 
+	gows "github.com/gorilla/websocket"
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
-	goreality "github.com/xtls/reality"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
@@ -24,12 +24,21 @@ import (
 	"github.com/xtls/xray-core/transport/internet/reality"
 	"github.com/xtls/xray-core/transport/internet/stat"
 	"github.com/xtls/xray-core/transport/internet/tls"
+	"github.com/xtls/xray-core/transport/internet/websocket"
 )
 
+var upgrader = &gows.Upgrader{
+	HandshakeTimeout: time.Second * 4,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
 type requestHandler struct {
 	config         *Config
 	host           string
 	path           string
+	fallbackPath   string
 	ln             *Listener
 	sessionMu      *sync.Mutex
 	sessions       sync.Map
@@ -93,7 +102,19 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	if !strings.HasPrefix(request.URL.Path, h.path) {
+	if h.fallbackPath != "" && strings.HasPrefix(request.URL.Path, h.fallbackPath) && gows.IsWebSocketUpgrade(request) {
+		h.serveFallback(writer, request)
+		return
+	}
+
+	// the path of the inbound itself wins over the shorter paths of the path handlers, such as "/"
+	path := h.path
+	var handedTo string
+	if handler := internet.MatchPathHandler(h.config.PathHandlers, request.URL.Path); handler != nil &&
+		(!strings.HasPrefix(request.URL.Path, h.path) || len(handler.Path) >= len(h.path)) {
+		path = strings.TrimSuffix(handler.Path, "/") + "/"
+		handedTo = handler.Tag
+	} else if !strings.HasPrefix(request.URL.Path, h.path) {
 		errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
 		writer.WriteHeader(http.StatusNotFound)
 		return
@@ -129,7 +150,8 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 	}
 
 	sessionId := ""
-	subpath := strings.Split(request.URL.Path[len(h.path):], "/")
+	rest := strings.TrimPrefix(request.URL.Path, strings.TrimSuffix(path, "/"))
+	subpath := strings.Split(strings.TrimPrefix(rest, "/"), "/")
 	if len(subpath) > 0 {
 		sessionId = subpath[0]
 	}
@@ -140,38 +162,8 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	var forwardedAddrs []net.Address
-	if h.socketSettings != nil && len(h.socketSettings.TrustedXForwardedFor) > 0 {
-		for _, key := range h.socketSettings.TrustedXForwardedFor {
-			if len(request.Header.Values(key)) > 0 {
-				forwardedAddrs = http_proto.ParseXForwardedFor(request.Header)
-				break
-			}
-		}
-	} else {
-		forwardedAddrs = http_proto.ParseXForwardedFor(request.Header)
-	}
-	var remoteAddr net.Addr
+	remoteAddr := h.remoteAddr(request)
 	var err error
-	remoteAddr, err = net.ResolveTCPAddr("tcp", request.RemoteAddr)
-	if err != nil {
-		remoteAddr = &net.TCPAddr{
-			IP:   []byte{0, 0, 0, 0},
-			Port: 0,
-		}
-	}
-	if request.ProtoMajor == 3 {
-		remoteAddr = &net.UDPAddr{
-			IP:   remoteAddr.(*net.TCPAddr).IP,
-			Port: remoteAddr.(*net.TCPAddr).Port,
-		}
-	}
-	if len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
-		remoteAddr = &net.TCPAddr{
-			IP:   forwardedAddrs[0].IP(),
-			Port: 0,
-		}
-	}
 
 	var currentSession *httpSession
 	if sessionId != "" {
@@ -298,13 +290,18 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 			writer:     httpSC,
 			reader:     httpSC,
 			remoteAddr: remoteAddr,
-			localAddr:  h.localAddr,
+			peerAddr:   h.peerAddr(request),
+			localAddr:  h.localAddrOf(request),
 		}
 		if sessionId != "" { // if not stream-one
 			conn.reader = currentSession.uploadQueue
 		}
 
-		h.ln.addConn(stat.Connection(&conn))
+		if handedTo != "" {
+			h.ln.addConn(&internet.HandedConnection{Connection: &conn, Tag: handedTo})
+		} else {
+			h.ln.addConn(stat.Connection(&conn))
+		}
 
 		// "A ResponseWriter may not be used after [Handler.ServeHTTP] has returned."
 		select {
@@ -319,6 +316,78 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 	}
 }
 
+// peerAddr returns the address of the peer that sent request.
+func (h *requestHandler) peerAddr(request *http.Request) net.Addr {
+	remoteAddr, err := net.ResolveTCPAddr("tcp", request.RemoteAddr)
+	if err != nil {
+		remoteAddr = &net.TCPAddr{
+			IP:   []byte{0, 0, 0, 0},
+			Port: 0,
+		}
+	}
+	if request.ProtoMajor == 3 {
+		return &net.UDPAddr{
+			IP:   remoteAddr.IP,
+			Port: remoteAddr.Port,
+		}
+	}
+	return remoteAddr
+}
+
+func (h *requestHandler) remoteAddr(request *http.Request) net.Addr {
+	var forwardedAddrs []net.Address
+	if h.socketSettings != nil && len(h.socketSettings.TrustedXForwardedFor) > 0 {
+		for _, key := range h.socketSettings.TrustedXForwardedFor {
+			if len(request.Header.Values(key)) > 0 {
+				forwardedAddrs = http_proto.ParseXForwardedFor(request.Header)
+				break
+			}
+		}
+	} else {
+		forwardedAddrs = http_proto.ParseXForwardedFor(request.Header)
+	}
+	remoteAddr := h.peerAddr(request)
+	if len(forwardedAddrs) > 0 && forwardedAddrs[0].Family().IsIP() {
+		remoteAddr = &net.TCPAddr{
+			IP:   forwardedAddrs[0].IP(),
+			Port: 0,
+		}
+	}
+	return remoteAddr
+}
+
+// localAddrOf returns the local address of the connection carrying the request, which is the destination
+// in the PROXY protocol header if the listener accepts it, or the address of the listener otherwise.
+func (h *requestHandler) localAddrOf(request *http.Request) net.Addr {
+	if addr, ok := request.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr != nil {
+		return addr
+	}
+	return h.localAddr
+}
+
+// serveFallback accepts the WebSocket fallback of the clients whose posts are blocked.
+// At the fallback path, the WebSocket carries a whole connection. Followed by a session ID,
+// it carries the upload of the session from the start, in place of the posts.
+func (h *requestHandler) serveFallback(writer http.ResponseWriter, request *http.Request) {
+	sessionId := strings.Trim(request.URL.Path[len(h.fallbackPath):], "/")
+
+	conn, err := upgrader.Upgrade(writer, request, nil)
+	if err != nil {
+		errors.LogInfoInner(context.Background(), err, "failed to convert to WebSocket connection")
+		return
+	}
+	wsConn := websocket.NewConnection(conn, h.remoteAddr(request), nil, 0)
+
+	if sessionId == "" {
+		h.ln.addConn(stat.Connection(wsConn))
+		return
+	}
+	if err := h.upsertSession(sessionId).uploadQueue.Push(Packet{Reader: wsConn}); err != nil {
+		errors.LogInfoInner(context.Background(), err, "failed to upload (PushWebSocket)")
+		wsConn.Close()
+	}
+}
+
 type httpServerConn struct {
 	sync.Mutex
 	*done.Instance
@@ -370,6 +439,7 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 		config:         l.config,
 		host:           l.config.Host,
 		path:           l.config.GetNormalizedPath(),
+		fallbackPath:   l.config.GetNormalizedFallbackPath(),
 		ln:             l,
 		sessionMu:      &sync.Mutex{},
 		sessions:       sync.Map{},
@@ -431,7 +501,7 @@ func ListenXH(ctx context.Context, address net.Address, port net.Port, streamSet
 			}
 		}
 		if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
-			l.listener = goreality.NewListener(l.listener, config.GetREALITYConfig())
+			l.listener = reality.NewListener(l.listener, config.GetServerConfig())
 		}
 
 		handler.localAddr = l.listener.Addr()
@@ -7,7 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,8 +20,12 @@ import (
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/testing/servers/tcp"
 	"github.com/xtls/xray-core/testing/servers/udp"
+	"github.com/xtls/xray-core/transport"
 	"github.com/xtls/xray-core/transport/internet"
 	. "github.com/xtls/xray-core/transport/internet/splithttp"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -123,6 +131,248 @@ func TestDialWithRemoteAddr(t *testing.T) {
 	common.Must(listen.Close())
 }
 
+func Test_ListenXHAndDial_SplitDownload(t *testing.T) {
+	listenPort := tcp.PickPort()
+	listen, err := ListenXH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName:     "splithttp",
+		ProtocolSettings: &Config{Path: "/sh"},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := c.Read(b[:]); err != nil {
+				return
+			}
+			common.Must2(c.Write([]byte("Response")))
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	config := &Config{
+		Path: "/sh",
+		Mode: "packet-up",
+		DownloadSettings: &internet.StreamConfig{
+			Address:      net.NewIPOrDomain(net.LocalHostIP),
+			Port:         uint32(listenPort),
+			ProtocolName: "splithttp",
+			TransportSettings: []*internet.TransportConfig{
+				{
+					ProtocolName: "splithttp",
+					Settings:     serial.ToTypedMessage(&Config{Path: "/sh"}),
+				},
+			},
+		},
+	}
+	conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("localhost"), listenPort), &internet.MemoryStreamConfig{
+		ProtocolName:     "splithttp",
+		ProtocolSettings: config,
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	common.Must2(conn.Write([]byte("Test connection")))
+	b, err := io.ReadAll(conn)
+	if string(b) != "Response" {
+		t.Error("response: ", string(b), " ", err)
+	}
+
+	// the upload is torn down once the download ends
+	if _, err := conn.Write([]byte("Test connection")); err == nil {
+		t.Error("expected the upload to end with the download")
+	}
+}
+
+// dialerProxy is the outbound of downloadDialerProxy, which dials the targets itself.
+type dialerProxy struct {
+	outbound.Handler
+	dispatched atomic.Int32
+}
+
+func (h *dialerProxy) Dispatch(ctx context.Context, link *transport.Link) {
+	h.dispatched.Add(1)
+	outbounds := session.OutboundsFromContext(ctx)
+	conn, err := net.Dial("tcp", outbounds[len(outbounds)-1].Target.NetAddr())
+	if err != nil {
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+		return
+	}
+	defer conn.Close()
+	go buf.Copy(link.Reader, buf.NewWriter(conn))
+	buf.Copy(buf.NewReader(conn), link.Writer)
+	common.Close(link.Writer)
+}
+
+type dialerProxyManager struct {
+	outbound.Manager
+	handler *dialerProxy
+}
+
+func (m *dialerProxyManager) GetHandler(tag string) outbound.Handler {
+	if tag == "cdn" {
+		return m.handler
+	}
+	return nil
+}
+
+func Test_ListenXHAndDial_DownloadDialerProxy(t *testing.T) {
+	listenPort := tcp.PickPort()
+	listen, err := ListenXH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName:     "splithttp",
+		ProtocolSettings: &Config{Path: "/sh"},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := c.Read(b[:]); err != nil {
+				return
+			}
+			common.Must2(c.Write([]byte("Response")))
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	proxy := &dialerProxy{}
+	internet.InitSystemDialer(nil, &dialerProxyManager{handler: proxy})
+	defer internet.InitSystemDialer(nil, nil)
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, listenPort), &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path:                "/sh",
+			Mode:                "packet-up",
+			DownloadDialerProxy: "cdn",
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	common.Must2(conn.Write([]byte("Test connection")))
+	b, err := io.ReadAll(conn)
+	if string(b) != "Response" {
+		t.Error("response: ", string(b), " ", err)
+	}
+	// only the download goes through the outbound
+	if n := proxy.dispatched.Load(); n != 1 {
+		t.Error("expected the download to be dialed through the outbound, but got ", n, " dials through it")
+	}
+}
+
+// cutRelay relays the connections to dest until cut is called, which closes them.
+func cutRelay(dest net.Destination) (port net.Port, cut func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	var access sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", dest.NetAddr())
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			access.Lock()
+			conns = append(conns, conn, upstream)
+			access.Unlock()
+			go io.Copy(upstream, conn)
+			go io.Copy(conn, upstream)
+		}
+	}()
+	return net.Port(listener.Addr().(*net.TCPAddr).Port), func() {
+		listener.Close()
+		access.Lock()
+		defer access.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+}
+
+func Test_ListenXHAndDial_StreamUpTeardown(t *testing.T) {
+	listenPort := tcp.PickPort()
+	done := make(chan struct{})
+	defer close(done)
+	listen, err := ListenXH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName:     "splithttp",
+		ProtocolSettings: &Config{Path: "/sh"},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			if _, err := c.Read(b[:]); err != nil {
+				return
+			}
+			common.Must2(c.Write([]byte("Response")))
+			// the server keeps the download open, whatever happens to the upload
+			<-done
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	// the upload goes through the relay, and the download straight to the server
+	relayPort, cut := cutRelay(net.TCPDestination(net.LocalHostIP, listenPort))
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, relayPort), &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path: "/sh",
+			Mode: "stream-up",
+			DownloadSettings: &internet.StreamConfig{
+				Address:      net.NewIPOrDomain(net.LocalHostIP),
+				Port:         uint32(listenPort),
+				ProtocolName: "splithttp",
+				TransportSettings: []*internet.TransportConfig{
+					{
+						ProtocolName: "splithttp",
+						Settings:     serial.ToTypedMessage(&Config{Path: "/sh"}),
+					},
+				},
+			},
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	common.Must2(conn.Write([]byte("Test connection")))
+	var b [8]byte
+	if _, err := io.ReadFull(conn, b[:]); err != nil || string(b[:]) != "Response" {
+		t.Fatal("response: ", string(b[:]), " ", err)
+	}
+
+	// the download is torn down once the upload fails, which is seen by the writes
+	cut()
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(b[:])
+		readErr <- err
+	}()
+	timeout := time.After(5 * time.Second)
+	for {
+		conn.Write([]byte("Test connection"))
+		select {
+		case err := <-readErr:
+			if err == nil {
+				t.Error("expected the download to end with the upload")
+			}
+			return
+		case <-timeout:
+			t.Fatal("the download goes on after the upload failed")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
 func Test_ListenXHAndDial_TLS(t *testing.T) {
 	if runtime.GOARCH == "arm64" {
 		return
@@ -458,3 +708,77 @@ func Test_maxUpload(t *testing.T) {
 
 	common.Must(listen.Close())
 }
+
+func Test_ListenXHAndDial_WebSocketFallback(t *testing.T) {
+	listenPort := tcp.PickPort()
+	listen, err := ListenXH(context.Background(), net.LocalHostIP, listenPort, &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path:              "/sh",
+			FallbackTransport: "ws",
+			FallbackPath:      "/ws",
+		},
+	}, func(conn stat.Connection) {
+		go func(c stat.Connection) {
+			defer c.Close()
+
+			var b [1024]byte
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, err := c.Read(b[:])
+			if err != nil {
+				return
+			}
+
+			common.Must2(c.Write(append([]byte("Response: "), b[:n]...)))
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	// a middlebox that kills the posts
+	proxyPort := tcp.PickPort()
+	target, _ := url.Parse("http://" + net.LocalHostIP.String() + ":" + listenPort.String())
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.FlushInterval = -1
+	proxyServer := &http.Server{
+		Addr: net.LocalHostIP.String() + ":" + proxyPort.String(),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			proxy.ServeHTTP(w, r)
+		}),
+	}
+	go proxyServer.ListenAndServe()
+	defer proxyServer.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx := context.Background()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "splithttp",
+		ProtocolSettings: &Config{
+			Path:                   "/sh",
+			FallbackTransport:      "ws",
+			FallbackPath:           "/ws",
+			FallbackUploadFailures: 1,
+		},
+	}
+	dest := net.TCPDestination(net.LocalHostIP, proxyPort)
+
+	// the upload of the first connection is moved to a WebSocket, and the second one is over a WebSocket
+	for _, payload := range []string{"Test connection 1", "Test connection 2"} {
+		conn, err := Dial(ctx, dest, streamSettings)
+		common.Must(err)
+		_, err = conn.Write([]byte(payload))
+		common.Must(err)
+
+		var b [1024]byte
+		conn.SetReadDeadline(time.Now().Add(4 * time.Second))
+		n, _ := io.ReadAtLeast(conn, b[:], len("Response: ")+len(payload))
+		if string(b[:n]) != "Response: "+payload {
+			t.Error("response: ", string(b[:n]))
+		}
+		common.Must(conn.Close())
+	}
+}
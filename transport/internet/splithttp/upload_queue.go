@@ -97,8 +97,7 @@ func (h *uploadQueue) Read(b []byte) (int, error) {
 			return 0, io.EOF
 		}
 		if packet.Reader != nil {
-			h.reader = packet.Reader
-			return h.reader.Read(b)
+			return h.readFromReader(packet.Reader, b)
 		}
 		heap.Push(&h.heap, packet)
 	}
@@ -135,6 +134,9 @@ func (h *uploadQueue) Read(b []byte) (int, error) {
 			if !more {
 				return 0, io.EOF
 			}
+			if packet2.Reader != nil {
+				return h.readFromReader(packet2.Reader, b)
+			}
 			heap.Push(&h.heap, packet2)
 		}
 	}
@@ -142,6 +144,18 @@ func (h *uploadQueue) Read(b []byte) (int, error) {
 	return 0, nil
 }
 
+// readFromReader switches to the reader, which carries the upload from the start.
+// Packets that have been received but not read yet are sent by the reader again.
+func (h *uploadQueue) readFromReader(reader io.ReadCloser, b []byte) (int, error) {
+	if h.nextSeq != 0 {
+		reader.Close()
+		return 0, errors.New("upload has already started with packets")
+	}
+	h.heap = h.heap[:0]
+	h.reader = reader
+	return h.reader.Read(b)
+}
+
 // heap code directly taken from https://pkg.go.dev/container/heap
 type uploadHeap []Packet
 
@@ -1,6 +1,8 @@
 package splithttp_test
 
 import (
+	"bytes"
+	"io"
 	"testing"
 
 	"github.com/xtls/xray-core/common"
@@ -20,3 +22,20 @@ func Test_regression_readzero(t *testing.T) {
 		t.Error("n=", n)
 	}
 }
+
+func Test_uploadQueueReaderFromStart(t *testing.T) {
+	q := NewUploadQueue(10)
+	q.Push(Packet{
+		Payload: []byte("y"),
+		Seq:     1,
+	})
+	q.Push(Packet{
+		Reader: io.NopCloser(bytes.NewReader([]byte("xy"))),
+	})
+	buf := make([]byte, 20)
+	n, err := q.Read(buf)
+	common.Must(err)
+	if string(buf[:n]) != "xy" {
+		t.Error("read ", string(buf[:n]))
+	}
+}
@@ -2,8 +2,10 @@ package internet
 
 import (
 	"context"
+	goerrors "errors"
 	"math/rand"
 	gonet "net"
+	"os"
 	"syscall"
 	"time"
 
@@ -45,6 +47,15 @@ func resolveSrcAddr(network net.Network, src net.Address) net.Addr {
 	}
 }
 
+// bindError explains err if it is the kernel rejecting to bind to the source address src.
+func bindError(err error, src net.Address) error {
+	var syscallErr *os.SyscallError
+	if src != nil && goerrors.As(err, &syscallErr) && syscallErr.Syscall == "bind" {
+		return errors.New("failed to bind to source address ", src, ", which must be on an interface of the host, or in a prefix routed to it for sendThrough CIDR").Base(err)
+	}
+	return err
+}
+
 func hasBindAddr(sockopt *SocketConfig) bool {
 	return sockopt != nil && len(sockopt.BindAddress) > 0 && sockopt.BindPort > 0
 }
@@ -81,7 +92,7 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		}
 		packetConn, err := lc.ListenPacket(ctx, srcAddr.Network(), srcAddr.String())
 		if err != nil {
-			return nil, err
+			return nil, bindError(err, src)
 		}
 		return &PacketConnWrapper{
 			Conn: packetConn,
@@ -143,7 +154,11 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 		}
 	}
 
-	return dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+	conn, err := dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+	if err != nil {
+		return nil, bindError(err, src)
+	}
+	return conn, nil
 }
 
 func (d *DefaultSystemDialer) DestIpAddress() net.IP {
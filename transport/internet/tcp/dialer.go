@@ -5,6 +5,7 @@ import (
 	gotls "crypto/tls"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/errors"
@@ -23,6 +24,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	if err != nil {
 		return nil, err
 	}
+	start := time.Now()
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		mitmServerName := session.MitmServerNameFromContext(ctx)
@@ -86,10 +88,12 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 			conn.Close()
 			return nil, errors.New("MITM freedom RAW TLS: unexpected Negotiated Protocol (" + negotiatedProtocol + ") with " + mitmServerName).AtWarning()
 		}
+		internet.ObserveHandshake(ctx, start)
 	} else if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
 		if conn, err = reality.UClient(conn, config, ctx, dest); err != nil {
 			return nil, err
 		}
+		internet.ObserveHandshake(ctx, start)
 	}
 
 	tcpSettings := streamSettings.ProtocolSettings.(*Config)
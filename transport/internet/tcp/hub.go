@@ -20,7 +20,7 @@ import (
 type Listener struct {
 	listener      net.Listener
 	tlsConfig     *gotls.Config
-	realityConfig *goreality.Config
+	realityConfig *reality.ServerConfig
 	authConfig    internet.ConnectionAuthenticator
 	config        *Config
 	addConn       internet.ConnHandler
@@ -74,8 +74,8 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSe
 		l.tlsConfig = config.GetTLSConfig()
 	}
 	if config := reality.ConfigFromStreamSettings(streamSettings); config != nil {
-		l.realityConfig = config.GetREALITYConfig()
-		go goreality.DetectPostHandshakeRecordsLens(l.realityConfig)
+		l.realityConfig = config.GetServerConfig()
+		go goreality.DetectPostHandshakeRecordsLens(l.realityConfig.Base())
 	}
 
 	if tcpSettings.HeaderSettings != nil {
@@ -112,7 +112,7 @@ func (v *Listener) keepAccepting() {
 			if v.tlsConfig != nil {
 				conn = tls.Server(conn, v.tlsConfig)
 			} else if v.realityConfig != nil {
-				if conn, err = reality.Server(conn, v.realityConfig); err != nil {
+				if conn, err = reality.Server(conn, v.realityConfig.Get()); err != nil {
 					errors.LogInfo(context.Background(), err.Error())
 					return
 				}
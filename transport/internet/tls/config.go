@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -282,7 +283,25 @@ func (c *Config) parseServerName() string {
 }
 
 func (r *RandCarrier) verifyPeerCert(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if err := r.verifyPeerCertChain(rawCerts, verifiedChains); err != nil {
+		// tells the certificates presented, so that the right one can be pinned
+		return errors.New("rejected the peer certs presented, ", DescribeCertChain(rawCerts)).Base(err)
+	}
+	return nil
+}
+
+func (r *RandCarrier) verifyPeerCertChain(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if r.PinnedPeerCertSha256 != nil || r.PinnedPeerSpkiSha256 != nil {
+		if !r.matchPinnedPeerLeaf(rawCerts) {
+			return errors.New("peer cert matches none of the pins.")
+		}
+		if !r.VerifyPinnedPeerChain {
+			return nil
+		}
+	}
+
 	if r.VerifyPeerCertInNames != nil {
+		var verifyErr error
 		if len(r.VerifyPeerCertInNames) > 0 {
 			certs := make([]*x509.Certificate, len(rawCerts))
 			for i, asn1Data := range rawCerts {
@@ -297,13 +316,13 @@ func (r *RandCarrier) verifyPeerCert(rawCerts [][]byte, verifiedChains [][]*x509
 				opts.Intermediates.AddCert(cert)
 			}
 			for _, opts.DNSName = range r.VerifyPeerCertInNames {
-				if _, err := certs[0].Verify(opts); err == nil {
+				if _, verifyErr = certs[0].Verify(opts); verifyErr == nil {
 					return nil
 				}
 			}
 		}
 		if r.PinnedPeerCertificateChainSha256 == nil {
-			return errors.New("peer cert is invalid.")
+			return errors.New("peer cert is invalid.").Base(verifyErr)
 		}
 	}
 
@@ -333,11 +352,41 @@ func (r *RandCarrier) verifyPeerCert(rawCerts [][]byte, verifiedChains [][]*x509
 	return nil
 }
 
+// matchPinnedPeerLeaf returns whether the leaf certificate matches a pin of the certificate or of its public key.
+func (r *RandCarrier) matchPinnedPeerLeaf(rawCerts [][]byte) bool {
+	if len(rawCerts) == 0 {
+		return false
+	}
+	certHash := sha256.Sum256(rawCerts[0])
+	for _, v := range r.PinnedPeerCertSha256 {
+		if hmac.Equal(certHash[:], v) {
+			return true
+		}
+	}
+	if r.PinnedPeerSpkiSha256 == nil {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return false
+	}
+	spkiHash := GenerateCertPublicKeyHash(leaf)
+	for _, v := range r.PinnedPeerSpkiSha256 {
+		if hmac.Equal(spkiHash, v) {
+			return true
+		}
+	}
+	return false
+}
+
 type RandCarrier struct {
 	RootCAs                              *x509.CertPool
 	VerifyPeerCertInNames                []string
 	PinnedPeerCertificateChainSha256     [][]byte
 	PinnedPeerCertificatePublicKeySha256 [][]byte
+	PinnedPeerCertSha256                 [][]byte
+	PinnedPeerSpkiSha256                 [][]byte
+	VerifyPinnedPeerChain                bool
 }
 
 func (r *RandCarrier) Read(p []byte) (n int, err error) {
@@ -366,14 +415,20 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		VerifyPeerCertInNames:                slices.Clone(c.VerifyPeerCertInNames),
 		PinnedPeerCertificateChainSha256:     c.PinnedPeerCertificateChainSha256,
 		PinnedPeerCertificatePublicKeySha256: c.PinnedPeerCertificatePublicKeySha256,
+		PinnedPeerCertSha256:                 c.PinnedPeerCertSha256,
+		PinnedPeerSpkiSha256:                 c.PinnedPeerSpkiSha256,
+		VerifyPinnedPeerChain:                c.VerifyPinnedPeerChain,
 	}
 	config := &tls.Config{
-		Rand:                   randCarrier,
-		ClientSessionCache:     globalSessionCache,
+		Rand: randCarrier,
+		ClientSessionCache: &clientSessionCache{
+			fingerprint: c.Fingerprint,
+			cache:       c.getSessionCache(),
+		},
 		RootCAs:                root,
 		InsecureSkipVerify:     c.AllowInsecure,
 		NextProtos:             slices.Clone(c.NextProtocol),
-		SessionTicketsDisabled: !c.EnableSessionResumption,
+		SessionTicketsDisabled: !c.sessionResumption(),
 		VerifyPeerCertificate:  randCarrier.verifyPeerCert,
 	}
 	if len(c.VerifyPeerCertInNames) > 0 {
@@ -397,6 +452,29 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		config.ServerName = sn
 	}
 
+	if len(c.ServerNameOverride) > 0 || c.NoSni {
+		// the certificate is verified against the real name, unless it is not verified or verified against other names
+		if !config.InsecureSkipVerify && len(config.ServerName) > 0 {
+			config.InsecureSkipVerify = true
+			randCarrier.VerifyPeerCertInNames = []string{config.ServerName}
+		}
+		config.ServerName = c.ServerNameOverride
+		if c.NoSni {
+			config.ServerName = ""
+		}
+	}
+
+	if len(c.PinnedPeerCertSha256) > 0 || len(c.PinnedPeerSpkiSha256) > 0 {
+		// verifyPeerCert checks the pins first, and the chain after them only if required
+		if !config.InsecureSkipVerify && c.VerifyPinnedPeerChain {
+			randCarrier.VerifyPeerCertInNames = []string{}
+			if len(config.ServerName) > 0 {
+				randCarrier.VerifyPeerCertInNames = []string{config.ServerName}
+			}
+		}
+		config.InsecureSkipVerify = true
+	}
+
 	if len(c.CurvePreferences) > 0 {
 		config.CurvePreferences = ParseCurveName(c.CurvePreferences)
 	}
@@ -482,6 +560,19 @@ func WithOverrideName(serverName string) Option {
 	}
 }
 
+// RequestHost returns the Host of the requests of the transports over HTTP: host > serverName > the address of dest.
+// It never follows the SNI, which serverNameOverride and noSNI set apart from serverName,
+// so the address to dial, the SNI and the Host can be set independently.
+func RequestHost(host string, serverName string, dest net.Destination) string {
+	if host != "" {
+		return host
+	}
+	if serverName != "" && !IsFromMitm(serverName) {
+		return serverName
+	}
+	return dest.Address.String()
+}
+
 // WithNextProto sets the ALPN values in TLS config.
 func WithNextProto(protocol ...string) Option {
 	return func(config *tls.Config) {
@@ -1,12 +1,18 @@
 package tls_test
 
 import (
+	"context"
+	"crypto/sha256"
 	gotls "crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	gonet "net"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol/tls/cert"
 	. "github.com/xtls/xray-core/transport/internet/tls"
 )
@@ -72,6 +78,176 @@ func TestInsecureCertificates(t *testing.T) {
 	}
 }
 
+func TestServerNameOverride(t *testing.T) {
+	caCert := cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign))
+	serverCert := cert.MustGenerate(caCert, cert.CommonName("www.example.com"), cert.DNSNames("www.example.com"))
+	ca := ParseCertificate(caCert)
+	ca.Usage = Certificate_AUTHORITY_VERIFY
+
+	// handshake returns the SNI of the ClientHello, and the error of the client
+	handshake := func(c *Config, dest net.Destination) (string, error) {
+		serverConfig := (&Config{Certificate: []*Certificate{ParseCertificate(serverCert)}}).GetTLSConfig()
+		sni := make(chan string, 1)
+		serverConfig.GetConfigForClient = func(hello *gotls.ClientHelloInfo) (*gotls.Config, error) {
+			sni <- hello.ServerName
+			return nil, nil
+		}
+		listener := common.Must2(gonet.Listen("tcp", "127.0.0.1:0"))
+		defer listener.Close()
+		go func() {
+			if conn, err := listener.Accept(); err == nil {
+				gotls.Server(conn, serverConfig).Handshake()
+				conn.Close()
+			}
+		}()
+		clientConn := common.Must2(gonet.Dial("tcp", listener.Addr().String()))
+		defer clientConn.Close()
+		err := gotls.Client(clientConn, c.GetTLSConfig(WithDestination(dest))).Handshake()
+		return <-sni, err
+	}
+
+	dest := net.TCPDestination(net.DomainAddress("www.example.com"), 443)
+	sni, err := handshake(&Config{Certificate: []*Certificate{ca}, ServerNameOverride: "front.example.org"}, dest)
+	if err != nil {
+		t.Error("failed to verify the real name: ", err)
+	}
+	if sni != "front.example.org" {
+		t.Error("unexpected SNI: ", sni)
+	}
+
+	sni, err = handshake(&Config{Certificate: []*Certificate{ca}, NoSni: true}, dest)
+	if err != nil {
+		t.Error("failed to verify the real name: ", err)
+	}
+	if sni != "" {
+		t.Error("expected no SNI, got ", sni)
+	}
+
+	// the certificate is not for the real name
+	other := net.TCPDestination(net.DomainAddress("www.example.net"), 443)
+	if _, err := handshake(&Config{Certificate: []*Certificate{ca}, ServerNameOverride: "www.example.com"}, other); err == nil {
+		t.Error("expected the certificate to be verified against the real name")
+	}
+
+	// verifyPeerCertInNames replaces the real name
+	if _, err := handshake(&Config{Certificate: []*Certificate{ca}, NoSni: true, VerifyPeerCertInNames: []string{"www.example.com"}}, other); err != nil {
+		t.Error("failed to verify the names: ", err)
+	}
+
+	if _, err := handshake(&Config{AllowInsecure: true, NoSni: true}, other); err != nil {
+		t.Error("failed to skip the verification: ", err)
+	}
+}
+
+func TestServerNames(t *testing.T) {
+	dest := net.TCPDestination(net.DomainAddress("dial.example.com"), 443)
+	tests := []struct {
+		serverName string
+		override   string
+		host       string
+		sni        string
+		verify     string
+		requested  string
+	}{
+		{"", "", "", "dial.example.com", "dial.example.com", "dial.example.com"},
+		{"", "", "host.example.com", "dial.example.com", "dial.example.com", "host.example.com"},
+		{"", "sni.example.com", "", "sni.example.com", "dial.example.com", "dial.example.com"},
+		{"", "sni.example.com", "host.example.com", "sni.example.com", "dial.example.com", "host.example.com"},
+		{"name.example.com", "", "", "name.example.com", "name.example.com", "name.example.com"},
+		{"name.example.com", "", "host.example.com", "name.example.com", "name.example.com", "host.example.com"},
+		{"name.example.com", "sni.example.com", "", "sni.example.com", "name.example.com", "name.example.com"},
+		{"name.example.com", "sni.example.com", "host.example.com", "sni.example.com", "name.example.com", "host.example.com"},
+	}
+	for i, test := range tests {
+		c := &Config{ServerName: test.serverName, ServerNameOverride: test.override}
+		config := c.GetTLSConfig(WithDestination(dest))
+		if config.ServerName != test.sni {
+			t.Error("case ", i, ": expected SNI ", test.sni, ", but got ", config.ServerName)
+		}
+		verify := config.ServerName
+		if names := config.Rand.(*RandCarrier).VerifyPeerCertInNames; len(names) > 0 {
+			verify = names[0]
+		}
+		if verify != test.verify {
+			t.Error("case ", i, ": expected to verify ", test.verify, ", but got ", verify)
+		}
+		if host := RequestHost(test.host, c.GetServerName(), dest); host != test.requested {
+			t.Error("case ", i, ": expected Host ", test.requested, ", but got ", host)
+		}
+	}
+
+	// noSNI only empties the SNI
+	c := &Config{ServerName: "name.example.com", NoSni: true}
+	if sni := c.GetTLSConfig(WithDestination(dest)).ServerName; sni != "" {
+		t.Error("expected no SNI, but got ", sni)
+	}
+	if host := RequestHost("", c.GetServerName(), dest); host != "name.example.com" {
+		t.Error("unexpected Host with noSNI: ", host)
+	}
+}
+
+func TestPinnedPeerCert(t *testing.T) {
+	caCert := cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign))
+	serverCert := cert.MustGenerate(caCert, cert.CommonName("www.example.com"), cert.DNSNames("www.example.com"))
+	ca := ParseCertificate(caCert)
+	ca.Usage = Certificate_AUTHORITY_VERIFY
+	leaf := common.Must2(x509.ParseCertificate(serverCert.Certificate))
+	certHash := sha256.Sum256(serverCert.Certificate)
+	spkiHash := GenerateCertPublicKeyHash(leaf)
+	wrongHash := sha256.Sum256([]byte("wrong"))
+
+	// handshake returns the error of the client, with crypto/tls or uTLS
+	handshake := func(c *Config, fingerprint string) error {
+		serverConfig := (&Config{Certificate: []*Certificate{ParseCertificate(serverCert)}}).GetTLSConfig()
+		listener := common.Must2(gonet.Listen("tcp", "127.0.0.1:0"))
+		defer listener.Close()
+		go func() {
+			if conn, err := listener.Accept(); err == nil {
+				gotls.Server(conn, serverConfig).Handshake()
+				conn.Close()
+			}
+		}()
+		clientConn := common.Must2(gonet.Dial("tcp", listener.Addr().String()))
+		defer clientConn.Close()
+		tlsConfig := c.GetTLSConfig(WithDestination(net.TCPDestination(net.DomainAddress("www.example.com"), 443)))
+		if fingerprint != "" {
+			return UClient(clientConn, tlsConfig, GetFingerprint(fingerprint)).(*UConn).HandshakeContext(context.Background())
+		}
+		return gotls.Client(clientConn, tlsConfig).Handshake()
+	}
+
+	tests := []struct {
+		config *Config
+		ok     bool
+	}{
+		// the pins replace the verification against the CAs
+		{&Config{PinnedPeerCertSha256: [][]byte{certHash[:]}}, true},
+		{&Config{PinnedPeerSpkiSha256: [][]byte{spkiHash}}, true},
+		{&Config{PinnedPeerCertSha256: [][]byte{wrongHash[:]}, PinnedPeerSpkiSha256: [][]byte{spkiHash}}, true},
+		{&Config{PinnedPeerCertSha256: [][]byte{wrongHash[:]}}, false},
+		{&Config{PinnedPeerCertSha256: [][]byte{wrongHash[:]}, AllowInsecure: true}, false},
+		// or come in addition to it
+		{&Config{PinnedPeerCertSha256: [][]byte{certHash[:]}, VerifyPinnedPeerChain: true}, false},
+		{&Config{Certificate: []*Certificate{ca}, PinnedPeerCertSha256: [][]byte{certHash[:]}, VerifyPinnedPeerChain: true}, true},
+		{&Config{Certificate: []*Certificate{ca}, PinnedPeerSpkiSha256: [][]byte{wrongHash[:]}, VerifyPinnedPeerChain: true}, false},
+		{&Config{Certificate: []*Certificate{ca}, PinnedPeerCertSha256: [][]byte{certHash[:]}, VerifyPinnedPeerChain: true, ServerNameOverride: "front.example.org"}, true},
+	}
+	for i, test := range tests {
+		for _, fingerprint := range []string{"", "chrome"} {
+			err := handshake(test.config, fingerprint)
+			if (err == nil) != test.ok {
+				t.Error("case ", i, " fingerprint ", fingerprint, ": expected ok ", test.ok, ", but got ", err)
+			}
+		}
+	}
+
+	// the error tells the hashes to pin
+	err := handshake(&Config{PinnedPeerCertSha256: [][]byte{wrongHash[:]}}, "")
+	if err == nil || !strings.Contains(err.Error(), base64.StdEncoding.EncodeToString(certHash[:])) || !strings.Contains(err.Error(), base64.StdEncoding.EncodeToString(spkiHash)) {
+		t.Error("expected the hashes of the presented certificate, but got ", err)
+	}
+}
+
 func BenchmarkCertificateIssuing(b *testing.B) {
 	certificate := ParseCertificate(cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign)))
 	certificate.Usage = Certificate_AUTHORITY_ISSUE
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"strings"
 )
 
 func CalculatePEMCertChainSHA256Hash(certContent []byte) string {
@@ -40,3 +41,23 @@ func GenerateCertPublicKeyHash(cert *x509.Certificate) []byte {
 	out := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
 	return out[:]
 }
+
+// DescribeCertChain returns the hashes of the chain, and of each certificate in it and of its public key,
+// in the base64 form that the pins are written in.
+func DescribeCertChain(rawCerts [][]byte) string {
+	var sb strings.Builder
+	sb.WriteString("chain sha256 ")
+	sb.WriteString(base64.StdEncoding.EncodeToString(GenerateCertChainHash(rawCerts)))
+	for _, raw := range rawCerts {
+		certHash := sha256.Sum256(raw)
+		sb.WriteString("; cert sha256 ")
+		sb.WriteString(base64.StdEncoding.EncodeToString(certHash[:]))
+		if cert, err := x509.ParseCertificate(raw); err == nil {
+			sb.WriteString(" spki sha256 ")
+			sb.WriteString(base64.StdEncoding.EncodeToString(GenerateCertPublicKeyHash(cert)))
+			sb.WriteString(" subject ")
+			sb.WriteString(cert.Subject.String())
+		}
+	}
+	return sb.String()
+}
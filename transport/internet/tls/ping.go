@@ -0,0 +1,137 @@
+package tls
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	utls "github.com/refraction-networking/utls"
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+)
+
+// PingConfig is the configuration of a handshake made by Ping.
+type PingConfig struct {
+	// Address is the host:port to connect to.
+	Address string
+	// ServerName is sent as the SNI, which is left out if it is empty.
+	ServerName string
+	// Fingerprint is the name of the uTLS fingerprint to handshake with, crypto/tls is used if it is empty.
+	// It does not apply to QUIC.
+	Fingerprint string
+	// ECHConfigList enables ECH if it is not empty.
+	ECHConfigList []byte
+	// QUIC makes the handshake over QUIC with h3 instead of TCP.
+	QUIC               bool
+	RootCAs            *x509.CertPool
+	InsecureSkipVerify bool
+}
+
+// PingResult is the outcome of a handshake made by Ping.
+type PingResult struct {
+	Version            uint16
+	CipherSuite        uint16
+	NegotiatedProtocol string
+	// CurveID is not known for uTLS handshakes.
+	CurveID          tls.CurveID
+	ECHAccepted      bool
+	PeerCertificates []*x509.Certificate
+	// Connect is the time to connect over TCP, zero for QUIC.
+	Connect time.Duration
+	// Handshake is the time of the TLS handshake. For QUIC, it includes connecting.
+	Handshake time.Duration
+}
+
+// Ping connects to the address, makes a TLS handshake and closes the connection.
+func Ping(ctx context.Context, config *PingConfig) (*PingResult, error) {
+	tlsConfig := &tls.Config{
+		ServerName:                     config.ServerName,
+		RootCAs:                        config.RootCAs,
+		InsecureSkipVerify:             config.InsecureSkipVerify,
+		NextProtos:                     []string{"h2", "http/1.1"},
+		MinVersion:                     tls.VersionTLS12,
+		MaxVersion:                     tls.VersionTLS13,
+		EncryptedClientHelloConfigList: config.ECHConfigList,
+	}
+	if config.QUIC {
+		tlsConfig.NextProtos = []string{"h3"}
+	}
+	if len(config.ECHConfigList) != 0 || config.QUIC {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	if config.QUIC {
+		start := time.Now()
+		conn, err := quic.DialAddr(ctx, config.Address, tlsConfig, &quic.Config{})
+		if err != nil {
+			return nil, errors.New("QUIC handshake failed").Base(err)
+		}
+		defer conn.CloseWithError(0, "")
+		result := resultFromState(conn.ConnectionState().TLS)
+		result.Handshake = time.Since(start)
+		return result, nil
+	}
+
+	var fingerprint *utls.ClientHelloID
+	if config.Fingerprint != "" {
+		if fingerprint = GetFingerprint(config.Fingerprint); fingerprint == nil {
+			return nil, errors.New("unknown fingerprint: ", config.Fingerprint)
+		}
+	}
+
+	start := time.Now()
+	var dialer net.Dialer
+	rawConn, err := dialer.DialContext(ctx, "tcp", config.Address)
+	if err != nil {
+		return nil, errors.New("failed to dial ", config.Address).Base(err)
+	}
+	defer rawConn.Close()
+	connect := time.Since(start)
+
+	start = time.Now()
+	var result *PingResult
+	if fingerprint != nil {
+		conn := utls.UClient(rawConn, copyConfig(tlsConfig), *fingerprint)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			return nil, errors.New("handshake failed").Base(err)
+		}
+		state := conn.ConnectionState()
+		result = &PingResult{
+			Version:            state.Version,
+			CipherSuite:        state.CipherSuite,
+			NegotiatedProtocol: state.NegotiatedProtocol,
+			ECHAccepted:        state.ECHAccepted,
+			PeerCertificates:   state.PeerCertificates,
+		}
+	} else {
+		conn := tls.Client(rawConn, tlsConfig)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			return nil, errors.New("handshake failed").Base(err)
+		}
+		result = resultFromState(conn.ConnectionState())
+	}
+	result.Connect = connect
+	result.Handshake = time.Since(start)
+	return result, nil
+}
+
+func resultFromState(state tls.ConnectionState) *PingResult {
+	return &PingResult{
+		Version:            state.Version,
+		CipherSuite:        state.CipherSuite,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		CurveID:            state.CurveID,
+		ECHAccepted:        state.ECHAccepted,
+		PeerCertificates:   state.PeerCertificates,
+	}
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 hash of the certificate.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(hash[:])
+}
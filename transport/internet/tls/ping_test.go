@@ -0,0 +1,105 @@
+package tls_test
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"testing"
+
+	"github.com/quic-go/quic-go"
+	"github.com/xtls/reality/hpke"
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	. "github.com/xtls/xray-core/transport/internet/tls"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestPing(t *testing.T) {
+	certificate, err := gotls.X509KeyPair(cert.MustGenerate(nil, cert.DNSNames("example.com")).ToPEM())
+	common.Must(err)
+
+	echConfig, priv, err := GenerateECHKeySet(0, "public.example.com", hpke.DHKEM_X25519_HKDF_SHA256)
+	common.Must(err)
+	echConfigBytes, err := MarshalBinary(echConfig)
+	common.Must(err)
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(child *cryptobyte.Builder) {
+		child.AddBytes(echConfigBytes)
+	})
+	echConfigList := b.BytesOrPanic()
+
+	serverConfig := &gotls.Config{
+		Certificates: []gotls.Certificate{certificate},
+		NextProtos:   []string{"h2"},
+		EncryptedClientHelloKeys: []gotls.EncryptedClientHelloKey{{
+			Config:     echConfigBytes,
+			PrivateKey: priv,
+		}},
+	}
+	listener, err := gotls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*gotls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	quicConfig := serverConfig.Clone()
+	quicConfig.NextProtos = []string{"h3"}
+	quicListener, err := quic.ListenAddr("127.0.0.1:0", quicConfig, nil)
+	common.Must(err)
+	defer quicListener.Close()
+	go func() {
+		for {
+			conn, err := quicListener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			<-conn.HandshakeComplete()
+			conn.CloseWithError(0, "")
+		}
+	}()
+
+	for _, c := range []struct {
+		name     string
+		config   *PingConfig
+		protocol string
+		ech      bool
+	}{
+		{"tcp", &PingConfig{Address: listener.Addr().String()}, "h2", false},
+		{"ech", &PingConfig{Address: listener.Addr().String(), ECHConfigList: echConfigList}, "h2", true},
+		{"chrome", &PingConfig{Address: listener.Addr().String(), Fingerprint: "chrome"}, "h2", false},
+		{"quic", &PingConfig{Address: quicListener.Addr().String(), QUIC: true}, "h3", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.ServerName = "example.com"
+			c.config.InsecureSkipVerify = true
+			result, err := Ping(context.Background(), c.config)
+			common.Must(err)
+			if result.Version != gotls.VersionTLS13 {
+				t.Error("unexpected version ", result.Version)
+			}
+			if result.NegotiatedProtocol != c.protocol {
+				t.Error("unexpected ALPN ", result.NegotiatedProtocol)
+			}
+			if result.ECHAccepted != c.ech {
+				t.Error("ECH accepted: ", result.ECHAccepted)
+			}
+			if result.CipherSuite == 0 || result.Handshake <= 0 {
+				t.Error("missing cipher suite or handshake latency")
+			}
+			if len(result.PeerCertificates) != 1 || result.PeerCertificates[0].DNSNames[0] != "example.com" {
+				t.Error("unexpected certificates ", result.PeerCertificates)
+			}
+		})
+	}
+
+	if _, err := Ping(context.Background(), &PingConfig{Address: listener.Addr().String(), Fingerprint: "nonexistent"}); err == nil {
+		t.Error("expect error for unknown fingerprint")
+	}
+}
@@ -0,0 +1,66 @@
+package tls
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/xtls/xray-core/features/policy"
+)
+
+// PolicyViolation is a setting of a Config that the security policy forbids.
+type PolicyViolation struct {
+	// Field is the name of the setting in the JSON config, or the path of it from where the Config is nested.
+	Field   string
+	Message string
+}
+
+func (v *PolicyViolation) Error() string {
+	return v.Field + ": " + v.Message
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// IsVersion returns whether v is a TLS version in the config, like "1.2".
+func IsVersion(v string) bool {
+	_, found := versions[v]
+	return found
+}
+
+// CheckPolicy returns the first setting of c that p forbids, or nil if c complies with p.
+func (c *Config) CheckPolicy(p policy.Security) *PolicyViolation {
+	if p.ForbidInsecure {
+		if c.AllowInsecure {
+			return &PolicyViolation{Field: "allowInsecure", Message: "forbidden by the security policy"}
+		}
+		if len(c.CipherSuites) > 0 {
+			insecure := make(map[string]bool)
+			for _, s := range tls.InsecureCipherSuites() {
+				insecure[s.Name] = true
+			}
+			for _, n := range strings.Split(c.CipherSuites, ":") {
+				if insecure[n] {
+					return &PolicyViolation{Field: "cipherSuites", Message: "insecure cipher suite " + n + " is forbidden by the security policy"}
+				}
+			}
+		}
+	}
+	if minVersion, found := versions[p.MinTLSVersion]; found {
+		// crypto/tls defaults to TLS 1.2, and so does GetTLSConfig with an unknown version
+		version, found := versions[c.MinVersion]
+		if !found {
+			version = tls.VersionTLS12
+		}
+		if version < minVersion {
+			return &PolicyViolation{Field: "minVersion", Message: tls.VersionName(version) + " is below " + p.MinTLSVersion + ", the minimum of the security policy"}
+		}
+		if version, found := versions[c.MaxVersion]; found && version < minVersion {
+			return &PolicyViolation{Field: "maxVersion", Message: "TLS " + c.MaxVersion + " is below " + p.MinTLSVersion + ", the minimum of the security policy"}
+		}
+	}
+	return nil
+}
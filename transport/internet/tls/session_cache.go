@@ -0,0 +1,85 @@
+package tls
+
+import (
+	"crypto/tls"
+	"runtime"
+	"sync"
+	"weak"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+const defaultSessionCacheSize = 128
+
+// sessionCaches holds the session caches of the configs, which are the configs of the outbounds.
+// An entry goes away with its config.
+var sessionCaches sync.Map // weak.Pointer[Config] -> *sessionCache
+
+type sessionCache struct {
+	std  tls.ClientSessionCache
+	utls utls.ClientSessionCache
+}
+
+func (c *Config) getSessionCache() *sessionCache {
+	key := weak.Make(c)
+	if cache, found := sessionCaches.Load(key); found {
+		return cache.(*sessionCache)
+	}
+	size := int(c.SessionCacheSize)
+	if size == 0 {
+		size = defaultSessionCacheSize
+	}
+	cache, loaded := sessionCaches.LoadOrStore(key, &sessionCache{
+		std:  tls.NewLRUClientSessionCache(size),
+		utls: utls.NewLRUClientSessionCache(size),
+	})
+	if !loaded {
+		runtime.AddCleanup(c, func(key weak.Pointer[Config]) {
+			sessionCaches.Delete(key)
+		}, key)
+	}
+	return cache.(*sessionCache)
+}
+
+// sessionResumption returns whether the connections of the config resume their sessions.
+func (c *Config) sessionResumption() bool {
+	return (c.EnableSessionResumption || c.SessionCacheSize > 0) && !c.DisableSessionResumption
+}
+
+// clientSessionCache keys the sessions by fingerprint as well as by server name,
+// as a session of one ClientHello would give the fingerprint away if another one resumed it.
+// It carries the cache of uTLS, which copyConfig hands to the uTLS connections.
+type clientSessionCache struct {
+	fingerprint string
+	cache       *sessionCache
+}
+
+// Get implements tls.ClientSessionCache.
+func (c *clientSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	return c.cache.std.Get(c.fingerprint + "|" + sessionKey)
+}
+
+// Put implements tls.ClientSessionCache.
+func (c *clientSessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	c.cache.std.Put(c.fingerprint+"|"+sessionKey, cs)
+}
+
+type uClientSessionCache clientSessionCache
+
+// Get implements utls.ClientSessionCache.
+func (c *uClientSessionCache) Get(sessionKey string) (*utls.ClientSessionState, bool) {
+	return c.cache.utls.Get(c.fingerprint + "|" + sessionKey)
+}
+
+// Put implements utls.ClientSessionCache.
+func (c *uClientSessionCache) Put(sessionKey string, cs *utls.ClientSessionState) {
+	c.cache.utls.Put(c.fingerprint+"|"+sessionKey, cs)
+}
+
+// uSessionCache returns the uTLS counterpart of the session cache of a config from GetTLSConfig.
+func uSessionCache(cache tls.ClientSessionCache) utls.ClientSessionCache {
+	if c, ok := cache.(*clientSessionCache); ok {
+		return (*uClientSessionCache)(c)
+	}
+	return nil
+}
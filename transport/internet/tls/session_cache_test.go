@@ -0,0 +1,92 @@
+package tls_test
+
+import (
+	"context"
+	gotls "crypto/tls"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	. "github.com/xtls/xray-core/transport/internet/tls"
+)
+
+func TestSessionResumption(t *testing.T) {
+	certificate, err := gotls.X509KeyPair(cert.MustGenerate(nil, cert.DNSNames("example.com")).ToPEM())
+	common.Must(err)
+	// whether the last ClientHello has the extension of ECH, which is GREASE without a config of ECH
+	var ech atomic.Bool
+	listener, err := gotls.Listen("tcp", "127.0.0.1:0", &gotls.Config{
+		Certificates: []gotls.Certificate{certificate},
+		GetConfigForClient: func(hello *gotls.ClientHelloInfo) (*gotls.Config, error) {
+			ech.Store(slices.Contains(hello.Extensions, 0xfe0d))
+			return nil, nil
+		},
+	})
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// the tickets of TLS 1.3 come after the handshake, the client gets them while reading this
+			conn.Write([]byte{0})
+			conn.Close()
+		}
+	}()
+
+	dial := func(config *Config) bool {
+		rawConn, err := net.Dial("tcp", listener.Addr().String())
+		common.Must(err)
+		defer rawConn.Close()
+
+		tlsConfig := config.GetTLSConfig()
+		var conn net.Conn
+		if fingerprint := GetFingerprint(config.Fingerprint); fingerprint != nil {
+			conn = UClient(rawConn, tlsConfig, fingerprint)
+		} else {
+			conn = Client(rawConn, tlsConfig)
+		}
+		common.Must(conn.(Interface).HandshakeContext(context.Background()))
+		conn.Read(make([]byte, 1))
+		switch conn := conn.(type) {
+		case *Conn:
+			return conn.ConnectionState().DidResume
+		case *UConn:
+			return conn.ConnectionState().DidResume
+		}
+		return false
+	}
+
+	for _, c := range []struct {
+		name   string
+		config *Config
+		resume bool
+	}{
+		{"default", &Config{}, false},
+		{"cache", &Config{SessionCacheSize: 8}, true},
+		{"enabled", &Config{EnableSessionResumption: true}, true},
+		{"disabled", &Config{SessionCacheSize: 8, DisableSessionResumption: true}, false},
+		{"chrome", &Config{SessionCacheSize: 8, Fingerprint: "chrome"}, true},
+		{"crypto/tls", &Config{SessionCacheSize: 8, Fingerprint: "unsafe"}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.ServerName = "example.com"
+			c.config.AllowInsecure = true
+			if dial(c.config) {
+				t.Fatal("first handshake resumed")
+			}
+			if resumed := dial(c.config); resumed != c.resume {
+				t.Error("expected resumption ", c.resume, ", but got ", resumed)
+			}
+			// the resumed ClientHello keeps the GREASE ECH of the fingerprint, Chrome unless it is crypto/tls
+			if expected := c.config.Fingerprint != "unsafe"; ech.Load() != expected {
+				t.Error("expected the extension of ECH ", expected, ", but got ", ech.Load())
+			}
+		})
+	}
+}
@@ -122,10 +122,43 @@ func (c *UConn) NegotiatedProtocol() string {
 }
 
 func UClient(c net.Conn, config *tls.Config, fingerprint *utls.ClientHelloID) net.Conn {
-	utlsConn := utls.UClient(c, copyConfig(config), *fingerprint)
+	uConfig := copyConfig(config)
+	if spec := resumptionSpec(uConfig, fingerprint); spec != nil {
+		utlsConn := utls.UClient(c, uConfig, utls.HelloCustom)
+		if utlsConn.ApplyPreset(spec) == nil {
+			return &UConn{UConn: utlsConn}
+		}
+	}
+	utlsConn := utls.UClient(c, uConfig, *fingerprint)
 	return &UConn{UConn: utlsConn}
 }
 
+// resumptionSpec returns the ClientHello of the fingerprint with the pre-shared key extension, which browsers add
+// to resume a session of TLS 1.3, or nil if there is no session of the server or the fingerprint has the extension already.
+func resumptionSpec(config *utls.Config, fingerprint *utls.ClientHelloID) *utls.ClientHelloSpec {
+	if config.SessionTicketsDisabled || config.ClientSessionCache == nil || config.ServerName == "" {
+		return nil
+	}
+	if session, found := config.ClientSessionCache.Get(config.ServerName); !found || session == nil {
+		return nil
+	}
+	spec, err := utls.UTLSIdToSpec(*fingerprint)
+	if err != nil {
+		return nil
+	}
+	extensions := make([]utls.TLSExtension, 0, len(spec.Extensions)+1)
+	for _, extension := range spec.Extensions {
+		switch extension.(type) {
+		case utls.PreSharedKeyExtension:
+			return nil
+		}
+		extensions = append(extensions, extension)
+	}
+	// the pre-shared key is the last extension
+	spec.Extensions = append(extensions, &utls.UtlsPreSharedKeyExtension{})
+	return &spec
+}
+
 func copyConfig(c *tls.Config) *utls.Config {
 	return &utls.Config{
 		Rand:                           c.Rand,
@@ -135,6 +168,10 @@ func copyConfig(c *tls.Config) *utls.Config {
 		VerifyPeerCertificate:          c.VerifyPeerCertificate,
 		KeyLogWriter:                   c.KeyLogWriter,
 		EncryptedClientHelloConfigList: c.EncryptedClientHelloConfigList,
+		ClientSessionCache:             uSessionCache(c.ClientSessionCache),
+		SessionTicketsDisabled:         c.SessionTicketsDisabled,
+		// a session of TLS 1.2 leaves the pre-shared key empty
+		OmitEmptyPsk: true,
 	}
 }
 
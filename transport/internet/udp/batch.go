@@ -0,0 +1,221 @@
+package udp
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/xtls/xray-core/common/errors"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/platform"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// DefaultBatchSize is the number of datagrams read or written by one syscall,
+// unless overridden by the xray.udp.batch environment variable.
+var DefaultBatchSize = platform.NewEnvFlag(platform.UDPBatchSize).GetValueAsInt(8)
+
+const (
+	// maxSegments is the kernel limit of datagrams in one UDP_SEGMENT send.
+	maxSegments = 64
+	// groMessages is the number of coalesced payloads read by one syscall when UDP_GRO is on.
+	groMessages = 2
+	groOOBSize  = 128
+)
+
+type batchReadWriter interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// BatchConn reads and writes several datagrams per syscall, with recvmmsg(2)
+// and sendmmsg(2) on Linux. Elsewhere each call moves a single datagram.
+type BatchConn struct {
+	conn       *net.UDPConn
+	rw         batchReadWriter
+	maxPayload int
+
+	txOffload atomic.Bool
+	rxOffload bool
+
+	// payloads read with UDP_GRO, handed out one datagram at a time
+	rx    []ipv4.Message
+	rxN   int
+	rxPos int
+	rxOff int
+}
+
+// NewBatchConn wraps conn for batched I/O.
+func NewBatchConn(conn *net.UDPConn) *BatchConn {
+	c := &BatchConn{conn: conn}
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() == nil {
+		c.rw = ipv6.NewPacketConn(conn)
+		c.maxPayload = 1<<16 - 1 - 8
+	} else {
+		c.rw = ipv4.NewPacketConn(conn)
+		c.maxPayload = 1<<16 - 1 - 20 - 8
+	}
+	return c
+}
+
+// EnableOffload turns on UDP segmentation offload for WriteBatch and UDP receive offload for ReadBatch,
+// as far as the kernel supports them. It returns false if neither is available.
+// Segmentation falls back to plain datagrams for good when the first offloaded send is refused by the interface.
+func (c *BatchConn) EnableOffload() bool {
+	tx, rx := enableOffload(c.conn)
+	c.txOffload.Store(tx)
+	if rx {
+		c.rx = make([]ipv4.Message, groMessages)
+		for i := range c.rx {
+			c.rx[i].Buffers = [][]byte{make([]byte, 1<<16)}
+			c.rx[i].OOB = make([]byte, groOOBSize)
+		}
+		c.rxOffload = true
+	}
+	return tx || rx
+}
+
+// ReadBatch reads at least one datagram into ms, and returns the number of datagrams read.
+// The source address of each datagram is set in its Addr.
+// With receive offload, ReadBatch must not be called concurrently.
+func (c *BatchConn) ReadBatch(ms []ipv4.Message) (int, error) {
+	if !c.rxOffload {
+		return c.rw.ReadBatch(ms, 0)
+	}
+	if c.rxPos == c.rxN {
+		n, err := c.rw.ReadBatch(c.rx, 0)
+		if err != nil {
+			return 0, err
+		}
+		c.rxN, c.rxPos, c.rxOff = n, 0, 0
+	}
+
+	n := 0
+	for n < len(ms) && c.rxPos < c.rxN {
+		m := &c.rx[c.rxPos]
+		end := m.N
+		if size := getGSOSize(m.OOB[:m.NN]); size > 0 && c.rxOff+size < end {
+			end = c.rxOff + size
+		}
+		ms[n].N = copy(ms[n].Buffers[0], m.Buffers[0][c.rxOff:end])
+		ms[n].NN = copy(ms[n].OOB, m.OOB[:m.NN])
+		ms[n].Flags = m.Flags
+		ms[n].Addr = m.Addr
+		n++
+		c.rxOff = end
+		if c.rxOff >= m.N {
+			c.rxPos++
+			c.rxOff = 0
+		}
+	}
+	return n, nil
+}
+
+// WriteBatch writes all datagrams in ms. Messages without Addr are sent to the connected peer.
+func (c *BatchConn) WriteBatch(ms []ipv4.Message) error {
+	if len(ms) > 1 && c.txOffload.Load() {
+		coalesced, runs := coalesceMessages(ms, c.maxPayload)
+		n, err := c.writeBatch(coalesced)
+		if err == nil || !isGSOError(err) {
+			return err
+		}
+		if c.txOffload.Swap(false) {
+			errors.LogWarningInner(context.Background(), err, "disabled UDP GSO on ", c.conn.LocalAddr(), ", the interface may not support checksum offload")
+		}
+		// the datagrams of the runs sent before the error are not sent again
+		for _, run := range runs[:n] {
+			ms = ms[run:]
+		}
+	}
+	_, err := c.writeBatch(ms)
+	return err
+}
+
+// writeBatch returns the number of messages of ms sent before an error.
+func (c *BatchConn) writeBatch(ms []ipv4.Message) (int, error) {
+	sent := 0
+	for sent < len(ms) {
+		n, err := c.rw.WriteBatch(ms[sent:], 0)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// coalesceMessages merges runs of datagrams to the same address into single UDP_SEGMENT sends.
+// All datagrams of a run have the size of the first one, except the last which may be shorter.
+// It also returns the number of datagrams merged into each message.
+func coalesceMessages(ms []ipv4.Message, maxPayload int) ([]ipv4.Message, []int) {
+	out := make([]ipv4.Message, 0, len(ms))
+	runs := make([]int, 0, len(ms))
+	for i := 0; i < len(ms); {
+		size := messageLen(&ms[i])
+		total := size
+		j := i + 1
+		for size > 0 && j < len(ms) && j-i < maxSegments && sameAddr(ms[i].Addr, ms[j].Addr) {
+			l := messageLen(&ms[j])
+			if l == 0 || l > size || total+l > maxPayload {
+				break
+			}
+			total += l
+			j++
+			if l < size {
+				break
+			}
+		}
+		runs = append(runs, j-i)
+		if j-i == 1 {
+			out = append(out, ms[i])
+			i = j
+			continue
+		}
+		m := ipv4.Message{
+			Addr: ms[i].Addr,
+			OOB:  appendGSOSize(append([]byte(nil), ms[i].OOB...), uint16(size)),
+		}
+		for k := i; k < j; k++ {
+			m.Buffers = append(m.Buffers, ms[k].Buffers...)
+		}
+		out = append(out, m)
+		i = j
+	}
+	return out, runs
+}
+
+func messageLen(m *ipv4.Message) int {
+	n := 0
+	for _, b := range m.Buffers {
+		n += len(b)
+	}
+	return n
+}
+
+func sameAddr(a, b net.Addr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a == b || a.String() == b.String()
+}
+
+// OffloadConn is a UDP connection for QUIC whose ReadBatch splits payloads coalesced by UDP_GRO.
+// quic-go picks up ReadBatch, and segments its own sends with UDP_SEGMENT.
+type OffloadConn struct {
+	*net.UDPConn
+	batch *BatchConn
+}
+
+// NewOffloadConn turns on UDP receive offload for conn. It returns conn itself if the kernel does not support it.
+func NewOffloadConn(conn *net.UDPConn) net.PacketConn {
+	batch := NewBatchConn(conn)
+	if batch.EnableOffload(); !batch.rxOffload {
+		return conn
+	}
+	return &OffloadConn{UDPConn: conn, batch: batch}
+}
+
+// ReadBatch implements the batchConn of quic-go.
+func (c *OffloadConn) ReadBatch(ms []ipv4.Message, _ int) (int, error) {
+	return c.batch.ReadBatch(ms)
+}
@@ -0,0 +1,68 @@
+package udp
+
+import (
+	"testing"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+)
+
+// gsoRefuser sends one message per call, and refuses segmented messages after the first one.
+type gsoRefuser struct {
+	segmented int
+	sent      [][]byte
+}
+
+func (w *gsoRefuser) ReadBatch(ms []ipv4.Message, flags int) (int, error) {
+	return 0, unix.EAGAIN
+}
+
+func (w *gsoRefuser) WriteBatch(ms []ipv4.Message, flags int) (int, error) {
+	if len(ms[0].OOB) > 0 {
+		if w.segmented > 0 {
+			return 0, unix.EIO
+		}
+		w.segmented++
+	}
+	w.sent = append(w.sent, ms[0].Buffers...)
+	return 1, nil
+}
+
+func TestBatchConnGSOFallback(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer conn.Close()
+
+	rw := &gsoRefuser{}
+	c := NewBatchConn(conn)
+	c.rw = rw
+	c.txOffload.Store(true)
+
+	// two runs of 4 datagrams, the second one is refused
+	ms := make([]ipv4.Message, 8)
+	for i := range ms {
+		size := 1200
+		if i == 3 {
+			size = 100
+		}
+		b := make([]byte, size)
+		b[0] = byte(i)
+		ms[i].Buffers = [][]byte{b}
+	}
+	if err := c.WriteBatch(ms); err != nil {
+		t.Fatal(err)
+	}
+	if c.txOffload.Load() {
+		t.Error("offload is still on")
+	}
+	if len(rw.sent) != len(ms) {
+		t.Fatal("sent ", len(rw.sent), " datagrams, expected ", len(ms))
+	}
+	for i, b := range rw.sent {
+		if int(b[0]) != i {
+			t.Error("datagram ", b[0], " sent as ", i)
+		}
+	}
+}
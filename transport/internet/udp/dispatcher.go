@@ -12,8 +12,10 @@ import (
 	"github.com/xtls/xray-core/common/errors"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol/udp"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/common/signal"
 	"github.com/xtls/xray-core/common/signal/done"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/transport"
 )
@@ -42,20 +44,47 @@ func (c *connEntry) terminate() {
 	common.Interrupt(c.link.Writer)
 }
 
+type DispatcherOption func(d *Dispatcher)
+
+// DispatcherIdleTimeout sets the duration after which an inactive UDP session is terminated.
+func DispatcherIdleTimeout(timeout time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		if timeout > 0 {
+			d.idleTimeout = timeout
+		}
+	}
+}
+
+// DispatcherPolicy terminates an inactive UDP session after the udpIdle of the policy of the level of its user,
+// which is known only when its first packet is dispatched.
+func DispatcherPolicy(pm policy.Manager) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.policyManager = pm
+	}
+}
+
 type Dispatcher struct {
 	sync.RWMutex
-	conn       *connEntry
-	dispatcher routing.Dispatcher
-	callback   ResponseCallback
-	callClose  func() error
-	closed     bool
+	conn        *connEntry
+	dispatcher  routing.Dispatcher
+	callback    ResponseCallback
+	callClose   func() error
+	closed      bool
+	idleTimeout time.Duration
+	// policyManager gives the idle timeout of the sessions by the levels of their users, if set
+	policyManager policy.Manager
 }
 
-func NewDispatcher(dispatcher routing.Dispatcher, callback ResponseCallback) *Dispatcher {
-	return &Dispatcher{
-		dispatcher: dispatcher,
-		callback:   callback,
+func NewDispatcher(dispatcher routing.Dispatcher, callback ResponseCallback, options ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		dispatcher:  dispatcher,
+		callback:    callback,
+		idleTimeout: time.Minute,
 	}
+	for _, opt := range options {
+		opt(d)
+	}
+	return d
 }
 
 func (v *Dispatcher) RemoveRay() {
@@ -68,6 +97,18 @@ func (v *Dispatcher) RemoveRay() {
 	}
 }
 
+// sessionIdleTimeout returns the idle timeout of the session of ctx.
+func (v *Dispatcher) sessionIdleTimeout(ctx context.Context) time.Duration {
+	if v.policyManager == nil {
+		return v.idleTimeout
+	}
+	var level uint32
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.User != nil {
+		level = inbound.User.Level
+	}
+	return v.policyManager.ForLevel(level).Timeouts.UDPIdle
+}
+
 func (v *Dispatcher) getInboundRay(ctx context.Context, dest net.Destination) (*connEntry, error) {
 	v.Lock()
 	defer v.Unlock()
@@ -99,7 +140,7 @@ func (v *Dispatcher) getInboundRay(ctx context.Context, dest net.Destination) (*
 		cancel: cancel,
 	}
 
-	entry.timer = signal.CancelAfterInactivity(ctx, entry.terminate, time.Minute)
+	entry.timer = signal.CancelAfterInactivity(ctx, entry.terminate, v.sessionIdleTimeout(ctx))
 	v.conn = entry
 	go handleInput(ctx, entry, dest, v.callback, v.callClose)
 	return entry, nil
@@ -177,9 +218,10 @@ func DialDispatcher(ctx context.Context, dispatcher routing.Dispatcher) (net.Pac
 	}
 
 	d := &Dispatcher{
-		dispatcher: dispatcher,
-		callback:   c.callback,
-		callClose:  c.Close,
+		dispatcher:  dispatcher,
+		callback:    c.callback,
+		callClose:   c.Close,
+		idleTimeout: time.Minute,
 	}
 	c.dispatcher = d
 	return c, nil
@@ -9,7 +9,10 @@ import (
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
 	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/protocol/udp"
+	"github.com/xtls/xray-core/common/session"
+	"github.com/xtls/xray-core/features/policy"
 	"github.com/xtls/xray-core/features/routing"
 	"github.com/xtls/xray-core/transport"
 	. "github.com/xtls/xray-core/transport/internet/udp"
@@ -88,3 +91,55 @@ func TestSameDestinationDispatching(t *testing.T) {
 		t.Error("msgCount: ", v)
 	}
 }
+
+// levelPolicyManager gives level n an udpIdle of n+1 seconds.
+type levelPolicyManager struct {
+	policy.DefaultManager
+}
+
+func (levelPolicyManager) ForLevel(level uint32) policy.Session {
+	p := policy.SessionDefault()
+	p.Timeouts.UDPIdle = time.Duration(level+1) * time.Second
+	return p
+}
+
+// idleOut returns how long the session of the first packet that dispatcher dispatches in ctx lasts without activity.
+func idleOut(ctx context.Context, options ...DispatcherOption) time.Duration {
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithSizeLimit(1024))
+	downlinkReader, _ := pipe.New(pipe.WithSizeLimit(1024))
+	td := &TestDispatcher{
+		OnDispatch: func(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+			return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
+		},
+	}
+	dispatcher := NewDispatcher(td, func(ctx context.Context, packet *udp.Packet) {}, options...)
+	defer dispatcher.RemoveRay()
+
+	start := time.Now()
+	b := buf.New()
+	b.WriteString("abcd")
+	dispatcher.Dispatch(ctx, net.UDPDestination(net.LocalHostIP, 53), b)
+	for {
+		mb, err := uplinkReader.ReadMultiBuffer()
+		if err != nil {
+			return time.Since(start)
+		}
+		buf.ReleaseMulti(mb)
+	}
+}
+
+func TestDispatcherIdleTimeout(t *testing.T) {
+	if d := idleOut(context.Background(), DispatcherIdleTimeout(500*time.Millisecond)); d < 400*time.Millisecond || d > 1500*time.Millisecond {
+		t.Error("expected the session to idle out after 500ms, but it lasted ", d)
+	}
+}
+
+func TestDispatcherPolicy(t *testing.T) {
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		User: &protocol.MemoryUser{Level: 1},
+	})
+	// the level of the user, not level 0, gives the timeout
+	if d := idleOut(ctx, DispatcherPolicy(levelPolicyManager{})); d < 1800*time.Millisecond || d > 4*time.Second {
+		t.Error("expected the session to idle out after the 2s of level 1, but it lasted ", d)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build linux
+// +build linux
+
+package udp
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/xtls/xray-core/common/net"
+	"golang.org/x/sys/unix"
+)
+
+const gsoDataLen = 2 // UDP_SEGMENT and UDP_GRO carry a uint16
+
+// enableOffload turns on UDP_GRO for conn, and reports which of UDP_SEGMENT and UDP_GRO the kernel supports.
+func enableOffload(conn *net.UDPConn) (tx, rx bool) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+	rc.Control(func(fd uintptr) {
+		_, err := unix.GetsockoptInt(int(fd), unix.IPPROTO_UDP, unix.UDP_SEGMENT)
+		tx = err == nil
+		rx = unix.SetsockoptInt(int(fd), unix.IPPROTO_UDP, unix.UDP_GRO, 1) == nil
+	})
+	return tx, rx
+}
+
+// appendGSOSize appends a UDP_SEGMENT control message, which makes the kernel split the payload into datagrams of size bytes.
+func appendGSOSize(oob []byte, size uint16) []byte {
+	start := len(oob)
+	oob = append(oob, make([]byte, unix.CmsgSpace(gsoDataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[start]))
+	h.Level = unix.IPPROTO_UDP
+	h.Type = unix.UDP_SEGMENT
+	h.SetLen(unix.CmsgLen(gsoDataLen))
+	*(*uint16)(unsafe.Pointer(&oob[start+unix.CmsgLen(0)])) = size
+	return oob
+}
+
+// getGSOSize returns the datagram size of a payload coalesced by UDP_GRO, or 0 if it is a single datagram.
+func getGSOSize(oob []byte) int {
+	for len(oob) > unix.SizeofCmsghdr {
+		h, data, rest, err := unix.ParseOneSocketControlMessage(oob)
+		if err != nil {
+			return 0
+		}
+		if h.Level == unix.IPPROTO_UDP && h.Type == unix.UDP_GRO && len(data) >= gsoDataLen {
+			return int(*(*uint16)(unsafe.Pointer(&data[0])))
+		}
+		oob = rest
+	}
+	return 0
+}
+
+// isGSOError reports whether err means the outgoing interface cannot segment UDP.
+// EIO is returned when the device has no tx checksum offload, EINVAL by kernels or tunnels that reject UDP_SEGMENT.
+func isGSOError(err error) bool {
+	return errors.Is(err, unix.EIO) || errors.Is(err, unix.EINVAL)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package udp
+
+import (
+	"github.com/xtls/xray-core/common/net"
+)
+
+func enableOffload(conn *net.UDPConn) (tx, rx bool) {
+	return false, false
+}
+
+func appendGSOSize(oob []byte, size uint16) []byte {
+	return oob
+}
+
+func getGSOSize(oob []byte) int {
+	return 0
+}
+
+func isGSOError(err error) bool {
+	return false
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol/udp"
 	"github.com/xtls/xray-core/transport/internet"
+	"golang.org/x/net/ipv4"
 )
 
 type HubOption func(h *Hub)
@@ -24,16 +25,26 @@ func HubReceiveOriginalDestination(r bool) HubOption {
 	}
 }
 
+// HubBatchSize sets the most datagrams read by one syscall. Values below 2 disable batching.
+func HubBatchSize(size int) HubOption {
+	return func(h *Hub) {
+		h.batchSize = size
+	}
+}
+
 type Hub struct {
 	conn         *net.UDPConn
+	batch        *BatchConn
 	cache        chan *udp.Packet
 	capacity     int
+	batchSize    int
 	recvOrigDest bool
 }
 
 func ListenUDP(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, options ...HubOption) (*Hub, error) {
 	hub := &Hub{
 		capacity:     256,
+		batchSize:    DefaultBatchSize,
 		recvOrigDest: false,
 	}
 	for _, opt := range options {
@@ -65,9 +76,14 @@ func ListenUDP(ctx context.Context, address net.Address, port net.Port, streamSe
 	}
 	errors.LogInfo(ctx, "listening UDP on ", address, ":", port)
 	hub.conn = udpConn.(*net.UDPConn)
+	hub.batch = NewBatchConn(hub.conn)
 	hub.cache = make(chan *udp.Packet, hub.capacity)
 
-	go hub.start()
+	if batchReadSupported && hub.batchSize > 1 {
+		go hub.startBatch()
+	} else {
+		go hub.start()
+	}
 	return hub, nil
 }
 
@@ -84,6 +100,28 @@ func (h *Hub) WriteTo(payload []byte, dest net.Destination) (int, error) {
 	})
 }
 
+// WriteMultiBuffer writes each buffer in mb as a datagram to dest, in as few syscalls as possible.
+// mb is released in any case.
+func (h *Hub) WriteMultiBuffer(mb buf.MultiBuffer, dest net.Destination) error {
+	defer buf.ReleaseMulti(mb)
+
+	addr := &net.UDPAddr{
+		IP:   dest.Address.IP(),
+		Port: int(dest.Port),
+	}
+	msgs := make([]ipv4.Message, 0, len(mb))
+	for _, b := range mb {
+		if b.IsEmpty() {
+			continue
+		}
+		msgs = append(msgs, ipv4.Message{
+			Buffers: [][]byte{b.Bytes()},
+			Addr:    addr,
+		})
+	}
+	return h.batch.WriteBatch(msgs)
+}
+
 func (h *Hub) start() {
 	c := h.cache
 	defer close(c)
@@ -131,6 +169,66 @@ func (h *Hub) start() {
 	}
 }
 
+func (h *Hub) startBatch() {
+	c := h.cache
+	defer close(c)
+
+	msgs := make([]ipv4.Message, h.batchSize)
+	buffers := make([]*buf.Buffer, h.batchSize)
+	for i := range msgs {
+		msgs[i].OOB = make([]byte, 256)
+	}
+
+	for {
+		for i := range msgs {
+			if buffers[i] == nil {
+				buffers[i] = buf.New()
+				msgs[i].Buffers = [][]byte{buffers[i].Extend(buf.Size)}
+			}
+		}
+
+		n, err := h.batch.ReadBatch(msgs)
+		if err != nil {
+			errors.LogInfoInner(context.Background(), err, "failed to read UDP msg")
+			for _, buffer := range buffers {
+				buffer.Release()
+			}
+			break
+		}
+
+		for i := 0; i < n; i++ {
+			msg := &msgs[i]
+			buffer := buffers[i]
+			if msg.N == 0 {
+				continue
+			}
+			buffers[i] = nil
+			buffer.Resize(0, int32(msg.N))
+
+			addr := msg.Addr.(*net.UDPAddr)
+			payload := &udp.Packet{
+				Payload: buffer,
+				Source:  net.UDPDestination(net.IPAddress(addr.IP), net.Port(addr.Port)),
+			}
+			if h.recvOrigDest && msg.NN > 0 {
+				payload.Target = RetrieveOriginalDest(msg.OOB[:msg.NN])
+				if payload.Target.IsValid() {
+					errors.LogDebug(context.Background(), "UDP original destination: ", payload.Target)
+				} else {
+					errors.LogInfo(context.Background(), "failed to read UDP original destination")
+				}
+			}
+
+			select {
+			case c <- payload:
+			default:
+				buffer.Release()
+				payload.Payload = nil
+			}
+		}
+	}
+}
+
 // Addr implements net.Listener.
 func (h *Hub) Addr() net.Addr {
 	return h.conn.LocalAddr()
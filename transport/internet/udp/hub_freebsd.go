@@ -13,6 +13,8 @@ import (
 	"github.com/xtls/xray-core/transport/internet"
 )
 
+const batchReadSupported = false
+
 // RetrieveOriginalDest from stored laddr, caddr
 func RetrieveOriginalDest(oob []byte) net.Destination {
 	dec := gob.NewDecoder(bytes.NewBuffer(oob))
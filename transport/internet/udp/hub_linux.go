@@ -10,6 +10,9 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// batchReadSupported reports whether the Hub reads several datagrams per syscall.
+const batchReadSupported = true
+
 func RetrieveOriginalDest(oob []byte) net.Destination {
 	msgs, err := syscall.ParseSocketControlMessage(oob)
 	if err != nil {
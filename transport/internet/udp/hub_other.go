@@ -7,6 +7,8 @@ import (
 	"github.com/xtls/xray-core/common/net"
 )
 
+const batchReadSupported = false
+
 func RetrieveOriginalDest(oob []byte) net.Destination {
 	return net.Destination{}
 }
@@ -0,0 +1,175 @@
+package udp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xtls/xray-core/common"
+	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
+	. "github.com/xtls/xray-core/transport/internet/udp"
+	"golang.org/x/net/ipv4"
+)
+
+func TestHubBatch(t *testing.T) {
+	hub, err := ListenUDP(context.Background(), net.LocalHostIP, 0, nil, HubBatchSize(8))
+	common.Must(err)
+	defer hub.Close()
+
+	const clients = 4
+	const packets = 16
+	conns := make([]*net.UDPConn, clients)
+	for i := range conns {
+		conns[i], err = net.DialUDP("udp", nil, hub.Addr().(*net.UDPAddr))
+		common.Must(err)
+		defer conns[i].Close()
+	}
+	for j := 0; j < packets; j++ {
+		for i, conn := range conns {
+			common.Must2(conn.Write([]byte{byte(i), byte(j)}))
+		}
+	}
+
+	// every packet keeps the source address of its own client
+	next := make([]int, clients)
+	timeout := time.After(5 * time.Second)
+	for received := 0; received < clients*packets; received++ {
+		select {
+		case p := <-hub.Receive():
+			i := int(p.Payload.Byte(0))
+			if local := conns[i].LocalAddr().(*net.UDPAddr); int(p.Source.Port) != local.Port {
+				t.Fatal("packet of client ", i, " from port ", p.Source.Port, ", expected ", local.Port)
+			}
+			if int(p.Payload.Byte(1)) != next[i] {
+				t.Fatal("packet ", p.Payload.Byte(1), " of client ", i, ", expected ", next[i])
+			}
+			next[i]++
+			p.Payload.Release()
+		case <-timeout:
+			t.Fatal("timeout after ", received, " packets")
+		}
+	}
+
+	mb := buf.MultiBuffer{}
+	for j := 0; j < packets; j++ {
+		b := buf.New()
+		b.WriteByte(byte(j))
+		mb = append(mb, b)
+	}
+	common.Must(hub.WriteMultiBuffer(mb, net.DestinationFromAddr(conns[0].LocalAddr())))
+	payload := make([]byte, 16)
+	for j := 0; j < packets; j++ {
+		common.Must(conns[0].SetReadDeadline(time.Now().Add(5 * time.Second)))
+		n, err := conns[0].Read(payload)
+		common.Must(err)
+		if n != 1 || int(payload[0]) != j {
+			t.Fatal("unexpected datagram ", payload[:n], ", expected ", j)
+		}
+	}
+}
+
+func TestBatchConnOffload(t *testing.T) {
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer receiver.Close()
+	sender, err := net.DialUDP("udp", nil, receiver.LocalAddr().(*net.UDPAddr))
+	common.Must(err)
+	defer sender.Close()
+
+	rbatch, sbatch := NewBatchConn(receiver), NewBatchConn(sender)
+	rbatch.EnableOffload()
+	sbatch.EnableOffload()
+
+	// equal sized datagrams are sent as one segmented payload, the shorter tail ends it
+	const packets = 16
+	smsgs := make([]ipv4.Message, packets)
+	for i := range smsgs {
+		size := 1200
+		if i == packets-1 {
+			size = 100
+		}
+		b := make([]byte, size)
+		b[0] = byte(i)
+		smsgs[i].Buffers = [][]byte{b}
+	}
+	common.Must(sbatch.WriteBatch(smsgs))
+
+	rmsgs := make([]ipv4.Message, 4)
+	for i := range rmsgs {
+		rmsgs[i].Buffers = [][]byte{make([]byte, buf.Size)}
+	}
+	common.Must(receiver.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	for received := 0; received < packets; {
+		n, err := rbatch.ReadBatch(rmsgs)
+		common.Must(err)
+		for _, m := range rmsgs[:n] {
+			if int(m.Buffers[0][0]) != received || m.N != len(smsgs[received].Buffers[0]) {
+				t.Fatal("datagram ", m.Buffers[0][0], " of ", m.N, " bytes, expected ", received)
+			}
+			received++
+		}
+	}
+}
+
+// benchmarkPacketRate sends and receives bursts of 1200 byte datagrams over loopback.
+// The loop runs on one goroutine, so ms/Gbit approximates the CPU time spent per gigabit.
+func benchmarkPacketRate(b *testing.B, burst int, batched, offload bool) {
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer receiver.Close()
+	common.Must(receiver.SetReadBuffer(4 << 20))
+	sender, err := net.DialUDP("udp", nil, receiver.LocalAddr().(*net.UDPAddr))
+	common.Must(err)
+	defer sender.Close()
+
+	rbatch, sbatch := NewBatchConn(receiver), NewBatchConn(sender)
+	if offload {
+		rbatch.EnableOffload()
+		sbatch.EnableOffload()
+	}
+	rmsgs := make([]ipv4.Message, burst)
+	smsgs := make([]ipv4.Message, burst)
+	for i := 0; i < burst; i++ {
+		rmsgs[i].Buffers = [][]byte{make([]byte, buf.Size)}
+		smsgs[i].Buffers = [][]byte{make([]byte, 1200)}
+	}
+
+	b.SetBytes(int64(burst) * 1200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batched {
+			common.Must(sbatch.WriteBatch(smsgs))
+			for received := 0; received < burst; {
+				n, err := rbatch.ReadBatch(rmsgs[received:])
+				common.Must(err)
+				received += n
+			}
+		} else {
+			for j := 0; j < burst; j++ {
+				common.Must2(sender.Write(smsgs[j].Buffers[0]))
+			}
+			for j := 0; j < burst; j++ {
+				common.Must2(receiver.Read(rmsgs[j].Buffers[0]))
+			}
+		}
+	}
+	gbits := float64(b.N) * float64(burst) * 1200 * 8 / 1e9
+	b.ReportMetric(float64(b.Elapsed().Milliseconds())/gbits, "ms/Gbit")
+}
+
+func BenchmarkPacketRate(b *testing.B) {
+	benchmarkPacketRate(b, 8, false, false)
+}
+
+func BenchmarkPacketRateBatch(b *testing.B) {
+	benchmarkPacketRate(b, 8, true, false)
+}
+
+func BenchmarkPacketRateOffload(b *testing.B) {
+	benchmarkPacketRate(b, 32, true, true)
+}
+
+func BenchmarkPacketRateNoOffload(b *testing.B) {
+	benchmarkPacketRate(b, 32, true, false)
+}
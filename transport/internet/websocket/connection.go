@@ -41,6 +41,11 @@ func NewConnection(conn *websocket.Conn, remoteAddr net.Addr, extraReader io.Rea
 	}
 }
 
+// PeerAddr implements internet.ForwardedConnection.
+func (c *connection) PeerAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
 // Read implements net.Conn.Read()
 func (c *connection) Read(b []byte) (int, error) {
 	for {
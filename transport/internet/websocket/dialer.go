@@ -63,28 +63,39 @@ func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *in
 		protocol = "wss"
 		tlsConfig := tConfig.GetTLSConfig(tls.WithDestination(dest), tls.WithNextProto("http/1.1"))
 		dialer.TLSClientConfig = tlsConfig
-		if fingerprint := tls.GetFingerprint(tConfig.Fingerprint); fingerprint != nil {
-			dialer.NetDialTLSContext = func(_ context.Context, _, addr string) (gonet.Conn, error) {
-				// Like the NetDial in the dialer
-				pconn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
-				if err != nil {
+		fingerprint := tls.GetFingerprint(tConfig.Fingerprint)
+		// the handshake is always applied here, as the dialer would set the SNI to the address if it is empty with noSNI
+		dialer.NetDialTLSContext = func(_ context.Context, _, addr string) (gonet.Conn, error) {
+			// Like the NetDial in the dialer
+			pconn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
+			if err != nil {
+				errors.LogErrorInner(ctx, err, "failed to dial to "+addr)
+				return nil, err
+			}
+			start := time.Now()
+			if fingerprint == nil {
+				cn := tls.Client(pconn, tlsConfig).(*tls.Conn)
+				if err := cn.HandshakeContext(ctx); err != nil {
 					errors.LogErrorInner(ctx, err, "failed to dial to "+addr)
 					return nil, err
 				}
-				// TLS and apply the handshake
-				cn := tls.UClient(pconn, tlsConfig, fingerprint).(*tls.UConn)
-				if err := cn.WebsocketHandshakeContext(ctx); err != nil {
+				internet.ObserveHandshake(ctx, start)
+				return cn, nil
+			}
+			// TLS and apply the handshake
+			cn := tls.UClient(pconn, tlsConfig, fingerprint).(*tls.UConn)
+			if err := cn.WebsocketHandshakeContext(ctx); err != nil {
+				errors.LogErrorInner(ctx, err, "failed to dial to "+addr)
+				return nil, err
+			}
+			if !tlsConfig.InsecureSkipVerify {
+				if err := cn.VerifyHostname(tlsConfig.ServerName); err != nil {
 					errors.LogErrorInner(ctx, err, "failed to dial to "+addr)
 					return nil, err
 				}
-				if !tlsConfig.InsecureSkipVerify {
-					if err := cn.VerifyHostname(tlsConfig.ServerName); err != nil {
-						errors.LogErrorInner(ctx, err, "failed to dial to "+addr)
-						return nil, err
-					}
-				}
-				return cn, nil
 			}
+			internet.ObserveHandshake(ctx, start)
+			return cn, nil
 		}
 	}
 
@@ -105,13 +116,7 @@ func dialWebSocket(ctx context.Context, dest net.Destination, streamSettings *in
 
 	header := wsSettings.GetRequestHeader()
 	// See dialer.DialContext()
-	header.Set("Host", wsSettings.Host)
-	if header.Get("Host") == "" && tConfig != nil {
-		header.Set("Host", tConfig.ServerName)
-	}
-	if header.Get("Host") == "" {
-		header.Set("Host", dest.Address.String())
-	}
+	header.Set("Host", tls.RequestHost(wsSettings.Host, tConfig.GetServerName(), dest))
 	if ed != nil {
 		// RawURLEncoding is support by both V2Ray/V2Fly and XRay.
 		header.Set("Sec-WebSocket-Protocol", base64.RawURLEncoding.EncodeToString(ed))
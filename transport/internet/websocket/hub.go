@@ -17,6 +17,7 @@ import (
 	"github.com/xtls/xray-core/common/net"
 	http_proto "github.com/xtls/xray-core/common/protocol/http"
 	"github.com/xtls/xray-core/transport/internet"
+	"github.com/xtls/xray-core/transport/internet/stat"
 	v2tls "github.com/xtls/xray-core/transport/internet/tls"
 )
 
@@ -44,10 +45,16 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		writer.WriteHeader(http.StatusNotFound)
 		return
 	}
+	// the path of the inbound itself wins over the path handlers
+	var handedTo string
 	if request.URL.Path != h.path {
-		errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
-		writer.WriteHeader(http.StatusNotFound)
-		return
+		handler := internet.MatchPathHandler(h.ln.config.GetPathHandlers(), request.URL.Path)
+		if handler == nil {
+			errors.LogInfo(context.Background(), "failed to validate path, request:", request.URL.Path, ", config:", h.path)
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		handedTo = handler.Tag
 	}
 
 	var extraReader io.Reader
@@ -84,7 +91,11 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 		}
 	}
 
-	h.ln.addConn(NewConnection(conn, remoteAddr, extraReader, h.ln.config.HeartbeatPeriod))
+	var wsConn stat.Connection = NewConnection(conn, remoteAddr, extraReader, h.ln.config.HeartbeatPeriod)
+	if handedTo != "" {
+		wsConn = &internet.HandedConnection{Connection: wsConn, Tag: handedTo}
+	}
+	h.ln.addConn(wsConn)
 }
 
 type Listener struct {
@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/xtls/xray-core/app/stats"
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/protocol/tls/cert"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/testing/servers/tcp"
 	"github.com/xtls/xray-core/transport/internet"
 	"github.com/xtls/xray-core/transport/internet/stat"
@@ -151,3 +153,36 @@ func Test_listenWSAndDial_TLS(t *testing.T) {
 		t.Error("end: ", end, " start: ", start)
 	}
 }
+
+func TestDialTLSHandshakeTimings(t *testing.T) {
+	listenPort := tcp.PickPort()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path: "wss",
+		},
+		SecurityType: "tls",
+		SecuritySettings: &tls.Config{
+			AllowInsecure: true,
+			Certificate:   []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("localhost")))},
+		},
+	}
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, listenPort, streamSettings, func(conn stat.Connection) {
+		_ = conn.Close()
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	connect, handshake := stats.NewHistogram([]int64{1000}), stats.NewHistogram([]int64{1000})
+	ctx := session.ContextWithDialTimings(context.Background(), &session.DialTimings{Connect: connect, Handshake: handshake})
+	conn, err := Dial(ctx, net.TCPDestination(net.DomainAddress("localhost"), listenPort), streamSettings)
+	common.Must(err)
+	_ = conn.Close()
+
+	if counts, _ := connect.Counts(); counts[0]+counts[1] != 1 {
+		t.Error("expected one connect time, but got ", counts)
+	}
+	if counts, _ := handshake.Counts(); counts[0]+counts[1] != 1 {
+		t.Error("expected one handshake time, but got ", counts)
+	}
+}
@@ -24,6 +24,8 @@ const (
 type pipeOption struct {
 	limit           int32 // maximum buffer size in bytes
 	discardOverflow bool
+	highWatermark   int32 // queued bytes at which writes block, replaces limit if positive
+	lowWatermark    int32 // queued bytes at which blocked writes resume
 }
 
 func (o *pipeOption) isFull(curSize int32) bool {
@@ -39,6 +41,7 @@ type pipe struct {
 	errChan     chan error
 	option      pipeOption
 	state       state
+	throttled   bool
 }
 
 var (
@@ -54,10 +57,35 @@ func (p *pipe) Len() int32 {
 	return data.Len()
 }
 
+// isFull reports whether writes must wait. With watermarks, the pipe stays full
+// from the high watermark until the reader drains it to the low watermark.
+func (p *pipe) isFull() bool {
+	if p.option.highWatermark <= 0 {
+		return p.option.isFull(p.data.Len())
+	}
+	size := p.data.Len()
+	if p.throttled && size <= p.option.lowWatermark {
+		p.throttled = false
+	} else if !p.throttled && size >= p.option.highWatermark {
+		p.throttled = true
+	}
+	return p.throttled
+}
+
+// setWatermarks replaces the watermarks of the pipe, and wakes up the blocked writers to check them again.
+func (p *pipe) setWatermarks(high, low int32) {
+	p.Lock()
+	WithWatermarks(high, low)(&p.option)
+	p.throttled = false
+	p.Unlock()
+
+	p.writeSignal.Signal()
+}
+
 func (p *pipe) getState(forRead bool) error {
 	switch p.state {
 	case open:
-		if !forRead && p.option.isFull(p.data.Len()) {
+		if !forRead && p.isFull() {
 			return errBufferFull
 		}
 		return nil
@@ -126,6 +154,8 @@ func (p *pipe) ReadMultiBufferTimeout(d time.Duration) (buf.MultiBuffer, error)
 	}
 }
 
+// writeMultiBufferInternal takes mb as a whole or not at all, as its buffers may be datagrams,
+// so a write below the high watermark may leave the pipe over it.
 func (p *pipe) writeMultiBufferInternal(mb buf.MultiBuffer) error {
 	p.Lock()
 	defer p.Unlock()
@@ -174,6 +204,7 @@ func (p *pipe) WriteMultiBuffer(mb buf.MultiBuffer) error {
 			return err
 		}
 
+		p.readSignal.Signal()
 		select {
 		case <-p.writeSignal.Wait():
 		case <-p.done.Wait():
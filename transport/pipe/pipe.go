@@ -25,6 +25,19 @@ func WithSizeLimit(limit int32) Option {
 	}
 }
 
+// WithWatermarks returns an Option for Pipe to block writes once high bytes are queued,
+// until the reader drains it to low bytes. It takes precedence over the size limit.
+// Writes are never split, so the last write before blocking may go over high.
+func WithWatermarks(high, low int32) Option {
+	return func(opt *pipeOption) {
+		if low < 0 || low > high {
+			low = high
+		}
+		opt.highWatermark = high
+		opt.lowWatermark = low
+	}
+}
+
 // DiscardOverflow returns an Option for Pipe to discard writes if full.
 func DiscardOverflow() Option {
 	return func(opt *pipeOption) {
@@ -32,12 +45,28 @@ func DiscardOverflow() Option {
 	}
 }
 
+type watermarksKey struct{}
+
+type watermarks struct {
+	high, low int32
+}
+
+// ContextWithWatermarks returns a context whose pipes use the given watermarks instead of those of the buffer policy.
+func ContextWithWatermarks(ctx context.Context, high, low int32) context.Context {
+	return context.WithValue(ctx, watermarksKey{}, watermarks{high: high, low: low})
+}
+
 // OptionsFromContext returns a list of Options from context.
 func OptionsFromContext(ctx context.Context) []Option {
 	var opt []Option
 
 	bp := policy.BufferPolicyFromContext(ctx)
-	if bp.PerConnection >= 0 {
+	if w, ok := ctx.Value(watermarksKey{}).(watermarks); ok {
+		bp.HighWatermark, bp.LowWatermark = w.high, w.low
+	}
+	if bp.HighWatermark > 0 {
+		opt = append(opt, WithWatermarks(bp.HighWatermark, bp.LowWatermark))
+	} else if bp.PerConnection >= 0 {
 		opt = append(opt, WithSizeLimit(bp.PerConnection))
 	} else {
 		opt = append(opt, WithoutSizeLimit())
@@ -11,6 +11,7 @@ import (
 
 	"github.com/xtls/xray-core/common"
 	"github.com/xtls/xray-core/common/buf"
+	"github.com/xtls/xray-core/common/net"
 	. "github.com/xtls/xray-core/transport/pipe"
 )
 
@@ -151,3 +152,119 @@ func BenchmarkPipeReadWrite(b *testing.B) {
 		c = d
 	}
 }
+
+func TestPipeWatermarks(t *testing.T) {
+	const high, low = 32 * 1024, 16 * 1024
+	pReader, pWriter := New(WithWatermarks(high, low))
+
+	var errg errgroup.Group
+	errg.Go(func() error {
+		for i := 0; i < 64; i++ {
+			b := buf.New()
+			b.Extend(buf.Size)
+			if err := pWriter.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+				return err
+			}
+		}
+		return pWriter.Close()
+	})
+
+	// A slow reader lets the writer run ahead, which must stop at the high watermark.
+	total := 0
+	for {
+		time.Sleep(time.Millisecond * 5)
+		mb, err := pReader.ReadMultiBuffer()
+		if err == io.EOF {
+			break
+		}
+		common.Must(err)
+		if mb.Len() > high {
+			t.Fatal("queued ", mb.Len(), " bytes, over the high watermark")
+		}
+		total += int(mb.Len())
+		buf.ReleaseMulti(mb)
+	}
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if total != 64*buf.Size {
+		t.Error("read ", total, " bytes")
+	}
+}
+
+func TestPipeSetWatermarks(t *testing.T) {
+	const high, low = 32 * 1024, 16 * 1024
+	pReader, pWriter := New(WithoutSizeLimit())
+	pReader.SetWatermarks(high, low)
+
+	var errg errgroup.Group
+	errg.Go(func() error {
+		for i := 0; i < 64; i++ {
+			b := buf.New()
+			b.Extend(buf.Size)
+			if err := pWriter.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+				return err
+			}
+		}
+		return pWriter.Close()
+	})
+
+	for {
+		time.Sleep(time.Millisecond * 5)
+		mb, err := pReader.ReadMultiBuffer()
+		if err == io.EOF {
+			break
+		}
+		common.Must(err)
+		if mb.Len() > high {
+			t.Fatal("queued ", mb.Len(), " bytes, over the high watermark")
+		}
+		buf.ReleaseMulti(mb)
+	}
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeWatermarksKeepDatagrams(t *testing.T) {
+	pReader, pWriter := New(WithWatermarks(1500, 1000))
+
+	var errg errgroup.Group
+	errg.Go(func() error {
+		for i := 0; i < 4; i++ {
+			b := buf.New()
+			b.Extend(1000)
+			b.UDP = &net.Destination{Network: net.Network_UDP, Address: net.LocalHostIP, Port: net.Port(1000 + i)}
+			if err := pWriter.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+				return err
+			}
+		}
+		return pWriter.Close()
+	})
+
+	var datagrams []*buf.Buffer
+	for {
+		time.Sleep(time.Millisecond * 5)
+		mb, err := pReader.ReadMultiBuffer()
+		if err == io.EOF {
+			break
+		}
+		common.Must(err)
+		datagrams = append(datagrams, mb...)
+	}
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(datagrams) != 4 {
+		t.Fatal("read ", len(datagrams), " datagrams, expected 4")
+	}
+	for i, b := range datagrams {
+		if b.Len() != 1000 {
+			t.Error("datagram ", i, " has ", b.Len(), " bytes")
+		}
+		if b.UDP == nil || b.UDP.Port != net.Port(1000+i) {
+			t.Error("datagram ", i, " lost its destination: ", b.UDP)
+		}
+	}
+	buf.ReleaseMulti(datagrams)
+}
@@ -21,6 +21,16 @@ func (r *Reader) ReadMultiBufferTimeout(d time.Duration) (buf.MultiBuffer, error
 	return r.pipe.ReadMultiBufferTimeout(d)
 }
 
+// Len returns the size of the content buffered in the pipe.
+func (r *Reader) Len() int32 {
+	return r.pipe.Len()
+}
+
+// SetWatermarks makes the pipe use the given watermarks from now on, as WithWatermarks does.
+func (r *Reader) SetWatermarks(high, low int32) {
+	r.pipe.setWatermarks(high, low)
+}
+
 // Interrupt implements common.Interruptible.
 func (r *Reader) Interrupt() {
 	r.pipe.Interrupt()